@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
+)
+
+func (dm *disruptionManager) addBlackout(blackout dm_proto.Blackout) error {
+	if blackout.Name == "" {
+		return errors.New("no blackout name specified")
+	}
+	if blackout.Start.IsZero() {
+		blackout.Start = time.Now()
+	}
+	if !blackout.End.After(blackout.Start) {
+		return errors.New("blackout end must be after start")
+	}
+	if time.Until(blackout.End) <= 0 {
+		return errors.New("blackout has already ended")
+	}
+	dm.mutex.Lock()
+	if _, ok := dm.blackouts[blackout.Name]; ok {
+		dm.mutex.Unlock()
+		return errors.New("blackout: " + blackout.Name + " already exists")
+	}
+	dm.blackouts[blackout.Name] = blackout
+	blackouts := dm.getBlackoutsLocked()
+	dm.exportable = nil
+	dm.mutex.Unlock()
+	dm.logger.Printf("Added blackout: %s from: %s until: %s, reason: %s\n",
+		blackout.Name, blackout.Start.Format(time.RFC3339),
+		blackout.End.Format(time.RFC3339), blackout.Reason)
+	return dm.writeBlackouts(blackouts)
+}
+
+// findBlackout returns the first active blackout for the group, or nil. It
+// must be called with the lock held.
+func (dm *disruptionManager) findBlackout(groupIdentifier string,
+	now time.Time) *dm_proto.Blackout {
+	for _, blackout := range dm.getBlackoutsLocked() {
+		if now.Before(blackout.Start) || !now.Before(blackout.End) {
+			continue
+		}
+		if len(blackout.Groups) < 1 {
+			return &blackout
+		}
+		for _, group := range blackout.Groups {
+			if group == groupIdentifier {
+				return &blackout
+			}
+		}
+	}
+	return nil
+}
+
+func (dm *disruptionManager) getBlackouts() []dm_proto.Blackout {
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	return dm.getBlackoutsLocked()
+}
+
+// getBlackoutsLocked returns the blackouts sorted by start time. It must be
+// called with the lock held.
+func (dm *disruptionManager) getBlackoutsLocked() []dm_proto.Blackout {
+	blackouts := make([]dm_proto.Blackout, 0, len(dm.blackouts))
+	for _, blackout := range dm.blackouts {
+		blackouts = append(blackouts, blackout)
+	}
+	sort.Slice(blackouts, func(left, right int) bool {
+		if blackouts[left].Start.Equal(blackouts[right].Start) {
+			return blackouts[left].Name < blackouts[right].Name
+		}
+		return blackouts[left].Start.Before(blackouts[right].Start)
+	})
+	return blackouts
+}
+
+func (dm *disruptionManager) loadBlackouts() error {
+	if dm.blackoutsFilename == "" {
+		return nil
+	}
+	var blackouts []dm_proto.Blackout
+	err := json.ReadFromFile(dm.blackoutsFilename, &blackouts)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, blackout := range blackouts {
+		if time.Until(blackout.End) > 0 {
+			dm.blackouts[blackout.Name] = blackout
+		}
+	}
+	return nil
+}
+
+// pruneBlackouts removes expired blackouts. It must be called with the lock
+// held. It returns true if any were removed.
+func (dm *disruptionManager) pruneBlackouts(now time.Time) bool {
+	var pruned bool
+	for name, blackout := range dm.blackouts {
+		if !now.Before(blackout.End) {
+			delete(dm.blackouts, name)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+func (dm *disruptionManager) removeBlackout(name string) error {
+	dm.mutex.Lock()
+	if _, ok := dm.blackouts[name]; !ok {
+		dm.mutex.Unlock()
+		return errors.New("blackout: " + name + " not found")
+	}
+	delete(dm.blackouts, name)
+	blackouts := dm.getBlackoutsLocked()
+	dm.exportable = nil
+	dm.mutex.Unlock()
+	dm.logger.Printf("Removed blackout: %s\n", name)
+	sendNotification(dm.recalculateNotifier)
+	return dm.writeBlackouts(blackouts)
+}
+
+func (dm *disruptionManager) writeBlackouts(
+	blackouts []dm_proto.Blackout) error {
+	if dm.blackoutsFilename == "" {
+		return nil
+	}
+	return json.WriteToFile(dm.blackoutsFilename, fsutil.PublicFilePerms,
+		"    ", blackouts)
+}
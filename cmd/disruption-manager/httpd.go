@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"io"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/format"
@@ -117,7 +119,7 @@ func (s *httpServer) showStateHandler(w http.ResponseWriter,
 	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
 	tw, _ := html.NewTableWriter(writer, true,
 		"Hostname", "State", "Group", "Request Age/Timeout", "Ready Timeout",
-		"Ready URL")
+		"Ready URL", "Reason")
 	groupList := s.disruptionManager.getGroupList()
 	now := time.Now()
 	for _, groupInfo := range groupList.groups {
@@ -132,7 +134,7 @@ func (s *httpServer) showStateHandler(w http.ResponseWriter,
 				format.Duration(now.Sub(hostInfo.LastRequest))+"/"+
 					format.Duration(hostInfo.LastRequest.Add(
 						s.disruptionManager.maxDuration).Sub(now)),
-				"", "")
+				"", "", "")
 		}
 		for _, hostInfo := range groupInfo.Requested {
 			tw.WriteRow("", "",
@@ -140,20 +142,49 @@ func (s *httpServer) showStateHandler(w http.ResponseWriter,
 				format.Duration(now.Sub(hostInfo.LastRequest))+"/"+
 					format.Duration(hostInfo.LastRequest.Add(
 						s.disruptionManager.maxDuration).Sub(now)),
-				"", "")
+				"", "", template.HTMLEscapeString(groupInfo.DenyReason))
 		}
 		for _, waitInfo := range groupInfo.Waiting {
 			tw.WriteRow("", "",
 				waitInfo.Hostname, "waiting", groupInfo.Identifier,
 				"", format.Duration(waitInfo.ReadyTimeout.Sub(now)),
-				waitInfo.ReadyUrl)
+				waitInfo.ReadyUrl, "waiting for readiness")
 		}
 	}
 	tw.Close()
+	s.writeBlackouts(writer, now)
 	fmt.Fprintln(writer, "</center>")
 	fmt.Fprintln(writer, "</body>")
 }
 
+func (s *httpServer) writeBlackouts(writer io.Writer, now time.Time) {
+	blackouts := s.disruptionManager.getBlackouts()
+	if len(blackouts) < 1 {
+		return
+	}
+	fmt.Fprintln(writer, "<br>Blackouts:<br>")
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true,
+		"Name", "Groups", "Starts", "Ends", "Reason")
+	for _, blackout := range blackouts {
+		groups := "all"
+		if len(blackout.Groups) > 0 {
+			groups = strings.Join(blackout.Groups, " ")
+		}
+		starts := "started"
+		if blackout.Start.After(now) {
+			starts = format.Duration(blackout.Start.Sub(now))
+		}
+		tw.WriteRow("", "",
+			template.HTMLEscapeString(blackout.Name),
+			template.HTMLEscapeString(groups),
+			starts,
+			format.Duration(blackout.End.Sub(now)),
+			template.HTMLEscapeString(blackout.Reason))
+	}
+	tw.Close()
+}
+
 func (s *httpServer) statusHandler(w http.ResponseWriter, req *http.Request) {
 	if req.URL.Path != "/" {
 		http.NotFound(w, req)
@@ -184,6 +215,11 @@ func (s *httpServer) statusHandler(w http.ResponseWriter, req *http.Request) {
 			groupList.totalWaiting)
 		fmt.Fprintln(writer, `<a href="showState">dashboard</a><br>`)
 	}
+	if numBlackouts := len(s.disruptionManager.getBlackouts()); numBlackouts > 0 {
+		fmt.Fprintf(writer,
+			"%d blackouts: <a href=\"showState\">dashboard</a><br>\n",
+			numBlackouts)
+	}
 	for _, htmlWriter := range s.htmlWriters {
 		htmlWriter.WriteHtml(writer)
 	}
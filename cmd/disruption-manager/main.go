@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/configwatch"
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
@@ -15,6 +16,11 @@ import (
 )
 
 var (
+	configurationUrl = flag.String("configurationUrl", "",
+		"Optional URL (or file) with group policy configuration (JSON)")
+	groupMembershipTimeout = flag.Duration("groupMembershipTimeout",
+		24*time.Hour,
+		"Time after which a machine not seen is no longer counted in a group")
 	maximumPermittedDuration = flag.Duration("maximumPermittedDuration",
 		time.Hour,
 		"Maximum time disruption will be permitted after last request")
@@ -46,6 +52,14 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Unable to create Disruption Manager: %s\n", err)
 	}
+	if *configurationUrl != "" {
+		configChannel, err := configwatch.Watch(*configurationUrl,
+			5*time.Minute, decodeConfiguration, logger)
+		if err != nil {
+			logger.Fatalf("Unable to watch configuration: %s\n", err)
+		}
+		go dm.watchConfiguration(configChannel)
+	}
 	err = setupserver.SetupTlsWithParams(setupserver.Params{Logger: logger})
 	if err != nil {
 		logger.Fatalln(err)
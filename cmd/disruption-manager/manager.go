@@ -6,8 +6,8 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"text/template"
@@ -19,6 +19,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
 	sub_proto "github.com/Cloud-Foundations/Dominator/proto/sub"
 )
 
@@ -30,28 +31,41 @@ const (
 )
 
 type disruptionManager struct {
+	blackoutsFilename   string
 	logger              log.DebugLogger
 	maxDuration         time.Duration
 	stateFilename       string
 	recalculateNotifier chan<- struct{}
 	writeNotifier       chan<- struct{}
-	mutex               sync.Mutex                // Protect everything below.
+	mutex               sync.Mutex                   // Protect everything below.
+	blackouts           map[string]dm_proto.Blackout // Key: name.
+	config              *configurationType
 	exportable          *groupListType            // nil if invalid.
 	groups              map[string]*groupInfoType // Key: group identifier.
 }
 
 type groupInfoType struct {
+	denyReason   string // Why requested machines are not yet permitted.
+	identifier   string
+	lastTags     tags.Tags // From the most recent machine in the group.
 	maxPermitted uint64
+	members      map[string]time.Time     // K: hostname, V: last seen time.
 	permitted    map[string]time.Time     // K: hostname, V: last request time.
+	policy       groupPolicy              // Parsed from policyTags.
+	policyError  error                    // From parsing policyTags.
+	policyTags   tags.Tags                // nil: not yet parsed.
 	requested    map[string]time.Time     // K: hostname, V: last request time.
 	waiting      map[string]*waitDataType // K: hostname.
 }
 
 type groupStatsType struct {
-	Identifier string
-	Permitted  []hostInfoType `json:",omitempty"`
-	Requested  []hostInfoType `json:",omitempty"`
-	Waiting    []waitInfoType `json:",omitempty"`
+	Identifier   string
+	DenyReason   string         `json:",omitempty"`
+	MaxPermitted uint64         `json:",omitempty"`
+	NumMembers   uint           `json:",omitempty"`
+	Permitted    []hostInfoType `json:",omitempty"`
+	Requested    []hostInfoType `json:",omitempty"`
+	Waiting      []waitInfoType `json:",omitempty"`
 }
 
 type hostInfoType struct {
@@ -133,6 +147,8 @@ func newDisruptionManager(stateFilename string,
 	writeNotifier := make(chan struct{}, 1)
 	var groupList groupListType
 	dm := &disruptionManager{
+		blackouts:           make(map[string]dm_proto.Blackout),
+		config:              &configurationType{},
 		exportable:          &groupList,
 		groups:              make(map[string]*groupInfoType),
 		logger:              logger,
@@ -142,6 +158,11 @@ func newDisruptionManager(stateFilename string,
 		writeNotifier:       writeNotifier,
 	}
 	if stateFilename != "" {
+		dm.blackoutsFilename = filepath.Join(filepath.Dir(stateFilename),
+			"blackouts.json")
+		if err := dm.loadBlackouts(); err != nil {
+			return nil, err
+		}
 		err := json.ReadFromFile(stateFilename, &groupList.groups)
 		if err != nil {
 			if !os.IsNotExist(err) {
@@ -149,7 +170,7 @@ func newDisruptionManager(stateFilename string,
 			}
 		} else {
 			for _, groupStats := range groupList.groups {
-				group := newGroup()
+				group := newGroup(groupStats.Identifier)
 				dm.groups[groupStats.Identifier] = group
 				for _, host := range groupStats.Permitted {
 					if _, ok := group.permitted[host.Hostname]; !ok {
@@ -244,7 +265,7 @@ func (dm *disruptionManager) check(machine mdb.Machine) (
 	if !previouslyRequested {
 		return sub_proto.DisruptionStateDenied, "", nil
 	}
-	if !group.canPermit(machine.Tags) {
+	if !dm.canPermit(group, machine.Tags) {
 		return sub_proto.DisruptionStateRequested, "", nil
 	}
 	// Previously requested and now there is room. W00t!
@@ -267,9 +288,11 @@ func (dm *disruptionManager) getGroup(machine mdb.Machine) (
 	}
 	group := dm.groups[groupIdentifier]
 	if group == nil {
-		group = newGroup()
+		group = newGroup(groupIdentifier)
 		dm.groups[groupIdentifier] = group
 	}
+	group.lastTags = machine.Tags
+	group.members[machine.Hostname] = time.Now()
 	return group, makeGroupText(groupIdentifier)
 }
 
@@ -287,7 +310,12 @@ func (dm *disruptionManager) getGroupList() *groupListType {
 			continue
 		}
 		groupStats := groupStatsType{
-			Identifier: groupIdentifier,
+			Identifier:   groupIdentifier,
+			MaxPermitted: group.maxPermitted,
+			NumMembers:   uint(len(group.members)),
+		}
+		if len(group.requested) > 0 {
+			groupStats.DenyReason = group.denyReason
 		}
 		for hostname, lastRequest := range group.permitted {
 			groupStats.Permitted = append(groupStats.Permitted, hostInfoType{
@@ -345,10 +373,20 @@ func (dm *disruptionManager) recalculateOnce() []string {
 	defer func() {
 		dm.unlockAndInvalidate(invalidate)
 	}()
-	expireBefore := time.Now().Add(-dm.maxDuration)
+	now := time.Now()
+	expireBefore := now.Add(-dm.maxDuration)
 	var logLines []string
+	if dm.pruneBlackouts(now) {
+		invalidate = true
+	}
 	for groupIdentifier, group := range dm.groups {
 		groupText := makeGroupText(groupIdentifier)
+		for hostname, lastSeen := range group.members {
+			if now.Sub(lastSeen) > *groupMembershipTimeout {
+				invalidate = true
+				delete(group.members, hostname)
+			}
+		}
 		for hostname, waitData := range group.waiting {
 			if waitData.finished {
 				invalidate = true
@@ -370,7 +408,7 @@ func (dm *disruptionManager) recalculateOnce() []string {
 				delete(group.requested, hostname)
 				dm.logger.Printf("%s: requested/expired->denied (%s)\n",
 					hostname, groupText)
-			} else if group.canPermit(nil) {
+			} else if dm.canPermit(group, group.lastTags) {
 				invalidate = true
 				group.permitted[hostname] = lastRequestTime
 				delete(group.requested, hostname)
@@ -393,7 +431,7 @@ func (dm *disruptionManager) request(machine mdb.Machine) (
 		return sub_proto.DisruptionStatePermitted, "", nil
 	}
 	var logMessage string
-	if group.canPermit(machine.Tags) {
+	if dm.canPermit(group, machine.Tags) {
 		group.permitted[machine.Hostname] = time.Now()
 		if _, ok := group.requested[machine.Hostname]; ok {
 			logMessage = fmt.Sprintf("%s: requested->permitted (%s)",
@@ -443,19 +481,11 @@ func (dm *disruptionManager) writeOnce() error {
 	return nil
 }
 
-// canPermit returns true if the group can permit more disruption.
-func (group *groupInfoType) canPermit(tgs tags.Tags) bool {
-	maximum, err := strconv.ParseUint(tgs[tagGroupMaximumDisrupting], 10, 64)
-	if err != nil || maximum < 1 {
-		maximum = 1
-	}
-	group.maxPermitted = maximum
-	return uint64(len(group.permitted)+len(group.waiting)) < maximum
-}
-
-func newGroup() *groupInfoType {
+func newGroup(identifier string) *groupInfoType {
 	return &groupInfoType{
+		identifier:   identifier,
 		maxPermitted: 1,
+		members:      make(map[string]time.Time),
 		permitted:    make(map[string]time.Time),
 		requested:    make(map[string]time.Time),
 		waiting:      make(map[string]*waitDataType),
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+)
+
+const (
+	tagGroupMaximumDisruptingPercent = "DisruptionManagerGroupMaximumDisruptingPercent"
+	tagGroupSize                     = "DisruptionManagerGroupSize"
+	tagMaintenanceWindows            = "DisruptionManagerMaintenanceWindows"
+)
+
+// configurationType is the format of the configuration file. Groups which are
+// listed in the configuration file ignore the policy tags in the MDB data.
+type configurationType struct {
+	Groups   map[string]groupConfigType // Key: group identifier.
+	policies map[string]groupPolicy     // Key: group identifier.
+}
+
+type groupConfigType struct {
+	MaintenanceWindows       []string `json:",omitempty"`
+	MaximumDisrupting        uint64   `json:",omitempty"`
+	MaximumDisruptingPercent float64  `json:",omitempty"`
+	Size                     uint64   `json:",omitempty"`
+}
+
+// groupPolicy is the policy for a group, from the configuration file or the
+// MDB tags of the most recent machine in the group to make a request.
+type groupPolicy struct {
+	maximum        uint64  // Zero means unspecified.
+	maximumPercent float64 // Zero means unspecified.
+	size           uint64  // Zero means use the number of known members.
+	windows        maintenanceWindows
+}
+
+func decodeConfiguration(reader io.Reader) (interface{}, error) {
+	var config configurationType
+	decoder := json.NewDecoder(reader)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("error decoding configuration: %s", err)
+	}
+	config.policies = make(map[string]groupPolicy, len(config.Groups))
+	for groupIdentifier, groupConfig := range config.Groups {
+		policy, err := groupConfig.makePolicy()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s",
+				makeGroupText(groupIdentifier), err)
+		}
+		config.policies[groupIdentifier] = policy
+	}
+	return &config, nil
+}
+
+// makePolicyTags returns the tags which specify the policy for a group.
+func makePolicyTags(tgs tags.Tags) tags.Tags {
+	policyTags := make(tags.Tags)
+	for _, key := range []string{
+		tagGroupMaximumDisrupting,
+		tagGroupMaximumDisruptingPercent,
+		tagGroupSize,
+		tagMaintenanceWindows,
+	} {
+		if value, ok := tgs[key]; ok {
+			policyTags[key] = value
+		}
+	}
+	return policyTags
+}
+
+func makePolicyFromTags(tgs tags.Tags) (groupPolicy, error) {
+	var policy groupPolicy
+	// Invalid or missing maximums default to one, as before.
+	if value, err := strconv.ParseUint(tgs[tagGroupMaximumDisrupting], 10,
+		64); err == nil {
+		policy.maximum = value
+	}
+	if value, ok := tgs[tagGroupMaximumDisruptingPercent]; ok {
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return groupPolicy{}, fmt.Errorf("bad %s: %s",
+				tagGroupMaximumDisruptingPercent, value)
+		}
+		policy.maximumPercent = percent
+	}
+	if value, ok := tgs[tagGroupSize]; ok {
+		size, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return groupPolicy{}, fmt.Errorf("bad %s: %s", tagGroupSize, value)
+		}
+		policy.size = size
+	}
+	if value, ok := tgs[tagMaintenanceWindows]; ok {
+		windows, err := parseMaintenanceWindows(value)
+		if err != nil {
+			return groupPolicy{}, err
+		}
+		policy.windows = windows
+	}
+	return policy, nil
+}
+
+func (config groupConfigType) makePolicy() (groupPolicy, error) {
+	policy := groupPolicy{
+		maximum:        config.MaximumDisrupting,
+		maximumPercent: config.MaximumDisruptingPercent,
+		size:           config.Size,
+	}
+	if policy.maximumPercent < 0 || policy.maximumPercent > 100 {
+		return groupPolicy{}, fmt.Errorf("bad MaximumDisruptingPercent: %g",
+			policy.maximumPercent)
+	}
+	for _, windowText := range config.MaintenanceWindows {
+		window, err := parseMaintenanceWindow(windowText)
+		if err != nil {
+			return groupPolicy{}, err
+		}
+		policy.windows = append(policy.windows, window)
+	}
+	return policy, nil
+}
+
+// canPermit returns true if the group can permit more disruption. If not, the
+// reason is recorded in the group. The tags should be those from the MDB data
+// for the most recent machine in the group. It must be called with the lock
+// held.
+func (dm *disruptionManager) canPermit(group *groupInfoType,
+	tgs tags.Tags) bool {
+	permit, reason := dm.checkPolicy(group, tgs, time.Now())
+	if reason != group.denyReason {
+		group.denyReason = reason
+		dm.exportable = nil
+	}
+	return permit
+}
+
+func (dm *disruptionManager) checkPolicy(group *groupInfoType,
+	tgs tags.Tags, now time.Time) (bool, string) {
+	if blackout := dm.findBlackout(group.identifier, now); blackout != nil {
+		reason := fmt.Sprintf("blackout: %s for %s", blackout.Name,
+			format.Duration(blackout.End.Sub(now)))
+		if blackout.Reason != "" {
+			reason += ": " + blackout.Reason
+		}
+		return false, reason
+	}
+	policy, err := group.getPolicy(dm.config, tgs)
+	if err != nil {
+		return false, "bad policy: " + err.Error()
+	}
+	if !policy.windows.openAt(now) {
+		if next := policy.windows.nextOpening(now); next.IsZero() {
+			return false, "outside maintenance windows"
+		} else {
+			return false, "outside maintenance windows, next opens in " +
+				format.Duration(next.Sub(now))
+		}
+	}
+	maximum := policy.getMaximum(uint64(len(group.members)))
+	group.maxPermitted = maximum
+	numDisrupting := uint64(len(group.permitted) + len(group.waiting))
+	if numDisrupting < maximum {
+		return true, ""
+	}
+	return false, fmt.Sprintf(
+		"limit reached: %d permitted and %d waiting for readiness, limit: %d",
+		len(group.permitted), len(group.waiting), maximum)
+}
+
+// getPolicy returns the policy for the group. Policies from tags are parsed
+// when the policy tags change.
+func (group *groupInfoType) getPolicy(config *configurationType,
+	tgs tags.Tags) (groupPolicy, error) {
+	if policy, ok := config.policies[group.identifier]; ok {
+		return policy, nil
+	}
+	policyTags := makePolicyTags(tgs)
+	if group.policyTags == nil || !policyTags.Equal(group.policyTags) {
+		group.policy, group.policyError = makePolicyFromTags(policyTags)
+		group.policyTags = policyTags
+	}
+	return group.policy, group.policyError
+}
+
+// getMaximum returns the maximum number of machines which may be disrupted
+// concurrently. If both a count and a percentage are specified, the smaller
+// applies. The limit is always at least one.
+func (policy groupPolicy) getMaximum(numMembers uint64) uint64 {
+	maximum := policy.maximum
+	if policy.maximumPercent > 0 {
+		size := policy.size
+		if size < 1 {
+			size = numMembers
+		}
+		fromPercent := uint64(float64(size) * policy.maximumPercent / 100)
+		if maximum < 1 || fromPercent < maximum {
+			maximum = fromPercent
+		}
+	}
+	if maximum < 1 {
+		maximum = 1
+	}
+	return maximum
+}
+
+// watchConfiguration receives new configurations and triggers recalculation.
+func (dm *disruptionManager) watchConfiguration(
+	configChannel <-chan interface{}) {
+	for config := range configChannel {
+		dm.mutex.Lock()
+		dm.config = config.(*configurationType)
+		dm.mutex.Unlock()
+		dm.logger.Println("Loaded new configuration")
+		sendNotification(dm.recalculateNotifier)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
+	proto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+func TestMaintenanceWindows(t *testing.T) {
+	windows, err := parseMaintenanceWindows(
+		"TZ=America/New_York 0 2 * * 1-5 2h; 30 12 1 * * 30m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err) // No time zone database.
+	}
+	tests := []struct {
+		time time.Time
+		open bool
+	}{
+		// Monday 2024-01-08.
+		{time.Date(2024, 1, 8, 1, 59, 0, 0, newYork), false},
+		{time.Date(2024, 1, 8, 2, 0, 0, 0, newYork), true},
+		{time.Date(2024, 1, 8, 3, 59, 0, 0, newYork), true},
+		{time.Date(2024, 1, 8, 4, 0, 0, 0, newYork), false},
+		// Saturday 2024-01-06.
+		{time.Date(2024, 1, 6, 2, 30, 0, 0, newYork), false},
+		// First of the month, in UTC.
+		{time.Date(2024, 2, 1, 12, 45, 0, 0, time.UTC), true},
+		{time.Date(2024, 2, 1, 13, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if open := windows.openAt(test.time); open != test.open {
+			t.Errorf("%s: open: %v, expected: %v", test.time, open, test.open)
+		}
+	}
+	next := windows.nextOpening(time.Date(2024, 1, 6, 12, 0, 0, 0, newYork))
+	expected := time.Date(2024, 1, 8, 2, 0, 0, 0, newYork)
+	if !next.Equal(expected) {
+		t.Errorf("next opening: %s, expected: %s", next, expected)
+	}
+	for _, bad := range []string{
+		"0 2 * * *",
+		"60 2 * * * 1h",
+		"0 2 * * * 8d",
+		"TZ=Nowhere/Special 0 2 * * * 1h",
+	} {
+		if _, err := parseMaintenanceWindow(bad); err == nil {
+			t.Errorf("no error for: %s", bad)
+		}
+	}
+}
+
+// bruteForceOpenAt checks every minute back for the duration of the window.
+func bruteForceOpenAt(window *maintenanceWindow, t time.Time) bool {
+	for start := t.Truncate(time.Minute); t.Sub(start) < window.duration; {
+		local := start.In(window.location)
+		if window.minutes.match(local.Minute()) &&
+			window.hours.match(local.Hour()) &&
+			window.matchDay(local.Date()) {
+			return true
+		}
+		start = start.Add(-time.Minute)
+	}
+	return false
+}
+
+func TestMaintenanceWindowsBruteForce(t *testing.T) {
+	for _, text := range []string{
+		"TZ=America/New_York 30 2 * * 0 3h",
+		"TZ=Europe/London */20 22-23 * * 5 90m",
+		"0 0 1,15 * 3 36h",
+		"45 6 * 2 * 10m",
+	} {
+		window, err := parseMaintenanceWindow(text)
+		if err != nil {
+			t.Skip(err) // No time zone database.
+		}
+		// Spans daylight saving transitions in both directions.
+		start := time.Date(2024, 2, 25, 0, 0, 0, 0, time.UTC)
+		for tm := start; tm.Before(start.AddDate(0, 0, 250)); {
+			expected := bruteForceOpenAt(window, tm)
+			if open := window.openAt(tm); open != expected {
+				t.Fatalf("%s: %s: open: %v, expected: %v",
+					text, tm, open, expected)
+			}
+			tm = tm.Add(53 * time.Minute)
+		}
+	}
+}
+
+func TestPercentageLimit(t *testing.T) {
+	policy := groupPolicy{maximumPercent: 10}
+	if maximum := policy.getMaximum(5); maximum != 1 {
+		t.Errorf("maximum: %d != 1", maximum)
+	}
+	if maximum := policy.getMaximum(45); maximum != 4 {
+		t.Errorf("maximum: %d != 4", maximum)
+	}
+	policy.maximum = 3
+	if maximum := policy.getMaximum(45); maximum != 3 {
+		t.Errorf("maximum: %d != 3", maximum)
+	}
+	policy.size = 100
+	policy.maximum = 0
+	if maximum := policy.getMaximum(5); maximum != 10 {
+		t.Errorf("maximum: %d != 10", maximum)
+	}
+}
+
+func TestBlackout(t *testing.T) {
+	logger := testlogger.New(t)
+	dm, err := newDisruptionManager("", time.Second, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := mdb.Machine{
+		Hostname: "testhost-5",
+		Tags:     tags.Tags{tagGroupIdentifier: "blackout-group"},
+	}
+	err = dm.addBlackout(dm_proto.Blackout{
+		Groups: []string{"blackout-group"},
+		Name:   "freeze",
+		End:    time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := dm.request(machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != proto.DisruptionStateRequested {
+		t.Fatalf("during blackout state: %s != %s",
+			state, proto.DisruptionStateRequested)
+	}
+	groupList := dm.getGroupList()
+	if len(groupList.groups) != 1 || groupList.groups[0].DenyReason == "" {
+		t.Fatal("no deny reason recorded")
+	}
+	if err := dm.removeBlackout("freeze"); err != nil {
+		t.Fatal(err)
+	}
+	state, _, err = dm.check(machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != proto.DisruptionStatePermitted {
+		t.Fatalf("after blackout state: %s != %s",
+			state, proto.DisruptionStatePermitted)
+	}
+}
@@ -23,11 +23,20 @@ func startRpcServer(dm *disruptionManager, logger log.DebugLogger) error {
 			PublicMethods: []string{
 				"Cancel",
 				"Check",
+				"ListBlackouts",
 				"Request",
 			}})
 	return nil
 }
 
+func (t *rpcType) AddBlackout(conn *srpc.Conn,
+	request dm_proto.AddBlackoutRequest,
+	reply *dm_proto.AddBlackoutResponse) error {
+	err := t.disruptionManager.addBlackout(request.Blackout)
+	reply.Error = errors.ErrorToString(err)
+	return nil
+}
+
 func (t *rpcType) Cancel(conn *srpc.Conn,
 	request dm_proto.DisruptionCancelRequest,
 	reply *dm_proto.DisruptionCancelResponse) error {
@@ -63,6 +72,21 @@ func (t *rpcType) Check(conn *srpc.Conn,
 	return nil
 }
 
+func (t *rpcType) ListBlackouts(conn *srpc.Conn,
+	request dm_proto.ListBlackoutsRequest,
+	reply *dm_proto.ListBlackoutsResponse) error {
+	reply.Blackouts = t.disruptionManager.getBlackouts()
+	return nil
+}
+
+func (t *rpcType) RemoveBlackout(conn *srpc.Conn,
+	request dm_proto.RemoveBlackoutRequest,
+	reply *dm_proto.RemoveBlackoutResponse) error {
+	err := t.disruptionManager.removeBlackout(request.Name)
+	reply.Error = errors.ErrorToString(err)
+	return nil
+}
+
 func (t *rpcType) Request(conn *srpc.Conn,
 	request dm_proto.DisruptionRequestRequest,
 	reply *dm_proto.DisruptionRequestResponse) error {
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxWindowDuration = 7 * 24 * time.Hour
+
+// cronField is a set of permitted values for a field of a cron expression.
+type cronField struct {
+	all    bool // True if the field was "*" (matters for day matching).
+	sorted []int
+	values map[int]struct{}
+}
+
+// maintenanceWindow is a window which opens at times matching a cron-like
+// expression (evaluated in a time zone) and stays open for a duration.
+type maintenanceWindow struct {
+	daysOfMonth cronField
+	daysOfWeek  cronField
+	duration    time.Duration
+	hours       cronField
+	location    *time.Location
+	minutes     cronField
+	months      cronField
+	text        string
+}
+
+type maintenanceWindows []*maintenanceWindow
+
+func parseCronField(text string, min, max int) (cronField, error) {
+	field := cronField{values: make(map[int]struct{})}
+	if text == "*" {
+		field.all = true
+	}
+	for _, part := range strings.Split(text, ",") {
+		rangeText, stepText, haveStep := strings.Cut(part, "/")
+		step := 1
+		if haveStep {
+			var err error
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step < 1 {
+				return cronField{}, fmt.Errorf("bad step: %s", part)
+			}
+		}
+		first, last := min, max
+		if rangeText != "*" {
+			firstText, lastText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if first, err = strconv.Atoi(firstText); err != nil {
+				return cronField{}, fmt.Errorf("bad value: %s", part)
+			}
+			last = first
+			if isRange {
+				if last, err = strconv.Atoi(lastText); err != nil {
+					return cronField{}, fmt.Errorf("bad value: %s", part)
+				}
+			} else if haveStep {
+				last = max
+			}
+		}
+		if first < min || last > max || first > last {
+			return cronField{}, fmt.Errorf("value out of range: %s", part)
+		}
+		for value := first; value <= last; value += step {
+			field.values[value] = struct{}{}
+		}
+	}
+	for value := range field.values {
+		field.sorted = append(field.sorted, value)
+	}
+	sort.Ints(field.sorted)
+	return field, nil
+}
+
+// parseMaintenanceWindow parses a window of the form:
+// [TZ=zone] minute hour day-of-month month day-of-week duration
+func parseMaintenanceWindow(text string) (*maintenanceWindow, error) {
+	fields := strings.Fields(text)
+	window := &maintenanceWindow{location: time.UTC, text: text}
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "TZ=") {
+		location, err := time.LoadLocation(fields[0][3:])
+		if err != nil {
+			return nil, err
+		}
+		window.location = location
+		fields = fields[1:]
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("bad maintenance window: \"%s\"", text)
+	}
+	var err error
+	if window.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if window.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	window.daysOfMonth, err = parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, err
+	}
+	if window.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if window.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if _, ok := window.daysOfWeek.values[7]; ok { // Sunday may be 0 or 7.
+		window.daysOfWeek.values[0] = struct{}{}
+	}
+	if window.duration, err = time.ParseDuration(fields[5]); err != nil {
+		return nil, err
+	}
+	if window.duration < time.Minute || window.duration > maxWindowDuration {
+		return nil, fmt.Errorf("window duration: %s not in range 1m-%s",
+			window.duration, maxWindowDuration)
+	}
+	return window, nil
+}
+
+// parseMaintenanceWindows parses windows separated by semicolons.
+func parseMaintenanceWindows(text string) (maintenanceWindows, error) {
+	var windows maintenanceWindows
+	for _, windowText := range strings.Split(text, ";") {
+		if strings.TrimSpace(windowText) == "" {
+			continue
+		}
+		window, err := parseMaintenanceWindow(windowText)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+func (field cronField) match(value int) bool {
+	_, ok := field.values[value]
+	return ok
+}
+
+// matchDay returns true if the window may open on the specified day.
+func (window *maintenanceWindow) matchDay(year int, month time.Month,
+	day int) bool {
+	if !window.months.match(int(month)) {
+		return false
+	}
+	weekday := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday()
+	// As with cron, if both day fields are restricted either may match.
+	dayOfMonth := window.daysOfMonth.match(day)
+	dayOfWeek := window.daysOfWeek.match(int(weekday))
+	if !window.daysOfMonth.all && !window.daysOfWeek.all {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// getDay returns the date offset by the specified number of days from the
+// date of t in the time zone of the window.
+func (window *maintenanceWindow) getDay(t time.Time, offset int) (
+	int, time.Month, int) {
+	t = t.In(window.location)
+	// Use midday so that daylight saving transitions do not change the date.
+	return time.Date(t.Year(), t.Month(), t.Day()+offset, 12, 0, 0, 0,
+		window.location).Date()
+}
+
+// makeOpening returns the time for the specified local time and true, or false
+// if the local time does not exist (it is skipped by a daylight saving
+// transition).
+func (window *maintenanceWindow) makeOpening(year int, month time.Month,
+	day, hour, minute int) (time.Time, bool) {
+	t := time.Date(year, month, day, hour, minute, 0, 0, window.location)
+	return t, t.Day() == day && t.Hour() == hour && t.Minute() == minute
+}
+
+// lastOpening returns the latest time at or before the specified time that the
+// window opened, searching back for the duration of the window.
+func (window *maintenanceWindow) lastOpening(t time.Time) (time.Time, bool) {
+	numDays := int(window.duration/(24*time.Hour)) + 1
+	for offset := 0; offset >= -numDays; offset-- {
+		year, month, day := window.getDay(t, offset)
+		if !window.matchDay(year, month, day) {
+			continue
+		}
+		hours := window.hours.sorted
+		minutes := window.minutes.sorted
+		for hourIndex := len(hours) - 1; hourIndex >= 0; hourIndex-- {
+			for minIndex := len(minutes) - 1; minIndex >= 0; minIndex-- {
+				opening, ok := window.makeOpening(year, month, day,
+					hours[hourIndex], minutes[minIndex])
+				if ok && !opening.After(t) {
+					return opening, true
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// openAt returns true if the window is open at the specified time.
+func (window *maintenanceWindow) openAt(t time.Time) bool {
+	opening, ok := window.lastOpening(t)
+	return ok && t.Sub(opening) < window.duration
+}
+
+// nextOpening returns the next time the window opens after the specified
+// time, or the zero time if it does not open within the search limit.
+func (window *maintenanceWindow) nextOpening(t time.Time) time.Time {
+	after := t.Truncate(time.Minute)
+	limit := after.Add(time.Minute + maxWindowDuration + 24*time.Hour)
+	numDays := int((maxWindowDuration+24*time.Hour)/(24*time.Hour)) + 1
+	for offset := 0; offset <= numDays; offset++ {
+		year, month, day := window.getDay(t, offset)
+		if !window.matchDay(year, month, day) {
+			continue
+		}
+		for _, hour := range window.hours.sorted {
+			for _, minute := range window.minutes.sorted {
+				opening, ok := window.makeOpening(year, month, day, hour,
+					minute)
+				if !ok || !opening.After(after) {
+					continue
+				}
+				if !opening.Before(limit) {
+					return time.Time{}
+				}
+				return opening
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// openAt returns true if there are no windows (no restriction) or any window
+// is open at the specified time.
+func (windows maintenanceWindows) openAt(t time.Time) bool {
+	if len(windows) < 1 {
+		return true
+	}
+	for _, window := range windows {
+		if window.openAt(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOpening returns the earliest time any window opens after the specified
+// time, or the zero time if none opens within the search limit.
+func (windows maintenanceWindows) nextOpening(t time.Time) time.Time {
+	var earliest time.Time
+	for _, window := range windows {
+		next := window.nextOpening(t)
+		if next.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
+)
+
+func disruptionAddBlackoutSubcommand(args []string,
+	logger log.DebugLogger) error {
+	err := disruptionAddBlackout(args[0], args[1], args[2], args[3:])
+	if err != nil {
+		return fmt.Errorf("error adding blackout: %s", err)
+	}
+	return nil
+}
+
+func disruptionListBlackoutsSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := disruptionListBlackouts(); err != nil {
+		return fmt.Errorf("error listing blackouts: %s", err)
+	}
+	return nil
+}
+
+func disruptionRemoveBlackoutSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := disruptionRemoveBlackout(args[0]); err != nil {
+		return fmt.Errorf("error removing blackout: %s", err)
+	}
+	return nil
+}
+
+func dialDisruptionManager() (*srpc.Client, error) {
+	parsedUrl, err := url.Parse(*disruptionManagerUrl)
+	if err != nil {
+		return nil, err
+	}
+	if parsedUrl.Scheme != "srpc" {
+		return nil, fmt.Errorf("unsupported scheme: %s (srpc required)",
+			*disruptionManagerUrl)
+	}
+	client, err := srpc.DialHTTP("tcp", parsedUrl.Host, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing: %s", err)
+	}
+	return client, nil
+}
+
+func disruptionAddBlackout(name, durationString, reason string,
+	groups []string) error {
+	duration, err := time.ParseDuration(durationString)
+	if err != nil {
+		return err
+	}
+	client, err := dialDisruptionManager()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	now := time.Now()
+	request := dm_proto.AddBlackoutRequest{
+		Blackout: dm_proto.Blackout{
+			Groups: groups,
+			Name:   name,
+			Reason: reason,
+			Start:  now,
+			End:    now.Add(duration),
+		},
+	}
+	var reply dm_proto.AddBlackoutResponse
+	err = client.RequestReply("DisruptionManager.AddBlackout", request, &reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
+func disruptionListBlackouts() error {
+	client, err := dialDisruptionManager()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var reply dm_proto.ListBlackoutsResponse
+	err = client.RequestReply("DisruptionManager.ListBlackouts",
+		dm_proto.ListBlackoutsRequest{}, &reply)
+	if err != nil {
+		return err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return err
+	}
+	return json.WriteWithIndent(os.Stdout, "    ", reply.Blackouts)
+}
+
+func disruptionRemoveBlackout(name string) error {
+	client, err := dialDisruptionManager()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var reply dm_proto.RemoveBlackoutResponse
+	err = client.RequestReply("DisruptionManager.RemoveBlackout",
+		dm_proto.RemoveBlackoutRequest{Name: strings.TrimSpace(name)}, &reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
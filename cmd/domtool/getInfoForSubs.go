@@ -29,6 +29,7 @@ func getInfoForSubs(client *srpc.Client) error {
 	}
 	request := dominator.GetInfoForSubsRequest{
 		Hostnames:        hostnames,
+		ImagesToMatch:    imagesToMatch,
 		LocationsToMatch: locationsToMatch,
 		StatusesToMatch:  statusesToMatch,
 		TagsToMatch:      tagsToMatch,
@@ -23,6 +23,7 @@ func listSubs(client *srpc.Client) error {
 	}
 	request := dominator.ListSubsRequest{
 		Hostnames:        hostnames,
+		ImagesToMatch:    imagesToMatch,
 		LocationsToMatch: locationsToMatch,
 		StatusesToMatch:  statusesToMatch,
 		TagsToMatch:      tagsToMatch,
@@ -25,6 +25,10 @@ var (
 		"Hostname of dominator")
 	domPortNum = flag.Uint("domPortNum", constants.DominatorPortNumber,
 		"Port number of dominator")
+	eventTypes    flagutil.StringList
+	hostnameRegex = flag.String("hostnameRegex", "",
+		"Regular expression to match sub hostnames when previewing")
+	imagesToMatch     flagutil.StringList
 	locationsToMatch  flagutil.StringList
 	mdbServerHostname = flag.String("mdbServerHostname", "",
 		"Hostname of MDB server (default same as domHostname)")
@@ -51,6 +55,10 @@ var (
 )
 
 func init() {
+	flag.Var(&eventTypes, "eventTypes",
+		"Sub event types to match when watching (default all)")
+	flag.Var(&imagesToMatch, "imagesToMatch",
+		"Images (running or required) to match when listing")
 	flag.Var(&locationsToMatch, "locationsToMatch",
 		"Sub locations to match when listing")
 	flag.Var(&scanExcludeList, "scanExcludeList",
@@ -73,8 +81,14 @@ var subcommands = []commands.Command{
 	{"clear-safety-shutoff", "sub", 1, 1, clearSafetyShutoffSubcommand},
 	{"configure-subs", "", 0, 0, configureSubsSubcommand},
 	{"disable-updates", "reason", 1, 1, disableUpdatesSubcommand},
+	{"disruption-add-blackout", "name duration reason [group...]", 3, -1,
+		disruptionAddBlackoutSubcommand},
 	{"disruption-cancel", "sub", 1, 1, disruptionCancelSubcommand},
 	{"disruption-check", "sub", 1, 1, disruptionCheckSubcommand},
+	{"disruption-list-blackouts", "", 0, 0,
+		disruptionListBlackoutsSubcommand},
+	{"disruption-remove-blackout", "name", 1, 1,
+		disruptionRemoveBlackoutSubcommand},
 	{"disruption-request", "sub", 1, 1, disruptionRequestSubcommand},
 	{"enable-updates", "reason", 1, 1, enableUpdatesSubcommand},
 	{"fast-update", "sub", 1, 1, fastUpdateSubcommand},
@@ -87,8 +101,10 @@ var subcommands = []commands.Command{
 	{"get-subs-configuration", "", 0, 0, getSubsConfigurationSubcommand},
 	{"list-subs", "", 0, 0, listSubsSubcommand},
 	{"pause-sub-updates", "sub reason", 2, 2, pauseSubUpdatesSubcommand},
+	{"preview-image-change", "image", 1, 1, previewImageChangeSubcommand},
 	{"resume-sub-updates", "sub", 1, 1, resumeSubUpdatesSubcommand},
 	{"set-default-image", "", 1, 1, setDefaultImageSubcommand},
+	{"watch", "[sub...]", 0, -1, watchSubcommand},
 }
 
 func getClient() *srpc.Client {
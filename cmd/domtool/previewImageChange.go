@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	domclient "github.com/Cloud-Foundations/Dominator/dom/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func previewImageChangeSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := previewImageChange(getClient(), args[0]); err != nil {
+		return fmt.Errorf("error previewing image change: %s", err)
+	}
+	return nil
+}
+
+func countChanges(preview dominator.SubImageChangePreview) uint {
+	return preview.DirectoriesToMake + preview.HardlinksToMake +
+		preview.InodesToChange + preview.InodesToMake + preview.PathsToDelete
+}
+
+func previewImageChange(client *srpc.Client, imageName string) error {
+	hostnames, err := getSubsFromFile()
+	if err != nil {
+		return err
+	}
+	request := dominator.PreviewImageChangeRequest{
+		HostnameRegex:    *hostnameRegex,
+		Hostnames:        hostnames,
+		ImageName:        imageName,
+		LocationsToMatch: locationsToMatch,
+		TagsToMatch:      tagsToMatch,
+	}
+	previews, err := domclient.PreviewImageChange(client, request)
+	if err != nil {
+		return err
+	}
+	var bytesToFetch uint64
+	var numChanged, numErrors, numHighImpact, numReboot, numUnsafe uint
+	services := make(map[string]uint)
+	for _, preview := range previews {
+		if preview.Error != "" {
+			fmt.Printf("%s: error: %s\n", preview.Hostname, preview.Error)
+			numErrors++
+			continue
+		}
+		numChanges := countChanges(preview)
+		if numChanges < 1 && preview.BytesToFetch < 1 {
+			continue
+		}
+		numChanged++
+		bytesToFetch += preview.BytesToFetch
+		notes := []string{
+			fmt.Sprintf("%d changes", numChanges),
+			"fetch " + format.FormatBytes(preview.BytesToFetch),
+		}
+		if len(preview.Triggers) > 0 {
+			notes = append(notes,
+				"restart: "+strings.Join(preview.Triggers, ","))
+		}
+		for _, service := range preview.Triggers {
+			services[service]++
+		}
+		if len(preview.HighImpactTriggers) > 0 {
+			notes = append(notes, "HIGH IMPACT")
+			numHighImpact++
+		}
+		if len(preview.RebootTriggers) > 0 {
+			notes = append(notes, "REBOOT")
+			numReboot++
+		}
+		if preview.Unsafe {
+			notes = append(notes, "UNSAFE")
+			numUnsafe++
+		}
+		if preview.MissingComputedFiles {
+			notes = append(notes, "missing computed files")
+		}
+		fmt.Printf("%s: %s\n", preview.Hostname, strings.Join(notes, ", "))
+	}
+	fmt.Printf("Summary: %d subs selected, %d would change, %d errors\n",
+		len(previews), numChanged, numErrors)
+	fmt.Printf("Total to fetch: %s\n", format.FormatBytes(bytesToFetch))
+	fmt.Printf("High impact: %d, reboot: %d, unsafe: %d\n",
+		numHighImpact, numReboot, numUnsafe)
+	serviceNames := make([]string, 0, len(services))
+	for service := range services {
+		serviceNames = append(serviceNames, service)
+	}
+	sort.Strings(serviceNames)
+	for _, service := range serviceNames {
+		fmt.Printf("Service: %s would restart on %d subs\n",
+			service, services[service])
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	domclient "github.com/Cloud-Foundations/Dominator/dom/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func watchSubcommand(args []string, logger log.DebugLogger) error {
+	if err := watch(getClient(), args); err != nil {
+		return fmt.Errorf("error watching sub events: %s", err)
+	}
+	return nil
+}
+
+func formatEvent(event dominator.SubEvent) string {
+	var details []string
+	switch event.Type {
+	case dominator.SubEventImageChange:
+		details = append(details,
+			fmt.Sprintf("%s -> %s", event.OldImage, event.Image))
+	case dominator.SubEventStatusChange:
+		details = append(details,
+			fmt.Sprintf("%s -> %s", event.OldStatus, event.Status))
+	default:
+		if event.Image != "" {
+			details = append(details, event.Image)
+		}
+		if event.Duration > 0 {
+			details = append(details, format.Duration(event.Duration))
+		}
+	}
+	if event.Error != "" {
+		details = append(details, "error: "+event.Error)
+	}
+	return fmt.Sprintf("%s %s %s: %s",
+		event.Time.Local().Format(format.TimeFormatSeconds), event.Hostname,
+		event.Type, strings.Join(details, ", "))
+}
+
+func watch(client *srpc.Client, hostnames []string) error {
+	if len(hostnames) < 1 {
+		var err error
+		if hostnames, err = getSubsFromFile(); err != nil {
+			return err
+		}
+	}
+	request := dominator.WatchSubEventsRequest{
+		Hostnames: hostnames,
+		Types:     eventTypes,
+	}
+	eventChannel := make(chan dominator.SubEvent, 1)
+	errorChannel := make(chan error, 1)
+	go func() {
+		errorChannel <- domclient.WatchSubEvents(client, request, eventChannel)
+	}()
+	for {
+		select {
+		case event := <-eventChannel:
+			fmt.Println(formatEvent(event))
+		case err := <-errorChannel:
+			return err
+		}
+	}
+}
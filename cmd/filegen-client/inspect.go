@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	liberrors "github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/filegenerator"
+)
+
+func inspectMode() bool {
+	return *diffVersions != "" || *history || *previewTemplate != "" ||
+		*showVersion != ""
+}
+
+// inspect performs one of the one-shot inspection operations for each
+// machine.
+func inspect(pathname, source string, machines []mdb.Machine) error {
+	if len(machines) < 1 {
+		return errors.New("no machines selected: specify -hostnames")
+	}
+	client, err := srpc.DialHTTP("tcp", source, 0)
+	if err != nil {
+		return fmt.Errorf("error dialing: %s: %s", source, err)
+	}
+	defer client.Close()
+	for _, machine := range machines {
+		if len(machines) > 1 {
+			fmt.Printf("For machine: %s:\n", machine.Hostname)
+		}
+		var err error
+		switch {
+		case *diffVersions != "":
+			err = diffVersionsForMachine(client, pathname, machine.Hostname)
+		case *history:
+			err = showHistoryForMachine(client, pathname, machine.Hostname)
+		case *previewTemplate != "":
+			err = previewTemplateForMachine(client, pathname, machine)
+		case *showVersion != "":
+			err = showVersionForMachine(client, pathname, machine.Hostname)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", machine.Hostname, err)
+		}
+	}
+	return nil
+}
+
+func diffVersionsForMachine(client *srpc.Client, pathname,
+	hostname string) error {
+	left, right, _ := strings.Cut(*diffVersions, ",")
+	request := proto.DiffFileVersionsRequest{
+		Hostname: hostname,
+		Pathname: pathname,
+	}
+	var err error
+	if request.LeftHash, err = parseVersion(left); err != nil {
+		return err
+	}
+	if request.RightHash, err = parseVersion(right); err != nil {
+		return err
+	}
+	var reply proto.DiffFileVersionsResponse
+	err = client.RequestReply("FileGenerator.DiffFileVersions", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	if err := liberrors.New(reply.Error); err != nil {
+		return err
+	}
+	fmt.Print(reply.Diff)
+	return nil
+}
+
+// parseVersion converts a hash in hexadecimal to a hash. The empty string and
+// "current" refer to the current version and yield the zero hash.
+func parseVersion(version string) (hash.Hash, error) {
+	if version == "" || version == "current" {
+		return hash.Hash{}, nil
+	}
+	return objectcache.FilenameToHash(version)
+}
+
+func previewTemplateForMachine(client *srpc.Client, pathname string,
+	machine mdb.Machine) error {
+	templateData, err := os.ReadFile(*previewTemplate)
+	if err != nil {
+		return err
+	}
+	request := proto.PreviewTemplateRequest{
+		Hostname: machine.Hostname,
+		Machine:  &machine,
+		Pathname: pathname,
+		Template: templateData,
+	}
+	var reply proto.PreviewTemplateResponse
+	err = client.RequestReply("FileGenerator.PreviewTemplate", request, &reply)
+	if err != nil {
+		return err
+	}
+	if err := liberrors.New(reply.Error); err != nil {
+		return err
+	}
+	if reply.Diff == "" {
+		os.Stdout.Write(reply.Data)
+	} else {
+		fmt.Print(reply.Diff)
+	}
+	return nil
+}
+
+func showHistoryForMachine(client *srpc.Client, pathname,
+	hostname string) error {
+	request := proto.GetFileHistoryRequest{
+		Hostname: hostname,
+		Pathname: pathname,
+	}
+	var reply proto.GetFileHistoryResponse
+	err := client.RequestReply("FileGenerator.GetFileHistory", request, &reply)
+	if err != nil {
+		return err
+	}
+	if err := liberrors.New(reply.Error); err != nil {
+		return err
+	}
+	for _, entry := range reply.Entries {
+		fmt.Printf("%s %x %8d %s\n",
+			entry.Time.Format("2006-01-02 15:04:05 MST"), entry.Hash,
+			entry.Length, entry.Source)
+	}
+	return nil
+}
+
+func showVersionForMachine(client *srpc.Client, pathname,
+	hostname string) error {
+	hashVal, err := parseVersion(*showVersion)
+	if err != nil {
+		return err
+	}
+	request := proto.GetFileVersionRequest{
+		Hash:     hashVal,
+		Hostname: hostname,
+		Pathname: pathname,
+	}
+	var reply proto.GetFileVersionResponse
+	err = client.RequestReply("FileGenerator.GetFileVersion", request, &reply)
+	if err != nil {
+		return err
+	}
+	if err := liberrors.New(reply.Error); err != nil {
+		return err
+	}
+	os.Stdout.Write(reply.Data)
+	return nil
+}
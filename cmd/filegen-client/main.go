@@ -25,9 +25,17 @@ var (
 		"If true, perform benchmark timing")
 	debug = flag.Bool("debug", false,
 		"If true, show debugging output")
+	diffVersions = flag.String("diffVersions", "",
+		"Show diff between left,right versions (hashes, empty means current)")
+	history = flag.Bool("history", false,
+		"If true, show history of generated data")
 	hostnames flagutil.StringList
 	mdbFile   = flag.String("mdbFile", "/var/lib/mdbd/mdb.json",
 		"File to read MDB data from (default format is JSON)")
+	previewTemplate = flag.String("previewTemplate", "",
+		"Template file to preview (shows diff from current data)")
+	showVersion = flag.String("showVersion", "",
+		"Show version of generated data (hash or \"current\")")
 
 	numMachines int
 )
@@ -125,6 +133,18 @@ func main() {
 	hostnameSet := stringutil.ConvertListToMap(hostnames, false)
 	manager := client.New(objectServer, logger)
 	mdbChannel := mdbd.StartMdbDaemon(*mdbFile, logger)
+	if inspectMode() {
+		mdb := filterMdb(<-mdbChannel, hostnameSet)
+		if len(hostnameSet) < 1 {
+			mdb.Machines = nil
+		}
+		err := inspect(flag.Arg(0), flag.Arg(1), mdb.Machines)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	machines := make(map[string]struct{})
 	computedFiles := make([]client.ComputedFile, 1)
 	computedFiles[0].Pathname = flag.Arg(0)
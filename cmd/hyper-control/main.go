@@ -52,6 +52,12 @@ var (
 		"Name of default image stream for building bootable installer ISO")
 	installerPortNum = flag.Uint("installerPortNum",
 		constants.InstallerPortNumber, "Port number of installer")
+	ipxeInitrd = flag.String("ipxeInitrd", "initrd.img",
+		"Name of initrd file to load when netbooting with iPXE")
+	ipxeKernel = flag.String("ipxeKernel", "vmlinuz",
+		"Name of kernel file to load when netbooting with iPXE")
+	ipxeKernelOptions = flag.String("ipxeKernelOptions", "",
+		"Kernel command-line options when netbooting with iPXE")
 	location = flag.String("location", "",
 		"Location to search for hypervisors")
 	lockTimeout = flag.Duration("lockTimeout", 15*time.Second,
@@ -65,6 +71,7 @@ var (
 	netbootFilesTimeout = flag.Duration("netbootFilesTimeout",
 		time.Minute+time.Second,
 		"How long to provide files via TFTP after last DHCP ACK")
+	netbootMethod  proto.NetbootMethod
 	netbootTimeout = flag.Duration("netbootTimeout", time.Minute,
 		"Time to wait for DHCP ACKs to be sent")
 	networkInterfacesFile = flag.String("networkInterfacesFile", "",
@@ -98,6 +105,8 @@ func init() {
 	flag.Var(&hypervisorTags, "hypervisorTags", "Tags to apply to Hypervisor")
 	flag.Var(&memory, "memory", "memory for VM")
 	flag.Var(&netbootFiles, "netbootFiles", "Extra files served by TFTP server")
+	flag.Var(&netbootMethod, "netbootMethod",
+		"Network boot method: tftp, http (UEFI HTTP Boot) or ipxe")
 	flag.Var(&subnetIDs, "subnetIDs", "Subnet IDs for VM")
 	flag.Var(&volumeSizes, "volumeSizes", "Sizes for volumes for VM")
 }
@@ -164,6 +164,10 @@ func makeConfigFiles(info fm_proto.GetMachineInfoResponse, imageName string,
 	} else if imageName != "" {
 		filesMap["imagename"] = []byte(imageName + "\n")
 	}
+	if *fleetManagerHostname != "" {
+		filesMap["fleetmanager"] = []byte(fmt.Sprintf("%s:%d\n",
+			*fleetManagerHostname, *fleetManagerPortNum))
+	}
 	buffer := new(bytes.Buffer)
 	fmt.Fprintf(buffer, "%s:%d\n", *imageServerHostname, *imageServerPortNum)
 	filesMap["imageserver"] = buffer.Bytes()
@@ -268,6 +272,10 @@ func netbootHost(hostname string, logger log.DebugLogger) error {
 		Files:                        configFiles,
 		FilesExpiration:              *netbootFilesTimeout,
 		Hostname:                     hostname,
+		IpxeInitrd:                   *ipxeInitrd,
+		IpxeKernel:                   *ipxeKernel,
+		IpxeKernelOptions:            *ipxeKernelOptions,
+		Method:                       netbootMethod,
 		NumAcknowledgementsToWaitFor: *numAcknowledgementsToWaitFor,
 		OfferExpiration:              *offerTimeout,
 		WaitTimeout:                  *netbootTimeout,
@@ -34,6 +34,10 @@ func netbootMachine(macAddr, ipAddr, hostname string,
 			len(netbootFiles)),
 		FilesExpiration:              *netbootFilesTimeout,
 		Hostname:                     hostname,
+		IpxeInitrd:                   *ipxeInitrd,
+		IpxeKernel:                   *ipxeKernel,
+		IpxeKernelOptions:            *ipxeKernelOptions,
+		Method:                       netbootMethod,
 		NumAcknowledgementsToWaitFor: *numAcknowledgementsToWaitFor,
 		OfferExpiration:              *offerTimeout,
 		WaitTimeout:                  *netbootTimeout,
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,11 +20,13 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/flags/commands"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
 	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/net"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
+	installer_proto "github.com/Cloud-Foundations/Dominator/proto/installer"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
 
@@ -44,6 +47,8 @@ var (
 	imageServerPortNum = flag.Uint("imageServerPortNum",
 		constants.ImageServerPortNumber,
 		"Port number of image server")
+	ipxeImage = flag.String("ipxeImage", "ipxe.%d",
+		"Name of iPXE image to chain-load when netbooting with iPXE")
 	lockCheckInterval = flag.Duration("lockCheckInterval", 2*time.Second,
 		"Interval between checks for lock timeouts")
 	lockLogTimeout = flag.Duration("lockLogTimeout", 5*time.Second,
@@ -58,6 +63,9 @@ var (
 	showVGA  = flag.Bool("showVGA", false, "If true, show VGA console")
 	stateDir = flag.String("stateDir", "/var/lib/hypervisor",
 		"Name of state directory")
+	storageLayoutFile = flag.String("storageLayoutFile",
+		"/var/log/installer/storage-layout.json",
+		"Storage layout used by the installer, used to find volume directories")
 	testMemoryAvailable = flag.Uint64("testMemoryAvailable", 0,
 		"test if memory is allocatable and exit (units of MiB)")
 	tftpbootImageStream = flag.String("tftpbootImageStream", "",
@@ -110,6 +118,19 @@ func main() {
 	processCommand(flag.Args())
 }
 
+// loadStorageLayout returns the storage layout, or nil if it does not exist.
+func loadStorageLayout(filename string) (
+	*installer_proto.StorageLayout, error) {
+	var layout installer_proto.StorageLayout
+	if err := json.ReadFromFile(filename, &layout); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &layout, nil
+}
+
 func run() {
 	if *testMemoryAvailable > 0 {
 		nBytes := *testMemoryAvailable << 20
@@ -178,13 +199,24 @@ func run() {
 	if err := dhcpServer.SetNetworkBootImage(*networkBootImage); err != nil {
 		logger.Fatalf("Cannot set NetworkBootImage name: %s\n", err)
 	}
+	if err := dhcpServer.SetIpxeImage(*ipxeImage); err != nil {
+		logger.Fatalf("Cannot set iPXE image name: %s\n", err)
+	}
+	dhcpServer.SetHttpBootPort(*portNum)
 	imageServerAddress := fmt.Sprintf("%s:%d",
 		*imageServerHostname, *imageServerPortNum)
 	tftpbootServer, err := tftpbootd.New(imageServerAddress,
-		*tftpbootImageStream, logger)
+		*tftpbootImageStream, http.DefaultServeMux, logger)
 	if err != nil {
 		logger.Fatalf("Cannot start tftpboot server: %s\n", err)
 	}
+	var storageLayout *installer_proto.StorageLayout
+	if len(volumeDirectories) < 1 && *storageLayoutFile != "" {
+		storageLayout, err = loadStorageLayout(*storageLayoutFile)
+		if err != nil {
+			logger.Fatalf("Cannot load storage layout: %s\n", err)
+		}
+	}
 	managerObj, err := manager.New(manager.StartOptions{
 		BridgeMap:            bridgeMap,
 		DhcpServer:           dhcpServer,
@@ -198,6 +230,7 @@ func run() {
 		ObjectCacheBytes:     uint64(objectCacheSize),
 		ShowVgaConsole:       *showVGA,
 		StateDir:             *stateDir,
+		StorageLayout:        storageLayout,
 		Username:             *username,
 		VlanIdToBridge:       vlanIdToBridge,
 		VolumeDirectories:    volumeDirectories,
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func deleteSubcommand(args []string, logger log.DebugLogger) error {
+	if err := deleteArtefacts(args, logger); err != nil {
+		return fmt.Errorf("error deleting images: %s", err)
+	}
+	return nil
+}
+
+func deleteArtefacts(names []string, logger log.DebugLogger) error {
+	targets, err := makeTargets()
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		for _, name := range names {
+			if err := target.Delete(name); err != nil {
+				return fmt.Errorf("%s: %s: %s", target, name, err)
+			}
+			logger.Printf("deleted: %s: %s\n", target, name)
+		}
+	}
+	return nil
+}
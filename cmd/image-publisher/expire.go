@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func expireSubcommand(args []string, logger log.DebugLogger) error {
+	if err := expire(logger); err != nil {
+		return fmt.Errorf("error expiring images: %s", err)
+	}
+	return nil
+}
+
+func expire(logger log.DebugLogger) error {
+	targets, err := makeTargets()
+	if err != nil {
+		return err
+	}
+	return publisher.Expire(targets, logger)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func listSubcommand(args []string, logger log.DebugLogger) error {
+	if err := list(); err != nil {
+		return fmt.Errorf("error listing images: %s", err)
+	}
+	return nil
+}
+
+func list() error {
+	targets, err := makeTargets()
+	if err != nil {
+		return err
+	}
+	results, err := publisher.List(targets)
+	if err != nil {
+		return err
+	}
+	return libjson.WriteWithIndent(os.Stdout, "    ", results)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/constants"
+	"github.com/Cloud-Foundations/Dominator/lib/flags/commands"
+	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
+	"github.com/Cloud-Foundations/Dominator/lib/log/cmdlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/mbr"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupclient"
+	libtags "github.com/Cloud-Foundations/Dominator/lib/tags"
+)
+
+var (
+	compress = flag.Bool("compress", false,
+		"If true, compress QCOW2 images")
+	diskImageFormat = diskimage.FormatQCOW2
+	expiresIn       = flag.Duration("expiresIn", 0,
+		"Expiration time for published images (default never)")
+	imageServerHostname = flag.String("imageServerHostname", "localhost",
+		"Hostname of imageserver")
+	imageServerPortNum = flag.Uint("imageServerPortNum",
+		constants.ImageServerPortNumber, "Port number of imageserver")
+	localDirectory = flag.String("localDirectory", "",
+		"Local directory to publish to")
+	minFreeBytes = flag.Uint64("minFreeBytes", 4<<20,
+		"minimum number of free bytes in image")
+	rootLabel = flag.String("rootLabel", "",
+		"Label to write for root file-system")
+	roundupPower = flag.Uint64("roundupPower", 24,
+		"power of 2 to round up raw image size")
+	s3Bucket = flag.String("s3Bucket", "",
+		"S3 bucket to publish to")
+	s3Endpoint = flag.String("s3Endpoint", "",
+		"Endpoint URL for S3-compatible object store (default AWS)")
+	s3Prefix = flag.String("s3Prefix", "",
+		"Prefix (folder) for objects in S3 bucket")
+	s3Profile = flag.String("s3Profile", "",
+		"AWS profile to use for S3 credentials")
+	s3Region = flag.String("s3Region", "",
+		"Region of S3 bucket (default us-east-1)")
+	tableType mbr.TableType = mbr.TABLE_TYPE_MSDOS
+	tags                    = make(libtags.Tags)
+	tmpDir                  = flag.String("tmpDir", "",
+		"Directory to write temporary images in (default system temporary directory)")
+)
+
+func init() {
+	flag.Var(&diskImageFormat, "diskImageFormat",
+		"format of published disk images")
+	flag.Var(&tableType, "tableType", "partition table type")
+	flag.Var(&tags, "tags", "Tags to apply")
+}
+
+func printUsage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w,
+		"Usage: image-publisher [flags...] publish [args...]")
+	fmt.Fprintln(w, "Common flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(w, "Commands:")
+	commands.PrintCommands(w, subcommands)
+}
+
+var subcommands = []commands.Command{
+	{"delete", "name...", 1, -1, deleteSubcommand},
+	{"expire", "", 0, 0, expireSubcommand},
+	{"list", "", 0, 0, listSubcommand},
+	{"publish", "image-name", 1, 1, publishSubcommand},
+}
+
+func doMain() int {
+	if err := loadflags.LoadForCli("image-publisher"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	cmdlogger.SetDatestampsDefault(true)
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		return 2
+	}
+	logger := cmdlogger.New()
+	srpc.SetDefaultLogger(logger)
+	if err := setupclient.SetupTls(true); err != nil {
+		logger.Println(err)
+		return 1
+	}
+	return commands.RunCommands(subcommands, printUsage, logger)
+}
+
+func main() {
+	os.Exit(doMain())
+}
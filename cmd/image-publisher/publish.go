@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func publishSubcommand(args []string, logger log.DebugLogger) error {
+	imageServerAddr := fmt.Sprintf("%s:%d",
+		*imageServerHostname, *imageServerPortNum)
+	if err := publish(imageServerAddr, args[0], logger); err != nil {
+		return fmt.Errorf("error publishing image: %s", err)
+	}
+	return nil
+}
+
+func publish(imageServerAddress string, imageName string,
+	logger log.DebugLogger) error {
+	if os.Geteuid() != 0 {
+		return errors.New("must run as root to make bootable images")
+	}
+	targets, err := makeTargets()
+	if err != nil {
+		return err
+	}
+	results, err := publisher.Publish(imageServerAddress, imageName, targets,
+		publisher.PublishOptions{
+			Compress:         *compress,
+			ExpiresIn:        *expiresIn,
+			Format:           diskImageFormat,
+			MinimumFreeBytes: *minFreeBytes,
+			RootLabel:        *rootLabel,
+			RoundupPower:     *roundupPower,
+			TableType:        tableType,
+			Tags:             tags,
+			TmpDir:           *tmpDir,
+		},
+		logger)
+	if err != nil {
+		return err
+	}
+	if err := libjson.WriteWithIndent(os.Stdout, "    ", results); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Error != "" {
+			return errors.New(result.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher/local"
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher/s3"
+)
+
+func makeTargets() ([]publisher.Target, error) {
+	var targets []publisher.Target
+	if *localDirectory != "" {
+		if target, err := local.New(*localDirectory); err != nil {
+			return nil, err
+		} else {
+			targets = append(targets, target)
+		}
+	}
+	if *s3Bucket != "" {
+		target, err := s3.New(s3.Params{
+			Bucket:   *s3Bucket,
+			Endpoint: *s3Endpoint,
+			Prefix:   *s3Prefix,
+			Profile:  *s3Profile,
+			Region:   *s3Region,
+		})
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) < 1 {
+		return nil, errors.New("no targets: specify -localDirectory or -s3Bucket")
+	}
+	return targets, nil
+}
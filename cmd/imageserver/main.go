@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/imageserver/scanner"
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
@@ -28,7 +31,12 @@ var (
 		"If true, allow all users to call CheckObjects method")
 	allowPublicGetObjects = flag.Bool("allowPublicGetObjects", false,
 		"If true, allow all users to call GetObjects method")
-	debug    = flag.Bool("debug", false, "If true, show debugging output")
+	debug = flag.Bool("debug", false,
+		"If true, show debugging output")
+	fleetManagerHostname = flag.String("fleetManagerHostname", "",
+		"Hostname of Fleet Manager (images used by VMs are retained)")
+	fleetManagerPortNum = flag.Uint("fleetManagerPortNum",
+		constants.FleetManagerPortNumber, "Port number of Fleet Manager")
 	imageDir = flag.String("imageDir", "/var/lib/imageserver",
 		"Name of image server data directory.")
 	imageServerHostname = flag.String("imageServerHostname", "",
@@ -46,14 +54,48 @@ var (
 	maximumExpirationDurationPrivileged = flag.Duration(
 		"maximumExpirationDurationPrivileged", 730*time.Hour,
 		"Maximum expiration time for privileged users")
+	mdbServerHostname = flag.String("mdbServerHostname", "",
+		"Hostname of MDB server (images used by machines are retained)")
+	mdbServerPortNum = flag.Uint("mdbServerPortNum",
+		constants.SimpleMdbServerPortNumber, "Port number of MDB server")
 	objectDir = flag.String("objectDir", "/var/lib/objectserver",
 		"Name of image server data directory.")
 	permitInsecureMode = flag.Bool("permitInsecureMode", false,
 		"If true, run in insecure mode. This gives remote access to all")
 	portNum = flag.Uint("portNum", constants.ImageServerPortNumber,
 		"Port number to allocate and listen on for HTTP/RPC")
+	retentionCheckInterval = flag.Duration("retentionCheckInterval", 0,
+		"Interval between applying directory retention policies (0: disabled)")
+
+	replicationMasters flagutil.StringList
 )
 
+func init() {
+	flag.Var(&replicationMasters, "replicationMasters",
+		"Comma separated list of image servers to receive updates from, in order of preference")
+}
+
+// getReplicationMasters returns the addresses of the replication masters, in
+// order of preference.
+func getReplicationMasters() ([]string, error) {
+	if *imageServerHostname != "" {
+		if len(replicationMasters) > 0 {
+			return nil, errors.New(
+				"cannot specify both imageServerHostname and replicationMasters")
+		}
+		return []string{fmt.Sprintf("%s:%d", *imageServerHostname,
+			*imageServerPortNum)}, nil
+	}
+	addresses := make([]string, 0, len(replicationMasters))
+	for _, master := range replicationMasters {
+		if _, _, err := net.SplitHostPort(master); err != nil {
+			master = fmt.Sprintf("%s:%d", master, *imageServerPortNum)
+		}
+		addresses = append(addresses, master)
+	}
+	return addresses, nil
+}
+
 func main() {
 	if os.Geteuid() == 0 {
 		fmt.Fprintln(os.Stderr, "Do not run the Image Server as root")
@@ -87,19 +129,34 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Cannot create ObjectServer: %s\n", err)
 	}
+	masters, err := getReplicationMasters()
+	if err != nil {
+		logger.Fatalln(err)
+	}
 	var imageServerAddress string
-	if *imageServerHostname != "" {
-		imageServerAddress = fmt.Sprintf("%s:%d", *imageServerHostname,
-			*imageServerPortNum)
+	if len(masters) > 0 {
+		imageServerAddress = masters[0]
+	}
+	var fleetManagerAddress, mdbServerAddress string
+	if *fleetManagerHostname != "" {
+		fleetManagerAddress = fmt.Sprintf("%s:%d", *fleetManagerHostname,
+			*fleetManagerPortNum)
+	}
+	if *mdbServerHostname != "" {
+		mdbServerAddress = fmt.Sprintf("%s:%d", *mdbServerHostname,
+			*mdbServerPortNum)
 	}
 	imdb, err := scanner.Load(
 		scanner.Config{
 			BaseDirectory:                       *imageDir,
+			FleetManagerAddress:                 fleetManagerAddress,
 			LockCheckInterval:                   *lockCheckInterval,
 			LockLogTimeout:                      *lockLogTimeout,
 			MaximumExpirationDuration:           *maximumExpirationDuration,
 			MaximumExpirationDurationPrivileged: *maximumExpirationDurationPrivileged,
+			MdbServerAddress:                    mdbServerAddress,
 			ReplicationMaster:                   imageServerAddress,
+			RetentionCheckInterval:              *retentionCheckInterval,
 		},
 		scanner.Params{
 			Logger:       logger,
@@ -111,8 +168,13 @@ func main() {
 	tricorder.RegisterMetric("/image-count",
 		func() uint { return imdb.CountImages() },
 		units.None, "number of images")
-	imgSrvRpcHtmlWriter, err := imageserverRpcd.Setup(imdb, imageServerAddress,
-		objSrv, logger)
+	imgSrvRpcHtmlWriter, err := imageserverRpcd.SetupWithConfig(
+		imageserverRpcd.Config{ReplicationMasters: masters},
+		imageserverRpcd.Params{
+			ImageDataBase: imdb,
+			Logger:        logger,
+			ObjectServer:  objSrv,
+		})
 	if err != nil {
 		logger.Fatalln(err)
 	}
@@ -124,8 +186,9 @@ func main() {
 			ReplicationMaster:       imageServerAddress,
 		},
 		objectserverRpcd.Params{
-			Logger:       logger,
-			ObjectServer: objSrv,
+			Logger:                  logger,
+			ObjectServer:            objSrv,
+			ReplicationMasterGetter: imdb.GetReplicationMaster,
 		})
 	httpd.AddHtmlWriter(imdb)
 	httpd.AddHtmlWriter(imgSrvRpcHtmlWriter)
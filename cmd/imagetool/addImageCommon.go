@@ -19,6 +19,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem/untar"
 	"github.com/Cloud-Foundations/Dominator/lib/filter"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/gpt"
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
@@ -29,6 +30,13 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
 )
 
+// rawPartitionTable is implemented by *gpt.Table and *mbr.Mbr.
+type rawPartitionTable interface {
+	GetNumPartitions() uint
+	GetPartitionOffset(index uint) uint64
+	GetPartitionSize(index uint) uint64
+}
+
 type hasher struct {
 	objQ *objectclient.ObjectAdderQueue
 }
@@ -122,6 +130,11 @@ func buildImageWithHasher(imageSClient *srpc.Client, filter *filter.Filter,
 		return nil, errors.New("error opening image file: " + err.Error())
 	}
 	defer imageFile.Close()
+	if gptTable, err := gpt.Decode(imageFile); err != nil {
+		return nil, err
+	} else if gptTable != nil {
+		return buildImageFromRaw(imageSClient, filter, imageFile, gptTable, h)
+	}
 	if partitionTable, err := mbr.Decode(imageFile); err != nil {
 		if err != io.EOF {
 			return nil, err
@@ -153,7 +166,7 @@ func buildImageWithHasher(imageSClient *srpc.Client, filter *filter.Filter,
 }
 
 func buildImageFromRaw(imageSClient *srpc.Client, filter *filter.Filter,
-	imageFile *os.File, partitionTable *mbr.Mbr,
+	imageFile *os.File, partitionTable rawPartitionTable,
 	h scanner.Hasher) (*filesystem.FileSystem, error) {
 	var index uint
 	var offsetOfLargest, sizeOfLargest uint64
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
+)
+
+func findImagesWithObjectSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := findImagesWithObject(args[0]); err != nil {
+		return fmt.Errorf("error finding images with object: %s", err)
+	}
+	return nil
+}
+
+func findImagesWithObject(hashName string) error {
+	hashVal, err := objectcache.FilenameToHash(hashName)
+	if err != nil {
+		return err
+	}
+	imageSClient, _ := getClients()
+	images, err := client.FindImagesWithObject(imageSClient, hashVal)
+	if err != nil {
+		return err
+	}
+	imageNames := make([]string, 0, len(images))
+	for _, img := range images {
+		imageNames = append(imageNames, img.ImageName)
+		for _, pathname := range img.Paths {
+			fmt.Printf("%s %s\n", img.ImageName, pathname)
+		}
+	}
+	return showSubsRunningImages(imageNames)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func findImagesWithPathSubcommand(args []string, logger log.DebugLogger) error {
+	if err := findImagesWithPath(args[0]); err != nil {
+		return fmt.Errorf("error finding images with path: %s", err)
+	}
+	return nil
+}
+
+func findImagesWithPath(pathname string) error {
+	imageSClient, _ := getClients()
+	imageNames, err := client.FindImagesWithPath(imageSClient, pathname)
+	if err != nil {
+		return err
+	}
+	for _, imageName := range imageNames {
+		fmt.Println(imageName)
+	}
+	return showSubsRunningImages(imageNames)
+}
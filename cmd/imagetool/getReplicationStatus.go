@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func getReplicationStatusSubcommand(args []string,
+	logger log.DebugLogger) error {
+	imageSClient, _ := getClients()
+	if err := getReplicationStatus(imageSClient); err != nil {
+		return fmt.Errorf("error getting replication status: %s", err)
+	}
+	return nil
+}
+
+func getReplicationStatus(imageSClient *srpc.Client) error {
+	status, err := client.GetReplicationStatus(imageSClient)
+	if err != nil {
+		return err
+	}
+	if status.ReplicationMaster == "" {
+		fmt.Println("Replication master: none (this is a master)")
+	} else if status.Connected {
+		fmt.Printf("Replication master: %s, connected since: %s, lag: %s\n",
+			status.ReplicationMaster,
+			status.ConnectedSince.Format(format.TimeFormatSeconds),
+			format.Duration(status.Lag))
+	} else {
+		fmt.Printf("Replication master: %s, not connected, lag: %s\n",
+			status.ReplicationMaster, format.Duration(status.Lag))
+	}
+	if len(status.ReplicationMasters) > 0 {
+		fmt.Printf("Replication masters: %s\n",
+			strings.Join(status.ReplicationMasters, ", "))
+	}
+	if !status.PromotedAt.IsZero() {
+		fmt.Printf("Promoted to master at: %s\n",
+			status.PromotedAt.Format(format.TimeFormatSeconds))
+	}
+	if len(status.DivergentImages) > 0 {
+		fmt.Printf("Divergent images (not on master): %d\n",
+			len(status.DivergentImages))
+		for _, name := range status.DivergentImages {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	if len(status.Replicas) < 1 {
+		return nil
+	}
+	fmt.Println("Replicas:")
+	for _, replica := range status.Replicas {
+		var lastUpdate string
+		if replica.LastUpdateSentAt.IsZero() {
+			lastUpdate = "never"
+		} else {
+			lastUpdate = format.Duration(
+				time.Since(replica.LastUpdateSentAt)) + " ago"
+		}
+		fmt.Printf("  %s: connected for: %s, updates sent: %d, last: %s"+
+			", lag: %s\n",
+			replica.Address,
+			format.Duration(time.Since(replica.ConnectedSince)),
+			replica.NumUpdatesSent, lastUpdate, getReplicaLag(replica))
+	}
+	return nil
+}
+
+// getReplicaLag queries the replica (assumed to be listening on the same port
+// as the imageserver) for its replication lag.
+func getReplicaLag(replica proto.ReplicaStatus) string {
+	host, _, err := net.SplitHostPort(replica.Address)
+	if err != nil {
+		return "unknown"
+	}
+	address := fmt.Sprintf("%s:%d", host, *imageServerPortNum)
+	srpcClient, err := srpc.DialHTTP("tcp", address, 5*time.Second)
+	if err != nil {
+		return "unknown"
+	}
+	defer srpcClient.Close()
+	status, err := client.GetReplicationStatus(srpcClient)
+	if err != nil {
+		return "unknown"
+	}
+	if len(status.DivergentImages) > 0 {
+		return fmt.Sprintf("%s (%d divergent images)",
+			format.Duration(status.Lag), len(status.DivergentImages))
+	}
+	return format.Duration(status.Lag)
+}
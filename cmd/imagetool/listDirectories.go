@@ -30,12 +30,21 @@ func listDirectories(imageSClient *srpc.Client) error {
 		}
 	}
 	for _, directory := range directories {
-		if directory.Metadata.OwnerGroup == "" {
+		if directory.Metadata.OwnerGroup == "" &&
+			!directory.Metadata.RetentionPolicy.IsEnabled() {
 			fmt.Println(directory.Name)
 			continue
 		}
 		fmt.Printf("%-*s  ", maxDirnameWidth, directory.Name)
-		fmt.Printf("OwnerGroup=%s", directory.Metadata.OwnerGroup)
+		if directory.Metadata.OwnerGroup != "" {
+			fmt.Printf("OwnerGroup=%s", directory.Metadata.OwnerGroup)
+			if directory.Metadata.RetentionPolicy.IsEnabled() {
+				fmt.Print(" ")
+			}
+		}
+		if directory.Metadata.RetentionPolicy.IsEnabled() {
+			fmt.Print(directory.Metadata.RetentionPolicy)
+		}
 		fmt.Println()
 	}
 	return nil
@@ -13,6 +13,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/flags/commands"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
 	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/log/cmdlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/mbr"
@@ -30,7 +31,8 @@ var (
 		"build Commit Id to match when finding latest image")
 	buildLog = flag.String("buildLog", "",
 		"Filename or URL containing build log")
-	compress      = flag.Bool("compress", false, "If true, compress tar output")
+	compress = flag.Bool("compress", false,
+		"If true, compress tar or QCOW2 output")
 	computedFiles = flag.String("computedFiles", "",
 		"Name of file containing computed files list")
 	computedFilesRoot = flag.String("computedFilesRoot", "",
@@ -41,10 +43,18 @@ var (
 		"If true, show debugging output")
 	deleteFilter = flag.String("deleteFilter", "",
 		"Name of delete filter file for addi, adds and diff subcommands")
-	expiresIn = flag.Duration("expiresIn", 0,
+	diskImageFormat diskimage.Format
+	domHostname     = flag.String("domHostname", "",
+		"Hostname of dominator (to find subs running found images)")
+	domPortNum = flag.Uint("domPortNum", constants.DominatorPortNumber,
+		"Port number of dominator")
+	efiSystemPartitionSize flagutil.Size
+	expiresIn              = flag.Duration("expiresIn", 0,
 		"How long before the image expires (auto deletes). Default: never")
 	filterFile = flag.String("filterFile", "",
 		"Filter file to apply when adding, diffing or showing images")
+	force = flag.Bool("force", false,
+		"If true, promote to master even if the replication master is active")
 	fleetManagerHostname = flag.String("fleetManagerHostname", "",
 		"Hostname of Fleet Manager (to find VM to scan)")
 	fleetManagerPortNum = flag.Uint("fleetManagerPortNum",
@@ -100,6 +110,10 @@ var (
 )
 
 func init() {
+	flag.Var(&diskImageFormat, "diskImageFormat",
+		"Disk image format for make-raw-image (default raw)")
+	flag.Var(&efiSystemPartitionSize, "efiSystemPartitionSize",
+		"Size of EFI System Partition for make-raw-image (requires gpt)")
 	flag.Var(&requiredPaths, "requiredPaths",
 		"Comma separated list of required path:type entries")
 	flag.Var(&scanExcludeList, "scanExcludeList",
@@ -169,6 +183,10 @@ var subcommands = []commands.Command{
 	{"diff-triggers", "          tool left right", 3, 3,
 		diffTriggersInImagesSubcommand},
 	{"estimate-usage", "         name", 1, 1, estimateImageUsageSubcommand},
+	{"find-images-with-object", "hash", 1, 1,
+		findImagesWithObjectSubcommand},
+	{"find-images-with-path", "  pathname", 1, 1,
+		findImagesWithPathSubcommand},
 	{"find-latest-image", "      directory", 1, 1, findLatestImageSubcommand},
 	{"get", "                    name directory", 2, 2, getImageSubcommand},
 	{"get-archive-data", "       name outfile", 2, 2,
@@ -182,6 +200,7 @@ var subcommands = []commands.Command{
 	{"get-package-list", "       name [outfile]", 1, 2,
 		getImagePackageListSubcommand},
 	{"get-replication-master", "", 0, 0, getReplicationMasterSubcommand},
+	{"get-replication-status", "", 0, 0, getReplicationStatusSubcommand},
 	{"list", "", 0, 0, listImagesSubcommand},
 	{"list-mdb", "", 0, 0, listMdbImagesSubcommand},
 	{"list-not-in-mdb", "", 0, 0, listImagesNotInMdbSubcommand},
@@ -196,10 +215,13 @@ var subcommands = []commands.Command{
 	{"mkdir", "                  name", 1, 1, makeDirectorySubcommand},
 	{"patch-directory", "        name directory", 2, 2,
 		patchDirectorySubcommand},
+	{"promote-to-master", "", 0, 0, promoteToMasterSubcommand},
 	{"restore-from-file", "      filename", 1, 1, restoreImageSubcommand},
 	{"save-to-file", "           name [outfile]", 1, 2, saveImageSubcommand},
 	{"scan-filtered-files", "    name directory", 2, 2,
 		scanFilteredFilesSubcommand},
+	{"set-retention", "          dirname keepLatest keepNewerThan", 3, 3,
+		setRetentionSubcommand},
 	{"show", "                   name", 1, 1, showImageSubcommand},
 	{"show-bad-computed-files", "", 0, 0, showBadComputedFilesSubcommand},
 	{"show-bad-image-subs", "", 0, 0, showBadImageSubsSubcommand},
@@ -209,6 +231,8 @@ var subcommands = []commands.Command{
 	{"show-inode", "             name inodePath", 2, 2,
 		showImageInodeSubcommand},
 	{"show-metadata", "          name", 1, 1, showImageMetadataSubcommand},
+	{"show-retention-report", "  [dirname]", 0, 1,
+		showRetentionReportSubcommand},
 	{"show-triggers", "          name", 1, 1, showImageTriggersSubcommand},
 	{"showunrefobj", "", 0, 0, showUnreferencedObjectsSubcommand},
 	{"tar", "                    name [file]", 1, 2, tarImageSubcommand},
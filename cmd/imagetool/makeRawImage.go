@@ -8,6 +8,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
 )
@@ -20,6 +21,37 @@ func makeRawImageSubcommand(args []string, logger log.DebugLogger) error {
 	return nil
 }
 
+// convertRawImage converts a RAW image file to the format specified by the
+// diskImageFormat flag.
+func convertRawImage(rawFilename, filename string) error {
+	rawFile, err := os.Open(rawFilename)
+	if err != nil {
+		return err
+	}
+	defer rawFile.Close()
+	fi, err := rawFile.Stat()
+	if err != nil {
+		return err
+	}
+	tmpFilename := filename + "~"
+	file, err := os.OpenFile(tmpFilename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
+		fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFilename)
+	_, err = diskimage.Write(file, rawFile, uint64(fi.Size()), diskImageFormat,
+		diskimage.WriteOptions{Compress: *compress})
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
+
 func loadOverlayFiles() (map[string][]byte, error) {
 	if *overlayDirectory == "" {
 		return nil, nil
@@ -53,19 +85,30 @@ func makeRawImage(objectClient *objectclient.ObjectClient, name,
 		return err
 	}
 	options := util.WriteRawOptions{
-		AllocateBlocks:    *allocateBlocks,
-		InitialImageName:  name,
-		InstallBootloader: *makeBootable,
-		MinimumFreeBytes:  *minFreeBytes,
-		WriteFstab:        *makeBootable,
-		RootLabel:         *rootLabel,
-		RoundupPower:      *roundupPower,
+		AllocateBlocks:         *allocateBlocks,
+		EfiSystemPartitionSize: uint64(efiSystemPartitionSize),
+		InitialImageName:       name,
+		InstallBootloader:      *makeBootable,
+		MinimumFreeBytes:       *minFreeBytes,
+		WriteFstab:             *makeBootable,
+		RootLabel:              *rootLabel,
+		RoundupPower:           *roundupPower,
 	}
 	if overlayFiles, err := loadOverlayFiles(); err != nil {
 		return err
 	} else {
 		options.OverlayFiles = overlayFiles
 	}
-	return util.WriteRawWithOptions(fs, objectsGetter, rawFilename,
-		fsutil.PublicFilePerms, tableType, options, logger)
+	if diskImageFormat == diskimage.FormatRaw {
+		return util.WriteRawWithOptions(fs, objectsGetter, rawFilename,
+			fsutil.PublicFilePerms, tableType, options, logger)
+	}
+	tmpFilename := rawFilename + ".raw"
+	defer os.Remove(tmpFilename)
+	err = util.WriteRawWithOptions(fs, objectsGetter, tmpFilename,
+		fsutil.PrivateFilePerms, tableType, options, logger)
+	if err != nil {
+		return err
+	}
+	return convertRawImage(tmpFilename, rawFilename)
 }
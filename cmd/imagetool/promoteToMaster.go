@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func promoteToMasterSubcommand(args []string, logger log.DebugLogger) error {
+	imageSClient, _ := getClients()
+	if err := client.PromoteToMaster(imageSClient, *force); err != nil {
+		return fmt.Errorf("error promoting to master: %s", err)
+	}
+	return nil
+}
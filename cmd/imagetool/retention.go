@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func setRetentionSubcommand(args []string, logger log.DebugLogger) error {
+	if err := setRetention(args[0], args[1], args[2]); err != nil {
+		return fmt.Errorf("error setting retention policy: %s", err)
+	}
+	return nil
+}
+
+func setRetention(dirname, keepLatest, keepNewerThan string) error {
+	var policy image.RetentionPolicy
+	if value, err := strconv.ParseUint(keepLatest, 10, 0); err != nil {
+		return err
+	} else {
+		policy.KeepLatest = uint(value)
+	}
+	if duration, err := time.ParseDuration(keepNewerThan); err != nil {
+		return err
+	} else {
+		policy.KeepNewerThan = duration
+	}
+	imageSClient, _ := getMasterClients()
+	return client.SetDirectoryRetention(imageSClient, dirname, policy)
+}
+
+func showRetentionReportSubcommand(args []string,
+	logger log.DebugLogger) error {
+	var dirname string
+	if len(args) > 0 {
+		dirname = args[0]
+	}
+	if err := showRetentionReport(dirname); err != nil {
+		return fmt.Errorf("error showing retention report: %s", err)
+	}
+	return nil
+}
+
+func showRetentionReport(dirname string) error {
+	imageSClient, _ := getMasterClients()
+	directories, err := client.GetRetentionReport(imageSClient, dirname)
+	if err != nil {
+		return err
+	}
+	for _, directory := range directories {
+		fmt.Printf("%s: %s\n", directory.DirectoryName, directory.Policy)
+		for _, name := range directory.ImagesToDelete {
+			fmt.Printf("  delete: %s\n", name)
+		}
+		for _, name := range directory.ImagesReferenced {
+			fmt.Printf("  keep (referenced): %s\n", name)
+		}
+	}
+	return nil
+}
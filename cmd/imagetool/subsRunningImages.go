@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	domclient "github.com/Cloud-Foundations/Dominator/dom/client"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/stringutil"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func dialDominator() (*srpc.Client, error) {
+	clientName := fmt.Sprintf("%s:%d", *domHostname, *domPortNum)
+	domClient, err := srpc.DialHTTP("tcp", clientName, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing: %s: %s", clientName, err)
+	}
+	return domClient, nil
+}
+
+// showSubsRunningImages will ask the dominator (if specified) for the subs
+// which are running or are scheduled to run any of the specified images and
+// will write each sub and image pair to stdout.
+func showSubsRunningImages(imageNames []string) error {
+	if *domHostname == "" || len(imageNames) < 1 {
+		return nil
+	}
+	domClient, err := dialDominator()
+	if err != nil {
+		return err
+	}
+	defer domClient.Close()
+	reply, err := domclient.GetInfoForSubs(domClient,
+		dominator.GetInfoForSubsRequest{ImagesToMatch: imageNames})
+	if err != nil {
+		return err
+	}
+	imagesToMatch := stringutil.ConvertListToMap(imageNames, false)
+	fmt.Println("Subs:")
+	for _, sub := range reply.Subs {
+		for _, imageName := range []string{
+			sub.LastSuccessfulImage,
+			sub.PlannedImage,
+			sub.RequiredImage,
+		} {
+			if _, ok := imagesToMatch[imageName]; ok {
+				fmt.Printf("  %s %s\n", sub.Hostname, imageName)
+				break
+			}
+		}
+	}
+	return nil
+}
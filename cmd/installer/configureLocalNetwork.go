@@ -38,6 +38,7 @@ type dhcpResponse struct {
 var (
 	tftpFiles = map[string]bool{ // If true, file is required.
 		"config.json":         true,
+		"fleetmanager":        false,
 		"imagename":           true,
 		"imageserver":         true,
 		"storage-layout.json": true,
@@ -103,79 +103,59 @@ func closeEncryptedVolumes(logger log.DebugLogger) error {
 	}
 }
 
-func configureBootDrive(cpuSharer cpusharer.CpuSharer, drive *driveType,
+func configureBootDrives(cpuSharer cpusharer.CpuSharer, drives []*driveType,
 	layout installer_proto.StorageLayout, rootPartition, bootPartition int,
 	img *image.Image, objGetter objectserver.ObjectsGetter,
 	bootInfo *util.BootInfoType, logger log.DebugLogger) error {
-	startTime := time.Now()
-	if run("blkdiscard", "", logger, drive.devpath) == nil {
-		drive.discarded = true
-		logger.Printf("discarded %s in %s\n",
-			drive.devpath, format.Duration(time.Since(startTime)))
-	} else { // Erase old partition.
-		if err := eraseStart(drive.devpath, logger); err != nil {
+	isEfi := checkIsEfi()
+	for _, drive := range drives {
+		if err := drive.partition(layout, bootPartition, isEfi, logger); err != nil {
 			return err
 		}
 	}
-	isEfi := checkIsEfi()
-	args := []string{"-s", "-a", "optimal", drive.devpath}
-	if isEfi {
-		args = append(args, "mklabel", "gpt")
-	} else {
-		args = append(args, "mklabel", "msdos")
-	}
-	unitSize := uint64(1 << 20)
-	unitSuffix := "MiB"
-	offsetInUnits := uint64(1)
-	for _, partition := range layout.BootDriveLayout {
-		sizeInUnits := partition.MinimumFreeBytes / unitSize
-		if sizeInUnits*unitSize < partition.MinimumFreeBytes {
-			sizeInUnits++
-		}
-		var partType string
-		switch partition.FileSystemType {
-		case installer_proto.FileSystemTypeVfat:
-			partType = "fat32"
-		default:
-			partType = partition.FileSystemType.String()
+	devices := bootDevices(drives, len(layout.BootDriveLayout)+1, isEfi)
+	if len(drives) > 1 {
+		err := makeBootArrays(drives, devices, layout.BootDriveRaidLevel, logger)
+		if err != nil {
+			return err
 		}
-		args = append(args, "mkpart", "primary", partType,
-			strconv.FormatUint(offsetInUnits, 10)+unitSuffix,
-			strconv.FormatUint(offsetInUnits+sizeInUnits, 10)+unitSuffix)
-		offsetInUnits += sizeInUnits
-	}
-	args = append(args, "mkpart", "primary", "ext2",
-		strconv.FormatUint(offsetInUnits, 10)+unitSuffix, "100%")
-	if isEfi { // EFI System Partition is always the first partition.
-		args = append(args, "set", "1", "esp", "on")
-	} else {
-		args = append(args,
-			"set", strconv.FormatInt(int64(bootPartition), 10), "boot", "on")
-	}
-	if err := run("parted", *tmpRoot, logger, args...); err != nil {
-		return err
 	}
+	bootDrive := mergeDrives(drives, "boot")
 	// Prepare all file-systems concurrently, make them serially.
 	concurrentState := concurrent.NewState(uint(
 		len(layout.BootDriveLayout) + 1))
 	var mkfsMutex sync.Mutex
+	mirrorEsp := isEfi && len(drives) > 1
 	for index, partition := range layout.BootDriveLayout {
-		device := partitionName(drive.devpath, index+1)
+		if mirrorEsp && index == 0 {
+			label := efiFsLabel
+			if partition.MountPoint == bootMountPoint {
+				label = bootFsLabel
+			}
+			err := concurrentState.GoRun(func() error {
+				return makeEspFileSystems(drives, label, logger)
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		device := devices[index]
 		partition := partition
 		err := concurrentState.GoRun(func() error {
-			return drive.makeFileSystem(cpuSharer, device, partition.MountPoint,
-				partition.FileSystemType, layout.Encrypt, &mkfsMutex, 0, logger)
+			return bootDrive.makeFileSystem(cpuSharer, device,
+				partition.MountPoint, partition.FileSystemType, layout.Encrypt,
+				&mkfsMutex, 0, logger)
 		})
 		if err != nil {
 			return err
 		}
 	}
 	concurrentState.GoRun(func() error {
-		device := partitionName(drive.devpath, len(layout.BootDriveLayout)+1)
-		return drive.makeFileSystem(cpuSharer, device,
-			layout.ExtraMountPointsBasename+"0",
-			installer_proto.FileSystemTypeExt4, layout.Encrypt, &mkfsMutex,
-			65536, logger)
+		device := devices[len(layout.BootDriveLayout)]
+		return bootDrive.makeFileSystem(cpuSharer, device,
+			layout.ExtraMountPointsBasename+"0", layout.ExtraFileSystemType,
+			layout.Encrypt, &mkfsMutex, 65536, logger)
 	})
 	if err := concurrentState.Reap(); err != nil {
 		return err
@@ -183,7 +163,7 @@ func configureBootDrive(cpuSharer cpusharer.CpuSharer, drive *driveType,
 	// Mount all file-systems, except the /boot and data file-systems, so that
 	// the image can create directories in them. First do the root partition,
 	// which might not be first in the list.
-	err := mount(partitionName(drive.devpath, rootPartition), *mountPoint,
+	err := mount(devices[rootPartition-1], *mountPoint,
 		layout.BootDriveLayout[rootPartition-1].FileSystemType.String(), logger)
 	if err != nil {
 		return err
@@ -192,8 +172,8 @@ func configureBootDrive(cpuSharer cpusharer.CpuSharer, drive *driveType,
 		if index+1 == rootPartition || index+1 == bootPartition {
 			continue
 		}
-		device := partitionName(drive.devpath, index+1)
-		err := mount(remapDevice(device, partition.MountPoint, layout.Encrypt),
+		err := mount(remapDevice(devices[index], partition.MountPoint,
+			layout.Encrypt),
 			filepath.Join(*mountPoint, partition.MountPoint),
 			partition.FileSystemType.String(), logger)
 		if err != nil {
@@ -204,8 +184,12 @@ func configureBootDrive(cpuSharer cpusharer.CpuSharer, drive *driveType,
 	if bootPartition != rootPartition {
 		bootP = bootPartition
 	}
-	return installRoot(drive.devpath, layout, img.FileSystem, objGetter,
-		bootInfo, bootP, logger)
+	err = installRoot(drives[0].devpath, devices, layout, img.FileSystem,
+		objGetter, bootInfo, bootP, logger)
+	if err != nil {
+		return err
+	}
+	return installBootLoaders(drives, layout, isEfi, logger)
 }
 
 func configureDataDrive(cpuSharer cpusharer.CpuSharer, drive *driveType,
@@ -220,8 +204,7 @@ func configureDataDrive(cpuSharer cpusharer.CpuSharer, drive *driveType,
 	dataMountPoint := layout.ExtraMountPointsBasename + strconv.FormatInt(
 		int64(index), 10)
 	return drive.makeFileSystem(cpuSharer, drive.devpath, dataMountPoint,
-		installer_proto.FileSystemTypeExt4, layout.Encrypt, nil, 1048576,
-		logger)
+		layout.ExtraFileSystemType, layout.Encrypt, nil, 1048576, logger)
 }
 
 func configureStorage(config fm_proto.GetMachineInfoResponse,
@@ -280,6 +263,10 @@ func configureStorage(config fm_proto.GetMachineInfoResponse,
 	if err != nil {
 		return nil, err
 	}
+	plan, err := makeStoragePlan(layout, drives)
+	if err != nil {
+		return nil, err
+	}
 	rootDevice := partitionName(drives[0].devpath, rootPartition)
 	var randomKey []byte
 	if layout.Encrypt {
@@ -364,19 +351,32 @@ func configureStorage(config fm_proto.GetMachineInfoResponse,
 			randomKey[index] = 0
 		}
 	}
+	// Release drives from previous RAID arrays, volume groups and encrypted
+	// volumes, so that re-running the installer builds the layout again.
+	for _, drive := range drives {
+		if err := releaseDevice(drive.name, logger); err != nil {
+			return nil, err
+		}
+	}
 	// Configure all drives concurrently, making file-systems.
 	// Use concurrent package because of it's reaping cabability.
 	// Use cpusharer package to limit CPU intensive operations.
-	concurrentState := concurrent.NewState(uint(len(drives)))
+	concurrentState := concurrent.NewState(uint(len(plan.dataDrives) + 2))
 	cpuSharer := cpusharer.NewFifoCpuSharer()
 	err = concurrentState.GoRun(func() error {
-		return configureBootDrive(cpuSharer, drives[0], layout, rootPartition,
-			bootPartition, img, objGetter, bootInfo, logger)
+		return configureBootDrives(cpuSharer, plan.bootDrives, layout,
+			rootPartition, bootPartition, img, objGetter, bootInfo, logger)
 	})
 	if err != nil {
 		return nil, concurrentState.Reap()
 	}
-	for index, drive := range drives[1:] {
+	err = concurrentState.GoRun(func() error {
+		return plan.configureVolumes(cpuSharer, layout, logger)
+	})
+	if err != nil {
+		return nil, concurrentState.Reap()
+	}
+	for index, drive := range plan.dataDrives {
 		drive := drive
 		index := index + 1
 		err := concurrentState.GoRun(func() error {
@@ -389,16 +389,19 @@ func configureStorage(config fm_proto.GetMachineInfoResponse,
 	if err := concurrentState.Reap(); err != nil {
 		return nil, err
 	}
+	devices := bootDevices(plan.bootDrives, len(layout.BootDriveLayout)+1,
+		isEfi)
+	bootDrive := mergeDrives(plan.bootDrives, "boot")
 	// Make table entries for the boot device file-systems, except data FS.
 	fsTab := &bytes.Buffer{}
 	cryptTab := &bytes.Buffer{}
 	// Write the root file-system entry first.
 	bootCheckCount := uint(1)
 	{
-		device := partitionName(drives[0].devpath, rootPartition)
 		partition := layout.BootDriveLayout[rootPartition-1]
-		err = drives[0].writeDeviceEntries(device, partition.MountPoint,
-			partition.FileSystemType, fsTab, cryptTab, bootCheckCount)
+		err = bootDrive.writeDeviceEntries(devices[rootPartition-1],
+			partition.MountPoint, partition.FileSystemType, fsTab, cryptTab,
+			bootCheckCount)
 		if err != nil {
 			return nil, err
 		}
@@ -408,32 +411,32 @@ func configureStorage(config fm_proto.GetMachineInfoResponse,
 			continue
 		}
 		bootCheckCount++
-		device := partitionName(drives[0].devpath, index+1)
-		err = drives[0].writeDeviceEntries(device, partition.MountPoint,
+		err = bootDrive.writeDeviceEntries(devices[index], partition.MountPoint,
 			partition.FileSystemType, fsTab, cryptTab, bootCheckCount)
 		if err != nil {
 			return nil, err
 		}
 	}
-	// Make table entries for data file-systems.
-	for index, drive := range drives {
-		checkCount := uint(2)
-		var device string
-		if index == 0 { // The boot device is partitioned.
-			checkCount = uint(len(layout.BootDriveLayout) + 1)
-			device = partitionName(drives[0].devpath,
-				len(layout.BootDriveLayout)+1)
-		} else { // Extra drives are used whole.
-			device = drive.devpath
-		}
+	// Make table entries for data file-systems. The boot device is
+	// partitioned and extra drives are used whole.
+	err = bootDrive.writeDeviceEntries(devices[len(layout.BootDriveLayout)],
+		layout.ExtraMountPointsBasename+"0", layout.ExtraFileSystemType,
+		fsTab, cryptTab, uint(len(layout.BootDriveLayout)+1))
+	if err != nil {
+		return nil, err
+	}
+	for index, drive := range plan.dataDrives {
 		dataMountPoint := layout.ExtraMountPointsBasename + strconv.FormatInt(
-			int64(index), 10)
-		err = drive.writeDeviceEntries(device, dataMountPoint,
-			installer_proto.FileSystemTypeExt4, fsTab, cryptTab, checkCount)
+			int64(index+1), 10)
+		err = drive.writeDeviceEntries(drive.devpath, dataMountPoint,
+			layout.ExtraFileSystemType, fsTab, cryptTab, 2)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if err := plan.writeVolumeEntries(fsTab, cryptTab); err != nil {
+		return nil, err
+	}
 	logger.Printf("Writing /etc/fstab:\n%s", string(fsTab.Bytes()))
 	err = ioutil.WriteFile(filepath.Join(*mountPoint, "etc", "fstab"),
 		fsTab.Bytes(), fsutil.PublicFilePerms)
@@ -616,7 +619,8 @@ func getRandomKey(numBytes uint, logger log.DebugLogger) ([]byte, error) {
 	}
 }
 
-func installRoot(device string, layout installer_proto.StorageLayout,
+func installRoot(device string, devices []string,
+	layout installer_proto.StorageLayout,
 	fileSystem *filesystem.FileSystem, objGetter objectserver.ObjectsGetter,
 	bootInfo *util.BootInfoType, bootPartition int,
 	logger log.DebugLogger) error {
@@ -642,7 +646,7 @@ func installRoot(device string, layout installer_proto.StorageLayout,
 		// This ensures that the bootloader has the files it needs and that the
 		// root file-system is fully up-to-date with the image.
 		partition := layout.BootDriveLayout[bootPartition-1]
-		err := mount(partitionName(device, bootPartition), "/tmpboot",
+		err := mount(devices[bootPartition-1], "/tmpboot",
 			partition.FileSystemType.String(), logger)
 		if err != nil {
 			return err
@@ -663,7 +667,7 @@ func installRoot(device string, layout installer_proto.StorageLayout,
 			return fmt.Errorf("error unmounting: %s: %s", "/tmpboot", err)
 		}
 		logger.Debugln(0, "unmounted /tmpboot")
-		err = mount(partitionName(device, bootPartition),
+		err = mount(devices[bootPartition-1],
 			filepath.Join(*mountPoint, partition.MountPoint),
 			partition.FileSystemType.String(), logger)
 		if err != nil {
@@ -990,6 +994,18 @@ func (drive driveType) cryptSetup(cpuSharer cpusharer.CpuSharer, device string,
 	return nil
 }
 
+// erase discards the drive if possible, else it erases the start of the drive.
+func (drive *driveType) erase(logger log.DebugLogger) error {
+	startTime := time.Now()
+	if run("blkdiscard", "", logger, drive.devpath) == nil {
+		drive.discarded = true
+		logger.Printf("discarded %s in %s\n",
+			drive.devpath, format.Duration(time.Since(startTime)))
+		return nil
+	}
+	return eraseStart(drive.devpath, logger)
+}
+
 func (drive driveType) makeFileSystem(cpuSharer cpusharer.CpuSharer,
 	device, target string, fstype installer_proto.FileSystemType, encrypt bool,
 	mkfsMutex *sync.Mutex, bytesPerInode uint, logger log.DebugLogger) error {
@@ -1041,6 +1057,8 @@ func (drive driveType) makeFileSystem(cpuSharer cpusharer.CpuSharer,
 	case installer_proto.FileSystemTypeVfat:
 		err = run("mkfs.vfat", *tmpRoot, logger, "--codepage=437",
 			"-n", label, device)
+	case installer_proto.FileSystemTypeXfs:
+		err = run("mkfs.xfs", *tmpRoot, logger, "-f", "-L", label, device)
 	default:
 		return fmt.Errorf("unsupported file-system type: %d (%s)",
 			fstype, fstype)
@@ -1056,6 +1074,50 @@ func (drive driveType) makeFileSystem(cpuSharer cpusharer.CpuSharer,
 	return nil
 }
 
+// partition erases the drive and writes a partition table for the boot drive
+// layout, with the data partition consuming the remaining space.
+func (drive *driveType) partition(layout installer_proto.StorageLayout,
+	bootPartition int, isEfi bool, logger log.DebugLogger) error {
+	if err := drive.erase(logger); err != nil {
+		return err
+	}
+	args := []string{"-s", "-a", "optimal", drive.devpath}
+	if isEfi {
+		args = append(args, "mklabel", "gpt")
+	} else {
+		args = append(args, "mklabel", "msdos")
+	}
+	unitSize := uint64(1 << 20)
+	unitSuffix := "MiB"
+	offsetInUnits := uint64(1)
+	for _, partition := range layout.BootDriveLayout {
+		sizeInUnits := partition.MinimumFreeBytes / unitSize
+		if sizeInUnits*unitSize < partition.MinimumFreeBytes {
+			sizeInUnits++
+		}
+		var partType string
+		switch partition.FileSystemType {
+		case installer_proto.FileSystemTypeVfat:
+			partType = "fat32"
+		default:
+			partType = partition.FileSystemType.String()
+		}
+		args = append(args, "mkpart", "primary", partType,
+			strconv.FormatUint(offsetInUnits, 10)+unitSuffix,
+			strconv.FormatUint(offsetInUnits+sizeInUnits, 10)+unitSuffix)
+		offsetInUnits += sizeInUnits
+	}
+	args = append(args, "mkpart", "primary", "ext2",
+		strconv.FormatUint(offsetInUnits, 10)+unitSuffix, "100%")
+	if isEfi { // EFI System Partition is always the first partition.
+		args = append(args, "set", "1", "esp", "on")
+	} else {
+		args = append(args,
+			"set", strconv.FormatInt(int64(bootPartition), 10), "boot", "on")
+	}
+	return run("parted", *tmpRoot, logger, args...)
+}
+
 func (drive driveType) writeDeviceEntries(device, target string,
 	fstype installer_proto.FileSystemType,
 	fsTab, cryptTab io.Writer, checkOrder uint) error {
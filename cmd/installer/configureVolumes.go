@@ -0,0 +1,561 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/concurrent"
+	"github.com/Cloud-Foundations/Dominator/lib/cpusharer"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	installer_proto "github.com/Cloud-Foundations/Dominator/proto/installer"
+)
+
+const xfsMaxLabelLength = 12
+
+type raidArrayType struct {
+	config  installer_proto.RaidArray
+	device  string
+	drive   *driveType // Merged from the members, after erasing.
+	members []*driveType
+}
+
+// storagePlan records which drives are used for which purpose.
+type storagePlan struct {
+	arrays       []*raidArrayType
+	bootDrives   []*driveType
+	dataDrives   []*driveType // Extra drives used whole for a file-system.
+	volumeGroups []*volumeGroupType
+}
+
+type volumeGroupType struct {
+	config installer_proto.VolumeGroup
+	drive  *driveType // Merged from the physical volumes, after erasing.
+	drives []*driveType
+	pvs    []string
+}
+
+// bootDevices returns the devices for the boot drive partitions, including
+// the data partition. If there are multiple boot drives these are RAID arrays,
+// except for the EFI System Partition, which is on each drive (see
+// espDevices) and is mounted from the first drive.
+func bootDevices(drives []*driveType, numPartitions int, isEfi bool) []string {
+	devices := make([]string, 0, numPartitions)
+	for index := 0; index < numPartitions; index++ {
+		if len(drives) < 2 || (isEfi && index == 0) {
+			devices = append(devices, partitionName(drives[0].devpath, index+1))
+		} else {
+			devices = append(devices,
+				"/dev/md/boot"+strconv.FormatInt(int64(index+1), 10))
+		}
+	}
+	return devices
+}
+
+// copyEsp copies the boot loader files from the EFI System Partition mounted
+// at espDirectory to the ESPs on the other boot drives.
+func copyEsp(drives []*driveType, espDirectory string,
+	logger log.DebugLogger) error {
+	const tmpEsp = "/tmpesp"
+	if err := os.MkdirAll(tmpEsp, fsutil.DirPerms); err != nil {
+		return err
+	}
+	for _, device := range espDevices(drives)[1:] {
+		if err := mount(device, tmpEsp, "vfat", logger); err != nil {
+			return err
+		}
+		err := fsutil.CopyFilesTree(espDirectory, tmpEsp)
+		if err != nil {
+			syscall.Unmount(tmpEsp, 0)
+			return err
+		}
+		if err := syscall.Unmount(tmpEsp, 0); err != nil {
+			return fmt.Errorf("error unmounting: %s: %s", tmpEsp, err)
+		}
+		logger.Printf("copied EFI System Partition to: %s\n", device)
+	}
+	return nil
+}
+
+func checkFileSystemType(fstype installer_proto.FileSystemType,
+	mountPoint string) error {
+	if mountPoint == "" {
+		return fmt.Errorf("no mount point specified")
+	}
+	switch fstype {
+	case installer_proto.FileSystemTypeExt4:
+	case installer_proto.FileSystemTypeXfs:
+		if len(mountPoint) > xfsMaxLabelLength {
+			return fmt.Errorf("mount point: %s too long for XFS label",
+				mountPoint)
+		}
+	default:
+		return fmt.Errorf("unsupported file-system type: %s for: %s",
+			fstype, mountPoint)
+	}
+	return nil
+}
+
+func checkRaidLevel(level, numDrives uint) error {
+	switch level {
+	case installer_proto.RaidLevel1:
+	case installer_proto.RaidLevel10:
+	default:
+		return fmt.Errorf("unsupported RAID level: %d", level)
+	}
+	if numDrives < 2 {
+		return fmt.Errorf("RAID%d requires at least 2 drives, have: %d",
+			level, numDrives)
+	}
+	return nil
+}
+
+// espDevices returns the EFI System Partition on each of the boot drives. The
+// first is the primary ESP.
+func espDevices(drives []*driveType) []string {
+	devices := make([]string, 0, len(drives))
+	for _, drive := range drives {
+		devices = append(devices, partitionName(drive.devpath, 1))
+	}
+	return devices
+}
+
+// installBootLoaders makes the boot drives after the first bootable, once the
+// boot loader has been installed on the first drive. For EFI the ESP is
+// copied, else GRUB is installed on each drive, using the mirrored /boot.
+func installBootLoaders(drives []*driveType,
+	layout installer_proto.StorageLayout, isEfi bool,
+	logger log.DebugLogger) error {
+	if len(drives) < 2 {
+		return nil
+	}
+	if *dryRun {
+		logger.Debugln(0, "dry run: skipping installing boot loaders")
+		return nil
+	}
+	if isEfi {
+		return copyEsp(drives, filepath.Join(*mountPoint,
+			layout.BootDriveLayout[0].MountPoint), logger)
+	}
+	grubInstaller := "grub-install"
+	if _, err := lookPath(*mountPoint, grubInstaller); err != nil {
+		grubInstaller = "grub2-install"
+	}
+	for _, drive := range drives[1:] {
+		err := run(grubInstaller, *mountPoint, logger,
+			"--boot-directory=/boot", "--target=i386-pc", drive.devpath)
+		if err != nil {
+			return err
+		}
+		logger.Printf("installed GRUB on: %s\n", drive.devpath)
+	}
+	return nil
+}
+
+// makeBootArrays creates a RAID array for each boot partition. The version
+// 1.0 metadata is at the end of the partitions, so each member may be read as
+// a plain file-system by firmware and boot loaders.
+func makeBootArrays(drives []*driveType, devices []string, level uint,
+	logger log.DebugLogger) error {
+	for index, device := range devices {
+		if !strings.HasPrefix(device, "/dev/md/") {
+			continue
+		}
+		members := make([]string, 0, len(drives))
+		for _, drive := range drives {
+			members = append(members, partitionName(drive.devpath, index+1))
+		}
+		if err := makeRaidArray(device, level, "1.0", members,
+			logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeEspFileSystems makes the EFI System Partition file-systems on the boot
+// drives. They share a volume ID, so that the boot loader finds its files
+// whichever drive the firmware boots from. Only the first has the label used
+// in the fstab.
+func makeEspFileSystems(drives []*driveType, label string,
+	logger log.DebugLogger) error {
+	volumeId, err := getRandomKey(4, logger)
+	if err != nil {
+		return err
+	}
+	for index, device := range espDevices(drives) {
+		_, _, err := fsutil.WaitForBlockAvailable(device, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		if index > 0 {
+			label = efiFsLabel + strconv.Itoa(index)
+		}
+		err = run("mkfs.vfat", *tmpRoot, logger, "--codepage=437",
+			"-i", hex.EncodeToString(volumeId), "-n", label, device)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Printf("made EFI System Partitions on %d drives\n", len(drives))
+	return nil
+}
+
+// makeRaidArray creates a RAID array. Any previous RAID metadata on the
+// members is overwritten.
+func makeRaidArray(device string, level uint, metadata string,
+	members []string, logger log.DebugLogger) error {
+	args := []string{"--create", device, "--run",
+		"--level=" + strconv.FormatUint(uint64(level), 10),
+		"--metadata=" + metadata,
+		"--raid-devices=" + strconv.Itoa(len(members)),
+	}
+	args = append(args, members...)
+	if err := run("mdadm", *tmpRoot, logger, args...); err != nil {
+		return err
+	}
+	logger.Printf("created RAID%d array: %s from: %s\n",
+		level, device, strings.Join(members, ","))
+	return nil
+}
+
+// makeStoragePlan checks the layout and assigns the drives. The first drives
+// are the boot drives, followed by the drives for the RAID arrays and volume
+// groups, in the order they are listed. Remaining drives are used whole.
+func makeStoragePlan(layout installer_proto.StorageLayout,
+	drives []*driveType) (*storagePlan, error) {
+	numBootDrives := layout.NumBootDrives
+	if numBootDrives < 1 {
+		numBootDrives = 1
+	}
+	if numBootDrives > 1 {
+		err := checkRaidLevel(layout.BootDriveRaidLevel, numBootDrives)
+		if err != nil {
+			return nil, fmt.Errorf("boot drives: %s", err)
+		}
+	} else if layout.BootDriveRaidLevel != 0 {
+		return nil, fmt.Errorf("BootDriveRaidLevel requires NumBootDrives > 1")
+	}
+	if layout.ExtraFileSystemType == installer_proto.FileSystemTypeXfs {
+		err := checkFileSystemType(layout.ExtraFileSystemType,
+			layout.ExtraMountPointsBasename+strconv.Itoa(len(drives)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	var numRequired uint
+	names := make(map[string]struct{})
+	checkName := func(name string) error {
+		if name == "" || strings.ContainsAny(name, "/ ") ||
+			strings.HasPrefix(name, "boot") || name == "control" {
+			return fmt.Errorf("bad name: \"%s\"", name)
+		}
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("duplicate name: %s", name)
+		}
+		names[name] = struct{}{}
+		return nil
+	}
+	groups := make(map[string]*volumeGroupType, len(layout.VolumeGroups))
+	plan := &storagePlan{}
+	for _, config := range layout.VolumeGroups {
+		if err := checkName(config.Name); err != nil {
+			return nil, fmt.Errorf("volume group: %s", err)
+		}
+		if len(config.LogicalVolumes) < 1 {
+			return nil, fmt.Errorf("volume group: %s has no logical volumes",
+				config.Name)
+		}
+		for index, logicalVolume := range config.LogicalVolumes {
+			if err := checkName(logicalVolume.Name); err != nil {
+				return nil, fmt.Errorf("logical volume: %s", err)
+			}
+			err := checkFileSystemType(logicalVolume.FileSystemType,
+				logicalVolume.MountPoint)
+			if err != nil {
+				return nil, fmt.Errorf("logical volume: %s: %s",
+					logicalVolume.Name, err)
+			}
+			if logicalVolume.SizeBytes < 1 &&
+				index != len(config.LogicalVolumes)-1 {
+				return nil, fmt.Errorf(
+					"logical volume: %s without size must be last",
+					logicalVolume.Name)
+			}
+		}
+		group := &volumeGroupType{config: config}
+		groups[config.Name] = group
+		plan.volumeGroups = append(plan.volumeGroups, group)
+		numRequired += config.NumDrives
+	}
+	for _, config := range layout.RaidArrays {
+		if err := checkName(config.Name); err != nil {
+			return nil, fmt.Errorf("RAID array: %s", err)
+		}
+		if err := checkRaidLevel(config.Level, config.NumDrives); err != nil {
+			return nil, fmt.Errorf("RAID array: %s: %s", config.Name, err)
+		}
+		if config.VolumeGroup != "" {
+			if _, ok := groups[config.VolumeGroup]; !ok {
+				return nil, fmt.Errorf("RAID array: %s: unknown volume group: %s",
+					config.Name, config.VolumeGroup)
+			}
+		} else {
+			err := checkFileSystemType(config.FileSystemType,
+				config.MountPoint)
+			if err != nil {
+				return nil, fmt.Errorf("RAID array: %s: %s", config.Name, err)
+			}
+		}
+		plan.arrays = append(plan.arrays, &raidArrayType{
+			config: config,
+			device: filepath.Join("/dev/md", config.Name),
+		})
+		numRequired += config.NumDrives
+	}
+	numRequired += numBootDrives
+	if numRequired > uint(len(drives)) {
+		return nil, fmt.Errorf("layout requires %d drives, have: %d",
+			numRequired, len(drives))
+	}
+	plan.bootDrives = drives[:numBootDrives]
+	drives = drives[numBootDrives:]
+	for _, array := range plan.arrays {
+		array.members = drives[:array.config.NumDrives]
+		drives = drives[array.config.NumDrives:]
+		if array.config.VolumeGroup != "" {
+			group := groups[array.config.VolumeGroup]
+			group.pvs = append(group.pvs, array.device)
+		}
+	}
+	for _, group := range plan.volumeGroups {
+		group.drives = drives[:group.config.NumDrives]
+		drives = drives[group.config.NumDrives:]
+		for _, drive := range group.drives {
+			group.pvs = append(group.pvs, drive.devpath)
+		}
+		if len(group.pvs) < 1 {
+			return nil, fmt.Errorf("volume group: %s has no physical volumes",
+				group.config.Name)
+		}
+	}
+	plan.dataDrives = drives
+	return plan, nil
+}
+
+// mergeDrives returns a drive which represents a device built from several
+// drives. It is discarded only if all the drives were discarded.
+func mergeDrives(drives []*driveType, name string) *driveType {
+	if len(drives) == 1 {
+		return drives[0]
+	}
+	merged := &driveType{discarded: true, name: name}
+	for _, drive := range drives {
+		if !drive.discarded {
+			merged.discarded = false
+		}
+		merged.size += drive.size
+	}
+	return merged
+}
+
+// releaseDevice stops any RAID arrays and removes any device-mapper devices
+// (LVM, encryption) which are using the device or its partitions, so that it
+// may be erased. This allows the installer to be run again on a machine with
+// a previous installation.
+func releaseDevice(name string, logger log.DebugLogger) error {
+	dirname := filepath.Join(*sysfsDirectory, "class", "block", name)
+	holders, err := readDirnames(filepath.Join(dirname, "holders"))
+	if err != nil {
+		return err
+	}
+	for _, holder := range holders {
+		if err := releaseDevice(holder, logger); err != nil {
+			return err
+		}
+		device := filepath.Join("/dev", holder)
+		if strings.HasPrefix(holder, "dm-") {
+			err = run("dmsetup", *tmpRoot, logger, "remove", device)
+		} else if strings.HasPrefix(holder, "md") {
+			err = run("mdadm", *tmpRoot, logger, "--stop", device)
+		} else {
+			err = fmt.Errorf("unknown holder: %s of: %s", holder, name)
+		}
+		if err != nil {
+			return err
+		}
+		logger.Printf("released %s from %s\n", device, name)
+	}
+	// Partitions are sub-directories named after the drive.
+	names, err := readDirnames(dirname)
+	if err != nil {
+		return err
+	}
+	for _, partition := range names {
+		if partition != name && strings.HasPrefix(partition, name) {
+			if err := releaseDevice(partition, logger); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readDirnames(dirname string) ([]string, error) {
+	file, err := os.Open(dirname)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	return file.Readdirnames(-1)
+}
+
+// configureVolumes builds the RAID arrays, volume groups and logical volumes
+// and makes the file-systems on them.
+func (plan *storagePlan) configureVolumes(cpuSharer cpusharer.CpuSharer,
+	layout installer_proto.StorageLayout, logger log.DebugLogger) error {
+	if len(plan.arrays) < 1 && len(plan.volumeGroups) < 1 {
+		return nil
+	}
+	for _, array := range plan.arrays {
+		members := make([]string, 0, len(array.members))
+		for _, drive := range array.members {
+			if err := drive.erase(logger); err != nil {
+				return err
+			}
+			members = append(members, drive.devpath)
+		}
+		err := makeRaidArray(array.device, array.config.Level, "1.2", members,
+			logger)
+		if err != nil {
+			return err
+		}
+		array.drive = mergeDrives(array.members, array.config.Name)
+	}
+	for _, group := range plan.volumeGroups {
+		for _, drive := range group.drives {
+			if err := drive.erase(logger); err != nil {
+				return err
+			}
+		}
+		drives := append([]*driveType(nil), group.drives...)
+		for _, array := range plan.arrays {
+			if array.config.VolumeGroup == group.config.Name {
+				drives = append(drives, array.drive)
+			}
+		}
+		group.drive = mergeDrives(drives, group.config.Name)
+		if err := group.create(logger); err != nil {
+			return err
+		}
+	}
+	// Make all file-systems concurrently.
+	concurrentState := concurrent.NewState(0)
+	var mkfsMutex sync.Mutex
+	for _, array := range plan.arrays {
+		if array.config.VolumeGroup != "" {
+			continue
+		}
+		array := array
+		err := concurrentState.GoRun(func() error {
+			return array.drive.makeFileSystem(cpuSharer, array.device,
+				array.config.MountPoint, array.config.FileSystemType,
+				layout.Encrypt, &mkfsMutex, 1048576, logger)
+		})
+		if err != nil {
+			break
+		}
+	}
+	for _, group := range plan.volumeGroups {
+		for _, logicalVolume := range group.config.LogicalVolumes {
+			group := group
+			logicalVolume := logicalVolume
+			err := concurrentState.GoRun(func() error {
+				return group.drive.makeFileSystem(cpuSharer,
+					group.logicalVolumeDevice(logicalVolume),
+					logicalVolume.MountPoint, logicalVolume.FileSystemType,
+					layout.Encrypt, &mkfsMutex, 1048576, logger)
+			})
+			if err != nil {
+				break
+			}
+		}
+	}
+	return concurrentState.Reap()
+}
+
+// writeVolumeEntries writes the table entries for the file-systems on the
+// RAID arrays and logical volumes.
+func (plan *storagePlan) writeVolumeEntries(fsTab, cryptTab io.Writer) error {
+	for _, array := range plan.arrays {
+		if array.config.VolumeGroup != "" {
+			continue
+		}
+		err := array.drive.writeDeviceEntries(array.device,
+			array.config.MountPoint, array.config.FileSystemType, fsTab,
+			cryptTab, 2)
+		if err != nil {
+			return err
+		}
+	}
+	for _, group := range plan.volumeGroups {
+		for _, logicalVolume := range group.config.LogicalVolumes {
+			err := group.drive.writeDeviceEntries(
+				group.logicalVolumeDevice(logicalVolume),
+				logicalVolume.MountPoint, logicalVolume.FileSystemType, fsTab,
+				cryptTab, 2)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// create makes the physical volumes, volume group and logical volumes. Any
+// previous LVM metadata on the physical volumes is overwritten.
+func (group *volumeGroupType) create(logger log.DebugLogger) error {
+	args := append([]string{"-ff", "-y"}, group.pvs...)
+	if err := run("pvcreate", *tmpRoot, logger, args...); err != nil {
+		return err
+	}
+	args = append([]string{group.config.Name}, group.pvs...)
+	if err := run("vgcreate", *tmpRoot, logger, args...); err != nil {
+		return err
+	}
+	for _, logicalVolume := range group.config.LogicalVolumes {
+		args := []string{"-y", "-W", "y", "-n", logicalVolume.Name}
+		if logicalVolume.SizeBytes > 0 {
+			args = append(args, "-L",
+				strconv.FormatUint(logicalVolume.SizeBytes, 10)+"b")
+		} else {
+			args = append(args, "-l", "100%FREE")
+		}
+		args = append(args, group.config.Name)
+		if err := run("lvcreate", *tmpRoot, logger, args...); err != nil {
+			return err
+		}
+	}
+	logger.Printf("created volume group: %s from: %s\n",
+		group.config.Name, strings.Join(group.pvs, ","))
+	return nil
+}
+
+func (group *volumeGroupType) logicalVolumeDevice(
+	logicalVolume installer_proto.LogicalVolume) string {
+	return filepath.Join("/dev", group.config.Name, logicalVolume.Name)
+}
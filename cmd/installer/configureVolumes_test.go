@@ -0,0 +1,262 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	installer_proto "github.com/Cloud-Foundations/Dominator/proto/installer"
+)
+
+const (
+	ext4 = installer_proto.FileSystemTypeExt4
+	xfs  = installer_proto.FileSystemTypeXfs
+)
+
+type raidArrays []installer_proto.RaidArray
+
+func makeTestDrives(numDrives int) []*driveType {
+	drives := make([]*driveType, 0, numDrives)
+	for index := 0; index < numDrives; index++ {
+		drives = append(drives, &driveType{
+			devpath: "/dev/testdrive" + strconv.Itoa(index),
+			name:    "testdrive" + strconv.Itoa(index),
+		})
+	}
+	return drives
+}
+
+func driveNames(drives []*driveType) []string {
+	names := make([]string, 0, len(drives))
+	for _, drive := range drives {
+		names = append(names, drive.name)
+	}
+	return names
+}
+
+func checkNames(t *testing.T, what string, got, want []string) {
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got: %v, want: %v", what, got, want)
+	}
+}
+
+func TestBootDevices(t *testing.T) {
+	tests := []struct {
+		name      string
+		numDrives int
+		isEfi     bool
+		want      []string
+	}{
+		{"single", 1, false,
+			[]string{"/dev/testdrive01", "/dev/testdrive02",
+				"/dev/testdrive03"}},
+		{"singleEfi", 1, true,
+			[]string{"/dev/testdrive01", "/dev/testdrive02",
+				"/dev/testdrive03"}},
+		{"mirrored", 2, false,
+			[]string{"/dev/md/boot1", "/dev/md/boot2", "/dev/md/boot3"}},
+		{"mirroredEfi", 2, true,
+			[]string{"/dev/testdrive01", "/dev/md/boot2", "/dev/md/boot3"}},
+	}
+	for _, test := range tests {
+		got := bootDevices(makeTestDrives(test.numDrives), 3, test.isEfi)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got: %v, want: %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEspDevices(t *testing.T) {
+	got := espDevices(makeTestDrives(3))
+	want := []string{"/dev/testdrive01", "/dev/testdrive11",
+		"/dev/testdrive21"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestMakeStoragePlan(t *testing.T) {
+	layout := installer_proto.StorageLayout{
+		BootDriveRaidLevel:       installer_proto.RaidLevel1,
+		ExtraMountPointsBasename: "/data/",
+		NumBootDrives:            2,
+		RaidArrays: []installer_proto.RaidArray{
+			{
+				Level:       installer_proto.RaidLevel10,
+				Name:        "data",
+				NumDrives:   4,
+				VolumeGroup: "vg0",
+			},
+			{
+				FileSystemType: xfs,
+				Level:          installer_proto.RaidLevel1,
+				MountPoint:     "/scratch",
+				Name:           "scratch",
+				NumDrives:      2,
+			},
+		},
+		VolumeGroups: []installer_proto.VolumeGroup{
+			{
+				LogicalVolumes: []installer_proto.LogicalVolume{
+					{
+						FileSystemType: xfs,
+						MountPoint:     "/data/big",
+						Name:           "big",
+					},
+				},
+				Name:      "vg0",
+				NumDrives: 1,
+			},
+		},
+	}
+	plan, err := makeStoragePlan(layout, makeTestDrives(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNames(t, "boot drives", driveNames(plan.bootDrives),
+		[]string{"testdrive0", "testdrive1"})
+	if len(plan.arrays) != 2 {
+		t.Fatalf("got %d arrays, want 2", len(plan.arrays))
+	}
+	checkNames(t, "data array", driveNames(plan.arrays[0].members),
+		[]string{"testdrive2", "testdrive3", "testdrive4", "testdrive5"})
+	checkNames(t, "scratch array", driveNames(plan.arrays[1].members),
+		[]string{"testdrive6", "testdrive7"})
+	if len(plan.volumeGroups) != 1 {
+		t.Fatalf("got %d volume groups, want 1", len(plan.volumeGroups))
+	}
+	checkNames(t, "physical volumes", plan.volumeGroups[0].pvs,
+		[]string{"/dev/md/data", "/dev/testdrive8"})
+	checkNames(t, "data drives", driveNames(plan.dataDrives),
+		[]string{"testdrive9", "testdrive10"})
+}
+
+func TestMakeStoragePlanDefault(t *testing.T) {
+	plan, err := makeStoragePlan(installer_proto.StorageLayout{},
+		makeTestDrives(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNames(t, "boot drives", driveNames(plan.bootDrives),
+		[]string{"testdrive0"})
+	checkNames(t, "data drives", driveNames(plan.dataDrives),
+		[]string{"testdrive1", "testdrive2"})
+}
+
+func TestMakeStoragePlanErrors(t *testing.T) {
+	array := func(name string, level, numDrives uint,
+		volumeGroup string) installer_proto.RaidArray {
+		return installer_proto.RaidArray{
+			FileSystemType: ext4,
+			Level:          level,
+			MountPoint:     "/" + name,
+			Name:           name,
+			NumDrives:      numDrives,
+			VolumeGroup:    volumeGroup,
+		}
+	}
+	group := func(name string,
+		volumes ...installer_proto.LogicalVolume) installer_proto.VolumeGroup {
+		return installer_proto.VolumeGroup{
+			LogicalVolumes: volumes,
+			Name:           name,
+			NumDrives:      1,
+		}
+	}
+	volume := func(name string, size uint64) installer_proto.LogicalVolume {
+		return installer_proto.LogicalVolume{
+			FileSystemType: ext4,
+			MountPoint:     "/" + name,
+			Name:           name,
+			SizeBytes:      size,
+		}
+	}
+	tests := []struct {
+		name   string
+		layout installer_proto.StorageLayout
+		error  string
+	}{
+		{"bootRaidLevel",
+			installer_proto.StorageLayout{NumBootDrives: 2,
+				BootDriveRaidLevel: 5},
+			"unsupported RAID level: 5"},
+		{"bootRaidWithoutDrives",
+			installer_proto.StorageLayout{BootDriveRaidLevel: 1},
+			"BootDriveRaidLevel requires NumBootDrives > 1"},
+		{"arrayTooFewDrives",
+			installer_proto.StorageLayout{RaidArrays: raidArrays{
+				array("data", 1, 1, "")}},
+			"RAID1 requires at least 2 drives"},
+		{"xfsLabel",
+			installer_proto.StorageLayout{RaidArrays: raidArrays{{
+				FileSystemType: xfs,
+				Level:          1,
+				MountPoint:     "/a/very/long/path",
+				Name:           "data",
+				NumDrives:      2,
+			}}},
+			"too long for XFS label"},
+		{"extraXfsLabel",
+			installer_proto.StorageLayout{
+				ExtraFileSystemType:      xfs,
+				ExtraMountPointsBasename: "/a/very/long/data/",
+			},
+			"too long for XFS label"},
+		{"emptyName",
+			installer_proto.StorageLayout{RaidArrays: raidArrays{
+				array("", 1, 2, "")}},
+			"bad name"},
+		{"slashName",
+			installer_proto.StorageLayout{RaidArrays: raidArrays{
+				array("a/b", 1, 2, "")}},
+			"bad name"},
+		{"bootName",
+			installer_proto.StorageLayout{RaidArrays: raidArrays{
+				array("boot1", 1, 2, "")}},
+			"bad name"},
+		{"controlName",
+			installer_proto.StorageLayout{
+				VolumeGroups: []installer_proto.VolumeGroup{
+					group("control", volume("lv", 0))}},
+			"bad name"},
+		{"duplicateName",
+			installer_proto.StorageLayout{
+				RaidArrays: []installer_proto.RaidArray{
+					array("data", 1, 2, "")},
+				VolumeGroups: []installer_proto.VolumeGroup{
+					group("data", volume("lv", 0))}},
+			"duplicate name: data"},
+		{"noLogicalVolumes",
+			installer_proto.StorageLayout{
+				VolumeGroups: []installer_proto.VolumeGroup{group("vg0")}},
+			"has no logical volumes"},
+		{"unsizedNotLast",
+			installer_proto.StorageLayout{
+				VolumeGroups: []installer_proto.VolumeGroup{
+					group("vg0", volume("a", 0), volume("b", 1<<30))}},
+			"without size must be last"},
+		{"unknownVolumeGroup",
+			installer_proto.StorageLayout{RaidArrays: raidArrays{
+				array("data", 1, 2, "vg1")}},
+			"unknown volume group: vg1"},
+		{"tooFewDrives",
+			installer_proto.StorageLayout{
+				NumBootDrives:      2,
+				BootDriveRaidLevel: 1,
+				RaidArrays: []installer_proto.RaidArray{
+					array("data", 10, 4, "")}},
+			"layout requires 6 drives, have: 4"},
+	}
+	for _, test := range tests {
+		_, err := makeStoragePlan(test.layout, makeTestDrives(4))
+		if err == nil {
+			t.Errorf("%s: no error, want: %s", test.name, test.error)
+		} else if !strings.Contains(err.Error(), test.error) {
+			t.Errorf("%s: error: %s, want: %s", test.name, err, test.error)
+		}
+	}
+}
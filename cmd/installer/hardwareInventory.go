@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	fm_client "github.com/Cloud-Foundations/Dominator/fleetmanager/client"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/hardware"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const inventoryFilename = "/var/log/installer/hardware-inventory.json"
+
+func showHardwareInventorySubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := showHardwareInventory(logger); err != nil {
+		return fmt.Errorf("error showing hardware inventory: %s", err)
+	}
+	return nil
+}
+
+func copyHardwareInventory() error {
+	if _, err := os.Stat(inventoryFilename); err != nil {
+		return nil
+	}
+	return fsutil.CopyFile(filepath.Join(*mountPoint, inventoryFilename),
+		inventoryFilename, fsutil.PublicFilePerms)
+}
+
+// recordHardwareInventory will collect the hardware inventory, log any
+// mismatches with the machine configuration, save the inventory so that it is
+// copied to the installed system and report it to the Fleet Manager (if its
+// address was provided by the TFTP server).
+func recordHardwareInventory(machineInfo *fm_proto.GetMachineInfoResponse,
+	logger log.DebugLogger) error {
+	inventory, err := hardware.GetInventory(logger)
+	if err != nil {
+		return err
+	}
+	logger.Printf("hardware: %s %s, CPU: %s, %d DIMMs, %d disks, %d NICs\n",
+		inventory.DMI.SystemVendor, inventory.DMI.ProductName,
+		inventory.CPU.ModelName, len(inventory.DIMMs), len(inventory.Disks),
+		len(inventory.NICs))
+	for _, mismatch := range machineInfo.Machine.CheckHardwareInventory(
+		inventory) {
+		logger.Printf("hardware mismatch: %s\n", mismatch)
+	}
+	err = json.WriteToFile(inventoryFilename, fsutil.PublicFilePerms, "    ",
+		inventory)
+	if err != nil {
+		return err
+	}
+	return reportHardwareInventory(machineInfo.Machine.Hostname, inventory,
+		logger)
+}
+
+// reportHardwareInventory will send the inventory to the Fleet Manager. The
+// Fleet Manager checks that the connection comes from the machine.
+func reportHardwareInventory(hostname string,
+	inventory *hyper_proto.HardwareInventory, logger log.DebugLogger) error {
+	fleetManagerAddress, err := readString(
+		filepath.Join(*tftpDirectory, "fleetmanager"), true)
+	if err != nil {
+		return err
+	}
+	if fleetManagerAddress == "" {
+		return nil
+	}
+	logger.Printf("reporting hardware inventory to: %s\n", fleetManagerAddress)
+	client, err := srpc.DialHTTP("tcp", fleetManagerAddress, time.Second*15)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = fm_client.ReportHardwareInventory(client, hostname, inventory)
+	return err
+}
+
+func showHardwareInventory(logger log.DebugLogger) error {
+	inventory, err := hardware.GetInventory(logger)
+	if err != nil {
+		return err
+	}
+	return json.WriteWithIndent(os.Stdout, "    ", inventory)
+}
@@ -113,13 +113,18 @@ var subcommands = []commands.Command{
 	{"load-configuration-from-tftp", "", 0, 0,
 		loadConfigurationFromTftpSubcommand},
 	{"load-image", "image-name root-dir", 2, 2, loadImageSubcommand},
+	{"show-hardware-inventory", "", 0, 0, showHardwareInventorySubcommand},
 }
 
 func copyLogs(logFlusher flusher) error {
 	logFlusher.Flush()
 	logdir := filepath.Join(*mountPoint, "var", "log", "installer")
-	return fsutil.CopyFile(filepath.Join(logdir, "log"), logfile,
+	err := fsutil.CopyFile(filepath.Join(logdir, "log"), logfile,
 		fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	return copyHardwareInventory()
 }
 
 func createLogger() (*logbuf.LogBuffer, log.DebugLogger, error) {
@@ -150,6 +155,9 @@ func install(updateHwClock bool, logFlusher flusher,
 	if err != nil {
 		return nil, err
 	}
+	if err := recordHardwareInventory(machineInfo, logger); err != nil {
+		logger.Printf("Error recording hardware inventory: %s\n", err)
+	}
 	if !*skipStorage {
 		rebooter, err = configureStorage(*machineInfo, logger)
 		if err != nil {
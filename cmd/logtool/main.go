@@ -8,6 +8,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/flags/commands"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
 	"github.com/Cloud-Foundations/Dominator/lib/log/cmdlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupclient"
@@ -22,8 +23,14 @@ var (
 		"Hostname of log server")
 	loggerName    = flag.String("loggerName", "", "Name of logger")
 	loggerPortNum = flag.Uint("loggerPortNum", 0, "Port number of log server")
+	matchFields   flagutil.StringList
 )
 
+func init() {
+	flag.Var(&matchFields, "matchFields",
+		"Comma separated list of name=value fields to filter for when watching")
+}
+
 func printUsage() {
 	w := flag.CommandLine.Output()
 	fmt.Fprintln(w,
@@ -18,19 +18,40 @@ func watchSubcommand(args []string, logger log.DebugLogger) error {
 	if err != nil {
 		return fmt.Errorf("error parsing level: %s", err)
 	}
+	fields, err := parseFields(matchFields)
+	if err != nil {
+		return err
+	}
 	clients, addrs, err := dial(true)
 	if err != nil {
 		return err
 	}
-	if err := watchAll(clients, addrs, int16(level)); err != nil {
+	if err := watchAll(clients, addrs, int16(level), fields); err != nil {
 		return fmt.Errorf("error watching: %s", err)
 	}
 	return nil
 }
 
-func watchAll(clients []*srpc.Client, addrs []string, level int16) error {
+func parseFields(list []string) (map[string]string, error) {
+	if len(list) < 1 {
+		return nil, nil
+	}
+	fields := make(map[string]string, len(list))
+	for _, entry := range list {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("bad field (expected name=value): %s",
+				entry)
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+func watchAll(clients []*srpc.Client, addrs []string, level int16,
+	fields map[string]string) error {
 	if len(clients) == 1 {
-		return watchOne(clients[0], level, "")
+		return watchOne(clients[0], level, fields, "")
 	}
 	maxWidth := 0
 	for _, addr := range addrs {
@@ -45,7 +66,7 @@ func watchAll(clients []*srpc.Client, addrs []string, level int16) error {
 			prefix += strings.Repeat(" ", maxWidth-len(prefix))
 		}
 		go func(client *srpc.Client, level int16, prefix string) {
-			errors <- watchOne(client, level, prefix)
+			errors <- watchOne(client, level, fields, prefix)
 		}(client, level, prefix)
 	}
 	for range clients {
@@ -56,9 +77,11 @@ func watchAll(clients []*srpc.Client, addrs []string, level int16) error {
 	return nil
 }
 
-func watchOne(client *srpc.Client, level int16, prefix string) error {
+func watchOne(client *srpc.Client, level int16, fields map[string]string,
+	prefix string) error {
 	request := proto.WatchRequest{
 		ExcludeRegex: *excludeRegex,
+		Fields:       fields,
 		IncludeRegex: *includeRegex,
 		Name:         *loggerName,
 		DebugLevel:   level,
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filegen/secrets"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func addSubcommand(args []string, logger log.DebugLogger) error {
+	if err := addSecret(args[0], args[1], logger); err != nil {
+		return fmt.Errorf("error adding secret: %s", err)
+	}
+	return nil
+}
+
+func addSecret(name, filename string, logger log.DebugLogger) error {
+	data, err := readData(filename)
+	if err != nil {
+		return err
+	}
+	store, err := secrets.Open(*storeDirectory, "")
+	if err != nil {
+		return err
+	}
+	if err := store.Add(name, data); err != nil {
+		return err
+	}
+	logger.Printf("Added secret: %s\n", name)
+	return nil
+}
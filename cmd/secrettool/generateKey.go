@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filegen/secrets"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func generateKeySubcommand(args []string, logger log.DebugLogger) error {
+	err := secrets.GenerateKey(*storeDirectory, *privateKeyFile)
+	if err != nil {
+		return fmt.Errorf("error generating key: %s", err)
+	}
+	logger.Printf("Wrote private key to: %s\n", *privateKeyFile)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filegen/secrets"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func listSubcommand(args []string, logger log.DebugLogger) error {
+	if err := listSecrets(); err != nil {
+		return fmt.Errorf("error listing secrets: %s", err)
+	}
+	return nil
+}
+
+func listSecrets() error {
+	store, err := secrets.Open(*storeDirectory, "")
+	if err != nil {
+		return err
+	}
+	secretInfos, err := store.List()
+	if err != nil {
+		return err
+	}
+	for _, secretInfo := range secretInfos {
+		if len(secretInfo.Versions) < 1 {
+			fmt.Fprintf(os.Stdout, "%s: no versions\n", secretInfo.Name)
+			continue
+		}
+		latest := secretInfo.Versions[len(secretInfo.Versions)-1]
+		fmt.Fprintf(os.Stdout, "%s: version %d (of %d) created %s\n",
+			secretInfo.Name, latest.Version, len(secretInfo.Versions),
+			latest.CreationTime.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/flags/commands"
+	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
+	"github.com/Cloud-Foundations/Dominator/lib/log/cmdlogger"
+)
+
+var (
+	privateKeyFile = flag.String("privateKeyFile",
+		"/var/lib/filegen-server/secrets.key",
+		"Name of file containing the private key for the secrets store")
+	storeDirectory = flag.String("storeDirectory",
+		"/var/lib/filegen-server/secrets",
+		"Name of directory containing the encrypted secrets store")
+)
+
+func printUsage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w,
+		"Usage: secrettool [flags...] add|generate-key|list|rotate [args...]")
+	fmt.Fprintln(w, "Common flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(w, "Commands:")
+	commands.PrintCommands(w, subcommands)
+}
+
+var subcommands = []commands.Command{
+	{"add", "          name file", 2, 2, addSubcommand},
+	{"generate-key", "", 0, 0, generateKeySubcommand},
+	{"list", "", 0, 0, listSubcommand},
+	{"rotate", "       name file", 2, 2, rotateSubcommand},
+}
+
+func doMain() int {
+	if err := loadflags.LoadForCli("secrettool"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		return 2
+	}
+	logger := cmdlogger.New()
+	return commands.RunCommands(subcommands, printUsage, logger)
+}
+
+func main() {
+	os.Exit(doMain())
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filegen/secrets"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+// readData reads the secret data from filename, or from stdin if filename is
+// "-".
+func readData(filename string) ([]byte, error) {
+	if filename == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(filename)
+}
+
+func rotateSubcommand(args []string, logger log.DebugLogger) error {
+	if err := rotateSecret(args[0], args[1], logger); err != nil {
+		return fmt.Errorf("error rotating secret: %s", err)
+	}
+	return nil
+}
+
+func rotateSecret(name, filename string, logger log.DebugLogger) error {
+	data, err := readData(filename)
+	if err != nil {
+		return err
+	}
+	store, err := secrets.Open(*storeDirectory, "")
+	if err != nil {
+		return err
+	}
+	version, err := store.Rotate(name, data)
+	if err != nil {
+		return err
+	}
+	logger.Printf("Rotated secret: %s to version: %d\n", name, version)
+	return nil
+}
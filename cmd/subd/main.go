@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/cpulimiter"
@@ -48,6 +49,10 @@ var (
 		"Scan speed as percentage of capacity (default 2)")
 	disruptionManager = flag.String("disruptionManager", "",
 		"Path to DisruptionManager tool")
+	eventDrivenScanning = flag.Bool("eventDrivenScanning", false,
+		"If true, use file-system events (fanotify) to rescan only changed directories between full scans")
+	fullScanInterval = flag.Duration("fullScanInterval", time.Hour,
+		"Interval between full verification scans when event-driven scanning")
 	maxThreads = flag.Uint("maxThreads", 1,
 		"Maximum number of parallel OS threads to use")
 	noteGenerator = flag.String("noteGenerator", "",
@@ -333,6 +338,8 @@ func main() {
 	var configuration scanner.Configuration
 	configuration.CpuLimiter = cpulimiter.New(100)
 	configuration.DefaultCpuPercent = configParams.CpuPercent
+	configuration.EventDrivenScanning = *eventDrivenScanning
+	configuration.FullScanInterval = *fullScanInterval
 	// Apply built-in defaults if nothing specified.
 	if configuration.DefaultCpuPercent < 1 {
 		configuration.DefaultCpuPercent = constants.DefaultCpuPercent
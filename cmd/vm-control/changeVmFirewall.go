@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func changeVmFirewallSubcommand(args []string, logger log.DebugLogger) error {
+	if err := changeVmFirewall(args[0], logger); err != nil {
+		return fmt.Errorf("error changing VM firewall policy: %s", err)
+	}
+	return nil
+}
+
+func changeVmFirewall(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return changeVmFirewallOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func changeVmFirewallOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	var policy *hyper_proto.FirewallPolicy
+	if *firewallPolicyFile != "" {
+		var err error
+		if policy, err = readFirewallPolicy(*firewallPolicyFile); err != nil {
+			return err
+		}
+	}
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return hyperclient.ChangeVmFirewallPolicy(client, ipAddr, policy)
+}
+
+func readFirewallPolicy(filename string) (*hyper_proto.FirewallPolicy, error) {
+	var policy hyper_proto.FirewallPolicy
+	if err := json.ReadFromFile(filename, &policy); err != nil {
+		return nil, err
+	}
+	if err := policy.CheckValid(); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return &policy, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func changeVmThrottlesSubcommand(args []string, logger log.DebugLogger) error {
+	if err := changeVmThrottles(args[0], logger); err != nil {
+		return fmt.Errorf("error changing VM throttles: %s", err)
+	}
+	return nil
+}
+
+func changeVmThrottles(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return changeVmThrottlesOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func changeVmThrottlesOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	request := hyper_proto.ChangeVmThrottlesRequest{
+		IpAddress:        ipAddr,
+		NetworkThrottles: makeNetworkThrottles(),
+		VolumeThrottles:  makeVolumeThrottles(),
+	}
+	if len(request.NetworkThrottles) < 1 && len(request.VolumeThrottles) < 1 {
+		return errors.New("no throttles specified")
+	}
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return hyperclient.ChangeVmThrottles(client, request)
+}
+
+// makeNetworkThrottles returns the network throttles specified by the
+// -networkReceiveRates and -networkTransmitRates flags.
+func makeNetworkThrottles() []hyper_proto.NetworkThrottle {
+	length := len(networkReceiveRates)
+	if len(networkTransmitRates) > length {
+		length = len(networkTransmitRates)
+	}
+	if length < 1 {
+		return nil
+	}
+	throttles := make([]hyper_proto.NetworkThrottle, length)
+	for index, rate := range networkReceiveRates {
+		throttles[index].ReceiveBytesPerSecond = uint64(rate)
+	}
+	for index, rate := range networkTransmitRates {
+		throttles[index].TransmitBytesPerSecond = uint64(rate)
+	}
+	return throttles
+}
+
+// makeVolumeThrottles returns the volume throttles specified by the
+// -volumeIOPS and -volumeRates flags.
+func makeVolumeThrottles() []hyper_proto.VolumeThrottle {
+	length := len(volumeIOPS)
+	if len(volumeRates) > length {
+		length = len(volumeRates)
+	}
+	if length < 1 {
+		return nil
+	}
+	throttles := make([]hyper_proto.VolumeThrottle, length)
+	for index, iops := range volumeIOPS {
+		throttles[index].IOPS = uint64(iops)
+	}
+	for index, rate := range volumeRates {
+		throttles[index].BytesPerSecond = uint64(rate)
+	}
+	return throttles
+}
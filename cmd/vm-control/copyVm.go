@@ -72,6 +72,14 @@ func copyVmFromHypervisor(sourceHypervisorAddress string, vmIP net.IP,
 	vmInfo.ConsoleType = sourceVmInfo.ConsoleType
 	vmInfo.DestroyProtection = vmInfo.DestroyProtection ||
 		sourceVmInfo.DestroyProtection
+	if *firewallPolicyFile == "" {
+		vmInfo.FirewallPolicy = sourceVmInfo.FirewallPolicy
+	} else {
+		vmInfo.FirewallPolicy, err = readFirewallPolicy(*firewallPolicyFile)
+		if err != nil {
+			return err
+		}
+	}
 	if vmInfo.Hostname == "" {
 		vmInfo.Hostname = sourceVmInfo.Hostname
 	}
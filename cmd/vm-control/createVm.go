@@ -208,13 +208,19 @@ func createVmInfoFromFlags() hyper_proto.VmInfo {
 	if len(volumeTypes) > 0 {
 		volumeType = volumeTypes[0]
 	}
+	var volumeThrottle hyper_proto.VolumeThrottle
+	if volumeThrottles := makeVolumeThrottles(); len(volumeThrottles) > 0 {
+		volumeThrottle = volumeThrottles[0]
+	}
 	if volumeFormat != hyper_proto.VolumeFormatRaw ||
 		volumeInterface != hyper_proto.VolumeInterfaceVirtIO ||
+		volumeThrottle != (hyper_proto.VolumeThrottle{}) ||
 		volumeType != hyper_proto.VolumeTypePersistent {
 		// If any provided, set for root volume. Secondaries are done later.
 		volumes = append(volumes, hyper_proto.Volume{
 			Format:    volumeFormat,
 			Interface: volumeInterface,
+			Throttle:  volumeThrottle,
 			Type:      volumeType,
 		})
 	}
@@ -225,17 +231,21 @@ func createVmInfoFromFlags() hyper_proto.VmInfo {
 		DestroyProtection:  *destroyProtection,
 		DisableVirtIO:      *disableVirtIO,
 		ExtraKernelOptions: *extraKernelOptions,
+		FirmwareType:       firmwareType,
 		Hostname:           *vmHostname,
 		MachineType:        machineType,
 		MemoryInMiB:        uint64(memory >> 20),
 		MilliCPUs:          *milliCPUs,
+		NetworkThrottles:   makeNetworkThrottles(),
 		OwnerGroups:        ownerGroups,
 		OwnerUsers:         ownerUsers,
+		PartitionTableType: partitionTableType,
 		Tags:               vmTags,
 		SecondarySubnetIDs: secondarySubnetIDs,
 		SpreadVolumes:      *spreadVolumes,
 		SubnetId:           *subnetId,
 		VirtualCPUs:        *virtualCPUs,
+		VirtualTPM:         *virtualTPM,
 		Volumes:            volumes,
 		WatchdogAction:     watchdogAction,
 		WatchdogModel:      watchdogModel,
@@ -244,6 +254,13 @@ func createVmInfoFromFlags() hyper_proto.VmInfo {
 
 func createVmOnHypervisor(hypervisor string,
 	request hyper_proto.CreateVmRequest, logger log.DebugLogger) error {
+	if *firewallPolicyFile != "" {
+		policy, err := readFirewallPolicy(*firewallPolicyFile)
+		if err != nil {
+			return err
+		}
+		request.FirewallPolicy = policy
+	}
 	secondaryFstab := &bytes.Buffer{}
 	var vinitParams []volumeInitParams
 	if *secondaryVolumesInitParams == "" {
@@ -254,11 +271,15 @@ func createVmOnHypervisor(hypervisor string,
 			return err
 		}
 	}
+	volumeThrottles := makeVolumeThrottles()
 	for index, size := range secondaryVolumeSizes {
 		volume := hyper_proto.Volume{Size: uint64(size)}
 		if index+1 < len(volumeInterfaces) {
 			volume.Interface = volumeInterfaces[index+1]
 		}
+		if index+1 < len(volumeThrottles) {
+			volume.Throttle = volumeThrottles[index+1]
+		}
 		if index+1 < len(volumeTypes) {
 			volume.Type = volumeTypes[index+1]
 		}
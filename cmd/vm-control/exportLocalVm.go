@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"time"
 
 	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
@@ -62,6 +66,73 @@ func (exporter vmExporterExec) destroyVm(hostname string) error {
 	return cmd.Run()
 }
 
+// convertExportVolumes converts the volumes to the format specified by the
+// diskImageFormat flag, writing the images alongside the volumes. The volume
+// locations in vmInfo are updated and the names of the new files are returned.
+func convertExportVolumes(vmInfo *proto.ExportLocalVmInfo,
+	logger log.DebugLogger) ([]string, error) {
+	if diskImageFormat == diskimage.FormatRaw {
+		return nil, nil
+	}
+	var filenames []string
+	for index, volume := range vmInfo.Volumes {
+		if volume.Format != proto.VolumeFormatRaw {
+			removeFiles(filenames)
+			return nil, fmt.Errorf("volume: %d is not in RAW format", index)
+		}
+		location := &vmInfo.VolumeLocations[index]
+		filename := location.Filename + diskImageFormat.Extension()
+		err := convertExportVolume(location.Filename, filename, logger)
+		if err != nil {
+			removeFiles(filenames)
+			return nil, err
+		}
+		filenames = append(filenames, filename)
+		location.Filename = filename
+	}
+	return filenames, nil
+}
+
+func convertExportVolume(rawFilename, filename string,
+	logger log.DebugLogger) error {
+	rawFile, err := os.Open(rawFilename)
+	if err != nil {
+		return err
+	}
+	defer rawFile.Close()
+	fi, err := rawFile.Stat()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
+		fsutil.PrivateFilePerms)
+	if err != nil {
+		return err
+	}
+	startTime := time.Now()
+	nWritten, err := diskimage.Write(file, rawFile, uint64(fi.Size()),
+		diskImageFormat, diskimage.WriteOptions{Compress: *compressDiskImage})
+	if err != nil {
+		file.Close()
+		os.Remove(filename)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(filename)
+		return err
+	}
+	logger.Debugf(0, "wrote %s B %s image: %s in %s\n",
+		format.FormatBytes(nWritten), diskImageFormat, filename,
+		format.Duration(time.Since(startTime)))
+	return nil
+}
+
+func removeFiles(filenames []string) {
+	for _, filename := range filenames {
+		os.Remove(filename)
+	}
+}
+
 func vmExport(vmHostname string, exporter vmExporter,
 	logger log.DebugLogger) error {
 	vmIpAddr, err := lookupIP(vmHostname)
@@ -93,6 +164,12 @@ func vmExport(vmHostname string, exporter vmExporter,
 	if err != nil {
 		return err
 	}
+	// Converted volumes must be copied or linked by the exporter.
+	convertedFilenames, err := convertExportVolumes(&vmInfo, logger)
+	if err != nil {
+		return err
+	}
+	defer removeFiles(convertedFilenames)
 	if err := exporter.createVm(vmHostname, vmInfo); err != nil {
 		return err
 	}
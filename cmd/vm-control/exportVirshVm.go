@@ -10,6 +10,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
@@ -143,6 +144,19 @@ func addressToInterface(address proto.Address, bridge string) interfaceType {
 	}
 }
 
+// getVirshDriverType returns the libvirt driver type for a volume, taking
+// account of any conversion to the format specified by the diskImageFormat flag.
+func getVirshDriverType(volume proto.Volume) string {
+	switch diskImageFormat {
+	case diskimage.FormatRaw:
+		return volume.Format.String()
+	case diskimage.FormatVHD, diskimage.FormatVHDDynamic:
+		return "vpc"
+	default:
+		return diskImageFormat.String()
+	}
+}
+
 func makeVolume(volume proto.Volume, index int,
 	filename string) (volumeType, error) {
 	dirname := filepath.Dir(filename)
@@ -160,7 +174,7 @@ func makeVolume(volume proto.Volume, index int,
 		Device: "disk",
 		Driver: driverType{
 			Name:  "qemu",
-			Type:  volume.Format.String(),
+			Type:  getVirshDriverType(volume),
 			Cache: "none",
 			Io:    "native",
 		},
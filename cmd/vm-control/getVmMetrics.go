@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func getVmMetricsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := getVmMetrics(args[0], logger); err != nil {
+		return fmt.Errorf("error getting VM metrics: %s", err)
+	}
+	return nil
+}
+
+func getVmMetrics(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return getVmMetricsOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func getVmMetricsOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	samples, err := hyperclient.GetVmMetrics(client, ipAddr, *maximumSamples)
+	if err != nil {
+		return err
+	}
+	return json.WriteWithIndent(os.Stdout, "    ", samples)
+}
@@ -37,5 +37,6 @@ func getVmVolumeOnHypervisor(hypervisor string, ipAddr net.IP,
 		return fmt.Errorf("volumeIndex too large")
 	}
 	return copyVolumeToVmSaver(&directorySaver{filename: *volumeFilename},
-		client, ipAddr, *volumeIndex, vmInfo.Volumes[*volumeIndex].Size, logger)
+		client, ipAddr, *volumeIndex, vmInfo.Volumes[*volumeIndex].Size, false,
+		logger)
 }
@@ -124,7 +124,15 @@ func importLocalVmInfo(vmInfo proto.VmInfo, rootVolume string,
 			return err
 		}
 	}
+	extraFilenames := make(map[string]string)
+	if *nvramFile != "" {
+		extraFilenames["nvram"] = *nvramFile
+	}
+	if *tpmStateFile != "" {
+		extraFilenames["tpm-state"] = *tpmStateFile
+	}
 	request := proto.ImportLocalVmRequest{
+		ExtraFilenames:     extraFilenames,
 		SkipMemoryCheck:    *skipMemoryCheck,
 		VerificationCookie: rootCookie,
 		VmInfo:             vmInfo,
@@ -11,8 +11,10 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/flags/commands"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
 	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/log/cmdlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/mbr"
 	"github.com/Cloud-Foundations/Dominator/lib/net/rrdialer"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupclient"
@@ -23,6 +25,8 @@ import (
 var (
 	adjacentVM = flag.String("adjacentVM", "",
 		"IP address of VM adjacent (same Hypervisor) to VM being created")
+	compressDiskImage = flag.Bool("compressDiskImage", false,
+		"If true, compress QCOW2 volume images when getting/saving VM")
 	consoleType hyper_proto.ConsoleType
 	cpuPriority = flag.Int("cpuPriority", 0,
 		"CPU priority (-20:+19) for VM process on Hypervisor")
@@ -32,7 +36,8 @@ var (
 		"If true, do not destroy running VM")
 	disableVirtIO = flag.Bool("disableVirtIO", false,
 		"If true, disable virtio drivers, reducing I/O performance")
-	dhcpTimeout = flag.Duration("dhcpTimeout", time.Minute,
+	diskImageFormat diskimage.Format
+	dhcpTimeout     = flag.Duration("dhcpTimeout", time.Minute,
 		"Time to wait before timing out on DHCP request from VM")
 	doNotStart = flag.Bool("doNotStart", false,
 		"If true, do not start VM when creating")
@@ -40,6 +45,9 @@ var (
 		"If true, enable boot from network for first boot")
 	extraKernelOptions = flag.String("extraKernelOptions", "",
 		"Extra options to pass to kernel")
+	firewallPolicyFile = flag.String("firewallPolicyFile", "",
+		"Name of JSON file containing firewall policy for VM")
+	firmwareType         hyper_proto.FirmwareType
 	fleetManagerHostname = flag.String("fleetManagerHostname", "",
 		"Hostname of Fleet Manager")
 	fleetManagerPortNum = flag.Uint("fleetManagerPortNum",
@@ -81,20 +89,27 @@ var (
 		"Command to destroy local VM when exporting. The VM name is given as the argument")
 	location = flag.String("location", "",
 		"Location to search for hypervisors")
-	machineType      hyper_proto.MachineType
+	machineType    hyper_proto.MachineType
+	maximumSamples = flag.Uint("maximumSamples", 0,
+		"Maximum number of metrics samples to show (0 for all)")
 	memory           flagutil.Size
 	milliCPUs        = flag.Uint("milliCPUs", 0, "milli CPUs (default 250)")
 	placement        placementType
 	placementCommand = flag.String("placementCommand", "",
 		"Command to make placement decisions when creating/copying/moving VM")
-	minFreeBytes     = flagutil.Size(256 << 20)
+	minFreeBytes         = flagutil.Size(256 << 20)
+	networkReceiveRates  flagutil.SizeList
+	networkTransmitRates flagutil.SizeList
+	nvramFile            = flag.String("nvramFile", "",
+		"Name of file containing UEFI NVRAM when importing a VM")
 	overlayDirectory = flag.String("overlayDirectory", "",
 		"Directory tree of files to overlay on top of the image")
 	overlayPrefix = flag.String("overlayPrefix", "/",
 		"Prefix to add to overlay filenames")
-	ownerGroups      flagutil.StringList
-	ownerUsers       flagutil.StringList
-	patchLogFilename = flag.String("patchLogFilename", "",
+	ownerGroups        flagutil.StringList
+	ownerUsers         flagutil.StringList
+	partitionTableType mbr.TableType
+	patchLogFilename   = flag.String("patchLogFilename", "",
 		"Name file to write VM patch log to")
 	probePortNum = flag.Uint("probePortNum", 0, "Port number on VM to probe")
 	probeTimeout = flag.Duration("probeTimeout", time.Minute*5,
@@ -124,12 +139,16 @@ var (
 	snapshotName     = flag.String("snapshotName", "", "Optional snapshot name")
 	snapshotRootOnly = flag.Bool("snapshotRootOnly", false,
 		"If true, snapshot only the root volume")
+	tpmStateFile = flag.String("tpmStateFile", "",
+		"Name of file containing virtual TPM state when importing a VM")
 	traceMetadata = flag.Bool("traceMetadata", false,
 		"If true, trace metadata calls until interrupted")
 	userDataFile = flag.String("userDataFile", "",
 		"Name file containing user-data accessible from the metadata server")
 	virtualCPUs = flag.Uint("vCPUs", 0,
 		"virtual CPUs (default rounds up milliCPUs)")
+	virtualTPM = flag.Bool("virtualTPM", false,
+		"If true, provide a virtual TPM to the VM")
 	vmHostname    = flag.String("vmHostname", "", "Hostname for VM")
 	vmTags        tags.Tags
 	vmTagsToMatch tags.MatchTags
@@ -142,6 +161,8 @@ var (
 		"Index of volume to get or delete")
 	volumeIndices    flagutil.UintList
 	volumeInterfaces volumeInterfaceList
+	volumeIOPS       flagutil.UintList
+	volumeRates      flagutil.SizeList
 	volumeSize       flagutil.Size
 	volumeTypes      volumeTypeList
 	watchdogAction   hyper_proto.WatchdogAction
@@ -154,6 +175,10 @@ var (
 func init() {
 	flag.Var(&consoleType, "consoleType",
 		"type of graphical console (default none)")
+	flag.Var(&diskImageFormat, "diskImageFormat",
+		"Disk image format for exported/saved VM volumes (default raw)")
+	flag.Var(&firmwareType, "firmwareType",
+		"Type of firmware to boot VM with (default bios)")
 	flag.Var(&hypervisorTagsToMatch, "hypervisorTagsToMatch",
 		"Tags to match when getting/listing or creating/copying/moving VMs")
 	flag.Var(&machineType, "machineType",
@@ -161,10 +186,16 @@ func init() {
 	flag.Var(&memory, "memory", "memory (default 1GiB)")
 	flag.Var(&minFreeBytes, "minFreeBytes",
 		"minimum number of free bytes in root volume")
+	flag.Var(&networkReceiveRates, "networkReceiveRates",
+		"Bandwidth limits (bytes/second) for traffic received by network interfaces")
+	flag.Var(&networkTransmitRates, "networkTransmitRates",
+		"Bandwidth limits (bytes/second) for traffic sent by network interfaces")
 	flag.Var(&placement, "placement",
 		"Placement choice when selecting Hypervisor to create/copy/move VM")
 	flag.Var(&ownerGroups, "ownerGroups", "Groups who own the VM")
 	flag.Var(&ownerUsers, "ownerUsers", "Extra users who own the VM")
+	flag.Var(&partitionTableType, "partitionTableType",
+		"Partition table type for root volume (gpt or msdos, default msdos)")
 	flag.Var(&requestIPs, "requestIPs", "Request specific IPs, if available")
 	flag.Var(&secondarySubnetIDs, "secondarySubnetIDs", "Secondary Subnet IDs")
 	flag.Var(&secondaryVolumeSizes, "secondaryVolumeSizes",
@@ -178,6 +209,10 @@ func init() {
 	flag.Var(&volumeIndices, "volumeIndices", "Index of volumes")
 	flag.Var(&volumeInterfaces, "volumeInterfaces",
 		"Interfaces (device type presented to VM) for volumes (default virtio)")
+	flag.Var(&volumeIOPS, "volumeIOPS",
+		"I/O operations per second limits for volumes")
+	flag.Var(&volumeRates, "volumeRates",
+		"Bandwidth limits (bytes/second) for volumes")
 	flag.Var(&volumeSize, "volumeSize", "New size of specified volume")
 	flag.Var(&volumeTypes, "volumeTypes",
 		"Types for volumes (default persistent)")
@@ -204,12 +239,14 @@ var subcommands = []commands.Command{
 	{"change-vm-cpus", "IPaddr", 1, 1, changeVmCPUsSubcommand},
 	{"change-vm-destroy-protection", "IPaddr", 1, 1,
 		changeVmDestroyProtectionSubcommand},
+	{"change-vm-firewall", "IPaddr", 1, 1, changeVmFirewallSubcommand},
 	{"change-vm-machine-type", "IPaddr", 1, 1, changeVmMachineTypeSubcommand},
 	{"change-vm-memory", "IPaddr", 1, 1, changeVmMemorySubcommand},
 	{"change-vm-owner-groups", "IPaddr", 1, 1, changeVmOwnerGroupsSubcommand},
 	{"change-vm-owner-users", "IPaddr", 1, 1, changeVmOwnerUsersSubcommand},
 	{"change-vm-subnet", "IPaddr", 1, 1, changeVmSubnetSubcommand},
 	{"change-vm-tags", "IPaddr", 1, 1, changeVmTagsSubcommand},
+	{"change-vm-throttles", "IPaddr", 1, 1, changeVmThrottlesSubcommand},
 	{"change-vm-vcpus", "IPaddr", 1, 1, changeVmVirtualCPUsSubcommand},
 	{"change-vm-volume-interfaces", "IPaddr", 1, 1,
 		changeVmVolumeInterfacesSubcommand},
@@ -232,6 +269,7 @@ var subcommands = []commands.Command{
 	{"get-vm-hypervisor", "IPaddr", 1, 1, getVmHypervisorSubcommand},
 	{"get-vm-info", "IPaddr", 1, 1, getVmInfoSubcommand},
 	{"get-vm-infos", "", 0, 0, getVmInfosSubcommand},
+	{"get-vm-metrics", "IPaddr", 1, 1, getVmMetricsSubcommand},
 	{"get-vm-user-data", "IPaddr", 1, 1, getVmUserDataSubcommand},
 	{"get-vm-volume", "IPaddr", 1, 1, getVmVolumeSubcommand},
 	{"import-local-vm", "info-file root-volume", 2, 2, importLocalVmSubcommand},
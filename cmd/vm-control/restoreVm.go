@@ -81,6 +81,27 @@ func decodeJsonFromVmRestorer(restorer vmRestorer, filename string,
 	}
 }
 
+// readFirmwareStateFromVmRestorer reads the firmware state files which were
+// saved. The keys are the extra file names.
+func readFirmwareStateFromVmRestorer(restorer vmRestorer) (
+	map[string][]byte, error) {
+	var extraFiles map[string][]byte
+	for _, name := range firmwareStateFiles {
+		data, err := readFromVmRestorer(restorer, name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if extraFiles == nil {
+			extraFiles = make(map[string][]byte)
+		}
+		extraFiles[name] = data
+	}
+	return extraFiles, nil
+}
+
 func readFromVmRestorer(restorer vmRestorer, filename string) ([]byte, error) {
 	if reader, size, err := restorer.OpenReader(filename); err != nil {
 		return nil, err
@@ -143,8 +164,13 @@ func restoreVm(source string, logger log.DebugLogger) error {
 			return err
 		}
 	}
+	extraFiles, err := readFirmwareStateFromVmRestorer(restorer)
+	if err != nil {
+		return err
+	}
 	request := proto.CreateVmRequest{
 		DhcpTimeout:          *dhcpTimeout,
+		ExtraFiles:           extraFiles,
 		ImageDataSize:        vmInfo.Volumes[0].Size,
 		SecondaryVolumes:     vmInfo.Volumes[1:],
 		SecondaryVolumesData: true,
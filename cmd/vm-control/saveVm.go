@@ -16,6 +16,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/rsync"
@@ -61,14 +62,77 @@ type vmSaver interface {
 	OpenWriter(filename string, length uint64) (writeSeekCloser, error)
 }
 
+// firmwareStateFiles lists the extra files which contain firmware state, in
+// the order they are saved before the root volume.
+var firmwareStateFiles = []string{"nvram", "tpm-state"}
+
+func callGetVmVolume(client *srpc.Client, request proto.GetVmVolumeRequest) (
+	*srpc.Conn, proto.GetVmVolumeResponse, error) {
+	var response proto.GetVmVolumeResponse
+	conn, err := client.Call("Hypervisor.GetVmVolume")
+	if err != nil {
+		return nil, response, err
+	}
+	if err := conn.Encode(request); err != nil {
+		conn.Close()
+		return nil, response, fmt.Errorf("error encoding request: %s", err)
+	}
+	if err := conn.Flush(); err != nil {
+		conn.Close()
+		return nil, response, err
+	}
+	if err := conn.Decode(&response); err != nil {
+		conn.Close()
+		return nil, response, err
+	}
+	if err := errors.New(response.Error); err != nil {
+		conn.Close()
+		return nil, response, err
+	}
+	return conn, response, nil
+}
+
+// copyVolumeToVmSaver will copy a volume to the saver. If saveFirmwareState
+// is true, the firmware state files are saved before the volume.
 func copyVolumeToVmSaver(saver vmSaver, client *srpc.Client, ipAddr net.IP,
-	volIndex uint, size uint64, logger log.DebugLogger) error {
+	volIndex uint, size uint64, saveFirmwareState bool,
+	logger log.DebugLogger) error {
 	var filename string
 	if volIndex == 0 {
 		filename = "root"
 	} else {
 		filename = fmt.Sprintf("secondary-volume.%d", volIndex-1)
 	}
+	request := proto.GetVmVolumeRequest{
+		IpAddress:   ipAddr,
+		VolumeIndex: volIndex,
+	}
+	if diskImageFormat != diskimage.FormatRaw {
+		filename += diskImageFormat.Extension()
+		request.CompressImage = *compressDiskImage
+		request.ImageFormat = diskImageFormat
+	}
+	conn, response, err := callGetVmVolume(client, request)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// The firmware state is saved before the root volume, so that it can be
+	// sent with the request when restoring.
+	for _, name := range firmwareStateFiles {
+		if data, ok := response.ExtraFiles[name]; ok && saveFirmwareState {
+			logger.Debugf(0, "saving %s\n", name)
+			err := saver.CopyToFile(name, bytes.NewReader(data),
+				uint64(len(data)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if diskImageFormat != diskimage.FormatRaw {
+		return copyVmVolumeImageToVmSaver(saver, filename, conn,
+			response.ImageLength, logger)
+	}
 	if reader, initialFileSize, err := saver.OpenReader(filename); err != nil {
 		return err
 	} else {
@@ -82,8 +146,8 @@ func copyVolumeToVmSaver(saver vmSaver, client *srpc.Client, ipAddr net.IP,
 		if writer, err := saver.OpenWriter(filename, size); err != nil {
 			return err
 		} else {
-			err := copyVmVolumeToWriter(writer, reader, initialFileSize,
-				client, ipAddr, volIndex, size, logger)
+			err := copyVmVolumeToWriter(writer, reader, initialFileSize, conn,
+				size, logger)
 			if err != nil {
 				writer.Close()
 				return err
@@ -93,31 +157,27 @@ func copyVolumeToVmSaver(saver vmSaver, client *srpc.Client, ipAddr net.IP,
 	}
 }
 
-func copyVmVolumeToWriter(writer io.WriteSeeker, reader io.Reader,
-	initialFileSize uint64, client *srpc.Client, ipAddr net.IP, volIndex uint,
-	size uint64, logger log.DebugLogger) error {
-	request := proto.GetVmVolumeRequest{
-		IpAddress:   ipAddr,
-		VolumeIndex: volIndex,
+func copyVmVolumeImageToVmSaver(saver vmSaver, filename string,
+	conn *srpc.Conn, imageLength uint64, logger log.DebugLogger) error {
+	if imageLength < 1 {
+		return errors.New("hypervisor does not support disk image formats")
 	}
-	conn, err := client.Call("Hypervisor.GetVmVolume")
+	startTime := time.Now()
+	err := saver.CopyToFile(filename, conn, imageLength)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	if err := conn.Encode(request); err != nil {
-		return fmt.Errorf("error encoding request: %s", err)
-	}
-	if err := conn.Flush(); err != nil {
-		return err
-	}
-	var response proto.GetVmVolumeResponse
-	if err := conn.Decode(&response); err != nil {
-		return err
-	}
-	if err := errors.New(response.Error); err != nil {
-		return err
-	}
+	duration := time.Since(startTime)
+	speed := uint64(float64(imageLength) / duration.Seconds())
+	logger.Debugf(0, "received %s B %s image (%s/s)\n",
+		format.FormatBytes(imageLength), diskImageFormat,
+		format.FormatBytes(speed))
+	return nil
+}
+
+func copyVmVolumeToWriter(writer io.WriteSeeker, reader io.Reader,
+	initialFileSize uint64, conn *srpc.Conn, size uint64,
+	logger log.DebugLogger) error {
 	startTime := time.Now()
 	stats, err := rsync.GetBlocks(conn, conn, conn, reader, writer,
 		size, initialFileSize)
@@ -214,7 +274,7 @@ func saveVmOnHypervisor(hypervisor string, ipAddr net.IP, destination string,
 	}
 	for index, volume := range vmInfo.Volumes {
 		err := copyVolumeToVmSaver(saver, client, ipAddr, uint(index),
-			volume.Size, logger)
+			volume.Size, index == 0, logger)
 		if err != nil {
 			return err
 		}
@@ -237,8 +297,8 @@ func (saver *directorySaver) Close() error {
 
 func (saver *directorySaver) CopyToFile(filename string, reader io.Reader,
 	length uint64) error {
-	file, err := os.OpenFile(saver.Filename(filename), os.O_WRONLY|os.O_CREATE,
-		fsutil.PrivateFilePerms)
+	file, err := os.OpenFile(saver.Filename(filename),
+		os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fsutil.PrivateFilePerms)
 	if err != nil {
 		return err
 	}
@@ -46,11 +46,29 @@ func GetSubsConfiguration(client srpc.ClientI) (subproto.Configuration, error) {
 	return getSubsConfiguration(client)
 }
 
+func Heartbeat(client srpc.ClientI, request proto.HeartbeatRequest) (
+	proto.HeartbeatResponse, error) {
+	return heartbeat(client, request)
+}
+
 func ListSubs(client srpc.ClientI, request proto.ListSubsRequest) (
 	[]string, error) {
 	return listSubs(client, request)
 }
 
+func PreviewImageChange(client srpc.ClientI,
+	request proto.PreviewImageChangeRequest) (
+	[]proto.SubImageChangePreview, error) {
+	return previewImageChange(client, request)
+}
+
 func SetDefaultImage(client srpc.ClientI, imageName string) error {
 	return setDefaultImage(client, imageName)
 }
+
+// WatchSubEvents will send sub events matching the request to eventChannel.
+// It returns when there is an error.
+func WatchSubEvents(client srpc.ClientI, request proto.WatchSubEventsRequest,
+	eventChannel chan<- proto.SubEvent) error {
+	return watchSubEvents(client, request, eventChannel)
+}
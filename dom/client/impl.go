@@ -112,6 +112,19 @@ func getSubsConfiguration(client srpc.ClientI) (subproto.Configuration, error) {
 	return subproto.Configuration(reply), nil
 }
 
+func heartbeat(client srpc.ClientI, request proto.HeartbeatRequest) (
+	proto.HeartbeatResponse, error) {
+	var reply proto.HeartbeatResponse
+	err := client.RequestReply("Dominator.Heartbeat", request, &reply)
+	if err != nil {
+		return proto.HeartbeatResponse{}, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return proto.HeartbeatResponse{}, err
+	}
+	return reply, nil
+}
+
 func listSubs(client srpc.ClientI, request proto.ListSubsRequest) (
 	[]string, error) {
 	var reply proto.ListSubsResponse
@@ -125,9 +138,50 @@ func listSubs(client srpc.ClientI, request proto.ListSubsRequest) (
 	return reply.Hostnames, nil
 }
 
+func previewImageChange(client srpc.ClientI,
+	request proto.PreviewImageChangeRequest) (
+	[]proto.SubImageChangePreview, error) {
+	var reply proto.PreviewImageChangeResponse
+	err := client.RequestReply("Dominator.PreviewImageChange", request, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return nil, err
+	}
+	return reply.Subs, nil
+}
+
 func setDefaultImage(client srpc.ClientI, imageName string) error {
 	request := proto.SetDefaultImageRequest{ImageName: imageName}
 	var reply proto.SetDefaultImageResponse
 	err := client.RequestReply("Dominator.SetDefaultImage", request, &reply)
 	return err
 }
+
+func watchSubEvents(client srpc.ClientI, request proto.WatchSubEventsRequest,
+	eventChannel chan<- proto.SubEvent) error {
+	conn, err := client.Call("Dominator.WatchSubEvents")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.Encode(request); err != nil {
+		return err
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	for {
+		var reply proto.WatchSubEventsResponse
+		if err := conn.Decode(&reply); err != nil {
+			return fmt.Errorf("error decoding: %s", err)
+		}
+		if err := errors.New(reply.Error); err != nil {
+			return err
+		}
+		for _, event := range reply.Events {
+			eventChannel <- event
+		}
+	}
+}
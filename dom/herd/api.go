@@ -73,6 +73,7 @@ const (
 	statusFailedToUpdate
 	statusWaitingForNextFullPoll
 	statusSynced
+	statusStandby
 )
 
 type HtmlWriter interface {
@@ -88,6 +89,7 @@ type Sub struct {
 	plannedImage                 *image.Image // Updated only by sub goroutine.
 	clientResource               *srpc.ClientResource
 	computedInodes               map[string]*filesystem.RegularInode
+	computedFileErrors           map[string]string // Copy-on-write.
 	fileUpdateReceiver           queue.Receiver[[]filegenproto.FileInfo]
 	busyFlagMutex                sync.Mutex
 	busy                         bool
@@ -117,6 +119,7 @@ type Sub struct {
 	lastConnectionSucceededTime  time.Time
 	lastConnectDuration          time.Duration
 	lastDisruptionState          subproto.DisruptionState
+	lastEventStatus              subStatus
 	lastPollStartTime            time.Time
 	lastPollSucceededTime        time.Time
 	lastShortPollDuration        time.Duration
@@ -124,6 +127,7 @@ type Sub struct {
 	lastPollWasFull              bool
 	lastScanDuration             time.Duration
 	lastComputeUpdateCpuDuration time.Duration
+	lastUpdateRequestId          string
 	lastUpdateTime               time.Time
 	lastSyncTime                 time.Time
 	lastSuccessfulImageName      string
@@ -147,9 +151,13 @@ type Herd struct {
 	updatesDisabledBy        string
 	updatesDisabledTime      time.Time
 	defaultImageName         string
+	eventMutex               sync.Mutex // Protect eventWatchers.
+	eventWatchers            map[*eventWatcher]struct{}
 	nextDefaultImageName     string
 	configurationForSubs     subproto.Configuration
 	nextSubToPoll            uint
+	peer                     *peerState // nil: no peer, always active.
+	peerMutex                sync.Mutex // Protect peer.
 	subsByName               map[string]*Sub
 	subsByIndex              []*Sub // Sorted by Sub.hostname.
 	pollSemaphore            chan struct{}
@@ -164,6 +172,7 @@ type Herd struct {
 	subdInstallerQueueDelete chan<- string
 	subdInstallerQueueErase  chan<- string
 	totalScanDuration        time.Duration
+	webhooks                 []*webhookType
 }
 
 type subCounter struct {
@@ -220,6 +229,11 @@ func (herd *Herd) GetInfoForSubs(request domproto.GetInfoForSubsRequest) (
 	return herd.getInfoForSubs(request)
 }
 
+func (herd *Herd) Heartbeat(request domproto.HeartbeatRequest) (
+	domproto.HeartbeatResponse, error) {
+	return herd.heartbeat(request)
+}
+
 func (herd *Herd) ListSubs(request domproto.ListSubsRequest) ([]string, error) {
 	return herd.listSubs(request)
 }
@@ -236,6 +250,12 @@ func (herd *Herd) PollNextSub() bool {
 	return herd.pollNextSub()
 }
 
+func (herd *Herd) PreviewImageChange(
+	request domproto.PreviewImageChangeRequest) (
+	[]domproto.SubImageChangePreview, error) {
+	return herd.previewImageChange(request)
+}
+
 func (herd *Herd) RLockWithTimeout(timeout time.Duration) {
 	herd.rLockWithTimeout(timeout)
 }
@@ -244,6 +264,14 @@ func (herd *Herd) SetDefaultImage(imageName string) error {
 	return herd.setDefaultImage(imageName)
 }
 
+// WatchSubEvents registers a watcher for sub events matching the request. The
+// returned channel is closed if the watcher falls too far behind. The returned
+// function must be called to unregister the watcher.
+func (herd *Herd) WatchSubEvents(request domproto.WatchSubEventsRequest) (
+	<-chan domproto.SubEvent, func(), error) {
+	return herd.watchSubEvents(request)
+}
+
 func (herd *Herd) StartServer(portNum uint, daemon bool) error {
 	return herd.startServer(portNum, daemon)
 }
@@ -0,0 +1,204 @@
+package herd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/backoffdelay"
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	domproto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+const (
+	eventQueueLength = 10000
+	webhookTimeout   = 10 * time.Second
+)
+
+var (
+	subEventWebhookRetries = flag.Uint("subEventWebhookRetries", 5,
+		"Number of times to retry delivering a sub event to a webhook")
+	subEventWebhooks flagutil.StringList
+
+	validEventTypes = map[string]struct{}{
+		domproto.SubEventImageChange:    {},
+		domproto.SubEventStatusChange:   {},
+		domproto.SubEventUpdateFinished: {},
+		domproto.SubEventUpdateStarted:  {},
+	}
+)
+
+type eventWatcher struct {
+	channel   chan domproto.SubEvent
+	hostnames map[string]struct{} // Empty: match all.
+	types     map[string]struct{} // Empty: match all.
+}
+
+type webhookType struct {
+	queue chan domproto.SubEvent
+	url   string
+}
+
+func init() {
+	flag.Var(&subEventWebhooks, "subEventWebhooks",
+		"Comma separated list of URLs to POST sub events to (JSON encoded)")
+}
+
+func makeSet(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, entry := range list {
+		set[entry] = struct{}{}
+	}
+	return set
+}
+
+func postEvent(url string, event domproto.SubEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	httpClient := &http.Client{Timeout: webhookTimeout}
+	resp, err := httpClient.Post(url, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func (watcher *eventWatcher) match(event domproto.SubEvent) bool {
+	if len(watcher.hostnames) > 0 {
+		if _, ok := watcher.hostnames[event.Hostname]; !ok {
+			return false
+		}
+	}
+	if len(watcher.types) > 0 {
+		if _, ok := watcher.types[event.Type]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// publishEvent sends an event to all watchers and webhooks. Watchers which are
+// not keeping up are dropped by closing their channel. Webhooks are only
+// called by the active dominator.
+func (herd *Herd) publishEvent(event domproto.SubEvent) {
+	event.Time = time.Now()
+	herd.eventMutex.Lock()
+	defer herd.eventMutex.Unlock()
+	for watcher := range herd.eventWatchers {
+		if !watcher.match(event) {
+			continue
+		}
+		select {
+		case watcher.channel <- event:
+		default:
+			close(watcher.channel)
+			delete(herd.eventWatchers, watcher)
+		}
+	}
+	if len(herd.webhooks) < 1 || !herd.isActive() {
+		return
+	}
+	for _, webhook := range herd.webhooks {
+		select {
+		case webhook.queue <- event:
+		default:
+			herd.logger.Printf("webhook: %s queue full, dropping %s event for: %s\n",
+				webhook.url, event.Type, event.Hostname)
+		}
+	}
+}
+
+func (herd *Herd) setupWebhooks() {
+	for _, url := range subEventWebhooks {
+		webhook := &webhookType{
+			queue: make(chan domproto.SubEvent, eventQueueLength),
+			url:   url,
+		}
+		herd.webhooks = append(herd.webhooks, webhook)
+		go herd.webhookLoop(webhook)
+	}
+}
+
+func (herd *Herd) watchSubEvents(request domproto.WatchSubEventsRequest) (
+	<-chan domproto.SubEvent, func(), error) {
+	for _, eventType := range request.Types {
+		if _, ok := validEventTypes[eventType]; !ok {
+			return nil, nil, fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	watcher := &eventWatcher{
+		channel:   make(chan domproto.SubEvent, eventQueueLength),
+		hostnames: makeSet(request.Hostnames),
+		types:     makeSet(request.Types),
+	}
+	herd.eventMutex.Lock()
+	if herd.eventWatchers == nil {
+		herd.eventWatchers = make(map[*eventWatcher]struct{})
+	}
+	herd.eventWatchers[watcher] = struct{}{}
+	herd.eventMutex.Unlock()
+	cancelFunc := func() {
+		herd.eventMutex.Lock()
+		defer herd.eventMutex.Unlock()
+		if _, ok := herd.eventWatchers[watcher]; ok {
+			delete(herd.eventWatchers, watcher)
+			close(watcher.channel)
+		}
+	}
+	return watcher.channel, cancelFunc, nil
+}
+
+func (herd *Herd) webhookLoop(webhook *webhookType) {
+	for event := range webhook.queue {
+		sleeper := backoffdelay.NewExponential(time.Second, time.Minute, 0)
+		for retry := uint(0); ; retry++ {
+			err := postEvent(webhook.url, event)
+			if err == nil {
+				break
+			}
+			if retry >= *subEventWebhookRetries {
+				herd.logger.Printf(
+					"webhook: %s failed, dropping %s event for: %s: %s\n",
+					webhook.url, event.Type, event.Hostname, err)
+				break
+			}
+			sleeper.Sleep()
+		}
+	}
+}
+
+// publishStatusEvent publishes a status change event if the status has changed
+// since the last event. Transient statuses and standby polling are ignored, as
+// is the first status seen for a sub.
+func (sub *Sub) publishStatusEvent() {
+	switch sub.status {
+	case statusConnecting, statusPolling, statusStandby:
+		return
+	}
+	oldStatus := sub.lastEventStatus
+	if sub.status == oldStatus {
+		return
+	}
+	sub.lastEventStatus = sub.status
+	if oldStatus == statusUnknown {
+		return
+	}
+	sub.herd.publishEvent(domproto.SubEvent{
+		Hostname:  sub.mdb.Hostname,
+		Image:     sub.requiredImageName,
+		OldStatus: oldStatus.String(),
+		Status:    sub.status.String(),
+		Type:      domproto.SubEventStatusChange,
+	})
+}
@@ -26,6 +26,11 @@ import (
 var (
 	disableUpdatesAtStartup = flag.Bool("disableUpdatesAtStartup", false,
 		"If true, updates are disabled at startup")
+	peerDominator = flag.String("peerDominator", "",
+		"Address (hostname:port) of peer dominator for active/standby operation")
+	peerTakeoverTimeout = flag.Duration("peerTakeoverTimeout",
+		10*time.Second,
+		"Time without contact from the peer dominator before taking over")
 	pollSlotsPerCPU = flag.Uint("pollSlotsPerCPU", 100,
 		"Number of poll slots per CPU")
 	subConnectTimeout = flag.Uint("subConnectTimeout", 15,
@@ -63,12 +68,17 @@ func newHerd(imageServerAddress string, objectServer objectserver.ObjectServer,
 		herd.cpuSharer)
 	herd.currentScanStartTime = time.Now()
 	herd.setupMetrics(metricsDir)
+	herd.setupPeer(*peerDominator, *peerTakeoverTimeout)
+	herd.setupWebhooks()
 	go herd.subdInstallerLoop()
 	return &herd
 }
 
 func (herd *Herd) clearSafetyShutoff(hostname string,
 	authInfo *srpc.AuthInformation) error {
+	if err := herd.checkActive(); err != nil {
+		return err
+	}
 	herd.Lock()
 	sub, ok := herd.subsByName[hostname]
 	herd.Unlock()
@@ -79,6 +89,9 @@ func (herd *Herd) clearSafetyShutoff(hostname string,
 }
 
 func (herd *Herd) configureSubs(configuration subproto.Configuration) error {
+	if err := herd.checkActive(); err != nil {
+		return err
+	}
 	herd.Lock()
 	defer herd.Unlock()
 	herd.configurationForSubs = configuration
@@ -89,6 +102,9 @@ func (herd *Herd) disableUpdates(username, reason string) error {
 	if reason == "" {
 		return errors.New("error disabling updates: no reason given")
 	}
+	if err := herd.checkActive(); err != nil {
+		return err
+	}
 	herd.updatesDisabledBy = username
 	herd.updatesDisabledReason = "because: " + reason
 	herd.updatesDisabledTime = time.Now()
@@ -96,12 +112,18 @@ func (herd *Herd) disableUpdates(username, reason string) error {
 }
 
 func (herd *Herd) enableUpdates() error {
+	if err := herd.checkActive(); err != nil {
+		return err
+	}
 	herd.updatesDisabledReason = ""
 	return nil
 }
 
 func (herd *Herd) fastUpdate(request domproto.FastUpdateRequest,
 	authInfo *srpc.AuthInformation) (<-chan FastUpdateMessage, error) {
+	if err := herd.checkActive(); err != nil {
+		return nil, err
+	}
 	if request.Timeout < time.Millisecond {
 		request.Timeout = 15 * time.Minute
 	}
@@ -116,6 +138,9 @@ func (herd *Herd) fastUpdate(request domproto.FastUpdateRequest,
 
 func (herd *Herd) forceDisruptiveUpdate(hostname string,
 	authInfo *srpc.AuthInformation) error {
+	if err := herd.checkActive(); err != nil {
+		return err
+	}
 	herd.Lock()
 	sub, ok := herd.subsByName[hostname]
 	herd.Unlock()
@@ -222,6 +247,13 @@ func (herd *Herd) rLockWithTimeout(timeout time.Duration) {
 }
 
 func (herd *Herd) setDefaultImage(imageName string) error {
+	if err := herd.checkActive(); err != nil {
+		return err
+	}
+	return herd.changeDefaultImage(imageName)
+}
+
+func (herd *Herd) changeDefaultImage(imageName string) error {
 	if imageName == "" {
 		herd.Lock()
 		defer herd.Unlock()
@@ -29,6 +29,7 @@ func (d uDuration) selector(sub *Sub) bool {
 }
 
 func (herd *Herd) writeHtml(writer io.Writer) {
+	herd.writePeerStatus(writer)
 	if herd.updatesDisabledReason != "" {
 		herd.writeDisableStatus(writer)
 		fmt.Fprintln(writer, "<br>")
@@ -288,7 +289,7 @@ func selectDisruptionWaitingSub(sub *Sub) bool {
 
 func selectLikelyCompliantSub(sub *Sub) bool {
 	switch sub.publishedStatus {
-	case statusWaitingToPoll, statusPolling:
+	case statusWaitingToPoll, statusPolling, statusStandby:
 		return sub.lastSuccessfulImageName == sub.mdb.RequiredImage
 	case statusWaitingForNextFullPoll:
 		return true
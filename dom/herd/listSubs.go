@@ -55,8 +55,9 @@ func (herd *Herd) listUnreachableSubsHandler(w http.ResponseWriter,
 }
 
 func (herd *Herd) listSubs(request proto.ListSubsRequest) ([]string, error) {
-	selectFunc := makeSelector(request.LocationsToMatch,
-		request.StatusesToMatch, tagmatcher.New(request.TagsToMatch, false))
+	selectFunc := makeSelector(request.ImagesToMatch,
+		request.LocationsToMatch, request.StatusesToMatch,
+		tagmatcher.New(request.TagsToMatch, false))
 	if len(request.Hostnames) < 1 {
 		return herd.selectSubs(selectFunc), nil
 	}
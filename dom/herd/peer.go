@@ -0,0 +1,268 @@
+package herd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/dom/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	domproto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+// peerState records the active/standby role of this dominator and what is
+// known about the peer dominator.
+type peerState struct {
+	address         string
+	active          bool
+	activeSince     time.Time
+	hostname        string
+	lastContactTime time.Time
+	lastError       error
+	peerActive      bool
+	peerActiveSince time.Time
+	peerHostname    string
+	peerStartTime   time.Time
+	startTime       time.Time
+	takeoverTimeout time.Duration
+}
+
+// shouldBeActive returns true if this dominator should be (or remain) active.
+func (p *peerState) shouldBeActive(now time.Time) bool {
+	if p.lastContactTime.IsZero() {
+		// Never heard from the peer: give it a chance to respond after startup.
+		return now.Sub(p.startTime) >= p.takeoverTimeout
+	}
+	if now.Sub(p.lastContactTime) >= p.takeoverTimeout {
+		return true // Peer is gone.
+	}
+	if p.active && p.peerActive {
+		// Split brain (probably after a network partition): the dominator
+		// which has been active the longest wins.
+		if !p.activeSince.Equal(p.peerActiveSince) {
+			return p.activeSince.Before(p.peerActiveSince)
+		}
+		return p.isSenior()
+	}
+	if p.active {
+		return true
+	}
+	if p.peerActive {
+		return false
+	}
+	return p.isSenior() // Neither is active: the longest running takes over.
+}
+
+// isSenior returns true if this dominator started before the peer. Ties are
+// broken by hostname.
+func (p *peerState) isSenior() bool {
+	if !p.startTime.Equal(p.peerStartTime) {
+		return p.startTime.Before(p.peerStartTime)
+	}
+	return p.hostname < p.peerHostname
+}
+
+func (herd *Herd) checkActive() error {
+	if herd.isActive() {
+		return nil
+	}
+	herd.peerMutex.Lock()
+	defer herd.peerMutex.Unlock()
+	return errors.New("this dominator is on standby, use the active peer: " +
+		herd.peer.address)
+}
+
+func (herd *Herd) heartbeat(request domproto.HeartbeatRequest) (
+	domproto.HeartbeatResponse, error) {
+	if herd.peer == nil {
+		return domproto.HeartbeatResponse{},
+			errors.New("no peer dominator configured")
+	}
+	herd.peerMutex.Lock()
+	herd.recordPeerState(request.Active, request.ActiveSince,
+		request.Hostname, request.StartTime)
+	response := domproto.HeartbeatResponse{
+		Active:      herd.peer.active,
+		ActiveSince: herd.peer.activeSince,
+		Hostname:    herd.peer.hostname,
+		StartTime:   herd.peer.startTime,
+	}
+	herd.peerMutex.Unlock()
+	if response.Active {
+		herd.RLock()
+		response.DefaultImage = herd.defaultImageName
+		response.UpdatesDisabledBy = herd.updatesDisabledBy
+		response.UpdatesDisabledReason = herd.updatesDisabledReason
+		response.UpdatesDisabledTime = herd.updatesDisabledTime
+		herd.RUnlock()
+	}
+	return response, nil
+}
+
+// isActive returns true if this dominator is permitted to make changes to
+// subs. A dominator without a peer is always active.
+func (herd *Herd) isActive() bool {
+	if herd.peer == nil {
+		return true
+	}
+	herd.peerMutex.Lock()
+	defer herd.peerMutex.Unlock()
+	return herd.peer.active
+}
+
+// peerLoop periodically exchanges heartbeats with the peer dominator and
+// switches between the active and standby roles.
+func (herd *Herd) peerLoop() {
+	interval := herd.peer.takeoverTimeout / 5
+	var srpcClient *srpc.Client
+	for ; ; time.Sleep(interval) {
+		if srpcClient == nil {
+			var err error
+			srpcClient, err = srpc.DialHTTP("tcp", herd.peer.address, interval)
+			if err != nil {
+				herd.peerMutex.Lock()
+				herd.peer.lastError = err
+				herd.peerMutex.Unlock()
+				herd.updateRole()
+				continue
+			}
+		}
+		herd.peerMutex.Lock()
+		request := domproto.HeartbeatRequest{
+			Active:      herd.peer.active,
+			ActiveSince: herd.peer.activeSince,
+			Hostname:    herd.peer.hostname,
+			StartTime:   herd.peer.startTime,
+		}
+		herd.peerMutex.Unlock()
+		srpcClient.SetTimeout(interval)
+		response, err := client.Heartbeat(srpcClient, request)
+		if err != nil {
+			srpcClient.Close()
+			srpcClient = nil
+			herd.peerMutex.Lock()
+			herd.peer.lastError = err
+			herd.peerMutex.Unlock()
+		} else {
+			herd.peerMutex.Lock()
+			herd.recordPeerState(response.Active, response.ActiveSince,
+				response.Hostname, response.StartTime)
+			herd.peerMutex.Unlock()
+			if response.Active && !herd.isActive() {
+				herd.syncFromPeer(response)
+			}
+		}
+		herd.updateRole()
+	}
+}
+
+// recordPeerState must be called with the peerMutex held.
+func (herd *Herd) recordPeerState(active bool, activeSince time.Time,
+	hostname string, startTime time.Time) {
+	herd.peer.lastContactTime = time.Now()
+	herd.peer.lastError = nil
+	herd.peer.peerActive = active
+	herd.peer.peerActiveSince = activeSince
+	herd.peer.peerHostname = hostname
+	herd.peer.peerStartTime = startTime
+}
+
+func (herd *Herd) setupPeer(address string, takeoverTimeout time.Duration) {
+	if address == "" {
+		return
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		herd.logger.Println(err)
+	}
+	herd.peer = &peerState{
+		address:         address,
+		hostname:        hostname,
+		startTime:       time.Now(),
+		takeoverTimeout: takeoverTimeout,
+	}
+	herd.logger.Printf("starting in standby mode, peer: %s\n", address)
+	go herd.peerLoop()
+}
+
+// syncFromPeer copies the herd state from the active peer.
+func (herd *Herd) syncFromPeer(response domproto.HeartbeatResponse) {
+	herd.Lock()
+	herd.updatesDisabledBy = response.UpdatesDisabledBy
+	herd.updatesDisabledReason = response.UpdatesDisabledReason
+	herd.updatesDisabledTime = response.UpdatesDisabledTime
+	defaultImageName := herd.defaultImageName
+	herd.Unlock()
+	if response.DefaultImage != defaultImageName {
+		if err := herd.changeDefaultImage(response.DefaultImage); err != nil {
+			herd.logger.Printf("error syncing default image: %s: %s\n",
+				response.DefaultImage, err)
+		}
+	}
+}
+
+func (herd *Herd) updateRole() {
+	herd.peerMutex.Lock()
+	wasActive := herd.peer.active
+	isActive := herd.peer.shouldBeActive(time.Now())
+	if isActive != wasActive {
+		herd.peer.active = isActive
+		if isActive {
+			herd.peer.activeSince = time.Now()
+		} else {
+			herd.peer.activeSince = time.Time{}
+		}
+	}
+	peerHostname := herd.peer.peerHostname
+	herd.peerMutex.Unlock()
+	if isActive == wasActive {
+		return
+	}
+	if isActive {
+		herd.logger.Println("taking over as the active dominator")
+		return
+	}
+	herd.logger.Printf("becoming standby, active peer: %s\n", peerHostname)
+	// Abort any blocked work so that subs are re-polled read-only.
+	herd.RLock()
+	defer herd.RUnlock()
+	for _, sub := range herd.subsByIndex {
+		sub.sendCancel()
+	}
+}
+
+func (herd *Herd) writePeerStatus(writer io.Writer) {
+	if herd.peer == nil {
+		return
+	}
+	herd.peerMutex.Lock()
+	defer herd.peerMutex.Unlock()
+	if herd.peer.active {
+		fmt.Fprintf(writer,
+			"Role: <font color=\"green\">ACTIVE</font> since %s<br>\n",
+			herd.peer.activeSince.Format(timeFormat))
+	} else {
+		fmt.Fprintln(writer,
+			"Role: <font color=\"orange\">STANDBY</font> (read-only polling)<br>")
+	}
+	fmt.Fprintf(writer, "Peer: <a href=\"http://%s/\">%s</a>",
+		herd.peer.address, herd.peer.address)
+	if herd.peer.lastContactTime.IsZero() {
+		fmt.Fprint(writer, " never contacted")
+	} else {
+		if herd.peer.peerActive {
+			fmt.Fprint(writer, " (active)")
+		} else {
+			fmt.Fprint(writer, " (standby)")
+		}
+		fmt.Fprintf(writer, ", last contact %s ago",
+			format.Duration(time.Since(herd.peer.lastContactTime)))
+	}
+	if herd.peer.lastError != nil {
+		fmt.Fprintf(writer, ", error: %s", herd.peer.lastError)
+	}
+	fmt.Fprintln(writer, "<br>")
+}
@@ -0,0 +1,55 @@
+package herd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShouldBeActive(t *testing.T) {
+	startTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	makePeer := func() *peerState {
+		return &peerState{
+			hostname:        "dom-a",
+			peerHostname:    "dom-b",
+			peerStartTime:   startTime.Add(time.Second),
+			startTime:       startTime,
+			takeoverTimeout: 10 * time.Second,
+		}
+	}
+	p := makePeer()
+	if p.shouldBeActive(startTime.Add(5 * time.Second)) {
+		t.Error("became active before takeover timeout without contact")
+	}
+	if !p.shouldBeActive(startTime.Add(10 * time.Second)) {
+		t.Error("did not become active without peer contact")
+	}
+	now := startTime.Add(time.Minute)
+	p.lastContactTime = now
+	if !p.shouldBeActive(now) {
+		t.Error("senior did not become active when neither is active")
+	}
+	p.peerActive = true
+	p.peerActiveSince = now.Add(-time.Second)
+	if p.shouldBeActive(now) {
+		t.Error("became active while peer is active")
+	}
+	if !p.shouldBeActive(now.Add(10 * time.Second)) {
+		t.Error("did not take over from lost peer")
+	}
+	// Split brain: the longest active wins.
+	p.active = true
+	p.activeSince = now
+	if p.shouldBeActive(now) {
+		t.Error("remained active after peer which was active longer")
+	}
+	p.activeSince = now.Add(-time.Minute)
+	if !p.shouldBeActive(now) {
+		t.Error("gave up active role to peer which was active shorter")
+	}
+	p = makePeer()
+	p.peerStartTime = p.startTime
+	p.lastContactTime = now
+	if !p.shouldBeActive(now) {
+		t.Error("hostname tie-break failed")
+	}
+}
@@ -0,0 +1,246 @@
+package herd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/dom/lib"
+	filegenclient "github.com/Cloud-Foundations/Dominator/lib/filegen/client"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
+	"github.com/Cloud-Foundations/Dominator/lib/tags/tagmatcher"
+	"github.com/Cloud-Foundations/Dominator/lib/triggers"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
+	"github.com/Cloud-Foundations/Dominator/sub/client"
+	sublib "github.com/Cloud-Foundations/Dominator/sub/lib"
+)
+
+var (
+	maxSubsToPreview = flag.Uint("maxSubsToPreview", 100,
+		"Maximum number of subs which may be selected for an image change preview")
+)
+
+// copyTriggers makes a private copy of the triggers, since matching records
+// state in the triggers.
+func copyTriggers(trigs *triggers.Triggers) *triggers.Triggers {
+	if trigs == nil {
+		return nil
+	}
+	newTriggers := triggers.New()
+	for _, trigger := range trigs.Triggers {
+		newTriggers.Triggers = append(newTriggers.Triggers, &triggers.Trigger{
+			MatchLines: trigger.MatchLines,
+			Service:    trigger.Service,
+			SortName:   trigger.SortName,
+			DoReboot:   trigger.DoReboot,
+			HighImpact: trigger.HighImpact,
+		})
+	}
+	return newTriggers
+}
+
+// makeComputedSources returns a table of computed file sources, keyed by
+// pathname.
+func makeComputedSources(
+	computedFiles []filegenclient.ComputedFile) map[string]string {
+	sources := make(map[string]string, len(computedFiles))
+	for _, computedFile := range computedFiles {
+		sources[computedFile.Pathname] = computedFile.Source
+	}
+	return sources
+}
+
+func (herd *Herd) previewImageChange(request proto.PreviewImageChangeRequest) (
+	[]proto.SubImageChangePreview, error) {
+	if request.ImageName == "" {
+		return nil, errors.New("no image specified")
+	}
+	var hostnameRegex *regexp.Regexp
+	if request.HostnameRegex != "" {
+		var err error
+		hostnameRegex, err = regexp.Compile(request.HostnameRegex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	img, err := herd.imageManager.Get(request.ImageName, true)
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		return nil, errors.New("unknown image: " + request.ImageName)
+	}
+	hostnames := make(map[string]struct{}, len(request.Hostnames))
+	for _, hostname := range request.Hostnames {
+		hostnames[hostname] = struct{}{}
+	}
+	selectFunc := makeSelector(nil, request.LocationsToMatch, nil,
+		tagmatcher.New(request.TagsToMatch, false))
+	subs := herd.getSelectedSubs(func(sub *Sub) bool {
+		if len(hostnames) > 0 {
+			if _, ok := hostnames[sub.mdb.Hostname]; !ok {
+				return false
+			}
+		}
+		if hostnameRegex != nil &&
+			!hostnameRegex.MatchString(sub.mdb.Hostname) {
+			return false
+		}
+		return selectFunc(sub)
+	})
+	// Subs without a retained file-system must be fully polled, which is
+	// expensive for both the Dominator and the subs, so limit the blast radius
+	// of the preview itself.
+	if uint(len(subs)) > *maxSubsToPreview {
+		return nil, fmt.Errorf("%d subs selected, maximum is %d",
+			len(subs), *maxSubsToPreview)
+	}
+	candidateSources := makeComputedSources(getComputedFiles(img))
+	previews := make([]proto.SubImageChangePreview, len(subs))
+	semaphore := make(chan struct{}, runtime.NumCPU())
+	completion := make(chan struct{}, len(subs))
+	for index, sub := range subs {
+		go func(index int, sub *Sub) {
+			semaphore <- struct{}{}
+			previews[index] = sub.previewImageChange(img, candidateSources)
+			<-semaphore
+			completion <- struct{}{}
+		}(index, sub)
+	}
+	for range subs {
+		<-completion
+	}
+	return previews, nil
+}
+
+// getPreviewComputedInodes returns the computed inodes which may be used for
+// the candidate image. The computed files for the sub are only known for its
+// current RequiredImage, so computed files which are not in that image (or
+// which have a different source) are omitted and will be reported as missing.
+// The sub must be busy.
+func (sub *Sub) getPreviewComputedInodes(img *image.Image,
+	candidateSources map[string]string) map[string]*filesystem.RegularInode {
+	if img == sub.requiredImage {
+		return sub.computedInodes
+	}
+	if len(candidateSources) < 1 || len(sub.computedInodes) < 1 {
+		return nil
+	}
+	currentSources := makeComputedSources(
+		getComputedFiles(sub.requiredImage))
+	computedInodes := make(map[string]*filesystem.RegularInode,
+		len(candidateSources))
+	for pathname, source := range candidateSources {
+		if currentSource, ok := currentSources[pathname]; !ok ||
+			currentSource != source {
+			continue
+		}
+		if inode, ok := sub.computedInodes[pathname]; ok {
+			computedInodes[pathname] = inode
+		}
+	}
+	return computedInodes
+}
+
+// previewImageChange computes the update that would be sent if the
+// RequiredImage for the sub was changed to img. The file-system from the last
+// poll is used if it has been retained, else the sub is fully polled. The sub
+// is busy (and will not be updated) for the duration. The sub is not modified.
+func (sub *Sub) previewImageChange(img *image.Image,
+	candidateSources map[string]string) proto.SubImageChangePreview {
+	preview := proto.SubImageChangePreview{Hostname: sub.mdb.Hostname}
+	sub.makeBusy()
+	defer sub.makeUnbusy()
+	fs := sub.fileSystem
+	objectCache := sub.objectCache
+	if fs == nil {
+		var err error
+		fs, objectCache, err = sub.pollForPreview()
+		if err != nil {
+			preview.Error = err.Error()
+			return preview
+		}
+	}
+	subObj := lib.Sub{
+		Hostname:       sub.mdb.Hostname,
+		FileSystem:     fs,
+		ComputedInodes: sub.getPreviewComputedInodes(img, candidateSources),
+		ObjectCache:    objectCache,
+	}
+	objectsToFetch, _ := lib.BuildMissingLists(subObj, img, false, true,
+		sub.herd.logger)
+	if objectsToFetch == nil {
+		preview.MissingComputedFiles = true
+	}
+	for _, length := range objectsToFetch {
+		preview.BytesToFetch += length
+	}
+	preview.ObjectsToFetch = uint(len(objectsToFetch))
+	var request subproto.UpdateRequest
+	if lib.BuildUpdateRequest(subObj, img, &request, false, true,
+		sub.herd.logger) {
+		preview.MissingComputedFiles = true
+	}
+	preview.DirectoriesToMake = uint(len(request.DirectoriesToMake))
+	preview.HardlinksToMake = uint(len(request.HardlinksToMake))
+	preview.InodesToChange = uint(len(request.InodesToChange))
+	preview.InodesToMake = uint(len(request.InodesToMake))
+	preview.PathsToDelete = uint(len(request.PathsToDelete))
+	preview.Unsafe = checkForUnsafeChange(sub.mdb, img, fs, request)
+	request.Triggers = copyTriggers(img.Triggers)
+	for _, trigger := range sublib.MatchTriggersInUpdate(request) {
+		preview.Triggers = append(preview.Triggers, trigger.Service)
+		if trigger.HighImpact {
+			preview.HighImpactTriggers = append(preview.HighImpactTriggers,
+				trigger.Service)
+		}
+		if trigger.DoReboot {
+			preview.RebootTriggers = append(preview.RebootTriggers,
+				trigger.Service)
+		}
+	}
+	sort.Strings(preview.Triggers)
+	sort.Strings(preview.HighImpactTriggers)
+	sort.Strings(preview.RebootTriggers)
+	return preview
+}
+
+// pollForPreview performs a full poll of the sub, without recording the
+// result. The sub must be busy.
+func (sub *Sub) pollForPreview() (*filesystem.FileSystem,
+	objectcache.ObjectCache, error) {
+	if sub.clientResource == nil {
+		return nil, nil, errors.New("sub not yet polled")
+	}
+	srpcClient, err := sub.clientResource.GetHTTPWithDialer(sub.cancelChannel,
+		sub.herd.dialer)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer srpcClient.Put()
+	if err := srpcClient.SetTimeout(5 * time.Minute); err != nil {
+		return nil, nil, err
+	}
+	var reply subproto.PollResponse
+	if err := client.CallPoll(srpcClient, subproto.PollRequest{},
+		&reply); err != nil {
+		srpcClient.Close()
+		return nil, nil, err
+	}
+	fs := reply.FileSystem
+	if fs == nil {
+		return nil, nil, errors.New("sub not ready")
+	}
+	if err := fs.RebuildInodePointers(); err != nil {
+		return nil, nil, err
+	}
+	fs.BuildEntryMap()
+	return fs, reply.ObjectCache, nil
+}
@@ -17,8 +17,9 @@ import (
 
 func (herd *Herd) getInfoForSubs(request proto.GetInfoForSubsRequest) (
 	[]proto.SubInfo, error) {
-	selectFunc := makeSelector(request.LocationsToMatch,
-		request.StatusesToMatch, tagmatcher.New(request.TagsToMatch, false))
+	selectFunc := makeSelector(request.ImagesToMatch,
+		request.LocationsToMatch, request.StatusesToMatch,
+		tagmatcher.New(request.TagsToMatch, false))
 	if len(request.Hostnames) < 1 {
 		herd.RLock()
 		defer herd.RUnlock()
@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"encoding/csv"
 	"fmt"
+	"html/template"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,16 +22,22 @@ import (
 	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
 )
 
-func makeSelector(locationsToMatch []string, statusesToMatch []string,
+func makeSelector(imagesToMatch []string, locationsToMatch []string,
+	statusesToMatch []string,
 	tagsToMatch *tagmatcher.TagMatcher) func(sub *Sub) bool {
-	if len(locationsToMatch) < 1 &&
+	if len(imagesToMatch) < 1 &&
+		len(locationsToMatch) < 1 &&
 		len(statusesToMatch) < 1 &&
 		tagsToMatch == nil {
 		return selectAll
 	}
+	imagesToMatchMap := stringutil.ConvertListToMap(imagesToMatch, false)
 	locationsToMatchMap := stringutil.ConvertListToMap(locationsToMatch, false)
 	statusesToMatchMap := stringutil.ConvertListToMap(statusesToMatch, false)
 	return func(sub *Sub) bool {
+		if len(imagesToMatch) > 0 && !sub.matchImages(imagesToMatchMap) {
+			return false
+		}
 		if len(locationsToMatch) > 0 {
 			subLocationLength := len(sub.mdb.Location)
 			if subLocationLength < 1 {
@@ -77,8 +85,26 @@ func makeUrlQuerySelector(queryValues map[string][]string) func(sub *Sub) bool {
 		value := split[1]
 		tagsToMatch[key] = append(tagsToMatch[key], value)
 	}
-	return makeSelector(queryValues["location"], queryValues["status"],
-		tagmatcher.New(tagsToMatch, false))
+	return makeSelector(queryValues["image"], queryValues["location"],
+		queryValues["status"], tagmatcher.New(tagsToMatch, false))
+}
+
+// matchImages returns true if the sub is running or is scheduled to run any of
+// the specified images.
+func (sub *Sub) matchImages(imagesToMatch map[string]struct{}) bool {
+	for _, imageName := range []string{
+		sub.lastSuccessfulImageName,
+		sub.mdb.PlannedImage,
+		sub.mdb.RequiredImage,
+	} {
+		if imageName == "" {
+			continue
+		}
+		if _, ok := imagesToMatch[imageName]; ok {
+			return true
+		}
+	}
+	return false
 }
 
 func selectAll(sub *Sub) bool {
@@ -360,6 +386,19 @@ func (herd *Herd) showSubHandler(writer http.ResponseWriter,
 	}
 	newRow(w, "Last address", false)
 	tw.WriteData("", sub.lastAddress)
+	if computedFileErrors := sub.computedFileErrors; len(
+		computedFileErrors) > 0 {
+		pathnames := make([]string, 0, len(computedFileErrors))
+		for pathname := range computedFileErrors {
+			pathnames = append(pathnames, pathname)
+		}
+		sort.Strings(pathnames)
+		for _, pathname := range pathnames {
+			newRow(w, "Computed file error", false)
+			tw.WriteData("", template.HTMLEscapeString(
+				pathname+": "+computedFileErrors[pathname]))
+		}
+	}
 	fmt.Fprint(w, "  </tr>\n")
 	tw.Close()
 	fmt.Fprintln(w, "<br>")
@@ -1,6 +1,8 @@
 package herd
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
@@ -17,9 +19,14 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/fieldlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
 	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
 	"github.com/Cloud-Foundations/Dominator/lib/resourcepool"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	domproto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+	filegenproto "github.com/Cloud-Foundations/Dominator/proto/filegenerator"
 	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
 	"github.com/Cloud-Foundations/Dominator/sub/client"
 )
@@ -106,7 +113,7 @@ func (sub *Sub) checkAdminAccess(authInfo *srpc.AuthInformation) bool {
 	return false
 }
 
-func (sub *Sub) getComputedFiles(im *image.Image) []filegenclient.ComputedFile {
+func getComputedFiles(im *image.Image) []filegenclient.ComputedFile {
 	if im == nil {
 		return nil
 	}
@@ -187,6 +194,7 @@ func (sub *Sub) connectAndPoll(
 	defer func() {
 		timer.Stop()
 		sub.publishedStatus = sub.status
+		sub.publishStatusEvent()
 		switch sub.status {
 		case statusUnknown:
 		case statusConnecting:
@@ -342,6 +350,7 @@ func (sub *Sub) loadConfiguration() {
 	}
 	if newRequiredImageName != sub.requiredImageName {
 		sub.computedInodes = nil
+		sub.computedFileErrors = nil
 	}
 	sub.herd.cpuSharer.ReleaseCpu()
 	defer sub.herd.cpuSharer.GrabCpu()
@@ -361,7 +370,7 @@ func (sub *Sub) processFileUpdates() bool {
 			sub.deletingFlagMutex.Unlock()
 			return false
 		}
-		computedFiles := sub.getComputedFiles(image)
+		computedFiles := getComputedFiles(image)
 		sub.herd.cpuSharer.ReleaseCpu()
 		sub.herd.logger.Debugf(0,
 			"processFileUpdates(%s): updating filegen manager\n", sub)
@@ -376,6 +385,7 @@ func (sub *Sub) processFileUpdates() bool {
 		}
 		filenameToInodeTable := image.FileSystem.FilenameToInodeTable()
 		for _, fileInfo := range fileInfos {
+			sub.updateComputedFileError(fileInfo)
 			if fileInfo.Hash == zeroHash {
 				continue // No object.
 			}
@@ -406,6 +416,32 @@ func (sub *Sub) processFileUpdates() bool {
 	return haveUpdates
 }
 
+// updateComputedFileError records or clears the generation error for a
+// computed file. The map is replaced rather than modified so that it may be
+// read safely by the status page.
+func (sub *Sub) updateComputedFileError(fileInfo filegenproto.FileInfo) {
+	oldError, haveError := sub.computedFileErrors[fileInfo.Pathname]
+	if fileInfo.Error == oldError {
+		return
+	}
+	if fileInfo.Error == "" && !haveError {
+		return
+	}
+	newErrors := make(map[string]string, len(sub.computedFileErrors)+1)
+	for pathname, err := range sub.computedFileErrors {
+		newErrors[pathname] = err
+	}
+	if fileInfo.Error == "" {
+		delete(newErrors, fileInfo.Pathname)
+	} else {
+		newErrors[fileInfo.Pathname] = fileInfo.Error
+	}
+	if len(newErrors) < 1 {
+		newErrors = nil
+	}
+	sub.computedFileErrors = newErrors
+}
+
 // Returns true if the Poll failed due to an I/O error, indicating a retry is
 // reasonable.
 func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus,
@@ -457,6 +493,13 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus,
 	} else {
 		haveImage = true
 	}
+	standby := !sub.herd.isActive()
+	if standby {
+		// Keep state warm without touching the sub. Ensure a full poll after
+		// taking over.
+		request.ShortPollOnly = true
+		sub.generationCount = 0
+	}
 	logger := sub.herd.logger
 	sub.lastPollStartTime = time.Now()
 	if err := client.CallPoll(srpcClient, request, &reply); err != nil {
@@ -476,6 +519,15 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus,
 			sub, format.Duration(time.Since(sub.lastPollStartTime)), err)
 		return retval
 	}
+	if reply.LastSuccessfulImageName != sub.lastSuccessfulImageName &&
+		!sub.lastPollSucceededTime.IsZero() {
+		sub.herd.publishEvent(domproto.SubEvent{
+			Hostname: sub.mdb.Hostname,
+			Image:    reply.LastSuccessfulImageName,
+			OldImage: sub.lastSuccessfulImageName,
+			Type:     domproto.SubEventImageChange,
+		})
+	}
 	sub.lastDisruptionState = reply.DisruptionState
 	sub.lastPollSucceededTime = time.Now()
 	sub.lastSuccessfulImageName = reply.LastSuccessfulImageName
@@ -518,7 +570,9 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus,
 	}
 	sub.startTime = reply.StartTime
 	sub.pollTime = reply.PollTime
-	sub.updateConfiguration(srpcClient, reply)
+	if !standby {
+		sub.updateConfiguration(srpcClient, reply)
+	}
 	if reply.FetchInProgress {
 		sub.status = statusFetching
 		return false
@@ -541,6 +595,10 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus,
 		sub.reclaim()
 		return false
 	}
+	if standby {
+		sub.status = statusStandby
+		return false
+	}
 	if previousStatus == statusLocked { // Not locked anymore, but was locked.
 		if sub.fileSystem == nil {
 			sub.generationCount = 0 // Force a full poll next cycle.
@@ -557,6 +615,18 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus,
 	}
 	if previousStatus == statusUpdating {
 		// Transition from updating to update ended (may be partial/failed).
+		var duration time.Duration
+		if !sub.lastUpdateTime.IsZero() {
+			duration = time.Since(sub.lastUpdateTime)
+		}
+		sub.herd.publishEvent(domproto.SubEvent{
+			Duration:  duration,
+			Error:     reply.LastUpdateError,
+			Hostname:  sub.mdb.Hostname,
+			Image:     sub.requiredImageName,
+			RequestId: sub.lastUpdateRequestId,
+			Type:      domproto.SubEventUpdateFinished,
+		})
 		switch reply.LastUpdateError {
 		case "":
 			sub.status = statusWaitingForNextFullPoll
@@ -565,7 +635,11 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus,
 		case subproto.ErrorDisruptionDenied:
 			sub.status = statusDisruptionDenied
 		default:
-			logger.Printf("Update failure for: %s: %s\n",
+			fieldlogger.New(log.Fields{
+				log.FieldSub:       sub.mdb.Hostname,
+				log.FieldImage:     sub.requiredImageName,
+				log.FieldRequestId: sub.lastUpdateRequestId,
+			}, logger).Printf("Update failure for: %s: %s\n",
 				sub, reply.LastUpdateError)
 			sub.status = statusFailedToUpdate
 		}
@@ -805,9 +879,18 @@ func (sub *Sub) fetchMissingObjects(srpcClient *srpc.Client, img *image.Image,
 	return returnAvailable, returnStatus
 }
 
+// makeRequestId returns a random identifier used to correlate the log messages
+// for an update.
+func makeRequestId() string {
+	buffer := make([]byte, 8)
+	if _, err := rand.Read(buffer); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buffer)
+}
+
 // Returns true if no update needs to be performed.
 func (sub *Sub) sendUpdate(srpcClient *srpc.Client) (bool, subStatus) {
-	logger := sub.herd.logger
 	var request subproto.UpdateRequest
 	var reply subproto.UpdateResponse
 	if idle, missing := sub.buildUpdateRequest(&request); missing {
@@ -833,7 +916,14 @@ func (sub *Sub) sendUpdate(srpcClient *srpc.Client) (bool, subStatus) {
 	if sub.pendingForceDisruptiveUpdate {
 		request.ForceDisruption = true
 	}
+	request.RequestId = makeRequestId()
+	logger := fieldlogger.New(log.Fields{
+		log.FieldSub:       sub.mdb.Hostname,
+		log.FieldImage:     sub.requiredImageName,
+		log.FieldRequestId: request.RequestId,
+	}, sub.herd.logger)
 	sub.status = statusSendingUpdate
+	sub.lastUpdateRequestId = request.RequestId
 	sub.lastUpdateTime = time.Now()
 	logger.Printf("Calling %s:Subd.Update() for image: %s\n",
 		sub, sub.requiredImageName)
@@ -845,6 +935,12 @@ func (sub *Sub) sendUpdate(srpcClient *srpc.Client) (bool, subStatus) {
 		}
 		return false, statusFailedToUpdate
 	}
+	sub.herd.publishEvent(domproto.SubEvent{
+		Hostname:  sub.mdb.Hostname,
+		Image:     sub.requiredImageName,
+		RequestId: request.RequestId,
+		Type:      domproto.SubEventUpdateStarted,
+	})
 	sub.pendingSafetyClear = false
 	sub.pendingForceDisruptiveUpdate = false
 	return false, statusUpdating
@@ -852,17 +948,22 @@ func (sub *Sub) sendUpdate(srpcClient *srpc.Client) (bool, subStatus) {
 
 // Returns true if the change is unsafe (very large number of deletions).
 func (sub *Sub) checkForUnsafeChange(request subproto.UpdateRequest) bool {
-	if sub.requiredImage.Filter == nil {
+	return checkForUnsafeChange(sub.mdb, sub.requiredImage, sub.fileSystem,
+		request)
+}
+
+func checkForUnsafeChange(machine mdb.Machine, img *image.Image,
+	fs *filesystem.FileSystem, request subproto.UpdateRequest) bool {
+	if img.Filter == nil {
 		return false // Sparse image: no deletions.
 	}
-	if _, ok := sub.mdb.Tags["DisableSafetyCheck"]; ok {
+	if _, ok := machine.Tags["DisableSafetyCheck"]; ok {
 		return false // This sub doesn't need a safety check.
 	}
-	if len(sub.requiredImage.FileSystem.InodeTable) <
-		len(sub.fileSystem.InodeTable)>>1 {
+	if len(img.FileSystem.InodeTable) < len(fs.InodeTable)>>1 {
 		return true
 	}
-	if len(request.PathsToDelete) > len(sub.fileSystem.InodeTable)>>1 {
+	if len(request.PathsToDelete) > len(fs.InodeTable)>>1 {
 		return true
 	}
 	return false
@@ -82,6 +82,8 @@ func (status subStatus) string() string {
 		return "waiting for next full poll"
 	case statusSynced:
 		return "synced"
+	case statusStandby:
+		return "standby"
 	default:
 		panic(fmt.Sprintf("unknown status: %d", status))
 	}
@@ -100,7 +100,7 @@ func (herd *Herd) subdInstallerLoop() {
 }
 
 func (herd *Herd) addSubToInstallerQueue(subHostname string) {
-	if herd.subdInstallerQueueAdd != nil {
+	if herd.subdInstallerQueueAdd != nil && herd.isActive() {
 		herd.subdInstallerQueueAdd <- subHostname
 	}
 }
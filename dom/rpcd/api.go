@@ -23,6 +23,7 @@ func Setup(herd *herd.Herd, logger log.Logger) {
 				"ForceDisruptiveUpdate": 1,
 				"GetInfoForSubs":        1,
 				"ListSubs":              1,
+				"PreviewImageChange":    1,
 			}),
 	}
 	srpc.RegisterNameWithOptions("Dominator", rpcObj,
@@ -33,5 +34,6 @@ func Setup(herd *herd.Herd, logger log.Logger) {
 				"ForceDisruptiveUpdate",
 				"GetInfoForSubs",
 				"ListSubs",
+				"WatchSubEvents",
 			}})
 }
@@ -0,0 +1,16 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (t *rpcType) Heartbeat(conn *srpc.Conn,
+	request dominator.HeartbeatRequest,
+	reply *dominator.HeartbeatResponse) error {
+	response, err := t.herd.Heartbeat(request)
+	response.Error = errors.ErrorToString(err)
+	*reply = response
+	return nil
+}
@@ -0,0 +1,19 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (t *rpcType) PreviewImageChange(conn *srpc.Conn,
+	request dominator.PreviewImageChangeRequest,
+	reply *dominator.PreviewImageChangeResponse) error {
+	subs, err := t.herd.PreviewImageChange(request)
+	response := dominator.PreviewImageChangeResponse{
+		Error: errors.ErrorToString(err),
+		Subs:  subs,
+	}
+	*reply = response
+	return nil
+}
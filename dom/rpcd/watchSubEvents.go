@@ -0,0 +1,55 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (t *rpcType) WatchSubEvents(conn *srpc.Conn,
+	decoder srpc.Decoder, encoder srpc.Encoder) error {
+	var request dominator.WatchSubEventsRequest
+	if err := decoder.Decode(&request); err != nil {
+		return err
+	}
+	eventChannel, cancelFunc, err := t.herd.WatchSubEvents(request)
+	if err != nil {
+		return encoder.Encode(dominator.WatchSubEventsResponse{
+			Error: err.Error()})
+	}
+	defer cancelFunc()
+	// Send an empty response to indicate that the watch is active.
+	if err := encoder.Encode(dominator.WatchSubEventsResponse{}); err != nil {
+		return err
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	closeChannel := conn.GetCloseNotifier()
+	for {
+		select {
+		case event, ok := <-eventChannel:
+			if !ok {
+				t.logger.Printf("WatchSubEvents(%s): queue full, dropping\n",
+					conn.Username())
+				return encoder.Encode(dominator.WatchSubEventsResponse{
+					Error: "event queue overflowed"})
+			}
+			reply := dominator.WatchSubEventsResponse{
+				Events: []dominator.SubEvent{event},
+			}
+			for len(eventChannel) > 0 {
+				if event, ok := <-eventChannel; ok {
+					reply.Events = append(reply.Events, event)
+				}
+			}
+			if err := encoder.Encode(reply); err != nil {
+				return err
+			}
+			if err := conn.Flush(); err != nil {
+				return err
+			}
+		case <-closeChannel:
+			return nil
+		}
+	}
+}
@@ -2,8 +2,16 @@ package client
 
 import (
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 func PowerOnMachine(client *srpc.Client, hostname string) error {
 	return powerOnMachine(client, hostname)
 }
+
+// ReportHardwareInventory will report the hardware inventory of the local
+// machine. The mismatches with the topology are returned.
+func ReportHardwareInventory(client *srpc.Client, hostname string,
+	inventory *hyper_proto.HardwareInventory) ([]string, error) {
+	return reportHardwareInventory(client, hostname, inventory)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 func powerOnMachine(client *srpc.Client, hostname string) error {
@@ -15,3 +16,18 @@ func powerOnMachine(client *srpc.Client, hostname string) error {
 	}
 	return errors.New(reply.Error)
 }
+
+func reportHardwareInventory(client *srpc.Client, hostname string,
+	inventory *hyper_proto.HardwareInventory) ([]string, error) {
+	request := proto.ReportHardwareInventoryRequest{
+		HardwareInventory: inventory,
+		Hostname:          hostname,
+	}
+	var reply proto.ReportHardwareInventoryResponse
+	err := client.RequestReply("FleetManager.ReportHardwareInventory", request,
+		&reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply.HardwareMismatches, errors.New(reply.Error)
+}
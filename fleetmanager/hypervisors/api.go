@@ -38,6 +38,7 @@ type hypervisorType struct {
 	closeClientChannel chan<- struct{}
 	deleteScheduled    bool
 	disabled           bool
+	hardwareInventory  *hyper_proto.HardwareInventory
 	healthStatus       string
 	lastConnectedTime  time.Time
 	lastIpmiProbe      time.Time
@@ -48,7 +49,8 @@ type hypervisorType struct {
 	probeStatus        probeStatus
 	serialNumber       string
 	subnets            []hyper_proto.Subnet
-	vms                map[string]*vmInfoType // Key: VM IP address.
+	throttleDefaults   *hyper_proto.ThrottleDefaults // nil: not reported.
+	vms                map[string]*vmInfoType        // Key: VM IP address.
 }
 
 type ipStorer interface {
@@ -151,6 +153,22 @@ func (m *Manager) GetHypervisorsInLocation(
 	return m.getHypervisorsInLocation(request)
 }
 
+// GetHardwareInventory returns the hardware inventory last reported by the
+// specified hypervisor and any mismatches with the topology.
+func (m *Manager) GetHardwareInventory(hostname string) (
+	*hyper_proto.HardwareInventory, []string, error) {
+	return m.getHardwareInventory(hostname)
+}
+
+// ReportHardwareInventory records the hardware inventory reported by a machine
+// which is not yet reporting as a Hypervisor (such as during installation).
+// The connection must come from an IP address of the machine. Any mismatches
+// with the topology are returned.
+func (m *Manager) ReportHardwareInventory(hostname string, remoteIP net.IP,
+	inventory *hyper_proto.HardwareInventory) ([]string, error) {
+	return m.reportHardwareInventory(hostname, remoteIP, inventory)
+}
+
 func (m *Manager) GetMachineInfo(request fm_proto.GetMachineInfoRequest) (
 	fm_proto.Machine, error) {
 	return m.getMachineInfo(request)
@@ -24,12 +24,78 @@ func (h *hypervisorType) makeProtoHypervisor(
 	return protoHypervisor
 }
 
+// checkHardwareInventory will compare the inventory with the topology.
+func (m *Manager) checkHardwareInventory(hostname string,
+	inventory *hyper_proto.HardwareInventory) ([]string, error) {
+	t, err := m.getTopology()
+	if err != nil {
+		return nil, err
+	}
+	machine, err := t.GetMachine(hostname)
+	if err != nil {
+		return nil, err
+	}
+	return machine.CheckHardwareInventory(inventory), nil
+}
+
 func (h *hypervisorType) getSerialNumber() string {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 	return h.serialNumber
 }
 
+func (m *Manager) getHardwareInventory(hostname string) (
+	*hyper_proto.HardwareInventory, []string, error) {
+	hypervisor, err := m.getLockedHypervisor(hostname, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	inventory := hypervisor.hardwareInventory
+	hypervisor.mutex.RUnlock()
+	if inventory == nil {
+		return nil, nil, nil
+	}
+	mismatches, err := m.checkHardwareInventory(hostname, inventory)
+	if err != nil {
+		return nil, nil, err
+	}
+	return inventory, mismatches, nil
+}
+
+func (m *Manager) reportHardwareInventory(hostname string, remoteIP net.IP,
+	inventory *hyper_proto.HardwareInventory) ([]string, error) {
+	if inventory == nil {
+		return nil, errors.New("no hardware inventory")
+	}
+	h, err := m.getLockedHypervisor(hostname, true)
+	if err != nil {
+		return nil, err
+	}
+	permitted := h.Machine.HostIpAddress.Equal(remoteIP)
+	for _, entry := range h.Machine.SecondaryNetworkEntries {
+		if entry.HostIpAddress.Equal(remoteIP) {
+			permitted = true
+		}
+	}
+	if !permitted {
+		h.mutex.Unlock()
+		return nil, errors.New("connection not from machine")
+	}
+	// The Hypervisor reports the inventory when connected, which is preferred.
+	if h.probeStatus != probeStatusConnected {
+		h.hardwareInventory = inventory
+	}
+	h.mutex.Unlock()
+	mismatches, err := m.checkHardwareInventory(hostname, inventory)
+	if err != nil {
+		return nil, err
+	}
+	for _, mismatch := range mismatches {
+		h.logger.Printf("reported hardware mismatch: %s\n", mismatch)
+	}
+	return mismatches, nil
+}
+
 func (m *Manager) getLockedHypervisor(name string,
 	writeLock bool) (*hypervisorType, error) {
 	m.mutex.RLock()
@@ -109,6 +109,19 @@ func (m *Manager) showHypervisorHandler(w http.ResponseWriter,
 		fmt.Fprintf(writer, "<a href=\"https://%s/\">IPMI</a><br>\n",
 			h.IPMI.Hostname)
 	}
+	if inventory := h.hardwareInventory; inventory != nil {
+		fmt.Fprintf(writer, "Hardware: %s %s, CPU: %s, %d disks, %d NICs<br>\n",
+			inventory.DMI.SystemVendor, inventory.DMI.ProductName,
+			inventory.CPU.ModelName, len(inventory.Disks),
+			len(inventory.NICs))
+		if machine, err := topology.GetMachine(hostname); err == nil {
+			for _, mismatch := range machine.CheckHardwareInventory(inventory) {
+				fmt.Fprintf(writer,
+					"<font color=\"red\">Hardware mismatch: %s</font><br>\n",
+					mismatch)
+			}
+		}
+	}
 	fmt.Fprintf(writer,
 		"Number of VMs known: %d (<a href=\"http://%s:%d/listVMs\">live view</a>)<br>\n",
 		numVMs, hostname, constants.HypervisorPortNumber)
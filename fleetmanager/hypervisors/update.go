@@ -164,7 +164,11 @@ func (m *Manager) changeMachineTags(hostname string,
 			ChangedMachines: []*fm_proto.Machine{h.getMachineLocked()},
 		}
 		location := h.location
+		connected := h.probeStatus == probeStatusConnected
 		h.mutex.Unlock()
+		if connected {
+			go m.processThrottleDefaultsUpdates(h)
+		}
 		m.mutex.Lock()
 		defer m.mutex.Unlock()
 		m.sendUpdate(location, update)
@@ -270,6 +274,7 @@ func (m *Manager) updateHypervisor(h *hypervisorType, machine fm_proto.Machine,
 			go h.changeOwners(nil)
 		}
 		go m.processSubnetsUpdates(h, subnets)
+		go m.processThrottleDefaultsUpdates(h)
 	}
 }
 
@@ -617,12 +622,18 @@ func (m *Manager) processHypervisorUpdate(h *hypervisorType,
 	if update.HaveDisabled {
 		h.disabled = update.Disabled
 	}
+	if update.HardwareInventory != nil {
+		h.hardwareInventory = update.HardwareInventory
+	}
 	if update.MemoryInMiB != nil {
 		h.MemoryInMiB = *update.MemoryInMiB
 	}
 	if update.NumCPUs != nil {
 		h.NumCPUs = *update.NumCPUs
 	}
+	if update.ThrottleDefaults != nil {
+		h.throttleDefaults = update.ThrottleDefaults
+	}
 	if update.TotalVolumeBytes != nil {
 		h.TotalVolumeBytes = *update.TotalVolumeBytes
 	}
@@ -637,6 +648,16 @@ func (m *Manager) processHypervisorUpdate(h *hypervisorType,
 		h.logger.Printf("health status changed from: \"%s\" to: \"%s\"\n",
 			oldHealthStatus, update.HealthStatus)
 	}
+	if update.HardwareInventory != nil {
+		mismatches, err := m.checkHardwareInventory(h.Machine.Hostname,
+			update.HardwareInventory)
+		if err != nil {
+			h.logger.Println(err)
+		}
+		for _, mismatch := range mismatches {
+			h.logger.Printf("hardware mismatch: %s\n", mismatch)
+		}
+	}
 	if *manageHypervisors {
 		if update.HaveSubnets { // Must do subnets first.
 			h.mutex.Lock()
@@ -645,6 +666,9 @@ func (m *Manager) processHypervisorUpdate(h *hypervisorType,
 			m.processSubnetsUpdates(h, update.Subnets)
 		}
 		m.processAddressPoolUpdates(h, update)
+		if update.ThrottleDefaults != nil {
+			m.processThrottleDefaultsUpdates(h)
+		}
 	}
 	if update.HaveSerialNumber && update.SerialNumber != "" &&
 		update.SerialNumber != oldSerialNumber {
@@ -742,6 +766,52 @@ func (m *Manager) processSubnetsUpdates(h *hypervisorType,
 		len(request.Add), len(request.Change), len(request.Delete))
 }
 
+// processThrottleDefaultsUpdates will send the VM throttle defaults for the
+// Hypervisor tags to the Hypervisor, if they differ from what it reported.
+func (m *Manager) processThrottleDefaultsUpdates(h *hypervisorType) {
+	t, err := m.getTopology()
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	h.mutex.RLock()
+	haveDefaults := h.throttleDefaults
+	machine := h.getMachineLocked()
+	h.mutex.RUnlock()
+	if haveDefaults == nil { // Older Hypervisor.
+		return
+	}
+	needDefaults, err := t.GetThrottleDefaultsForMachine(machine.Hostname,
+		machine.Tags)
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	if needDefaults == *haveDefaults {
+		return
+	}
+	client, err := srpc.DialHTTP("tcp", h.address(), time.Minute)
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	defer client.Close()
+	request := hyper_proto.SetThrottleDefaultsRequest{
+		ThrottleDefaults: needDefaults,
+	}
+	var reply hyper_proto.SetThrottleDefaultsResponse
+	err = client.RequestReply("Hypervisor.SetThrottleDefaults", request,
+		&reply)
+	if err == nil {
+		err = errors.New(reply.Error)
+	}
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	h.logger.Debugf(0, "set throttle defaults: %+v\n", needDefaults)
+}
+
 func (m *Manager) processVmUpdates(h *hypervisorType,
 	updateVMs map[string]*hyper_proto.VmInfo) {
 	for ipAddr, vm := range updateVMs {
@@ -28,8 +28,9 @@ func Setup(hypervisorsManager *hypervisors.Manager, logger log.DebugLogger) (
 		logger:             logger,
 		PerUserMethodLimiter: serverutil.NewPerUserMethodLimiter(
 			map[string]uint{
-				"GetMachineInfo": 1,
-				"GetUpdates":     1,
+				"GetMachineInfo":          1,
+				"GetUpdates":              1,
+				"ReportHardwareInventory": 1,
 			}),
 	}
 	srpc.RegisterNameWithOptions("FleetManager", srpcObj,
@@ -44,6 +45,7 @@ func Setup(hypervisorsManager *hypervisors.Manager, logger log.DebugLogger) (
 				"ListHypervisorsInLocation",
 				"ListVMsInLocation",
 				"PowerOnMachine",
+				"ReportHardwareInventory",
 			}})
 	return (*htmlWriter)(srpcObj), nil
 }
@@ -33,6 +33,11 @@ func (t *srpcType) getMachineInfo(request fm_proto.GetMachineInfoRequest) (
 	if err != nil {
 		return fm_proto.GetMachineInfoResponse{}, err
 	}
+	inventory, mismatches, err := t.hypervisorsManager.GetHardwareInventory(
+		request.Hostname)
+	if err != nil {
+		return fm_proto.GetMachineInfoResponse{}, err
+	}
 	tSubnets, err := topology.GetSubnetsForMachine(request.Hostname)
 	if err != nil {
 		return fm_proto.GetMachineInfoResponse{}, err
@@ -42,8 +47,10 @@ func (t *srpcType) getMachineInfo(request fm_proto.GetMachineInfoRequest) (
 		subnets = append(subnets, &tSubnet.Subnet)
 	}
 	return fm_proto.GetMachineInfoResponse{
-		Location: location,
-		Machine:  machine,
-		Subnets:  subnets,
+		HardwareInventory:  inventory,
+		HardwareMismatches: mismatches,
+		Location:           location,
+		Machine:            machine,
+		Subnets:            subnets,
 	}, nil
 }
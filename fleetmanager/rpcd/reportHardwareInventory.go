@@ -0,0 +1,25 @@
+package rpcd
+
+import (
+	"net"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) ReportHardwareInventory(conn *srpc.Conn,
+	request fm_proto.ReportHardwareInventoryRequest,
+	reply *fm_proto.ReportHardwareInventoryResponse) error {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr())
+	if err != nil {
+		return err
+	}
+	mismatches, err := t.hypervisorsManager.ReportHardwareInventory(
+		request.Hostname, net.ParseIP(host), request.HardwareInventory)
+	*reply = fm_proto.ReportHardwareInventoryResponse{
+		Error:              errors.ErrorToString(err),
+		HardwareMismatches: mismatches,
+	}
+	return nil
+}
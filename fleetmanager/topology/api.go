@@ -12,10 +12,11 @@ import (
 
 type Directory struct {
 	Name             string
-	Directories      []*Directory        `json:",omitempty"`
-	Machines         []*fm_proto.Machine `json:",omitempty"`
-	Subnets          []*Subnet           `json:",omitempty"`
-	Tags             tags.Tags           `json:",omitempty"`
+	Directories      []*Directory               `json:",omitempty"`
+	Machines         []*fm_proto.Machine        `json:",omitempty"`
+	Subnets          []*Subnet                  `json:",omitempty"`
+	Tags             tags.Tags                  `json:",omitempty"`
+	ThrottleDefaults []*ThrottleDefaultsForTags `json:",omitempty"`
 	logger           log.DebugLogger
 	nameToDirectory  map[string]*Directory // Key: directory name.
 	owners           *ownersType
@@ -68,6 +69,14 @@ func (subnet *Subnet) Shrink() {
 	subnet.shrink()
 }
 
+// ThrottleDefaultsForTags specifies the default VM limits for Hypervisors which
+// match the tags. They are read from the throttle-defaults.json file in a
+// directory and apply to the machines in that directory and below.
+type ThrottleDefaultsForTags struct {
+	HypervisorTagsToMatch tags.MatchTags `json:",omitempty"` // Empty: all.
+	hyper_proto.ThrottleDefaults
+}
+
 type Topology struct {
 	Root            *Directory
 	Variables       map[string]string
@@ -126,6 +135,10 @@ func (t *Topology) GetLocationOfMachine(name string) (string, error) {
 	return t.getLocationOfMachine(name)
 }
 
+func (t *Topology) GetMachine(name string) (*fm_proto.Machine, error) {
+	return t.getMachine(name)
+}
+
 func (t *Topology) GetNumMachines() uint {
 	return uint(len(t.machineParents))
 }
@@ -134,6 +147,15 @@ func (t *Topology) GetSubnetsForMachine(name string) ([]*Subnet, error) {
 	return t.getSubnetsForMachine(name)
 }
 
+// GetThrottleDefaultsForMachine returns the default VM limits for the machine
+// with the specified tags (which may include tags which are not in the
+// topology). For each limit, the first match in the directory closest to the
+// machine is used.
+func (t *Topology) GetThrottleDefaultsForMachine(name string,
+	machineTags tags.Tags) (hyper_proto.ThrottleDefaults, error) {
+	return t.getThrottleDefaultsForMachine(name, machineTags)
+}
+
 func (t *Topology) ListMachines(dirname string) ([]*fm_proto.Machine, error) {
 	return t.listMachines(dirname)
 }
@@ -63,6 +63,14 @@ func (left *Directory) equal(right *Directory) bool {
 	if !left.Tags.Equal(right.Tags) {
 		return false
 	}
+	if len(left.ThrottleDefaults) != len(right.ThrottleDefaults) {
+		return false
+	}
+	for index, leftDefaults := range left.ThrottleDefaults {
+		if !leftDefaults.equal(right.ThrottleDefaults[index]) {
+			return false
+		}
+	}
 	return true
 }
 
@@ -78,3 +86,25 @@ func (left *Subnet) equal(right *Subnet) bool {
 	}
 	return hypervisor.IpListsEqual(left.ReservedIPs, right.ReservedIPs)
 }
+
+func (left *ThrottleDefaultsForTags) equal(
+	right *ThrottleDefaultsForTags) bool {
+	if left.ThrottleDefaults != right.ThrottleDefaults {
+		return false
+	}
+	if len(left.HypervisorTagsToMatch) != len(right.HypervisorTagsToMatch) {
+		return false
+	}
+	for key, leftValues := range left.HypervisorTagsToMatch {
+		rightValues, ok := right.HypervisorTagsToMatch[key]
+		if !ok || len(leftValues) != len(rightValues) {
+			return false
+		}
+		for index, value := range leftValues {
+			if rightValues[index] != value {
+				return false
+			}
+		}
+	}
+	return true
+}
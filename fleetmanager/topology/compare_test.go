@@ -36,6 +36,12 @@ func testNonzero(t *testing.T, valueValue reflect.Value, equalTest func(),
 			notEqualTest()
 			fieldValue.Set(reflect.MakeMap(fieldValue.Type()))
 			equalTest()
+		case reflect.Ptr:
+			equalTest()
+			fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+			notEqualTest()
+			fieldValue.Set(reflect.Zero(fieldValue.Type()))
+			equalTest()
 		case reflect.Slice:
 			for index := 0; index < fieldValue.Len(); index++ {
 				testNonzero(t, fieldValue.Index(index), equalTest, notEqualTest)
@@ -48,7 +54,7 @@ func testNonzero(t *testing.T, valueValue reflect.Value, equalTest func(),
 			equalTest()
 		case reflect.Struct:
 			testNonzero(t, fieldValue, equalTest, notEqualTest)
-		case reflect.Uint:
+		case reflect.Uint, reflect.Uint64:
 			equalTest()
 			fieldValue.SetUint(1)
 			notEqualTest()
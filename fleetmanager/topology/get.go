@@ -2,6 +2,11 @@ package topology
 
 import (
 	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	"github.com/Cloud-Foundations/Dominator/lib/tags/tagmatcher"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 func (t *Topology) getLocationOfMachine(name string) (string, error) {
@@ -12,6 +17,17 @@ func (t *Topology) getLocationOfMachine(name string) (string, error) {
 	}
 }
 
+func (t *Topology) getMachine(name string) (*fm_proto.Machine, error) {
+	if directory, ok := t.machineParents[name]; ok {
+		for _, machine := range directory.Machines {
+			if machine.Hostname == name {
+				return machine, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown machine: %s", name)
+}
+
 func (t *Topology) getSubnetsForMachine(name string) ([]*Subnet, error) {
 	if directory, ok := t.machineParents[name]; !ok {
 		return nil, fmt.Errorf("unknown machine: %s", name)
@@ -23,3 +39,36 @@ func (t *Topology) getSubnetsForMachine(name string) ([]*Subnet, error) {
 		return subnets, nil
 	}
 }
+
+func (t *Topology) getThrottleDefaultsForMachine(name string,
+	machineTags tags.Tags) (hyper_proto.ThrottleDefaults, error) {
+	var defaults hyper_proto.ThrottleDefaults
+	directory, ok := t.machineParents[name]
+	if !ok {
+		return defaults, fmt.Errorf("unknown machine: %s", name)
+	}
+	for ; directory != nil; directory = directory.parent {
+		for _, entry := range directory.ThrottleDefaults {
+			matcher := tagmatcher.New(entry.HypervisorTagsToMatch, false)
+			if !matcher.MatchEach(machineTags) {
+				continue
+			}
+			network := &defaults.Network
+			if network.ReceiveBytesPerSecond == 0 {
+				network.ReceiveBytesPerSecond =
+					entry.Network.ReceiveBytesPerSecond
+			}
+			if network.TransmitBytesPerSecond == 0 {
+				network.TransmitBytesPerSecond =
+					entry.Network.TransmitBytesPerSecond
+			}
+			if defaults.Volume.BytesPerSecond == 0 {
+				defaults.Volume.BytesPerSecond = entry.Volume.BytesPerSecond
+			}
+			if defaults.Volume.IOPS == 0 {
+				defaults.Volume.IOPS = entry.Volume.IOPS
+			}
+		}
+	}
+	return defaults, nil
+}
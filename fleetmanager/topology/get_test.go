@@ -0,0 +1,83 @@
+package topology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func writeTopologyFile(t *testing.T, filename, data string) {
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetThrottleDefaultsForMachine(t *testing.T) {
+	topDir := t.TempDir()
+	writeTopologyFile(t, filepath.Join(topDir, "throttle-defaults.json"), `[
+	{
+		"HypervisorTagsToMatch": {"Type": ["big"]},
+		"Network": {"ReceiveBytesPerSecond": 100}
+	},
+	{
+		"Network": {"ReceiveBytesPerSecond": 1, "TransmitBytesPerSecond": 5},
+		"Volume": {"IOPS": 10}
+	}
+]`)
+	writeTopologyFile(t, filepath.Join(topDir, "site", "machines.json"), `[
+	{"Hostname": "big", "HostIpAddress": "10.0.0.1", "Tags": {"Type": "big"}},
+	{"Hostname": "small", "HostIpAddress": "10.0.0.2"}
+]`)
+	writeTopologyFile(t,
+		filepath.Join(topDir, "site", "throttle-defaults.json"),
+		`[{"Volume": {"IOPS": 20}}]`)
+	topology, err := Load(topDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bigDefaults := hyper_proto.ThrottleDefaults{
+		Network: hyper_proto.NetworkThrottle{
+			ReceiveBytesPerSecond:  100,
+			TransmitBytesPerSecond: 5,
+		},
+		Volume: hyper_proto.VolumeThrottle{IOPS: 20},
+	}
+	smallDefaults := hyper_proto.ThrottleDefaults{
+		Network: hyper_proto.NetworkThrottle{
+			ReceiveBytesPerSecond:  1,
+			TransmitBytesPerSecond: 5,
+		},
+		Volume: hyper_proto.VolumeThrottle{IOPS: 20},
+	}
+	tests := []struct {
+		name     string
+		tags     tags.Tags
+		expected hyper_proto.ThrottleDefaults
+	}{
+		{"big", tags.Tags{"Type": "big"}, bigDefaults},
+		{"small", nil, smallDefaults},
+		{"small", tags.Tags{"Type": "big"}, bigDefaults}, // Local tags.
+	}
+	for _, test := range tests {
+		got, err := topology.GetThrottleDefaultsForMachine(test.name,
+			test.tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.expected {
+			t.Errorf("%s: tags: %v: expected: %+v, got: %+v",
+				test.name, test.tags, test.expected, got)
+		}
+	}
+	_, err = topology.GetThrottleDefaultsForMachine("unknown", nil)
+	if err == nil {
+		t.Error("no error for unknown machine")
+	}
+}
@@ -304,6 +304,9 @@ func (t *Topology) readDirectory(topDir, dirname string,
 	if err := directory.loadTags(dirpath, iState.tags); err != nil {
 		return nil, err
 	}
+	if err := directory.loadThrottleDefaults(dirpath); err != nil {
+		return nil, err
+	}
 	if err := t.loadMachines(directory, dirpath, cState, iState); err != nil {
 		return nil, err
 	}
@@ -407,6 +410,18 @@ func (directory *Directory) loadTags(dirname string,
 	return nil
 }
 
+func (directory *Directory) loadThrottleDefaults(dirname string) error {
+	filename := filepath.Join(dirname, "throttle-defaults.json")
+	err := json.ReadFromFile(filename, &directory.ThrottleDefaults)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading: %s: %s", filename, err)
+	}
+	return nil
+}
+
 func (owners *ownersType) copy() *ownersType {
 	newOwners := ownersType{
 		OwnerGroups: make([]string, 0, len(owners.OwnerGroups)),
@@ -12,10 +12,11 @@ require (
 	github.com/pin/tftp v2.1.0+incompatible
 	golang.org/x/crypto v0.30.0
 	golang.org/x/net v0.21.0
+	golang.org/x/sys v0.28.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
-	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/term v0.27.0 // indirect
 )
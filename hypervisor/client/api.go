@@ -29,11 +29,21 @@ func ChangeVmCpuPriority(client *srpc.Client, ipAddress net.IP,
 	return changeVmCpuPriority(client, ipAddress, request)
 }
 
+func ChangeVmFirewallPolicy(client *srpc.Client, ipAddress net.IP,
+	policy *proto.FirewallPolicy) error {
+	return changeVmFirewallPolicy(client, ipAddress, policy)
+}
+
 func ChangeVmMachineType(client *srpc.Client, ipAddress net.IP,
 	machineType proto.MachineType) error {
 	return changeVmMachineType(client, ipAddress, machineType)
 }
 
+func ChangeVmThrottles(client *srpc.Client,
+	request proto.ChangeVmThrottlesRequest) error {
+	return changeVmThrottles(client, request)
+}
+
 func ChangeVmSize(client *srpc.Client,
 	request proto.ChangeVmSizeRequest) error {
 	return changeVmSize(client, request)
@@ -110,6 +120,14 @@ func GetVmLastPatchLog(client *srpc.Client, ipAddr net.IP) (
 	return getVmLastPatchLog(client, ipAddr)
 }
 
+// GetVmMetrics returns up to maximumSamples of the most recent resource usage
+// samples for the VM. If maximumSamples is zero, all retained samples are
+// returned.
+func GetVmMetrics(client *srpc.Client, ipAddr net.IP, maximumSamples uint) (
+	[]proto.VmMetrics, error) {
+	return getVmMetrics(client, ipAddr, maximumSamples)
+}
+
 func HoldLock(client *srpc.Client, timeout time.Duration,
 	writeLock bool) error {
 	return holdLock(client, timeout, writeLock)
@@ -64,6 +64,21 @@ func changeVmCpuPriority(client *srpc.Client, ipAddress net.IP,
 	return errors.New(reply.Error)
 }
 
+func changeVmFirewallPolicy(client *srpc.Client, ipAddress net.IP,
+	policy *proto.FirewallPolicy) error {
+	request := proto.ChangeVmFirewallPolicyRequest{
+		FirewallPolicy: policy,
+		IpAddress:      ipAddress,
+	}
+	var reply proto.ChangeVmFirewallPolicyResponse
+	err := client.RequestReply("Hypervisor.ChangeVmFirewallPolicy", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
 func changeVmMachineType(client *srpc.Client, ipAddress net.IP,
 	consoleType proto.MachineType) error {
 	request := proto.ChangeVmMachineTypeRequest{
@@ -79,6 +94,17 @@ func changeVmMachineType(client *srpc.Client, ipAddress net.IP,
 	return errors.New(reply.Error)
 }
 
+func changeVmThrottles(client *srpc.Client,
+	request proto.ChangeVmThrottlesRequest) error {
+	var reply proto.ChangeVmThrottlesResponse
+	err := client.RequestReply("Hypervisor.ChangeVmThrottles", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
 func changeVmSize(client *srpc.Client,
 	request proto.ChangeVmSizeRequest) error {
 	var reply proto.ChangeVmSizeResponse
@@ -343,6 +369,23 @@ func getVmInfos(client *srpc.Client,
 	return reply.VmInfos, nil
 }
 
+func getVmMetrics(client *srpc.Client, ipAddr net.IP, maximumSamples uint) (
+	[]proto.VmMetrics, error) {
+	request := proto.GetVmMetricsRequest{
+		IpAddress:      ipAddr,
+		MaximumSamples: maximumSamples,
+	}
+	var reply proto.GetVmMetricsResponse
+	err := client.RequestReply("Hypervisor.GetVmMetrics", request, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return nil, err
+	}
+	return reply.Samples, nil
+}
+
 func getVmLastPatchLog(client *srpc.Client, ipAddr net.IP) (
 	[]byte, time.Time, error) {
 	conn, err := client.Call("Hypervisor.GetVmLastPatchLog")
@@ -15,7 +15,9 @@ type DhcpServer struct {
 	dynamicLeasesFile string
 	logger            log.DebugLogger
 	cleanupTrigger    chan<- struct{}
+	httpBootPort      uint
 	interfaceIPs      map[string][]net.IP // Key: interface name.
+	ipxeImage         string
 	myIPs             []net.IP
 	networkBootImage  string
 	requestInterface  string
@@ -37,6 +39,7 @@ type leaseType struct {
 	expires        time.Time
 	hostname       string
 	doNetboot      bool
+	netbootMethod  proto.NetbootMethod
 	subnet         *subnetType
 }
 
@@ -53,12 +56,12 @@ func New(interfaceNames []string, dynamicLeasesFile string,
 }
 
 func (s *DhcpServer) AddLease(address proto.Address, hostname string) error {
-	return s.addLease(address, false, hostname, nil)
+	return s.addLease(address, false, 0, hostname, nil)
 }
 
 func (s *DhcpServer) AddNetbootLease(address proto.Address,
-	hostname string, subnet *proto.Subnet) error {
-	return s.addLease(address, true, hostname, subnet)
+	hostname string, subnet *proto.Subnet, method proto.NetbootMethod) error {
+	return s.addLease(address, true, method, hostname, subnet)
 }
 
 func (s *DhcpServer) AddSubnet(subnet proto.Subnet) {
@@ -90,6 +93,20 @@ func (s *DhcpServer) RemoveSubnet(subnetId string) {
 	s.removeSubnet(subnetId)
 }
 
+// SetHttpBootPort sets the port number of the HTTP server which serves the
+// network boot files, used for UEFI HTTP Boot and iPXE clients.
+func (s *DhcpServer) SetHttpBootPort(portNum uint) {
+	s.httpBootPort = portNum
+}
+
+// SetIpxeImage sets the name of the iPXE image to chain-load. The name may
+// contain a %d format specifier, which is replaced with the client
+// architecture.
+func (s *DhcpServer) SetIpxeImage(name string) error {
+	s.ipxeImage = name
+	return nil
+}
+
 func (s *DhcpServer) SetNetworkBootImage(nbiName string) error {
 	s.networkBootImage = nbiName
 	return nil
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
@@ -176,6 +177,26 @@ func newServer(interfaceNames []string, dynamicLeasesFile string,
 	return dhcpServer, nil
 }
 
+// selectOptions returns the lease options requested by the client. The vendor
+// class is always included if set, since UEFI HTTP Boot clients require it.
+func selectOptions(leaseOptions, reqOptions dhcp.Options) []dhcp.Option {
+	selected := leaseOptions.SelectOrderOrAll(
+		reqOptions[dhcp.OptionParameterRequestList])
+	vendorClass, ok := leaseOptions[dhcp.OptionVendorClassIdentifier]
+	if !ok {
+		return selected
+	}
+	for _, option := range selected {
+		if option.Code == dhcp.OptionVendorClassIdentifier {
+			return selected
+		}
+	}
+	return append(selected, dhcp.Option{
+		Code:  dhcp.OptionVendorClassIdentifier,
+		Value: vendorClass,
+	})
+}
+
 func (s *DhcpServer) acknowledgeLease(ipAddr net.IP) {
 	ipStr := ipAddr.String()
 	s.mutex.Lock()
@@ -189,7 +210,8 @@ func (s *DhcpServer) acknowledgeLease(ipAddr net.IP) {
 }
 
 func (s *DhcpServer) addLease(address proto.Address, doNetboot bool,
-	hostname string, protoSubnet *proto.Subnet) error {
+	netbootMethod proto.NetbootMethod, hostname string,
+	protoSubnet *proto.Subnet) error {
 	address.Shrink()
 	if len(address.IpAddress) < 1 {
 		return errors.New("no IP address")
@@ -209,6 +231,15 @@ func (s *DhcpServer) addLease(address proto.Address, doNetboot bool,
 		if len(s.networkBootImage) < 1 {
 			return errors.New("no Network Boot Image name configured")
 		}
+		if err := netbootMethod.CheckValid(); err != nil {
+			return err
+		}
+		if netbootMethod != proto.NetbootMethodTFTP && s.httpBootPort < 1 {
+			return errors.New("no HTTP boot port configured")
+		}
+		if netbootMethod == proto.NetbootMethodIPXE && s.ipxeImage == "" {
+			return errors.New("no iPXE image name configured")
+		}
 		if _, ok := s.staticLeases[address.MacAddress]; ok {
 			return errors.New("already have lease for: " + address.MacAddress)
 		}
@@ -237,14 +268,55 @@ func (s *DhcpServer) addLease(address proto.Address, doNetboot bool,
 	}
 	s.ipAddrToMacAddr[ipAddr] = address.MacAddress
 	s.staticLeases[address.MacAddress] = leaseType{
-		Address:   address,
-		hostname:  hostname,
-		doNetboot: doNetboot,
-		subnet:    subnet,
+		Address:       address,
+		hostname:      hostname,
+		doNetboot:     doNetboot,
+		netbootMethod: netbootMethod,
+		subnet:        subnet,
 	}
 	return nil
 }
 
+// addNetbootOptions adds the boot file option for the client. UEFI HTTP Boot
+// clients are given a URL and must be told that the reply is for HTTP Boot.
+// Clients which are chain-loading iPXE are given the iPXE image, and once iPXE
+// is running it is given the URL of the generated iPXE script.
+func (s *DhcpServer) addNetbootOptions(leaseOptions dhcp.Options,
+	subnet *subnetType, lease *leaseType, reqOptions dhcp.Options) {
+	var clientArchitecture uint16
+	if ca := reqOptions[dhcp.OptionClientArchitecture]; len(ca) > 1 {
+		clientArchitecture = uint16(ca[0])<<8 + uint16(ca[1])
+	}
+	isHttpClient := strings.HasPrefix(
+		string(reqOptions[dhcp.OptionVendorClassIdentifier]), "HTTPClient")
+	isIpxe := strings.Contains(string(reqOptions[dhcp.OptionUserClass]),
+		"iPXE")
+	bootFile := fmt.Sprintf(s.networkBootImage, clientArchitecture)
+	useHttp := false
+	switch lease.netbootMethod {
+	case proto.NetbootMethodHTTP:
+		useHttp = isHttpClient
+	case proto.NetbootMethodIPXE:
+		if isIpxe {
+			bootFile = constants.NetbootIpxeScriptFile
+			useHttp = true
+		} else {
+			bootFile = fmt.Sprintf(s.ipxeImage, clientArchitecture)
+			useHttp = isHttpClient
+		}
+	}
+	if useHttp {
+		bootFile = fmt.Sprintf("http://%s:%d%s/%s", subnet.myIP,
+			s.httpBootPort, constants.NetbootHttpPrefix,
+			strings.TrimPrefix(bootFile, "/"))
+		if isHttpClient {
+			leaseOptions[dhcp.OptionVendorClassIdentifier] = []byte(
+				"HTTPClient")
+		}
+	}
+	leaseOptions[dhcp.OptionBootFileName] = []byte(bootFile)
+}
+
 func (s *DhcpServer) addSubnet(protoSubnet proto.Subnet) {
 	subnet := s.makeSubnet(&protoSubnet)
 	var ifaceName string
@@ -525,12 +597,7 @@ func (s *DhcpServer) makeOptions(subnet *subnetType,
 		leaseOptions[dhcp.OptionHostName] = []byte(lease.hostname)
 	}
 	if lease.doNetboot {
-		var clientArchitecture uint16
-		if ca := reqOptions[dhcp.OptionClientArchitecture]; len(ca) > 1 {
-			clientArchitecture = uint16(ca[0])<<8 + uint16(ca[1])
-		}
-		leaseOptions[dhcp.OptionBootFileName] = []byte(
-			fmt.Sprintf(s.networkBootImage, clientArchitecture))
+		s.addNetbootOptions(leaseOptions, subnet, lease, reqOptions)
 	}
 	return leaseOptions
 }
@@ -687,8 +754,7 @@ func (s *DhcpServer) ServeDHCP(req dhcp.Packet, msgType dhcp.MessageType,
 		leaseOptions := s.makeOptions(subnet, lease, options)
 		packet := dhcp.ReplyPacket(req, dhcp.Offer, subnet.myIP,
 			lease.IpAddress, s.computeLeaseTime(lease, true),
-			leaseOptions.SelectOrderOrAll(
-				options[dhcp.OptionParameterRequestList]))
+			selectOptions(leaseOptions, options))
 		packet.SetSIAddr(subnet.myIP)
 		return packet
 	case dhcp.Request:
@@ -753,8 +819,8 @@ func (s *DhcpServer) ServeDHCP(req dhcp.Packet, msgType dhcp.MessageType,
 			s.logger.Debugf(0, "ACK for: %s to: %s on: %s, server: %s\n",
 				reqIP, macAddr, s.requestInterface, subnet.myIP)
 			packet := dhcp.ReplyPacket(req, dhcp.ACK, subnet.myIP, reqIP,
-				s.computeLeaseTime(lease, false), leaseOptions.SelectOrderOrAll(
-					options[dhcp.OptionParameterRequestList]))
+				s.computeLeaseTime(lease, false),
+				selectOptions(leaseOptions, options))
 			packet.SetSIAddr(subnet.myIP)
 			return packet
 		} else {
@@ -0,0 +1,127 @@
+package dhcpd
+
+import (
+	"net"
+	"testing"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+	dhcp "github.com/krolaw/dhcp4"
+)
+
+func TestAddNetbootOptions(t *testing.T) {
+	server := &DhcpServer{
+		httpBootPort:     6976,
+		ipxeImage:        "ipxe.%d",
+		networkBootImage: "pxelinux.%d",
+	}
+	subnet := &subnetType{myIP: net.IPv4(10, 0, 0, 1)}
+	const (
+		efiX64  = "\x00\x07"
+		httpX64 = "\x00\x10"
+	)
+	tests := []struct {
+		name         string
+		method       proto.NetbootMethod
+		architecture string
+		vendorClass  string
+		userClass    string
+		bootFile     string
+		httpClient   bool // Expect the HTTPClient vendor class in the reply.
+	}{
+		{
+			name:     "tftpBios",
+			method:   proto.NetbootMethodTFTP,
+			bootFile: "pxelinux.0",
+		},
+		{
+			name:         "tftpEfi",
+			method:       proto.NetbootMethodTFTP,
+			architecture: efiX64,
+			vendorClass:  "PXEClient:Arch:00007",
+			bootFile:     "pxelinux.7",
+		},
+		{
+			name:         "tftpHttpClient",
+			method:       proto.NetbootMethodTFTP,
+			architecture: httpX64,
+			vendorClass:  "HTTPClient:Arch:00016",
+			bootFile:     "pxelinux.16",
+		},
+		{
+			name:         "httpHttpClient",
+			method:       proto.NetbootMethodHTTP,
+			architecture: httpX64,
+			vendorClass:  "HTTPClient:Arch:00016",
+			bootFile:     "http://10.0.0.1:6976/tftpboot/pxelinux.16",
+			httpClient:   true,
+		},
+		{
+			name:         "httpPxeClient",
+			method:       proto.NetbootMethodHTTP,
+			architecture: efiX64,
+			vendorClass:  "PXEClient:Arch:00007",
+			bootFile:     "pxelinux.7",
+		},
+		{
+			name:         "ipxeChainLoadTftp",
+			method:       proto.NetbootMethodIPXE,
+			architecture: efiX64,
+			vendorClass:  "PXEClient:Arch:00007",
+			bootFile:     "ipxe.7",
+		},
+		{
+			name:         "ipxeChainLoadHttp",
+			method:       proto.NetbootMethodIPXE,
+			architecture: httpX64,
+			vendorClass:  "HTTPClient:Arch:00016",
+			bootFile:     "http://10.0.0.1:6976/tftpboot/ipxe.16",
+			httpClient:   true,
+		},
+		{
+			name:         "ipxeScript",
+			method:       proto.NetbootMethodIPXE,
+			architecture: efiX64,
+			vendorClass:  "PXEClient:Arch:00007",
+			userClass:    "iPXE",
+			bootFile:     "http://10.0.0.1:6976/tftpboot/netboot.ipxe",
+		},
+		{
+			name:         "tftpIgnoresIpxe",
+			method:       proto.NetbootMethodTFTP,
+			architecture: efiX64,
+			userClass:    "iPXE",
+			bootFile:     "pxelinux.7",
+		},
+	}
+	for _, test := range tests {
+		reqOptions := make(dhcp.Options)
+		if test.architecture != "" {
+			reqOptions[dhcp.OptionClientArchitecture] = []byte(
+				test.architecture)
+		}
+		if test.vendorClass != "" {
+			reqOptions[dhcp.OptionVendorClassIdentifier] = []byte(
+				test.vendorClass)
+		}
+		if test.userClass != "" {
+			reqOptions[dhcp.OptionUserClass] = []byte(test.userClass)
+		}
+		leaseOptions := make(dhcp.Options)
+		server.addNetbootOptions(leaseOptions, subnet,
+			&leaseType{netbootMethod: test.method}, reqOptions)
+		if got := string(leaseOptions[dhcp.OptionBootFileName]); got !=
+			test.bootFile {
+			t.Errorf("%s: boot file: got: %s, want: %s",
+				test.name, got, test.bootFile)
+		}
+		vendorClass, ok := leaseOptions[dhcp.OptionVendorClassIdentifier]
+		if test.httpClient {
+			if string(vendorClass) != "HTTPClient" {
+				t.Errorf("%s: vendor class: got: \"%s\", want: HTTPClient",
+					test.name, vendorClass)
+			}
+		} else if ok {
+			t.Errorf("%s: unexpected vendor class: %s", test.name, vendorClass)
+		}
+	}
+}
@@ -35,6 +35,7 @@ func StartServer(portNum uint, managerObj *manager.Manager, daemon bool) error {
 		myState.listRegisteredAddressesHandler)
 	html.HandleFunc("/listSubnets", myState.listSubnetsHandler)
 	html.HandleFunc("/listVMs", myState.listVMsHandler)
+	html.HandleFunc("/prometheusMetrics", myState.prometheusMetricsHandler)
 	html.HandleFunc("/showVmBootLog", myState.showBootLogHandler)
 	html.HandleFunc("/showVmLastPatchLog", myState.showLastPatchLogHandler)
 	html.HandleFunc("/showVM", myState.showVMHandler)
@@ -0,0 +1,152 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+type prometheusMetricType struct {
+	help       string
+	metricType string
+	name       string
+	getValues  func(sample proto.VmMetrics) []prometheusValueType
+}
+
+type prometheusValueType struct {
+	labels string
+	value  float64
+}
+
+var prometheusMetrics = []prometheusMetricType{
+	{
+		help:       "Size of the memory balloon for the VM",
+		metricType: "gauge",
+		name:       "hypervisor_vm_balloon_bytes",
+		getValues: func(sample proto.VmMetrics) []prometheusValueType {
+			return []prometheusValueType{{"", float64(sample.BalloonBytes)}}
+		},
+	},
+	{
+		help:       "CPU time used by the VM",
+		metricType: "counter",
+		name:       "hypervisor_vm_cpu_seconds_total",
+		getValues: func(sample proto.VmMetrics) []prometheusValueType {
+			return []prometheusValueType{{"", sample.CpuTime.Seconds()}}
+		},
+	},
+	{
+		help:       "Resident memory used by the VM",
+		metricType: "gauge",
+		name:       "hypervisor_vm_memory_resident_bytes",
+		getValues: func(sample proto.VmMetrics) []prometheusValueType {
+			return []prometheusValueType{
+				{"", float64(sample.MemoryResidentBytes)},
+			}
+		},
+	},
+	makeInterfaceMetric("receive_bytes", "Bytes received by the VM",
+		func(metrics proto.NetworkInterfaceMetrics) uint64 {
+			return metrics.ReceiveBytes
+		}),
+	makeInterfaceMetric("receive_packets", "Packets received by the VM",
+		func(metrics proto.NetworkInterfaceMetrics) uint64 {
+			return metrics.ReceivePackets
+		}),
+	makeInterfaceMetric("transmit_bytes", "Bytes transmitted by the VM",
+		func(metrics proto.NetworkInterfaceMetrics) uint64 {
+			return metrics.TransmitBytes
+		}),
+	makeInterfaceMetric("transmit_packets", "Packets transmitted by the VM",
+		func(metrics proto.NetworkInterfaceMetrics) uint64 {
+			return metrics.TransmitPackets
+		}),
+	makeVolumeMetric("read_bytes", "Bytes read from the volume",
+		func(metrics proto.VolumeMetrics) uint64 {
+			return metrics.ReadBytes
+		}),
+	makeVolumeMetric("read_operations", "Read operations on the volume",
+		func(metrics proto.VolumeMetrics) uint64 {
+			return metrics.ReadOperations
+		}),
+	makeVolumeMetric("write_bytes", "Bytes written to the volume",
+		func(metrics proto.VolumeMetrics) uint64 {
+			return metrics.WriteBytes
+		}),
+	makeVolumeMetric("write_operations", "Write operations on the volume",
+		func(metrics proto.VolumeMetrics) uint64 {
+			return metrics.WriteOperations
+		}),
+}
+
+func makeInterfaceMetric(name, help string,
+	getValue func(proto.NetworkInterfaceMetrics) uint64) prometheusMetricType {
+	return prometheusMetricType{
+		help:       help,
+		metricType: "counter",
+		name:       "hypervisor_vm_network_" + name + "_total",
+		getValues: func(sample proto.VmMetrics) []prometheusValueType {
+			values := make([]prometheusValueType, 0,
+				len(sample.NetworkInterfaces))
+			for index, metrics := range sample.NetworkInterfaces {
+				values = append(values, prometheusValueType{
+					fmt.Sprintf(`,interface="%d"`, index),
+					float64(getValue(metrics)),
+				})
+			}
+			return values
+		},
+	}
+}
+
+func makeVolumeMetric(name, help string,
+	getValue func(proto.VolumeMetrics) uint64) prometheusMetricType {
+	return prometheusMetricType{
+		help:       help,
+		metricType: "counter",
+		name:       "hypervisor_vm_volume_" + name + "_total",
+		getValues: func(sample proto.VmMetrics) []prometheusValueType {
+			values := make([]prometheusValueType, 0, len(sample.Volumes))
+			for index, metrics := range sample.Volumes {
+				values = append(values, prometheusValueType{
+					fmt.Sprintf(`,volume="%d"`, index),
+					float64(getValue(metrics)),
+				})
+			}
+			return values
+		},
+	}
+}
+
+func writePrometheusMetrics(writer io.Writer,
+	latestMetrics map[string]proto.VmMetrics) {
+	ipAddrs := make([]string, 0, len(latestMetrics))
+	for ipAddr := range latestMetrics {
+		ipAddrs = append(ipAddrs, ipAddr)
+	}
+	sort.Strings(ipAddrs)
+	for _, metric := range prometheusMetrics {
+		fmt.Fprintf(writer, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(writer, "# TYPE %s %s\n", metric.name, metric.metricType)
+		for _, ipAddr := range ipAddrs {
+			sample := latestMetrics[ipAddr]
+			for _, value := range metric.getValues(sample) {
+				fmt.Fprintf(writer, "%s{ip=\"%s\"%s} %g %d\n",
+					metric.name, ipAddr, value.labels, value.value,
+					sample.Timestamp.UnixNano()/1000000)
+			}
+		}
+	}
+}
+
+func (s state) prometheusMetricsHandler(w http.ResponseWriter,
+	req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	writePrometheusMetrics(writer, s.manager.GetLatestVmMetrics())
+}
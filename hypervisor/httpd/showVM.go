@@ -97,6 +97,14 @@ func (s state) showVMHandler(w http.ResponseWriter, req *http.Request) {
 		writeTime(writer, "Created on", vm.CreatedOn)
 		writeTime(writer, "Last state change", vm.ChangedStateOn)
 		writeString(writer, "State", vm.State.String())
+		writeString(writer, "Firmware", vm.FirmwareType.String())
+		writeBool(writer, "Virtual TPM", vm.VirtualTPM)
+		if policy := vm.FirewallPolicy; policy != nil {
+			writeString(writer, "Firewall policy", fmt.Sprintf(
+				"ingress: %d rules, default %s; egress: %d rules, default %s",
+				len(policy.IngressRules), policy.IngressDefault,
+				len(policy.EgressRules), policy.EgressDefault))
+		}
 		writeString(writer, "RAM", format.FormatBytes(vm.MemoryInMiB<<20))
 		writeString(writer, "CPU", format.FormatMilli(uint64(vm.MilliCPUs)))
 		writeStrings(writer, "Volume sizes", volumeSizes)
@@ -16,6 +16,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
 	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+	installer_proto "github.com/Cloud-Foundations/Dominator/proto/installer"
 )
 
 const (
@@ -46,6 +47,10 @@ type DhcpServer interface {
 
 type Manager struct {
 	StartOptions
+	firewallRefresh   chan<- struct{}
+	firewallMutex     sync.Mutex                // Protect firewallVms.
+	firewallVms       map[string]firewallVmType // Key: IP address.
+	hardwareInventory *proto.HardwareInventory
 	healthStatusMutex sync.RWMutex
 	healthStatus      string
 	lockWatcher       *lockwatcher.LockWatcher
@@ -72,6 +77,7 @@ type Manager struct {
 	ownerUsers        map[string]struct{}
 	subnets           map[string]proto.Subnet // Key: Subnet ID.
 	subnetChannels    []chan<- proto.Subnet
+	throttleDefaults  proto.ThrottleDefaults // From Hypervisor tags.
 	totalVolumeBytes  uint64
 	vms               map[string]*vmInfoType // Key: IP address.
 	vsocketsEnabled   bool
@@ -91,6 +97,7 @@ type StartOptions struct {
 	ObjectCacheBytes     uint64
 	ShowVgaConsole       bool
 	StateDir             string
+	StorageLayout        *installer_proto.StorageLayout // Used if no VolumeDirectories.
 	Username             string
 	VlanIdToBridge       map[uint]string // Key: VLAN ID, value: bridge interface.
 	VolumeDirectories    []string
@@ -119,6 +126,7 @@ type vmInfoType struct {
 	destroyTimer               *time.Timer
 	dirname                    string
 	doNotWriteOrSend           bool
+	firewallRuleset            string
 	hasHealthAgent             bool
 	identityProviderNotifier   chan<- time.Time
 	identityProviderTransport  *http.Transport
@@ -126,9 +134,11 @@ type vmInfoType struct {
 	logger                     log.DebugLogger
 	manager                    *Manager
 	metadataChannels           map[chan<- string]struct{}
+	metrics                    vmMetricsType
 	monitorSockname            string
 	blockMutations             bool
 	ownerUsers                 map[string]struct{}
+	qmpReplies                 *qmpRepliesType
 	serialInput                io.Writer
 	serialOutput               chan<- byte
 	stoppedNotifier            chan<- struct{}
@@ -182,6 +192,11 @@ func (m *Manager) ChangeVmDestroyProtection(ipAddr net.IP,
 	return m.changeVmDestroyProtection(ipAddr, authInfo, destroyProtection)
 }
 
+func (m *Manager) ChangeVmFirewallPolicy(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, policy *proto.FirewallPolicy) error {
+	return m.changeVmFirewallPolicy(ipAddr, authInfo, policy)
+}
+
 func (m *Manager) ChangeVmMachineType(ipAddr net.IP,
 	authInfo *srpc.AuthInformation, machineType proto.MachineType) error {
 	return m.changeVmMachineType(ipAddr, authInfo, machineType)
@@ -212,6 +227,13 @@ func (m *Manager) ChangeVmTags(ipAddr net.IP, authInfo *srpc.AuthInformation,
 	return m.changeVmTags(ipAddr, authInfo, tgs)
 }
 
+func (m *Manager) ChangeVmThrottles(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, networkThrottles []proto.NetworkThrottle,
+	volumeThrottles []proto.VolumeThrottle) error {
+	return m.changeVmThrottles(ipAddr, authInfo, networkThrottles,
+		volumeThrottles)
+}
+
 func (m *Manager) ChangeVmVolumeInterfaces(ipAddr net.IP,
 	authInfo *srpc.AuthInformation,
 	volumeInterfaces []proto.VolumeInterface) error {
@@ -346,6 +368,12 @@ func (m *Manager) GetVolumeDirectories() map[string]VolumeInfo {
 	return m.volumeInfos
 }
 
+// GetLatestVmMetrics returns the most recent resource usage sample for each
+// VM, keyed by IP address.
+func (m *Manager) GetLatestVmMetrics() map[string]proto.VmMetrics {
+	return m.getLatestVmMetrics()
+}
+
 func (m *Manager) GetVmAccessToken(ipAddr net.IP,
 	authInfo *srpc.AuthInformation, lifetime time.Duration) ([]byte, error) {
 	return m.getVmAccessToken(ipAddr, authInfo, lifetime)
@@ -386,6 +414,11 @@ func (m *Manager) GetVmLockWatcher(ipAddr net.IP) (
 	return m.getVmLockWatcher(ipAddr)
 }
 
+func (m *Manager) GetVmMetrics(ipAddr net.IP, maximumSamples uint) (
+	[]proto.VmMetrics, error) {
+	return m.getVmMetrics(ipAddr, maximumSamples)
+}
+
 func (m *Manager) GetVmUserData(ipAddr net.IP) (io.ReadCloser, error) {
 	rc, _, err := m.getVmFileReader(ipAddr,
 		&srpc.AuthInformation{HaveMethodAccess: true},
@@ -543,6 +576,10 @@ func (m *Manager) SetDisabledState(disable bool) error {
 	return m.setDisabledState(disable)
 }
 
+func (m *Manager) SetThrottleDefaults(defaults proto.ThrottleDefaults) error {
+	return m.setThrottleDefaults(defaults)
+}
+
 func (m *Manager) ShutdownVMsAndExit() {
 	m.shutdownVMsAndExit()
 }
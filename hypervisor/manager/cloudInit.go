@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Cloud-Foundations/Dominator/lib/cloudinit"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const cloudInitSeedFilename = "cloud-init-seed.iso"
+
+var (
+	cloudInitSeed = flag.Bool("cloudInitSeed", false,
+		"If true, attach a cloud-init NoCloud seed image to VMs on subnets where the metadata service is disabled")
+)
+
+// setupCloudInitSeed will create the NoCloud seed image for the VM if needed
+// and returns the QEMU options to attach it.
+func (vm *vmInfoType) setupCloudInitSeed(haveManagerLock bool) (
+	[]string, error) {
+	filename := filepath.Join(vm.dirname, cloudInitSeedFilename)
+	if !haveManagerLock {
+		vm.manager.mutex.RLock()
+	}
+	primarySubnet := vm.manager.subnets[vm.SubnetId]
+	subnets := make(map[string]proto.Subnet, len(vm.manager.subnets))
+	for id, subnet := range vm.manager.subnets {
+		subnets[id] = subnet
+	}
+	if !haveManagerLock {
+		vm.manager.mutex.RUnlock()
+	}
+	if !*cloudInitSeed || !primarySubnet.DisableMetadata {
+		os.Remove(filename)
+		return nil, nil
+	}
+	userData, err := ioutil.ReadFile(filepath.Join(vm.dirname, UserDataFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	metadata := cloudinit.New(vm.VmInfo, subnets)
+	if err := metadata.MakeSeedImage(filename, userData); err != nil {
+		return nil, err
+	}
+	return []string{
+		"-drive",
+		"file=" + filename + ",format=raw,if=ide,media=cdrom,readonly=on",
+	}, nil
+}
@@ -0,0 +1,392 @@
+package manager
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	"github.com/Cloud-Foundations/Dominator/lib/tags/tagmatcher"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+var (
+	nftCommand = flag.String("nftCommand", "nft",
+		"nftables command used to apply VM firewall policies")
+)
+
+type firewallInterface struct {
+	policy  *proto.FirewallPolicy
+	tapName string
+}
+
+// firewallVmType is a snapshot of the VM fields which tag rules match on. It
+// is owned by the Manager so that rules can be generated without taking the
+// lock for every other VM.
+type firewallVmType struct {
+	ipAddrs []string // Primary address first.
+	key     string   // Used to detect changes.
+	tags    tags.Tags
+}
+
+// getTapName returns the name of the tap device for the VM network interface
+// with the specified address. The name is derived from the MAC address so that
+// it is stable across restarts and migrations.
+func getTapName(address proto.Address) string {
+	return "vt" + strings.Replace(strings.ToLower(address.MacAddress), ":", "",
+		-1)
+}
+
+// makeFirewallVm returns the snapshot of vm used for matching tag rules.
+func makeFirewallVm(ipAddress string, vm *proto.VmInfo) firewallVmType {
+	ipAddrs := make([]string, 0, len(vm.SecondaryAddresses)+1)
+	ipAddrs = append(ipAddrs, ipAddress)
+	for _, address := range vm.SecondaryAddresses {
+		ipAddrs = append(ipAddrs, address.IpAddress.String())
+	}
+	return firewallVmType{
+		ipAddrs: ipAddrs,
+		key:     fmt.Sprintf("%v %v %v", vm.Tags, ipAddrs, vm.FirewallPolicy),
+		tags:    vm.Tags.Copy(),
+	}
+}
+
+func writeFirewallChain(writer io.Writer, name string, rules []string,
+	defaultAction proto.FirewallAction) {
+	fmt.Fprintf(writer, "\tchain %s {\n", name)
+	fmt.Fprintln(writer, "\t\tct state established,related accept")
+	fmt.Fprintln(writer, "\t\tether type arp accept")
+	for _, rule := range rules {
+		fmt.Fprintf(writer, "\t\t%s\n", rule)
+	}
+	fmt.Fprintf(writer, "\t\t%s\n", defaultAction)
+	fmt.Fprintln(writer, "\t}")
+}
+
+// applyFirewall will install the firewall rules for the VM, if they have
+// changed. The tap devices must exist. The VM lock must be held.
+func (vm *vmInfoType) applyFirewall(haveManagerLock bool) error {
+	if !haveManagerLock {
+		vm.manager.mutex.RLock()
+	}
+	interfaces := vm.getFirewallInterfaces()
+	if !haveManagerLock {
+		vm.manager.mutex.RUnlock()
+	}
+	tableName := vm.getFirewallTableName()
+	ruleset, err := vm.manager.makeFirewallRuleset(tableName, interfaces)
+	if err != nil {
+		return err
+	}
+	if ruleset == vm.firewallRuleset {
+		return nil
+	}
+	for _, netInterface := range interfaces {
+		if netInterface.policy == nil {
+			continue
+		}
+		if err := checkTapDevice(netInterface.tapName); err != nil {
+			return err
+		}
+	}
+	// Declaring the table first ensures that the deletion succeeds even if the
+	// table does not yet exist. The whole script is applied atomically.
+	script := fmt.Sprintf("table bridge %s\ndelete table bridge %s\n%s",
+		tableName, tableName, ruleset)
+	if err := runNft(script); err != nil {
+		return err
+	}
+	vm.firewallRuleset = ruleset
+	if ruleset == "" {
+		vm.logger.Debugln(0, "removed firewall rules")
+	} else {
+		vm.logger.Debugln(0, "applied firewall rules")
+	}
+	return nil
+}
+
+func (m *Manager) changeVmFirewallPolicy(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, policy *proto.FirewallPolicy) error {
+	if policy != nil {
+		if err := policy.CheckValid(); err != nil {
+			return err
+		}
+	}
+	vm, err := m.getVmLockAndAuth(ipAddr, true, authInfo, nil)
+	if err != nil {
+		return err
+	}
+	defer vm.mutex.Unlock()
+	oldPolicy := vm.FirewallPolicy
+	vm.FirewallPolicy = policy
+	if vm.State == proto.StateRunning {
+		if err := vm.applyFirewall(false); err != nil {
+			vm.FirewallPolicy = oldPolicy
+			return err
+		}
+	}
+	vm.writeAndSendInfo()
+	return nil
+}
+
+// checkFirewallRefresh will update the firewall snapshot for the VM and will
+// request a refresh of the firewall rules for all VMs if the VM tags, addresses
+// or firewall policy have changed, since these are what tag rules match on. If
+// vm is nil, the VM was destroyed. The VM lock must be held.
+func (m *Manager) checkFirewallRefresh(ipAddress string, vm *proto.VmInfo) {
+	var firewallVm firewallVmType
+	if vm != nil {
+		firewallVm = makeFirewallVm(ipAddress, vm)
+	}
+	m.firewallMutex.Lock()
+	oldFirewallVm, ok := m.firewallVms[ipAddress]
+	if vm == nil {
+		delete(m.firewallVms, ipAddress)
+	} else {
+		if m.firewallVms == nil {
+			m.firewallVms = make(map[string]firewallVmType)
+		}
+		m.firewallVms[ipAddress] = firewallVm
+	}
+	m.firewallMutex.Unlock()
+	if vm == nil && !ok {
+		return
+	}
+	if ok && firewallVm.key == oldFirewallVm.key {
+		return
+	}
+	m.requestFirewallRefresh()
+}
+
+// checkTapDevice returns an error if the tap device does not exist. VMs which
+// were started by an older Hypervisor have kernel-assigned tap names, so
+// firewall rules cannot be attached to them until the VM is restarted.
+func checkTapDevice(tapName string) error {
+	if _, err := os.Stat(filepath.Join("/sys/class/net", tapName)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf(
+				"tap device: %s not found, restart VM to apply firewall policy",
+				tapName)
+		}
+		return err
+	}
+	return nil
+}
+
+// getFirewallInterfaces returns the network interfaces for the VM along with
+// the firewall policy for each. The VM policy takes precedence over the subnet
+// policy. The Manager lock must be held.
+func (vm *vmInfoType) getFirewallInterfaces() []firewallInterface {
+	interfaces := make([]firewallInterface, 0,
+		len(vm.SecondaryAddresses)+1)
+	addresses := append([]proto.Address{vm.Address}, vm.SecondaryAddresses...)
+	subnetIDs := append([]string{vm.SubnetId}, vm.SecondarySubnetIDs...)
+	for index, address := range addresses {
+		policy := vm.FirewallPolicy
+		if policy == nil && index < len(subnetIDs) {
+			if subnet, ok := vm.manager.subnets[subnetIDs[index]]; ok {
+				policy = subnet.FirewallPolicy
+			}
+		}
+		interfaces = append(interfaces, firewallInterface{
+			policy:  policy,
+			tapName: getTapName(address),
+		})
+	}
+	return interfaces
+}
+
+func (vm *vmInfoType) getFirewallTableName() string {
+	return "vm_" + strings.Replace(vm.ipAddress, ".", "_", -1)
+}
+
+// makeFirewallRule returns the nftables rule corresponding to rule. The
+// addressKeyword is "saddr" for ingress rules and "daddr" for egress rules.
+// If the rule cannot match anything, an empty string is returned.
+func (m *Manager) makeFirewallRule(rule proto.FirewallRule,
+	addressKeyword string) string {
+	var fields []string
+	if rule.Cidr != "" {
+		fields = append(fields, "ip", addressKeyword, rule.Cidr)
+	} else if len(rule.VmTags) > 0 {
+		ipAddrs := m.matchVmIPs(rule.VmTags)
+		if len(ipAddrs) < 1 {
+			return ""
+		}
+		fields = append(fields, "ip", addressKeyword,
+			"{ "+strings.Join(ipAddrs, ", ")+" }")
+	}
+	switch rule.Protocol {
+	case "icmp":
+		fields = append(fields, "ip", "protocol", "icmp")
+	case "tcp", "udp":
+		if rule.FromPort == 0 {
+			fields = append(fields, "meta", "l4proto", rule.Protocol)
+		} else if rule.ToPort == 0 || rule.ToPort == rule.FromPort {
+			fields = append(fields, rule.Protocol, "dport",
+				fmt.Sprintf("%d", rule.FromPort))
+		} else {
+			fields = append(fields, rule.Protocol, "dport",
+				fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort))
+		}
+	}
+	fields = append(fields, rule.Action.String())
+	return strings.Join(fields, " ")
+}
+
+// makeFirewallRuleset returns the nftables table definition for the network
+// interfaces. If no policies apply to the interfaces, an empty string is
+// returned.
+func (m *Manager) makeFirewallRuleset(tableName string,
+	interfaces []firewallInterface) (string, error) {
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "table bridge %s {\n", tableName)
+	fmt.Fprintln(buffer, "\tchain forward {")
+	fmt.Fprintln(buffer,
+		"\t\ttype filter hook forward priority 0; policy accept;")
+	var haveRules bool
+	for index, netInterface := range interfaces {
+		if netInterface.policy == nil {
+			continue
+		}
+		haveRules = true
+		fmt.Fprintf(buffer, "\t\toifname \"%s\" jump ingress%d\n",
+			netInterface.tapName, index)
+		fmt.Fprintf(buffer, "\t\tiifname \"%s\" jump egress%d\n",
+			netInterface.tapName, index)
+	}
+	if !haveRules {
+		return "", nil
+	}
+	fmt.Fprintln(buffer, "\t}")
+	for index, netInterface := range interfaces {
+		policy := netInterface.policy
+		if policy == nil {
+			continue
+		}
+		if err := policy.CheckValid(); err != nil {
+			return "", err
+		}
+		var rules []string
+		for _, rule := range policy.IngressRules {
+			if text := m.makeFirewallRule(rule, "saddr"); text != "" {
+				rules = append(rules, text)
+			}
+		}
+		writeFirewallChain(buffer, fmt.Sprintf("ingress%d", index), rules,
+			policy.IngressDefault)
+		rules = nil
+		for _, rule := range policy.EgressRules {
+			if text := m.makeFirewallRule(rule, "daddr"); text != "" {
+				rules = append(rules, text)
+			}
+		}
+		writeFirewallChain(buffer, fmt.Sprintf("egress%d", index), rules,
+			policy.EgressDefault)
+	}
+	fmt.Fprintln(buffer, "}")
+	return buffer.String(), nil
+}
+
+// matchVmIPs returns the IP addresses of the VMs on this Hypervisor which match
+// the specified tags, in sorted order. VMs on other Hypervisors are not
+// matched, since the Hypervisor does not have a view of the fleet; rules for
+// remote VMs should use a Cidr instead. The firewall snapshot is used, so the
+// VM locks are not needed.
+func (m *Manager) matchVmIPs(matchTags map[string][]string) []string {
+	matcher := tagmatcher.New(matchTags, false)
+	var ipAddrs []string
+	m.firewallMutex.Lock()
+	defer m.firewallMutex.Unlock()
+	for _, firewallVm := range m.firewallVms {
+		if matcher.MatchEach(firewallVm.tags) {
+			ipAddrs = append(ipAddrs, firewallVm.ipAddrs...)
+		}
+	}
+	sort.Strings(ipAddrs)
+	return ipAddrs
+}
+
+// refreshFirewalls will rebuild the firewall snapshot and re-apply the firewall
+// rules for all running VMs. This is needed when subnets change or when VMs
+// matched by tag rules change.
+func (m *Manager) refreshFirewalls() {
+	m.mutex.RLock()
+	vms := make([]*vmInfoType, 0, len(m.vms))
+	for _, vm := range m.vms {
+		vms = append(vms, vm)
+	}
+	m.mutex.RUnlock()
+	firewallVms := make(map[string]firewallVmType, len(vms))
+	for _, vm := range vms {
+		vm.mutex.RLock()
+		if vm.ipAddress != "0.0.0.0" {
+			firewallVms[vm.ipAddress] = makeFirewallVm(vm.ipAddress,
+				&vm.VmInfo)
+		}
+		vm.mutex.RUnlock()
+	}
+	m.firewallMutex.Lock()
+	m.firewallVms = firewallVms
+	m.firewallMutex.Unlock()
+	for _, vm := range vms {
+		vm.mutex.Lock()
+		if vm.State == proto.StateRunning {
+			if err := vm.applyFirewall(false); err != nil {
+				vm.logger.Printf("error applying firewall rules: %s\n", err)
+			}
+		}
+		vm.mutex.Unlock()
+	}
+}
+
+func (m *Manager) loopRefreshFirewalls(refreshChannel <-chan struct{}) {
+	for range refreshChannel {
+		m.refreshFirewalls()
+	}
+}
+
+// removeFirewall will remove the firewall rules for the VM, if any were
+// applied.
+func (vm *vmInfoType) removeFirewall() {
+	if vm.firewallRuleset == "" {
+		return
+	}
+	tableName := vm.getFirewallTableName()
+	err := runNft(fmt.Sprintf("table bridge %s\ndelete table bridge %s\n",
+		tableName, tableName))
+	if err != nil {
+		vm.logger.Printf("error removing firewall rules: %s\n", err)
+		return
+	}
+	vm.firewallRuleset = ""
+}
+
+// requestFirewallRefresh will request an asynchronous refresh of the firewall
+// rules for all VMs. Multiple requests are coalesced.
+func (m *Manager) requestFirewallRefresh() {
+	if m.firewallRefresh == nil {
+		return
+	}
+	select {
+	case m.firewallRefresh <- struct{}{}:
+	default:
+	}
+}
+
+func runNft(script string) error {
+	cmd := exec.Command(*nftCommand, "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running nft: %s: %s", err, output)
+	}
+	return nil
+}
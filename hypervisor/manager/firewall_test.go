@@ -0,0 +1,300 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func makeFirewallTestManager() *Manager {
+	m := &Manager{}
+	m.checkFirewallRefresh("10.0.0.2", &proto.VmInfo{
+		SecondaryAddresses: []proto.Address{{IpAddress: []byte{10, 1, 0, 2}}},
+		Tags:               tags.Tags{"role": "db"},
+	})
+	m.checkFirewallRefresh("10.0.0.1", &proto.VmInfo{
+		Tags: tags.Tags{"role": "db", "env": "prod"},
+	})
+	m.checkFirewallRefresh("10.0.0.3", &proto.VmInfo{
+		Tags: tags.Tags{"role": "web"},
+	})
+	return m
+}
+
+func TestMakeFirewallRule(t *testing.T) {
+	m := makeFirewallTestManager()
+	tests := []struct {
+		name     string
+		rule     proto.FirewallRule
+		keyword  string
+		expected string
+	}{
+		{
+			name:     "any",
+			rule:     proto.FirewallRule{Action: proto.FirewallDrop},
+			keyword:  "saddr",
+			expected: "drop",
+		},
+		{
+			name: "cidr",
+			rule: proto.FirewallRule{
+				Action: proto.FirewallAccept,
+				Cidr:   "192.168.0.0/16",
+			},
+			keyword:  "saddr",
+			expected: "ip saddr 192.168.0.0/16 accept",
+		},
+		{
+			name: "cidr egress icmp",
+			rule: proto.FirewallRule{
+				Action:   proto.FirewallAccept,
+				Cidr:     "192.168.1.0/24",
+				Protocol: "icmp",
+			},
+			keyword:  "daddr",
+			expected: "ip daddr 192.168.1.0/24 ip protocol icmp accept",
+		},
+		{
+			name: "protocol without ports",
+			rule: proto.FirewallRule{
+				Action:   proto.FirewallDrop,
+				Protocol: "udp",
+			},
+			keyword:  "saddr",
+			expected: "meta l4proto udp drop",
+		},
+		{
+			name: "single port",
+			rule: proto.FirewallRule{
+				Action:   proto.FirewallAccept,
+				FromPort: 22,
+				Protocol: "tcp",
+				ToPort:   22,
+			},
+			keyword:  "saddr",
+			expected: "tcp dport 22 accept",
+		},
+		{
+			name: "port range",
+			rule: proto.FirewallRule{
+				Action:   proto.FirewallAccept,
+				Cidr:     "10.0.0.0/8",
+				FromPort: 8000,
+				Protocol: "tcp",
+				ToPort:   8080,
+			},
+			keyword:  "saddr",
+			expected: "ip saddr 10.0.0.0/8 tcp dport 8000-8080 accept",
+		},
+		{
+			name: "tags",
+			rule: proto.FirewallRule{
+				Action: proto.FirewallAccept,
+				VmTags: tags.MatchTags{"role": {"db"}},
+			},
+			keyword:  "daddr",
+			expected: "ip daddr { 10.0.0.1, 10.0.0.2, 10.1.0.2 } accept",
+		},
+		{
+			name: "tags with multiple values",
+			rule: proto.FirewallRule{
+				Action:   proto.FirewallAccept,
+				Protocol: "tcp",
+				FromPort: 443,
+				VmTags: tags.MatchTags{
+					"env":  {"prod"},
+					"role": {"db", "web"},
+				},
+			},
+			keyword:  "saddr",
+			expected: "ip saddr { 10.0.0.1 } tcp dport 443 accept",
+		},
+		{
+			name: "tags matching nothing",
+			rule: proto.FirewallRule{
+				Action: proto.FirewallAccept,
+				VmTags: tags.MatchTags{"role": {"cache"}},
+			},
+			keyword:  "saddr",
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		got := m.makeFirewallRule(test.rule, test.keyword)
+		if got != test.expected {
+			t.Errorf("%s: expected: \"%s\", got: \"%s\"",
+				test.name, test.expected, got)
+		}
+	}
+}
+
+func TestMakeFirewallRuleset(t *testing.T) {
+	m := makeFirewallTestManager()
+	tests := []struct {
+		name       string
+		interfaces []firewallInterface
+		expected   string
+	}{
+		{
+			name: "no policy",
+			interfaces: []firewallInterface{
+				{tapName: "vt525400000001"},
+			},
+			expected: "",
+		},
+		{
+			name: "defaults",
+			interfaces: []firewallInterface{
+				{
+					policy: &proto.FirewallPolicy{
+						EgressDefault:  proto.FirewallAccept,
+						IngressDefault: proto.FirewallDrop,
+					},
+					tapName: "vt525400000001",
+				},
+			},
+			expected: `table bridge vm_10_0_0_1 {
+	chain forward {
+		type filter hook forward priority 0; policy accept;
+		oifname "vt525400000001" jump ingress0
+		iifname "vt525400000001" jump egress0
+	}
+	chain ingress0 {
+		ct state established,related accept
+		ether type arp accept
+		drop
+	}
+	chain egress0 {
+		ct state established,related accept
+		ether type arp accept
+		accept
+	}
+}
+`,
+		},
+		{
+			name: "rules on secondary interface",
+			interfaces: []firewallInterface{
+				{tapName: "vt525400000001"},
+				{
+					policy: &proto.FirewallPolicy{
+						EgressDefault: proto.FirewallDrop,
+						EgressRules: []proto.FirewallRule{
+							{
+								Action: proto.FirewallAccept,
+								VmTags: tags.MatchTags{"role": {"web"}},
+							},
+							{
+								Action: proto.FirewallAccept,
+								VmTags: tags.MatchTags{"role": {"cache"}},
+							},
+						},
+						IngressDefault: proto.FirewallDrop,
+						IngressRules: []proto.FirewallRule{
+							{
+								Action:   proto.FirewallAccept,
+								Cidr:     "10.0.0.0/8",
+								FromPort: 22,
+								Protocol: "tcp",
+							},
+						},
+					},
+					tapName: "vt525400000002",
+				},
+			},
+			expected: `table bridge vm_10_0_0_1 {
+	chain forward {
+		type filter hook forward priority 0; policy accept;
+		oifname "vt525400000002" jump ingress1
+		iifname "vt525400000002" jump egress1
+	}
+	chain ingress1 {
+		ct state established,related accept
+		ether type arp accept
+		ip saddr 10.0.0.0/8 tcp dport 22 accept
+		drop
+	}
+	chain egress1 {
+		ct state established,related accept
+		ether type arp accept
+		ip daddr { 10.0.0.3 } accept
+		drop
+	}
+}
+`,
+		},
+	}
+	for _, test := range tests {
+		got, err := m.makeFirewallRuleset("vm_10_0_0_1", test.interfaces)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.name, test.expected,
+				got)
+		}
+	}
+}
+
+func TestMakeFirewallRulesetInvalid(t *testing.T) {
+	m := makeFirewallTestManager()
+	_, err := m.makeFirewallRuleset("vm_10_0_0_1", []firewallInterface{
+		{
+			policy: &proto.FirewallPolicy{
+				IngressRules: []proto.FirewallRule{{Cidr: "bad"}},
+			},
+			tapName: "vt525400000001",
+		},
+	})
+	if err == nil {
+		t.Error("invalid policy accepted")
+	}
+}
+
+func TestCheckFirewallRefresh(t *testing.T) {
+	refreshChannel := make(chan struct{}, 1)
+	m := &Manager{firewallRefresh: refreshChannel}
+	refreshed := func() bool {
+		select {
+		case <-refreshChannel:
+			return true
+		default:
+			return false
+		}
+	}
+	vm := &proto.VmInfo{Tags: tags.Tags{"role": "db"}}
+	m.checkFirewallRefresh("10.0.0.1", vm)
+	if !refreshed() {
+		t.Error("new VM did not refresh")
+	}
+	vm.State = proto.StateStopped
+	m.checkFirewallRefresh("10.0.0.1", vm)
+	if refreshed() {
+		t.Error("state change refreshed")
+	}
+	vm.Tags = tags.Tags{"role": "web"}
+	m.checkFirewallRefresh("10.0.0.1", vm)
+	if !refreshed() {
+		t.Error("tag change did not refresh")
+	}
+	vm.SecondaryAddresses = []proto.Address{{IpAddress: []byte{10, 1, 0, 1}}}
+	m.checkFirewallRefresh("10.0.0.1", vm)
+	if !refreshed() {
+		t.Error("address change did not refresh")
+	}
+	vm.FirewallPolicy = &proto.FirewallPolicy{}
+	m.checkFirewallRefresh("10.0.0.1", vm)
+	if !refreshed() {
+		t.Error("policy change did not refresh")
+	}
+	m.checkFirewallRefresh("10.0.0.1", nil)
+	if !refreshed() {
+		t.Error("destroyed VM did not refresh")
+	}
+	m.checkFirewallRefresh("10.0.0.1", nil)
+	if refreshed() {
+		t.Error("unknown destroyed VM refreshed")
+	}
+}
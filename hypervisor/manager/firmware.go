@@ -0,0 +1,217 @@
+package manager
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	nvramExtraFile    = "nvram"
+	tpmStateDirname   = "tpm"
+	tpmStateExtraFile = "tpm-state"
+	tpmStateFilename  = "tpm2-00.permall"
+	tpmSockFilename   = "tpm.sock"
+)
+
+var (
+	ovmfCodeFile = flag.String("ovmfCodeFile",
+		"/usr/share/OVMF/OVMF_CODE.fd", "Name of UEFI firmware code file")
+	ovmfSecureBootCodeFile = flag.String("ovmfSecureBootCodeFile",
+		"/usr/share/OVMF/OVMF_CODE.secboot.fd",
+		"Name of UEFI firmware code file supporting Secure Boot")
+	ovmfSecureBootVarsFile = flag.String("ovmfSecureBootVarsFile",
+		"/usr/share/OVMF/OVMF_VARS.ms.fd",
+		"Name of UEFI NVRAM template with Secure Boot keys enrolled")
+	ovmfVarsFile = flag.String("ovmfVarsFile",
+		"/usr/share/OVMF/OVMF_VARS.fd", "Name of UEFI NVRAM template")
+	swtpmCommand = flag.String("swtpmCommand", "swtpm",
+		"Software TPM emulator command")
+)
+
+// checkFirmware checks if the firmware configuration for a VM is valid.
+func checkFirmware(vmInfo proto.VmInfo) error {
+	if err := vmInfo.FirmwareType.CheckValid(); err != nil {
+		return err
+	}
+	if vmInfo.FirmwareType == proto.FirmwareUEFISecureBoot &&
+		vmInfo.MachineType != proto.MachineTypeQ35 {
+		return errors.New("UEFI Secure Boot requires q35 machine type")
+	}
+	return nil
+}
+
+// checkFirmwareStateFile returns an error if name is not the name of a
+// firmware state extra file.
+func checkFirmwareStateFile(name string) error {
+	switch name {
+	case nvramExtraFile, tpmStateExtraFile:
+		return nil
+	}
+	return fmt.Errorf("unsupported extra file: %s", name)
+}
+
+// extraFilePath returns the path to the specified extra file (such as a
+// kernel or NVRAM) for the VM whose root volume is in directory. Any needed
+// sub-directories are created.
+func extraFilePath(directory, name string) (string, error) {
+	switch name {
+	case "initrd", "kernel", nvramExtraFile:
+		return filepath.Join(directory, name), nil
+	case tpmStateExtraFile:
+		dirname := filepath.Join(directory, tpmStateDirname)
+		if err := os.MkdirAll(dirname, fsutil.DirPerms); err != nil {
+			return "", err
+		}
+		return filepath.Join(dirname, tpmStateFilename), nil
+	}
+	return "", fmt.Errorf("unsupported extra file: %s", name)
+}
+
+// getFirmwareStatePaths returns a table of the firmware state files (NVRAM and
+// TPM state) for the VM which exist. The keys are the extra file names.
+func (vm *vmInfoType) getFirmwareStatePaths() map[string]string {
+	paths := make(map[string]string)
+	if filename := vm.getNvramPath(); fileExists(filename) {
+		paths[nvramExtraFile] = filename
+	}
+	if filename := vm.getTpmStatePath(); fileExists(filename) {
+		paths[tpmStateExtraFile] = filename
+	}
+	return paths
+}
+
+func (vm *vmInfoType) getNvramPath() string {
+	return filepath.Join(vm.VolumeLocations[0].DirectoryToCleanup,
+		nvramExtraFile)
+}
+
+func (vm *vmInfoType) getTpmStateDirectory() string {
+	return filepath.Join(vm.VolumeLocations[0].DirectoryToCleanup,
+		tpmStateDirname)
+}
+
+func (vm *vmInfoType) getTpmStatePath() string {
+	return filepath.Join(vm.getTpmStateDirectory(), tpmStateFilename)
+}
+
+// readFirmwareStateFiles returns the contents of the firmware state files, for
+// sending as extra files with the root volume.
+func (vm *vmInfoType) readFirmwareStateFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for name, filename := range vm.getFirmwareStatePaths() {
+		if data, err := ioutil.ReadFile(filename); err != nil {
+			return nil, err
+		} else {
+			files[name] = data
+		}
+	}
+	return files, nil
+}
+
+// setupFirmware will prepare the firmware state for the VM prior to starting
+// it and returns the extra QEMU options required. A fresh NVRAM is created
+// from the template if there is none. If a virtual TPM is enabled, the TPM
+// emulator is started.
+func (vm *vmInfoType) setupFirmware() ([]string, error) {
+	var options []string
+	if vm.FirmwareType.IsUEFI() {
+		codeFile := *ovmfCodeFile
+		varsFile := *ovmfVarsFile
+		if vm.FirmwareType == proto.FirmwareUEFISecureBoot {
+			codeFile = *ovmfSecureBootCodeFile
+			varsFile = *ovmfSecureBootVarsFile
+		}
+		nvramPath := vm.getNvramPath()
+		if !fileExists(nvramPath) {
+			err := fsutil.CopyFile(nvramPath, varsFile,
+				fsutil.PrivateFilePerms)
+			if err != nil {
+				return nil, fmt.Errorf("error creating NVRAM: %s", err)
+			}
+			vm.logger.Debugf(0, "created NVRAM from: %s\n", varsFile)
+		}
+		if vm.FirmwareType == proto.FirmwareUEFISecureBoot {
+			options = append(options,
+				"-global", "driver=cfi.pflash01,property=secure,value=on")
+		}
+		options = append(options,
+			"-drive", fmt.Sprintf(
+				"if=pflash,format=raw,unit=0,readonly=on,file=%s", codeFile),
+			"-drive", fmt.Sprintf(
+				"if=pflash,format=raw,unit=1,file=%s", nvramPath))
+	}
+	if vm.VirtualTPM {
+		sockname, err := vm.startTpmEmulator()
+		if err != nil {
+			return nil, err
+		}
+		tpmDevice := "tpm-tis"
+		if vm.MachineType == proto.MachineTypeQ35 {
+			tpmDevice = "tpm-crb"
+		}
+		options = append(options,
+			"-chardev", "socket,id=chrtpm,path="+sockname,
+			"-tpmdev", "emulator,id=tpm0,chardev=chrtpm",
+			"-device", tpmDevice+",tpmdev=tpm0")
+	}
+	return options, nil
+}
+
+// startTpmEmulator starts a TPM emulator for the VM, which will terminate when
+// the VM disconnects. The name of the control socket is returned.
+func (vm *vmInfoType) startTpmEmulator() (string, error) {
+	stateDir := vm.getTpmStateDirectory()
+	if err := os.MkdirAll(stateDir, fsutil.DirPerms); err != nil {
+		return "", err
+	}
+	sockname := filepath.Join(vm.dirname, tpmSockFilename)
+	if err := removeFile(sockname); err != nil {
+		return "", err
+	}
+	cmd := exec.Command(*swtpmCommand, "socket",
+		"--tpm2",
+		"--tpmstate", "dir="+stateDir+",mode=0600",
+		"--ctrl", "type=unixio,path="+sockname,
+		"--pid", "file="+filepath.Join(vm.dirname, "tpm.pid"),
+		"--log", "file="+filepath.Join(vm.dirname, "tpm.log"),
+		"--terminate",
+		"--daemon")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("error starting TPM emulator: %s: %s",
+			err, output)
+	}
+	return sockname, nil
+}
+
+// writeFirmwareStateFiles writes the firmware state files received as extra
+// files for the root volume in the specified directory.
+func writeFirmwareStateFiles(directory string,
+	extraFiles map[string][]byte) error {
+	for name, data := range extraFiles {
+		if checkFirmwareStateFile(name) != nil {
+			continue
+		}
+		filename, err := extraFilePath(directory, name)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filename, data, fsutil.PrivateFilePerms)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
@@ -23,6 +23,11 @@ func (m *Manager) writeHtml(writer io.Writer) {
 	if m.serialNumber != "" {
 		fmt.Fprintf(writer, "Serial number: \"%s\"<br>\n", m.serialNumber)
 	}
+	if inventory := m.hardwareInventory; inventory != nil {
+		fmt.Fprintf(writer, "Hardware: %s %s, CPU: %s<br>\n",
+			inventory.DMI.SystemVendor, inventory.DMI.ProductName,
+			inventory.CPU.ModelName)
+	}
 	fmt.Fprintf(writer,
 		"Volume directories: <a href=\"showVolumeDirectories\">%d</a>",
 		len(m.volumeInfos))
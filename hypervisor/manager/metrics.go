@@ -0,0 +1,399 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+	"github.com/Cloud-Foundations/tricorder/go/tricorder"
+	"github.com/Cloud-Foundations/tricorder/go/tricorder/units"
+)
+
+const (
+	clockTicksPerSecond = 100 // USER_HZ, which is fixed for Linux/x86.
+	qmpIdBalloon        = "metrics-balloon"
+	qmpIdBlockStats     = "metrics-blockstats"
+)
+
+var (
+	vmMetricsInterval = flag.Duration("vmMetricsInterval", 10*time.Second,
+		"Interval between samples of VM resource usage (0 to disable)")
+	vmMetricsSamples = flag.Uint("vmMetricsSamples", 360,
+		"Number of VM resource usage samples to retain for each VM")
+)
+
+type balloonInfoType struct {
+	Actual uint64 `json:"actual"`
+}
+
+type blockStatsType struct {
+	Device string              `json:"device"`
+	Qdev   string              `json:"qdev"`
+	Stats  blockStatsCountType `json:"stats"`
+}
+
+type vmMetricsType struct {
+	mutex        sync.Mutex
+	balloonBytes uint64          // Latest reply from the QEMU monitor.
+	exported     proto.VmMetrics // Registered with tricorder.
+	samples      []proto.VmMetrics
+	tricorderDir *tricorder.DirectorySpec
+	volumes      []proto.VolumeMetrics // Latest reply from the QEMU monitor.
+}
+
+type blockStatsCountType struct {
+	ReadBytes       uint64 `json:"rd_bytes"`
+	ReadOperations  uint64 `json:"rd_operations"`
+	WriteBytes      uint64 `json:"wr_bytes"`
+	WriteOperations uint64 `json:"wr_operations"`
+}
+
+// parseDriveIndex extracts the volume index from a QEMU drive or device name
+// which contains an identifier made by getDriveId.
+func parseDriveIndex(name string) (int, bool) {
+	for _, field := range strings.Split(name, "/") {
+		if !strings.HasPrefix(field, "drive") {
+			continue
+		}
+		if index, err := strconv.Atoi(field[5:]); err == nil && index >= 0 {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// readProcessUsage returns the CPU time and resident memory for a process.
+func readProcessUsage(pid int) (time.Duration, uint64, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// Skip past the command name, which may contain spaces.
+	stat := string(data)
+	if pos := strings.LastIndexByte(stat, ')'); pos < 0 {
+		return 0, 0, errors.New("malformed stat file")
+	} else {
+		stat = stat[pos+1:]
+	}
+	fields := strings.Fields(stat)
+	if len(fields) < 13 {
+		return 0, 0, errors.New("short stat file")
+	}
+	// Fields 14 and 15 of the stat file: utime and stime.
+	userTicks, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	systemTicks, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	cpuTime := time.Duration(userTicks+systemTicks) * time.Second /
+		clockTicksPerSecond
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "VmRSS:" && fields[2] == "kB" {
+			rss, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			return cpuTime, rss << 10, nil
+		}
+	}
+	return cpuTime, 0, scanner.Err()
+}
+
+// readTapCounters returns the network counters for a tap device. Since the
+// counters are from the perspective of the Hypervisor, transmit and receive are
+// swapped.
+func readTapCounters(tapName string) (proto.NetworkInterfaceMetrics, error) {
+	var metrics proto.NetworkInterfaceMetrics
+	dirname := filepath.Join("/sys/class/net", tapName, "statistics")
+	for name, value := range map[string]*uint64{
+		"rx_bytes":   &metrics.TransmitBytes,
+		"rx_packets": &metrics.TransmitPackets,
+		"tx_bytes":   &metrics.ReceiveBytes,
+		"tx_packets": &metrics.ReceivePackets,
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dirname, name))
+		if err != nil {
+			return metrics, err
+		}
+		*value, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10,
+			64)
+		if err != nil {
+			return metrics, err
+		}
+	}
+	return metrics, nil
+}
+
+func (m *Manager) getLatestVmMetrics() map[string]proto.VmMetrics {
+	m.mutex.RLock()
+	vms := make(map[string]*vmInfoType, len(m.vms))
+	for ipAddr, vm := range m.vms {
+		vms[ipAddr] = vm
+	}
+	m.mutex.RUnlock()
+	latestMetrics := make(map[string]proto.VmMetrics, len(vms))
+	for ipAddr, vm := range vms {
+		vm.metrics.mutex.Lock()
+		if numSamples := len(vm.metrics.samples); numSamples > 0 {
+			latestMetrics[ipAddr] = vm.metrics.samples[numSamples-1]
+		}
+		vm.metrics.mutex.Unlock()
+	}
+	return latestMetrics
+}
+
+func (m *Manager) getVmMetrics(ipAddr net.IP, maximumSamples uint) (
+	[]proto.VmMetrics, error) {
+	vm, err := m.getVmAndLock(ipAddr, false)
+	if err != nil {
+		return nil, err
+	}
+	vm.mutex.RUnlock()
+	vm.metrics.mutex.Lock()
+	defer vm.metrics.mutex.Unlock()
+	samples := vm.metrics.samples
+	if maximumSamples > 0 && uint(len(samples)) > maximumSamples {
+		samples = samples[uint(len(samples))-maximumSamples:]
+	}
+	return append([]proto.VmMetrics(nil), samples...), nil
+}
+
+func (m *Manager) loopSampleVmMetrics() {
+	if *vmMetricsInterval <= 0 {
+		return
+	}
+	for range time.Tick(*vmMetricsInterval) {
+		m.mutex.RLock()
+		vms := make([]*vmInfoType, 0, len(m.vms))
+		for _, vm := range m.vms {
+			vms = append(vms, vm)
+		}
+		m.mutex.RUnlock()
+		for _, vm := range vms {
+			if err := vm.sampleMetrics(); err != nil {
+				vm.logger.Debugf(1, "error sampling metrics: %s\n", err)
+			}
+		}
+	}
+}
+
+// processMonitorReply processes a reply from the QEMU monitor to a command
+// which was sent with an identifier.
+func (vm *vmInfoType) processMonitorReply(message monitorMessageType) {
+	if len(message.Error) > 0 {
+		vm.logger.Debugf(2, "error reply for: %s: %s\n",
+			message.Id, string(message.Error))
+		return
+	}
+	switch message.Id {
+	case qmpIdBalloon:
+		var balloonInfo balloonInfoType
+		if err := json.Unmarshal(message.Return, &balloonInfo); err != nil {
+			vm.logger.Printf("error unmarshaling balloon info: %s\n", err)
+			return
+		}
+		vm.metrics.mutex.Lock()
+		vm.metrics.balloonBytes = balloonInfo.Actual
+		vm.metrics.mutex.Unlock()
+	case qmpIdBlockStats:
+		var blockStats []blockStatsType
+		if err := json.Unmarshal(message.Return, &blockStats); err != nil {
+			vm.logger.Printf("error unmarshaling block stats: %s\n", err)
+			return
+		}
+		var volumes []proto.VolumeMetrics
+		for _, stats := range blockStats {
+			index, ok := parseDriveIndex(stats.Device)
+			if !ok {
+				if index, ok = parseDriveIndex(stats.Qdev); !ok {
+					continue
+				}
+			}
+			for len(volumes) <= index {
+				volumes = append(volumes, proto.VolumeMetrics{})
+			}
+			volumes[index] = proto.VolumeMetrics{
+				ReadBytes:       stats.Stats.ReadBytes,
+				ReadOperations:  stats.Stats.ReadOperations,
+				WriteBytes:      stats.Stats.WriteBytes,
+				WriteOperations: stats.Stats.WriteOperations,
+			}
+		}
+		vm.metrics.mutex.Lock()
+		vm.metrics.volumes = volumes
+		vm.metrics.mutex.Unlock()
+	}
+}
+
+// registerMetrics will (re)register the tricorder metrics for the VM, if the
+// number of network interfaces or volumes has changed. The metrics mutex must
+// be held.
+func (vm *vmInfoType) registerMetrics(sample proto.VmMetrics) error {
+	metrics := &vm.metrics
+	if metrics.tricorderDir != nil &&
+		len(sample.NetworkInterfaces) == len(metrics.exported.NetworkInterfaces) &&
+		len(sample.Volumes) == len(metrics.exported.Volumes) {
+		return nil
+	}
+	vm.unregisterMetrics()
+	// The sample is retained in the history, so the exported slices must not
+	// share storage with it.
+	metrics.exported = sample
+	metrics.exported.NetworkInterfaces = append(
+		[]proto.NetworkInterfaceMetrics(nil), sample.NetworkInterfaces...)
+	metrics.exported.Volumes = append([]proto.VolumeMetrics(nil),
+		sample.Volumes...)
+	dir, err := tricorder.RegisterDirectory("/vms/" + vm.ipAddress)
+	if err != nil {
+		return err
+	}
+	metrics.tricorderDir = dir
+	group := tricorder.NewGroup()
+	group.RegisterUpdateFunc(func() time.Time {
+		metrics.mutex.Lock()
+		defer metrics.mutex.Unlock()
+		if numSamples := len(metrics.samples); numSamples > 0 {
+			latest := metrics.samples[numSamples-1]
+			// Keep the slices registered with tricorder.
+			copy(metrics.exported.NetworkInterfaces, latest.NetworkInterfaces)
+			copy(metrics.exported.Volumes, latest.Volumes)
+			latest.NetworkInterfaces = metrics.exported.NetworkInterfaces
+			latest.Volumes = metrics.exported.Volumes
+			metrics.exported = latest
+		}
+		return time.Now()
+	})
+	exported := &metrics.exported
+	err = dir.RegisterMetricInGroup("balloon", &exported.BalloonBytes, group,
+		units.Byte, "memory balloon size")
+	if err != nil {
+		return err
+	}
+	err = dir.RegisterMetricInGroup("cpu-time", &exported.CpuTime, group,
+		units.Second, "CPU time used by VM")
+	if err != nil {
+		return err
+	}
+	err = dir.RegisterMetricInGroup("memory-resident",
+		&exported.MemoryResidentBytes, group, units.Byte,
+		"resident memory used by VM")
+	if err != nil {
+		return err
+	}
+	for index := range exported.NetworkInterfaces {
+		netInterface := &exported.NetworkInterfaces[index]
+		dir, err := dir.RegisterDirectory(fmt.Sprintf("network/%d", index))
+		if err != nil {
+			return err
+		}
+		for name, value := range map[string]*uint64{
+			"receive-bytes":    &netInterface.ReceiveBytes,
+			"receive-packets":  &netInterface.ReceivePackets,
+			"transmit-bytes":   &netInterface.TransmitBytes,
+			"transmit-packets": &netInterface.TransmitPackets,
+		} {
+			err := dir.RegisterMetricInGroup(name, value, group, units.None,
+				"cumulative "+strings.Replace(name, "-", " ", -1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for index := range exported.Volumes {
+		volume := &exported.Volumes[index]
+		dir, err := dir.RegisterDirectory(fmt.Sprintf("volumes/%d", index))
+		if err != nil {
+			return err
+		}
+		for name, value := range map[string]*uint64{
+			"read-bytes":       &volume.ReadBytes,
+			"read-operations":  &volume.ReadOperations,
+			"write-bytes":      &volume.WriteBytes,
+			"write-operations": &volume.WriteOperations,
+		} {
+			err := dir.RegisterMetricInGroup(name, value, group, units.None,
+				"cumulative "+strings.Replace(name, "-", " ", -1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sampleMetrics will record a sample of the resource usage of the VM, if it is
+// running. Requests for the block and balloon statistics are sent to the QEMU
+// monitor and the replies are recorded in the next sample.
+func (vm *vmInfoType) sampleMetrics() error {
+	vm.mutex.RLock()
+	if vm.State != proto.StateRunning || vm.commandInput == nil {
+		vm.mutex.RUnlock()
+		return nil
+	}
+	vm.commandInput <- fmt.Sprintf(`\{"execute":"query-blockstats","id":"%s"}`,
+		qmpIdBlockStats)
+	vm.commandInput <- fmt.Sprintf(`\{"execute":"query-balloon","id":"%s"}`,
+		qmpIdBalloon)
+	addresses := append([]proto.Address{vm.Address}, vm.SecondaryAddresses...)
+	pid, err := vm.readPid()
+	vm.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+	cpuTime, rss, err := readProcessUsage(pid)
+	if err != nil {
+		return err
+	}
+	sample := proto.VmMetrics{
+		CpuTime:             cpuTime,
+		MemoryResidentBytes: rss,
+		Timestamp:           time.Now(),
+	}
+	for _, address := range addresses {
+		counters, err := readTapCounters(getTapName(address))
+		if err != nil {
+			return err
+		}
+		sample.NetworkInterfaces = append(sample.NetworkInterfaces, counters)
+	}
+	vm.metrics.mutex.Lock()
+	defer vm.metrics.mutex.Unlock()
+	sample.BalloonBytes = vm.metrics.balloonBytes
+	sample.Volumes = vm.metrics.volumes
+	vm.metrics.samples = append(vm.metrics.samples, sample)
+	if excess := len(vm.metrics.samples) - int(*vmMetricsSamples); excess > 0 {
+		vm.metrics.samples = append([]proto.VmMetrics(nil),
+			vm.metrics.samples[excess:]...)
+	}
+	return vm.registerMetrics(sample)
+}
+
+// unregisterMetrics will remove the tricorder metrics for the VM. The metrics
+// mutex must be held.
+func (vm *vmInfoType) unregisterMetrics() {
+	if vm.metrics.tricorderDir != nil {
+		vm.metrics.tricorderDir.UnregisterDirectory()
+		vm.metrics.tricorderDir = nil
+	}
+}
@@ -3,26 +3,54 @@ package manager
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
-type copyingReader struct {
-	copyChannel chan<- byte
-	r           io.Reader
-}
+const (
+	qmpIdRequestPrefix = "request-"
+	qmpReplyTimeout    = 10 * time.Second
+)
 
 type monitorMessageType struct {
 	Data      json.RawMessage      `json:data",omitempty"`
+	Error     json.RawMessage      `json:"error,omitempty"`
 	Event     string               `json:event",omitempty"`
+	Id        string               `json:"id,omitempty"`
+	Return    json.RawMessage      `json:"return,omitempty"`
 	Timestamp monitorTimestampType `json:timestamp",omitempty"`
 }
 
+type qmpCommand struct {
+	Execute   string      `json:"execute"`
+	Arguments interface{} `json:"arguments,omitempty"`
+	Id        string      `json:"id,omitempty"`
+}
+
+type qmpErrorType struct {
+	Class       string `json:"class"`
+	Description string `json:"desc"`
+}
+
+// qmpRepliesType tracks the commands sent with sendQmpCommand which are waiting
+// for a reply. There is one per monitor connection.
+type qmpRepliesType struct {
+	mutex   sync.Mutex
+	closed  bool
+	nextId  uint64
+	waiters map[string]chan<- monitorMessageType // Key: command ID.
+}
+
 type monitorTimestampType struct {
 	Microseconds int64 `json:microseconds",omitempty"`
 	Seconds      int64 `json:seconds",omitempty"`
@@ -37,25 +65,89 @@ type watchdogDataType struct {
 	Action string `json:action",omitempty"`
 }
 
-func (r *copyingReader) Read(p []byte) (int, error) {
-	nRead, err := r.r.Read(p)
-	for index := 0; index < nRead; index++ {
+// copyMonitorMessage will copy a message from the QEMU monitor to the
+// command output channel, dropping bytes if the channel is full.
+func copyMonitorMessage(rawMessage []byte, commandOutput chan<- byte) {
+	for _, char := range append(rawMessage, '\r', '\n') {
 		select {
-		case r.copyChannel <- p[index]:
+		case commandOutput <- char:
 		default:
 		}
 	}
-	return nRead, err
+}
+
+// isInternalReply returns true if the message is a reply to a command which was
+// sent by the Hypervisor rather than by an interactive monitor session.
+func isInternalReply(message monitorMessageType) bool {
+	switch message.Id {
+	case qmpIdBalloon, qmpIdBlockStats:
+		return true
+	}
+	return strings.HasPrefix(message.Id, qmpIdRequestPrefix)
+}
+
+func newQmpReplies() *qmpRepliesType {
+	return &qmpRepliesType{
+		waiters: make(map[string]chan<- monitorMessageType),
+	}
+}
+
+// close will wake up all the waiters, since no more replies will arrive.
+func (replies *qmpRepliesType) close() {
+	replies.mutex.Lock()
+	defer replies.mutex.Unlock()
+	replies.closed = true
+	for id, waiter := range replies.waiters {
+		close(waiter)
+		delete(replies.waiters, id)
+	}
+}
+
+// deliver will send the message to the waiter for the command, if there is one.
+// It returns true if the message was delivered.
+func (replies *qmpRepliesType) deliver(message monitorMessageType) bool {
+	replies.mutex.Lock()
+	defer replies.mutex.Unlock()
+	waiter, ok := replies.waiters[message.Id]
+	if !ok {
+		return false
+	}
+	delete(replies.waiters, message.Id)
+	waiter <- message
+	return true
+}
+
+func (replies *qmpRepliesType) register(
+	waiter chan<- monitorMessageType) (string, error) {
+	replies.mutex.Lock()
+	defer replies.mutex.Unlock()
+	if replies.closed {
+		return "", errors.New("monitor connection closed")
+	}
+	replies.nextId++
+	id := qmpIdRequestPrefix + strconv.FormatUint(replies.nextId, 10)
+	replies.waiters[id] = waiter
+	return id, nil
+}
+
+func (replies *qmpRepliesType) unregister(id string) {
+	replies.mutex.Lock()
+	defer replies.mutex.Unlock()
+	delete(replies.waiters, id)
 }
 
 func (vm *vmInfoType) processMonitorResponses(monitorSock net.Conn,
-	commandOutput chan<- byte) {
-	reader := &copyingReader{commandOutput, monitorSock}
-	decoder := json.NewDecoder(reader)
+	commandOutput chan<- byte, replies *qmpRepliesType) {
+	decoder := json.NewDecoder(monitorSock)
 	var guestShutdown, hostQuit, lastDecodeFailed, watchdogPowerOff bool
 	for {
 		var message monitorMessageType
-		if err := decoder.Decode(&message); err != nil {
+		var rawMessage json.RawMessage
+		err := decoder.Decode(&rawMessage)
+		if err == nil {
+			err = json.Unmarshal(rawMessage, &message)
+		}
+		if err != nil {
 			if err == io.EOF {
 				if !guestShutdown && !hostQuit {
 					vm.logger.Debugln(0, "EOF on monitor socket")
@@ -76,6 +168,17 @@ func (vm *vmInfoType) processMonitorResponses(monitorSock net.Conn,
 		} else {
 			lastDecodeFailed = false
 		}
+		// Keep replies to periodic internal queries away from interactive
+		// sessions.
+		if !isInternalReply(message) {
+			copyMonitorMessage(rawMessage, commandOutput)
+		}
+		if message.Id != "" {
+			if !replies.deliver(message) {
+				vm.processMonitorReply(message)
+			}
+			continue
+		}
 		switch message.Event {
 		case "SHUTDOWN":
 			var shutdownData shutdownDataType
@@ -105,11 +208,13 @@ func (vm *vmInfoType) processMonitorResponses(monitorSock net.Conn,
 		}
 	}
 	close(commandOutput)
+	replies.close()
 	vm.mutex.Lock()
 	defer vm.mutex.Unlock()
 	close(vm.commandInput)
 	vm.commandInput = nil
 	vm.commandOutput = nil
+	vm.qmpReplies = nil
 	os.Remove(filepath.Join(vm.dirname, "pidfile"))
 	switch vm.State {
 	case proto.StateStarting:
@@ -167,3 +272,46 @@ func (vm *vmInfoType) processMonitorResponses(monitorSock net.Conn,
 		vm.logger.Println("unknown state: " + vm.State.String())
 	}
 }
+
+// sendQmpCommand will send a QMP command to the QEMU monitor and will wait for
+// the reply. If QEMU rejects the command, the error is returned. The VM lock
+// must be held.
+func (vm *vmInfoType) sendQmpCommand(execute string,
+	arguments interface{}) (json.RawMessage, error) {
+	if vm.commandInput == nil || vm.qmpReplies == nil {
+		return nil, errors.New("no commandInput for VM")
+	}
+	replyChannel := make(chan monitorMessageType, 1)
+	id, err := vm.qmpReplies.register(replyChannel)
+	if err != nil {
+		return nil, err
+	}
+	defer vm.qmpReplies.unregister(id)
+	data, err := json.Marshal(qmpCommand{
+		Execute:   execute,
+		Arguments: arguments,
+		Id:        id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	vm.commandInput <- "\\" + string(data)
+	timer := time.NewTimer(qmpReplyTimeout)
+	defer timer.Stop()
+	select {
+	case message, ok := <-replyChannel:
+		if !ok {
+			return nil, fmt.Errorf("%s: monitor connection closed", execute)
+		}
+		if len(message.Error) > 0 {
+			var qmpError qmpErrorType
+			if err := json.Unmarshal(message.Error, &qmpError); err != nil {
+				return nil, fmt.Errorf("%s: %s", execute, message.Error)
+			}
+			return nil, fmt.Errorf("%s: %s", execute, qmpError.Description)
+		}
+		return message.Return, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%s: timed out waiting for reply", execute)
+	}
+}
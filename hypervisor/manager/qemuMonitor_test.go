@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+)
+
+// fakeMonitor replies to each command sent on commandInput using the reply
+// function.
+func fakeMonitor(commandInput <-chan string, replies *qmpRepliesType,
+	reply func(command qmpCommand) monitorMessageType) {
+	for rawCommand := range commandInput {
+		var command qmpCommand
+		if err := json.Unmarshal([]byte(rawCommand[1:]), &command); err != nil {
+			panic(err)
+		}
+		message := reply(command)
+		message.Id = command.Id
+		if !isInternalReply(message) {
+			panic("reply not internal: " + message.Id)
+		}
+		replies.deliver(message)
+	}
+}
+
+func makeQmpTestVm(t *testing.T,
+	reply func(command qmpCommand) monitorMessageType) *vmInfoType {
+	commandInput := make(chan string, 1)
+	vm := &vmInfoType{
+		commandInput: commandInput,
+		logger:       testlogger.New(t),
+		qmpReplies:   newQmpReplies(),
+	}
+	go fakeMonitor(commandInput, vm.qmpReplies, reply)
+	t.Cleanup(func() { close(commandInput) })
+	return vm
+}
+
+func TestSendQmpCommand(t *testing.T) {
+	vm := makeQmpTestVm(t, func(command qmpCommand) monitorMessageType {
+		if command.Execute != "block_set_io_throttle" {
+			return monitorMessageType{
+				Error: json.RawMessage(
+					`{"class":"CommandNotFound","desc":"unknown command"}`),
+			}
+		}
+		arguments := command.Arguments.(map[string]interface{})
+		if arguments["device"] != "drive0" {
+			return monitorMessageType{
+				Error: json.RawMessage(
+					`{"class":"GenericError","desc":"Device not found"}`),
+			}
+		}
+		return monitorMessageType{Return: json.RawMessage("{}")}
+	})
+	_, err := vm.sendQmpCommand("block_set_io_throttle",
+		blockSetIoThrottleArguments{Device: "drive0", Bps: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vm.sendQmpCommand("block_set_io_throttle",
+		blockSetIoThrottleArguments{Device: "drive9", Bps: 1 << 20})
+	if err == nil {
+		t.Fatal("rejected command did not fail")
+	}
+	if !strings.Contains(err.Error(), "Device not found") {
+		t.Errorf("QEMU error not reported: %s", err)
+	}
+	if len(vm.qmpReplies.waiters) > 0 {
+		t.Errorf("waiters not removed: %v", vm.qmpReplies.waiters)
+	}
+}
+
+func TestSendQmpCommandClosed(t *testing.T) {
+	vm := makeQmpTestVm(t, func(command qmpCommand) monitorMessageType {
+		return monitorMessageType{}
+	})
+	vm.qmpReplies.close()
+	if _, err := vm.sendQmpCommand("quit", nil); err == nil {
+		t.Error("command sent on closed monitor")
+	}
+	vm.qmpReplies = nil
+	if _, err := vm.sendQmpCommand("quit", nil); err == nil {
+		t.Error("command sent without monitor")
+	}
+}
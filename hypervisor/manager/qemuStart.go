@@ -45,6 +45,24 @@ func getQemuCpuModelFlags() (map[string]struct{}, error) {
 	return modelFlags, nil
 }
 
+// getDriveId returns the QEMU identifier for the specified volume.
+func getDriveId(index int) string {
+	return fmt.Sprintf("drive%d", index)
+}
+
+// getVolumeInterface returns the interface QEMU will use for the specified
+// volume.
+func (vm *vmInfoType) getVolumeInterface(index int) proto.VolumeInterface {
+	var volumeInterface proto.VolumeInterface
+	if index < len(vm.Volumes) {
+		volumeInterface = vm.Volumes[index].Interface
+	}
+	if vm.DisableVirtIO && volumeInterface == proto.VolumeInterfaceVirtIO {
+		volumeInterface = proto.VolumeInterfaceIDE
+	}
+	return volumeInterface
+}
+
 func (vm *vmInfoType) startQemuVm(enableNetboot, haveManagerLock bool,
 	pidfile string, nCpus uint, netOptions []string,
 	tapFiles []*os.File) error {
@@ -58,8 +76,20 @@ func (vm *vmInfoType) startQemuVm(enableNetboot, haveManagerLock bool,
 	} else if _, ok := cpuModelFlags["kvmclock"]; ok {
 		cpuModel += ",+kvmclock" // Fall back to something faster than HPET.
 	}
+	machineOptions := vm.MachineType.String()
+	if vm.FirmwareType == proto.FirmwareUEFISecureBoot {
+		machineOptions += ",smm=on"
+	}
+	firmwareOptions, err := vm.setupFirmware()
+	if err != nil {
+		return err
+	}
+	cloudInitOptions, err := vm.setupCloudInitSeed(haveManagerLock)
+	if err != nil {
+		return err
+	}
 	cmd := exec.Command(*qemuCommand,
-		"-machine", fmt.Sprintf("%s,accel=kvm", vm.MachineType),
+		"-machine", machineOptions+",accel=kvm",
 		"-cpu", cpuModel,
 		"-nodefaults",
 		"-name", vm.ipAddress,
@@ -73,6 +103,7 @@ func (vm *vmInfoType) startQemuVm(enableNetboot, haveManagerLock bool,
 		"-qmp", "unix:"+vm.monitorSockname+",server,nowait",
 		"-pidfile", pidfile,
 		"-daemonize")
+	cmd.Args = append(cmd.Args, firmwareOptions...)
 	var interfaceDriver string
 	if !vm.DisableVirtIO {
 		interfaceDriver = ",if=virtio"
@@ -123,22 +154,19 @@ func (vm *vmInfoType) startQemuVm(enableNetboot, haveManagerLock bool,
 	}
 	for index, volume := range vm.VolumeLocations {
 		var volumeFormat proto.VolumeFormat
-		var volumeInterface proto.VolumeInterface
 		if index < len(vm.Volumes) {
 			volumeFormat = vm.Volumes[index].Format
-			volumeInterface = vm.Volumes[index].Interface
-		}
-		if vm.DisableVirtIO && volumeInterface == proto.VolumeInterfaceVirtIO {
-			volumeInterface = proto.VolumeInterfaceIDE
 		}
+		volumeInterface := vm.getVolumeInterface(index)
 		// For the simple cases (VirtIO and IDE), use old-style flags to
 		// maintain compatibility with old versions of QEMU (like 2.0.0).
 		switch volumeInterface {
 		case proto.VolumeInterfaceVirtIO, proto.VolumeInterfaceIDE:
 			cmd.Args = append(cmd.Args,
 				"-drive", fmt.Sprintf(
-					"file=%s,format=%s,discard=off,if=%s",
-					volume.Filename, volumeFormat, volumeInterface))
+					"file=%s,format=%s,discard=off,if=%s,id=%s",
+					volume.Filename, volumeFormat, volumeInterface,
+					getDriveId(index)))
 			continue
 		}
 		cmd.Args = append(cmd.Args,
@@ -149,20 +177,21 @@ func (vm *vmInfoType) startQemuVm(enableNetboot, haveManagerLock bool,
 		case proto.VolumeInterfaceVirtIO:
 			cmd.Args = append(cmd.Args,
 				"-device", fmt.Sprintf(
-					"virtio-blk,drive=blk%d", index))
+					"virtio-blk,drive=blk%d,id=%s", index, getDriveId(index)))
 		case proto.VolumeInterfaceIDE:
 			cmd.Args = append(cmd.Args,
 				"-device", fmt.Sprintf(
-					"ide-hd,drive=blk%d", index))
+					"ide-hd,drive=blk%d,id=%s", index, getDriveId(index)))
 		case proto.VolumeInterfaceNVMe:
 			cmd.Args = append(cmd.Args,
 				"-device", fmt.Sprintf(
-					"nvme,serial=fu%s-%d,drive=blk%d",
-					vm.Address.IpAddress, index, index))
+					"nvme,serial=fu%s-%d,drive=blk%d,id=%s",
+					vm.Address.IpAddress, index, index, getDriveId(index)))
 		default:
 			return fmt.Errorf("invalid volume interface: %v", volumeInterface)
 		}
 	}
+	cmd.Args = append(cmd.Args, cloudInitOptions...)
 	if cid, err := vm.manager.GetVmCID(vm.Address.IpAddress); err != nil {
 		return err
 	} else if cid > 2 {
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/hardware"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/lockwatcher"
 	"github.com/Cloud-Foundations/Dominator/lib/log/prefixlogger"
@@ -72,15 +73,23 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	hardwareInventory, err := hardware.GetInventory(startOptions.Logger)
+	if err != nil {
+		startOptions.Logger.Printf("error getting hardware inventory: %s\n",
+			err)
+	}
+	firewallRefresh := make(chan struct{}, 1)
 	manager := &Manager{
-		StartOptions:  startOptions,
-		rootCookie:    rootCookie,
-		memTotalInMiB: memInfo.Total >> 20,
-		notifiers:     make(map[<-chan proto.Update]chan<- proto.Update),
-		numCPUs:       uint(runtime.NumCPU()),
-		serialNumber:  readSystemSerial(),
-		vms:           make(map[string]*vmInfoType),
-		uuid:          uuid,
+		StartOptions:      startOptions,
+		firewallRefresh:   firewallRefresh,
+		hardwareInventory: hardwareInventory,
+		rootCookie:        rootCookie,
+		memTotalInMiB:     memInfo.Total >> 20,
+		notifiers:         make(map[<-chan proto.Update]chan<- proto.Update),
+		numCPUs:           uint(runtime.NumCPU()),
+		serialNumber:      readSystemSerial(),
+		vms:               make(map[string]*vmInfoType),
+		uuid:              uuid,
 	}
 	err = fsutil.CopyToFile(manager.GetRootCookiePath(),
 		fsutil.PrivateFilePerms, bytes.NewReader(rootCookie), 0)
@@ -103,6 +112,9 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 	if err := manager.loadSubnets(); err != nil {
 		return nil, err
 	}
+	if err := manager.loadThrottleDefaults(); err != nil {
+		return nil, err
+	}
 	if err := manager.loadAddressPool(); err != nil {
 		return nil, err
 	}
@@ -222,6 +234,9 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 		manager.writeAddressPoolWithLock(manager.addressPool, false)
 	}
 	go manager.loopCheckHealthStatus()
+	go manager.loopRefreshFirewalls(firewallRefresh)
+	manager.requestFirewallRefresh()
+	go manager.loopSampleVmMetrics()
 	lockCheckInterval := startOptions.LockCheckInterval
 	if lockCheckInterval > time.Second {
 		// Leveraged for dashboard, so keep it fresh.
@@ -41,6 +41,16 @@ func checkSubnetAccess(subnet proto.Subnet,
 	return false
 }
 
+func checkSubnetFirewallPolicy(subnet proto.Subnet) error {
+	if subnet.FirewallPolicy == nil {
+		return nil
+	}
+	if err := subnet.FirewallPolicy.CheckValid(); err != nil {
+		return fmt.Errorf("subnet: %s: %s", subnet.Id, err)
+	}
+	return nil
+}
+
 func getHypervisorSubnet() (proto.Subnet, error) {
 	defaultRoute, err := util.GetDefaultRoute()
 	if err != nil {
@@ -211,12 +221,18 @@ func (m *Manager) updateSubnets(request proto.UpdateSubnetsRequest) error {
 		if subnet.Id == "hypervisor" {
 			return fmt.Errorf("cannot add hypervisor subnet")
 		}
+		if err := checkSubnetFirewallPolicy(subnet); err != nil {
+			return err
+		}
 		request.Add[index].Shrink()
 	}
 	for index, subnet := range request.Change {
 		if subnet.Id == "hypervisor" {
 			return fmt.Errorf("cannot change hypervisor subnet")
 		}
+		if err := checkSubnetFirewallPolicy(subnet); err != nil {
+			return err
+		}
 		request.Change[index].Shrink()
 	}
 	for _, subnetId := range request.Delete {
@@ -242,6 +258,7 @@ func (m *Manager) updateSubnets(request proto.UpdateSubnetsRequest) error {
 		m.DhcpServer.RemoveSubnet(subnetId)
 		// TOOO(rgooch): Design a clean way to send deletes to the channels.
 	}
+	m.requestFirewallRefresh()
 	return nil
 }
 
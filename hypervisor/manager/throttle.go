@@ -0,0 +1,327 @@
+package manager
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	minimumBurstBytes    = 32 << 10
+	throttleDefaultsFile = "throttle-defaults.json"
+)
+
+var (
+	defaultNetworkBytesPerSecond = flag.Uint64("defaultNetworkBytesPerSecond",
+		0, "Default bandwidth limit (each direction) for VM network interfaces")
+	defaultVolumeBytesPerSecond = flag.Uint64("defaultVolumeBytesPerSecond",
+		0, "Default bandwidth limit for VM volumes")
+	defaultVolumeIOPS = flag.Uint64("defaultVolumeIOPS", 0,
+		"Default I/O operations per second limit for VM volumes")
+)
+
+type blockSetIoThrottleArguments struct {
+	Device    string `json:"device,omitempty"`
+	Id        string `json:"id,omitempty"`
+	Bps       uint64 `json:"bps"`
+	BpsRead   uint64 `json:"bps_rd"`
+	BpsWrite  uint64 `json:"bps_wr"`
+	Iops      uint64 `json:"iops"`
+	IopsRead  uint64 `json:"iops_rd"`
+	IopsWrite uint64 `json:"iops_wr"`
+}
+
+func mergeNetworkThrottle(throttle,
+	defaults proto.NetworkThrottle) proto.NetworkThrottle {
+	if throttle.ReceiveBytesPerSecond == 0 {
+		throttle.ReceiveBytesPerSecond = defaults.ReceiveBytesPerSecond
+	}
+	if throttle.TransmitBytesPerSecond == 0 {
+		throttle.TransmitBytesPerSecond = defaults.TransmitBytesPerSecond
+	}
+	return throttle
+}
+
+func mergeVolumeThrottle(throttle,
+	defaults proto.VolumeThrottle) proto.VolumeThrottle {
+	if throttle.BytesPerSecond == 0 {
+		throttle.BytesPerSecond = defaults.BytesPerSecond
+	}
+	if throttle.IOPS == 0 {
+		throttle.IOPS = defaults.IOPS
+	}
+	return throttle
+}
+
+func runTc(args ...string) error {
+	cmd := exec.Command("tc", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running tc: %s: %s", err, output)
+	}
+	return nil
+}
+
+// setNetworkThrottle will set the bandwidth limits on the specified tap
+// device. Traffic received by the VM is shaped on egress from the tap device
+// and traffic transmitted by the VM is policed on ingress to the tap device.
+func setNetworkThrottle(tapName string, throttle proto.NetworkThrottle) error {
+	// Remove any existing limits. Errors are ignored since there may be none.
+	exec.Command("tc", "qdisc", "del", "dev", tapName, "root").Run()
+	exec.Command("tc", "qdisc", "del", "dev", tapName, "ingress").Run()
+	if rate := throttle.ReceiveBytesPerSecond; rate > 0 {
+		err := runTc("qdisc", "add", "dev", tapName, "root", "tbf",
+			"rate", strconv.FormatUint(rate, 10)+"bps",
+			"burst", makeBurst(rate),
+			"latency", "50ms")
+		if err != nil {
+			return err
+		}
+	}
+	if rate := throttle.TransmitBytesPerSecond; rate > 0 {
+		err := runTc("qdisc", "add", "dev", tapName, "handle", "ffff:",
+			"ingress")
+		if err != nil {
+			return err
+		}
+		err = runTc("filter", "add", "dev", tapName, "parent", "ffff:",
+			"protocol", "all", "u32", "match", "u32", "0", "0",
+			"police", "rate", strconv.FormatUint(rate, 10)+"bps",
+			"burst", makeBurst(rate), "drop", "flowid", ":1")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeBurst returns the burst size (100 milliseconds of traffic) for the
+// specified rate.
+func makeBurst(rate uint64) string {
+	burst := rate / 10
+	if burst < minimumBurstBytes {
+		burst = minimumBurstBytes
+	}
+	return strconv.FormatUint(burst, 10) + "b"
+}
+
+// applyNetworkThrottles will set the bandwidth limits for each of the VM tap
+// devices.
+func (vm *vmInfoType) applyNetworkThrottles(haveManagerLock bool) error {
+	throttles := vm.getNetworkThrottles(haveManagerLock)
+	addresses := append([]proto.Address{vm.Address}, vm.SecondaryAddresses...)
+	for index, throttle := range throttles {
+		if index >= len(addresses) {
+			break
+		}
+		err := setNetworkThrottle(getTapName(addresses[index]), throttle)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyVolumeThrottles will send the I/O limits for each of the VM volumes to
+// the QEMU monitor and will wait for QEMU to accept them. Volumes without any
+// limits are skipped unless force is true. The VM lock must be held.
+func (vm *vmInfoType) applyVolumeThrottles(haveManagerLock, force bool) error {
+	for index, throttle := range vm.getVolumeThrottles(haveManagerLock) {
+		if !force && throttle == (proto.VolumeThrottle{}) {
+			continue
+		}
+		arguments := blockSetIoThrottleArguments{
+			Bps:  throttle.BytesPerSecond,
+			Iops: throttle.IOPS,
+		}
+		switch vm.getVolumeInterface(index) {
+		case proto.VolumeInterfaceVirtIO, proto.VolumeInterfaceIDE:
+			arguments.Device = getDriveId(index)
+		default:
+			arguments.Id = getDriveId(index)
+		}
+		_, err := vm.sendQmpCommand("block_set_io_throttle", arguments)
+		if err != nil {
+			return fmt.Errorf("error throttling volume: %d: %s", index, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) changeVmThrottles(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, networkThrottles []proto.NetworkThrottle,
+	volumeThrottles []proto.VolumeThrottle) error {
+	vm, err := m.getVmLockAndAuth(ipAddr, true, authInfo, nil)
+	if err != nil {
+		return err
+	}
+	defer vm.mutex.Unlock()
+	if len(networkThrottles) > len(vm.SecondaryAddresses)+1 {
+		return errors.New("more network throttles specified than interfaces")
+	}
+	if len(volumeThrottles) > len(vm.Volumes) {
+		return errors.New("more volume throttles specified than VM volumes")
+	}
+	switch vm.State {
+	case proto.StateStarting:
+		return errors.New("VM is starting")
+	case proto.StateStopping:
+		return errors.New("VM is stopping")
+	case proto.StateDestroying:
+		return errors.New("VM is destroying")
+	}
+	oldNetworkThrottles := append([]proto.NetworkThrottle(nil),
+		vm.NetworkThrottles...)
+	oldVolumeThrottles := make([]proto.VolumeThrottle, 0, len(vm.Volumes))
+	for _, volume := range vm.Volumes {
+		oldVolumeThrottles = append(oldVolumeThrottles, volume.Throttle)
+	}
+	restoreThrottles := func() {
+		vm.NetworkThrottles = oldNetworkThrottles
+		for index, throttle := range oldVolumeThrottles {
+			vm.Volumes[index].Throttle = throttle
+		}
+		if vm.State == proto.StateRunning {
+			vm.applyNetworkThrottles(false)
+			vm.applyVolumeThrottles(false, true)
+		}
+	}
+	for len(vm.NetworkThrottles) < len(networkThrottles) {
+		vm.NetworkThrottles = append(vm.NetworkThrottles,
+			proto.NetworkThrottle{})
+	}
+	for index, throttle := range networkThrottles {
+		vm.NetworkThrottles[index] = throttle
+	}
+	for index, throttle := range volumeThrottles {
+		vm.Volumes[index].Throttle = throttle
+	}
+	if vm.State == proto.StateRunning {
+		if len(networkThrottles) > 0 {
+			if err := vm.applyNetworkThrottles(false); err != nil {
+				restoreThrottles()
+				return err
+			}
+		}
+		if len(volumeThrottles) > 0 {
+			if err := vm.applyVolumeThrottles(false, true); err != nil {
+				restoreThrottles()
+				return err
+			}
+		}
+	}
+	vm.writeAndSendInfo()
+	return nil
+}
+
+// getNetworkThrottles returns the effective bandwidth limits for each of the VM
+// network interfaces, taking into account the subnet, Hypervisor tag and
+// Hypervisor flag defaults, in that order of precedence.
+func (vm *vmInfoType) getNetworkThrottles(
+	haveManagerLock bool) []proto.NetworkThrottle {
+	if !haveManagerLock {
+		vm.manager.mutex.RLock()
+		defer vm.manager.mutex.RUnlock()
+	}
+	hypervisorDefaults := proto.NetworkThrottle{
+		ReceiveBytesPerSecond:  *defaultNetworkBytesPerSecond,
+		TransmitBytesPerSecond: *defaultNetworkBytesPerSecond,
+	}
+	subnetIDs := append([]string{vm.SubnetId}, vm.SecondarySubnetIDs...)
+	throttles := make([]proto.NetworkThrottle, 0, len(subnetIDs))
+	for index, subnetId := range subnetIDs {
+		var throttle proto.NetworkThrottle
+		if index < len(vm.NetworkThrottles) {
+			throttle = vm.NetworkThrottles[index]
+		}
+		if subnet, ok := vm.manager.subnets[subnetId]; ok {
+			throttle = mergeNetworkThrottle(throttle,
+				subnet.ThrottleDefaults.Network)
+		}
+		throttle = mergeNetworkThrottle(throttle,
+			vm.manager.throttleDefaults.Network)
+		throttles = append(throttles,
+			mergeNetworkThrottle(throttle, hypervisorDefaults))
+	}
+	return throttles
+}
+
+// getVolumeThrottles returns the effective I/O limits for each of the VM
+// volumes, taking into account the primary subnet, Hypervisor tag and
+// Hypervisor flag defaults, in that order of precedence.
+func (vm *vmInfoType) getVolumeThrottles(
+	haveManagerLock bool) []proto.VolumeThrottle {
+	if !haveManagerLock {
+		vm.manager.mutex.RLock()
+		defer vm.manager.mutex.RUnlock()
+	}
+	hypervisorDefaults := proto.VolumeThrottle{
+		BytesPerSecond: *defaultVolumeBytesPerSecond,
+		IOPS:           *defaultVolumeIOPS,
+	}
+	subnet := vm.manager.subnets[vm.SubnetId]
+	throttles := make([]proto.VolumeThrottle, 0, len(vm.Volumes))
+	for _, volume := range vm.Volumes {
+		throttle := mergeVolumeThrottle(volume.Throttle,
+			subnet.ThrottleDefaults.Volume)
+		throttle = mergeVolumeThrottle(throttle,
+			vm.manager.throttleDefaults.Volume)
+		throttles = append(throttles,
+			mergeVolumeThrottle(throttle, hypervisorDefaults))
+	}
+	return throttles
+}
+
+func (m *Manager) loadThrottleDefaults() error {
+	err := json.ReadFromFile(filepath.Join(m.StateDir, throttleDefaultsFile),
+		&m.throttleDefaults)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// setThrottleDefaults will save the defaults which are derived from the
+// Hypervisor tags and will re-apply the limits to all running VMs.
+func (m *Manager) setThrottleDefaults(defaults proto.ThrottleDefaults) error {
+	m.mutex.Lock()
+	if defaults == m.throttleDefaults {
+		m.mutex.Unlock()
+		return nil
+	}
+	err := json.WriteToFile(filepath.Join(m.StateDir, throttleDefaultsFile),
+		fsutil.PublicFilePerms, "    ", defaults)
+	if err != nil {
+		m.mutex.Unlock()
+		return err
+	}
+	m.throttleDefaults = defaults
+	vms := make([]*vmInfoType, 0, len(m.vms))
+	for _, vm := range m.vms {
+		vms = append(vms, vm)
+	}
+	m.mutex.Unlock()
+	m.sendUpdate(proto.Update{ThrottleDefaults: &defaults})
+	for _, vm := range vms {
+		vm.mutex.Lock()
+		if vm.State == proto.StateRunning {
+			if err := vm.applyNetworkThrottles(false); err != nil {
+				vm.logger.Println(err)
+			}
+			if err := vm.applyVolumeThrottles(false, true); err != nil {
+				vm.logger.Println(err)
+			}
+		}
+		vm.mutex.Unlock()
+	}
+	return nil
+}
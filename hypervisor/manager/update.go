@@ -33,27 +33,30 @@ func (m *Manager) makeUpdateChannel() <-chan proto.Update {
 	if err != nil {
 		m.Logger.Println(err)
 	}
+	throttleDefaults := m.throttleDefaults
 	channel := make(chan proto.Update, 16)
 	m.notifiersMutex.Lock()
 	defer m.notifiersMutex.Unlock()
 	m.notifiers[channel] = channel
 	// Initial update: give everything.
 	channel <- proto.Update{
-		HaveAddressPool:  true,
-		AddressPool:      m.addressPool.Registered,
-		HaveDisabled:     true,
-		Disabled:         m.disabled,
-		MemoryInMiB:      &m.memTotalInMiB,
-		NumCPUs:          &m.numCPUs,
-		NumFreeAddresses: numFreeAddresses,
-		HealthStatus:     m.healthStatus,
-		HaveSerialNumber: true,
-		SerialNumber:     m.serialNumber,
-		HaveSubnets:      true,
-		Subnets:          subnets,
-		TotalVolumeBytes: &m.totalVolumeBytes,
-		HaveVMs:          true,
-		VMs:              vms,
+		HaveAddressPool:   true,
+		AddressPool:       m.addressPool.Registered,
+		HaveDisabled:      true,
+		Disabled:          m.disabled,
+		HardwareInventory: m.hardwareInventory,
+		MemoryInMiB:       &m.memTotalInMiB,
+		NumCPUs:           &m.numCPUs,
+		NumFreeAddresses:  numFreeAddresses,
+		HealthStatus:      m.healthStatus,
+		HaveSerialNumber:  true,
+		SerialNumber:      m.serialNumber,
+		HaveSubnets:       true,
+		Subnets:           subnets,
+		ThrottleDefaults:  &throttleDefaults,
+		TotalVolumeBytes:  &m.totalVolumeBytes,
+		HaveVMs:           true,
+		VMs:               vms,
 	}
 	return channel
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil/mounts"
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/lockwatcher"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
@@ -186,12 +187,12 @@ func copyData(filename string, reader io.Reader, length uint64,
 	return err
 }
 
-func createTapDevice(bridge string) (*os.File, error) {
+func createTapDevice(bridge, tapName string) (*os.File, error) {
 	bridgeIf, err := net.InterfaceByName(bridge)
 	if err != nil {
 		return nil, err
 	}
-	tapFile, tapName, err := libnet.CreateTapDevice()
+	tapFile, err := libnet.CreateTapDeviceWithName(tapName)
 	if err != nil {
 		return nil, fmt.Errorf("error creating tap device: %s", err)
 	}
@@ -487,6 +488,22 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 	if err := req.MachineType.CheckValid(); err != nil {
 		return nil, err
 	}
+	if err := checkFirmware(req.VmInfo); err != nil {
+		return nil, err
+	}
+	if err := checkPartitionTableType(req.PartitionTableType); err != nil {
+		return nil, err
+	}
+	for name := range req.ExtraFiles {
+		if err := checkFirmwareStateFile(name); err != nil {
+			return nil, err
+		}
+	}
+	if req.FirewallPolicy != nil {
+		if err := req.FirewallPolicy.CheckValid(); err != nil {
+			return nil, err
+		}
+	}
 	if req.MemoryInMiB < 1 {
 		return nil, errors.New("no memory specified")
 	}
@@ -570,6 +587,8 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 				DestroyProtection:  req.DestroyProtection,
 				DisableVirtIO:      req.DisableVirtIO,
 				ExtraKernelOptions: req.ExtraKernelOptions,
+				FirewallPolicy:     req.FirewallPolicy,
+				FirmwareType:       req.FirmwareType,
 				Hostname:           req.Hostname,
 				ImageName:          req.ImageName,
 				ImageURL:           req.ImageURL,
@@ -577,6 +596,7 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 				MemoryInMiB:        req.MemoryInMiB,
 				MilliCPUs:          req.MilliCPUs,
 				OwnerGroups:        req.OwnerGroups,
+				PartitionTableType: req.PartitionTableType,
 				SpreadVolumes:      req.SpreadVolumes,
 				SecondaryAddresses: secondaryAddresses,
 				SecondarySubnetIDs: req.SecondarySubnetIDs,
@@ -584,6 +604,7 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 				SubnetId:           subnetId,
 				Tags:               req.Tags,
 				VirtualCPUs:        req.VirtualCPUs,
+				VirtualTPM:         req.VirtualTPM,
 				WatchdogAction:     req.WatchdogAction,
 				WatchdogModel:      req.WatchdogModel,
 			},
@@ -1425,8 +1446,9 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 			RootLabel:          vm.rootLabel(false),
 			RoundupPower:       request.RoundupPower,
 		}
-		err = m.writeRaw(vm.VolumeLocations[0], "", client, fs, writeRawOptions,
-			request.SkipBootloader)
+		tableType := vm.getRootPartitionTableType(&writeRawOptions)
+		err = m.writeRaw(vm.VolumeLocations[0], "", client, fs, tableType,
+			writeRawOptions, request.SkipBootloader)
 		if err != nil {
 			return sendError(conn, err)
 		}
@@ -1475,6 +1497,11 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 		vm.Volumes[0].Interface = request.Volumes[0].Interface
 	}
 	vm.Volumes[0].Type = rootVolumeType
+	err = writeFirmwareStateFiles(vm.VolumeLocations[0].DirectoryToCleanup,
+		request.ExtraFiles)
+	if err != nil {
+		return sendError(conn, err)
+	}
 	if request.UserDataSize > 0 {
 		filename := filepath.Join(vm.dirname, UserDataFile)
 		// Create a teelogger so that we get progress messages back to vm-control
@@ -1645,8 +1672,9 @@ func (m *Manager) debugVmImage(conn *srpc.Conn,
 			RootLabel:        vm.rootLabel(true),
 			RoundupPower:     request.RoundupPower,
 		}
+		tableType := vm.getRootPartitionTableType(&writeRawOptions)
 		err = m.writeRaw(vm.VolumeLocations[0], ".debug", client, fs,
-			writeRawOptions, false)
+			tableType, writeRawOptions, false)
 		if err != nil {
 			return sendError(conn, err)
 		}
@@ -1866,8 +1894,9 @@ func (m *Manager) exportLocalVm(authInfo *srpc.AuthInformation,
 	}
 	vm.setState(proto.StateExporting)
 	vmInfo := proto.ExportLocalVmInfo{
-		Bridges:     bridges,
-		LocalVmInfo: vm.LocalVmInfo,
+		Bridges:        bridges,
+		ExtraFilenames: vm.getFirmwareStatePaths(),
+		LocalVmInfo:    vm.LocalVmInfo,
 	}
 	return &vmInfo, nil
 }
@@ -2132,6 +2161,15 @@ func (m *Manager) getVmVolume(conn *srpc.Conn) error {
 	if err != nil {
 		return conn.Encode(proto.GetVmVolumeResponse{Error: err.Error()})
 	}
+	// Converting reads the volume more than once and the image length is sent
+	// first, so the data must not change. Blocking mutations prevents the VM
+	// from being started.
+	if request.ImageFormat != diskimage.FormatRaw &&
+		vm.State != proto.StateStopped {
+		vm.mutex.Unlock()
+		return conn.Encode(proto.GetVmVolumeResponse{
+			Error: "VM must be stopped to convert volume"})
+	}
 	vm.blockMutations = true
 	vm.mutex.Unlock()
 	defer vm.allowMutationsAndUnlock(false)
@@ -2170,6 +2208,19 @@ func (m *Manager) getVmVolume(conn *srpc.Conn) error {
 		response.ExtraFiles["initrd"] = initrd
 		response.ExtraFiles["kernel"] = kernel
 	}
+	if request.VolumeIndex == 0 {
+		// Always send the firmware state, since it changes while running.
+		firmwareFiles, err := vm.readFirmwareStateFiles()
+		if err != nil {
+			return conn.Encode(proto.GetVmVolumeResponse{Error: err.Error()})
+		}
+		for name, data := range firmwareFiles {
+			if response.ExtraFiles == nil {
+				response.ExtraFiles = make(map[string][]byte)
+			}
+			response.ExtraFiles[name] = data
+		}
+	}
 	if request.VolumeIndex >= uint(len(vm.VolumeLocations)) {
 		return conn.Encode(proto.GetVmVolumeResponse{
 			Error: "index too large"})
@@ -2179,14 +2230,17 @@ func (m *Manager) getVmVolume(conn *srpc.Conn) error {
 		return conn.Encode(proto.GetVmVolumeResponse{Error: err.Error()})
 	}
 	defer file.Close()
+	volume := vm.Volumes[request.VolumeIndex]
+	if request.ImageFormat != diskimage.FormatRaw {
+		return sendVmVolumeImage(conn, file, volume, request, response)
+	}
 	if err := conn.Encode(response); err != nil {
 		return err
 	}
 	if err := conn.Flush(); err != nil {
 		return err
 	}
-	return rsync.ServeBlocks(conn, conn, conn, file,
-		vm.Volumes[request.VolumeIndex].Size)
+	return rsync.ServeBlocks(conn, conn, conn, file, volume.Size)
 }
 
 func (m *Manager) holdVmLock(ipAddr net.IP, timeout time.Duration,
@@ -2241,6 +2295,14 @@ func (m *Manager) importLocalVm(authInfo *srpc.AuthInformation,
 	if !bytes.Equal(m.rootCookie, request.VerificationCookie) {
 		return fmt.Errorf("bad verification cookie: you are not root")
 	}
+	if err := checkFirmware(request.VmInfo); err != nil {
+		return err
+	}
+	for name := range request.ExtraFilenames {
+		if err := checkFirmwareStateFile(name); err != nil {
+			return err
+		}
+	}
 	request.VmInfo.OwnerUsers = []string{authInfo.Username}
 	request.VmInfo.Uncommitted = true
 	volumeDirectories := make([]string, 0, len(request.VolumeFilenames))
@@ -2352,6 +2414,18 @@ func (m *Manager) importLocalVm(authInfo *srpc.AuthInformation,
 		vm.VolumeLocations = append(vm.VolumeLocations, proto.LocalVolume{
 			dirname, destFilename})
 	}
+	for name, sourceFilename := range request.ExtraFilenames {
+		destFilename, err := extraFilePath(
+			vm.VolumeLocations[0].DirectoryToCleanup, name)
+		if err != nil {
+			return err
+		}
+		err = fsutil.CopyFile(destFilename, sourceFilename,
+			fsutil.PrivateFilePerms)
+		if err != nil {
+			return err
+		}
+	}
 	m.vms[ipAddress] = vm
 	if _, err := vm.startManaging(0, false, true); err != nil {
 		return err
@@ -2625,6 +2699,40 @@ func sendVmPatchImageMessage(conn *srpc.Conn, message string) error {
 	return conn.Flush()
 }
 
+// sendVmVolumeImage converts the volume to the requested disk image format and
+// streams it. The length is computed first, so the data are read twice and the
+// VM must be stopped.
+func sendVmVolumeImage(conn *srpc.Conn, file *os.File, volume proto.Volume,
+	request proto.GetVmVolumeRequest,
+	response proto.GetVmVolumeResponse) error {
+	if volume.Format != proto.VolumeFormatRaw {
+		return conn.Encode(proto.GetVmVolumeResponse{
+			Error: "cannot convert volume with format: " +
+				volume.Format.String()})
+	}
+	options := diskimage.WriteOptions{Compress: request.CompressImage}
+	length, err := diskimage.GetLength(file, volume.Size, request.ImageFormat,
+		options)
+	if err != nil {
+		return conn.Encode(proto.GetVmVolumeResponse{Error: err.Error()})
+	}
+	response.ImageLength = length
+	if err := conn.Encode(response); err != nil {
+		return err
+	}
+	nWritten, err := diskimage.Write(conn, file, volume.Size,
+		request.ImageFormat, options)
+	if err != nil {
+		return err
+	}
+	if nWritten != length {
+		// The connection is out of sync, so close it.
+		return fmt.Errorf("image length changed from: %d to: %d",
+			length, nWritten)
+	}
+	return conn.Flush()
+}
+
 func (m *Manager) migrateVmChecks(vmInfo proto.VmInfo,
 	skipMemoryCheck bool) error {
 	switch vmInfo.State {
@@ -2788,11 +2896,19 @@ func migrateVmVolume(hypervisor *srpc.Client, directory, filename string,
 	if err != nil {
 		return nil, err
 	}
+	err = writeFirmwareStateFiles(directory, response.ExtraFiles)
+	if err != nil {
+		return nil, err
+	}
 	if !getExtraFiles {
 		return &stats, nil
 	}
 	for name, data := range response.ExtraFiles {
-		if name != "initrd" && name != "kernel" {
+		switch name {
+		case "initrd", "kernel":
+		case nvramExtraFile, tpmStateExtraFile:
+			continue
+		default:
 			return nil, fmt.Errorf("received unsupported extra file: %s", name)
 		}
 		err := ioutil.WriteFile(filepath.Join(directory, name), data,
@@ -3288,8 +3404,9 @@ func (m *Manager) replaceVmImage(conn *srpc.Conn,
 			RootLabel:          vm.rootLabelSaved(false),
 			RoundupPower:       request.RoundupPower,
 		}
+		tableType := vm.getRootPartitionTableType(&writeRawOptions)
 		err = m.writeRaw(vm.VolumeLocations[0], ".new", client, img.FileSystem,
-			writeRawOptions, request.SkipBootloader)
+			tableType, writeRawOptions, request.SkipBootloader)
 		if err != nil {
 			return sendError(conn, err)
 		}
@@ -3467,6 +3584,14 @@ func (m *Manager) restoreVmFromSnapshot(ipAddr net.IP,
 		vm.mutex.Unlock()
 		changed = true
 	}
+	for _, filename := range vm.getFirmwareStatePaths() {
+		snapshotFilename := filename + "." + snapshotSuffix
+		if err := os.Rename(snapshotFilename, filename); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -3539,6 +3664,9 @@ func (m *Manager) reorderVmVolumes(ipAddr net.IP,
 		if vm.getActiveKernelPath() != "" {
 			return errors.New("cannot reorder root volume with separate kernel")
 		}
+		if len(vm.getFirmwareStatePaths()) > 0 {
+			return errors.New("cannot reorder root volume with firmware state")
+		}
 	}
 	if len(volumeIndices) != len(vm.VolumeLocations) {
 		return fmt.Errorf(
@@ -3632,6 +3760,7 @@ func (vm *vmInfoType) scanVmRoot(scanFilter *filter.Filter) (
 
 func (m *Manager) sendVmInfo(ipAddress string, vm *proto.VmInfo) {
 	if ipAddress != "0.0.0.0" {
+		m.checkFirewallRefresh(ipAddress, vm)
 		if vm == nil { // GOB cannot encode a nil value in a map.
 			vm = new(proto.VmInfo)
 		}
@@ -3706,6 +3835,13 @@ func (m *Manager) snapshotVm(ipAddr net.IP, authInfo *srpc.AuthInformation,
 			changed = true
 		}
 	}
+	for _, filename := range vm.getFirmwareStatePaths() {
+		err := fsutil.CopyFile(filename+"."+snapshotSuffix, filename,
+			fsutil.PrivateFilePerms)
+		if err != nil {
+			return err
+		}
+	}
 	doCleanup = false
 	return nil
 }
@@ -3839,7 +3975,7 @@ func (m *Manager) unregisterVmMetadataNotifier(ipAddr net.IP,
 }
 
 func (m *Manager) writeRaw(volume proto.LocalVolume, extension string,
-	client *srpc.Client, fs *filesystem.FileSystem,
+	client *srpc.Client, fs *filesystem.FileSystem, tableType mbr.TableType,
 	writeRawOptions util.WriteRawOptions, skipBootloader bool) error {
 	startTime := time.Now()
 	var objectsGetter objectserver.ObjectsGetter
@@ -3866,7 +4002,7 @@ func (m *Manager) writeRaw(volume proto.LocalVolume, extension string,
 	writeRawOptions.WriteFstab = true
 	err := util.WriteRawWithOptions(fs, objectsGetter,
 		volume.Filename+extension, fsutil.PrivateFilePerms,
-		mbr.TABLE_TYPE_MSDOS, writeRawOptions, m.Logger)
+		tableType, writeRawOptions, m.Logger)
 	if err != nil {
 		return err
 	}
@@ -4003,6 +4139,10 @@ func (vm *vmInfoType) delete() {
 		close(vm.identityProviderNotifier)
 		vm.identityProviderNotifier = nil
 	}
+	vm.removeFirewall()
+	vm.metrics.mutex.Lock()
+	vm.unregisterMetrics()
+	vm.metrics.mutex.Unlock()
 	vm.mutex.Unlock()
 	for _, volume := range vm.VolumeLocations {
 		os.Remove(volume.Filename)
@@ -4072,6 +4212,11 @@ func (vm *vmInfoType) discardSnapshot(snapshotName, snapshotSuff string) (
 		vm.mutex.Unlock()
 		changed = true
 	}
+	for _, filename := range []string{vm.getNvramPath(), vm.getTpmStatePath()} {
+		if err := removeFile(filename + "." + snapshotSuff); err != nil {
+			return changed, err
+		}
+	}
 	return changed, nil
 }
 
@@ -4116,10 +4261,11 @@ func (vm *vmInfoType) kill() {
 }
 
 func (vm *vmInfoType) monitor(monitorSock net.Conn,
-	commandInput <-chan string, commandOutput chan<- byte) {
+	commandInput <-chan string, commandOutput chan<- byte,
+	replies *qmpRepliesType) {
 	vm.hasHealthAgent = false
 	defer monitorSock.Close()
-	go vm.processMonitorResponses(monitorSock, commandOutput)
+	go vm.processMonitorResponses(monitorSock, commandOutput, replies)
 	cancelChannel := make(chan struct{}, 1)
 	go vm.probeHealthAgent(cancelChannel)
 	go vm.serialManager()
@@ -4275,7 +4421,9 @@ func (vm *vmInfoType) startManaging(dhcpTimeout time.Duration,
 			commandOutput := make(chan byte, 16<<10)
 			vm.commandInput = commandInput
 			vm.commandOutput = commandOutput
-			go vm.monitor(monitorSock, commandInput, commandOutput)
+			vm.qmpReplies = newQmpReplies()
+			go vm.monitor(monitorSock, commandInput, commandOutput,
+				vm.qmpReplies)
 			commandInput <- "qmp_capabilities"
 			commandInput <- "quit"
 		} else {
@@ -4334,9 +4482,13 @@ func (vm *vmInfoType) startManaging(dhcpTimeout time.Duration,
 	vm.commandInput = commandInput
 	commandOutput := make(chan byte, 16<<10)
 	vm.commandOutput = commandOutput
-	go vm.monitor(monitorSock, commandInput, commandOutput)
+	vm.qmpReplies = newQmpReplies()
+	go vm.monitor(monitorSock, commandInput, commandOutput, vm.qmpReplies)
 	commandInput <- "qmp_capabilities"
 	if vm.getDebugRoot() == "" {
+		if err := vm.applyVolumeThrottles(haveManagerLock, false); err != nil {
+			vm.logger.Println(err)
+		}
 		vm.setState(proto.StateRunning)
 	} else {
 		vm.setState(proto.StateDebugging)
@@ -4484,15 +4636,22 @@ func (vm *vmInfoType) startVm(enableNetboot, haveManagerLock bool) error {
 	if err != nil {
 		return err
 	}
+	addresses := append([]proto.Address{vm.Address}, vm.SecondaryAddresses...)
 	var tapFiles []*os.File
-	for _, bridge := range bridges {
-		tapFile, err := createTapDevice(bridge)
+	for index, bridge := range bridges {
+		tapFile, err := createTapDevice(bridge, getTapName(addresses[index]))
 		if err != nil {
 			return fmt.Errorf("error creating tap device: %s", err)
 		}
 		defer tapFile.Close()
 		tapFiles = append(tapFiles, tapFile)
 	}
+	if err := vm.applyFirewall(haveManagerLock); err != nil {
+		return err
+	}
+	if err := vm.applyNetworkThrottles(haveManagerLock); err != nil {
+		return err
+	}
 	pidfile := filepath.Join(vm.dirname, "pidfile")
 	err = vm.startQemuVm(enableNetboot, haveManagerLock, pidfile, nCpus,
 		netOptions, tapFiles)
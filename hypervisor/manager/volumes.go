@@ -14,9 +14,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil/mounts"
+	"github.com/Cloud-Foundations/Dominator/lib/gpt"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/mbr"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver/cachingreader"
@@ -25,7 +27,8 @@ import (
 )
 
 const (
-	sysClassBlock = "/sys/class/block"
+	efiSystemPartitionSize = 64 << 20
+	sysClassBlock          = "/sys/class/block"
 )
 
 var (
@@ -44,6 +47,16 @@ func check2fs(device string) bool {
 	return cmd.Run() == nil
 }
 
+// checkPartitionTableType checks if the partition table type for the root
+// volume of a VM is supported. Zero selects the default (msdos).
+func checkPartitionTableType(tableType mbr.TableType) error {
+	switch tableType {
+	case 0, mbr.TABLE_TYPE_GPT, mbr.TABLE_TYPE_MSDOS:
+		return nil
+	}
+	return fmt.Errorf("unsupported partition table type: %s", tableType)
+}
+
 func checkTrim(mountEntry *mounts.MountEntry) bool {
 	for _, option := range strings.Split(mountEntry.Options, ",") {
 		if option == "discard" {
@@ -53,6 +66,10 @@ func checkTrim(mountEntry *mounts.MountEntry) bool {
 	return false
 }
 
+// demapDevice returns the underlying device for a device-mapper device (such
+// as an encrypted volume). Devices built from multiple devices (such as RAID
+// arrays and LVM logical volumes spanning several physical volumes) are
+// returned unchanged, as they are volumes in their own right.
 func demapDevice(device string) (string, error) {
 	sysDir := filepath.Join(sysClassBlock, filepath.Base(device), "slaves")
 	if file, err := os.Open(sysDir); err != nil {
@@ -64,7 +81,7 @@ func demapDevice(device string) (string, error) {
 			return "", err
 		}
 		if len(names) != 1 {
-			return "", fmt.Errorf("%s has %d entries", device, len(names))
+			return device, nil
 		}
 		return filepath.Join("/dev", names[0]), nil
 	}
@@ -200,11 +217,25 @@ func grow2fs(volume string, logger log.DebugLogger) error {
 		// Simple case: file-system is on the raw volume, no partition table.
 		return resize2fs(volume, 0)
 	}
-	// Read MBR and check if it's a simple single-partition volume.
 	file, err := os.Open(volume)
 	if err != nil {
 		return err
 	}
+	// A GPT is checked first, since it is preceeded by a protective MBR.
+	gptTable, err := gpt.Decode(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if gptTable != nil {
+		file.Close()
+		// The root partition is the last partition (see gpt.WriteDefault).
+		if err := gptTable.GrowPartition(volume, 0); err != nil {
+			return err
+		}
+		return growFirstPartition2fs(volume, logger)
+	}
+	// Read MBR and check if it's a simple single-partition volume.
 	partitionTable, err := mbr.Decode(file)
 	file.Close()
 	if err != nil {
@@ -230,6 +261,12 @@ func grow2fs(volume string, logger log.DebugLogger) error {
 		return fmt.Errorf("error running parted for: %s: %s: %s",
 			volume, err, string(output))
 	}
+	return growFirstPartition2fs(volume, logger)
+}
+
+// growFirstPartition2fs will try and grow an ext{2,3,4} file-system in the
+// first partition of a volume to fit the partition size.
+func growFirstPartition2fs(volume string, logger log.DebugLogger) error {
 	// Try and resize the file-system in the partition (need a loop device).
 	device, err := fsutil.LoopbackSetupAndWaitForPartition(volume, "p1",
 		time.Minute, logger)
@@ -283,11 +320,18 @@ func shrink2fs(volume string, size uint64, logger log.DebugLogger) error {
 		// Simple case: file-system is on the raw volume, no partition table.
 		return resize2fs(volume, size)
 	}
-	// Read MBR and check if it's a simple single-partition volume.
 	file, err := os.Open(volume)
 	if err != nil {
 		return err
 	}
+	if gptTable, err := gpt.Decode(file); err != nil {
+		file.Close()
+		return err
+	} else if gptTable != nil {
+		file.Close()
+		return errors.New("shrinking volumes with a GPT is not supported")
+	}
+	// Read MBR and check if it's a simple single-partition volume.
 	partitionTable, err := mbr.Decode(file)
 	file.Close()
 	if err != nil {
@@ -386,6 +430,13 @@ func (m *Manager) detectVolumeDirectories(mountTable *mounts.MountTable) error {
 	if err != nil {
 		return err
 	}
+	if m.StorageLayout != nil {
+		if m.detectVolumeDirectoriesFromLayout(mountMap) {
+			return nil
+		}
+		m.Logger.Println(
+			"no data file-systems from storage layout found, scanning")
+	}
 	var mountEntriesToUse []*mounts.MountEntry
 	biggestMounts := make(map[string]mountInfo)
 	for device, mountEntry := range mountMap {
@@ -421,7 +472,29 @@ func (m *Manager) detectVolumeDirectories(mountTable *mounts.MountTable) error {
 	for _, biggestMount := range biggestMounts {
 		mountEntriesToUse = append(mountEntriesToUse, biggestMount.mountEntry)
 	}
-	for _, entry := range mountEntriesToUse {
+	m.addVolumeDirectories(mountEntriesToUse)
+	return nil
+}
+
+// detectVolumeDirectoriesFromLayout uses the data file-systems from the
+// storage layout used by the installer. It returns false if none are mounted.
+func (m *Manager) detectVolumeDirectoriesFromLayout(
+	mountMap map[string]*mounts.MountEntry) bool {
+	var mountEntriesToUse []*mounts.MountEntry
+	for _, mountEntry := range mountMap {
+		if m.StorageLayout.IsDataMountPoint(mountEntry.MountPoint) {
+			mountEntriesToUse = append(mountEntriesToUse, mountEntry)
+		}
+	}
+	if len(mountEntriesToUse) < 1 {
+		return false
+	}
+	m.addVolumeDirectories(mountEntriesToUse)
+	return true
+}
+
+func (m *Manager) addVolumeDirectories(mountEntries []*mounts.MountEntry) {
+	for _, entry := range mountEntries {
 		volumeDirectory := filepath.Join(entry.MountPoint, "hyper-volumes")
 		m.volumeDirectories = append(m.volumeDirectories, volumeDirectory)
 		m.volumeInfos[volumeDirectory] = VolumeInfo{
@@ -430,7 +503,6 @@ func (m *Manager) detectVolumeDirectories(mountTable *mounts.MountTable) error {
 		}
 	}
 	sort.Strings(m.volumeDirectories)
-	return nil
 }
 
 func (m *Manager) findFreeSpace(size uint64, freeSpaceTable map[string]uint64,
@@ -590,3 +662,17 @@ func (m *Manager) setupVolumesAndObjectCache(startOptions StartOptions) error {
 	}
 	return nil
 }
+
+// getRootPartitionTableType returns the partition table type to use when
+// writing the root volume. For a GPT on a VM with UEFI firmware, an EFI System
+// Partition is added to the options.
+func (vm *vmInfoType) getRootPartitionTableType(
+	options *util.WriteRawOptions) mbr.TableType {
+	if vm.PartitionTableType != mbr.TABLE_TYPE_GPT {
+		return mbr.TABLE_TYPE_MSDOS
+	}
+	if vm.FirmwareType.IsUEFI() {
+		options.EfiSystemPartitionSize = efiSystemPartitionSize
+	}
+	return mbr.TABLE_TYPE_GPT
+}
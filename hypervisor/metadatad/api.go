@@ -46,10 +46,26 @@ func StartServer(hypervisorPortNum uint, bridges []net.Interface,
 		constants.MetadataIdentityRsaX509Cert: manager.IdentityRsaX509CertFile,
 		constants.MetadataIdentityRsaX509Key:  manager.IdentityRsaX509KeyFile,
 		constants.MetadataUserData:            manager.UserDataFile,
+
+		constants.MetadataNoCloudUserData:   manager.UserDataFile,
+		constants.MetadataOpenStackUserData: manager.UserDataFile,
 	}
 	s.infoHandlers = map[string]metadataWriter{
 		constants.MetadataEpochTime:   s.showTime,
 		constants.MetadataIdentityDoc: s.showVM,
+
+		constants.MetadataEc2Hostname:      s.showHostname,
+		constants.MetadataEc2InstanceId:    s.showInstanceId,
+		constants.MetadataEc2LocalHostname: s.showHostname,
+		constants.MetadataEc2LocalIPv4:     s.showLocalIPv4,
+		constants.MetadataEc2MacAddress:    s.showMacAddress,
+		constants.MetadataEc2PublicKey:     s.showPublicKeys,
+
+		constants.MetadataNoCloudMetaData:      s.showNoCloudMetaData,
+		constants.MetadataNoCloudNetworkConfig: s.showNetworkConfig,
+
+		constants.MetadataOpenStackMetaData:    s.showOpenStackMetaData,
+		constants.MetadataOpenStackNetworkData: s.showOpenStackNetworkData,
 	}
 	s.rawHandlers = map[string]rawHandlerFunc{
 		constants.SmallStackDataSource:        s.showTrue,
@@ -0,0 +1,88 @@
+package metadatad
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/Cloud-Foundations/Dominator/lib/cloudinit"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+// normalisePath maps versioned EC2 and OpenStack metadata paths (such as
+// /2009-04-04/meta-data/ and /openstack/2018-08-27/) to the "latest" version.
+func normalisePath(path string) string {
+	splitPath := strings.SplitN(path, "/", 4)
+	versionIndex := 1
+	if len(splitPath) > 2 && splitPath[1] == "openstack" {
+		versionIndex = 2
+	}
+	if len(splitPath) <= versionIndex+1 {
+		return path
+	}
+	version := splitPath[versionIndex]
+	if version == "" || !unicode.IsDigit(rune(version[0])) {
+		return path
+	}
+	splitPath[versionIndex] = "latest"
+	return strings.Join(splitPath, "/")
+}
+
+func (s *server) getCloudInitMetadata(
+	vmInfo proto.VmInfo) *cloudinit.Metadata {
+	subnets := make(map[string]proto.Subnet)
+	for _, subnet := range s.manager.ListSubnets(false) {
+		subnets[subnet.Id] = subnet
+	}
+	return cloudinit.New(vmInfo, subnets)
+}
+
+func (s *server) showHostname(writer io.Writer, vmInfo proto.VmInfo) error {
+	_, err := fmt.Fprintln(writer, s.getCloudInitMetadata(vmInfo).Hostname)
+	return err
+}
+
+func (s *server) showInstanceId(writer io.Writer, vmInfo proto.VmInfo) error {
+	_, err := fmt.Fprintln(writer, s.getCloudInitMetadata(vmInfo).InstanceId)
+	return err
+}
+
+func (s *server) showLocalIPv4(writer io.Writer, vmInfo proto.VmInfo) error {
+	_, err := fmt.Fprintln(writer, vmInfo.Address.IpAddress)
+	return err
+}
+
+func (s *server) showMacAddress(writer io.Writer, vmInfo proto.VmInfo) error {
+	_, err := fmt.Fprintln(writer, vmInfo.Address.MacAddress)
+	return err
+}
+
+func (s *server) showNetworkConfig(writer io.Writer,
+	vmInfo proto.VmInfo) error {
+	return s.getCloudInitMetadata(vmInfo).WriteNetworkConfig(writer)
+}
+
+func (s *server) showNoCloudMetaData(writer io.Writer,
+	vmInfo proto.VmInfo) error {
+	return s.getCloudInitMetadata(vmInfo).WriteMetaData(writer)
+}
+
+func (s *server) showOpenStackMetaData(writer io.Writer,
+	vmInfo proto.VmInfo) error {
+	return s.getCloudInitMetadata(vmInfo).WriteOpenStackMetaData(writer)
+}
+
+func (s *server) showOpenStackNetworkData(writer io.Writer,
+	vmInfo proto.VmInfo) error {
+	return s.getCloudInitMetadata(vmInfo).WriteOpenStackNetworkData(writer)
+}
+
+func (s *server) showPublicKeys(writer io.Writer, vmInfo proto.VmInfo) error {
+	for _, key := range s.getCloudInitMetadata(vmInfo).PublicKeys {
+		if _, err := fmt.Fprintln(writer, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
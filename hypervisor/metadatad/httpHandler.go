@@ -41,17 +41,18 @@ func (s *server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	if filename, ok := s.fileHandlers[req.URL.Path]; ok {
+	urlPath := normalisePath(req.URL.Path)
+	if filename, ok := s.fileHandlers[urlPath]; ok {
 		s.showFileData(w, ipAddr, filename)
 		return
 	}
-	if rawHandler, ok := s.rawHandlers[req.URL.Path]; ok {
+	if rawHandler, ok := s.rawHandlers[urlPath]; ok {
 		rawHandler(w, ipAddr)
 		return
 	}
 	writer := bufio.NewWriter(w)
 	defer writer.Flush()
-	if infoHandler, ok := s.infoHandlers[req.URL.Path]; ok {
+	if infoHandler, ok := s.infoHandlers[urlPath]; ok {
 		if err := infoHandler(writer, vmInfo); err != nil {
 			fmt.Fprintln(writer, err)
 		}
@@ -61,13 +62,16 @@ func (s *server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	pathsSet := make(map[string]struct{})
 	for path := range s.paths {
 		result := ""
-		if strings.HasPrefix(path, req.URL.Path) {
-			splitPath := strings.Split(path[len(req.URL.Path):], "/")
+		if strings.HasPrefix(path, urlPath) {
+			// Directories are shown with a trailing slash, as cloud-init
+			// expects.
+			splitPath := strings.SplitN(
+				strings.TrimPrefix(path[len(urlPath):], "/"), "/", 2)
 			result = splitPath[0]
-			if result == "" {
-				result = splitPath[1]
+			if len(splitPath) > 1 && result != "" {
+				result += "/"
 			}
-		} else if req.URL.Path == "/*" {
+		} else if urlPath == "/*" {
 			result = path[1:]
 		}
 		if result != "" {
@@ -14,7 +14,7 @@ import (
 type DhcpServer interface {
 	AddLease(address proto.Address, hostname string) error
 	AddNetbootLease(address proto.Address, hostname string,
-		subnet *proto.Subnet) error
+		subnet *proto.Subnet, method proto.NetbootMethod) error
 	ClosePacketWatchChannel(channel <-chan proto.WatchDhcpResponse)
 	MakeAcknowledgmentChannel(ipAddr net.IP) <-chan struct{}
 	MakePacketWatchChannel() <-chan proto.WatchDhcpResponse
@@ -66,12 +66,14 @@ func Setup(manager *manager.Manager, dhcpServer DhcpServer,
 			"ChangeVmConsoleType",
 			"ChangeVmCpuPriority",
 			"ChangeVmDestroyProtection",
+			"ChangeVmFirewallPolicy",
 			"ChangeVmMachineType",
 			"ChangeVmOwnerGroups",
 			"ChangeVmOwnerUsers",
 			"ChangeVmSize",
 			"ChangeVmSubnet",
 			"ChangeVmTags",
+			"ChangeVmThrottles",
 			"ChangeVmVolumeInterfaces",
 			"ChangeVmVolumeSize",
 			"CommitImportedVm",
@@ -96,6 +98,7 @@ func Setup(manager *manager.Manager, dhcpServer DhcpServer,
 			"GetVmInfo",
 			"GetVmInfos",
 			"GetVmLastPatchLog",
+			"GetVmMetrics",
 			"GetVmUserData",
 			"GetVmVolume",
 			"ImportLocalVm",
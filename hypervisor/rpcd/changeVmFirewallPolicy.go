@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) ChangeVmFirewallPolicy(conn *srpc.Conn,
+	request hypervisor.ChangeVmFirewallPolicyRequest,
+	reply *hypervisor.ChangeVmFirewallPolicyResponse) error {
+	*reply = hypervisor.ChangeVmFirewallPolicyResponse{
+		errors.ErrorToString(
+			t.manager.ChangeVmFirewallPolicy(request.IpAddress,
+				conn.GetAuthInformation(),
+				request.FirewallPolicy))}
+	return nil
+}
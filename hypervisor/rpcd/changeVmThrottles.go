@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) ChangeVmThrottles(conn *srpc.Conn,
+	request hypervisor.ChangeVmThrottlesRequest,
+	reply *hypervisor.ChangeVmThrottlesResponse) error {
+	*reply = hypervisor.ChangeVmThrottlesResponse{
+		errors.ErrorToString(
+			t.manager.ChangeVmThrottles(request.IpAddress,
+				conn.GetAuthInformation(),
+				request.NetworkThrottles, request.VolumeThrottles))}
+	return nil
+}
@@ -0,0 +1,19 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) GetVmMetrics(conn *srpc.Conn,
+	request hypervisor.GetVmMetricsRequest,
+	reply *hypervisor.GetVmMetricsResponse) error {
+	samples, err := t.manager.GetVmMetrics(request.IpAddress,
+		request.MaximumSamples)
+	*reply = hypervisor.GetVmMetricsResponse{
+		Error:   errors.ErrorToString(err),
+		Samples: samples,
+	}
+	return nil
+}
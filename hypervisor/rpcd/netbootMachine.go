@@ -1,9 +1,12 @@
 package rpcd
 
 import (
+	"fmt"
 	"net"
+	"path"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
@@ -20,10 +23,19 @@ func (t *srpcType) NetbootMachine(conn *srpc.Conn,
 func (t *srpcType) netbootMachine(
 	request hypervisor.NetbootMachineRequest) error {
 	err := t.dhcpServer.AddNetbootLease(request.Address, request.Hostname,
-		request.Subnet)
+		request.Subnet, request.Method)
 	if err != nil {
 		return err
 	}
+	if request.Method == hypervisor.NetbootMethodIPXE {
+		files := make(map[string][]byte, len(request.Files)+1)
+		for filename, data := range request.Files {
+			files[filename] = data
+		}
+		files[constants.NetbootIpxeScriptFile] = makeIpxeScript(request)
+		request.Files = files
+		request.NumAcknowledgementsToWaitFor++ // iPXE makes a DHCP request.
+	}
 	t.tftpbootServer.RegisterFiles(request.Address.IpAddress, request.Files)
 	if request.WaitTimeout <= 0 {
 		request.WaitTimeout = time.Minute
@@ -69,3 +81,23 @@ func expireLeaseAfter(dhcpServer DhcpServer, ipAddr net.IP,
 	time.Sleep(timeout)
 	dhcpServer.RemoveLease(ipAddr)
 }
+
+// makeIpxeScript returns an iPXE script which loads the kernel and initrd. The
+// filenames are relative to the URL of the script, so they are fetched over
+// HTTP from the same server.
+func makeIpxeScript(request hypervisor.NetbootMachineRequest) []byte {
+	initrd := request.IpxeInitrd
+	if initrd == "" {
+		initrd = "initrd.img"
+	}
+	kernel := request.IpxeKernel
+	if kernel == "" {
+		kernel = "vmlinuz"
+	}
+	kernelLine := fmt.Sprintf("kernel %s initrd=%s", kernel, path.Base(initrd))
+	if request.IpxeKernelOptions != "" {
+		kernelLine += " " + request.IpxeKernelOptions
+	}
+	return []byte(fmt.Sprintf("#!ipxe\n%s\ninitrd %s\nboot\n",
+		kernelLine, initrd))
+}
@@ -0,0 +1,44 @@
+package rpcd
+
+import (
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func TestMakeIpxeScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		request hypervisor.NetbootMachineRequest
+		script  string
+	}{
+		{
+			name: "defaults",
+			script: "#!ipxe\nkernel vmlinuz initrd=initrd.img\n" +
+				"initrd initrd.img\nboot\n",
+		},
+		{
+			name: "kernelOptions",
+			request: hypervisor.NetbootMachineRequest{
+				IpxeKernelOptions: "console=ttyS0,115200n8 quiet",
+			},
+			script: "#!ipxe\n" +
+				"kernel vmlinuz initrd=initrd.img console=ttyS0,115200n8 quiet\n" +
+				"initrd initrd.img\nboot\n",
+		},
+		{
+			name: "subdirectory",
+			request: hypervisor.NetbootMachineRequest{
+				IpxeInitrd: "installer/initrd.gz",
+				IpxeKernel: "installer/kernel",
+			},
+			script: "#!ipxe\nkernel installer/kernel initrd=initrd.gz\n" +
+				"initrd installer/initrd.gz\nboot\n",
+		},
+	}
+	for _, test := range tests {
+		if got := string(makeIpxeScript(test.request)); got != test.script {
+			t.Errorf("%s: got:\n%swant:\n%s", test.name, got, test.script)
+		}
+	}
+}
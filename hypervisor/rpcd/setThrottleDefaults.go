@@ -0,0 +1,16 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) SetThrottleDefaults(conn *srpc.Conn,
+	request hypervisor.SetThrottleDefaultsRequest,
+	reply *hypervisor.SetThrottleDefaultsResponse) error {
+	*reply = hypervisor.SetThrottleDefaultsResponse{
+		errors.ErrorToString(
+			t.manager.SetThrottleDefaults(request.ThrottleDefaults))}
+	return nil
+}
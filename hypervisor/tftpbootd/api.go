@@ -2,6 +2,7 @@ package tftpbootd
 
 import (
 	"net"
+	"net/http"
 	"sync"
 	"time"
 
@@ -30,9 +31,12 @@ type TftpbootServer struct {
 	imageServerClient      *srpc.Client
 }
 
-func New(imageServerAddress, imageStreamName string,
+// New starts a TFTP server for network booting. If mux is not nil, the files
+// are also served over HTTP (for UEFI HTTP Boot and iPXE) under
+// constants.NetbootHttpPrefix, using mux.
+func New(imageServerAddress, imageStreamName string, mux *http.ServeMux,
 	logger log.DebugLogger) (*TftpbootServer, error) {
-	return newServer(imageServerAddress, imageStreamName, logger)
+	return newServer(imageServerAddress, imageStreamName, mux, logger)
 }
 
 func (s *TftpbootServer) RegisterFiles(ipAddr net.IP, files map[string][]byte) {
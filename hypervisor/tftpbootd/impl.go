@@ -7,11 +7,14 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	imageclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
@@ -21,11 +24,9 @@ import (
 	"github.com/pin/tftp"
 )
 
-const tftpbootPrefix = "/tftpboot"
-
 func cleanPath(filename string) string {
-	if strings.HasPrefix(filename, tftpbootPrefix) {
-		return filename[len(tftpbootPrefix):]
+	if strings.HasPrefix(filename, constants.NetbootHttpPrefix) {
+		return filename[len(constants.NetbootHttpPrefix):]
 	} else if filename[0] != '/' {
 		return "/" + filename
 	} else {
@@ -48,7 +49,7 @@ func readHandler(rf io.ReaderFrom, reader io.Reader,
 	return nil
 }
 
-func newServer(imageServerAddress, imageStreamName string,
+func newServer(imageServerAddress, imageStreamName string, mux *http.ServeMux,
 	logger log.DebugLogger) (*TftpbootServer, error) {
 	s := &TftpbootServer{
 		cachedFileSystems:  make(map[string]*cachedFileSystem),
@@ -59,6 +60,9 @@ func newServer(imageServerAddress, imageStreamName string,
 		closeClientTimer:   time.NewTimer(time.Minute),
 	}
 	s.tftpdServer = tftp.NewServer(s.readHandler, nil)
+	if mux != nil {
+		mux.HandleFunc(constants.NetbootHttpPrefix+"/", s.httpHandler)
+	}
 	go func() {
 		if err := s.tftpdServer.ListenAndServe(":69"); err != nil {
 			s.logger.Println(err)
@@ -155,61 +159,114 @@ func (s *TftpbootServer) getImageServerClient() *srpc.Client {
 	}
 }
 
-func (s *TftpbootServer) imageServerClientCloser() {
-	for range s.closeClientTimer.C {
-		s.closeImageServerClient()
+func (s *TftpbootServer) httpHandler(w http.ResponseWriter, req *http.Request) {
+	filename := cleanPath(req.URL.Path)
+	remoteAddr, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remoteAddr = req.RemoteAddr
 	}
-}
-
-func (s *TftpbootServer) readHandler(filename string, rf io.ReaderFrom) error {
-	filename = cleanPath(filename)
-	rAddr := rf.(tftp.OutgoingTransfer).RemoteAddr().IP.String()
-	logger := prefixlogger.New("tftpd("+rAddr+":"+filename+"): ", s.logger)
+	logger := prefixlogger.New("httpboot("+remoteAddr+":"+filename+"): ",
+		s.logger)
 	logger.Debugln(1, "received request")
-	if err := s.readHandlerInternal(filename, rf, rAddr, logger); err != nil {
+	size, reader, release, err := s.openFile(filename, remoteAddr)
+	if err != nil {
 		logger.Println(err)
-		return err
+		if os.IsNotExist(err) {
+			http.NotFound(w, req)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
 	}
-	return nil
+	defer release()
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	startTime := time.Now()
+	nCopied, err := io.Copy(w, reader)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	timeTaken := time.Since(startTime)
+	speed := uint64(float64(nCopied) / timeTaken.Seconds())
+	logger.Printf("%d bytes sent in %s (%s/s)\n",
+		nCopied, format.Duration(timeTaken), format.FormatBytes(speed))
 }
 
-func (s *TftpbootServer) readHandlerInternal(filename string, rf io.ReaderFrom,
-	remoteAddr string, logger log.DebugLogger) error {
+func (s *TftpbootServer) imageServerClientCloser() {
+	for range s.closeClientTimer.C {
+		s.closeImageServerClient()
+	}
+}
+
+// openFile returns the size of and a reader for the file, first looking in
+// the files registered for the client and then in the image stream. The
+// returned release function must be called after reading.
+func (s *TftpbootServer) openFile(filename, remoteAddr string) (
+	int64, io.Reader, func(), error) {
 	s.lock.Lock()
 	if files, ok := s.filesForIPs[remoteAddr]; ok {
 		if data, ok := files[filename]; ok {
 			s.lock.Unlock()
-			rf.(tftp.OutgoingTransfer).SetSize(int64(len(data)))
-			return readHandler(rf, bytes.NewReader(data), logger)
+			return int64(len(data)), bytes.NewReader(data), func() {}, nil
 		}
 	}
 	imageStreamName := s.imageStreamName
 	s.lock.Unlock()
 	client := s.getImageServerClient()
-	defer s.releaseImageServerClient()
 	fs, err := s.getFileSystem(imageStreamName, client)
 	if err != nil {
-		return err
+		s.releaseImageServerClient()
+		return 0, nil, nil, err
 	}
-	defer s.getCachedFileSystem(imageStreamName) // Reset expiration timer.
 	filenameToInodeTable := fs.FilenameToInodeTable()
 	if inum, ok := filenameToInodeTable[filename]; !ok {
-		return os.ErrNotExist
+		err = os.ErrNotExist
 	} else if gInode, ok := fs.InodeTable[inum]; !ok {
-		return fmt.Errorf("inode: %d does not exist", inum)
+		err = fmt.Errorf("inode: %d does not exist", inum)
 	} else if inode, ok := gInode.(*filesystem.RegularInode); !ok {
-		return fmt.Errorf("inode is not a regular file: %d", inum)
+		err = fmt.Errorf("inode is not a regular file: %d", inum)
 	} else {
 		objSrv := objectclient.AttachObjectClient(client)
-		defer objSrv.Close()
-		if size, reader, err := objSrv.GetObject(inode.Hash); err != nil {
-			return err
-		} else {
-			defer reader.Close()
-			rf.(tftp.OutgoingTransfer).SetSize(int64(size))
-			return readHandler(rf, reader, logger)
+		size, reader, err := objSrv.GetObject(inode.Hash)
+		if err != nil {
+			objSrv.Close()
+			s.releaseImageServerClient()
+			return 0, nil, nil, err
 		}
+		return int64(size), reader, func() {
+			reader.Close()
+			objSrv.Close()
+			s.getCachedFileSystem(imageStreamName) // Reset expiration timer.
+			s.releaseImageServerClient()
+		}, nil
+	}
+	s.getCachedFileSystem(imageStreamName) // Reset expiration timer.
+	s.releaseImageServerClient()
+	return 0, nil, nil, err
+}
+
+func (s *TftpbootServer) readHandler(filename string, rf io.ReaderFrom) error {
+	filename = cleanPath(filename)
+	rAddr := rf.(tftp.OutgoingTransfer).RemoteAddr().IP.String()
+	logger := prefixlogger.New("tftpd("+rAddr+":"+filename+"): ", s.logger)
+	logger.Debugln(1, "received request")
+	if err := s.readHandlerInternal(filename, rf, rAddr, logger); err != nil {
+		logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (s *TftpbootServer) readHandlerInternal(filename string, rf io.ReaderFrom,
+	remoteAddr string, logger log.DebugLogger) error {
+	size, reader, release, err := s.openFile(filename, remoteAddr)
+	if err != nil {
+		return err
 	}
+	defer release()
+	rf.(tftp.OutgoingTransfer).SetSize(size)
+	return readHandler(rf, reader, logger)
 }
 
 func (s *TftpbootServer) registerFiles(ipAddr net.IP, files map[string][]byte) {
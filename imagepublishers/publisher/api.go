@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/mbr"
+	libtags "github.com/Cloud-Foundations/Dominator/lib/tags"
+)
+
+const (
+	ChecksumSuffix = ".sha256"
+	MetadataSuffix = ".json"
+)
+
+// Artefact describes a bootable disk image which has been published to a
+// Target. The metadata (with MetadataSuffix) are stored alongside the image
+// data and a checksum file (with ChecksumSuffix, in sha256sum(1) format) so
+// that the state of a Target may be reconstructed by listing it.
+type Artefact struct {
+	Checksum  string // SHA-256, hex encoded.
+	CreatedOn time.Time
+	ExpiresAt time.Time    `json:",omitempty"` // Zero value: never expires.
+	Format    string       // Example: "qcow2".
+	ImageName string       // Name of the image on the imageserver.
+	Name      string       // Pathname relative to the root of the Target.
+	Size      uint64       // Size of the image data in bytes.
+	Tags      libtags.Tags `json:",omitempty"`
+}
+
+type PublishOptions struct {
+	Compress         bool // QCOW2 only.
+	ExpiresIn        time.Duration
+	Format           diskimage.Format
+	MinimumFreeBytes uint64
+	RootLabel        string
+	RoundupPower     uint64
+	TableType        mbr.TableType
+	Tags             libtags.Tags
+	TmpDir           string // Default: the system temporary directory.
+}
+
+type Result struct {
+	Artefact
+	Error  string `json:",omitempty"`
+	Target string
+}
+
+// Target is the interface to a place where artefacts are published.
+type Target interface {
+	// Delete will delete the named artefact, its checksum and metadata.
+	Delete(name string) error
+	// List will return the metadata for all the artefacts in the Target.
+	List() ([]Artefact, error)
+	// Publish will copy the image data from the specified file and write the
+	// checksum and metadata for the artefact. The metadata are written last.
+	Publish(artefact Artefact, filename string) error
+	String() string
+}
+
+// ChecksumFileData returns the contents of the checksum file for the
+// artefact.
+func (artefact Artefact) ChecksumFileData() []byte {
+	return artefact.checksumFileData()
+}
+
+// Expire will delete the expired artefacts in the specified targets.
+func Expire(targets []Target, logger log.Logger) error {
+	return expire(targets, logger)
+}
+
+// List will list the artefacts in the specified targets.
+func List(targets []Target) ([]Result, error) {
+	return list(targets)
+}
+
+// Publish will fetch the specified image from the imageserver, convert it to
+// a bootable disk image and publish it to the specified targets. Targets
+// which already have an identical artefact are skipped. This requires root
+// privileges. The results for each target are returned.
+func Publish(imageServerAddress string, imageName string, targets []Target,
+	options PublishOptions, logger log.DebugLogger) ([]Result, error) {
+	return publish(imageServerAddress, imageName, targets, options, logger)
+}
@@ -0,0 +1,48 @@
+package publisher
+
+import (
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func expire(targets []Target, logger log.Logger) error {
+	currentTime := time.Now() // Need a common "now" time.
+	var firstError error
+	for _, target := range targets {
+		if err := expireTarget(target, currentTime, logger); err != nil {
+			logger.Printf("error expiring: %s: %s\n", target, err)
+			if firstError == nil {
+				firstError = err
+			}
+		}
+	}
+	return firstError
+}
+
+func expireTarget(target Target, currentTime time.Time,
+	logger log.Logger) error {
+	artefacts, err := target.List()
+	if err != nil {
+		return err
+	}
+	for _, artefact := range artefacts {
+		if !artefact.hasExpired(currentTime) {
+			continue
+		}
+		if err := target.Delete(artefact.Name); err != nil {
+			logger.Printf("error deleting: %s: %s: %s\n",
+				target, artefact.Name, err)
+		} else {
+			logger.Printf("deleted: %s: %s\n", target, artefact.Name)
+		}
+	}
+	return nil
+}
+
+func (artefact Artefact) hasExpired(currentTime time.Time) bool {
+	if artefact.ExpiresAt.IsZero() {
+		return false
+	}
+	return currentTime.After(artefact.ExpiresAt)
+}
@@ -0,0 +1,28 @@
+package publisher
+
+import (
+	"fmt"
+	"path"
+)
+
+func (artefact Artefact) checksumFileData() []byte {
+	return []byte(fmt.Sprintf("%s  %s\n",
+		artefact.Checksum, path.Base(artefact.Name)))
+}
+
+func list(targets []Target) ([]Result, error) {
+	var results []Result
+	for _, target := range targets {
+		artefacts, err := target.List()
+		if err != nil {
+			return nil, fmt.Errorf("error listing: %s: %s", target, err)
+		}
+		for _, artefact := range artefacts {
+			results = append(results, Result{
+				Artefact: artefact,
+				Target:   target.String(),
+			})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,33 @@
+package local
+
+import (
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+)
+
+// Target publishes artefacts to a local directory, which may be shared via
+// HTTP or a network file-system. It implements the publisher.Target interface.
+type Target struct {
+	directory string
+}
+
+// New creates a Target which publishes to the specified directory, which must
+// exist.
+func New(directory string) (*Target, error) {
+	return newTarget(directory)
+}
+
+func (t *Target) Delete(name string) error {
+	return t.delete(name)
+}
+
+func (t *Target) List() ([]publisher.Artefact, error) {
+	return t.list()
+}
+
+func (t *Target) Publish(artefact publisher.Artefact, filename string) error {
+	return t.publish(artefact, filename)
+}
+
+func (t *Target) String() string {
+	return t.directory
+}
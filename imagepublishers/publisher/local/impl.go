@@ -0,0 +1,104 @@
+package local
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+func checkName(name string) error {
+	if name == "" || path.IsAbs(name) || path.Clean(name) != name ||
+		name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("bad artefact name: \"%s\"", name)
+	}
+	return nil
+}
+
+func newTarget(directory string) (*Target, error) {
+	if fi, err := os.Stat(directory); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, errors.New(directory + " is not a directory")
+	}
+	return &Target{directory: filepath.Clean(directory)}, nil
+}
+
+func (t *Target) delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	pathname := t.pathname(name)
+	// Remove the metadata first so that a partial deletion is not listed.
+	if err := os.Remove(pathname + publisher.MetadataSuffix); err != nil {
+		return err
+	}
+	if err := fsutil.ForceRemove(pathname + publisher.ChecksumSuffix); err != nil {
+		return err
+	}
+	return fsutil.ForceRemove(pathname)
+}
+
+func (t *Target) list() ([]publisher.Artefact, error) {
+	var artefacts []publisher.Artefact
+	err := filepath.Walk(t.directory,
+		func(pathname string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() ||
+				!strings.HasSuffix(pathname, publisher.MetadataSuffix) {
+				return nil
+			}
+			var artefact publisher.Artefact
+			if err := json.ReadFromFile(pathname, &artefact); err != nil {
+				return nil // Not one of ours.
+			}
+			name := strings.TrimSuffix(pathname, publisher.MetadataSuffix)
+			name, err = filepath.Rel(t.directory, name)
+			if err != nil {
+				return err
+			}
+			if artefact.Name == filepath.ToSlash(name) {
+				artefacts = append(artefacts, artefact)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return artefacts, nil
+}
+
+func (t *Target) pathname(name string) string {
+	return filepath.Join(t.directory, filepath.FromSlash(name))
+}
+
+func (t *Target) publish(artefact publisher.Artefact, filename string) error {
+	if err := checkName(artefact.Name); err != nil {
+		return err
+	}
+	pathname := t.pathname(artefact.Name)
+	if err := os.MkdirAll(filepath.Dir(pathname), fsutil.DirPerms); err != nil {
+		return err
+	}
+	err := fsutil.CopyFile(pathname, filename, fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	err = fsutil.CopyToFile(pathname+publisher.ChecksumSuffix,
+		fsutil.PublicFilePerms, bytes.NewReader(artefact.ChecksumFileData()),
+		0)
+	if err != nil {
+		return err
+	}
+	return json.WriteToFile(pathname+publisher.MetadataSuffix,
+		fsutil.PublicFilePerms, "    ", artefact)
+}
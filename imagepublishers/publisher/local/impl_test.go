@@ -0,0 +1,84 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+)
+
+func TestPublishAndExpire(t *testing.T) {
+	topDir := t.TempDir()
+	target, err := New(topDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "image.qcow2")
+	if err := os.WriteFile(filename, []byte("disk image"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	artefacts := []publisher.Artefact{
+		{
+			Checksum:  "0123",
+			ExpiresAt: now.Add(-time.Minute),
+			Name:      "stream/old.qcow2",
+		},
+		{
+			Checksum:  "4567",
+			ExpiresAt: now.Add(time.Hour),
+			Name:      "stream/new.qcow2",
+		},
+		{
+			Checksum: "89ab",
+			Name:     "stream/forever.qcow2",
+		},
+	}
+	for _, artefact := range artefacts {
+		if err := target.Publish(artefact, filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(
+		filepath.Join(topDir, "stream", "old.qcow2"+publisher.ChecksumSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123  old.qcow2\n" {
+		t.Errorf("checksum data: \"%s\"", string(data))
+	}
+	if err := target.Publish(publisher.Artefact{Name: "../bad"},
+		filename); err == nil {
+		t.Error("published artefact outside of directory")
+	}
+	results, err := publisher.List([]publisher.Target{target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("listed %d artefacts, expected 3", len(results))
+	}
+	err = publisher.Expire([]publisher.Target{target}, testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, err := target.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("listed %d artefacts after expiring, expected 2", len(listed))
+	}
+	for _, artefact := range listed {
+		if artefact.Name == "stream/old.qcow2" {
+			t.Error("expired artefact not deleted")
+		}
+	}
+	_, err = os.Stat(filepath.Join(topDir, "stream", "old.qcow2"))
+	if !os.IsNotExist(err) {
+		t.Error("expired image data not deleted")
+	}
+}
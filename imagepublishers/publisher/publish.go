@@ -0,0 +1,188 @@
+package publisher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	iclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+// computeChecksum returns the hex encoded SHA-256 checksum and the size of
+// the file.
+func computeChecksum(filename string) (string, uint64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	nCopied, err := io.Copy(hasher, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), uint64(nCopied), nil
+}
+
+func convertRawImage(rawFilename, filename string,
+	options PublishOptions) error {
+	rawFile, err := os.Open(rawFilename)
+	if err != nil {
+		return err
+	}
+	defer rawFile.Close()
+	fi, err := rawFile.Stat()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
+		fsutil.PrivateFilePerms)
+	if err != nil {
+		return err
+	}
+	_, err = diskimage.Write(file, rawFile, uint64(fi.Size()), options.Format,
+		diskimage.WriteOptions{Compress: options.Compress})
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// findArtefact returns true if the target already has the named artefact.
+// Since images are immutable, an existing artefact does not need to be
+// published again.
+func findArtefact(target Target, name string) (bool, error) {
+	artefacts, err := target.List()
+	if err != nil {
+		return false, err
+	}
+	for _, artefact := range artefacts {
+		if artefact.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// makeArtefact will write the bootable disk image for the image to a file in
+// tmpDir and returns the artefact (without the name and creation time) and
+// the filename.
+func makeArtefact(imageServerAddress string, imageName string, tmpDir string,
+	options PublishOptions, logger log.DebugLogger) (Artefact, string, error) {
+	logger.Printf("Loading image: %s...\n", imageName)
+	srpcClient, err := srpc.DialHTTP("tcp", imageServerAddress, 0)
+	if err != nil {
+		return Artefact{}, "", err
+	}
+	defer srpcClient.Close()
+	img, err := iclient.GetImage(srpcClient, imageName)
+	if err != nil {
+		return Artefact{}, "", err
+	}
+	if img == nil {
+		return Artefact{}, "", errors.New("image: " + imageName + " not found")
+	}
+	if err := img.FileSystem.RebuildInodePointers(); err != nil {
+		return Artefact{}, "", err
+	}
+	objectClient := objectclient.NewObjectClient(imageServerAddress)
+	defer objectClient.Close()
+	rawFilename := filepath.Join(tmpDir, "image.raw")
+	err = util.WriteRawWithOptions(img.FileSystem, objectClient, rawFilename,
+		fsutil.PrivateFilePerms, options.TableType,
+		util.WriteRawOptions{
+			InitialImageName:  imageName,
+			InstallBootloader: true,
+			MinimumFreeBytes:  options.MinimumFreeBytes,
+			RootLabel:         options.RootLabel,
+			RoundupPower:      options.RoundupPower,
+			WriteFstab:        true,
+		},
+		logger)
+	if err != nil {
+		return Artefact{}, "", err
+	}
+	filename := rawFilename
+	if options.Format != diskimage.FormatRaw {
+		filename = filepath.Join(tmpDir, "image"+options.Format.Extension())
+		if err := convertRawImage(rawFilename, filename, options); err != nil {
+			return Artefact{}, "", err
+		}
+		os.Remove(rawFilename)
+	}
+	checksum, size, err := computeChecksum(filename)
+	if err != nil {
+		return Artefact{}, "", err
+	}
+	artefact := Artefact{
+		Checksum:  checksum,
+		Format:    options.Format.String(),
+		ImageName: imageName,
+		Size:      size,
+		Tags:      options.Tags,
+	}
+	return artefact, filename, nil
+}
+
+func publish(imageServerAddress string, imageName string, targets []Target,
+	options PublishOptions, logger log.DebugLogger) ([]Result, error) {
+	if len(targets) < 1 {
+		return nil, errors.New("no targets specified")
+	}
+	name := path.Clean(imageName) + options.Format.Extension()
+	results := make([]Result, len(targets))
+	var targetsToPublish []int
+	for index, target := range targets {
+		results[index].Target = target.String()
+		if found, err := findArtefact(target, name); err != nil {
+			results[index].Error = err.Error()
+		} else if found {
+			logger.Printf("%s: %s already published\n", target, name)
+		} else {
+			targetsToPublish = append(targetsToPublish, index)
+		}
+	}
+	if len(targetsToPublish) < 1 {
+		return results, nil
+	}
+	tmpDir, err := os.MkdirTemp(options.TmpDir, "image-publisher")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	artefact, filename, err := makeArtefact(imageServerAddress, imageName,
+		tmpDir, options, logger)
+	if err != nil {
+		return nil, err
+	}
+	artefact.CreatedOn = time.Now().UTC()
+	artefact.Name = name
+	if options.ExpiresIn > 0 {
+		artefact.ExpiresAt = artefact.CreatedOn.Add(options.ExpiresIn)
+	}
+	for _, index := range targetsToPublish {
+		target := targets[index]
+		results[index].Artefact = artefact
+		startTime := time.Now()
+		if err := target.Publish(artefact, filename); err != nil {
+			logger.Printf("error publishing to: %s: %s\n", target, err)
+			results[index].Error = err.Error()
+		} else {
+			logger.Printf("Published: %s to: %s in %s\n",
+				artefact.Name, target, time.Since(startTime))
+		}
+	}
+	return results, nil
+}
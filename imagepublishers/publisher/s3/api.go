@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	aws_s3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+type Params struct {
+	Bucket   string
+	Endpoint string // Optional. Used for S3-compatible object stores.
+	Prefix   string // Optional. Prepended to the artefact names.
+	Profile  string // Optional. The name of the AWS profile for credentials.
+	Region   string // Default: "us-east-1".
+}
+
+// Target publishes artefacts to an S3 bucket or an S3-compatible object store
+// such as MinIO. It implements the publisher.Target interface.
+type Target struct {
+	bucket   string
+	client   *aws_s3.S3
+	prefix   string
+	uploader *s3manager.Uploader
+}
+
+// New creates a Target which publishes to the specified bucket. If an endpoint
+// is specified, path-style addressing is used.
+func New(params Params) (*Target, error) {
+	return newTarget(params)
+}
+
+func (t *Target) Delete(name string) error {
+	return t.delete(name)
+}
+
+func (t *Target) List() ([]publisher.Artefact, error) {
+	return t.list()
+}
+
+func (t *Target) Publish(artefact publisher.Artefact, filename string) error {
+	return t.publish(artefact, filename)
+}
+
+func (t *Target) String() string {
+	return "s3://" + t.bucket + "/" + t.prefix
+}
@@ -0,0 +1,154 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	aws_s3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+func newTarget(params Params) (*Target, error) {
+	if params.Bucket == "" {
+		return nil, errors.New("no bucket specified")
+	}
+	config := aws.Config{Region: aws.String("us-east-1")}
+	if params.Region != "" {
+		config.Region = aws.String(params.Region)
+	}
+	if params.Endpoint != "" {
+		config.Endpoint = aws.String(params.Endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	awsSession, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		Profile:           params.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.Trim(params.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &Target{
+		bucket:   params.Bucket,
+		client:   aws_s3.New(awsSession),
+		prefix:   prefix,
+		uploader: s3manager.NewUploader(awsSession),
+	}, nil
+}
+
+func (t *Target) delete(name string) error {
+	key := t.prefix + name
+	// Remove the metadata first so that a partial deletion is not listed.
+	for _, suffix := range []string{publisher.MetadataSuffix,
+		publisher.ChecksumSuffix, ""} {
+		_, err := t.client.DeleteObject(&aws_s3.DeleteObjectInput{
+			Bucket: aws.String(t.bucket),
+			Key:    aws.String(key + suffix),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Target) list() ([]publisher.Artefact, error) {
+	var keys []string
+	err := t.client.ListObjectsV2Pages(&aws_s3.ListObjectsV2Input{
+		Bucket: aws.String(t.bucket),
+		Prefix: aws.String(t.prefix),
+	},
+		func(page *aws_s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, object := range page.Contents {
+				key := aws.StringValue(object.Key)
+				if strings.HasSuffix(key, publisher.MetadataSuffix) {
+					keys = append(keys, key)
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	var artefacts []publisher.Artefact
+	for _, key := range keys {
+		artefact, err := t.readMetadata(key)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(key[len(t.prefix):],
+			publisher.MetadataSuffix)
+		if artefact.Name == name {
+			artefacts = append(artefacts, artefact)
+		}
+	}
+	return artefacts, nil
+}
+
+func (t *Target) publish(artefact publisher.Artefact, filename string) error {
+	if path.IsAbs(artefact.Name) || path.Clean(artefact.Name) != artefact.Name {
+		return errors.New("bad artefact name: " + artefact.Name)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	key := t.prefix + artefact.Name
+	_, err = t.uploader.Upload(&s3manager.UploadInput{
+		Body:   file,
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	err = t.putObject(key+publisher.ChecksumSuffix, "text/plain",
+		artefact.ChecksumFileData())
+	if err != nil {
+		return err
+	}
+	metadata, err := json.MarshalIndent(artefact, "", "    ")
+	if err != nil {
+		return err
+	}
+	return t.putObject(key+publisher.MetadataSuffix, "application/json",
+		metadata)
+}
+
+func (t *Target) putObject(key, contentType string, data []byte) error {
+	_, err := t.client.PutObject(&aws_s3.PutObjectInput{
+		Body:        bytes.NewReader(data),
+		Bucket:      aws.String(t.bucket),
+		ContentType: aws.String(contentType),
+		Key:         aws.String(key),
+	})
+	return err
+}
+
+func (t *Target) readMetadata(key string) (publisher.Artefact, error) {
+	output, err := t.client.GetObject(&aws_s3.GetObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return publisher.Artefact{}, err
+	}
+	defer output.Body.Close()
+	var artefact publisher.Artefact
+	if err := json.NewDecoder(output.Body).Decode(&artefact); err != nil {
+		return publisher.Artefact{}, nil // Not one of ours.
+	}
+	return artefact, nil
+}
@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/imagepublishers/publisher"
+)
+
+type listBucketResult struct {
+	XMLName     xml.Name `xml:"ListBucketResult"`
+	Contents    []listBucketContents
+	IsTruncated bool
+	KeyCount    int
+	Name        string
+	Prefix      string
+}
+
+type listBucketContents struct {
+	Key  string
+	Size int
+}
+
+// objectStore is a minimal, in-memory stand-in for an S3-compatible object
+// store which supports path-style addressing for a single bucket.
+type objectStore struct {
+	bucket  string
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (store *objectStore) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	bucketPrefix := "/" + store.bucket
+	if !strings.HasPrefix(req.URL.Path, bucketPrefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(req.URL.Path[len(bucketPrefix):], "/")
+	switch req.Method {
+	case http.MethodDelete:
+		delete(store.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		if key == "" {
+			store.list(w, req.URL.Query().Get("prefix"))
+			return
+		}
+		if data, ok := store.objects[key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.Write(data)
+		}
+	case http.MethodPut:
+		if data, err := io.ReadAll(req.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			store.objects[key] = data
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (store *objectStore) list(w http.ResponseWriter, prefix string) {
+	result := listBucketResult{Name: store.bucket, Prefix: prefix}
+	for key, data := range store.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents,
+				listBucketContents{Key: key, Size: len(data)})
+		}
+	}
+	sort.Slice(result.Contents, func(left, right int) bool {
+		return result.Contents[left].Key < result.Contents[right].Key
+	})
+	result.KeyCount = len(result.Contents)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func TestPublishListDelete(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	store := &objectStore{bucket: "images", objects: make(map[string][]byte)}
+	server := httptest.NewServer(store)
+	defer server.Close()
+	target, err := New(Params{
+		Bucket:   "images",
+		Endpoint: server.URL,
+		Prefix:   "/published/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "image.raw")
+	if err := os.WriteFile(filename, []byte("disk image"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	artefact := publisher.Artefact{
+		Checksum:  "0123",
+		Format:    "raw",
+		ImageName: "stream/leaf",
+		Name:      "stream/leaf.raw",
+		Size:      10,
+	}
+	if err := target.Publish(artefact, filename); err != nil {
+		t.Fatal(err)
+	}
+	if data := string(store.objects["published/stream/leaf.raw"]); data != "disk image" {
+		t.Errorf("image data: \"%s\"", data)
+	}
+	checksumData := string(store.objects["published/stream/leaf.raw.sha256"])
+	if checksumData != "0123  leaf.raw\n" {
+		t.Errorf("checksum data: \"%s\"", checksumData)
+	}
+	artefacts, err := target.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artefacts) != 1 || artefacts[0].Name != artefact.Name {
+		t.Fatalf("listed: %v", artefacts)
+	}
+	if err := target.Delete(artefact.Name); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.objects) > 0 {
+		t.Errorf("objects remaining after delete: %d", len(store.objects))
+	}
+}
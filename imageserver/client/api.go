@@ -44,6 +44,20 @@ func DeleteUnreferencedObjects(client srpc.ClientI, percentage uint8,
 	return deleteUnreferencedObjects(client, percentage, bytes)
 }
 
+// FindImagesWithObject returns the images which contain the specified object,
+// along with the pathnames of the files in each image with that object.
+func FindImagesWithObject(client srpc.ClientI, hashVal hash.Hash) (
+	[]proto.ImageWithPaths, error) {
+	return findImagesWithObject(client, hashVal)
+}
+
+// FindImagesWithPath returns the names of the images which contain the
+// specified pathname.
+func FindImagesWithPath(client srpc.ClientI, pathname string) (
+	[]string, error) {
+	return findImagesWithPath(client, pathname)
+}
+
 func FindLatestImage(client srpc.ClientI, dirname string,
 	ignoreExpiring bool) (string, error) {
 	return findLatestImage(client, proto.FindLatestImageRequest{
@@ -79,6 +93,21 @@ func GetReplicationMaster(client srpc.ClientI) (string, error) {
 	return getReplicationMaster(client)
 }
 
+// GetReplicationStatus returns the replication status of the imageserver,
+// including the replicas which are connected to it.
+func GetReplicationStatus(client srpc.ClientI) (
+	proto.ReplicationStatus, error) {
+	return getReplicationStatus(client)
+}
+
+// GetRetentionReport returns a report of the images which would be deleted by
+// the retention policy for the specified directory, or for all directories if
+// dirname is empty.
+func GetRetentionReport(client srpc.ClientI, dirname string) (
+	[]proto.DirectoryRetention, error) {
+	return getRetentionReport(client, dirname)
+}
+
 func GetImageWithTimeout(client srpc.ClientI, name string,
 	timeout time.Duration) (*image.Image, error) {
 	return getImage(client, name, timeout)
@@ -110,8 +139,22 @@ func MakeDirectoryAll(client srpc.ClientI, dirname string) error {
 	return makeDirectory(client, dirname, true)
 }
 
+// PromoteToMaster will stop replication and make the imageserver a master.
+// Unless force is true, the imageserver must have finished replicating and
+// none of its replication masters may be active.
+func PromoteToMaster(client srpc.ClientI, force bool) error {
+	return promoteToMaster(client, force)
+}
+
 func RestoreImageFromArchive(client srpc.ClientI,
 	request proto.RestoreImageFromArchiveRequest) (
 	proto.RestoreImageFromArchiveResponse, error) {
 	return restoreImageFromArchive(client, request)
 }
+
+// SetDirectoryRetention sets the retention policy for the specified directory.
+// A zero policy disables retention for the directory.
+func SetDirectoryRetention(client srpc.ClientI, dirname string,
+	policy image.RetentionPolicy) error {
+	return setDirectoryRetention(client, dirname, policy)
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func findImagesWithObject(client srpc.ClientI, hashVal hash.Hash) (
+	[]imageserver.ImageWithPaths, error) {
+	request := imageserver.FindImagesWithObjectRequest{Hash: hashVal}
+	var reply imageserver.FindImagesWithObjectResponse
+	err := client.RequestReply("ImageServer.FindImagesWithObject", request,
+		&reply)
+	if err == nil {
+		err = errors.New(reply.Error)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply.Images, nil
+}
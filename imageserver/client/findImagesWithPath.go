@@ -0,0 +1,22 @@
+package client
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func findImagesWithPath(client srpc.ClientI, pathname string) (
+	[]string, error) {
+	request := imageserver.FindImagesWithPathRequest{Pathname: pathname}
+	var reply imageserver.FindImagesWithPathResponse
+	err := client.RequestReply("ImageServer.FindImagesWithPath", request,
+		&reply)
+	if err == nil {
+		err = errors.New(reply.Error)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply.ImageNames, nil
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func getReplicationStatus(client srpc.ClientI) (
+	imageserver.ReplicationStatus, error) {
+	request := imageserver.GetReplicationStatusRequest{}
+	var reply imageserver.GetReplicationStatusResponse
+	err := client.RequestReply("ImageServer.GetReplicationStatus", request,
+		&reply)
+	if err == nil {
+		err = errors.New(reply.Error)
+	}
+	if err != nil {
+		return imageserver.ReplicationStatus{}, err
+	}
+	return reply.ReplicationStatus, nil
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func getRetentionReport(client srpc.ClientI, dirname string) (
+	[]imageserver.DirectoryRetention, error) {
+	request := imageserver.GetRetentionReportRequest{DirectoryName: dirname}
+	var reply imageserver.GetRetentionReportResponse
+	err := client.RequestReply("ImageServer.GetRetentionReport", request,
+		&reply)
+	if err == nil {
+		err = errors.New(reply.Error)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply.Directories, nil
+}
@@ -0,0 +1,17 @@
+package client
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func promoteToMaster(client srpc.ClientI, force bool) error {
+	request := imageserver.PromoteToMasterRequest{Force: force}
+	var reply imageserver.PromoteToMasterResponse
+	err := client.RequestReply("ImageServer.PromoteToMaster", request, &reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
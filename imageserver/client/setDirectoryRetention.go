@@ -0,0 +1,23 @@
+package client
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func setDirectoryRetention(client srpc.ClientI, dirname string,
+	policy image.RetentionPolicy) error {
+	request := imageserver.SetDirectoryRetentionRequest{
+		DirectoryName: dirname,
+		Policy:        policy,
+	}
+	var reply imageserver.SetDirectoryRetentionResponse
+	err := client.RequestReply("ImageServer.SetDirectoryRetention", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
@@ -1,6 +1,7 @@
 package httpd
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"net"
@@ -15,11 +16,16 @@ type HtmlWriter interface {
 	WriteHtml(writer io.Writer)
 }
 
-var htmlWriters []HtmlWriter
+var (
+	allowImageFileAccess = flag.Bool("allowImageFileAccess", false,
+		"If true, allow file contents to be downloaded, viewed and compared using the unauthenticated status port")
+	htmlWriters []HtmlWriter
+)
 
 type state struct {
-	imageDataBase *scanner.ImageDataBase
-	objectServer  *filesystem.ObjectServer
+	allowFileAccess bool
+	imageDataBase   *scanner.ImageDataBase
+	objectServer    *filesystem.ObjectServer
 }
 
 func StartServer(portNum uint, imdb *scanner.ImageDataBase,
@@ -28,8 +34,15 @@ func StartServer(portNum uint, imdb *scanner.ImageDataBase,
 	if err != nil {
 		return err
 	}
-	myState := state{imageDataBase: imdb, objectServer: objSrv}
+	myState := state{
+		allowFileAccess: *allowImageFileAccess,
+		imageDataBase:   imdb,
+		objectServer:    objSrv,
+	}
 	html.HandleFunc("/", statusHandler)
+	html.HandleFunc("/browseImage", myState.browseImageHandler)
+	html.HandleFunc("/diffImages", myState.diffImagesHandler)
+	html.HandleFunc("/getImageFile", myState.getImageFileHandler)
 	html.HandleFunc("/listBuildLog", myState.listBuildLogHandler)
 	html.HandleFunc("/listComputedInodes", myState.listComputedInodesHandler)
 	html.HandleFunc("/listDirectories", myState.listDirectoriesHandler)
@@ -40,6 +53,7 @@ func StartServer(portNum uint, imdb *scanner.ImageDataBase,
 	html.HandleFunc("/listReleaseNotes", myState.listReleaseNotesHandler)
 	html.HandleFunc("/listTriggers", myState.listTriggersHandler)
 	html.HandleFunc("/showImage", myState.showImageHandler)
+	html.HandleFunc("/viewImageFile", myState.viewImageFileHandler)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
@@ -0,0 +1,265 @@
+package httpd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+)
+
+// inodeInfo describes a named inode in an image.
+type inodeInfo struct {
+	inode       filesystem.GenericInode
+	inodeNumber uint64
+	name        string // Full pathname.
+}
+
+// describeInode returns the mode, owner, size and mtime columns for an inode.
+func describeInode(inode filesystem.GenericInode) []string {
+	var mode filesystem.FileMode
+	var mtimeSeconds int64
+	var mtimeNanoSeconds int32
+	var size string
+	switch inode := inode.(type) {
+	case *filesystem.RegularInode:
+		mode = inode.Mode
+		mtimeSeconds = inode.MtimeSeconds
+		mtimeNanoSeconds = inode.MtimeNanoSeconds
+		size = format.FormatBytes(inode.Size)
+	case *filesystem.ComputedRegularInode:
+		mode = inode.Mode
+	case *filesystem.SymlinkInode:
+		mode = filesystem.FileMode(wsyscall.S_IFLNK | 0777)
+		size = format.FormatBytes(uint64(len(inode.Symlink)))
+	case *filesystem.SpecialInode:
+		mode = inode.Mode
+		mtimeSeconds = inode.MtimeSeconds
+		mtimeNanoSeconds = inode.MtimeNanoSeconds
+		size = fmt.Sprintf("%d,%d", inode.Rdev>>8, inode.Rdev&0xff)
+	case *filesystem.DirectoryInode:
+		mode = inode.Mode
+	}
+	var mtime string
+	if mtimeSeconds != 0 || mtimeNanoSeconds != 0 {
+		mtime = time.Unix(mtimeSeconds, int64(mtimeNanoSeconds)).In(
+			time.Local).Format(timeFormat)
+	}
+	return []string{
+		mode.String(),
+		fmt.Sprintf("%d:%d", inode.GetUid(), inode.GetGid()),
+		size,
+		mtime,
+	}
+}
+
+// lookupPath returns the inode for the specified absolute pathname.
+func lookupPath(fs *filesystem.FileSystem, pathname string) (
+	*inodeInfo, error) {
+	if !path.IsAbs(pathname) {
+		return nil, errors.New("pathname must be absolute: " + pathname)
+	}
+	pathname = path.Clean(pathname)
+	info := &inodeInfo{inode: &fs.DirectoryInode, name: pathname}
+	if pathname == "/" {
+		return info, nil
+	}
+	for _, component := range strings.Split(pathname[1:], "/") {
+		directory, ok := info.inode.(*filesystem.DirectoryInode)
+		if !ok {
+			return nil, errors.New("not a directory: " + path.Dir(pathname))
+		}
+		var found bool
+		for _, dirent := range directory.EntryList {
+			if dirent.Name == component {
+				info.inode = dirent.Inode()
+				info.inodeNumber = dirent.InodeNumber
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("no such file or directory: " + pathname)
+		}
+	}
+	return info, nil
+}
+
+func makeBrowseURL(imageName, pathname string) string {
+	return "browseImage?" + url.Values{
+		"image": {imageName},
+		"path":  {pathname},
+	}.Encode()
+}
+
+func writeBreadcrumbs(writer io.Writer, imageName, pathname string) {
+	fmt.Fprintf(writer, "<a href=\"showImage?%s\">%s</a>:",
+		imageName, template.HTMLEscapeString(imageName))
+	fmt.Fprintf(writer, "<a href=\"%s\">/</a>",
+		makeBrowseURL(imageName, "/"))
+	if pathname == "/" {
+		return
+	}
+	var partial string
+	for index, component := range strings.Split(pathname[1:], "/") {
+		partial += "/" + component
+		if index > 0 {
+			fmt.Fprint(writer, "/")
+		}
+		fmt.Fprintf(writer, "<a href=\"%s\">%s</a>",
+			makeBrowseURL(imageName, partial),
+			template.HTMLEscapeString(component))
+	}
+}
+
+func writeDiffForm(writer io.Writer, imageName, pathname string) {
+	fmt.Fprintln(writer, `<form action="diffImages" method="get">`)
+	fmt.Fprintf(writer,
+		"<input type=\"hidden\" name=\"left\" value=\"%s\">\n",
+		template.HTMLEscapeString(imageName))
+	fmt.Fprintf(writer,
+		"<input type=\"hidden\" name=\"path\" value=\"%s\">\n",
+		template.HTMLEscapeString(pathname))
+	fmt.Fprintln(writer,
+		`Compare with image: <input type="text" name="right" size="60">`)
+	fmt.Fprintln(writer, `<input type="submit" value="Diff">`)
+	fmt.Fprintln(writer, "</form>")
+}
+
+func (s state) browseImageHandler(w http.ResponseWriter, req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	query := req.URL.Query()
+	imageName := query.Get("image")
+	pathname := query.Get("path")
+	if pathname == "" {
+		pathname = "/"
+	}
+	fmt.Fprintf(writer, "<title>image %s:%s</title>\n",
+		template.HTMLEscapeString(imageName),
+		template.HTMLEscapeString(pathname))
+	fmt.Fprintln(writer, `<style>
+                          table, th, td {
+                          border-collapse: collapse;
+                          }
+                          </style>`)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	defer fmt.Fprintln(writer, "</body>")
+	img := s.imageDataBase.GetImage(imageName)
+	if img == nil {
+		fmt.Fprintf(writer, "Image: %s UNKNOWN!\n",
+			template.HTMLEscapeString(imageName))
+		return
+	}
+	info, err := lookupPath(img.FileSystem, pathname)
+	if err != nil {
+		fmt.Fprintln(writer, template.HTMLEscapeString(err.Error()))
+		return
+	}
+	writeBreadcrumbs(writer, imageName, info.name)
+	fmt.Fprintln(writer, "</h3>")
+	if directory, ok := info.inode.(*filesystem.DirectoryInode); ok {
+		s.writeDirectoryListing(writer, img.FileSystem, imageName, info.name,
+			directory)
+	} else {
+		s.writeFileInfo(writer, img.FileSystem, imageName, info)
+	}
+	writeDiffForm(writer, imageName, info.name)
+}
+
+func (s state) writeDirectoryListing(writer io.Writer,
+	fs *filesystem.FileSystem, imageName, dirname string,
+	directory *filesystem.DirectoryInode) {
+	numLinksTable := fs.BuildNumLinksTable()
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true, "Mode", "Owner", "Size",
+		"Modified", "Name", "Notes")
+	for _, dirent := range directory.EntryList {
+		pathname := path.Join(dirname, dirent.Name)
+		inode := dirent.Inode()
+		columns := describeInode(inode)
+		name := fmt.Sprintf("<a href=\"%s\">%s</a>",
+			makeBrowseURL(imageName, pathname),
+			template.HTMLEscapeString(dirent.Name))
+		var notes []string
+		switch inode := inode.(type) {
+		case *filesystem.ComputedRegularInode:
+			notes = append(notes, "computed from: "+
+				template.HTMLEscapeString(inode.Source))
+		case *filesystem.DirectoryInode:
+			name += "/"
+		case *filesystem.SymlinkInode:
+			notes = append(notes,
+				"-&gt; "+template.HTMLEscapeString(inode.Symlink))
+		}
+		if _, ok := inode.(*filesystem.DirectoryInode); !ok {
+			if numLinks := numLinksTable[dirent.InodeNumber]; numLinks > 1 {
+				notes = append(notes,
+					fmt.Sprintf("hardlink group: inode %d (%d links)",
+						dirent.InodeNumber, numLinks))
+			}
+		}
+		columns = append(columns, name, strings.Join(notes, ", "))
+		tw.WriteRow("", "", columns...)
+	}
+	tw.Close()
+}
+
+func (s state) writeFileInfo(writer io.Writer, fs *filesystem.FileSystem,
+	imageName string, info *inodeInfo) {
+	columns := describeInode(info.inode)
+	fmt.Fprintln(writer, `<table border="1">`)
+	tw, _ := html.NewTableWriter(writer, true, "Field", "Value")
+	tw.WriteRow("", "", "Mode", columns[0])
+	tw.WriteRow("", "", "Owner (uid:gid)", columns[1])
+	tw.WriteRow("", "", "Size", columns[2])
+	tw.WriteRow("", "", "Modified", columns[3])
+	tw.WriteRow("", "", "Inode number", fmt.Sprintf("%d", info.inodeNumber))
+	switch inode := info.inode.(type) {
+	case *filesystem.ComputedRegularInode:
+		tw.WriteRow("", "", "Computed from",
+			template.HTMLEscapeString(inode.Source))
+	case *filesystem.RegularInode:
+		tw.WriteRow("", "", "Hash", fmt.Sprintf("%x", inode.Hash))
+	case *filesystem.SymlinkInode:
+		tw.WriteRow("", "", "Target",
+			template.HTMLEscapeString(inode.Symlink))
+	}
+	if names := fs.InodeToFilenamesTable()[info.inodeNumber]; len(names) > 1 {
+		var links []string
+		for _, name := range names {
+			if name == info.name {
+				continue
+			}
+			links = append(links, fmt.Sprintf("<a href=\"%s\">%s</a>",
+				makeBrowseURL(imageName, name),
+				template.HTMLEscapeString(name)))
+		}
+		tw.WriteRow("", "", "Hardlinks", strings.Join(links, "<br>"))
+	}
+	tw.Close()
+	if inode, ok := info.inode.(*filesystem.RegularInode); ok &&
+		s.allowFileAccess {
+		query := url.Values{
+			"image": {imageName},
+			"path":  {info.name},
+		}.Encode()
+		fmt.Fprintf(writer,
+			"<p><a href=\"getImageFile?%s\">Download</a>\n", query)
+		if inode.Size > 0 {
+			fmt.Fprintf(writer, " <a href=\"viewImageFile?%s\">View</a>\n",
+				query)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package httpd
+
+import (
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+)
+
+func makeDirent(name string, inodeNumber uint64,
+	inode filesystem.GenericInode) *filesystem.DirectoryEntry {
+	dirent := &filesystem.DirectoryEntry{Name: name, InodeNumber: inodeNumber}
+	dirent.SetInode(inode)
+	return dirent
+}
+
+func makeTestFileSystem() *filesystem.FileSystem {
+	fs := &filesystem.FileSystem{}
+	etc := &filesystem.DirectoryInode{}
+	etc.EntryList = append(etc.EntryList,
+		makeDirent("hosts", 3, &filesystem.RegularInode{Size: 10}))
+	fs.EntryList = append(fs.EntryList,
+		makeDirent("etc", 2, etc),
+		makeDirent("file", 4, &filesystem.RegularInode{Size: 5}))
+	return fs
+}
+
+func TestLookupPath(t *testing.T) {
+	fs := makeTestFileSystem()
+	tests := []struct {
+		pathname    string
+		name        string
+		inodeNumber uint64
+	}{
+		{"/", "/", 0},
+		{"/etc", "/etc", 2},
+		{"/etc/", "/etc", 2},
+		{"/etc/hosts", "/etc/hosts", 3},
+		{"//etc/../etc/./hosts", "/etc/hosts", 3},
+		{"/file", "/file", 4},
+	}
+	for _, test := range tests {
+		info, err := lookupPath(fs, test.pathname)
+		if err != nil {
+			t.Errorf("%s: %s", test.pathname, err)
+			continue
+		}
+		if info.name != test.name {
+			t.Errorf("%s: expected name: %s, got: %s",
+				test.pathname, test.name, info.name)
+		}
+		if info.inodeNumber != test.inodeNumber {
+			t.Errorf("%s: expected inode: %d, got: %d",
+				test.pathname, test.inodeNumber, info.inodeNumber)
+		}
+	}
+	if info, _ := lookupPath(fs, "/"); info.inode != &fs.DirectoryInode {
+		t.Error("/: not the root directory")
+	}
+	for _, pathname := range []string{
+		"",
+		"etc",
+		"/missing",
+		"/etc/missing",
+		"/file/child",
+	} {
+		if _, err := lookupPath(fs, pathname); err == nil {
+			t.Errorf("%s: no error", pathname)
+		}
+	}
+}
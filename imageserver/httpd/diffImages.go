@@ -0,0 +1,224 @@
+package httpd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Cloud-Foundations/Dominator/lib/diff"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+)
+
+const (
+	addedBackground   = "#e6ffe6"
+	changedBackground = "#fff5e0"
+	removedBackground = "#ffe6e6"
+)
+
+func makeDiffURL(leftName, rightName, pathname string) string {
+	return "diffImages?" + url.Values{
+		"left":  {leftName},
+		"path":  {pathname},
+		"right": {rightName},
+	}.Encode()
+}
+
+func splitLines(data []byte) []string {
+	if len(data) < 1 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func (s state) diffImagesHandler(w http.ResponseWriter, req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	query := req.URL.Query()
+	leftName := query.Get("left")
+	rightName := query.Get("right")
+	pathname := query.Get("path")
+	if pathname == "" {
+		pathname = "/"
+	}
+	pathname = path.Clean(pathname)
+	fmt.Fprintf(writer, "<title>diff %s %s:%s</title>\n",
+		template.HTMLEscapeString(leftName),
+		template.HTMLEscapeString(rightName),
+		template.HTMLEscapeString(pathname))
+	fmt.Fprintln(writer, `<style>
+                          table, th, td {
+                          border-collapse: collapse;
+                          }
+                          </style>`)
+	fmt.Fprintln(writer, "<body>")
+	defer fmt.Fprintln(writer, "</body>")
+	fmt.Fprintln(writer, "<h3>")
+	fmt.Fprintf(writer, "Comparing %s in ", template.HTMLEscapeString(pathname))
+	fmt.Fprintf(writer, "<a href=\"%s\">%s</a> and ",
+		makeBrowseURL(leftName, pathname), template.HTMLEscapeString(leftName))
+	fmt.Fprintf(writer, "<a href=\"%s\">%s</a>\n",
+		makeBrowseURL(rightName, pathname),
+		template.HTMLEscapeString(rightName))
+	fmt.Fprintln(writer, "</h3>")
+	var infos [2]*inodeInfo
+	for index, imageName := range []string{leftName, rightName} {
+		img := s.imageDataBase.GetImage(imageName)
+		if img == nil {
+			fmt.Fprintf(writer, "Image: %s UNKNOWN!\n",
+				template.HTMLEscapeString(imageName))
+			return
+		}
+		if info, err := lookupPath(img.FileSystem, pathname); err != nil {
+			fmt.Fprintf(writer, "%s: %s<br>\n",
+				template.HTMLEscapeString(imageName),
+				template.HTMLEscapeString(err.Error()))
+		} else {
+			infos[index] = info
+		}
+	}
+	if infos[0] == nil || infos[1] == nil {
+		return
+	}
+	buffer := &bytes.Buffer{}
+	sameType, sameMetadata, sameData := filesystem.CompareInodes(
+		infos[0].inode, infos[1].inode, buffer)
+	if sameType && sameMetadata && sameData {
+		fmt.Fprintln(writer, "Identical<br>")
+	} else if buffer.Len() > 0 {
+		fmt.Fprintln(writer, "<pre>")
+		template.HTMLEscape(writer, buffer.Bytes())
+		fmt.Fprintln(writer, "</pre>")
+	}
+	if !sameType {
+		return
+	}
+	switch left := infos[0].inode.(type) {
+	case *filesystem.DirectoryInode:
+		right := infos[1].inode.(*filesystem.DirectoryInode)
+		writeDirectoryDiff(writer, leftName, rightName, pathname, left, right)
+	case *filesystem.RegularInode:
+		if !sameData {
+			s.writeFileDiff(writer, left,
+				infos[1].inode.(*filesystem.RegularInode))
+		}
+	}
+}
+
+func writeDirectoryDiff(writer io.Writer, leftName, rightName, dirname string,
+	left, right *filesystem.DirectoryInode) {
+	entries := make(map[string][2]*filesystem.DirectoryEntry)
+	for _, dirent := range left.EntryList {
+		entries[dirent.Name] = [2]*filesystem.DirectoryEntry{dirent, nil}
+	}
+	for _, dirent := range right.EntryList {
+		pair := entries[dirent.Name]
+		pair[1] = dirent
+		entries[dirent.Name] = pair
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true, "Name", "Left", "Right",
+		"Status")
+	for _, name := range names {
+		pair := entries[name]
+		link := fmt.Sprintf("<a href=\"%s\">%s</a>",
+			makeDiffURL(leftName, rightName, path.Join(dirname, name)),
+			template.HTMLEscapeString(name))
+		var leftText, rightText, background, status string
+		if pair[0] != nil {
+			leftText = strings.Join(describeInode(pair[0].Inode()), " ")
+		}
+		if pair[1] != nil {
+			rightText = strings.Join(describeInode(pair[1].Inode()), " ")
+		}
+		if pair[1] == nil {
+			background = removedBackground
+			status = "only in left"
+		} else if pair[0] == nil {
+			background = addedBackground
+			status = "only in right"
+		} else {
+			sameType, sameMetadata, sameData := filesystem.CompareInodes(
+				pair[0].Inode(), pair[1].Inode(), nil)
+			if !sameType {
+				background = changedBackground
+				status = "type differs"
+			} else if !sameData {
+				background = changedBackground
+				status = "data differs"
+			} else if !sameMetadata {
+				background = changedBackground
+				status = "metadata differs"
+			}
+		}
+		tw.WriteRow("", background, link, leftText, rightText, status)
+	}
+	tw.Close()
+}
+
+func (s state) writeFileDiff(writer io.Writer,
+	left, right *filesystem.RegularInode) {
+	if !s.allowFileAccess {
+		fmt.Fprintln(writer, "File contents differ<br>")
+		return
+	}
+	leftData, leftTruncated, err := s.readFile(left, maxViewSize)
+	if err != nil {
+		fmt.Fprintln(writer, template.HTMLEscapeString(err.Error()))
+		return
+	}
+	rightData, rightTruncated, err := s.readFile(right, maxViewSize)
+	if err != nil {
+		fmt.Fprintln(writer, template.HTMLEscapeString(err.Error()))
+		return
+	}
+	if leftTruncated || rightTruncated {
+		fmt.Fprintln(writer, "File(s) too large to compare<br>")
+		return
+	}
+	if !isText(leftData) || !isText(rightData) {
+		fmt.Fprintln(writer, "Binary files differ<br>")
+		return
+	}
+	leftLines := splitLines(leftData)
+	rightLines := splitLines(rightData)
+	fmt.Fprintln(writer,
+		`<table border="1" style="width:100%; font-family: monospace">`)
+	tw, _ := html.NewTableWriter(writer, true, "", "Left", "", "Right")
+	for _, line := range diff.Lines(leftLines, rightLines) {
+		var leftNumber, leftText, rightNumber, rightText, background string
+		if line.Left >= 0 {
+			leftNumber = fmt.Sprintf("%d", line.Left+1)
+			leftText = formatDiffLine(leftLines[line.Left])
+		}
+		if line.Right >= 0 {
+			rightNumber = fmt.Sprintf("%d", line.Right+1)
+			rightText = formatDiffLine(rightLines[line.Right])
+		}
+		if line.Left < 0 {
+			background = addedBackground
+		} else if line.Right < 0 {
+			background = removedBackground
+		}
+		tw.WriteRow("", background, leftNumber, leftText, rightNumber,
+			rightText)
+	}
+	tw.Close()
+}
+
+func formatDiffLine(line string) string {
+	return "<pre style=\"margin: 0\">" +
+		template.HTMLEscapeString(line) + "</pre>"
+}
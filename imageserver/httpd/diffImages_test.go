@@ -0,0 +1,17 @@
+package httpd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	if lines := splitLines(nil); lines != nil {
+		t.Errorf("expected no lines, got: %q", lines)
+	}
+	expected := []string{"a", "", "b"}
+	if lines := splitLines([]byte("a\n\nb\n")); !reflect.DeepEqual(lines,
+		expected) {
+		t.Errorf("expected: %q, got: %q", expected, lines)
+	}
+}
@@ -0,0 +1,150 @@
+package httpd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+)
+
+const maxViewSize = 1 << 20
+
+var errFileAccessDisabled = errors.New(
+	"file access disabled: start imageserver with -allowImageFileAccess")
+
+// isText returns true if the data look like text: no NUL bytes and valid
+// UTF-8 (allowing for a truncated final character).
+func isText(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			return len(data) < utf8.UTFMax && !utf8.FullRune(data)
+		}
+		data = data[size:]
+	}
+	return true
+}
+
+// getRegularInode returns the regular inode for the image and pathname given
+// in the query. An error is returned if file access is not permitted.
+func (s state) getRegularInode(req *http.Request) (
+	*filesystem.RegularInode, string, error) {
+	if !s.allowFileAccess {
+		return nil, "", errFileAccessDisabled
+	}
+	query := req.URL.Query()
+	imageName := query.Get("image")
+	pathname := query.Get("path")
+	img := s.imageDataBase.GetImage(imageName)
+	if img == nil {
+		return nil, "", errors.New("unknown image: " + imageName)
+	}
+	info, err := lookupPath(img.FileSystem, pathname)
+	if err != nil {
+		return nil, "", err
+	}
+	inode, ok := info.inode.(*filesystem.RegularInode)
+	if !ok {
+		return nil, "", errors.New("not a regular file: " + pathname)
+	}
+	return inode, info.name, nil
+}
+
+// readFile returns up to maxBytes of the contents of inode and whether the
+// contents were truncated.
+func (s state) readFile(inode *filesystem.RegularInode, maxBytes uint64) (
+	[]byte, bool, error) {
+	if inode.Size < 1 {
+		return nil, false, nil
+	}
+	_, reader, err := s.objectServer.GetObject(inode.Hash)
+	if err != nil {
+		return nil, false, err
+	}
+	defer reader.Close()
+	length := inode.Size
+	if length > maxBytes {
+		length = maxBytes
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, false, err
+	}
+	return data, inode.Size > length, nil
+}
+
+func (s state) getImageFileHandler(w http.ResponseWriter, req *http.Request) {
+	inode, pathname, err := s.getRegularInode(req)
+	if err == errFileAccessDisabled {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", path.Base(pathname)))
+	w.Header().Set("Content-Length", strconv.FormatUint(inode.Size, 10))
+	if inode.Size < 1 {
+		return
+	}
+	_, reader, err := s.objectServer.GetObject(inode.Hash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+	io.CopyN(w, reader, int64(inode.Size))
+}
+
+func (s state) viewImageFileHandler(w http.ResponseWriter, req *http.Request) {
+	inode, pathname, err := s.getRegularInode(req)
+	if err == errFileAccessDisabled {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	data, truncated, err := s.readFile(inode, maxViewSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	imageName := req.URL.Query().Get("image")
+	fmt.Fprintf(writer, "<title>image %s:%s</title>\n",
+		template.HTMLEscapeString(imageName),
+		template.HTMLEscapeString(pathname))
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	writeBreadcrumbs(writer, imageName, pathname)
+	fmt.Fprintln(writer, "</h3>")
+	if !isText(data) {
+		fmt.Fprintln(writer, "Binary file: not shown<br>")
+	} else {
+		if truncated {
+			fmt.Fprintf(writer, "Showing first %s of %s<br>\n",
+				format.FormatBytes(uint64(len(data))),
+				format.FormatBytes(inode.Size))
+		}
+		fmt.Fprintln(writer, "<pre>")
+		template.HTMLEscape(writer, data)
+		fmt.Fprintln(writer, "</pre>")
+	}
+	fmt.Fprintln(writer, "</body>")
+}
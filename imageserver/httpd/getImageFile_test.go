@@ -0,0 +1,50 @@
+package httpd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{"empty", "", true},
+		{"ASCII", "hello\nworld\n", true},
+		{"UTF-8", "café 世界", true},
+		{"truncated UTF-8", "caf\xc3", true},
+		{"truncated 3 byte UTF-8", "世\xe7\x95", true},
+		{"NUL", "hello\x00world", false},
+		{"invalid UTF-8", "caf\xc3(", false},
+		{"invalid final byte", "hello\xff", false},
+	}
+	for _, test := range tests {
+		if got := isText([]byte(test.data)); got != test.expected {
+			t.Errorf("%s: expected: %v, got: %v",
+				test.name, test.expected, got)
+		}
+	}
+}
+
+func TestFileAccessDisabled(t *testing.T) {
+	s := state{}
+	for name, handler := range map[string]http.HandlerFunc{
+		"getImageFile":  s.getImageFileHandler,
+		"viewImageFile": s.viewImageFileHandler,
+	} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET",
+			"/"+name+"?image=image&path=/etc/shadow", nil))
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("%s: expected status: %d, got: %d",
+				name, http.StatusForbidden, recorder.Code)
+		}
+		if strings.Contains(recorder.Body.String(), "<pre>") {
+			t.Errorf("%s: file contents shown", name)
+		}
+	}
+}
@@ -30,9 +30,11 @@ func (s state) listDirectoriesHandler(w http.ResponseWriter,
 	fmt.Fprintln(writer, "<body>")
 	fmt.Fprintln(writer, "<h3>")
 	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
-	tw, _ := html.NewTableWriter(writer, true, "Name", "Owner Group")
+	tw, _ := html.NewTableWriter(writer, true, "Name", "Owner Group",
+		"Retention Policy")
 	for _, directory := range directories {
-		tw.WriteRow("", "", directory.Name, directory.Metadata.OwnerGroup)
+		tw.WriteRow("", "", directory.Name, directory.Metadata.OwnerGroup,
+			directory.Metadata.RetentionPolicy.String())
 	}
 	tw.Close()
 	fmt.Fprintln(writer, "</body>")
@@ -31,6 +31,8 @@ func (s state) showImageHandler(w http.ResponseWriter, req *http.Request) {
 	fmt.Fprintln(writer, "</h3>")
 	fmt.Fprintf(writer, "Data size: <a href=\"listImage?%s\">%s</a><br>\n",
 		imageName, format.FormatBytes(img.FileSystem.TotalDataBytes))
+	fmt.Fprintf(writer, "Browse: <a href=\"%s\">file-system</a><br>\n",
+		makeBrowseURL(imageName, "/"))
 	fmt.Fprintf(writer, "Number of data inodes: %d<br>\n",
 		img.FileSystem.NumRegularInodes)
 	if numInodes := img.FileSystem.NumComputedRegularInodes(); numInodes > 0 {
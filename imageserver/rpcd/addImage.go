@@ -51,10 +51,11 @@ func (t *srpcType) AddImageTrusted(conn *srpc.Conn,
 
 func (t *srpcType) injectImage(conn *srpc.Conn,
 	request imageserver.AddImageRequest) error {
-	if t.replicationMaster == "" {
+	replicationMaster := t.imageDataBase.GetReplicationMaster()
+	if replicationMaster == "" {
 		return nil
 	}
-	masterClient, err := srpc.DialHTTP("tcp", t.replicationMaster, 0)
+	masterClient, err := srpc.DialHTTP("tcp", replicationMaster, 0)
 	if err != nil {
 		return err
 	}
@@ -5,12 +5,14 @@ import (
 	"flag"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/imageserver/scanner"
 	"github.com/Cloud-Foundations/Dominator/lib/filter"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
 
 var (
@@ -24,20 +26,46 @@ var (
 		"Filename containing filter to include images for replication (default include all)")
 )
 
+type Config struct {
+	ReplicationMasters []string // In order of preference. Empty: be a master.
+}
+
+type Params struct {
+	ImageDataBase *scanner.ImageDataBase
+	Logger        log.DebugLogger
+	ObjectServer  objectserver.FullObjectServer
+}
+
 type srpcType struct {
-	imageDataBase             *scanner.ImageDataBase
-	excludeFilter             *filter.Filter
-	finishedReplication       <-chan struct{} // Closed when finished.
-	includeFilter             *filter.Filter
-	replicationMaster         string
-	imageserverResource       *srpc.ClientResource
-	objSrv                    objectserver.FullObjectServer
-	archiveMode               bool
-	logger                    log.DebugLogger
-	numReplicationClientsLock sync.RWMutex // Protect numReplicationClients.
-	numReplicationClients     uint
-	imagesBeingInjectedLock   sync.Mutex // Protect imagesBeingInjected.
-	imagesBeingInjected       map[string]struct{}
+	imageDataBase           *scanner.ImageDataBase
+	excludeFilter           *filter.Filter
+	finishedReplication     <-chan struct{} // Closed when finished.
+	includeFilter           *filter.Filter
+	replicationMasters      []string
+	stopReplication         chan struct{} // Closed when promoted.
+	replicatorStopped       chan struct{} // Closed when replicator exits.
+	objSrv                  objectserver.FullObjectServer
+	archiveMode             bool
+	logger                  log.DebugLogger
+	replicationClientsLock  sync.RWMutex // Protect replicationClients.
+	replicationClients      map[*srpc.Conn]*imageserver.ReplicaStatus
+	imagesBeingInjectedLock sync.Mutex // Protect imagesBeingInjected.
+	imagesBeingInjected     map[string]struct{}
+	replicaLock             sync.Mutex // Protect everything below.
+	imageserverResource     *srpc.ClientResource
+	promotedAt              time.Time
+	replica                 replicaState
+}
+
+// replicaState records the state of the connection to the current master.
+type replicaState struct {
+	behindSince      time.Time    // Zero: all received updates applied.
+	client           *srpc.Client // Closed when promoted.
+	connectedSince   time.Time
+	divergentImages  map[string]struct{} // Not on master.
+	lastSyncedMaster string              // Master which sent the last list.
+	lastUpdateAt     time.Time
+	mismatchedImages map[string]struct{} // Content differs from master.
 }
 
 type htmlWriter srpcType
@@ -52,18 +80,37 @@ var replicationMessage = "cannot make changes while under replication control" +
 func Setup(imdb *scanner.ImageDataBase, replicationMaster string,
 	objSrv objectserver.FullObjectServer,
 	logger log.DebugLogger) (*htmlWriter, error) {
-	if *archiveMode && replicationMaster == "" {
+	var replicationMasters []string
+	if replicationMaster != "" {
+		replicationMasters = []string{replicationMaster}
+	}
+	return SetupWithConfig(
+		Config{ReplicationMasters: replicationMasters},
+		Params{
+			ImageDataBase: imdb,
+			Logger:        logger,
+			ObjectServer:  objSrv,
+		})
+}
+
+// SetupWithConfig will register the ImageServer RPC methods. If replication
+// masters are specified, images are replicated from the first available master
+// in the list, failing over to the next master if it is unavailable.
+func SetupWithConfig(config Config, params Params) (*htmlWriter, error) {
+	if *archiveMode && len(config.ReplicationMasters) < 1 {
 		return nil, errors.New("replication master required in archive mode")
 	}
 	finishedReplication := make(chan struct{})
 	srpcObj := &srpcType{
-		imageDataBase:       imdb,
+		imageDataBase:       params.ImageDataBase,
 		finishedReplication: finishedReplication,
-		replicationMaster:   replicationMaster,
-		imageserverResource: srpc.NewClientResource("tcp", replicationMaster),
-		objSrv:              objSrv,
-		logger:              logger,
+		replicationMasters:  config.ReplicationMasters,
+		stopReplication:     make(chan struct{}),
+		replicatorStopped:   make(chan struct{}),
+		objSrv:              params.ObjectServer,
+		logger:              params.Logger,
 		archiveMode:         *archiveMode,
+		replicationClients:  make(map[*srpc.Conn]*imageserver.ReplicaStatus),
 		imagesBeingInjected: make(map[string]struct{}),
 	}
 	var err error
@@ -86,6 +133,8 @@ func Setup(imdb *scanner.ImageDataBase, replicationMaster string,
 			"CheckImage",
 			"ChownDirectory",
 			"DeleteImage",
+			"FindImagesWithObject",
+			"FindImagesWithPath",
 			"FindLatestImage",
 			"GetFilteredImageUpdates",
 			"GetImage",
@@ -94,14 +143,21 @@ func Setup(imdb *scanner.ImageDataBase, replicationMaster string,
 			"GetImageExpiration",
 			"GetImageUpdates",
 			"GetReplicationMaster",
+			"GetReplicationStatus",
+			"GetRetentionReport",
 			"ListDirectories",
 			"ListImages",
 			"ListSelectedImages",
+			"SetDirectoryRetention",
 		}})
-	if replicationMaster != "" {
+	if len(config.ReplicationMasters) > 0 {
+		srpcObj.imageDataBase.SetReplicationMaster(config.ReplicationMasters[0])
+		srpcObj.replica.behindSince = time.Now()
 		go srpcObj.replicator(finishedReplication)
 	} else {
+		srpcObj.imageDataBase.SetReplicationMaster("")
 		close(finishedReplication)
+		close(srpcObj.replicatorStopped)
 	}
 	return (*htmlWriter)(srpcObj), nil
 }
@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func (t *srpcType) FindImagesWithObject(conn *srpc.Conn,
+	request imageserver.FindImagesWithObjectRequest,
+	reply *imageserver.FindImagesWithObjectResponse) error {
+	images, err := t.imageDataBase.FindImagesWithObject(request.Hash)
+	*reply = imageserver.FindImagesWithObjectResponse{
+		Error:  errors.ErrorToString(err),
+		Images: images,
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func (t *srpcType) FindImagesWithPath(conn *srpc.Conn,
+	request imageserver.FindImagesWithPathRequest,
+	reply *imageserver.FindImagesWithPathResponse) error {
+	imageNames, err := t.imageDataBase.FindImagesWithPath(request.Pathname)
+	*reply = imageserver.FindImagesWithPathResponse{
+		Error:      errors.ErrorToString(err),
+		ImageNames: imageNames,
+	}
+	return nil
+}
@@ -10,7 +10,8 @@ func (t *srpcType) GetImageArchive(conn *srpc.Conn,
 	request imageserver.GetImageArchiveRequest,
 	reply *imageserver.GetImageArchiveResponse) error {
 	var response imageserver.GetImageArchiveResponse
-	if t.replicationMaster == "" {
+	replicationMaster := t.imageDataBase.GetReplicationMaster()
+	if replicationMaster == "" {
 		if username := conn.Username(); username == "" {
 			t.logger.Printf("GetImageArchive(%s)\n", request.ImageName)
 		} else {
@@ -21,7 +22,7 @@ func (t *srpcType) GetImageArchive(conn *srpc.Conn,
 		response.ArchiveData = archiveData
 		response.Error = errors.ErrorToString(err)
 	} else {
-		response.ReplicationMaster = t.replicationMaster
+		response.ReplicationMaster = replicationMaster
 	}
 	*reply = response
 	return nil
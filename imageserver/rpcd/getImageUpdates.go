@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
@@ -37,8 +38,8 @@ func (t *srpcType) getImageUpdates(conn *srpc.Conn,
 			"Replication finished, unblocking replication client: %s\n",
 			conn.RemoteAddr())
 	}
-	t.incrementNumReplicationClients(true)
-	defer t.incrementNumReplicationClients(false)
+	replica := t.registerReplicationClient(conn)
+	defer t.unregisterReplicationClient(conn)
 	addChannel := t.imageDataBase.RegisterAddNotifier()
 	deleteChannel := t.imageDataBase.RegisterDeleteNotifier()
 	mkdirChannel := t.imageDataBase.RegisterMakeDirectoryNotifier()
@@ -47,28 +48,37 @@ func (t *srpcType) getImageUpdates(conn *srpc.Conn,
 	defer t.imageDataBase.UnregisterMakeDirectoryNotifier(mkdirChannel)
 	directories := t.imageDataBase.ListDirectories()
 	image.SortDirectories(directories)
+	var numUpdates uint64
 	for _, directory := range directories {
 		imageUpdate := imageserver.ImageUpdate{
 			Directory: &directory,
 			Operation: imageserver.OperationMakeDirectory,
+			Timestamp: startTime,
 		}
 		if err := conn.Encode(imageUpdate); err != nil {
 			t.logger.Println(err)
 			return err
 		}
+		numUpdates++
 	}
 	for _, imageName := range t.imageDataBase.ListImages() {
 		if t.checkIgnoreImage(request.IgnoreExpiring, imageName) {
 			continue
 		}
-		imageUpdate := imageserver.ImageUpdate{Name: imageName}
+		imageUpdate := imageserver.ImageUpdate{
+			ContentHash: t.getImageContentHash(imageName),
+			Name:        imageName,
+			Timestamp:   startTime,
+		}
 		if err := conn.Encode(imageUpdate); err != nil {
 			t.logger.Println(err)
 			return err
 		}
+		numUpdates++
 	}
 	// Signal end of initial image list.
-	if err := conn.Encode(imageserver.ImageUpdate{}); err != nil {
+	err := conn.Encode(imageserver.ImageUpdate{Timestamp: startTime})
+	if err != nil {
 		t.logger.Println(err)
 		return err
 	}
@@ -76,6 +86,7 @@ func (t *srpcType) getImageUpdates(conn *srpc.Conn,
 		t.logger.Println(err)
 		return err
 	}
+	t.updateReplicationClient(replica, numUpdates+1)
 	t.logger.Printf(
 		"Finished sending initial image list to replication client in %s\n",
 		format.Duration(time.Since(startTime)))
@@ -86,19 +97,25 @@ func (t *srpcType) getImageUpdates(conn *srpc.Conn,
 			if t.checkIgnoreImage(request.IgnoreExpiring, imageName) {
 				break
 			}
-			if err := sendUpdate(conn, imageName,
-				imageserver.OperationAddImage); err != nil {
+			imageUpdate := imageserver.ImageUpdate{
+				ContentHash: t.getImageContentHash(imageName),
+				Name:        imageName,
+				Operation:   imageserver.OperationAddImage,
+				Timestamp:   time.Now(),
+			}
+			if err := conn.Encode(imageUpdate); err != nil {
 				t.logger.Println(err)
 				return err
 			}
 		case imageName := <-deleteChannel:
 			if err := sendUpdate(conn, imageName,
-				imageserver.OperationDeleteImage); err != nil {
+				imageserver.OperationDeleteImage, time.Now()); err != nil {
 				t.logger.Println(err)
 				return err
 			}
 		case directory := <-mkdirChannel:
-			if err := sendMakeDirectory(conn, directory); err != nil {
+			err := sendMakeDirectory(conn, directory, time.Now())
+			if err != nil {
 				t.logger.Println(err)
 				return err
 			}
@@ -115,6 +132,7 @@ func (t *srpcType) getImageUpdates(conn *srpc.Conn,
 			t.logger.Println(err)
 			return err
 		}
+		t.updateReplicationClient(replica, 1)
 	}
 }
 
@@ -132,25 +150,60 @@ func (t *srpcType) checkIgnoreImage(ignoreExpiring bool,
 	return true
 }
 
-func (t *srpcType) incrementNumReplicationClients(increment bool) {
-	t.numReplicationClientsLock.Lock()
-	defer t.numReplicationClientsLock.Unlock()
-	if increment {
-		t.numReplicationClients++
-	} else {
-		t.numReplicationClients--
+// getImageContentHash returns the content hash of the image to send to
+// replicas, or nil if it is not available.
+func (t *srpcType) getImageContentHash(imageName string) *hash.Hash {
+	contentHash, err := t.imageDataBase.GetImageContentHash(imageName)
+	if err != nil {
+		t.logger.Printf("error getting content hash for: %s: %s\n",
+			imageName, err)
+		return nil
+	}
+	return &contentHash
+}
+
+func (t *srpcType) registerReplicationClient(
+	conn *srpc.Conn) *imageserver.ReplicaStatus {
+	replica := &imageserver.ReplicaStatus{
+		Address:        conn.RemoteAddr(),
+		ConnectedSince: time.Now(),
 	}
+	t.replicationClientsLock.Lock()
+	defer t.replicationClientsLock.Unlock()
+	t.replicationClients[conn] = replica
+	return replica
 }
 
-func sendUpdate(encoder srpc.Encoder, name string, operation uint) error {
-	imageUpdate := imageserver.ImageUpdate{Name: name, Operation: operation}
+func (t *srpcType) unregisterReplicationClient(conn *srpc.Conn) {
+	t.replicationClientsLock.Lock()
+	defer t.replicationClientsLock.Unlock()
+	delete(t.replicationClients, conn)
+}
+
+func (t *srpcType) updateReplicationClient(replica *imageserver.ReplicaStatus,
+	numUpdates uint64) {
+	t.replicationClientsLock.Lock()
+	defer t.replicationClientsLock.Unlock()
+	replica.LastUpdateSentAt = time.Now()
+	replica.NumUpdatesSent += numUpdates
+}
+
+func sendUpdate(encoder srpc.Encoder, name string, operation uint,
+	timestamp time.Time) error {
+	imageUpdate := imageserver.ImageUpdate{
+		Name:      name,
+		Operation: operation,
+		Timestamp: timestamp,
+	}
 	return encoder.Encode(imageUpdate)
 }
 
-func sendMakeDirectory(encoder srpc.Encoder, directory image.Directory) error {
+func sendMakeDirectory(encoder srpc.Encoder, directory image.Directory,
+	timestamp time.Time) error {
 	imageUpdate := imageserver.ImageUpdate{
 		Directory: &directory,
 		Operation: imageserver.OperationMakeDirectory,
+		Timestamp: timestamp,
 	}
 	return encoder.Encode(imageUpdate)
 }
@@ -8,6 +8,6 @@ import (
 func (t *srpcType) GetReplicationMaster(conn *srpc.Conn,
 	request imageserver.GetReplicationMasterRequest,
 	reply *imageserver.GetReplicationMasterResponse) error {
-	reply.ReplicationMaster = t.replicationMaster
+	reply.ReplicationMaster = t.imageDataBase.GetReplicationMaster()
 	return nil
 }
@@ -0,0 +1,15 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func (t *srpcType) GetReplicationStatus(conn *srpc.Conn,
+	request imageserver.GetReplicationStatusRequest,
+	reply *imageserver.GetReplicationStatusResponse) error {
+	*reply = imageserver.GetReplicationStatusResponse{
+		ReplicationStatus: t.getReplicationStatus(),
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func (t *srpcType) GetRetentionReport(conn *srpc.Conn,
+	request imageserver.GetRetentionReportRequest,
+	reply *imageserver.GetRetentionReportResponse) error {
+	directories, err := t.imageDataBase.GetRetentionReport(
+		request.DirectoryName)
+	*reply = imageserver.GetRetentionReportResponse{
+		Directories: directories,
+		Error:       errors.ErrorToString(err),
+	}
+	return nil
+}
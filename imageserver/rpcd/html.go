@@ -3,6 +3,8 @@ package rpcd
 import (
 	"fmt"
 	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
 )
 
 func (hw *htmlWriter) writeHtml(writer io.Writer) {
@@ -10,12 +12,25 @@ func (hw *htmlWriter) writeHtml(writer io.Writer) {
 		fmt.Fprintln(writer,
 			`<font color="purple">Running in archive mode</font><br>`)
 	}
+	status := (*srpcType)(hw).getReplicationStatus()
+	if !status.PromotedAt.IsZero() {
+		fmt.Fprintf(writer, "Promoted to replication master at: %s<br>\n",
+			status.PromotedAt.Format(format.TimeFormatSeconds))
+	} else if status.ReplicationMaster != "" {
+		if status.Connected {
+			fmt.Fprintf(writer, "Replication lag: %s<br>\n",
+				format.Duration(status.Lag))
+		} else {
+			fmt.Fprintf(writer, `<font color="red">`+
+				"Not connected to replication master, lag: %s</font><br>\n",
+				format.Duration(status.Lag))
+		}
+	}
+	if len(status.DivergentImages) > 0 {
+		fmt.Fprintf(writer, `<font color="red">`+
+			"Divergent images (not on master): %d</font><br>\n",
+			len(status.DivergentImages))
+	}
 	fmt.Fprintf(writer, "Replication clients: %d<br>\n",
-		hw.getNumReplicationClients())
-}
-
-func (hw *htmlWriter) getNumReplicationClients() uint {
-	hw.numReplicationClientsLock.RLock()
-	defer hw.numReplicationClientsLock.RUnlock()
-	return hw.numReplicationClients
+		len(status.Replicas))
 }
@@ -5,8 +5,9 @@ import (
 )
 
 func (t *srpcType) checkMutability() error {
-	if t.replicationMaster != "" {
-		return errors.New(replicationMessage + t.replicationMaster)
+	replicationMaster := t.imageDataBase.GetReplicationMaster()
+	if replicationMaster != "" {
+		return errors.New(replicationMessage + replicationMaster)
 	}
 	return nil
 }
@@ -0,0 +1,77 @@
+package rpcd
+
+import (
+	"fmt"
+	"time"
+
+	iclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func (t *srpcType) PromoteToMaster(conn *srpc.Conn,
+	request imageserver.PromoteToMasterRequest,
+	reply *imageserver.PromoteToMasterResponse) error {
+	if username := conn.Username(); username == "" {
+		t.logger.Printf("PromoteToMaster(force=%v)\n", request.Force)
+	} else {
+		t.logger.Printf("PromoteToMaster(force=%v) by %s\n",
+			request.Force, username)
+	}
+	*reply = imageserver.PromoteToMasterResponse{
+		Error: errors.ErrorToString(t.promoteToMaster(request.Force)),
+	}
+	return nil
+}
+
+// findActiveMaster returns the first replication master which is reachable
+// and is itself a master, or the empty string if there is none.
+func (t *srpcType) findActiveMaster() string {
+	for _, master := range t.replicationMasters {
+		client, err := srpc.DialHTTP("tcp", master, 5*time.Second)
+		if err != nil {
+			continue
+		}
+		replicationMaster, err := iclient.GetReplicationMaster(client)
+		client.Close()
+		if err == nil && replicationMaster == "" {
+			return master
+		}
+	}
+	return ""
+}
+
+func (t *srpcType) promoteToMaster(force bool) error {
+	if t.imageDataBase.GetReplicationMaster() == "" {
+		return errors.New("already a replication master")
+	}
+	if t.archiveMode {
+		return errors.New("cannot promote in archive mode")
+	}
+	if !force && !t.checkPromoted() {
+		select {
+		case <-t.finishedReplication:
+		default:
+			return errors.New("initial replication has not finished")
+		}
+		if master := t.findActiveMaster(); master != "" {
+			return fmt.Errorf("replication master: %s is still active", master)
+		}
+	}
+	t.replicaLock.Lock()
+	if !t.checkPromoted() {
+		close(t.stopReplication)
+	}
+	if t.replica.client != nil {
+		t.replica.client.Close()
+	}
+	t.replicaLock.Unlock()
+	select {
+	case <-t.replicatorStopped:
+		return nil
+	case <-time.After(time.Minute):
+		return errors.New(
+			"timed out waiting for replication to stop, promotion pending")
+	}
+}
@@ -0,0 +1,205 @@
+package rpcd
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func (t *srpcType) checkPromoted() bool {
+	select {
+	case <-t.stopReplication:
+		return true
+	default:
+		return false
+	}
+}
+
+// finishPromotion is called by the replicator when it stops after a
+// promotion. Changes are permitted from here on.
+func (t *srpcType) finishPromotion(finishedReplication chan<- struct{}) {
+	t.imageDataBase.SetReplicationMaster("")
+	if finishedReplication != nil {
+		close(finishedReplication)
+	}
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	t.promotedAt = time.Now()
+	if t.imageserverResource != nil {
+		t.imageserverResource.ScheduleClose()
+		t.imageserverResource = nil
+	}
+	t.logger.Println("Promoted to replication master, replication stopped")
+}
+
+func (t *srpcType) getImageserverResource() *srpc.ClientResource {
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	return t.imageserverResource
+}
+
+func (t *srpcType) getReplicationStatus() imageserver.ReplicationStatus {
+	status := imageserver.ReplicationStatus{
+		ReplicationMaster:  t.imageDataBase.GetReplicationMaster(),
+		ReplicationMasters: t.replicationMasters,
+	}
+	t.replicaLock.Lock()
+	status.PromotedAt = t.promotedAt
+	if status.ReplicationMaster != "" {
+		status.Connected = t.replica.client != nil
+		status.ConnectedSince = t.replica.connectedSince
+		status.LastUpdateAt = t.replica.lastUpdateAt
+		if !t.replica.behindSince.IsZero() {
+			status.Lag = time.Since(t.replica.behindSince)
+			if status.Lag < 0 {
+				status.Lag = 0
+			}
+		}
+	}
+	for name := range t.replica.divergentImages {
+		status.DivergentImages = append(status.DivergentImages, name)
+	}
+	for name := range t.replica.mismatchedImages {
+		if _, ok := t.replica.divergentImages[name]; !ok {
+			status.DivergentImages = append(status.DivergentImages, name)
+		}
+	}
+	t.replicaLock.Unlock()
+	sort.Strings(status.DivergentImages)
+	t.replicationClientsLock.RLock()
+	for _, replica := range t.replicationClients {
+		status.Replicas = append(status.Replicas, *replica)
+	}
+	t.replicationClientsLock.RUnlock()
+	sort.Slice(status.Replicas, func(left, right int) bool {
+		return status.Replicas[left].Address < status.Replicas[right].Address
+	})
+	return status
+}
+
+// selectImagesToDelete returns the images missing from the master which may
+// be deleted. If the master has changed since the last initial list was
+// received, the missing images are instead recorded as divergent. Divergent
+// images are kept until the master has them or the imageserver is restarted.
+// The last synchronised master is persisted. If it was never recorded, the
+// preferred master is assumed to be the last synchronised master.
+func (t *srpcType) selectImagesToDelete(master string,
+	missingImages []string) []string {
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	lastSyncedMaster := t.replica.lastSyncedMaster
+	if lastSyncedMaster == "" {
+		var err error
+		lastSyncedMaster, err = t.imageDataBase.GetLastSyncedMaster()
+		if err != nil {
+			t.logger.Printf("Error reading last synchronised master: %s\n",
+				err)
+		}
+	}
+	if lastSyncedMaster == "" && len(t.replicationMasters) > 0 {
+		lastSyncedMaster = t.replicationMasters[0]
+	}
+	switched := master != lastSyncedMaster
+	if master != t.replica.lastSyncedMaster {
+		if err := t.imageDataBase.SetLastSyncedMaster(master); err != nil {
+			t.logger.Printf("Error recording last synchronised master: %s\n",
+				err)
+		}
+	}
+	t.replica.lastSyncedMaster = master
+	divergentImages := make(map[string]struct{})
+	var imagesToDelete []string
+	for _, name := range missingImages {
+		if _, ok := t.replica.divergentImages[name]; ok || switched {
+			divergentImages[name] = struct{}{}
+		} else {
+			imagesToDelete = append(imagesToDelete, name)
+		}
+	}
+	if len(divergentImages) > 0 {
+		t.logger.Printf("History diverged from master: %s, keeping %d images\n",
+			master, len(divergentImages))
+		for name := range divergentImages {
+			t.logger.Printf("Replicator(%s): divergent image, not on master\n",
+				name)
+		}
+	}
+	t.replica.divergentImages = divergentImages
+	return imagesToDelete
+}
+
+// setMismatchedImages records the images which the replica has but which
+// differ from the images with the same names on the master. They are kept and
+// reported as divergent.
+func (t *srpcType) setMismatchedImages(master string,
+	mismatchedImages map[string]struct{}) {
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	images := make(map[string]struct{}, len(mismatchedImages))
+	for name := range mismatchedImages {
+		if _, ok := t.replica.mismatchedImages[name]; !ok {
+			t.logger.Printf(
+				"Replicator(%s): divergent image, differs from: %s\n",
+				name, master)
+		}
+		images[name] = struct{}{}
+	}
+	t.replica.mismatchedImages = images
+}
+
+// setReplicaCaughtUp records that all updates received from the master have
+// been applied.
+func (t *srpcType) setReplicaCaughtUp() {
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	t.replica.behindSince = time.Time{}
+}
+
+// setReplicaConnected records the connection to a master. It returns false if
+// replication has been stopped by a promotion.
+func (t *srpcType) setReplicaConnected(master string,
+	client *srpc.Client) bool {
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	if t.checkPromoted() {
+		return false
+	}
+	if t.imageserverResource == nil ||
+		master != t.imageDataBase.GetReplicationMaster() {
+		if t.imageserverResource != nil {
+			t.imageserverResource.ScheduleClose()
+		}
+		t.imageserverResource = srpc.NewClientResource("tcp", master)
+	}
+	t.imageDataBase.SetReplicationMaster(master)
+	t.replica.client = client
+	t.replica.connectedSince = time.Now()
+	return true
+}
+
+func (t *srpcType) setReplicaDisconnected() {
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	t.replica.client = nil
+	t.replica.connectedSince = time.Time{}
+	if t.replica.behindSince.IsZero() {
+		t.replica.behindSince = time.Now()
+	}
+}
+
+// setReplicaProcessing records that an update sent by the master at the
+// specified time is being applied.
+func (t *srpcType) setReplicaProcessing(timestamp time.Time) {
+	t.replicaLock.Lock()
+	defer t.replicaLock.Unlock()
+	t.replica.lastUpdateAt = time.Now()
+	if timestamp.IsZero() { // Older masters do not send timestamps.
+		timestamp = t.replica.lastUpdateAt
+	}
+	if t.replica.behindSince.IsZero() ||
+		timestamp.Before(t.replica.behindSince) {
+		t.replica.behindSince = timestamp
+	}
+}
@@ -0,0 +1,331 @@
+package rpcd
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/scanner"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+const getImageUpdatesMethod = "ImageServer.GetImageUpdates"
+
+// fakeMasterType serves the initial image list to replicas. The same fake is
+// reachable with different master addresses, so the test selects which images
+// the "current" master has.
+type fakeMasterType struct {
+	mutex         sync.Mutex
+	block         bool // If true, do not close the connection after list.
+	contentHashes map[string]hash.Hash
+	images        []string
+}
+
+type refcountingObjectServer struct {
+	objectserver.FullObjectServer
+}
+
+var (
+	fakeMaster     = &fakeMasterType{}
+	fakeMasterAddr net.Addr
+	setupOnce      sync.Once
+)
+
+func (m *fakeMasterType) GetImageUpdates(conn *srpc.Conn) error {
+	m.mutex.Lock()
+	block := m.block
+	contentHashes := m.contentHashes
+	images := m.images
+	m.mutex.Unlock()
+	for _, name := range images {
+		imageUpdate := proto.ImageUpdate{
+			Name:      name,
+			Operation: proto.OperationAddImage,
+		}
+		if contentHash, ok := contentHashes[name]; ok {
+			imageUpdate.ContentHash = &contentHash
+		}
+		if err := conn.Encode(imageUpdate); err != nil {
+			return err
+		}
+	}
+	err := conn.Encode(proto.ImageUpdate{Operation: proto.OperationAddImage})
+	if err != nil {
+		return err
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	if block { // Wait for the replica to close the connection.
+		var request proto.GetFilteredImageUpdatesRequest
+		conn.Decode(&request)
+	}
+	return srpc.ErrorCloseClient
+}
+
+// GetReplicationMaster reports that the fake is a master.
+func (m *fakeMasterType) GetReplicationMaster(conn *srpc.Conn,
+	request proto.GetReplicationMasterRequest,
+	reply *proto.GetReplicationMasterResponse) error {
+	return nil
+}
+
+func (m *fakeMasterType) set(images []string, block bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.block = block
+	m.contentHashes = nil
+	m.images = images
+}
+
+func (m *fakeMasterType) setContentHashes(contentHashes map[string]hash.Hash) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.contentHashes = contentHashes
+}
+
+func (objSrv *refcountingObjectServer) AdjustRefcounts(bool,
+	objectserver.ObjectsIterator) error {
+	return nil
+}
+
+// CheckObjects reports that there are no objects, which is correct for the
+// empty test images.
+func (objSrv *refcountingObjectServer) CheckObjects(
+	hashes []hash.Hash) ([]uint64, error) {
+	return make([]uint64, len(hashes)), nil
+}
+
+func setupFakeMaster(t *testing.T) (string, string) {
+	setupOnce.Do(func() {
+		srpc.RegisterName("ImageServer", fakeMaster)
+		listener, err := net.Listen("tcp", "127.0.0.1:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go http.Serve(listener, nil)
+		fakeMasterAddr = listener.Addr()
+	})
+	_, port, err := net.SplitHostPort(fakeMasterAddr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "127.0.0.1:" + port, "localhost:" + port
+}
+
+func makeReplica(t *testing.T, masters []string,
+	images []string) *srpcType {
+	dirname := t.TempDir()
+	replica := loadReplica(t, dirname, masters)
+	for _, name := range images {
+		img := &image.Image{FileSystem: &filesystem.FileSystem{}}
+		err := replica.imageDataBase.AddImage(img, name,
+			&srpc.AuthInformation{HaveMethodAccess: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return replica
+}
+
+// loadReplica makes a replica using the image database in dirname. This is
+// used to simulate a restart.
+func loadReplica(t *testing.T, dirname string, masters []string) *srpcType {
+	logger := testlogger.New(t)
+	imdb, err := scanner.LoadImageDataBase(dirname,
+		&refcountingObjectServer{}, masters[0], logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &srpcType{
+		imageDataBase:       imdb,
+		replicationMasters:  masters,
+		stopReplication:     make(chan struct{}),
+		replicatorStopped:   make(chan struct{}),
+		logger:              logger,
+		imagesBeingInjected: make(map[string]struct{}),
+	}
+}
+
+func listDivergentImages(t *srpcType) []string {
+	return t.getReplicationStatus().DivergentImages
+}
+
+func listImages(t *srpcType) []string {
+	names := t.imageDataBase.ListImages()
+	sort.Strings(names)
+	return names
+}
+
+func (t *srpcType) testReplicateFrom(master string) error {
+	var finishedReplication chan<- struct{}
+	_, err := t.replicateFrom(master, getImageUpdatesMethod, nil,
+		&finishedReplication, 5*time.Second)
+	return err
+}
+
+func TestReplicationFailover(t *testing.T) {
+	master0, master1 := setupFakeMaster(t)
+	replica := makeReplica(t, []string{master0, master1},
+		[]string{"a", "b", "c"})
+	// First synchronisation from the preferred master.
+	fakeMaster.set([]string{"a", "b"}, false)
+	replica.testReplicateFrom(master0)
+	if got := listImages(replica); !reflect.DeepEqual(got,
+		[]string{"a", "b"}) {
+		t.Fatalf("first sync: missing image not deleted: %v", got)
+	}
+	if got := listDivergentImages(replica); len(got) > 0 {
+		t.Errorf("first sync: unexpected divergent images: %v", got)
+	}
+	// Fail over to a master which has lost an image.
+	fakeMaster.set([]string{"a"}, false)
+	replica.testReplicateFrom(master1)
+	if got := listImages(replica); !reflect.DeepEqual(got,
+		[]string{"a", "b"}) {
+		t.Fatalf("failover: divergent image deleted: %v", got)
+	}
+	if got := listDivergentImages(replica); !reflect.DeepEqual(got,
+		[]string{"b"}) {
+		t.Errorf("failover: expected divergent: [b], got: %v", got)
+	}
+	// Stay with the same master: the divergent image is still retained.
+	replica.testReplicateFrom(master1)
+	if got := listImages(replica); !reflect.DeepEqual(got,
+		[]string{"a", "b"}) {
+		t.Fatalf("resync: divergent image deleted: %v", got)
+	}
+	// Fail back to the preferred master, which does not have the image either.
+	replica.testReplicateFrom(master0)
+	if got := listImages(replica); !reflect.DeepEqual(got,
+		[]string{"a", "b"}) {
+		t.Fatalf("failback: divergent image deleted: %v", got)
+	}
+	if got := listDivergentImages(replica); !reflect.DeepEqual(got,
+		[]string{"b"}) {
+		t.Errorf("failback: expected divergent: [b], got: %v", got)
+	}
+	// The master has the image again, so it is no longer divergent.
+	fakeMaster.set([]string{"a", "b"}, false)
+	replica.testReplicateFrom(master0)
+	if got := listDivergentImages(replica); len(got) > 0 {
+		t.Errorf("unexpected divergent images: %v", got)
+	}
+}
+
+func TestReplicationFirstSyncFromSecondaryMaster(t *testing.T) {
+	master0, master1 := setupFakeMaster(t)
+	replica := makeReplica(t, []string{master0, master1},
+		[]string{"a", "b"})
+	fakeMaster.set([]string{"a"}, false)
+	replica.testReplicateFrom(master1)
+	if got := listImages(replica); !reflect.DeepEqual(got,
+		[]string{"a", "b"}) {
+		t.Fatalf("divergent image deleted: %v", got)
+	}
+	if got := listDivergentImages(replica); !reflect.DeepEqual(got,
+		[]string{"b"}) {
+		t.Errorf("expected divergent: [b], got: %v", got)
+	}
+}
+
+func TestReplicationContentMismatch(t *testing.T) {
+	master0, _ := setupFakeMaster(t)
+	replica := makeReplica(t, []string{master0}, []string{"a", "b"})
+	hashA, err := replica.imageDataBase.GetImageContentHash("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashB, err := replica.imageDataBase.GetImageContentHash("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashA != hashB {
+		t.Fatal("content hashes differ for identical images")
+	}
+	otherHash := hashA
+	otherHash[0]++
+	fakeMaster.set([]string{"a", "b"}, false)
+	fakeMaster.setContentHashes(map[string]hash.Hash{
+		"a": hashA,
+		"b": otherHash,
+	})
+	replica.testReplicateFrom(master0)
+	if got := listImages(replica); !reflect.DeepEqual(got,
+		[]string{"a", "b"}) {
+		t.Fatalf("mismatched image deleted: %v", got)
+	}
+	if got := listDivergentImages(replica); !reflect.DeepEqual(got,
+		[]string{"b"}) {
+		t.Errorf("expected divergent: [b], got: %v", got)
+	}
+	// The master now has the same image.
+	fakeMaster.setContentHashes(map[string]hash.Hash{
+		"a": hashA,
+		"b": hashB,
+	})
+	replica.testReplicateFrom(master0)
+	if got := listDivergentImages(replica); len(got) > 0 {
+		t.Errorf("unexpected divergent images: %v", got)
+	}
+}
+
+func TestReplicationLastSyncedMasterPersisted(t *testing.T) {
+	master0, master1 := setupFakeMaster(t)
+	masters := []string{master0, master1}
+	replica := makeReplica(t, masters, []string{"a", "b"})
+	dirname := replica.imageDataBase.BaseDirectory
+	fakeMaster.set([]string{"a", "b"}, false)
+	replica.testReplicateFrom(master0)
+	replica.testReplicateFrom(master1)
+	// Restart. The preferred master does not have an image, but the replica
+	// last synchronised with the other master, so the image is divergent.
+	replica = loadReplica(t, dirname, masters)
+	fakeMaster.set([]string{"a"}, false)
+	replica.testReplicateFrom(master0)
+	if got := listImages(replica); !reflect.DeepEqual(got,
+		[]string{"a", "b"}) {
+		t.Fatalf("divergent image deleted after restart: %v", got)
+	}
+	if got := listDivergentImages(replica); !reflect.DeepEqual(got,
+		[]string{"b"}) {
+		t.Errorf("expected divergent: [b], got: %v", got)
+	}
+}
+
+func TestPromoteWhileReplicating(t *testing.T) {
+	master0, _ := setupFakeMaster(t)
+	replica := makeReplica(t, []string{master0}, []string{"a"})
+	fakeMaster.set([]string{"a"}, true)
+	finishedReplication := make(chan struct{})
+	replica.finishedReplication = finishedReplication
+	go replica.replicator(finishedReplication)
+	select {
+	case <-finishedReplication:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for initial replication")
+	}
+	// The replicator is now blocked in Decode waiting for updates.
+	if err := replica.promoteToMaster(false); err == nil {
+		t.Fatal("promoted while master is still active")
+	}
+	if err := replica.promoteToMaster(true); err != nil {
+		t.Fatal(err)
+	}
+	if master := replica.imageDataBase.GetReplicationMaster(); master != "" {
+		t.Errorf("still replicating from: %s", master)
+	}
+	if err := replica.promoteToMaster(true); err == nil {
+		t.Error("promoted twice")
+	}
+}
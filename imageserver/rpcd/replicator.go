@@ -8,6 +8,7 @@ import (
 
 	imageclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/log/prefixlogger"
@@ -16,7 +17,13 @@ import (
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
 
+var (
+	errorContentDiffers = errors.New("image content differs from master")
+	errorPromoted       = errors.New("promoted to master")
+)
+
 func (t *srpcType) replicator(finishedReplication chan<- struct{}) {
+	defer close(t.replicatorStopped)
 	initialTimeout := time.Second * 15
 	timeout := initialTimeout
 	var nextSleepStopTime time.Time
@@ -30,40 +37,69 @@ func (t *srpcType) replicator(finishedReplication chan<- struct{}) {
 	}
 	for {
 		nextSleepStopTime = time.Now().Add(timeout)
-		if client, err := srpc.DialHTTP("tcp", t.replicationMaster,
-			timeout); err != nil {
-			t.logger.Printf("Error dialing: %s %s\n", t.replicationMaster, err)
-		} else {
-			if conn, err := client.Call(method); err != nil {
+		// Try the masters in order of preference until one is connected.
+		for _, master := range t.replicationMasters {
+			connected, err := t.replicateFrom(master, method, request,
+				&finishedReplication, timeout)
+			if t.checkPromoted() {
+				t.finishPromotion(finishedReplication)
+				return
+			}
+			if !connected {
 				t.logger.Println(err)
-			} else {
-				err := t.getUpdates(conn, &finishedReplication, request)
-				if err != nil {
-					if err == io.EOF {
-						t.logger.Println(
-							"Connection to image replicator closed")
-						if nextSleepStopTime.Sub(time.Now()) < 1 {
-							timeout = initialTimeout
-						}
-					} else {
-						t.logger.Println(err)
+				continue
+			}
+			if err != nil {
+				if err == io.EOF {
+					t.logger.Println("Connection to image replicator closed")
+					if nextSleepStopTime.Sub(time.Now()) < 1 {
+						timeout = initialTimeout
 					}
+				} else {
+					t.logger.Println(err)
 				}
-				conn.Close()
 			}
-			client.Close()
+			break
+		}
+		select {
+		case <-t.stopReplication:
+			t.finishPromotion(finishedReplication)
+			return
+		case <-time.After(nextSleepStopTime.Sub(time.Now())):
 		}
-		time.Sleep(nextSleepStopTime.Sub(time.Now()))
 		if timeout < time.Minute {
 			timeout *= 2
 		}
 	}
 }
 
-func (t *srpcType) getUpdates(conn *srpc.Conn,
+// replicateFrom will replicate from the specified master. It returns true if
+// the connection was established, along with the reason for disconnecting.
+func (t *srpcType) replicateFrom(master string, method string,
+	request *imageserver.GetFilteredImageUpdatesRequest,
+	finishedReplication *chan<- struct{}, timeout time.Duration) (
+	bool, error) {
+	client, err := srpc.DialHTTP("tcp", master, timeout)
+	if err != nil {
+		return false, fmt.Errorf("error dialing: %s: %s", master, err)
+	}
+	defer client.Close()
+	conn, err := client.Call(method)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if !t.setReplicaConnected(master, client) {
+		return true, errorPromoted
+	}
+	defer t.setReplicaDisconnected()
+	return true, t.getUpdates(master, conn, finishedReplication, request)
+}
+
+func (t *srpcType) getUpdates(master string, conn *srpc.Conn,
 	finishedReplication *chan<- struct{},
 	request *imageserver.GetFilteredImageUpdatesRequest) error {
-	t.logger.Printf("Image replicator: connected to: %s\n", t.replicationMaster)
+	t.logger.Printf("Image replicator: connected to: %s\n", master)
 	replicationStartTime := time.Now()
 	initialImages := make(map[string]struct{})
 	if t.archiveMode {
@@ -77,8 +113,13 @@ func (t *srpcType) getUpdates(conn *srpc.Conn,
 			return err
 		}
 	}
+	receivedInitialList := false
 	someImagesFailed := false
+	mismatchedImages := make(map[string]struct{})
 	for {
+		if receivedInitialList {
+			t.setReplicaCaughtUp()
+		}
 		var imageUpdate imageserver.ImageUpdate
 		if err := conn.Decode(&imageUpdate); err != nil {
 			if err == io.EOF {
@@ -86,11 +127,17 @@ func (t *srpcType) getUpdates(conn *srpc.Conn,
 			}
 			return errors.New("decode err: " + err.Error())
 		}
+		if t.checkPromoted() {
+			return errorPromoted
+		}
+		t.setReplicaProcessing(imageUpdate.Timestamp)
 		switch imageUpdate.Operation {
 		case imageserver.OperationAddImage:
 			if imageUpdate.Name == "" { // Initial list has been sent.
+				receivedInitialList = true
+				t.setMismatchedImages(master, mismatchedImages)
 				if initialImages != nil {
-					t.deleteMissingImages(initialImages)
+					t.deleteMissingImages(master, initialImages)
 					initialImages = nil
 				}
 				if *finishedReplication != nil {
@@ -121,7 +168,13 @@ func (t *srpcType) getUpdates(conn *srpc.Conn,
 			if initialImages != nil {
 				initialImages[imageUpdate.Name] = struct{}{}
 			}
-			if err := t.addImage(imageUpdate.Name); err != nil {
+			err := t.addImage(imageUpdate.Name, imageUpdate.ContentHash)
+			if err == errorContentDiffers {
+				mismatchedImages[imageUpdate.Name] = struct{}{}
+				if receivedInitialList {
+					t.setMismatchedImages(master, mismatchedImages)
+				}
+			} else if err != nil {
 				t.logger.Printf("error adding image: %s: %s\n",
 					imageUpdate.Name, err)
 				someImagesFailed = true
@@ -136,6 +189,10 @@ func (t *srpcType) getUpdates(conn *srpc.Conn,
 			if err != nil {
 				return err
 			}
+			if _, ok := mismatchedImages[imageUpdate.Name]; ok {
+				delete(mismatchedImages, imageUpdate.Name)
+				t.setMismatchedImages(master, mismatchedImages)
+			}
 		case imageserver.OperationMakeDirectory:
 			directory := imageUpdate.Directory
 			if directory == nil {
@@ -148,14 +205,19 @@ func (t *srpcType) getUpdates(conn *srpc.Conn,
 	}
 }
 
-func (t *srpcType) deleteMissingImages(imagesToKeep map[string]struct{}) {
+// deleteMissingImages will delete images which the master does not have. If
+// the master is not the master which sent the previous initial list, the
+// history of the master may have diverged (e.g. it was lagging or was promoted
+// after a failure), so the missing images are retained and reported instead.
+func (t *srpcType) deleteMissingImages(master string,
+	imagesToKeep map[string]struct{}) {
 	missingImages := make([]string, 0)
 	for _, imageName := range t.imageDataBase.ListImages() {
 		if _, ok := imagesToKeep[imageName]; !ok {
 			missingImages = append(missingImages, imageName)
 		}
 	}
-	for _, imageName := range missingImages {
+	for _, imageName := range t.selectImagesToDelete(master, missingImages) {
 		t.logger.Printf("Replicator(%s): delete missing image\n", imageName)
 		err := t.imageDataBase.DeleteImage(imageName,
 			&srpc.AuthInformation{HaveMethodAccess: true})
@@ -168,7 +230,7 @@ func (t *srpcType) deleteMissingImages(imagesToKeep map[string]struct{}) {
 func (t *srpcType) extendImageExpiration(name string,
 	img *image.Image) (bool, error) {
 	timeout := time.Second * 60
-	client, err := t.imageserverResource.GetHTTP(nil, timeout)
+	client, err := t.getImageserverResource().GetHTTP(nil, timeout)
 	if err != nil {
 		return false, err
 	}
@@ -184,13 +246,25 @@ func (t *srpcType) extendImageExpiration(name string,
 		&srpc.AuthInformation{HaveMethodAccess: true})
 }
 
-func (t *srpcType) addImage(name string) error {
+// addImage will add the image from the master if it is not present. If the
+// image is present but its content differs from the image on the master, the
+// image is kept and errorContentDiffers is returned.
+func (t *srpcType) addImage(name string, contentHash *hash.Hash) error {
 	timeout := time.Second * 60
 	if t.checkImageBeingInjected(name) {
 		return nil
 	}
 	logger := prefixlogger.New(fmt.Sprintf("Replicator(%s): ", name), t.logger)
 	if img := t.imageDataBase.GetImage(name); img != nil {
+		if contentHash != nil {
+			localHash, err := t.imageDataBase.GetImageContentHash(name)
+			if err != nil {
+				return err
+			}
+			if localHash != *contentHash {
+				return errorContentDiffers
+			}
+		}
 		if img.ExpiresAt.IsZero() {
 			return nil
 		}
@@ -202,7 +276,7 @@ func (t *srpcType) addImage(name string) error {
 		return nil
 	}
 	logger.Println("add image")
-	client, err := t.imageserverResource.GetHTTP(nil, timeout)
+	client, err := t.getImageserverResource().GetHTTP(nil, timeout)
 	if err != nil {
 		return err
 	}
@@ -9,9 +9,10 @@ import (
 func (t *srpcType) RestoreImageFromArchive(conn *srpc.Conn,
 	request imageserver.RestoreImageFromArchiveRequest,
 	reply *imageserver.RestoreImageFromArchiveResponse) error {
-	if t.replicationMaster != "" {
+	replicationMaster := t.imageDataBase.GetReplicationMaster()
+	if replicationMaster != "" {
 		*reply = imageserver.RestoreImageFromArchiveResponse{
-			ReplicationMaster: t.replicationMaster,
+			ReplicationMaster: replicationMaster,
 		}
 		return nil
 	}
@@ -0,0 +1,29 @@
+package rpcd
+
+import (
+	"errors"
+
+	liberrors "github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func (t *srpcType) SetDirectoryRetention(conn *srpc.Conn,
+	request imageserver.SetDirectoryRetentionRequest,
+	reply *imageserver.SetDirectoryRetentionResponse) error {
+	username := conn.Username()
+	if username == "" {
+		return errors.New("no username: unauthenticated connection")
+	}
+	if err := t.checkMutability(); err != nil {
+		return err
+	}
+	t.logger.Printf("SetDirectoryRetention(%s) to: \"%s\" by %s\n",
+		request.DirectoryName, request.Policy, username)
+	err := t.imageDataBase.SetDirectoryRetention(request.DirectoryName,
+		request.Policy, conn.GetAuthInformation())
+	*reply = imageserver.SetDirectoryRetentionResponse{
+		Error: liberrors.ErrorToString(err),
+	}
+	return nil
+}
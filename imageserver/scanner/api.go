@@ -22,11 +22,14 @@ const metadataFile = ".metadata"
 
 type Config struct {
 	BaseDirectory                       string
+	FleetManagerAddress                 string // For retention.
 	LockCheckInterval                   time.Duration
 	LockLogTimeout                      time.Duration
 	MaximumExpirationDuration           time.Duration // Default: 1 day.
 	MaximumExpirationDurationPrivileged time.Duration // Default: 1 month.
-	ReplicationMaster                   string
+	MdbServerAddress                    string        // For retention.
+	ReplicationMaster                   string        // Initial master.
+	RetentionCheckInterval              time.Duration // Zero: disabled.
 }
 
 type notifiers map[<-chan string]chan<- string
@@ -38,10 +41,14 @@ type ImageDataBase struct {
 	lockWatcher *lockwatcher.LockWatcher
 	secretLock  sync.Mutex
 	secret      []byte
+	// Protected by replicationMasterLock.
+	replicationMasterLock sync.RWMutex
+	replicationMaster     string
 	sync.RWMutex
 	// Protected by main lock.
 	directoryMap    map[string]image.DirectoryMetadata
 	imageMap        map[string]*imageType // nil: write in progress.
+	index           reverseIndex
 	addNotifiers    notifiers
 	deleteNotifiers notifiers
 	mkdirNotifiers  makeDirectoryNotifiers
@@ -52,6 +59,7 @@ type ImageDataBase struct {
 
 type imageType struct {
 	computedFiles []filesystem.ComputedFile
+	contentHash   *hash.Hash // Computed when first needed.
 	fileChecksum  []byte
 	image         *image.Image
 	modifying     bool
@@ -136,6 +144,20 @@ func (imdb *ImageDataBase) FindLatestImage(
 	return imdb.findLatestImage(request)
 }
 
+// FindImagesWithObject returns the images which contain the specified object,
+// along with the pathnames of the files in each image with that object.
+func (imdb *ImageDataBase) FindImagesWithObject(hashVal hash.Hash) (
+	[]proto.ImageWithPaths, error) {
+	return imdb.findImagesWithObject(hashVal)
+}
+
+// FindImagesWithPath returns the names of the images which contain the
+// specified pathname.
+func (imdb *ImageDataBase) FindImagesWithPath(pathname string) (
+	[]string, error) {
+	return imdb.findImagesWithPath(pathname)
+}
+
 func (imdb *ImageDataBase) GetImage(name string) *image.Image {
 	return imdb.getImage(name)
 }
@@ -144,6 +166,12 @@ func (imdb *ImageDataBase) GetImageArchive(name string) ([]byte, error) {
 	return imdb.getImageArchive(name)
 }
 
+// GetImageContentHash returns a hash of the content of the specified image,
+// which may be compared with the content hash from another imageserver.
+func (imdb *ImageDataBase) GetImageContentHash(name string) (hash.Hash, error) {
+	return imdb.getImageContentHash(name)
+}
+
 func (imdb *ImageDataBase) GetImageFileChecksum(name string) []byte {
 	return imdb.getImageFileChecksum(name)
 }
@@ -155,6 +183,27 @@ func (imdb *ImageDataBase) GetImageComputedFiles(name string) (
 	return imdb.getImageComputedFiles(name)
 }
 
+// GetLastSyncedMaster returns the replication master which last sent a full
+// image list, as recorded by SetLastSyncedMaster. The empty string is
+// returned if none was recorded.
+func (imdb *ImageDataBase) GetLastSyncedMaster() (string, error) {
+	return imdb.getLastSyncedMaster()
+}
+
+// GetReplicationMaster returns the address of the current replication master.
+// The empty string is returned if this is a master.
+func (imdb *ImageDataBase) GetReplicationMaster() string {
+	return imdb.getReplicationMaster()
+}
+
+// GetRetentionReport returns a report of the images which would be deleted
+// by the retention policy for the specified directory, or for all directories
+// if dirname is empty. No images are deleted.
+func (imdb *ImageDataBase) GetRetentionReport(dirname string) (
+	[]proto.DirectoryRetention, error) {
+	return imdb.getRetentionReport(dirname)
+}
+
 func (imdb *ImageDataBase) GetUnreferencedObjectsStatistics() (uint64, uint64) {
 	return 0, 0
 }
@@ -213,6 +262,24 @@ func (imdb *ImageDataBase) RestoreImageFromArchive(
 	return imdb.restoreImageFromArchive(request, authInfo)
 }
 
+// SetDirectoryRetention sets the retention policy for the specified directory.
+func (imdb *ImageDataBase) SetDirectoryRetention(dirname string,
+	policy image.RetentionPolicy, authInfo *srpc.AuthInformation) error {
+	return imdb.setDirectoryRetention(dirname, policy, authInfo)
+}
+
+// SetLastSyncedMaster records the replication master which last sent a full
+// image list. The record persists across restarts.
+func (imdb *ImageDataBase) SetLastSyncedMaster(master string) error {
+	return imdb.setLastSyncedMaster(master)
+}
+
+// SetReplicationMaster will change the address of the replication master. An
+// empty string will make this a master, permitting changes to be made.
+func (imdb *ImageDataBase) SetReplicationMaster(replicationMaster string) {
+	imdb.setReplicationMaster(replicationMaster)
+}
+
 func (imdb *ImageDataBase) UnregisterAddNotifier(channel <-chan string) {
 	imdb.unregisterAddNotifier(channel)
 }
@@ -15,10 +15,13 @@ func (imdb *ImageDataBase) writeHtml(writer io.Writer) {
 		"Number of  <a href=\"listDirectories?output=text\">directories</a>: "+
 			"<a href=\"listDirectories\">%d</a><br>\n",
 		imdb.CountDirectories())
-	if imdb.ReplicationMaster != "" {
+	numObjects, numPaths := imdb.getIndexStatistics()
+	fmt.Fprintf(writer, "Reverse index: %d objects, %d paths<br>\n",
+		numObjects, numPaths)
+	replicationMaster := imdb.getReplicationMaster()
+	if replicationMaster != "" {
 		fmt.Fprintf(writer,
 			"Replication master: <a href=\"http://%s/\">%s</a><br>\n",
-			imdb.ReplicationMaster,
-			imdb.ReplicationMaster)
+			replicationMaster, replicationMaster)
 	}
 }
@@ -13,11 +13,13 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
@@ -25,11 +27,33 @@ import (
 	proto "github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
 
+const lastSyncedMasterFile = ".lastSyncedMaster"
+
 var (
 	errNoAccess   = errors.New("no access to image")
 	errNoAuthInfo = errors.New("no authentication information")
 )
 
+// computeContentHash computes a hash of the creation time and the file-system
+// listing of an image. Unlike the file checksum, it does not depend on the
+// encoding of the image file or on the expiration time, so it may be compared
+// between imageservers.
+func computeContentHash(img *image.Image) (hash.Hash, error) {
+	hasher := sha512.New()
+	_, err := fmt.Fprintln(hasher, img.CreatedOn.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	if img.FileSystem != nil {
+		if err := img.FileSystem.List(hasher); err != nil {
+			return hash.Hash{}, err
+		}
+	}
+	var hashVal hash.Hash
+	copy(hashVal[:], hasher.Sum(nil))
+	return hashVal, nil
+}
+
 // writeImage will write an image to the specified filename, ensuring that a
 // failure during the process will not leave a corrupted/truncated file.
 // The file checksum is returned.
@@ -98,7 +122,7 @@ func (imdb *ImageDataBase) addImage(img *image.Image, name string,
 	if err := imdb.checkPermissions(name, nil, authInfo); err != nil {
 		return err
 	}
-	exclusive := imdb.ReplicationMaster == ""
+	exclusive := imdb.getReplicationMaster() == ""
 	if err := imdb.writeImage(name, img, exclusive); err != nil {
 		if os.IsExist(err) {
 			return errors.New("cannot add previously deleted image: " + name)
@@ -392,6 +416,7 @@ func (imdb *ImageDataBase) deleteImageAndUpdateUnreferencedObjectsList(
 		return
 	}
 	delete(imdb.imageMap, name)
+	imdb.index.remove(name, img)
 	imdb.Params.ObjectServer.AdjustRefcounts(false, img)
 }
 
@@ -506,6 +531,41 @@ func (imdb *ImageDataBase) getImageComputedFiles(name string) (
 	return img.computedFiles, true
 }
 
+func (imdb *ImageDataBase) getImageContentHash(name string) (
+	hash.Hash, error) {
+	imdb.RLock()
+	imgType, _ := imdb.getImageTypeWithLock(name)
+	if imgType == nil || imgType.image == nil {
+		imdb.RUnlock()
+		return hash.Hash{}, errors.New("image not found")
+	}
+	contentHash := imgType.contentHash
+	imdb.RUnlock()
+	if contentHash != nil {
+		return *contentHash, nil
+	}
+	hashVal, err := computeContentHash(imgType.image)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	imdb.Lock()
+	imgType.contentHash = &hashVal
+	imdb.Unlock()
+	return hashVal, nil
+}
+
+func (imdb *ImageDataBase) getLastSyncedMaster() (string, error) {
+	data, err := os.ReadFile(filepath.Join(imdb.BaseDirectory,
+		lastSyncedMasterFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 func (imdb *ImageDataBase) getSecret() ([]byte, error) {
 	imdb.secretLock.Lock()
 	defer imdb.secretLock.Unlock()
@@ -764,6 +824,7 @@ func (imdb *ImageDataBase) writeImage(name string, img *image.Image,
 		fileChecksum:  fileChecksum,
 		image:         img,
 	}
+	imdb.index.add(name, img)
 	imdb.addNotifiers.sendPlain(name, "add", imdb.Logger)
 	imdb.Unlock()
 	return imdb.Params.ObjectServer.AdjustRefcounts(true, img)
@@ -816,3 +877,20 @@ func (n makeDirectoryNotifiers) sendMakeDirectory(dir image.Directory,
 		}(sendChannel)
 	}
 }
+
+func (imdb *ImageDataBase) getReplicationMaster() string {
+	imdb.replicationMasterLock.RLock()
+	defer imdb.replicationMasterLock.RUnlock()
+	return imdb.replicationMaster
+}
+
+func (imdb *ImageDataBase) setLastSyncedMaster(master string) error {
+	return os.WriteFile(filepath.Join(imdb.BaseDirectory, lastSyncedMasterFile),
+		[]byte(master+"\n"), fsutil.PublicFilePerms)
+}
+
+func (imdb *ImageDataBase) setReplicationMaster(replicationMaster string) {
+	imdb.replicationMasterLock.Lock()
+	defer imdb.replicationMasterLock.Unlock()
+	imdb.replicationMaster = replicationMaster
+}
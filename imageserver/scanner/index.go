@@ -0,0 +1,185 @@
+package scanner
+
+import (
+	"errors"
+	"path"
+	"sort"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+// reverseIndex maps objects and pathnames to the images which contain them.
+// Image names are interned so that each index entry costs only a few bytes.
+// It is protected by the main lock.
+type reverseIndex struct {
+	freeIds  []uint32
+	imageIds map[string]uint32
+	names    []string
+	objects  map[hash.Hash][]uint32
+	paths    map[string][]uint32
+}
+
+func makeReverseIndex() reverseIndex {
+	return reverseIndex{
+		imageIds: make(map[string]uint32),
+		objects:  make(map[hash.Hash][]uint32),
+		paths:    make(map[string][]uint32),
+	}
+}
+
+func appendId(ids []uint32, id uint32) []uint32 {
+	for _, existingId := range ids {
+		if existingId == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func removeId(ids []uint32, id uint32) []uint32 {
+	for index, existingId := range ids {
+		if existingId == id {
+			ids[index] = ids[len(ids)-1]
+			return ids[:len(ids)-1]
+		}
+	}
+	return ids
+}
+
+// forEachKey calls objectFunc for each object and pathFunc for each pathname
+// in the image.
+func forEachKey(img *image.Image, objectFunc func(hashVal hash.Hash),
+	pathFunc func(name string)) {
+	img.FileSystem.ForEachFile(
+		func(name string, inodeNumber uint64,
+			inode filesystem.GenericInode) error {
+			pathFunc(name)
+			if inode, ok := inode.(*filesystem.RegularInode); ok {
+				if inode.Size > 0 {
+					objectFunc(inode.Hash)
+				}
+			}
+			return nil
+		})
+}
+
+// This must be called with the lock held.
+func (index *reverseIndex) add(name string, img *image.Image) {
+	if _, ok := index.imageIds[name]; ok {
+		index.remove(name, img)
+	}
+	var id uint32
+	if length := len(index.freeIds); length > 0 {
+		id = index.freeIds[length-1]
+		index.freeIds = index.freeIds[:length-1]
+		index.names[id] = name
+	} else {
+		id = uint32(len(index.names))
+		index.names = append(index.names, name)
+	}
+	index.imageIds[name] = id
+	forEachKey(img,
+		func(hashVal hash.Hash) {
+			index.objects[hashVal] = appendId(index.objects[hashVal], id)
+		},
+		func(name string) {
+			index.paths[name] = appendId(index.paths[name], id)
+		})
+}
+
+// This must be called with the lock held.
+func (index *reverseIndex) findImagesWithObject(hashVal hash.Hash) []string {
+	return index.getNames(index.objects[hashVal])
+}
+
+// This must be called with the lock held.
+func (index *reverseIndex) findImagesWithPath(pathname string) []string {
+	return index.getNames(index.paths[pathname])
+}
+
+func (index *reverseIndex) getNames(ids []uint32) []string {
+	if len(ids) < 1 {
+		return nil
+	}
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, index.names[id])
+	}
+	sort.Strings(names)
+	return names
+}
+
+// This must be called with the lock held.
+func (index *reverseIndex) remove(name string, img *image.Image) {
+	id, ok := index.imageIds[name]
+	if !ok {
+		return
+	}
+	forEachKey(img,
+		func(hashVal hash.Hash) {
+			if ids := removeId(index.objects[hashVal], id); len(ids) > 0 {
+				index.objects[hashVal] = ids
+			} else {
+				delete(index.objects, hashVal)
+			}
+		},
+		func(name string) {
+			if ids := removeId(index.paths[name], id); len(ids) > 0 {
+				index.paths[name] = ids
+			} else {
+				delete(index.paths, name)
+			}
+		})
+	delete(index.imageIds, name)
+	index.names[id] = ""
+	index.freeIds = append(index.freeIds, id)
+}
+
+func (imdb *ImageDataBase) findImagesWithObject(hashVal hash.Hash) (
+	[]proto.ImageWithPaths, error) {
+	imdb.RLock()
+	defer imdb.RUnlock()
+	imageNames := imdb.index.findImagesWithObject(hashVal)
+	images := make([]proto.ImageWithPaths, 0, len(imageNames))
+	for _, imageName := range imageNames {
+		img := imdb.imageMap[imageName]
+		if img == nil {
+			continue
+		}
+		var paths []string
+		img.image.FileSystem.ForEachFile(
+			func(name string, inodeNumber uint64,
+				inode filesystem.GenericInode) error {
+				if inode, ok := inode.(*filesystem.RegularInode); ok {
+					if inode.Size > 0 && inode.Hash == hashVal {
+						paths = append(paths, name)
+					}
+				}
+				return nil
+			})
+		images = append(images, proto.ImageWithPaths{
+			ImageName: imageName,
+			Paths:     paths,
+		})
+	}
+	return images, nil
+}
+
+func (imdb *ImageDataBase) findImagesWithPath(pathname string) (
+	[]string, error) {
+	if !path.IsAbs(pathname) {
+		return nil, errors.New("pathname must be absolute: " + pathname)
+	}
+	imdb.RLock()
+	defer imdb.RUnlock()
+	return imdb.index.findImagesWithPath(path.Clean(pathname)), nil
+}
+
+func (imdb *ImageDataBase) getIndexStatistics() (uint, uint) {
+	imdb.RLock()
+	defer imdb.RUnlock()
+	return uint(len(imdb.index.objects)), uint(len(imdb.index.paths))
+}
@@ -46,10 +46,12 @@ func loadImageDataBase(config Config, params Params) (*ImageDataBase, error) {
 		Params:          params,
 		directoryMap:    make(map[string]image.DirectoryMetadata),
 		imageMap:        make(map[string]*imageType),
+		index:           makeReverseIndex(),
 		addNotifiers:    make(notifiers),
 		deleteNotifiers: make(notifiers),
 		mkdirNotifiers:  make(makeDirectoryNotifiers),
 	}
+	imdb.replicationMaster = config.ReplicationMaster
 	imdb.lockWatcher = lockwatcher.New(&imdb.RWMutex,
 		lockwatcher.LockWatcherOptions{
 			CheckInterval: config.LockCheckInterval,
@@ -80,6 +82,9 @@ func loadImageDataBase(config Config, params Params) (*ImageDataBase, error) {
 			imdb.CountImages(), plural, time.Since(startTime), userTime)
 		logutil.LogMemory(params.Logger, 0, "after loading")
 	}
+	if config.RetentionCheckInterval > 0 {
+		go imdb.periodicApplyRetention()
+	}
 	return imdb, nil
 }
 
@@ -157,7 +162,7 @@ func (imdb *ImageDataBase) loadFile(filename string) error {
 	}
 	img, checksum, err := imdb.loadAndVerifyFile(filename)
 	if err != nil {
-		if imdb.ReplicationMaster == "" {
+		if imdb.getReplicationMaster() == "" {
 			return err
 		}
 		e := os.Remove(pathname)
@@ -173,7 +178,7 @@ func (imdb *ImageDataBase) loadFile(filename string) error {
 		return os.Remove(pathname)
 	}
 	if err := img.VerifyObjects(imdb.Params.ObjectServer); err != nil {
-		if imdb.ReplicationMaster == "" ||
+		if imdb.getReplicationMaster() == "" ||
 			!strings.Contains(err.Error(), "not available") {
 			return fmt.Errorf("error verifying: %s: %s", filename, err)
 		}
@@ -198,6 +203,7 @@ func (imdb *ImageDataBase) loadFile(filename string) error {
 		fileChecksum:  checksum,
 		image:         img,
 	}
+	imdb.index.add(filename, img)
 	return nil
 }
 
@@ -231,7 +237,8 @@ func (imdb *ImageDataBase) fetchMissingObjects(img *image.Image,
 	logger log.DebugLogger) error {
 	imdb.objectFetchLock.Lock()
 	defer imdb.objectFetchLock.Unlock()
-	client, err := srpc.DialHTTP("tcp", imdb.ReplicationMaster, time.Minute)
+	client, err := srpc.DialHTTP("tcp", imdb.getReplicationMaster(),
+		time.Minute)
 	if err != nil {
 		return err
 	}
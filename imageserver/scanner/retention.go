@@ -0,0 +1,266 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imageserver"
+	"github.com/Cloud-Foundations/Dominator/proto/mdbserver"
+)
+
+type retentionCandidate struct {
+	createdOn time.Time
+	name      string
+}
+
+func getMdbReferencedImages(address string,
+	referencedImages map[string]struct{}) error {
+	client, err := srpc.DialHTTP("tcp", address, time.Minute)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var reply mdbserver.GetMdbResponse
+	err = client.RequestReply("MdbServer.GetMdb", mdbserver.GetMdbRequest{},
+		&reply)
+	if err != nil {
+		return err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return err
+	}
+	for _, machine := range reply.Machines {
+		if machine.RequiredImage != "" {
+			referencedImages[machine.RequiredImage] = struct{}{}
+		}
+		if machine.PlannedImage != "" {
+			referencedImages[machine.PlannedImage] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func getVmReferencedImages(address string,
+	referencedImages map[string]struct{}) error {
+	client, err := srpc.DialHTTP("tcp", address, time.Minute)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	conn, err := client.Call("FleetManager.GetUpdates")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.Encode(fm_proto.GetUpdatesRequest{MaxUpdates: 1}); err != nil {
+		return err
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	var update fm_proto.Update
+	if err := conn.Decode(&update); err != nil {
+		return err
+	}
+	if err := errors.New(update.Error); err != nil {
+		return err
+	}
+	for _, vm := range update.ChangedVMs {
+		if vm.ImageName != "" {
+			referencedImages[vm.ImageName] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// selectImagesToDelete returns the names of images in the candidate list which
+// are not retained by the policy. The candidates are sorted (newest first).
+func selectImagesToDelete(candidates []retentionCandidate,
+	policy image.RetentionPolicy, now time.Time) []string {
+	sort.Slice(candidates, func(left, right int) bool {
+		if candidates[left].createdOn.Equal(candidates[right].createdOn) {
+			return candidates[left].name > candidates[right].name
+		}
+		return candidates[left].createdOn.After(candidates[right].createdOn)
+	})
+	var imagesToDelete []string
+	for index, candidate := range candidates {
+		if uint(index) < policy.KeepLatest {
+			continue
+		}
+		if policy.KeepNewerThan > 0 &&
+			now.Sub(candidate.createdOn) < policy.KeepNewerThan {
+			continue
+		}
+		imagesToDelete = append(imagesToDelete, candidate.name)
+	}
+	return imagesToDelete
+}
+
+// applyRetention will delete images which are not retained by the retention
+// policies for their directories. If there is no source of referenced images,
+// nothing is deleted since images which are in use cannot be detected.
+func (imdb *ImageDataBase) applyRetention() error {
+	if imdb.MdbServerAddress == "" && imdb.FleetManagerAddress == "" {
+		return errors.New("neither MDB server nor Fleet Manager configured: " +
+			"not deleting images which may be in use")
+	}
+	report, err := imdb.getRetentionReport("")
+	if err != nil {
+		return err
+	}
+	for _, directory := range report {
+		for _, name := range directory.ImagesToDelete {
+			if err := imdb.deleteRetiredImage(name); err != nil {
+				imdb.Logger.Println(err)
+			}
+		}
+	}
+	return nil
+}
+
+// deleteRetiredImage will delete an image which was selected for deletion by
+// a retention policy.
+func (imdb *ImageDataBase) deleteRetiredImage(name string) error {
+	imdb.Logger.Printf("Retention policy: deleting image: %s\n", name)
+	pathname := filepath.Join(imdb.BaseDirectory, name)
+	imdb.Lock()
+	defer imdb.Unlock()
+	if img, _ := imdb.getImageWithLock(name); img == nil {
+		return nil // Deleted or being written.
+	}
+	if err := os.Truncate(pathname, 0); err != nil {
+		return err
+	}
+	imdb.deleteImageAndUpdateUnreferencedObjectsList(name)
+	imdb.deleteNotifiers.sendPlain(name, "delete", imdb.Logger)
+	return nil
+}
+
+// evaluateRetention returns the retention report for the specified directory
+// or all directories with an enabled policy if dirname is empty.
+func (imdb *ImageDataBase) evaluateRetention(dirname string,
+	referencedImages map[string]struct{}) []proto.DirectoryRetention {
+	now := time.Now()
+	imdb.RLock()
+	defer imdb.RUnlock()
+	candidatesPerDirectory := make(map[string][]retentionCandidate)
+	for name, img := range imdb.imageMap {
+		if img == nil || !img.image.ExpiresAt.IsZero() {
+			continue
+		}
+		imageDirname := filepath.Dir(name)
+		if dirname != "" && imageDirname != dirname {
+			continue
+		}
+		if !imdb.directoryMap[imageDirname].RetentionPolicy.IsEnabled() {
+			continue
+		}
+		candidatesPerDirectory[imageDirname] = append(
+			candidatesPerDirectory[imageDirname],
+			retentionCandidate{img.image.CreatedOn, name})
+	}
+	report := make([]proto.DirectoryRetention, 0, len(candidatesPerDirectory))
+	for imageDirname, candidates := range candidatesPerDirectory {
+		policy := imdb.directoryMap[imageDirname].RetentionPolicy
+		directory := proto.DirectoryRetention{
+			DirectoryName: imageDirname,
+			Policy:        policy,
+		}
+		for _, name := range selectImagesToDelete(candidates, policy, now) {
+			if _, ok := referencedImages[name]; ok {
+				directory.ImagesReferenced = append(directory.ImagesReferenced,
+					name)
+			} else {
+				directory.ImagesToDelete = append(directory.ImagesToDelete,
+					name)
+			}
+		}
+		sort.Strings(directory.ImagesReferenced)
+		sort.Strings(directory.ImagesToDelete)
+		report = append(report, directory)
+	}
+	sort.Slice(report, func(left, right int) bool {
+		return report[left].DirectoryName < report[right].DirectoryName
+	})
+	return report
+}
+
+// getReferencedImages returns the set of images referenced by the MDB and by
+// VMs. If either source is configured but cannot be queried, an error is
+// returned, so that no images are deleted based on incomplete information.
+func (imdb *ImageDataBase) getReferencedImages() (map[string]struct{}, error) {
+	referencedImages := make(map[string]struct{})
+	if imdb.MdbServerAddress != "" {
+		err := getMdbReferencedImages(imdb.MdbServerAddress, referencedImages)
+		if err != nil {
+			return nil, fmt.Errorf("error getting images from MDB: %s", err)
+		}
+	}
+	if imdb.FleetManagerAddress != "" {
+		err := getVmReferencedImages(imdb.FleetManagerAddress,
+			referencedImages)
+		if err != nil {
+			return nil, fmt.Errorf("error getting images for VMs: %s", err)
+		}
+	}
+	return referencedImages, nil
+}
+
+func (imdb *ImageDataBase) getRetentionReport(dirname string) (
+	[]proto.DirectoryRetention, error) {
+	if dirname != "" {
+		dirname = filepath.Clean(dirname)
+		if !imdb.CheckDirectory(dirname) {
+			return nil, fmt.Errorf("unknown directory: %s", dirname)
+		}
+	}
+	referencedImages, err := imdb.getReferencedImages()
+	if err != nil {
+		return nil, err
+	}
+	return imdb.evaluateRetention(dirname, referencedImages), nil
+}
+
+func (imdb *ImageDataBase) periodicApplyRetention() {
+	for range time.Tick(imdb.RetentionCheckInterval) {
+		if imdb.getReplicationMaster() != "" {
+			continue // Replicas follow the retention of their master.
+		}
+		if err := imdb.applyRetention(); err != nil {
+			imdb.Logger.Printf("Error applying retention policies: %s\n", err)
+		}
+	}
+}
+
+func (imdb *ImageDataBase) setDirectoryRetention(dirname string,
+	policy image.RetentionPolicy, authInfo *srpc.AuthInformation) error {
+	dirname = filepath.Clean(dirname)
+	imdb.Lock()
+	defer imdb.Unlock()
+	directoryMetadata, ok := imdb.directoryMap[dirname]
+	if !ok {
+		return fmt.Errorf("no metadata for: \"%s\"", dirname)
+	}
+	if authInfo == nil {
+		return errNoAuthInfo
+	}
+	if !authInfo.HaveMethodAccess {
+		if directoryMetadata.OwnerGroup == "" {
+			return errNoAccess
+		}
+		if _, ok := authInfo.GroupList[directoryMetadata.OwnerGroup]; !ok {
+			return errNoAccess
+		}
+	}
+	directoryMetadata.RetentionPolicy = policy
+	return imdb.updateDirectoryMetadata(
+		image.Directory{Name: dirname, Metadata: directoryMetadata})
+}
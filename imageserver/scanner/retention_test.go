@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+)
+
+func TestSelectImagesToDelete(t *testing.T) {
+	now := time.Now()
+	candidates := []retentionCandidate{
+		{now.Add(-4 * time.Hour), "dir/img.4"},
+		{now.Add(-1 * time.Hour), "dir/img.1"},
+		{now.Add(-3 * time.Hour), "dir/img.3"},
+		{now.Add(-2 * time.Hour), "dir/img.2"},
+		{now.Add(-4 * time.Hour), "dir/img.4a"},
+	}
+	tests := []struct {
+		name     string
+		policy   image.RetentionPolicy
+		expected []string
+	}{
+		{
+			name:     "KeepLatest",
+			policy:   image.RetentionPolicy{KeepLatest: 2},
+			expected: []string{"dir/img.3", "dir/img.4a", "dir/img.4"},
+		},
+		{
+			name:     "KeepNewerThan",
+			policy:   image.RetentionPolicy{KeepNewerThan: 150 * time.Minute},
+			expected: []string{"dir/img.3", "dir/img.4a", "dir/img.4"},
+		},
+		{
+			name: "KeepLatestAndNewerThan",
+			policy: image.RetentionPolicy{
+				KeepLatest:    3,
+				KeepNewerThan: 90 * time.Minute,
+			},
+			expected: []string{"dir/img.4a", "dir/img.4"},
+		},
+		{
+			name:     "KeepAll",
+			policy:   image.RetentionPolicy{KeepLatest: 10},
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		input := append([]retentionCandidate(nil), candidates...)
+		got := selectImagesToDelete(input, test.policy, now)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected: %v, got: %v", test.name, test.expected,
+				got)
+		}
+	}
+}
+
+func TestApplyRetentionWithoutReferenceSources(t *testing.T) {
+	imdb := &ImageDataBase{}
+	if err := imdb.applyRetention(); err == nil {
+		t.Error("retention applied without sources of referenced images")
+	}
+}
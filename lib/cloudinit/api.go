@@ -0,0 +1,66 @@
+package cloudinit
+
+import (
+	"io"
+	"net"
+
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+// PublicKeyTagPrefix is the prefix for VM tags which contain SSH public keys
+// to be provided to cloud-init. Each matching tag contains one key.
+const PublicKeyTagPrefix = "SshPublicKey"
+
+// Interface describes a VM network interface and the subnet it is on.
+type Interface struct {
+	Address hyper_proto.Address
+	Index   uint // Position in the VM (0: primary), used to name the interface.
+	Subnet  hyper_proto.Subnet
+}
+
+// Metadata contains the cloud-init metadata for a VM.
+type Metadata struct {
+	Hostname   string
+	InstanceId string
+	Interfaces []Interface
+	PublicKeys []string
+}
+
+// New computes the cloud-init metadata for a VM. The subnets are keyed by
+// subnet ID. Interfaces on unknown subnets are skipped, without changing the
+// names of later interfaces.
+func New(vmInfo hyper_proto.VmInfo,
+	subnets map[string]hyper_proto.Subnet) *Metadata {
+	return newMetadata(vmInfo, subnets)
+}
+
+// LocalIPv4 returns the primary IP address of the VM.
+func (m *Metadata) LocalIPv4() net.IP {
+	return m.localIPv4()
+}
+
+// MakeSeedImage will write a NoCloud seed ISO image (volume label "cidata")
+// to filename, containing the metadata, network configuration and user data.
+func (m *Metadata) MakeSeedImage(filename string, userData []byte) error {
+	return m.makeSeedImage(filename, userData)
+}
+
+// WriteMetaData writes the NoCloud meta-data document.
+func (m *Metadata) WriteMetaData(writer io.Writer) error {
+	return m.writeMetaData(writer)
+}
+
+// WriteNetworkConfig writes the network configuration (version 2).
+func (m *Metadata) WriteNetworkConfig(writer io.Writer) error {
+	return m.writeNetworkConfig(writer)
+}
+
+// WriteOpenStackMetaData writes the OpenStack meta_data.json document.
+func (m *Metadata) WriteOpenStackMetaData(writer io.Writer) error {
+	return m.writeOpenStackMetaData(writer)
+}
+
+// WriteOpenStackNetworkData writes the OpenStack network_data.json document.
+func (m *Metadata) WriteOpenStackNetworkData(writer io.Writer) error {
+	return m.writeOpenStackNetworkData(writer)
+}
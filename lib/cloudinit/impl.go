@@ -0,0 +1,251 @@
+package cloudinit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+// The YAML documents which cloud-init reads are written as JSON, which is a
+// subset of YAML.
+
+type ethernetType struct {
+	Addresses   []string          `json:"addresses,omitempty"`
+	Gateway4    string            `json:"gateway4,omitempty"`
+	Match       map[string]string `json:"match"`
+	Nameservers *nameserversType  `json:"nameservers,omitempty"`
+	SetName     string            `json:"set-name"`
+}
+
+type metaDataType struct {
+	InstanceId    string `json:"instance-id"`
+	LocalHostname string `json:"local-hostname"`
+}
+
+type nameserversType struct {
+	Addresses []string `json:"addresses,omitempty"`
+	Search    []string `json:"search,omitempty"`
+}
+
+type networkConfigType struct {
+	Ethernets map[string]ethernetType `json:"ethernets"`
+	Version   uint                    `json:"version"`
+}
+
+type openStackLinkType struct {
+	Id         string `json:"id"`
+	MacAddress string `json:"ethernet_mac_address"`
+	Type       string `json:"type"`
+}
+
+type openStackMetaDataType struct {
+	AvailabilityZone string            `json:"availability_zone"`
+	Hostname         string            `json:"hostname"`
+	LaunchIndex      uint              `json:"launch_index"`
+	Name             string            `json:"name"`
+	PublicKeys       map[string]string `json:"public_keys,omitempty"`
+	Uuid             string            `json:"uuid"`
+}
+
+type openStackNetworkDataType struct {
+	Links    []openStackLinkType    `json:"links"`
+	Networks []openStackNetworkType `json:"networks"`
+	Services []openStackServiceType `json:"services"`
+}
+
+type openStackNetworkType struct {
+	Id        string               `json:"id"`
+	IpAddress string               `json:"ip_address"`
+	Link      string               `json:"link"`
+	Netmask   string               `json:"netmask"`
+	Routes    []openStackRouteType `json:"routes,omitempty"`
+	Type      string               `json:"type"`
+}
+
+type openStackRouteType struct {
+	Gateway string `json:"gateway"`
+	Netmask string `json:"netmask"`
+	Network string `json:"network"`
+}
+
+type openStackServiceType struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+}
+
+func getInterfaceName(index uint) string {
+	return fmt.Sprintf("eth%d", index)
+}
+
+// makeInstanceId returns an identifier which is unique to the VM instance, and
+// stable across migrations.
+func makeInstanceId(vmInfo hyper_proto.VmInfo) string {
+	var ipAddr uint32
+	if ip := vmInfo.Address.IpAddress.To4(); ip != nil {
+		ipAddr = uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 |
+			uint32(ip[3])
+	}
+	return fmt.Sprintf("i-%08x%08x", ipAddr, uint32(vmInfo.CreatedOn.Unix()))
+}
+
+func newMetadata(vmInfo hyper_proto.VmInfo,
+	subnets map[string]hyper_proto.Subnet) *Metadata {
+	metadata := &Metadata{
+		Hostname:   vmInfo.Hostname,
+		InstanceId: makeInstanceId(vmInfo),
+	}
+	if metadata.Hostname == "" {
+		if ip := vmInfo.Address.IpAddress.To4(); ip != nil {
+			metadata.Hostname = fmt.Sprintf("ip-%d-%d-%d-%d",
+				ip[0], ip[1], ip[2], ip[3])
+		}
+	}
+	addresses := append([]hyper_proto.Address{vmInfo.Address},
+		vmInfo.SecondaryAddresses...)
+	subnetIDs := append([]string{vmInfo.SubnetId},
+		vmInfo.SecondarySubnetIDs...)
+	for index, address := range addresses {
+		if index >= len(subnetIDs) {
+			break
+		}
+		if subnet, ok := subnets[subnetIDs[index]]; ok {
+			metadata.Interfaces = append(metadata.Interfaces, Interface{
+				Address: address,
+				Index:   uint(index),
+				Subnet:  subnet,
+			})
+		}
+	}
+	tagKeys := make([]string, 0)
+	for key := range vmInfo.Tags {
+		if strings.HasPrefix(key, PublicKeyTagPrefix) {
+			tagKeys = append(tagKeys, key)
+		}
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		if value := strings.TrimSpace(vmInfo.Tags[key]); value != "" {
+			metadata.PublicKeys = append(metadata.PublicKeys, value)
+		}
+	}
+	return metadata
+}
+
+func writeJson(writer io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(value)
+}
+
+func (m *Metadata) localIPv4() net.IP {
+	if len(m.Interfaces) < 1 || m.Interfaces[0].Index != 0 {
+		return nil
+	}
+	return m.Interfaces[0].Address.IpAddress
+}
+
+func (m *Metadata) writeMetaData(writer io.Writer) error {
+	return writeJson(writer, metaDataType{
+		InstanceId:    m.InstanceId,
+		LocalHostname: m.Hostname,
+	})
+}
+
+func (m *Metadata) writeNetworkConfig(writer io.Writer) error {
+	config := networkConfigType{
+		Ethernets: make(map[string]ethernetType, len(m.Interfaces)),
+		Version:   2,
+	}
+	for _, netInterface := range m.Interfaces {
+		subnet := netInterface.Subnet
+		name := getInterfaceName(netInterface.Index)
+		prefixLength, _ := net.IPMask(subnet.IpMask).Size()
+		ethernet := ethernetType{
+			Addresses: []string{fmt.Sprintf("%s/%d",
+				netInterface.Address.IpAddress, prefixLength)},
+			Match: map[string]string{
+				"macaddress": netInterface.Address.MacAddress,
+			},
+			SetName: name,
+		}
+		if netInterface.Index == 0 {
+			ethernet.Gateway4 = subnet.IpGateway.String()
+		}
+		if len(subnet.DomainNameServers) > 0 || subnet.DomainName != "" {
+			nameservers := &nameserversType{}
+			for _, nameserver := range subnet.DomainNameServers {
+				nameservers.Addresses = append(nameservers.Addresses,
+					nameserver.String())
+			}
+			if subnet.DomainName != "" {
+				nameservers.Search = []string{subnet.DomainName}
+			}
+			ethernet.Nameservers = nameservers
+		}
+		config.Ethernets[name] = ethernet
+	}
+	return writeJson(writer, config)
+}
+
+func (m *Metadata) writeOpenStackMetaData(writer io.Writer) error {
+	metadata := openStackMetaDataType{
+		Hostname: m.Hostname,
+		Name:     m.Hostname,
+		Uuid:     m.InstanceId,
+	}
+	if len(m.PublicKeys) > 0 {
+		metadata.PublicKeys = make(map[string]string, len(m.PublicKeys))
+		for index, key := range m.PublicKeys {
+			metadata.PublicKeys[fmt.Sprintf("key%d", index)] = key
+		}
+	}
+	return writeJson(writer, metadata)
+}
+
+func (m *Metadata) writeOpenStackNetworkData(writer io.Writer) error {
+	networkData := openStackNetworkDataType{
+		Links:    make([]openStackLinkType, 0, len(m.Interfaces)),
+		Networks: make([]openStackNetworkType, 0, len(m.Interfaces)),
+		Services: make([]openStackServiceType, 0),
+	}
+	nameservers := make(map[string]struct{})
+	for _, netInterface := range m.Interfaces {
+		subnet := netInterface.Subnet
+		name := getInterfaceName(netInterface.Index)
+		networkData.Links = append(networkData.Links, openStackLinkType{
+			Id:         name,
+			MacAddress: netInterface.Address.MacAddress,
+			Type:       "phy",
+		})
+		network := openStackNetworkType{
+			Id:        fmt.Sprintf("network%d", netInterface.Index),
+			IpAddress: netInterface.Address.IpAddress.String(),
+			Link:      name,
+			Netmask:   subnet.IpMask.String(),
+			Type:      "ipv4",
+		}
+		if netInterface.Index == 0 {
+			network.Routes = []openStackRouteType{{
+				Gateway: subnet.IpGateway.String(),
+				Netmask: "0.0.0.0",
+				Network: "0.0.0.0",
+			}}
+		}
+		networkData.Networks = append(networkData.Networks, network)
+		for _, nameserver := range subnet.DomainNameServers {
+			address := nameserver.String()
+			if _, ok := nameservers[address]; ok {
+				continue
+			}
+			nameservers[address] = struct{}{}
+			networkData.Services = append(networkData.Services,
+				openStackServiceType{Address: address, Type: "dns"})
+		}
+	}
+	return writeJson(writer, networkData)
+}
@@ -0,0 +1,151 @@
+package cloudinit
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+var updateGolden = flag.Bool("updateGolden", false,
+	"If true, update the golden files in testdata")
+
+var testSubnets = makeTestSubnets(
+	hyper_proto.Subnet{
+		Id:                "primary",
+		IpGateway:         net.ParseIP("10.0.0.1"),
+		IpMask:            net.ParseIP("255.255.255.0"),
+		DomainName:        "example.com",
+		DomainNameServers: []net.IP{net.ParseIP("10.0.0.2")},
+	},
+	hyper_proto.Subnet{
+		Id:                "storage",
+		IpGateway:         net.ParseIP("10.2.0.1"),
+		IpMask:            net.ParseIP("255.255.0.0"),
+		DomainNameServers: []net.IP{net.ParseIP("10.0.0.2")},
+	},
+)
+
+// makeTestSubnets returns the subnets keyed by ID. The subnets are shrunk, as
+// the Hypervisor does when loading subnets.
+func makeTestSubnets(
+	subnets ...hyper_proto.Subnet) map[string]hyper_proto.Subnet {
+	subnetsMap := make(map[string]hyper_proto.Subnet, len(subnets))
+	for _, subnet := range subnets {
+		subnet.Shrink()
+		subnetsMap[subnet.Id] = subnet
+	}
+	return subnetsMap
+}
+
+// makeTestVmInfo returns a VM with three interfaces, where the second is on a
+// subnet which is not known.
+func makeTestVmInfo() hyper_proto.VmInfo {
+	return hyper_proto.VmInfo{
+		Address: hyper_proto.Address{
+			IpAddress:  net.ParseIP("10.0.0.10"),
+			MacAddress: "52:54:00:00:00:01",
+		},
+		CreatedOn: time.Unix(1700000000, 0),
+		Hostname:  "test-vm",
+		SecondaryAddresses: []hyper_proto.Address{
+			{
+				IpAddress:  net.ParseIP("10.1.0.10"),
+				MacAddress: "52:54:00:00:00:02",
+			},
+			{
+				IpAddress:  net.ParseIP("10.2.0.10"),
+				MacAddress: "52:54:00:00:00:03",
+			},
+		},
+		SecondarySubnetIDs: []string{"unknown", "storage"},
+		SubnetId:           "primary",
+		Tags: map[string]string{
+			PublicKeyTagPrefix + "1": "ssh-ed25519 AAAA key1",
+			PublicKeyTagPrefix + "0": " ssh-ed25519 AAAA key0 ",
+			"Name":                   "test",
+		},
+	}
+}
+
+func checkGolden(t *testing.T, filename string,
+	writeFunc func(io.Writer) error) {
+	buffer := &bytes.Buffer{}
+	if err := writeFunc(buffer); err != nil {
+		t.Fatal(err)
+	}
+	filename = filepath.Join("testdata", filename)
+	if *updateGolden {
+		if err := os.WriteFile(filename, buffer.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Errorf("%s: expected:\n%s\ngot:\n%s", filename, expected, buffer)
+	}
+}
+
+func TestNew(t *testing.T) {
+	metadata := New(makeTestVmInfo(), testSubnets)
+	if metadata.InstanceId != "i-0a00000a6553f100" {
+		t.Errorf("unexpected instance ID: %s", metadata.InstanceId)
+	}
+	if len(metadata.Interfaces) != 2 {
+		t.Fatalf("expected 2 interfaces, got: %d", len(metadata.Interfaces))
+	}
+	for position, index := range []uint{0, 2} {
+		if got := metadata.Interfaces[position].Index; got != index {
+			t.Errorf("interface %d: expected index: %d, got: %d",
+				position, index, got)
+		}
+	}
+	if ip := metadata.LocalIPv4(); !ip.Equal(net.ParseIP("10.0.0.10")) {
+		t.Errorf("unexpected local IPv4: %s", ip)
+	}
+	expectedKeys := []string{"ssh-ed25519 AAAA key0", "ssh-ed25519 AAAA key1"}
+	if len(metadata.PublicKeys) != len(expectedKeys) {
+		t.Fatalf("expected keys: %q, got: %q", expectedKeys,
+			metadata.PublicKeys)
+	}
+	for index, key := range expectedKeys {
+		if metadata.PublicKeys[index] != key {
+			t.Errorf("expected key: %q, got: %q", key,
+				metadata.PublicKeys[index])
+		}
+	}
+}
+
+func TestUnknownPrimarySubnet(t *testing.T) {
+	vmInfo := makeTestVmInfo()
+	vmInfo.SubnetId = "unknown"
+	metadata := New(vmInfo, testSubnets)
+	if ip := metadata.LocalIPv4(); ip != nil {
+		t.Errorf("local IPv4 from secondary interface: %s", ip)
+	}
+}
+
+func TestWriteNetworkConfig(t *testing.T) {
+	metadata := New(makeTestVmInfo(), testSubnets)
+	checkGolden(t, "network-config.json", metadata.WriteNetworkConfig)
+}
+
+func TestWriteOpenStackNetworkData(t *testing.T) {
+	metadata := New(makeTestVmInfo(), testSubnets)
+	checkGolden(t, "network_data.json", metadata.WriteOpenStackNetworkData)
+}
+
+func TestWriteOpenStackMetaData(t *testing.T) {
+	metadata := New(makeTestVmInfo(), testSubnets)
+	checkGolden(t, "meta_data.json", metadata.WriteOpenStackMetaData)
+}
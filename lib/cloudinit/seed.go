@@ -0,0 +1,53 @@
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+)
+
+const emptyUserData = "#cloud-config\n"
+
+func (m *Metadata) makeSeedImage(filename string, userData []byte) error {
+	tmpDir, err := ioutil.TempDir("", "cidata")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	if len(userData) < 1 {
+		userData = []byte(emptyUserData)
+	}
+	metaData := &bytes.Buffer{}
+	if err := m.writeMetaData(metaData); err != nil {
+		return err
+	}
+	networkConfig := &bytes.Buffer{}
+	if err := m.writeNetworkConfig(networkConfig); err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		"meta-data":      metaData.Bytes(),
+		"network-config": networkConfig.Bytes(),
+		"user-data":      userData,
+	}
+	for name, data := range files {
+		err := ioutil.WriteFile(filepath.Join(tmpDir, name), data,
+			fsutil.PrivateFilePerms)
+		if err != nil {
+			return err
+		}
+	}
+	tmpFilename := filename + "~"
+	cmd := exec.Command("genisoimage", "-o", tmpFilename, "-volid", "cidata",
+		"-joliet", "-rock", "-quiet", tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpFilename)
+		return fmt.Errorf("error running genisoimage: %s: %s", err, output)
+	}
+	return os.Rename(tmpFilename, filename)
+}
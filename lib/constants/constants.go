@@ -53,8 +53,28 @@ const (
 	MetadataIdentityRsaX509Cert = "/latest/dynamic/instance-identity/RSA-X.509-certificate"
 	MetadataIdentityRsaX509Key  = "/latest/dynamic/instance-identity/RSA-X.509-key"
 
+	// Hypervisor network boot files served over HTTP.
+	NetbootHttpPrefix     = "/tftpboot"
+	NetbootIpxeScriptFile = "/netboot.ipxe"
+
 	// AWS endpoints.
 	MetadataAwsInstanceType = "/latest/meta-data/instance-type"
+
+	// cloud-init EC2-compatible endpoints.
+	MetadataEc2Hostname      = "/latest/meta-data/hostname"
+	MetadataEc2InstanceId    = "/latest/meta-data/instance-id"
+	MetadataEc2LocalHostname = "/latest/meta-data/local-hostname"
+	MetadataEc2LocalIPv4     = "/latest/meta-data/local-ipv4"
+	MetadataEc2MacAddress    = "/latest/meta-data/mac"
+	MetadataEc2PublicKey     = "/latest/meta-data/public-keys/0/openssh-key"
+	// cloud-init NoCloud (nocloud-net) endpoints.
+	MetadataNoCloudMetaData      = "/nocloud/meta-data"
+	MetadataNoCloudNetworkConfig = "/nocloud/network-config"
+	MetadataNoCloudUserData      = "/nocloud/user-data"
+	// cloud-init OpenStack-compatible endpoints.
+	MetadataOpenStackMetaData    = "/openstack/latest/meta_data.json"
+	MetadataOpenStackNetworkData = "/openstack/latest/network_data.json"
+	MetadataOpenStackUserData    = "/openstack/latest/user_data"
 )
 
 var RequiredPaths = map[string]rune{
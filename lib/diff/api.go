@@ -0,0 +1,16 @@
+package diff
+
+// Line is a pair of line indices. An index of -1 means the line is not present
+// on that side.
+type Line struct {
+	Left  int
+	Right int
+}
+
+// Lines computes a line-by-line diff of left and right using the longest
+// common subsequence. The common prefix and suffix are trimmed first. If the
+// remainder is too large, it is shown as wholly replaced, so as to limit the
+// memory used.
+func Lines(left, right []string) []Line {
+	return diffLines(left, right)
+}
@@ -0,0 +1,67 @@
+package diff
+
+const maxDiffCells = 1 << 22 // Limit memory used for the LCS table.
+
+func diffLines(left, right []string) []Line {
+	var prefix, suffix int
+	for prefix < len(left) && prefix < len(right) &&
+		left[prefix] == right[prefix] {
+		prefix++
+	}
+	for suffix < len(left)-prefix && suffix < len(right)-prefix &&
+		left[len(left)-1-suffix] == right[len(right)-1-suffix] {
+		suffix++
+	}
+	lines := make([]Line, 0, len(left)+len(right))
+	for index := 0; index < prefix; index++ {
+		lines = append(lines, Line{index, index})
+	}
+	leftMiddle := left[prefix : len(left)-suffix]
+	rightMiddle := right[prefix : len(right)-suffix]
+	numLeft := len(leftMiddle)
+	numRight := len(rightMiddle)
+	if (numLeft+1)*(numRight+1) > maxDiffCells {
+		for index := 0; index < numLeft; index++ {
+			lines = append(lines, Line{prefix + index, -1})
+		}
+		for index := 0; index < numRight; index++ {
+			lines = append(lines, Line{-1, prefix + index})
+		}
+	} else {
+		// lcs[i*(numRight+1)+j] is the LCS length of leftMiddle[i:] and
+		// rightMiddle[j:].
+		width := numRight + 1
+		lcs := make([]int32, (numLeft+1)*width)
+		for i := numLeft - 1; i >= 0; i-- {
+			for j := numRight - 1; j >= 0; j-- {
+				if leftMiddle[i] == rightMiddle[j] {
+					lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+				} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+					lcs[i*width+j] = lcs[(i+1)*width+j]
+				} else {
+					lcs[i*width+j] = lcs[i*width+j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < numLeft || j < numRight {
+			if i < numLeft && j < numRight && leftMiddle[i] == rightMiddle[j] {
+				lines = append(lines, Line{prefix + i, prefix + j})
+				i++
+				j++
+			} else if j >= numRight ||
+				(i < numLeft && lcs[(i+1)*width+j] >= lcs[i*width+j+1]) {
+				lines = append(lines, Line{prefix + i, -1})
+				i++
+			} else {
+				lines = append(lines, Line{-1, prefix + j})
+				j++
+			}
+		}
+	}
+	for index := 0; index < suffix; index++ {
+		lines = append(lines, Line{len(left) - suffix + index,
+			len(right) - suffix + index})
+	}
+	return lines
+}
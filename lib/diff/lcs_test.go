@@ -0,0 +1,88 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// formatDiff returns the diff in unified style: " " for common lines, "-" for
+// lines only on the left and "+" for lines only on the right.
+func formatDiff(left, right []string, lines []Line) []string {
+	var output []string
+	for _, line := range lines {
+		switch {
+		case line.Left >= 0 && line.Right >= 0:
+			if left[line.Left] != right[line.Right] {
+				output = append(output, "!"+left[line.Left])
+			} else {
+				output = append(output, " "+left[line.Left])
+			}
+		case line.Left >= 0:
+			output = append(output, "-"+left[line.Left])
+		default:
+			output = append(output, "+"+right[line.Right])
+		}
+	}
+	return output
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     string
+		right    string
+		expected []string
+	}{
+		{"identical", "a b c", "a b c", []string{" a", " b", " c"}},
+		{"empty", "", "", nil},
+		{"added", "", "a b", []string{"+a", "+b"}},
+		{"removed", "a b", "", []string{"-a", "-b"}},
+		{"insert middle", "a c", "a b c", []string{" a", "+b", " c"}},
+		{"delete middle", "a b c", "a c", []string{" a", "-b", " c"}},
+		{"change", "a b c", "a x c", []string{" a", "-b", "+x", " c"}},
+		{
+			"moved",
+			"a b c d",
+			"b c d a",
+			[]string{"-a", " b", " c", " d", "+a"},
+		},
+	}
+	for _, test := range tests {
+		left := strings.Fields(test.left)
+		right := strings.Fields(test.right)
+		got := formatDiff(left, right, Lines(left, right))
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected: %q, got: %q",
+				test.name, test.expected, got)
+		}
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	left := make([]string, 4096)
+	right := make([]string, 4096)
+	for index := range left {
+		left[index] = "l"
+		right[index] = "r"
+	}
+	left = append([]string{"same"}, append(left, "end")...)
+	right = append([]string{"same"}, append(right, "end")...)
+	lines := Lines(left, right)
+	if len(lines) != len(left)+len(right)-2 {
+		t.Fatalf("expected: %d lines, got: %d",
+			len(left)+len(right)-2, len(lines))
+	}
+	if lines[0] != (Line{0, 0}) {
+		t.Errorf("common prefix not kept: %v", lines[0])
+	}
+	if last := lines[len(lines)-1]; last != (Line{len(left) - 1,
+		len(right) - 1}) {
+		t.Errorf("common suffix not kept: %v", last)
+	}
+	for _, line := range lines[1 : len(lines)-1] {
+		if line.Left >= 0 && line.Right >= 0 {
+			t.Fatalf("unexpected common line: %v", line)
+		}
+	}
+}
@@ -33,6 +33,35 @@ type FileGenerator interface {
 		data []byte, validUntil time.Time, err error)
 }
 
+// GenerationFailure describes a failure to generate (or validate) the data for
+// a pathname for a machine.
+type GenerationFailure struct {
+	Error            string
+	Hostname         string
+	Pathname         string
+	RetainedPrevious bool // True if previous good data are being distributed.
+	Time             time.Time
+}
+
+// GitFileVersion describes a version of the data generated for a pathname
+// from a Git repository.
+type GitFileVersion struct {
+	CommitId           string
+	FirstGenerated     time.Time
+	Hash               hash.Hash
+	Hostnames          []string // Machines currently given this version.
+	RepositoryPathname string   // Relative to the top of the repository.
+}
+
+// Validator is the interface that wraps the Validate method.
+//
+// Validate checks the candidate data generated for pathname. If an error is
+// returned the data are not distributed and the previous good data for the
+// machine (if any) are retained.
+type Validator interface {
+	Validate(pathname string, data []byte) error
+}
+
 type expiringHash struct {
 	hash       hash.Hash
 	length     uint64
@@ -43,17 +72,23 @@ type pathManager struct {
 	distributionFailed     *tricorder.CumulativeDistribution
 	distributionSuccessful *tricorder.CumulativeDistribution
 	generator              hashGenerator
+	objectServer           *memory.ObjectServer
 	rwMutex                sync.RWMutex
 	// Protected by lock.
-	machineHashes map[string]expiringHash // Key: hostname.
+	failures      map[string]GenerationFailure    // Key: hostname.
+	goodHashes    map[string]expiringHash         // Key: hostname.
+	history       map[string][]proto.HistoryEntry // Key: hostname.
+	machineHashes map[string]expiringHash         // Key: hostname.
+	validator     Validator
 }
 
 type Manager struct {
 	rwMutex sync.RWMutex
 	// Protected by lock.
-	pathManagers map[string]*pathManager // Key: pathname.
-	machineData  map[string]mdb.Machine  // Key: hostname.
-	clients      map[<-chan *proto.ServerMessage]chan<- *proto.ServerMessage
+	pathManagers    map[string]*pathManager   // Key: pathname.
+	machineData     map[string]mdb.Machine    // Key: hostname.
+	gitRepositories map[string]*gitRepository // Key: local directory.
+	clients         map[<-chan *proto.ServerMessage]chan<- *proto.ServerMessage
 	// Not protected by lock.
 	bucketer     *tricorder.Bucketer
 	objectServer *memory.ObjectServer
@@ -66,11 +101,61 @@ func New(logger log.Logger) *Manager {
 	return newManager(logger)
 }
 
+// DiffFileVersions returns a unified diff between two versions of the data
+// generated for pathname for a machine. A zero hash refers to the current
+// version. The data for secrets are never returned.
+func (m *Manager) DiffFileVersions(hostname, pathname string,
+	leftHash, rightHash hash.Hash) (string, error) {
+	return m.diffFileVersions(hostname, pathname, leftHash, rightHash)
+}
+
+// GetFileHistory returns the recent history (oldest first) of the data
+// generated for pathname for a machine.
+func (m *Manager) GetFileHistory(hostname, pathname string) (
+	[]proto.HistoryEntry, error) {
+	return m.getFileHistory(hostname, pathname)
+}
+
+// GetFileVersion returns the data for a version of the data generated for
+// pathname for a machine, which must be in the history. A zero hash refers to
+// the current version. The data for secrets are never returned.
+func (m *Manager) GetFileVersion(hostname, pathname string,
+	hashVal hash.Hash) ([]byte, proto.HistoryEntry, error) {
+	return m.getFileVersion(hostname, pathname, hashVal)
+}
+
+// GetGenerationFailures returns the current generation failures, sorted by
+// pathname and hostname.
+func (m *Manager) GetGenerationFailures() []GenerationFailure {
+	return m.getGenerationFailures()
+}
+
+// GetGitFileVersions returns the versions of the data generated for pathname,
+// which must have a Git generator registered, sorted by the time they were
+// first generated. Only versions currently given to machines are returned.
+func (m *Manager) GetGitFileVersions(pathname string) (
+	[]GitFileVersion, error) {
+	return m.getGitFileVersions(pathname)
+}
+
+// GetGitPaths returns a slice of filenames which have Git generators.
+func (m *Manager) GetGitPaths() []string {
+	return m.getGitPaths()
+}
+
 // GetRegisteredPaths returns a slice of filenames which have generators.
 func (m *Manager) GetRegisteredPaths() []string {
 	return m.getRegisteredPaths()
 }
 
+// PreviewTemplate returns the data which would be generated from the specified
+// template for a machine. If pathname is not empty, a unified diff from the
+// current data for the machine is also returned.
+func (m *Manager) PreviewTemplate(machine mdb.Machine, pathname string,
+	templateData []byte) ([]byte, string, error) {
+	return m.previewTemplate(machine, pathname, templateData)
+}
+
 // RegisterFileForPath registers a source file for a specific pathname. The
 // source file is used as the data source. If the source file changes, the data
 // are re-read.
@@ -92,6 +177,24 @@ func (m *Manager) RegisterGeneratorForPath(pathname string,
 	return m.registerDataGeneratorForPath(pathname, gen)
 }
 
+// RegisterGitFileForPath registers a generator for pathname which yields data
+// from the repositoryPathname file in a Git repository. The repository at
+// repositoryURL is cloned into localDirectory and is periodically pulled, and
+// the data are regenerated when the repository changes. Repositories are
+// shared between pathnames with the same localDirectory. Per-machine overrides
+// are selected by the specified MDB fields: for each field (in order) the
+// overrides/<field>/<value>/<repositoryPathname> file is used if it exists,
+// otherwise the default file is used. If expandTemplate is true the file is
+// used as a template, as with RegisterTemplateFileForPath. The commit ID of
+// each version generated is recorded and may be retrieved with
+// GetGitFileVersions.
+func (m *Manager) RegisterGitFileForPath(pathname string,
+	repositoryURL, localDirectory, repositoryPathname string,
+	overrideFields []string, expandTemplate bool) error {
+	return m.registerGitFileForPath(pathname, repositoryURL, localDirectory,
+		repositoryPathname, overrideFields, expandTemplate)
+}
+
 // RegisterMdbFieldDirectoryForPath registers a generator for pathname which
 // yields data from files under the specified directory where the filenames
 // are taken from the specified field of the MDB data for a machine. The data
@@ -120,6 +223,34 @@ func (m *Manager) RegisterProgrammeForPath(pathname, programmePath string) {
 	m.registerProgrammeForPath(pathname, programmePath)
 }
 
+// RegisterSecretForPath registers a generator for pathname which yields
+// secrets from the encrypted secrets store in storeDirectory (see the
+// lib/filegen/secrets package), decrypted with the private key in
+// privateKeyFile. The name of the secret is taken from the specified field of
+// the MDB data for a machine (a "Tags." prefix selects a tag). If the secret
+// does not exist, the "*" secret is used. The latest version of the secret is
+// used and the secrets are periodically reloaded every specified interval (if
+// greater than zero) so that rotated secrets are distributed. Secret data are
+// never logged.
+func (m *Manager) RegisterSecretForPath(pathname string,
+	field, storeDirectory, privateKeyFile string,
+	interval time.Duration) error {
+	return m.registerSecretForPath(pathname, field, storeDirectory,
+		privateKeyFile, interval)
+}
+
+// RegisterSyntaxValidatorForPath registers a built-in validator for the
+// specified syntax (INI, JSON or YAML) for pathname, which must already have a
+// generator registered.
+func (m *Manager) RegisterSyntaxValidatorForPath(pathname string,
+	syntax string) error {
+	if validator, err := newSyntaxValidator(syntax); err != nil {
+		return err
+	} else {
+		return m.registerValidatorForPath(pathname, validator)
+	}
+}
+
 // RegisterTemplateFileForPath registers a template file for a specific
 // pathname.
 // The template file is used to generate the data, modified by the machine data.
@@ -132,6 +263,33 @@ func (m *Manager) RegisterTemplateFileForPath(pathname string,
 		watchForUpdates)
 }
 
+// RegisterValidatorCommandForPath registers a command which will be run to
+// validate the data generated for pathname, which must already have a
+// generator registered. The first element of args is the command to run. The
+// candidate data are written to a file in a private temporary directory and
+// the pathname of that file is appended to the arguments. The data are also
+// provided on standard input. The command is run with a scrubbed environment
+// and a time limit, without network access and (if the caller is root) as the
+// nobody user. A non-zero exit status indicates that the data are invalid.
+// The output of validators is not shown for secrets.
+func (m *Manager) RegisterValidatorCommandForPath(pathname string,
+	args []string) error {
+	if validator, err := newCommandValidator(args); err != nil {
+		return err
+	} else {
+		return m.registerValidatorForPath(pathname, validator)
+	}
+}
+
+// RegisterValidatorForPath registers a Validator for pathname, which must
+// already have a generator registered. Data which fail validation are not
+// distributed and the previous good data for the machine (if any) are
+// retained.
+func (m *Manager) RegisterValidatorForPath(pathname string,
+	validator Validator) error {
+	return m.registerValidatorForPath(pathname, validator)
+}
+
 // RegisterUrlForPath registers a URL where a HTTP POST request may be sent to
 // generate data for the specified pathname. The pathname will be provided in
 // a pathname= query parameter.
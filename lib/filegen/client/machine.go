@@ -103,8 +103,13 @@ func (m *Manager) handleYieldResponse(machine *machineType,
 			panic("no source for: " + sourceName)
 		}
 		if file.Hash == zeroHash {
-			m.logger.Printf("Received zero hash for machine: %s file: %s\n",
-				machine.machine.Hostname, file.Pathname)
+			if file.Error != "" {
+				m.logger.Printf("Error generating file: %s for machine: %s: %s\n",
+					file.Pathname, machine.machine.Hostname, file.Error)
+			} else {
+				m.logger.Printf("Received zero hash for machine: %s file: %s\n",
+					machine.machine.Hostname, file.Pathname)
+			}
 			continue // No object.
 		}
 		hashes := []hash.Hash{file.Hash}
@@ -77,6 +77,8 @@ func (m *Manager) handleRequest(decoder srpc.Decoder,
 		fileInfos := make([]proto.FileInfo, 0, len(request.Pathnames))
 		for _, pathname := range request.Pathnames {
 			if fileInfo, ok := m.computeFile(request.Machine, pathname); ok {
+				// Entries with an Error (and no Hash) are included so that the
+				// client can report why the file is missing.
 				fileInfos = append(fileInfos, fileInfo)
 				m.logger.Debugf(1,
 					"handleRequest(): machine: %s, path: %s, hash: %0x\n",
@@ -140,13 +142,20 @@ func (m *Manager) computeFile(machine mdb.Machine, pathname string) (
 			return fileInfo, true
 		}
 	}
-	hashVal, length, validUntil, err := pathMgr.generate(machine, m.logger)
+	hashVal, length, validUntil, err := pathMgr.generate(machine, pathname,
+		m.logger)
 	if err != nil {
 		m.logger.Printf("Error generating path: %s for machine: %s: %s\n",
 			pathname, machine.Hostname, err)
 		m.scheduleTimer(pathname, machine.Hostname,
 			time.Now().Add(generateFailureRetryInterval))
-		return fileInfo, false
+		if fi, ok := pathMgr.getPreviousGood(machine.Hostname); ok {
+			fileInfo.Hash = fi.hash
+			fileInfo.Length = fi.length
+			return fileInfo, true
+		}
+		fileInfo.Error = err.Error()
+		return fileInfo, true
 	}
 	fileInfo.Hash = hashVal
 	fileInfo.Length = length
@@ -0,0 +1,110 @@
+package filegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/diff"
+)
+
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // One of: ' ', '-', '+'.
+	line string
+}
+
+func splitLines(data []byte) []string {
+	if len(data) < 1 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the edit operations to transform left into right.
+func diffLines(left, right []string) []diffOp {
+	lines := diff.Lines(left, right)
+	ops := make([]diffOp, 0, len(lines))
+	for _, line := range lines {
+		if line.Left < 0 {
+			ops = append(ops, diffOp{'+', right[line.Right]})
+		} else if line.Right < 0 {
+			ops = append(ops, diffOp{'-', left[line.Left]})
+		} else {
+			ops = append(ops, diffOp{' ', left[line.Left]})
+		}
+	}
+	return ops
+}
+
+// diffData returns a unified diff between left and right, or the empty string
+// if they are the same.
+func diffData(leftName, rightName string, left, right []byte) string {
+	if bytes.Equal(left, right) {
+		return ""
+	}
+	ops := diffLines(splitLines(left), splitLines(right))
+	buffer := new(bytes.Buffer)
+	fmt.Fprintf(buffer, "--- %s\n+++ %s\n", leftName, rightName)
+	leftLine := make([]int, len(ops)+1) // Line numbers before each op.
+	rightLine := make([]int, len(ops)+1)
+	for index, op := range ops {
+		leftLine[index+1] = leftLine[index]
+		rightLine[index+1] = rightLine[index]
+		if op.kind != '+' {
+			leftLine[index+1]++
+		}
+		if op.kind != '-' {
+			rightLine[index+1]++
+		}
+	}
+	for start := 0; start < len(ops); {
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start >= len(ops) {
+			break
+		}
+		// Extend the hunk until there is enough unchanged context.
+		end := start
+		for unchanged := 0; end < len(ops); end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+				if unchanged > 2*diffContextLines {
+					break
+				}
+			} else {
+				unchanged = 0
+			}
+		}
+		first := start - diffContextLines
+		if first < 0 {
+			first = 0
+		}
+		last := end
+		for last > start && ops[last-1].kind == ' ' {
+			last--
+		}
+		last += diffContextLines
+		if last > len(ops) {
+			last = len(ops)
+		}
+		fmt.Fprintf(buffer, "@@ -%d,%d +%d,%d @@\n",
+			leftLine[first]+1, leftLine[last]-leftLine[first],
+			rightLine[first]+1, rightLine[last]-rightLine[first])
+		for _, op := range ops[first:last] {
+			buffer.WriteByte(op.kind)
+			buffer.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				buffer.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = last
+	}
+	return buffer.String()
+}
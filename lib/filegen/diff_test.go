@@ -0,0 +1,42 @@
+package filegen
+
+import (
+	"testing"
+)
+
+func TestDiffData(t *testing.T) {
+	left := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n")
+	right := []byte("a\nb\nc\nd\nE\nf\ng\nh\ni\nj\nk\n")
+	expected := `--- left
++++ right
+@@ -2,9 +2,10 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+ i
+ j
++k
+`
+	if diff := diffData("left", "right", left, right); diff != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", diff, expected)
+	}
+	if diff := diffData("left", "right", left, left); diff != "" {
+		t.Errorf("expected no diff, got:\n%s", diff)
+	}
+	expected = `--- left
++++ right
+@@ -1,1 +1,1 @@
+-x
++y
+\ No newline at end of file
+`
+	if diff := diffData("left", "right", []byte("x\n"),
+		[]byte("y")); diff != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", diff, expected)
+	}
+}
@@ -18,18 +18,24 @@ type fileGenerator struct {
 	hash            *hash.Hash
 	length          uint64
 	notifierChannel chan<- string
+	sourceFile      string
 }
 
 func (m *Manager) registerFileForPath(pathname string, sourceFile string) {
 	readCloserChannel := fsutil.WatchFile(sourceFile, m.logger)
 	fgen := &fileGenerator{
 		objectServer: m.objectServer,
-		logger:       m.logger}
+		logger:       m.logger,
+		sourceFile:   sourceFile}
 	fgen.notifierChannel = m.registerHashGeneratorForPath(pathname, fgen)
 	go fgen.handleReaders(readCloserChannel)
 
 }
 
+func (fgen *fileGenerator) describeSource(hashVal hash.Hash) string {
+	return "file: " + fgen.sourceFile
+}
+
 func (fgen *fileGenerator) generate(machine mdb.Machine, logger log.Logger) (
 	hash.Hash, uint64, time.Time, error) {
 	if fgen.hash == nil {
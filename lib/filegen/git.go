@@ -0,0 +1,375 @@
+package filegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/gitutil"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/memory"
+	"github.com/Cloud-Foundations/Dominator/lib/repowatch"
+)
+
+const (
+	gitCheckInterval      = time.Minute
+	gitOverridesDirectory = "overrides"
+)
+
+type gitRepository struct {
+	directory string
+	logger    log.DebugLogger
+	url       string
+	rwMutex   sync.RWMutex
+	// Protected by lock.
+	commitId  string
+	files     map[string][]byte // Key: pathname, nil value: absent at commit.
+	notifiers []chan<- string
+	ready     bool
+}
+
+type gitFileGenerator struct {
+	fieldNames         []string
+	fields             []*mdbFieldDirectoryType
+	logger             log.DebugLogger
+	objectServer       *memory.ObjectServer
+	repository         *gitRepository
+	repositoryPathname string
+	template           bool
+	mutex              sync.Mutex
+	// Protected by lock.
+	hostVersions map[string]hash.Hash          // Key: hostname.
+	versions     map[hash.Hash]*GitFileVersion // Key: hash of data.
+	versionUsers map[hash.Hash]uint            // Key: hash of data.
+}
+
+func cleanRepositoryPathname(pathname string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+pathname), "/")
+	if cleaned == "" {
+		return "", errors.New("empty repository pathname")
+	}
+	if cleaned == gitOverridesDirectory ||
+		strings.HasPrefix(cleaned, gitOverridesDirectory+"/") {
+		return "", fmt.Errorf("repository pathname: %s is under %s/",
+			pathname, gitOverridesDirectory)
+	}
+	return cleaned, nil
+}
+
+func (m *Manager) getGitFileVersions(pathname string) (
+	[]GitFileVersion, error) {
+	m.rwMutex.RLock()
+	pathMgr, ok := m.pathManagers[pathname]
+	m.rwMutex.RUnlock()
+	if !ok {
+		return nil, errors.New("no generator registered for: " + pathname)
+	}
+	generator, ok := pathMgr.generator.(*gitFileGenerator)
+	if !ok {
+		return nil, errors.New("not a Git generator: " + pathname)
+	}
+	return generator.getVersions(), nil
+}
+
+func (m *Manager) getGitRepository(repositoryURL, localDirectory string) (
+	*gitRepository, error) {
+	localDirectory = filepath.Clean(localDirectory)
+	m.rwMutex.Lock()
+	defer m.rwMutex.Unlock()
+	if repo, ok := m.gitRepositories[localDirectory]; ok {
+		if repo.url != repositoryURL {
+			return nil, fmt.Errorf("%s already used for: %s",
+				localDirectory, repo.url)
+		}
+		return repo, nil
+	}
+	notificationChannel, err := repowatch.Watch(repositoryURL, localDirectory,
+		gitCheckInterval, path.Join("filegen/git", localDirectory), m.logger)
+	if err != nil {
+		return nil, err
+	}
+	repo := &gitRepository{
+		directory: localDirectory,
+		logger:    m.logger,
+		url:       repositoryURL,
+	}
+	m.gitRepositories[localDirectory] = repo
+	go repo.handleNotifications(notificationChannel)
+	return repo, nil
+}
+
+func (m *Manager) getGitPaths() []string {
+	m.rwMutex.RLock()
+	defer m.rwMutex.RUnlock()
+	var pathnames []string
+	for pathname, pathMgr := range m.pathManagers {
+		if _, ok := pathMgr.generator.(*gitFileGenerator); ok {
+			pathnames = append(pathnames, pathname)
+		}
+	}
+	sort.Strings(pathnames)
+	return pathnames
+}
+
+func (m *Manager) registerGitFileForPath(pathname string,
+	repositoryURL, localDirectory, repositoryPathname string,
+	overrideFields []string, expandTemplate bool) error {
+	repositoryPathname, err := cleanRepositoryPathname(repositoryPathname)
+	if err != nil {
+		return err
+	}
+	generator := &gitFileGenerator{
+		fieldNames:         overrideFields,
+		logger:             m.logger,
+		objectServer:       m.objectServer,
+		repositoryPathname: repositoryPathname,
+		template:           expandTemplate,
+		hostVersions:       make(map[string]hash.Hash),
+		versions:           make(map[hash.Hash]*GitFileVersion),
+		versionUsers:       make(map[hash.Hash]uint),
+	}
+	for _, field := range overrideFields {
+		fieldGenerator, err := makeGenerator(field)
+		if err != nil {
+			return err
+		}
+		generator.fields = append(generator.fields, fieldGenerator)
+	}
+	repo, err := m.getGitRepository(repositoryURL, localDirectory)
+	if err != nil {
+		return err
+	}
+	generator.repository = repo
+	repo.addNotifier(m.registerHashGeneratorForPath(pathname, generator))
+	return nil
+}
+
+func (repo *gitRepository) addNotifier(notifierChannel chan<- string) {
+	repo.rwMutex.Lock()
+	defer repo.rwMutex.Unlock()
+	repo.notifiers = append(repo.notifiers, notifierChannel)
+	if repo.ready {
+		select {
+		case notifierChannel <- "":
+		default:
+		}
+	}
+}
+
+func (repo *gitRepository) getCommitId() (string, bool) {
+	repo.rwMutex.RLock()
+	defer repo.rwMutex.RUnlock()
+	return repo.commitId, repo.ready
+}
+
+// readFile returns the data for the file at pathname (relative to the top of
+// the repository) as it was at the specified commit. The working tree is not
+// used, since it may be updated by a pull at any time. Files are cached until
+// the repository moves to a different commit.
+func (repo *gitRepository) readFile(commitId, pathname string) (
+	[]byte, error) {
+	repo.rwMutex.RLock()
+	data, ok := repo.files[pathname]
+	if commitId != repo.commitId {
+		ok = false
+	}
+	repo.rwMutex.RUnlock()
+	if !ok {
+		var err error
+		data, err = gitutil.ReadFileAtCommit(repo.directory, commitId,
+			pathname)
+		if errors.Is(err, os.ErrNotExist) {
+			data = nil
+		} else if err != nil {
+			return nil, err
+		} else if data == nil {
+			data = []byte{} // Distinguish an empty file from an absent file.
+		}
+		repo.rwMutex.Lock()
+		if commitId == repo.commitId {
+			if repo.files == nil {
+				repo.files = make(map[string][]byte)
+			}
+			repo.files[pathname] = data
+		}
+		repo.rwMutex.Unlock()
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%s at commit: %s: %w",
+			pathname, commitId, os.ErrNotExist)
+	}
+	return data, nil
+}
+
+// handleNotifications records the commit ID of the working tree and triggers
+// regeneration for all paths served from the repository. The commit ID is
+// read after each pull, so it may briefly lag the working tree, but every
+// pull is followed by a notification and thus a regeneration.
+func (repo *gitRepository) handleNotifications(
+	notificationChannel <-chan string) {
+	for directory := range notificationChannel {
+		commitId, err := gitutil.GetCommitIdOfRef(directory, "HEAD")
+		if err != nil {
+			repo.logger.Debugf(0, "unable to get commit ID for: %s: %s\n",
+				directory, err)
+		}
+		repo.rwMutex.Lock()
+		changed := !repo.ready || commitId != repo.commitId
+		if changed {
+			repo.files = nil
+		}
+		repo.commitId = commitId
+		repo.ready = true
+		notifiers := repo.notifiers
+		repo.rwMutex.Unlock()
+		if !changed && commitId != "" {
+			continue
+		}
+		if commitId != "" {
+			repo.logger.Printf("Git repository: %s at commit: %s\n",
+				repo.url, commitId)
+		}
+		for _, notifierChannel := range notifiers {
+			notifierChannel <- ""
+		}
+	}
+}
+
+func (g *gitFileGenerator) describeSource(hashVal hash.Hash) string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if version, ok := g.versions[hashVal]; ok {
+		return "Git commit: " + version.CommitId + " path: " +
+			version.RepositoryPathname
+	}
+	return "Git"
+}
+
+// findSource returns the pathname (relative to the top of the repository) and
+// data of the file at the commit to use for the machine. Overrides are searched
+// in the order of the fields, followed by the default file.
+func (g *gitFileGenerator) findSource(machine mdb.Machine, commitId string) (
+	string, []byte, error) {
+	for index, field := range g.fields {
+		value := field.getFieldValue(machine)
+		if value == "" || value == "." || value == ".." ||
+			strings.ContainsRune(value, '/') {
+			continue
+		}
+		sourcePathname := path.Join(gitOverridesDirectory,
+			g.fieldNames[index], value, g.repositoryPathname)
+		data, err := g.repository.readFile(commitId, sourcePathname)
+		if err == nil {
+			return sourcePathname, data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", nil, err
+		}
+	}
+	data, err := g.repository.readFile(commitId, g.repositoryPathname)
+	if err != nil {
+		return "", nil, err
+	}
+	return g.repositoryPathname, data, nil
+}
+
+func (g *gitFileGenerator) generate(machine mdb.Machine,
+	logger log.Logger) (
+	hash.Hash, uint64, time.Time, error) {
+	commitId, ready := g.repository.getCommitId()
+	if !ready {
+		return hash.Hash{}, 0, time.Time{},
+			errors.New("no repository data yet")
+	}
+	if commitId == "" {
+		return hash.Hash{}, 0, time.Time{},
+			errors.New("unknown repository commit")
+	}
+	sourcePathname, data, err := g.findSource(machine, commitId)
+	if err != nil {
+		return hash.Hash{}, 0, time.Time{}, err
+	}
+	if g.template {
+		tmpl, err := template.New(sourcePathname).Parse(string(data))
+		if err != nil {
+			return hash.Hash{}, 0, time.Time{}, err
+		}
+		buffer := new(bytes.Buffer)
+		if err := tmpl.Execute(buffer, machine); err != nil {
+			return hash.Hash{}, 0, time.Time{}, err
+		}
+		data = buffer.Bytes()
+	}
+	length := uint64(len(data))
+	hashVal, _, err := g.objectServer.AddObject(bytes.NewReader(data), length,
+		nil)
+	if err != nil {
+		return hash.Hash{}, 0, time.Time{}, err
+	}
+	g.recordVersion(machine.Hostname, hashVal, commitId, sourcePathname)
+	return hashVal, length, time.Time{}, nil
+}
+
+func (g *gitFileGenerator) getVersions() []GitFileVersion {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	hostnamesPerVersion := make(map[hash.Hash][]string)
+	for hostname, hashVal := range g.hostVersions {
+		hostnamesPerVersion[hashVal] = append(hostnamesPerVersion[hashVal],
+			hostname)
+	}
+	versions := make([]GitFileVersion, 0, len(g.versions))
+	for hashVal, version := range g.versions {
+		versionCopy := *version
+		versionCopy.Hostnames = hostnamesPerVersion[hashVal]
+		sort.Strings(versionCopy.Hostnames)
+		versions = append(versions, versionCopy)
+	}
+	sort.Slice(versions, func(left, right int) bool {
+		return versions[left].FirstGenerated.Before(
+			versions[right].FirstGenerated)
+	})
+	return versions
+}
+
+// recordVersion records the version generated for a machine. Versions which
+// are no longer generated for any machine are forgotten.
+func (g *gitFileGenerator) recordVersion(hostname string, hashVal hash.Hash,
+	commitId, sourcePathname string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if oldHashVal, ok := g.hostVersions[hostname]; ok {
+		if oldHashVal == hashVal {
+			return
+		}
+		if g.versionUsers[oldHashVal] <= 1 {
+			delete(g.versionUsers, oldHashVal)
+			delete(g.versions, oldHashVal)
+		} else {
+			g.versionUsers[oldHashVal]--
+		}
+	}
+	g.hostVersions[hostname] = hashVal
+	g.versionUsers[hashVal]++
+	if _, ok := g.versions[hashVal]; ok {
+		return
+	}
+	g.versions[hashVal] = &GitFileVersion{
+		CommitId:           commitId,
+		FirstGenerated:     time.Now(),
+		Hash:               hashVal,
+		RepositoryPathname: sourcePathname,
+	}
+	g.logger.Debugf(0, "New version: %x of: %s from commit: %s\n",
+		hashVal, sourcePathname, commitId)
+}
@@ -0,0 +1,163 @@
+package filegen
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/memory"
+)
+
+func writeTestFile(t *testing.T, topdir, pathname, data string) {
+	filename := filepath.Join(topdir, pathname)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runTestGit(t *testing.T, topdir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test",
+		"-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = topdir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", args[0], err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func commitTestFiles(t *testing.T, topdir string) string {
+	runTestGit(t, topdir, "add", "-A")
+	runTestGit(t, topdir, "commit", "-q", "-m", "test")
+	return runTestGit(t, topdir, "rev-parse", "HEAD")
+}
+
+func readTestObject(t *testing.T, objectServer *memory.ObjectServer,
+	hashVal hash.Hash) string {
+	_, reader, err := objectServer.GetObject(hashVal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGitFileOverrides(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip(err)
+	}
+	topdir := t.TempDir()
+	runTestGit(t, topdir, "init", "-q")
+	writeTestFile(t, topdir, "etc/app.conf", "default {{.Hostname}}\n")
+	writeTestFile(t, topdir, "overrides/Hostname/host1/etc/app.conf",
+		"host1 override\n")
+	writeTestFile(t, topdir, "overrides/Tags.Group/web/etc/app.conf",
+		"web {{.Hostname}}\n")
+	commitId := commitTestFiles(t, topdir)
+	// Changes to the working tree which are not at the recorded commit (such
+	// as a pull in progress) must be ignored.
+	writeTestFile(t, topdir, "etc/app.conf", "pulling\n")
+	writeTestFile(t, topdir, "overrides/Hostname/host3/etc/app.conf",
+		"pulling\n")
+	logger := testlogger.New(t)
+	generator := &gitFileGenerator{
+		fieldNames:   []string{"Hostname", "Tags.Group"},
+		logger:       logger,
+		objectServer: memory.NewObjectServer(),
+		repository: &gitRepository{
+			commitId:  commitId,
+			directory: topdir,
+			logger:    logger,
+			ready:     true,
+		},
+		repositoryPathname: "etc/app.conf",
+		template:           true,
+		hostVersions:       make(map[string]hash.Hash),
+		versions:           make(map[hash.Hash]*GitFileVersion),
+		versionUsers:       make(map[hash.Hash]uint),
+	}
+	for _, field := range generator.fieldNames {
+		fieldGenerator, err := makeGenerator(field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generator.fields = append(generator.fields, fieldGenerator)
+	}
+	tests := []struct {
+		machine mdb.Machine
+		data    string
+	}{
+		{mdb.Machine{Hostname: "host1",
+			Tags: map[string]string{"Group": "web"}}, "host1 override\n"},
+		{mdb.Machine{Hostname: "host2",
+			Tags: map[string]string{"Group": "web"}}, "web host2\n"},
+		{mdb.Machine{Hostname: "host3",
+			Tags: map[string]string{"Group": "../.."}}, "default host3\n"},
+	}
+	for _, test := range tests {
+		hashVal, _, _, err := generator.generate(test.machine, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := readTestObject(t, generator.objectServer, hashVal)
+		if data != test.data {
+			t.Errorf("%s: got: %q, expected: %q",
+				test.machine.Hostname, data, test.data)
+		}
+	}
+	versions := generator.getVersions()
+	if len(versions) != len(tests) {
+		t.Fatalf("got %d versions, expected %d", len(versions), len(tests))
+	}
+	for _, version := range versions {
+		if version.CommitId != commitId {
+			t.Errorf("got commit ID: %s", version.CommitId)
+		}
+		if len(version.Hostnames) != 1 {
+			t.Errorf("got hostnames: %v", version.Hostnames)
+		}
+	}
+	if _, err := cleanRepositoryPathname("overrides/x"); err == nil {
+		t.Error("expected error for pathname under overrides")
+	}
+	// Move host1 to a new version at a new commit: the old version is no
+	// longer used by any machine and is forgotten.
+	writeTestFile(t, topdir, "overrides/Hostname/host1/etc/app.conf",
+		"host1 new override\n")
+	os.Remove(filepath.Join(topdir, "overrides/Hostname/host3/etc/app.conf"))
+	writeTestFile(t, topdir, "etc/app.conf", "default {{.Hostname}}\n")
+	generator.repository.commitId = commitTestFiles(t, topdir)
+	generator.repository.files = nil
+	hashVal, _, _, err := generator.generate(tests[0].machine, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := readTestObject(t, generator.objectServer, hashVal)
+	if data != "host1 new override\n" {
+		t.Errorf("got: %q", data)
+	}
+	versions = generator.getVersions()
+	if len(versions) != len(tests) {
+		t.Fatalf("got %d versions, expected %d", len(versions), len(tests))
+	}
+	for _, version := range versions {
+		if version.RepositoryPathname ==
+			"overrides/Hostname/host1/etc/app.conf" &&
+			version.CommitId != generator.repository.commitId {
+			t.Errorf("old version not forgotten: %v", version)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package filegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	liberrors "github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/filegenerator"
+)
+
+const maxHistoryEntries = 16 // Per machine and pathname.
+
+func (m *Manager) diffFileVersions(hostname, pathname string,
+	leftHash, rightHash hash.Hash) (string, error) {
+	leftData, leftEntry, err := m.getFileVersion(hostname, pathname,
+		leftHash)
+	if err != nil {
+		return "", err
+	}
+	rightData, rightEntry, err := m.getFileVersion(hostname, pathname,
+		rightHash)
+	if err != nil {
+		return "", err
+	}
+	return diffData(
+		fmt.Sprintf("%s %x %s", pathname, leftEntry.Hash,
+			leftEntry.Time.Format(time.RFC3339)),
+		fmt.Sprintf("%s %x %s", pathname, rightEntry.Hash,
+			rightEntry.Time.Format(time.RFC3339)),
+		leftData, rightData), nil
+}
+
+func (m *Manager) getFileHistory(hostname, pathname string) (
+	[]proto.HistoryEntry, error) {
+	pathMgr, err := m.getPathManager(pathname)
+	if err != nil {
+		return nil, err
+	}
+	pathMgr.rwMutex.RLock()
+	defer pathMgr.rwMutex.RUnlock()
+	history := pathMgr.history[hostname]
+	if len(history) < 1 {
+		return nil, fmt.Errorf("no history for: %s on: %s", pathname, hostname)
+	}
+	return append([]proto.HistoryEntry(nil), history...), nil
+}
+
+func (m *Manager) getFileVersion(hostname, pathname string,
+	hashVal hash.Hash) ([]byte, proto.HistoryEntry, error) {
+	history, err := m.getFileHistory(hostname, pathname)
+	if err != nil {
+		return nil, proto.HistoryEntry{}, err
+	}
+	pathMgr, err := m.getPathManager(pathname)
+	if err != nil {
+		return nil, proto.HistoryEntry{}, err
+	}
+	if pathMgr.isSecret() {
+		return nil, proto.HistoryEntry{},
+			errors.New("data for secrets are not available")
+	}
+	var entry proto.HistoryEntry
+	if hashVal == (hash.Hash{}) {
+		entry = history[len(history)-1]
+	} else {
+		found := false
+		for _, entry = range history {
+			if entry.Hash == hashVal {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, proto.HistoryEntry{},
+				fmt.Errorf("version: %x not in history", hashVal)
+		}
+	}
+	data, err := m.readObject(entry.Hash)
+	if err != nil {
+		return nil, proto.HistoryEntry{}, err
+	}
+	return data, entry, nil
+}
+
+func (m *Manager) getPathManager(pathname string) (*pathManager, error) {
+	m.rwMutex.RLock()
+	pathMgr, ok := m.pathManagers[pathname]
+	m.rwMutex.RUnlock()
+	if !ok {
+		return nil, errors.New("no generator registered for: " + pathname)
+	}
+	return pathMgr, nil
+}
+
+func (m *Manager) previewTemplate(machine mdb.Machine, pathname string,
+	templateData []byte) ([]byte, string, error) {
+	tmpl, err := template.New("previewTemplate").Parse(string(templateData))
+	if err != nil {
+		return nil, "", err
+	}
+	buffer := new(bytes.Buffer)
+	if err := tmpl.Execute(buffer, machine); err != nil {
+		return nil, "", err
+	}
+	if pathname == "" {
+		return buffer.Bytes(), "", nil
+	}
+	currentData, currentEntry, err := m.getFileVersion(machine.Hostname,
+		pathname, hash.Hash{})
+	if err != nil {
+		return nil, "", err
+	}
+	diff := diffData(
+		fmt.Sprintf("%s %x (current)", pathname, currentEntry.Hash),
+		pathname+" (preview)",
+		currentData, buffer.Bytes())
+	return buffer.Bytes(), diff, nil
+}
+
+func (m *Manager) readObject(hashVal hash.Hash) ([]byte, error) {
+	_, reader, err := m.objectServer.GetObject(hashVal)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// isSecret returns true if the data for the path must not be shown.
+func (p *pathManager) isSecret() bool {
+	if wrapper, ok := p.generator.(*hashGeneratorWrapper); ok {
+		_, ok := wrapper.dataGenerator.(*secretGenerator)
+		return ok
+	}
+	return false
+}
+
+// recordHistory must be called with the lock held.
+func (p *pathManager) recordHistory(hostname string, hashVal hash.Hash,
+	length uint64) {
+	history := p.history[hostname]
+	if len(history) > 0 && history[len(history)-1].Hash == hashVal {
+		return
+	}
+	var source string
+	if describer, ok := p.generator.(sourceDescriber); ok {
+		source = describer.describeSource(hashVal)
+	}
+	if len(history) >= maxHistoryEntries {
+		history = append(history[:0:0], history[1:]...)
+	}
+	p.history[hostname] = append(history, proto.HistoryEntry{
+		Hash:   hashVal,
+		Length: length,
+		Source: source,
+		Time:   time.Now(),
+	})
+}
+
+func (t *rpcType) DiffFileVersions(conn *srpc.Conn,
+	request proto.DiffFileVersionsRequest,
+	reply *proto.DiffFileVersionsResponse) error {
+	diff, err := t.manager.DiffFileVersions(request.Hostname,
+		request.Pathname, request.LeftHash, request.RightHash)
+	*reply = proto.DiffFileVersionsResponse{
+		Diff:  diff,
+		Error: liberrors.ErrorToString(err),
+	}
+	return nil
+}
+
+func (t *rpcType) GetFileHistory(conn *srpc.Conn,
+	request proto.GetFileHistoryRequest,
+	reply *proto.GetFileHistoryResponse) error {
+	entries, err := t.manager.GetFileHistory(request.Hostname,
+		request.Pathname)
+	*reply = proto.GetFileHistoryResponse{
+		Entries: entries,
+		Error:   liberrors.ErrorToString(err),
+	}
+	return nil
+}
+
+func (t *rpcType) GetFileVersion(conn *srpc.Conn,
+	request proto.GetFileVersionRequest,
+	reply *proto.GetFileVersionResponse) error {
+	data, entry, err := t.manager.GetFileVersion(request.Hostname,
+		request.Pathname, request.Hash)
+	*reply = proto.GetFileVersionResponse{
+		Data:  data,
+		Entry: entry,
+		Error: liberrors.ErrorToString(err),
+	}
+	return nil
+}
+
+func (t *rpcType) PreviewTemplate(conn *srpc.Conn,
+	request proto.PreviewTemplateRequest,
+	reply *proto.PreviewTemplateResponse) error {
+	var machine mdb.Machine
+	if request.Machine != nil {
+		machine = *request.Machine
+	} else {
+		t.manager.rwMutex.RLock()
+		var ok bool
+		machine, ok = t.manager.machineData[request.Hostname]
+		t.manager.rwMutex.RUnlock()
+		if !ok {
+			reply.Error = "unknown machine: " + request.Hostname
+			return nil
+		}
+	}
+	data, diff, err := t.manager.PreviewTemplate(machine, request.Pathname,
+		request.Template)
+	*reply = proto.PreviewTemplateResponse{
+		Data:  data,
+		Diff:  diff,
+		Error: liberrors.ErrorToString(err),
+	}
+	return nil
+}
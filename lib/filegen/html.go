@@ -9,4 +9,14 @@ func (m *Manager) writeHtml(writer io.Writer) {
 	fmt.Fprintf(writer,
 		"Number of generated files: <a href=\"listGenerators\">%d</a><br>\n",
 		len(m.pathManagers))
+	if pathnames := m.getGitPaths(); len(pathnames) > 0 {
+		fmt.Fprintf(writer,
+			"Files from Git: <a href=\"showGitFileVersions\">%d</a><br>\n",
+			len(pathnames))
+	}
+	if failures := m.getGenerationFailures(); len(failures) > 0 {
+		fmt.Fprintf(writer,
+			"Generation failures: <a href=\"showGenerationFailures\">%d</a><br>\n",
+			len(failures))
+	}
 }
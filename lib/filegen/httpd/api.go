@@ -28,6 +28,9 @@ func StartServer(portNum uint, manager *filegen.Manager, daemon bool) error {
 	myState := &state{manager}
 	html.HandleFunc("/", myState.statusHandler)
 	html.HandleFunc("/listGenerators", myState.listGeneratorsHandler)
+	html.HandleFunc("/showGitFileVersions", myState.showGitFileVersionsHandler)
+	html.HandleFunc("/showGenerationFailures",
+		myState.showGenerationFailuresHandler)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
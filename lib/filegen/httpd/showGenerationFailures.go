@@ -0,0 +1,43 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+)
+
+func (s *state) showGenerationFailuresHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	fmt.Fprintln(writer, "<title>filegen server generation failures</title>")
+	fmt.Fprintln(writer, "<body>")
+	failures := s.manager.GetGenerationFailures()
+	if len(failures) < 1 {
+		fmt.Fprintln(writer, "No generation failures<br>")
+		fmt.Fprintln(writer, "</body>")
+		return
+	}
+	tw, _ := html.NewTableWriter(writer, true, "Pathname", "Hostname", "Age",
+		"Distributing", "Error")
+	for _, failure := range failures {
+		distributing := "nothing"
+		if failure.RetainedPrevious {
+			distributing = "previous"
+		}
+		tw.WriteRow("", "",
+			failure.Pathname,
+			failure.Hostname,
+			format.Duration(time.Since(failure.Time)),
+			distributing,
+			template.HTMLEscapeString(failure.Error),
+		)
+	}
+	tw.Close()
+	fmt.Fprintln(writer, "</body>")
+}
@@ -0,0 +1,53 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+)
+
+func (s *state) showGitFileVersionsHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	pathname := req.URL.Query().Get("pathname")
+	if pathname == "" {
+		fmt.Fprintln(writer, "<title>filegen server files from Git</title>")
+		fmt.Fprintln(writer, "<body>")
+		for _, pathname := range s.manager.GetGitPaths() {
+			fmt.Fprintf(writer,
+				"<a href=\"showGitFileVersions?pathname=%s\">%s</a><br>\n",
+				template.URLQueryEscaper(pathname),
+				template.HTMLEscapeString(pathname))
+		}
+		fmt.Fprintln(writer, "</body>")
+		return
+	}
+	versions, err := s.manager.GetGitFileVersions(pathname)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	fmt.Fprintf(writer, "<title>Versions of %s</title>\n",
+		template.HTMLEscapeString(pathname))
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintf(writer, "<h3>Versions of %s</h3>\n",
+		template.HTMLEscapeString(pathname))
+	tw, _ := html.NewTableWriter(writer, true, "Hash", "Commit ID",
+		"Repository Path", "First Generated", "Machines")
+	for _, version := range versions {
+		tw.WriteRow("", "",
+			fmt.Sprintf("%x", version.Hash),
+			version.CommitId,
+			template.HTMLEscapeString(version.RepositoryPathname),
+			version.FirstGenerated.Format("2006-01-02 15:04:05 MST"),
+			strings.Join(version.Hostnames, " "),
+		)
+	}
+	tw.Close()
+	fmt.Fprintln(writer, "</body>")
+}
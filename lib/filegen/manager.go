@@ -19,10 +19,11 @@ func newManager(logger log.Logger) *Manager {
 		bucketer: tricorder.NewGeometricBucketer(0.01, 1e5),
 		clients: make(
 			map[<-chan *proto.ServerMessage]chan<- *proto.ServerMessage),
-		logger:       debuglogger.Upgrade(logger),
-		machineData:  make(map[string]mdb.Machine),
-		objectServer: memory.NewObjectServer(),
-		pathManagers: make(map[string]*pathManager),
+		gitRepositories: make(map[string]*gitRepository),
+		logger:          debuglogger.Upgrade(logger),
+		machineData:     make(map[string]mdb.Machine),
+		objectServer:    memory.NewObjectServer(),
+		pathManagers:    make(map[string]*pathManager),
 	}
 	m.registerMdbGeneratorForPath("/etc/mdb.json")
 	srpc.RegisterNameWithOptions("FileGenerator", &rpcType{m},
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
@@ -15,6 +16,10 @@ func (m *Manager) registerMdbGeneratorForPath(pathname string) {
 	close(m.registerDataGeneratorForPath(pathname, jsonType{}))
 }
 
+func (jsonType) describeSource(hashVal hash.Hash) string {
+	return "MDB"
+}
+
 func (jsonType) Generate(machine mdb.Machine, logger log.Logger) (
 	[]byte, time.Time, error) {
 	buffer := new(bytes.Buffer)
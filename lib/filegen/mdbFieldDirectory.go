@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
 )
@@ -65,15 +66,20 @@ func (m *Manager) registerMdbFieldDirectoryForPath(pathname string,
 	return nil
 }
 
-func (g *mdbFieldDirectoryType) Generate(machine mdb.Machine,
-	logger log.Logger) ([]byte, time.Time, error) {
-	var fieldValue string
+func (g *mdbFieldDirectoryType) describeSource(hashVal hash.Hash) string {
+	return "directory: " + g.directory
+}
+
+func (g *mdbFieldDirectoryType) getFieldValue(machine mdb.Machine) string {
 	if g.tagKey != "" {
-		fieldValue = machine.Tags[g.tagKey]
-	} else {
-		fieldValue = reflect.ValueOf(machine).FieldByIndex(
-			g.index).String()
+		return machine.Tags[g.tagKey]
 	}
+	return reflect.ValueOf(machine).FieldByIndex(g.index).String()
+}
+
+func (g *mdbFieldDirectoryType) Generate(machine mdb.Machine,
+	logger log.Logger) ([]byte, time.Time, error) {
+	fieldValue := g.getFieldValue(machine)
 	if fieldValue == "" {
 		fieldValue = "*"
 	}
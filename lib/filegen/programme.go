@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
@@ -36,6 +37,10 @@ func (m *Manager) registerProgrammeForPath(pathname, programmePath string) {
 	progGen.notifierChannel = m.RegisterGeneratorForPath(pathname, progGen)
 }
 
+func (progGen *programmeGenerator) describeSource(hashVal hash.Hash) string {
+	return "programme: " + progGen.programmePath
+}
+
 func (progGen *programmeGenerator) Generate(machine mdb.Machine,
 	logger log.Logger) ([]byte, time.Time, error) {
 	cmd := exec.Command(progGen.programmePath, progGen.pathname)
@@ -2,6 +2,9 @@ package filegen
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"path"
 	"sort"
 	"time"
@@ -20,6 +23,12 @@ type hashGenerator interface {
 		hashVal hash.Hash, length uint64, validUntil time.Time, err error)
 }
 
+// sourceDescriber may be implemented by generators (or the FileGenerator
+// wrapped by a hashGeneratorWrapper) to describe the source of generated data.
+type sourceDescriber interface {
+	describeSource(hashVal hash.Hash) string
+}
+
 type hashGeneratorWrapper struct {
 	dataGenerator FileGenerator
 	objectServer  *memory.ObjectServer
@@ -43,8 +52,13 @@ func (m *Manager) registerHashGeneratorForPath(pathname string,
 	pathMgr := &pathManager{
 		distributionFailed:     m.bucketer.NewCumulativeDistribution(),
 		distributionSuccessful: m.bucketer.NewCumulativeDistribution(),
+		failures:               make(map[string]GenerationFailure),
 		generator:              gen,
-		machineHashes:          make(map[string]expiringHash)}
+		goodHashes:             make(map[string]expiringHash),
+		history:                make(map[string][]proto.HistoryEntry),
+		machineHashes:          make(map[string]expiringHash),
+		objectServer:           m.objectServer,
+	}
 	err := tricorder.RegisterMetric(
 		path.Join("filegen/generators", pathname, "failed-durations"),
 		pathMgr.distributionFailed,
@@ -78,7 +92,7 @@ func (m *Manager) processPathDataInvalidations(pathname string,
 			m.rwMutex.RLock()
 			for _, mdbData := range m.machineData {
 				hashVal, length, validUntil, err := pathMgr.generate(mdbData,
-					m.logger)
+					pathname, m.logger)
 				if err != nil {
 					continue
 				}
@@ -103,7 +117,7 @@ func (m *Manager) processPathDataInvalidations(pathname string,
 			mdbData := m.machineData[machineName]
 			m.rwMutex.RUnlock()
 			hashVal, length, validUntil, err := pathMgr.generate(mdbData,
-				m.logger)
+				pathname, m.logger)
 			if err != nil {
 				continue
 			}
@@ -140,7 +154,8 @@ func (m *Manager) scheduleTimer(pathname string, hostname string,
 		if !ok {
 			return
 		}
-		hashVal, length, validUntil, err := pathMgr.generate(mdbData, m.logger)
+		hashVal, length, validUntil, err := pathMgr.generate(mdbData,
+			pathname, m.logger)
 		if err != nil {
 			m.logger.Printf("Error regenerating path: %s for machine: %s: %s\n",
 				pathname, hostname, err)
@@ -167,6 +182,30 @@ func (m *Manager) scheduleTimer(pathname string, hostname string,
 	})
 }
 
+func (m *Manager) getGenerationFailures() []GenerationFailure {
+	m.rwMutex.RLock()
+	pathManagers := make([]*pathManager, 0, len(m.pathManagers))
+	for _, pathMgr := range m.pathManagers {
+		pathManagers = append(pathManagers, pathMgr)
+	}
+	m.rwMutex.RUnlock()
+	var failures []GenerationFailure
+	for _, pathMgr := range pathManagers {
+		pathMgr.rwMutex.RLock()
+		for _, failure := range pathMgr.failures {
+			failures = append(failures, failure)
+		}
+		pathMgr.rwMutex.RUnlock()
+	}
+	sort.Slice(failures, func(left, right int) bool {
+		if failures[left].Pathname != failures[right].Pathname {
+			return failures[left].Pathname < failures[right].Pathname
+		}
+		return failures[left].Hostname < failures[right].Hostname
+	})
+	return failures
+}
+
 func (m *Manager) getRegisteredPaths() []string {
 	m.rwMutex.RLock()
 	pathnames := make([]string, 0, len(m.pathManagers))
@@ -178,17 +217,104 @@ func (m *Manager) getRegisteredPaths() []string {
 	return pathnames
 }
 
-func (p *pathManager) generate(machine mdb.Machine, logger log.Logger) (
-	hash.Hash, uint64, time.Time, error) {
+func (m *Manager) registerValidatorForPath(pathname string,
+	validator Validator) error {
+	m.rwMutex.RLock()
+	pathMgr, ok := m.pathManagers[pathname]
+	m.rwMutex.RUnlock()
+	if !ok {
+		return errors.New("no generator registered for: " + pathname)
+	}
+	pathMgr.rwMutex.Lock()
+	defer pathMgr.rwMutex.Unlock()
+	if pathMgr.validator != nil {
+		return errors.New("validator already registered for: " + pathname)
+	}
+	pathMgr.validator = validator
+	return nil
+}
+
+// generate will generate and validate the data for a machine. Failures are
+// recorded.
+func (p *pathManager) generate(machine mdb.Machine, pathname string,
+	logger log.Logger) (hash.Hash, uint64, time.Time, error) {
 	startTime := time.Now()
 	hashVal, length, expiresAt, err := p.generator.generate(machine, logger)
+	if err == nil {
+		err = p.validate(pathname, hashVal)
+	}
 	timeTaken := time.Since(startTime)
 	if err == nil {
 		p.distributionSuccessful.Add(timeTaken)
 	} else {
 		p.distributionFailed.Add(timeTaken)
 	}
-	return hashVal, length, expiresAt, err
+	p.rwMutex.Lock()
+	defer p.rwMutex.Unlock()
+	if err != nil {
+		_, retained := p.goodHashes[machine.Hostname]
+		p.failures[machine.Hostname] = GenerationFailure{
+			Error:            err.Error(),
+			Hostname:         machine.Hostname,
+			Pathname:         pathname,
+			RetainedPrevious: retained && p.validator != nil,
+			Time:             time.Now(),
+		}
+		return hashVal, length, expiresAt, err
+	}
+	delete(p.failures, machine.Hostname)
+	p.recordHistory(machine.Hostname, hashVal, length)
+	if p.validator != nil {
+		p.goodHashes[machine.Hostname] = expiringHash{hashVal, length,
+			expiresAt}
+	}
+	return hashVal, length, expiresAt, nil
+}
+
+// getPreviousGood returns the previous data which passed validation for the
+// machine. Previous data are only retained for paths with a validator.
+func (p *pathManager) getPreviousGood(hostname string) (expiringHash, bool) {
+	p.rwMutex.RLock()
+	defer p.rwMutex.RUnlock()
+	if p.validator == nil {
+		return expiringHash{}, false
+	}
+	fi, ok := p.goodHashes[hostname]
+	return fi, ok
+}
+
+func (p *pathManager) validate(pathname string, hashVal hash.Hash) error {
+	p.rwMutex.RLock()
+	validator := p.validator
+	p.rwMutex.RUnlock()
+	if validator == nil {
+		return nil
+	}
+	_, reader, err := p.objectServer.GetObject(hashVal)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	if err := validator.Validate(pathname, data); err != nil {
+		if p.isSecret() {
+			// Validators may quote the data (such as the offending line), so
+			// the details are not shown.
+			return errors.New("validation failed: details withheld for secret")
+		}
+		return fmt.Errorf("validation failed: %s", err)
+	}
+	return nil
+}
+
+func (g *hashGeneratorWrapper) describeSource(hashVal hash.Hash) string {
+	if describer, ok := g.dataGenerator.(sourceDescriber); ok {
+		return describer.describeSource(hashVal)
+	}
+	return ""
 }
 
 func (g *hashGeneratorWrapper) generate(machine mdb.Machine,
@@ -0,0 +1,71 @@
+package filegen
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+const nobodyId = 65534
+
+var (
+	unprivilegedIdsOnce sync.Once
+	unprivilegedUid     uint32
+	unprivilegedGid     uint32
+)
+
+// getUnprivilegedIds returns the user and group IDs of the nobody user.
+func getUnprivilegedIds() (uint32, uint32) {
+	unprivilegedIdsOnce.Do(func() {
+		unprivilegedUid = nobodyId
+		unprivilegedGid = nobodyId
+		usr, err := user.Lookup("nobody")
+		if err != nil {
+			return
+		}
+		if uid, err := strconv.ParseUint(usr.Uid, 10, 32); err == nil {
+			unprivilegedUid = uint32(uid)
+		}
+		if gid, err := strconv.ParseUint(usr.Gid, 10, 32); err == nil {
+			unprivilegedGid = uint32(gid)
+		}
+	})
+	return unprivilegedUid, unprivilegedGid
+}
+
+// sandboxCommand configures cmd to run in private IPC, network and PID
+// namespaces, so it has no network access and cannot signal other processes.
+// If the caller is root, the command is run as the nobody user with no
+// supplementary groups and dirname and filename are given to that user.
+// Otherwise the command is run in a user namespace as the caller.
+func sandboxCommand(cmd *exec.Cmd, dirname, filename string) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWIPC | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWPID,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if uid := os.Geteuid(); uid != 0 {
+		gid := os.Getegid()
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{
+			{ContainerID: uid, HostID: uid, Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{
+			{ContainerID: gid, HostID: gid, Size: 1}}
+		return nil
+	}
+	uid, gid := getUnprivilegedIds()
+	for _, pathname := range []string{dirname, filename} {
+		if err := os.Chown(pathname, int(uid), int(gid)); err != nil {
+			return err
+		}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    uid,
+		Gid:    gid,
+		Groups: []uint32{},
+	}
+	return nil
+}
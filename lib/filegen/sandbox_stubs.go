@@ -0,0 +1,12 @@
+//go:build !linux
+
+package filegen
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func sandboxCommand(cmd *exec.Cmd, dirname, filename string) error {
+	return syscall.ENOTSUP
+}
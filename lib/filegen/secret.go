@@ -0,0 +1,58 @@
+package filegen
+
+import (
+	"os"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filegen/secrets"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+)
+
+type secretGenerator struct {
+	field *mdbFieldDirectoryType
+	store *secrets.Store
+}
+
+func (m *Manager) registerSecretForPath(pathname string,
+	field, storeDirectory, privateKeyFile string,
+	interval time.Duration) error {
+	fieldGenerator, err := makeGenerator(field)
+	if err != nil {
+		return err
+	}
+	store, err := secrets.Open(storeDirectory, privateKeyFile)
+	if err != nil {
+		return err
+	}
+	generator := &secretGenerator{field: fieldGenerator, store: store}
+	notifierChannel := m.RegisterGeneratorForPath(pathname, generator)
+	if interval <= 0 {
+		close(notifierChannel)
+		return nil
+	}
+	go sendNotifications(notifierChannel, interval)
+	return nil
+}
+
+func (g *secretGenerator) describeSource(hashVal hash.Hash) string {
+	return "secret"
+}
+
+// Generate will never include secret data in errors, since errors are logged.
+func (g *secretGenerator) Generate(machine mdb.Machine,
+	logger log.Logger) ([]byte, time.Time, error) {
+	name := g.field.getFieldValue(machine)
+	if name == "" {
+		name = "*"
+	}
+	data, _, err := g.store.Get(name)
+	if err != nil && os.IsNotExist(err) && name != "*" {
+		data, _, err = g.store.Get("*")
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, time.Time{}, nil
+}
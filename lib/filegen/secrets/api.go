@@ -0,0 +1,73 @@
+/*
+Package secrets implements an encrypted store for secrets.
+
+Each secret is stored in a directory named after the secret. Each version of
+the secret is stored in a file named after the version number, encrypted with
+a NaCl anonymous sealed box using the public key of the store (the
+"public.key" file in the store directory). Adding or rotating secrets only
+requires the public key. Reading secrets requires the private key, which
+should only be readable by the server which distributes the secrets. Versions
+are never modified, so the previous versions are available for auditing and
+rollback.
+*/
+package secrets
+
+import (
+	"time"
+)
+
+const PublicKeyFile = "public.key"
+
+type Store struct {
+	directory  string
+	privateKey *[32]byte // nil: store is write-only.
+	publicKey  *[32]byte
+}
+
+type SecretInfo struct {
+	Name     string
+	Versions []VersionInfo // Sorted by version number.
+}
+
+type VersionInfo struct {
+	Version      uint64
+	CreationTime time.Time
+}
+
+// GenerateKey will generate a new key pair for the store in directory. The
+// private key is written to the file privateKeyFile, which must not exist. The
+// directory is created if needed.
+func GenerateKey(directory, privateKeyFile string) error {
+	return generateKey(directory, privateKeyFile)
+}
+
+// Open will open the store in directory. If privateKeyFile is the empty
+// string, the store is opened write-only and secrets may only be added and
+// listed.
+func Open(directory, privateKeyFile string) (*Store, error) {
+	return openStore(directory, privateKeyFile)
+}
+
+// Add will add a new secret with the specified name and data. The new secret
+// will have version 1. It is an error if the secret already exists.
+func (s *Store) Add(name string, data []byte) error {
+	return s.add(name, data)
+}
+
+// Get will return the data and version for the latest version of the named
+// secret. If the secret does not exist, the error will satisfy os.IsNotExist.
+func (s *Store) Get(name string) ([]byte, uint64, error) {
+	return s.get(name)
+}
+
+// List will return information about all the secrets in the store, sorted by
+// name. The secret data are not read.
+func (s *Store) List() ([]SecretInfo, error) {
+	return s.list()
+}
+
+// Rotate will add a new version of the named secret, which must already exist.
+// It returns the new version number.
+func (s *Store) Rotate(name string, data []byte) (uint64, error) {
+	return s.rotate(name, data)
+}
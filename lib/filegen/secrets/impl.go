@@ -0,0 +1,208 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// Secrets are encrypted, so the directories and files may be readable by all.
+// Only the private key must be protected.
+const (
+	dirPerms = 0755
+	keyPerms = 0400
+)
+
+func checkName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") ||
+		strings.ContainsRune(name, '/') || name == PublicKeyFile {
+		return fmt.Errorf("invalid secret name: \"%s\"", name)
+	}
+	return nil
+}
+
+func generateKey(directory, privateKeyFile string) error {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, dirPerms); err != nil {
+		return err
+	}
+	publicKeyFile := filepath.Join(directory, PublicKeyFile)
+	if _, err := os.Stat(publicKeyFile); err == nil {
+		return errors.New(publicKeyFile + " already exists")
+	}
+	err = fsutil.CopyToFileExclusive(privateKeyFile, keyPerms,
+		bytes.NewReader(privateKey[:]), uint64(len(privateKey)))
+	if err != nil {
+		return err
+	}
+	return fsutil.CopyToFileExclusive(publicKeyFile, fsutil.PublicFilePerms,
+		bytes.NewReader(publicKey[:]), uint64(len(publicKey)))
+}
+
+func readKey(filename string) (*[32]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	if len(data) != len(key) {
+		return nil, fmt.Errorf("%s: bad key length: %d", filename, len(data))
+	}
+	copy(key[:], data)
+	return &key, nil
+}
+
+func openStore(directory, privateKeyFile string) (*Store, error) {
+	publicKey, err := readKey(filepath.Join(directory, PublicKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	store := &Store{directory: directory, publicKey: publicKey}
+	if privateKeyFile != "" {
+		if store.privateKey, err = readKey(privateKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func (s *Store) add(name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	dirname := filepath.Join(s.directory, name)
+	if err := os.Mkdir(dirname, dirPerms); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("secret: %s already exists", name)
+		}
+		return err
+	}
+	return s.writeVersion(name, 1, data)
+}
+
+func (s *Store) get(name string) ([]byte, uint64, error) {
+	if err := checkName(name); err != nil {
+		return nil, 0, err
+	}
+	if s.privateKey == nil {
+		return nil, 0, errors.New("store is write-only")
+	}
+	versions, err := s.listVersions(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(versions) < 1 {
+		return nil, 0, fmt.Errorf("secret: %s has no versions", name)
+	}
+	version := versions[len(versions)-1].Version
+	filename := filepath.Join(s.directory, name,
+		strconv.FormatUint(version, 10))
+	sealed, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, ok := box.OpenAnonymous(nil, sealed, s.publicKey, s.privateKey)
+	if !ok {
+		// Do not include any of the data in the error.
+		return nil, 0, fmt.Errorf("secret: %s version: %d: decryption failed",
+			name, version)
+	}
+	return data, version, nil
+}
+
+func (s *Store) list() ([]SecretInfo, error) {
+	names, err := fsutil.ReadDirnames(s.directory, false)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	secrets := make([]SecretInfo, 0, len(names))
+	for _, name := range names {
+		if checkName(name) != nil {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(s.directory, name)); err != nil {
+			return nil, err
+		} else if !fi.IsDir() {
+			continue
+		}
+		versions, err := s.listVersions(name)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, SecretInfo{Name: name, Versions: versions})
+	}
+	return secrets, nil
+}
+
+func (s *Store) listVersions(name string) ([]VersionInfo, error) {
+	dirname := filepath.Join(s.directory, name)
+	file, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	fileInfos, err := file.Readdir(-1)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]VersionInfo, 0, len(fileInfos))
+	for _, fi := range fileInfos {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		version, err := strconv.ParseUint(fi.Name(), 10, 64)
+		if err != nil || version < 1 {
+			continue
+		}
+		versions = append(versions, VersionInfo{
+			Version:      version,
+			CreationTime: fi.ModTime(),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	return versions, nil
+}
+
+func (s *Store) rotate(name string, data []byte) (uint64, error) {
+	if err := checkName(name); err != nil {
+		return 0, err
+	}
+	versions, err := s.listVersions(name)
+	if err != nil {
+		return 0, err
+	}
+	var version uint64 = 1
+	if len(versions) > 0 {
+		version = versions[len(versions)-1].Version + 1
+	}
+	if err := s.writeVersion(name, version, data); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *Store) writeVersion(name string, version uint64, data []byte) error {
+	sealed, err := box.SealAnonymous(nil, data, s.publicKey, rand.Reader)
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(s.directory, name,
+		strconv.FormatUint(version, 10))
+	return fsutil.CopyToFileExclusive(filename, fsutil.PublicFilePerms,
+		bytes.NewReader(sealed), uint64(len(sealed)))
+}
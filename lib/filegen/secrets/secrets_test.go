@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddGetRotate(t *testing.T) {
+	topdir := t.TempDir()
+	directory := filepath.Join(topdir, "store")
+	keyFile := filepath.Join(topdir, "private.key")
+	if err := GenerateKey(directory, keyFile); err != nil {
+		t.Fatal(err)
+	}
+	writeStore, err := Open(directory, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStore.Add("host1", []byte("secret1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStore.Add("host1", []byte("secret1")); err == nil {
+		t.Error("duplicate add did not fail")
+	}
+	if _, _, err := writeStore.Get("host1"); err == nil {
+		t.Error("get from write-only store did not fail")
+	}
+	if version, err := writeStore.Rotate("host1",
+		[]byte("secret2")); err != nil {
+		t.Fatal(err)
+	} else if version != 2 {
+		t.Errorf("rotated version: %d != 2", version)
+	}
+	if _, err := writeStore.Rotate("host2", []byte("x")); err == nil {
+		t.Error("rotate of missing secret did not fail")
+	}
+	readStore, err := Open(directory, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, version, err := readStore.Get("host1"); err != nil {
+		t.Fatal(err)
+	} else if version != 2 {
+		t.Errorf("version: %d != 2", version)
+	} else if !bytes.Equal(data, []byte("secret2")) {
+		t.Error("data mismatch")
+	}
+	if _, _, err := readStore.Get("host2"); !os.IsNotExist(err) {
+		t.Errorf("missing secret error: %v", err)
+	}
+	if secrets, err := readStore.List(); err != nil {
+		t.Fatal(err)
+	} else if len(secrets) != 1 || len(secrets[0].Versions) != 2 {
+		t.Errorf("unexpected list: %v", secrets)
+	}
+	if err := writeStore.Add("../escape", nil); err == nil {
+		t.Error("invalid name accepted")
+	}
+}
@@ -2,10 +2,13 @@ package filegen
 
 import (
 	"bytes"
+	"crypto/sha512"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"sync"
 	"text/template"
 	"time"
 
@@ -19,15 +22,20 @@ import (
 type templateGenerator struct {
 	objectServer    *memory.ObjectServer
 	logger          log.Logger
-	template        *template.Template
+	templateFile    string
 	notifierChannel chan<- string
+	rwMutex         sync.RWMutex
+	// Protected by lock.
+	template        *template.Template
+	templateVersion string
 }
 
 func (m *Manager) registerTemplateFileForPath(pathname string,
 	templateFile string, watchForUpdates bool) error {
 	tgen := &templateGenerator{
 		objectServer: m.objectServer,
-		logger:       m.logger}
+		logger:       m.logger,
+		templateFile: templateFile}
 	tgen.notifierChannel = m.registerHashGeneratorForPath(pathname, tgen)
 	if watchForUpdates {
 		readCloserChannel := fsutil.WatchFile(templateFile, m.logger)
@@ -44,14 +52,24 @@ func (m *Manager) registerTemplateFileForPath(pathname string,
 	return nil
 }
 
+func (tgen *templateGenerator) describeSource(hashVal hash.Hash) string {
+	tgen.rwMutex.RLock()
+	defer tgen.rwMutex.RUnlock()
+	return "template: " + tgen.templateFile + " version: " +
+		tgen.templateVersion
+}
+
 func (tgen *templateGenerator) generate(machine mdb.Machine,
 	logger log.Logger) (
 	hash.Hash, uint64, time.Time, error) {
-	if tgen.template == nil {
+	tgen.rwMutex.RLock()
+	tmpl := tgen.template
+	tgen.rwMutex.RUnlock()
+	if tmpl == nil {
 		return hash.Hash{}, 0, time.Time{}, errors.New("no template data yet")
 	}
 	buffer := new(bytes.Buffer)
-	if err := tgen.template.Execute(buffer, machine); err != nil {
+	if err := tmpl.Execute(buffer, machine); err != nil {
 		return hash.Hash{}, 0, time.Time{}, err
 	}
 	length := uint64(buffer.Len())
@@ -79,7 +97,10 @@ func (tgen *templateGenerator) handleReadCloser(
 	if err != nil {
 		return err
 	}
+	tgen.rwMutex.Lock()
 	tgen.template = tmpl
+	tgen.templateVersion = fmt.Sprintf("%x", sha512.Sum512(data))[:16]
+	tgen.rwMutex.Unlock()
 	tgen.notifierChannel <- ""
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
@@ -30,6 +31,10 @@ func (m *Manager) registerUrlForPath(pathname, URL string) {
 	urlGen.notifierChannel = m.RegisterGeneratorForPath(pathname, urlGen)
 }
 
+func (urlGen *urlGenerator) describeSource(hashVal hash.Hash) string {
+	return "URL"
+}
+
 func (urlGen *urlGenerator) Generate(machine mdb.Machine,
 	logger log.Logger) ([]byte, time.Time, error) {
 	requestBody := &bytes.Buffer{}
@@ -20,11 +20,15 @@ type configType struct {
 var configs = map[string]configType{
 	"DynamicTemplateFile": {1, 1, dynamicTemplateFileGenerator},
 	"File":                {1, 1, fileGenerator},
+	"GitFile":             {3, -1, gitFileGenerator},
+	"GitTemplateFile":     {3, -1, gitTemplateFileGenerator},
 	"MdbFieldDirectory":   {2, 3, mdbFieldDirectoryGenerator},
 	"MDB":                 {0, 0, mdbGenerator},
 	"Programme":           {1, 1, programmeGenerator},
+	"Secret":              {3, 4, secretGenerator},
 	"StaticTemplateFile":  {1, 1, staticTemplateFileGenerator},
 	"URL":                 {1, 1, urlGenerator},
+	"Validate":            {1, -1, validateGenerator},
 }
 
 func loadConfiguration(manager *filegen.Manager, filename string) error {
@@ -67,6 +71,18 @@ func fileGenerator(manager *filegen.Manager, pathname string,
 	return nil
 }
 
+func gitFileGenerator(manager *filegen.Manager, pathname string,
+	params []string) error {
+	return manager.RegisterGitFileForPath(pathname, params[0], params[1],
+		params[2], params[3:], false)
+}
+
+func gitTemplateFileGenerator(manager *filegen.Manager, pathname string,
+	params []string) error {
+	return manager.RegisterGitFileForPath(pathname, params[0], params[1],
+		params[2], params[3:], true)
+}
+
 func mdbFieldDirectoryGenerator(manager *filegen.Manager, pathname string,
 	params []string) error {
 	interval := time.Duration(-1)
@@ -96,6 +112,23 @@ func programmeGenerator(manager *filegen.Manager, pathname string,
 	return nil
 }
 
+func secretGenerator(manager *filegen.Manager, pathname string,
+	params []string) error {
+	interval := time.Duration(-1)
+	if len(params) > 3 {
+		duration, err := time.ParseDuration(params[3])
+		if err != nil {
+			return err
+		}
+		interval = duration
+		if interval < time.Second {
+			interval = time.Second
+		}
+	}
+	return manager.RegisterSecretForPath(pathname, params[0], params[1],
+		params[2], interval)
+}
+
 func staticTemplateFileGenerator(manager *filegen.Manager, pathname string,
 	params []string) error {
 	return manager.RegisterTemplateFileForPath(pathname, params[0], false)
@@ -106,3 +139,18 @@ func urlGenerator(manager *filegen.Manager, pathname string,
 	manager.RegisterUrlForPath(pathname, params[0])
 	return nil
 }
+
+func validateGenerator(manager *filegen.Manager, pathname string,
+	params []string) error {
+	if strings.EqualFold(params[0], "Command") {
+		if len(params) < 2 {
+			return fmt.Errorf("no validator command for: %s", pathname)
+		}
+		return manager.RegisterValidatorCommandForPath(pathname, params[1:])
+	}
+	if len(params) > 1 {
+		return fmt.Errorf("too many params for %s validator for: %s",
+			params[0], pathname)
+	}
+	return manager.RegisterSyntaxValidatorForPath(pathname, params[0])
+}
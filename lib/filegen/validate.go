@@ -0,0 +1,148 @@
+package filegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	maxValidatorOutput = 256
+	validatorTimeout   = 30 * time.Second
+)
+
+type commandValidator struct {
+	args []string
+}
+
+type syntaxValidator func(data []byte) error
+
+func newCommandValidator(args []string) (Validator, error) {
+	if len(args) < 1 {
+		return nil, errors.New("no validator command specified")
+	}
+	return &commandValidator{args: args}, nil
+}
+
+func newSyntaxValidator(syntax string) (Validator, error) {
+	switch strings.ToUpper(syntax) {
+	case "INI":
+		return syntaxValidator(checkIni), nil
+	case "JSON":
+		return syntaxValidator(checkJson), nil
+	case "YAML":
+		return syntaxValidator(checkYaml), nil
+	}
+	return nil, errors.New("unknown syntax: " + syntax)
+}
+
+func checkIni(data []byte) error {
+	for index, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' || len(line) < 3 {
+				return fmt.Errorf("line %d: bad section header", index+1)
+			}
+			continue
+		}
+		if key, _, ok := strings.Cut(line, "="); ok {
+			if strings.TrimSpace(key) == "" {
+				return fmt.Errorf("line %d: empty key", index+1)
+			}
+			continue
+		}
+		return fmt.Errorf("line %d: expected section or key=value", index+1)
+	}
+	return nil
+}
+
+func checkJson(data []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %s", err)
+	}
+	if decoder.More() {
+		return errors.New("invalid JSON: trailing data")
+	}
+	return nil
+}
+
+func checkYaml(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document interface{}
+		if err := decoder.Decode(&document); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("invalid YAML: %s", err)
+		}
+	}
+}
+
+// Validate runs the command with the candidate data written to a file in a
+// private temporary directory, which is the working directory. The pathname of
+// the file is appended to the arguments and the data are also provided on
+// standard input. The environment is scrubbed, a time limit is enforced and
+// the command is run in a sandbox (see sandboxCommand).
+func (v *commandValidator) Validate(pathname string, data []byte) error {
+	dirname, err := os.MkdirTemp("", "filegen-validate.")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dirname)
+	filename := filepath.Join(dirname, filepath.Base(pathname))
+	if err := os.WriteFile(filename, data, 0400); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), validatorTimeout)
+	defer cancel()
+	args := make([]string, 0, len(v.args))
+	args = append(args, v.args[1:]...)
+	args = append(args, filename)
+	cmd := exec.CommandContext(ctx, v.args[0], args...)
+	cmd.Dir = dirname
+	cmd.Env = []string{
+		"FILEGEN_PATHNAME=" + pathname,
+		"HOME=" + dirname,
+		"PATH=/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin",
+		"TMPDIR=" + dirname,
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	if err := sandboxCommand(cmd, dirname, filename); err != nil {
+		return fmt.Errorf("error creating sandbox: %s", err)
+	}
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s: timed out after %s", v.args[0], validatorTimeout)
+	}
+	message := strings.TrimSpace(string(output))
+	if len(message) > maxValidatorOutput {
+		message = message[:maxValidatorOutput] + "..."
+	}
+	if message == "" {
+		return fmt.Errorf("%s: %s", v.args[0], err)
+	}
+	return fmt.Errorf("%s: %s: %s", v.args[0], err, message)
+}
+
+func (v syntaxValidator) Validate(pathname string, data []byte) error {
+	return v(data)
+}
@@ -0,0 +1,100 @@
+package filegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/memory"
+)
+
+func TestSyntaxValidators(t *testing.T) {
+	tests := []struct {
+		syntax string
+		data   string
+		valid  bool
+	}{
+		{"INI", "[section]\nkey = value\n; comment\n", true},
+		{"INI", "[section\nkey = value\n", false},
+		{"INI", "just some text\n", false},
+		{"JSON", `{"key": ["value", 1]}`, true},
+		{"JSON", `{"key": "value"`, false},
+		{"JSON", `{"key": "value"} {}`, false},
+		{"YAML", "key: value\nlist:\n  - one\n  - two # comment\n", true},
+		{"YAML", "text: |\n  line one\n    indented\nother: [1, 2]\n", true},
+		{"YAML", "quoted: 'it''s'\n", true},
+		{"YAML", "key:\n\t- value\n", false},
+		{"YAML", "a:\n    b: 1\n  c: 2\n", false},
+		{"YAML", "list: [1, 2\n", false},
+		{"YAML", "key: \"unterminated\n", false},
+		{"YAML", "one: 1\n---\ntwo: 2\n", true},
+		{"YAML", "one: 1\n---\ntwo: [\n", false},
+		{"YAML", "key: value\nkey: other\n", false},
+	}
+	for _, test := range tests {
+		validator, err := newSyntaxValidator(test.syntax)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = validator.Validate("file", []byte(test.data))
+		if test.valid && err != nil {
+			t.Errorf("%s: %q: unexpected error: %s", test.syntax, test.data,
+				err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: %q: expected error", test.syntax, test.data)
+		}
+	}
+	if _, err := newSyntaxValidator("XML"); err == nil {
+		t.Error("expected error for unknown syntax")
+	}
+}
+
+func TestCommandValidator(t *testing.T) {
+	script := `grep -q good "$0" && grep -q good && ` +
+		`test "$(id -u)" != 0 && ! ping -c 1 -W 1 127.0.0.1 >/dev/null 2>&1`
+	validator, err := newCommandValidator([]string{"/bin/sh", "-c", script})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = validator.Validate("/etc/file", []byte("good\n"))
+	if err != nil && strings.HasPrefix(err.Error(), "error creating sandbox") {
+		t.Skip(err)
+	} else if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := validator.Validate("/etc/file", []byte("bad\n")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSecretValidationError(t *testing.T) {
+	objectServer := memory.NewObjectServer()
+	hashVal, _, err := objectServer.AddObject(bytes.NewReader(testData),
+		uint64(len(testData)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoValidator := syntaxValidator(func(data []byte) error {
+		return fmt.Errorf("bad line: %s", data)
+	})
+	plainPathMgr := &pathManager{
+		generator:    &hashGeneratorWrapper{dataGenerator: &testGenerator{}},
+		objectServer: objectServer,
+		validator:    echoValidator,
+	}
+	err = plainPathMgr.validate("plain", hashVal)
+	if err == nil || !strings.Contains(err.Error(), string(testData)) {
+		t.Errorf("expected data in error, got: %v", err)
+	}
+	secretPathMgr := &pathManager{
+		generator:    &hashGeneratorWrapper{dataGenerator: &secretGenerator{}},
+		objectServer: objectServer,
+		validator:    echoValidator,
+	}
+	if err := secretPathMgr.validate("secret", hashVal); err == nil {
+		t.Error("expected error")
+	} else if strings.Contains(err.Error(), string(testData)) {
+		t.Errorf("secret data leaked in error: %s", err)
+	}
+}
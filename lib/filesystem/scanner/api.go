@@ -33,10 +33,11 @@ type cpuLimitedHasher struct {
 }
 
 type FileSystem struct {
-	params      Params
-	dev         uint64
-	inodeNumber uint64
-	fsLock      sync.Locker // Protect everything below.
+	params         Params
+	dev            uint64
+	dirtyAncestors map[string]struct{} // Ancestors of DirtyDirectories.
+	inodeNumber    uint64
+	fsLock         sync.Locker // Protect everything below.
 	filesystem.FileSystem
 	hashWaiters map[uint64]<-chan struct{} // Key: inode number.
 }
@@ -49,6 +50,11 @@ type Params struct {
 	CheckScanDisableRequest func() bool
 	Hasher                  Hasher
 	OldFS                   *FileSystem
+	// If DirtyDirectories is not nil and OldFS is provided, only the listed
+	// directories (and their ancestors) are scanned. All other directories are
+	// copied from OldFS. Regular files with unchanged metadata in scanned
+	// directories are not rehashed.
+	DirtyDirectories map[string]struct{}
 }
 
 func MakeRegularInode(stat *wsyscall.Stat_t) *filesystem.RegularInode {
@@ -48,6 +48,33 @@ func makeSpecialInode(stat *wsyscall.Stat_t) *filesystem.SpecialInode {
 	return &inode
 }
 
+// makeDirtyAncestors returns the set of all ancestors of the dirty
+// directories.
+func makeDirtyAncestors(
+	dirtyDirectories map[string]struct{}) map[string]struct{} {
+	ancestors := map[string]struct{}{"/": {}}
+	for dirname := range dirtyDirectories {
+		for dirname != "/" && dirname != "." && dirname != "" {
+			dirname = path.Dir(dirname)
+			if _, ok := ancestors[dirname]; ok {
+				break
+			}
+			ancestors[dirname] = struct{}{}
+		}
+	}
+	return ancestors
+}
+
+func regularInodeUnchanged(inode *filesystem.RegularInode,
+	stat *wsyscall.Stat_t) bool {
+	return inode.Mode == filesystem.FileMode(stat.Mode) &&
+		inode.Uid == stat.Uid &&
+		inode.Gid == stat.Gid &&
+		inode.MtimeSeconds == int64(stat.Mtim.Sec) &&
+		inode.MtimeNanoSeconds == int32(stat.Mtim.Nsec) &&
+		inode.Size == uint64(stat.Size)
+}
+
 func scanFileSystem(params Params) (*FileSystem, error) {
 	if params.CheckScanDisableRequest != nil &&
 		params.CheckScanDisableRequest() {
@@ -84,6 +111,10 @@ func scanFileSystem(params Params) (*FileSystem, error) {
 	var oldDirectory *filesystem.DirectoryInode
 	if params.OldFS != nil && params.OldFS.InodeTable != nil {
 		oldDirectory = &params.OldFS.DirectoryInode
+		if params.DirtyDirectories != nil {
+			fileSystem.dirtyAncestors = makeDirtyAncestors(
+				params.DirtyDirectories)
+		}
 	}
 	err, _ := fileSystem.scanDirectory(&fileSystem.FileSystem.DirectoryInode,
 		oldDirectory, "/")
@@ -101,8 +132,78 @@ func scanFileSystem(params Params) (*FileSystem, error) {
 	return &fileSystem, nil
 }
 
+// copyDirectory copies the entries of an unchanged directory (and all the
+// inodes below it) from the old file-system.
+func (fs *FileSystem) copyDirectory(directory *filesystem.DirectoryInode,
+	oldDirectory *filesystem.DirectoryInode) {
+	directory.EntryList = oldDirectory.EntryList
+	for _, dirent := range oldDirectory.EntryList {
+		inode := dirent.Inode()
+		fs.fsLock.Lock()
+		if _, ok := fs.InodeTable[dirent.InodeNumber]; !ok {
+			fs.InodeTable[dirent.InodeNumber] = inode
+		}
+		fs.fsLock.Unlock()
+		if inode, ok := inode.(*filesystem.DirectoryInode); ok {
+			fs.copyDirectory(inode, inode)
+			fs.DirectoryCount++
+		}
+	}
+}
+
+// needsScan returns true if the specified directory must be scanned, rather
+// than copied from the old file-system.
+func (fs *FileSystem) needsScan(myPathName string) bool {
+	if fs.dirtyAncestors == nil {
+		return true
+	}
+	if _, ok := fs.params.DirtyDirectories[myPathName]; ok {
+		return true
+	}
+	_, ok := fs.dirtyAncestors[myPathName]
+	return ok
+}
+
+// reuseRegularFile will use the old inode for a regular file if the metadata
+// are unchanged, avoiding rehashing the file. It returns true if the old inode
+// was used.
+func (fs *FileSystem) reuseRegularFile(dirent *filesystem.DirectoryEntry,
+	oldDirent *filesystem.DirectoryEntry, stat *wsyscall.Stat_t) bool {
+	if oldDirent == nil || oldDirent.InodeNumber != stat.Ino {
+		return false
+	}
+	oldInode, ok := oldDirent.Inode().(*filesystem.RegularInode)
+	if !ok || !regularInodeUnchanged(oldInode, stat) {
+		return false
+	}
+	fs.fsLock.Lock()
+	defer fs.fsLock.Unlock()
+	if inode, ok := fs.InodeTable[stat.Ino]; ok {
+		if inode, ok := inode.(*filesystem.RegularInode); ok {
+			dirent.SetInode(inode)
+			return true
+		}
+		return false
+	}
+	fs.InodeTable[stat.Ino] = oldInode
+	dirent.SetInode(oldInode)
+	return true
+}
+
 func (fs *FileSystem) scanDirectory(directory *filesystem.DirectoryInode,
 	oldDirectory *filesystem.DirectoryInode, myPathName string) (error, bool) {
+	if oldDirectory != nil && !fs.needsScan(myPathName) {
+		fs.copyDirectory(directory, oldDirectory)
+		return nil, true
+	}
+	var oldEntries map[string]*filesystem.DirectoryEntry
+	if oldDirectory != nil && fs.dirtyAncestors != nil {
+		oldEntries = make(map[string]*filesystem.DirectoryEntry,
+			len(oldDirectory.EntryList))
+		for _, dirent := range oldDirectory.EntryList {
+			oldEntries[dirent.Name] = dirent
+		}
+	}
 	file, err := os.Open(path.Join(fs.params.RootDirectoryName,
 		myPathName))
 	if err != nil {
@@ -150,12 +251,17 @@ func (fs *FileSystem) scanDirectory(directory *filesystem.DirectoryInode,
 			if len(oldDirectory.EntryList) > index &&
 				oldDirectory.EntryList[index].Name == name {
 				oldDirent = oldDirectory.EntryList[index]
+			} else if oldEntries != nil {
+				oldDirent = oldEntries[name]
 			}
 		}
 		if stat.Mode&syscall.S_IFMT == syscall.S_IFDIR {
 			err = fs.addDirectory(dirent, oldDirent, myPathName, &stat)
 		} else if stat.Mode&syscall.S_IFMT == syscall.S_IFREG {
-			err = fs.addRegularFile(dirent, myPathName, &stat)
+			if fs.dirtyAncestors == nil ||
+				!fs.reuseRegularFile(dirent, oldDirent, &stat) {
+				err = fs.addRegularFile(dirent, myPathName, &stat)
+			}
 		} else if stat.Mode&syscall.S_IFMT == syscall.S_IFLNK {
 			err = fs.addSymlink(dirent, myPathName, &stat)
 		} else if stat.Mode&syscall.S_IFMT == syscall.S_IFSOCK {
@@ -0,0 +1,179 @@
+package scanner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+)
+
+type countingHasher struct {
+	count  int
+	hasher Hasher
+}
+
+func (h *countingHasher) Hash(reader io.Reader, length uint64) (
+	hash.Hash, error) {
+	h.count++
+	return h.hasher.Hash(reader, length)
+}
+
+func findInode(t *testing.T, fs *FileSystem,
+	pathname string) filesystem.GenericInode {
+	var inode filesystem.GenericInode = &fs.DirectoryInode
+	for _, name := range strings.Split(strings.Trim(pathname, "/"), "/") {
+		directory, ok := inode.(*filesystem.DirectoryInode)
+		if !ok {
+			t.Fatalf("%s: not a directory", pathname)
+		}
+		inode = nil
+		for _, dirent := range directory.EntryList {
+			if dirent.Name == name {
+				inode = dirent.Inode()
+				break
+			}
+		}
+		if inode == nil {
+			return nil
+		}
+	}
+	return inode
+}
+
+func writeFile(t *testing.T, filename, data string) {
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMakeDirtyAncestors(t *testing.T) {
+	ancestors := makeDirtyAncestors(map[string]struct{}{
+		"/a/b/c": {},
+		"/a/d":   {},
+		"/x":     {},
+	})
+	expected := map[string]struct{}{
+		"/":    {},
+		"/a":   {},
+		"/a/b": {},
+	}
+	if !reflect.DeepEqual(ancestors, expected) {
+		t.Errorf("expected: %v, got: %v", expected, ancestors)
+	}
+}
+
+func TestNeedsScan(t *testing.T) {
+	fs := &FileSystem{}
+	if !fs.needsScan("/anything") {
+		t.Error("full scan did not scan directory")
+	}
+	fs.params.DirtyDirectories = map[string]struct{}{"/a/b": {}}
+	fs.dirtyAncestors = makeDirtyAncestors(fs.params.DirtyDirectories)
+	for pathname, expected := range map[string]bool{
+		"/":      true,
+		"/a":     true,
+		"/a/b":   true,
+		"/a/b/c": false,
+		"/a/c":   false,
+		"/d":     false,
+	} {
+		if got := fs.needsScan(pathname); got != expected {
+			t.Errorf("%s: expected: %v, got: %v", pathname, expected, got)
+		}
+	}
+}
+
+func TestReuseRegularFile(t *testing.T) {
+	stat := wsyscall.Stat_t{Ino: 10, Mode: 0100644, Size: 3}
+	stat.Mtim.Sec = 1000
+	oldInode := makeRegularInode(&stat)
+	oldInode.Hash[0] = 1
+	oldDirent := &filesystem.DirectoryEntry{Name: "file", InodeNumber: 10}
+	oldDirent.SetInode(oldInode)
+	fs := &FileSystem{fsLock: &nilLocker{}}
+	fs.InodeTable = make(filesystem.InodeTable)
+	dirent := &filesystem.DirectoryEntry{Name: "file", InodeNumber: 10}
+	if !fs.reuseRegularFile(dirent, oldDirent, &stat) {
+		t.Fatal("unchanged file not reused")
+	}
+	if dirent.Inode() != oldInode || fs.InodeTable[10] != oldInode {
+		t.Error("old inode not used")
+	}
+	// A hard link to the same inode must share the inode.
+	otherDirent := &filesystem.DirectoryEntry{Name: "link", InodeNumber: 10}
+	if !fs.reuseRegularFile(otherDirent, oldDirent, &stat) {
+		t.Fatal("unchanged hard link not reused")
+	}
+	if otherDirent.Inode() != oldInode {
+		t.Error("hard link does not share inode")
+	}
+	if fs.reuseRegularFile(dirent, nil, &stat) {
+		t.Error("reused without old entry")
+	}
+	changedStat := stat
+	changedStat.Mtim.Nsec = 1
+	if fs.reuseRegularFile(dirent, oldDirent, &changedStat) {
+		t.Error("reused file with changed mtime")
+	}
+	changedStat = stat
+	changedStat.Ino = 11
+	if fs.reuseRegularFile(dirent, oldDirent, &changedStat) {
+		t.Error("reused file with changed inode number")
+	}
+}
+
+func TestIncrementalScan(t *testing.T) {
+	rootDir := t.TempDir()
+	for _, dirname := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(rootDir, dirname), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(rootDir, "a", "file1"), "one")
+	writeFile(t, filepath.Join(rootDir, "b", "file2"), "two")
+	hasher := &countingHasher{hasher: GetSimpleHasher(false)}
+	oldFS, err := ScanFileSystemWithParams(Params{
+		Hasher:            hasher,
+		RootDirectoryName: rootDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasher.count != 2 {
+		t.Fatalf("expected 2 files hashed, got: %d", hasher.count)
+	}
+	writeFile(t, filepath.Join(rootDir, "a", "file3"), "three")
+	writeFile(t, filepath.Join(rootDir, "b", "file2"), "changed")
+	hasher.count = 0
+	fs, err := ScanFileSystemWithParams(Params{
+		DirtyDirectories:  map[string]struct{}{"/a": {}},
+		Hasher:            hasher,
+		OldFS:             oldFS,
+		RootDirectoryName: rootDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasher.count != 1 {
+		t.Errorf("expected 1 file hashed, got: %d", hasher.count)
+	}
+	if findInode(t, fs, "/a/file3") == nil {
+		t.Error("new file in dirty directory not found")
+	}
+	if findInode(t, fs, "/a/file1") != findInode(t, oldFS, "/a/file1") {
+		t.Error("unchanged file in dirty directory not reused")
+	}
+	// The clean directory is copied, so the change is not seen.
+	inode, ok := findInode(t, fs, "/b/file2").(*filesystem.RegularInode)
+	if !ok {
+		t.Error("file in clean directory not found")
+	} else if inode.Size != 3 {
+		t.Errorf("clean directory was rescanned, size: %d", inode.Size)
+	}
+}
@@ -146,19 +146,20 @@ func WriteImageName(mountPoint, imageName string) error {
 }
 
 type WriteRawOptions struct {
-	AllocateBlocks       bool
-	DoChroot             bool
-	DisableFillZero      bool
-	ExtraKernelOptions   string
-	InitialImageName     string
-	InstallBootloader    bool
-	MinimumFreeBytes     uint64
-	OverlayDirectories   []string
-	OverlayFiles         map[string][]byte
-	PartitionWaitTimeout time.Duration // Default: 2 seconds.
-	RootLabel            string
-	RoundupPower         uint64
-	WriteFstab           bool
+	AllocateBlocks         bool
+	DoChroot               bool
+	DisableFillZero        bool
+	EfiSystemPartitionSize uint64 // Only used for GPT tables.
+	ExtraKernelOptions     string
+	InitialImageName       string
+	InstallBootloader      bool
+	MinimumFreeBytes       uint64
+	OverlayDirectories     []string
+	OverlayFiles           map[string][]byte
+	PartitionWaitTimeout   time.Duration // Default: 2 seconds.
+	RootLabel              string
+	RoundupPower           uint64
+	WriteFstab             bool
 }
 
 func WriteRaw(fs *filesystem.FileSystem,
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/backoffdelay"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/gpt"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/mbr"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+)
+
+const (
+	efiMountPoint  = "/mnt/efi"
+	efiPartition   = 3
+	efiVolumeLabel = "EFI"
+)
+
+// getPartitionDevice returns the name of the device for the specified
+// partition number, given the device for partition 1.
+func getPartitionDevice(rootDevice string, partition uint) string {
+	return fmt.Sprintf("%s%d", rootDevice[:len(rootDevice)-1], partition)
+}
+
+// makeAndMountEsp will make a FAT file-system on the EFI System Partition,
+// mount it under the root file-system and populate it with the contents of
+// /boot/efi from the image (if present). The mount point is returned.
+func makeAndMountEsp(espDevice, rootDir string, logger log.DebugLogger) (
+	string, error) {
+	startTime := time.Now()
+	cmd := exec.Command("mkfs.vfat", "-F", "32", "-n", efiVolumeLabel,
+		espDevice)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("error making FAT file-system on: %s: %s: %s",
+			espDevice, err, output)
+	}
+	logger.Debugf(0, "Made FAT file-system on: %s in %s\n",
+		espDevice, format.Duration(time.Since(startTime)))
+	mountPoint := filepath.Join(rootDir, efiMountPoint)
+	if err := os.MkdirAll(mountPoint, fsutil.DirPerms); err != nil {
+		return "", err
+	}
+	if err := wsyscall.Mount(espDevice, mountPoint, "vfat", 0, ""); err != nil {
+		return "", fmt.Errorf("error mounting: %s: %s", espDevice, err)
+	}
+	imageEfiDir := filepath.Join(rootDir, "boot", "efi")
+	if fi, err := os.Stat(imageEfiDir); err == nil && fi.IsDir() {
+		if err := fsutil.CopyFilesTree(mountPoint, imageEfiDir); err != nil {
+			wsyscall.Unmount(mountPoint, 0)
+			return "", fmt.Errorf("error copying %s to ESP: %s",
+				imageEfiDir, err)
+		}
+	}
+	return mountPoint, nil
+}
+
+func rereadPartitionTable(device string) error {
+	cmd := exec.Command("blockdev", "--rereadpt", device)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error re-reading partition table: %s: %s: %s",
+			device, err, output)
+	}
+	return nil
+}
+
+func waitForPartition(device string, timeout time.Duration) error {
+	sleeper := backoffdelay.NewExponential(time.Millisecond,
+		100*time.Millisecond, 2)
+	stopTime := time.Now().Add(timeout)
+	for time.Until(stopTime) >= 0 {
+		if isPartition, err := checkIfPartition(device); err != nil {
+			return err
+		} else if isPartition {
+			return nil
+		}
+		sleeper.Sleep()
+	}
+	return errors.New("timed out waiting for partition: " + device)
+}
+
+func writeEspFstabEntry(rootDir string) error {
+	pathname := filepath.Join(rootDir, "etc", "fstab")
+	file, err := os.OpenFile(pathname, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	err = writeFstabEntry(file, "LABEL="+efiVolumeLabel, efiMountPoint, "vfat",
+		"umask=0077", 0, 2)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// writePartitionTable will write a partition table to the specified file or
+// device. It returns true if an EFI System Partition was created.
+func writePartitionTable(filename string, tableType mbr.TableType,
+	options WriteRawOptions) (bool, error) {
+	if tableType != mbr.TABLE_TYPE_GPT {
+		return false, mbr.WriteDefault(filename, tableType)
+	}
+	err := gpt.WriteDefault(filename, gpt.DefaultParams{
+		EfiSystemPartitionSize: options.EfiSystemPartitionSize,
+	})
+	return options.EfiSystemPartitionSize > 0, err
+}
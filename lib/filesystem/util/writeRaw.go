@@ -220,7 +220,8 @@ func lookPath(rootDir, file string) (string, error) {
 }
 
 func makeAndWriteRoot(fs *filesystem.FileSystem,
-	objectsGetter objectserver.ObjectsGetter, bootDevice, rootDevice string,
+	objectsGetter objectserver.ObjectsGetter,
+	bootDevice, rootDevice, espDevice string,
 	options WriteRawOptions, logger log.DebugLogger) error {
 	unsupportedOptions, err := getUnsupportedOptions(fs, objectsGetter)
 	if err != nil {
@@ -294,13 +295,31 @@ func makeAndWriteRoot(fs *filesystem.FileSystem,
 			return err
 		}
 	}
+	if espDevice != "" {
+		espMountPoint, err := makeAndMountEsp(espDevice, mountPoint, logger)
+		if err != nil {
+			return err
+		}
+		defer wsyscall.Unmount(espMountPoint, 0)
+		if options.WriteFstab {
+			if err := writeEspFstabEntry(mountPoint); err != nil {
+				return err
+			}
+		}
+	}
 	if options.InstallBootloader {
 		err := bootInfo.installBootloader(bootDevice, mountPoint,
-			options.RootLabel, options.DoChroot, logger)
+			options.RootLabel, options.DoChroot, espDevice != "", logger)
 		if err != nil {
 			return err
 		}
 	}
+	if espDevice != "" {
+		espMountPoint := filepath.Join(mountPoint, efiMountPoint)
+		if err := wsyscall.Unmount(espMountPoint, 0); err != nil {
+			return err
+		}
+	}
 	doUnmount = false
 	startTime := time.Now()
 	if err := wsyscall.Unmount(mountPoint, 0); err != nil {
@@ -323,7 +342,7 @@ func makeBootable(fs *filesystem.FileSystem,
 		return err
 	} else {
 		return bootInfo.installBootloader(deviceName, rootDir, rootLabel,
-			doChroot, logger)
+			doChroot, false, logger)
 	}
 }
 
@@ -423,8 +442,12 @@ func getBootInfo(fs *filesystem.FileSystem, rootLabel string,
 	return bootInfo, nil
 }
 
+// installBootloader will install GRUB. If removable is true, GRUB is installed
+// in the fallback path on the EFI System Partition rather than registering a
+// boot entry in NVRAM, which is required for portable images.
 func (bootInfo *BootInfoType) installBootloader(deviceName string,
-	rootDir, rootLabel string, doChroot bool, logger log.DebugLogger) error {
+	rootDir, rootLabel string, doChroot, removable bool,
+	logger log.DebugLogger) error {
 	startTime := time.Now()
 	mountTable, err := mounts.GetMountTable()
 	if err != nil {
@@ -476,6 +499,11 @@ func (bootInfo *BootInfoType) installBootloader(deviceName string,
 				"--removable",
 				"--force",
 			)
+		} else if removable {
+			cmd.Args = append(cmd.Args,
+				"--removable",
+				"--no-nvram",
+			)
 		}
 	} else {
 		cmd.Args = append(cmd.Args,
@@ -643,17 +671,30 @@ func writeToBlock(fs *filesystem.FileSystem,
 	objectsGetter objectserver.ObjectsGetter, bootDevice string,
 	tableType mbr.TableType, options WriteRawOptions,
 	logger log.DebugLogger) error {
-	if err := mbr.WriteDefault(bootDevice, tableType); err != nil {
+	haveEsp, err := writePartitionTable(bootDevice, tableType, options)
+	if err != nil {
 		return err
 	}
+	if tableType == mbr.TABLE_TYPE_GPT {
+		if err := rereadPartitionTable(bootDevice); err != nil {
+			return err
+		}
+	}
 	rootDevice, err := waitForRootPartition(bootDevice,
 		options.PartitionWaitTimeout)
 	if err != nil {
 		return err
-	} else {
-		return makeAndWriteRoot(fs, objectsGetter, bootDevice, rootDevice,
-			options, logger)
 	}
+	var espDevice string
+	if haveEsp {
+		espDevice = getPartitionDevice(rootDevice, efiPartition)
+		err := waitForPartition(espDevice, options.PartitionWaitTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	return makeAndWriteRoot(fs, objectsGetter, bootDevice, rootDevice,
+		espDevice, options, logger)
 }
 
 func writeToFile(fs *filesystem.FileSystem,
@@ -670,6 +711,9 @@ func writeToFile(fs *filesystem.FileSystem,
 	usageEstimate := fs.EstimateUsage(0)
 	minBytes := usageEstimate + usageEstimate>>3 // 12% extra for good luck.
 	minBytes += options.MinimumFreeBytes
+	if tableType == mbr.TABLE_TYPE_GPT {
+		minBytes += options.EfiSystemPartitionSize + 8<<20 // Also BIOS boot.
+	}
 	if options.RoundupPower < 24 {
 		options.RoundupPower = 24 // 16 MiB.
 	}
@@ -688,7 +732,8 @@ func writeToFile(fs *filesystem.FileSystem,
 				tmpFilename, err)
 		}
 	}
-	if err := mbr.WriteDefault(tmpFilename, tableType); err != nil {
+	haveEsp, err := writePartitionTable(tmpFilename, tableType, options)
+	if err != nil {
 		return err
 	}
 	partition := "p1"
@@ -700,8 +745,16 @@ func writeToFile(fs *filesystem.FileSystem,
 	defer fsutil.LoopbackDeleteAndWaitForPartition(loopDevice, partition,
 		time.Minute, logger)
 	rootDevice := loopDevice + partition
-	err = makeAndWriteRoot(fs, objectsGetter, loopDevice, rootDevice, options,
-		logger)
+	var espDevice string
+	if haveEsp {
+		espDevice = getPartitionDevice(rootDevice, efiPartition)
+		err := waitForPartition(espDevice, options.PartitionWaitTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	err = makeAndWriteRoot(fs, objectsGetter, loopDevice, rootDevice,
+		espDevice, options, logger)
 	if err != nil {
 		return err
 	}
@@ -16,6 +16,14 @@ func GetCommitIdOfRef(topdir, ref string) (string, error) {
 	return getCommitIdOfRef(topdir, ref)
 }
 
+// ReadFileAtCommit will read the file at pathname (relative to the top of the
+// repository in topdir) as it was at the specified commit, ignoring the working
+// tree. If the file does not exist at that commit the error wraps
+// os.ErrNotExist.
+func ReadFileAtCommit(topdir, commitId, pathname string) ([]byte, error) {
+	return readFileAtCommit(topdir, commitId, pathname)
+}
+
 // ShallowClone will make a shallow clone of a Git repository. The repository
 // will be written to the directory specified by topdir.
 func ShallowClone(topdir string, params ShallowCloneParams,
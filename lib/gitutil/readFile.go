@@ -0,0 +1,50 @@
+package gitutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func readFileAtCommit(topdir, commitId, pathname string) ([]byte, error) {
+	output, err := runGit(topdir, "ls-tree", "-z", "--full-tree", commitId,
+		"--", pathname)
+	if err != nil {
+		return nil, err
+	}
+	// Format: <mode> SP <type> SP <object> TAB <file> NUL
+	entry, _, _ := bytes.Cut(output, []byte{0})
+	if len(entry) < 1 {
+		return nil, fmt.Errorf("%s at commit: %s: %w",
+			pathname, commitId, os.ErrNotExist)
+	}
+	info, _, _ := bytes.Cut(entry, []byte{'\t'})
+	fields := strings.Fields(string(info))
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("bad ls-tree entry: %q", string(entry))
+	}
+	if fields[1] != "blob" {
+		return nil, fmt.Errorf("%s at commit: %s is a: %s",
+			pathname, commitId, fields[1])
+	}
+	return runGit(topdir, "cat-file", "blob", fields[2])
+}
+
+func runGit(topdir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = topdir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if len(message) < 1 {
+			return nil, fmt.Errorf("error running: git %s: %s", args[0], err)
+		}
+		return nil, fmt.Errorf("error running: git %s: %s: %s",
+			args[0], err, message)
+	}
+	return output, nil
+}
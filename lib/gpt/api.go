@@ -0,0 +1,94 @@
+package gpt
+
+import (
+	"os"
+)
+
+const (
+	SectorSize = 512
+
+	AttributeRequired       = 1 << 0
+	AttributeNoBlockIO      = 1 << 1
+	AttributeLegacyBootable = 1 << 2
+)
+
+var (
+	TypeBiosBoot        = mustParseGuid("21686148-6449-6E6F-744E-656564454649")
+	TypeEfiSystem       = mustParseGuid("C12A7328-F81F-11D2-BA4B-00A0C93EC93B")
+	TypeLinuxFilesystem = mustParseGuid("0FC63DAF-8483-4772-8E79-3D69D8477DE4")
+)
+
+// Guid is a globally unique identifier, stored in the mixed-endian byte order
+// used on disk.
+type Guid [16]byte
+
+type Partition struct {
+	Attributes uint64
+	FirstLba   uint64
+	LastLba    uint64 // Inclusive.
+	Name       string
+	TypeGuid   Guid // Zero: unused entry.
+	UniqueGuid Guid
+}
+
+// Table is a GUID Partition Table. Partitions are numbered from 1 by the
+// kernel, corresponding to index 0 in the Partitions slice.
+type Table struct {
+	DiskGuid   Guid
+	Partitions []Partition // Maximum 128 entries.
+}
+
+type DefaultParams struct {
+	EfiSystemPartitionSize uint64 // Bytes. Zero: no EFI System Partition.
+}
+
+// Decode will read a GPT from file. If there is no GPT, nil is returned. If the
+// primary table is corrupt, the backup table is used.
+func Decode(file *os.File) (*Table, error) {
+	return decode(file)
+}
+
+func MakeRandomGuid() (Guid, error) {
+	return makeRandomGuid()
+}
+
+func ParseGuid(value string) (Guid, error) {
+	return parseGuid(value)
+}
+
+// WriteDefault will write a GPT to the specified file or block device with a
+// root partition (partition 1), a BIOS boot partition (partition 2), so that
+// GRUB may be installed for legacy BIOS systems, and optionally an EFI System
+// Partition (partition 3).
+func WriteDefault(filename string, params DefaultParams) error {
+	return writeDefault(filename, params)
+}
+
+func (guid Guid) String() string {
+	return guid.string()
+}
+
+func (table *Table) GetNumPartitions() uint {
+	return uint(len(table.Partitions))
+}
+
+func (table *Table) GetPartitionOffset(index uint) uint64 {
+	return table.getPartitionOffset(index)
+}
+
+func (table *Table) GetPartitionSize(index uint) uint64 {
+	return table.getPartitionSize(index)
+}
+
+// GrowPartition will extend the specified partition to the end of the usable
+// space on the file or block device and then write the table. The partition
+// must be the last partition on the device.
+func (table *Table) GrowPartition(filename string, index uint) error {
+	return table.growPartition(filename, index)
+}
+
+// Write will write the table to the specified file or block device, including
+// a protective MBR and the backup table at the end of the device.
+func (table *Table) Write(filename string) error {
+	return table.write(filename)
+}
@@ -0,0 +1,54 @@
+package gpt
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+func mustParseGuid(value string) Guid {
+	guid, err := parseGuid(value)
+	if err != nil {
+		panic(err)
+	}
+	return guid
+}
+
+func makeRandomGuid() (Guid, error) {
+	var guid Guid
+	if _, err := rand.Read(guid[:]); err != nil {
+		return guid, err
+	}
+	guid[7] = (guid[7] & 0x0f) | 0x40 // Version 4 (stored little-endian).
+	guid[8] = (guid[8] & 0x3f) | 0x80 // RFC 4122 variant.
+	return guid, nil
+}
+
+func parseGuid(value string) (Guid, error) {
+	var guid Guid
+	fields := strings.Split(value, "-")
+	if len(fields) != 5 || len(fields[0]) != 8 || len(fields[1]) != 4 ||
+		len(fields[2]) != 4 || len(fields[3]) != 4 || len(fields[4]) != 12 {
+		return guid, fmt.Errorf("malformed GUID: %s", value)
+	}
+	raw, err := hex.DecodeString(strings.Join(fields, ""))
+	if err != nil {
+		return guid, fmt.Errorf("malformed GUID: %s: %s", value, err)
+	}
+	// The first three fields are stored little-endian.
+	binary.LittleEndian.PutUint32(guid[0:], binary.BigEndian.Uint32(raw[0:]))
+	binary.LittleEndian.PutUint16(guid[4:], binary.BigEndian.Uint16(raw[4:]))
+	binary.LittleEndian.PutUint16(guid[6:], binary.BigEndian.Uint16(raw[6:]))
+	copy(guid[8:], raw[8:])
+	return guid, nil
+}
+
+func (guid Guid) string() string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(guid[0:]),
+		binary.LittleEndian.Uint16(guid[4:]),
+		binary.LittleEndian.Uint16(guid[6:]),
+		guid[8:10], guid[10:])
+}
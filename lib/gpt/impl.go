@@ -0,0 +1,395 @@
+package gpt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"unicode/utf16"
+)
+
+const (
+	alignmentSectors = 2048 // 1 MiB.
+	entriesSectors   = numEntries * entrySize / SectorSize
+	entrySize        = 128
+	headerSize       = 92
+	maxEntrySize     = 4096
+	maxNameLength    = 36 // UTF-16 code units.
+	maxNumEntries    = 1024
+	numEntries       = 128
+	revision         = 0x00010000
+	signature        = "EFI PART"
+)
+
+type header struct {
+	currentLba     uint64
+	backupLba      uint64
+	firstUsableLba uint64
+	lastUsableLba  uint64
+	diskGuid       Guid
+	entriesLba     uint64
+	numEntries     uint32
+	entrySize      uint32
+	entriesCrc     uint32
+}
+
+func decode(file *os.File) (*Table, error) {
+	numSectors, err := getNumSectors(file)
+	if err != nil {
+		return nil, err
+	}
+	if numSectors < 3+2*entriesSectors {
+		return nil, nil
+	}
+	primaryHeader, err := readHeader(file, 1)
+	if err != nil {
+		return nil, err
+	}
+	if primaryHeader == nil {
+		return nil, nil
+	}
+	table, primaryErr := readTable(file, primaryHeader)
+	if primaryErr == nil {
+		return table, nil
+	}
+	backupLba := primaryHeader.backupLba
+	if backupLba < 1 || backupLba >= numSectors {
+		backupLba = numSectors - 1
+	}
+	backupHeader, err := readHeader(file, backupLba)
+	if err != nil || backupHeader == nil {
+		return nil, primaryErr
+	}
+	if table, err := readTable(file, backupHeader); err != nil {
+		return nil, primaryErr
+	} else {
+		return table, nil
+	}
+}
+
+func decodeEntry(raw []byte) Partition {
+	partition := Partition{
+		FirstLba:   binary.LittleEndian.Uint64(raw[32:]),
+		LastLba:    binary.LittleEndian.Uint64(raw[40:]),
+		Attributes: binary.LittleEndian.Uint64(raw[48:]),
+	}
+	copy(partition.TypeGuid[:], raw[0:16])
+	copy(partition.UniqueGuid[:], raw[16:32])
+	name := make([]uint16, 0, maxNameLength)
+	for index := 0; index < maxNameLength; index++ {
+		char := binary.LittleEndian.Uint16(raw[56+2*index:])
+		if char == 0 {
+			break
+		}
+		name = append(name, char)
+	}
+	partition.Name = string(utf16.Decode(name))
+	return partition
+}
+
+func encodeEntries(partitions []Partition) ([]byte, error) {
+	if len(partitions) > numEntries {
+		return nil, fmt.Errorf("too many partitions: %d", len(partitions))
+	}
+	raw := make([]byte, numEntries*entrySize)
+	for index, partition := range partitions {
+		entry := raw[index*entrySize : (index+1)*entrySize]
+		copy(entry[0:16], partition.TypeGuid[:])
+		copy(entry[16:32], partition.UniqueGuid[:])
+		binary.LittleEndian.PutUint64(entry[32:], partition.FirstLba)
+		binary.LittleEndian.PutUint64(entry[40:], partition.LastLba)
+		binary.LittleEndian.PutUint64(entry[48:], partition.Attributes)
+		name := utf16.Encode([]rune(partition.Name))
+		if len(name) > maxNameLength {
+			return nil, fmt.Errorf("partition name too long: %s",
+				partition.Name)
+		}
+		for charIndex, char := range name {
+			binary.LittleEndian.PutUint16(entry[56+2*charIndex:], char)
+		}
+	}
+	return raw, nil
+}
+
+func encodeHeader(hdr header) []byte {
+	raw := make([]byte, SectorSize)
+	copy(raw[0:8], signature)
+	binary.LittleEndian.PutUint32(raw[8:], revision)
+	binary.LittleEndian.PutUint32(raw[12:], headerSize)
+	binary.LittleEndian.PutUint64(raw[24:], hdr.currentLba)
+	binary.LittleEndian.PutUint64(raw[32:], hdr.backupLba)
+	binary.LittleEndian.PutUint64(raw[40:], hdr.firstUsableLba)
+	binary.LittleEndian.PutUint64(raw[48:], hdr.lastUsableLba)
+	copy(raw[56:72], hdr.diskGuid[:])
+	binary.LittleEndian.PutUint64(raw[72:], hdr.entriesLba)
+	binary.LittleEndian.PutUint32(raw[80:], hdr.numEntries)
+	binary.LittleEndian.PutUint32(raw[84:], hdr.entrySize)
+	binary.LittleEndian.PutUint32(raw[88:], hdr.entriesCrc)
+	binary.LittleEndian.PutUint32(raw[16:],
+		crc32.ChecksumIEEE(raw[:headerSize]))
+	return raw
+}
+
+func encodeProtectiveMbr(numSectors uint64) []byte {
+	raw := make([]byte, SectorSize)
+	entry := raw[0x1BE : 0x1BE+16]
+	entry[1] = 0x00 // CHS of first sector: 0/0/2.
+	entry[2] = 0x02
+	entry[3] = 0x00
+	entry[4] = 0xEE // GPT protective.
+	entry[5] = 0xFF // CHS of last sector: maximum.
+	entry[6] = 0xFF
+	entry[7] = 0xFF
+	binary.LittleEndian.PutUint32(entry[8:], 1)
+	size := numSectors - 1
+	if size > 0xFFFFFFFF {
+		size = 0xFFFFFFFF
+	}
+	binary.LittleEndian.PutUint32(entry[12:], uint32(size))
+	raw[0x1FE] = 0x55
+	raw[0x1FF] = 0xAA
+	return raw
+}
+
+func getNumSectors(file *os.File) (uint64, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(size) / SectorSize, nil
+}
+
+func readHeader(file *os.File, lba uint64) (*header, error) {
+	raw := make([]byte, SectorSize)
+	if _, err := file.ReadAt(raw, int64(lba*SectorSize)); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(raw[0:8], []byte(signature)) {
+		return nil, nil
+	}
+	size := binary.LittleEndian.Uint32(raw[12:])
+	if size < headerSize || size > SectorSize {
+		return nil, fmt.Errorf("invalid GPT header size: %d", size)
+	}
+	checksum := binary.LittleEndian.Uint32(raw[16:])
+	binary.LittleEndian.PutUint32(raw[16:], 0)
+	if crc32.ChecksumIEEE(raw[:size]) != checksum {
+		return nil, fmt.Errorf("GPT header at LBA: %d has bad checksum", lba)
+	}
+	hdr := &header{
+		currentLba:     binary.LittleEndian.Uint64(raw[24:]),
+		backupLba:      binary.LittleEndian.Uint64(raw[32:]),
+		firstUsableLba: binary.LittleEndian.Uint64(raw[40:]),
+		lastUsableLba:  binary.LittleEndian.Uint64(raw[48:]),
+		entriesLba:     binary.LittleEndian.Uint64(raw[72:]),
+		numEntries:     binary.LittleEndian.Uint32(raw[80:]),
+		entrySize:      binary.LittleEndian.Uint32(raw[84:]),
+		entriesCrc:     binary.LittleEndian.Uint32(raw[88:]),
+	}
+	copy(hdr.diskGuid[:], raw[56:72])
+	// The entry size must be 128*2^n. Together with the bound on the number
+	// of entries this limits the size of the entry array to 4 MiB.
+	if hdr.entrySize < entrySize || hdr.entrySize > maxEntrySize ||
+		hdr.entrySize&(hdr.entrySize-1) != 0 ||
+		hdr.numEntries > maxNumEntries {
+		return nil, fmt.Errorf("unsupported GPT entry array: %d*%d",
+			hdr.numEntries, hdr.entrySize)
+	}
+	return hdr, nil
+}
+
+func readTable(file *os.File, hdr *header) (*Table, error) {
+	raw := make([]byte, uint64(hdr.numEntries)*uint64(hdr.entrySize))
+	_, err := file.ReadAt(raw, int64(hdr.entriesLba*SectorSize))
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(raw) != hdr.entriesCrc {
+		return nil, errors.New("GPT partition entries have bad checksum")
+	}
+	table := &Table{DiskGuid: hdr.diskGuid}
+	var numUsed int
+	for index := uint32(0); index < hdr.numEntries; index++ {
+		partition := decodeEntry(raw[index*hdr.entrySize:])
+		table.Partitions = append(table.Partitions, partition)
+		if partition.TypeGuid != (Guid{}) {
+			numUsed = len(table.Partitions)
+		}
+	}
+	table.Partitions = table.Partitions[:numUsed]
+	return table, nil
+}
+
+func writeDefault(filename string, params DefaultParams) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	numSectors, err := getNumSectors(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	lastUsableLba := numSectors - 2 - entriesSectors
+	table := &Table{
+		Partitions: []Partition{
+			{
+				Name:     "root",
+				TypeGuid: TypeLinuxFilesystem,
+			},
+			{
+				FirstLba: alignmentSectors,
+				LastLba:  2*alignmentSectors - 1,
+				Name:     "BIOS boot partition",
+				TypeGuid: TypeBiosBoot,
+			},
+		},
+	}
+	nextLba := uint64(2 * alignmentSectors)
+	if params.EfiSystemPartitionSize > 0 {
+		numEspSectors := (params.EfiSystemPartitionSize + SectorSize - 1) /
+			SectorSize
+		numEspSectors = (numEspSectors + alignmentSectors - 1) /
+			alignmentSectors * alignmentSectors
+		table.Partitions = append(table.Partitions, Partition{
+			FirstLba: nextLba,
+			LastLba:  nextLba + numEspSectors - 1,
+			Name:     "EFI System Partition",
+			TypeGuid: TypeEfiSystem,
+		})
+		nextLba += numEspSectors
+	}
+	rootLastLba := (lastUsableLba+1)/alignmentSectors*alignmentSectors - 1
+	if numSectors < 3+2*entriesSectors || rootLastLba <= nextLba {
+		return fmt.Errorf("%s: too small for partitions", filename)
+	}
+	table.Partitions[0].FirstLba = nextLba
+	table.Partitions[0].LastLba = rootLastLba
+	if table.DiskGuid, err = makeRandomGuid(); err != nil {
+		return err
+	}
+	for index := range table.Partitions {
+		table.Partitions[index].UniqueGuid, err = makeRandomGuid()
+		if err != nil {
+			return err
+		}
+	}
+	return table.write(filename)
+}
+
+func (table *Table) getPartitionOffset(index uint) uint64 {
+	if index >= uint(len(table.Partitions)) {
+		return 0
+	}
+	return table.Partitions[index].FirstLba * SectorSize
+}
+
+func (table *Table) getPartitionSize(index uint) uint64 {
+	if index >= uint(len(table.Partitions)) {
+		return 0
+	}
+	partition := table.Partitions[index]
+	if partition.TypeGuid == (Guid{}) {
+		return 0
+	}
+	return (partition.LastLba - partition.FirstLba + 1) * SectorSize
+}
+
+func (table *Table) growPartition(filename string, index uint) error {
+	if table.getPartitionSize(index) < 1 {
+		return fmt.Errorf("partition: %d does not exist", index+1)
+	}
+	partition := &table.Partitions[index]
+	for otherIndex, other := range table.Partitions {
+		if uint(otherIndex) != index && other.TypeGuid != (Guid{}) &&
+			other.FirstLba > partition.LastLba {
+			return fmt.Errorf("partition: %d is not the last partition",
+				index+1)
+		}
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	numSectors, err := getNumSectors(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if numSectors < 3+2*entriesSectors {
+		return fmt.Errorf("%s: too small for GPT", filename)
+	}
+	lastUsableLba := numSectors - 2 - entriesSectors
+	newLastLba := (lastUsableLba+1)/alignmentSectors*alignmentSectors - 1
+	if newLastLba < partition.LastLba {
+		return fmt.Errorf("%s: smaller than partition: %d", filename, index+1)
+	}
+	partition.LastLba = newLastLba
+	return table.write(filename)
+}
+
+func (table *Table) write(filename string) error {
+	file, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	numSectors, err := getNumSectors(file)
+	if err != nil {
+		return err
+	}
+	if numSectors < 3+2*entriesSectors {
+		return fmt.Errorf("%s: too small for GPT", filename)
+	}
+	firstUsableLba := uint64(2 + entriesSectors)
+	lastUsableLba := numSectors - 2 - entriesSectors
+	for index, partition := range table.Partitions {
+		if partition.TypeGuid == (Guid{}) {
+			continue
+		}
+		if partition.FirstLba < firstUsableLba ||
+			partition.LastLba > lastUsableLba ||
+			partition.FirstLba > partition.LastLba {
+			return fmt.Errorf("partition: %d [%d,%d] outside usable range",
+				index+1, partition.FirstLba, partition.LastLba)
+		}
+	}
+	entries, err := encodeEntries(table.Partitions)
+	if err != nil {
+		return err
+	}
+	hdr := header{
+		currentLba:     1,
+		backupLba:      numSectors - 1,
+		firstUsableLba: firstUsableLba,
+		lastUsableLba:  lastUsableLba,
+		diskGuid:       table.DiskGuid,
+		entriesLba:     2,
+		numEntries:     numEntries,
+		entrySize:      entrySize,
+		entriesCrc:     crc32.ChecksumIEEE(entries),
+	}
+	backupHdr := hdr
+	backupHdr.currentLba, backupHdr.backupLba = hdr.backupLba, hdr.currentLba
+	backupHdr.entriesLba = lastUsableLba + 1
+	writes := []struct {
+		lba  uint64
+		data []byte
+	}{
+		{0, encodeProtectiveMbr(numSectors)},
+		{hdr.entriesLba, entries},
+		{backupHdr.entriesLba, entries},
+		{backupHdr.currentLba, encodeHeader(backupHdr)},
+		{hdr.currentLba, encodeHeader(hdr)}, // Last: makes the table valid.
+	}
+	for _, write := range writes {
+		_, err := file.WriteAt(write.data, int64(write.lba*SectorSize))
+		if err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
@@ -0,0 +1,199 @@
+package gpt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeDisk(t *testing.T, size int64) string {
+	filename := filepath.Join(t.TempDir(), "disk")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := file.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func readTableFromFile(t *testing.T, filename string) *Table {
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	table, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table == nil {
+		t.Fatal("no GPT found")
+	}
+	return table
+}
+
+func TestGuid(t *testing.T) {
+	const text = "C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
+	guid, err := ParseGuid(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guid[0] != 0x28 || guid[3] != 0xC1 || guid[8] != 0xBA {
+		t.Errorf("incorrect mixed-endian encoding: %x", guid[:])
+	}
+	if guid.String() != text {
+		t.Errorf("expected: %s, got: %s", text, guid)
+	}
+	if _, err := ParseGuid("not-a-guid"); err == nil {
+		t.Error("malformed GUID accepted")
+	}
+}
+
+func TestWriteDefault(t *testing.T) {
+	const diskSize = 64 << 20
+	filename := makeDisk(t, diskSize)
+	err := WriteDefault(filename,
+		DefaultParams{EfiSystemPartitionSize: 5<<20 + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := readTableFromFile(t, filename)
+	if table.GetNumPartitions() != 3 {
+		t.Fatalf("expected 3 partitions, got: %d", table.GetNumPartitions())
+	}
+	if table.Partitions[0].TypeGuid != TypeLinuxFilesystem ||
+		table.Partitions[1].TypeGuid != TypeBiosBoot ||
+		table.Partitions[2].TypeGuid != TypeEfiSystem {
+		t.Fatal("unexpected partition types")
+	}
+	if table.Partitions[2].Name != "EFI System Partition" {
+		t.Errorf("unexpected name: %s", table.Partitions[2].Name)
+	}
+	if size := table.GetPartitionSize(2); size != 6<<20 {
+		t.Errorf("ESP size not rounded up to 6 MiB: %d", size)
+	}
+	rootOffset := table.GetPartitionOffset(0)
+	if rootOffset != 8<<20 {
+		t.Errorf("unexpected root offset: %d", rootOffset)
+	}
+	if end := rootOffset + table.GetPartitionSize(0); end > diskSize-33*512 {
+		t.Errorf("root partition overlaps backup table: %d", end)
+	}
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[0x1C2] != 0xEE || raw[0x1FE] != 0x55 || raw[0x1FF] != 0xAA {
+		t.Error("missing protective MBR")
+	}
+}
+
+func TestBackupTable(t *testing.T) {
+	filename := makeDisk(t, 16<<20)
+	if err := WriteDefault(filename, DefaultParams{}); err != nil {
+		t.Fatal(err)
+	}
+	original := readTableFromFile(t, filename)
+	// Corrupt the primary partition entries.
+	file, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteAt([]byte{0xff}, 2*SectorSize+1); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	recovered := readTableFromFile(t, filename)
+	if recovered.DiskGuid != original.DiskGuid {
+		t.Error("disk GUID mismatch")
+	}
+	if len(recovered.Partitions) != len(original.Partitions) {
+		t.Fatalf("expected %d partitions, got: %d",
+			len(original.Partitions), len(recovered.Partitions))
+	}
+	for index, partition := range original.Partitions {
+		if recovered.Partitions[index] != partition {
+			t.Errorf("partition %d: expected: %v, got: %v",
+				index+1, partition, recovered.Partitions[index])
+		}
+	}
+}
+
+func TestNoTable(t *testing.T) {
+	filename := makeDisk(t, 1<<20)
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if table, err := Decode(file); err != nil {
+		t.Fatal(err)
+	} else if table != nil {
+		t.Error("found GPT on empty disk")
+	}
+}
+
+func TestBadEntrySize(t *testing.T) {
+	for _, size := range []uint32{64, 130, 8192, 0x80000000} {
+		filename := makeDisk(t, 16<<20)
+		if err := WriteDefault(filename, DefaultParams{}); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.OpenFile(filename, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdr := header{
+			currentLba: 1,
+			entriesLba: 2,
+			numEntries: 3,
+			entrySize:  size,
+		}
+		_, err = file.WriteAt(encodeHeader(hdr), SectorSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(file); err == nil {
+			t.Errorf("entry size: %d accepted", size)
+		}
+		file.Close()
+	}
+}
+
+func TestGrowPartition(t *testing.T) {
+	filename := makeDisk(t, 16<<20)
+	if err := WriteDefault(filename, DefaultParams{}); err != nil {
+		t.Fatal(err)
+	}
+	original := readTableFromFile(t, filename)
+	if err := os.Truncate(filename, 32<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := original.GrowPartition(filename, 1); err == nil {
+		t.Error("grew partition which is not the last partition")
+	}
+	if err := original.GrowPartition(filename, 0); err != nil {
+		t.Fatal(err)
+	}
+	grown := readTableFromFile(t, filename)
+	if size := grown.GetPartitionSize(0); size != 29<<20 {
+		t.Errorf("expected root size: %d, got: %d", 29<<20, size)
+	}
+	if grown.GetPartitionSize(1) != original.GetPartitionSize(1) {
+		t.Error("BIOS boot partition changed size")
+	}
+	// The backup table must have been moved to the new end of the disk.
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if hdr, err := readHeader(file, (32<<20)/SectorSize-1); err != nil {
+		t.Fatal(err)
+	} else if hdr == nil {
+		t.Error("no backup header at end of disk")
+	}
+}
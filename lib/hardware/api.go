@@ -0,0 +1,13 @@
+package hardware
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+// GetInventory will collect the hardware inventory of the local machine from
+// sysfs and procfs. Components which cannot be probed are omitted and the
+// problems are logged.
+func GetInventory(logger log.DebugLogger) (*proto.HardwareInventory, error) {
+	return getInventory(logger)
+}
@@ -0,0 +1,134 @@
+package hardware
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const sysBlockDirectory = "/sys/block"
+
+func readDisks(logger log.DebugLogger) ([]proto.DiskInfo, error) {
+	dirnames, err := readDirnames(sysBlockDirectory)
+	if err != nil {
+		return nil, err
+	}
+	smartctl, _ := exec.LookPath("smartctl")
+	var disks []proto.DiskInfo
+	for _, name := range dirnames {
+		dirname := filepath.Join(sysBlockDirectory, name)
+		deviceDir := filepath.Join(dirname, "device")
+		if _, err := os.Stat(deviceDir); err != nil {
+			continue // Virtual device.
+		}
+		if readSysfsFile(filepath.Join(dirname, "removable")) == "1" {
+			continue
+		}
+		disk := proto.DiskInfo{
+			Device: name,
+			Model:  readSysfsFile(filepath.Join(deviceDir, "model")),
+			Rotational: readSysfsFile(
+				filepath.Join(dirname, "queue", "rotational")) == "1",
+			SerialNumber: readDiskSerialNumber(deviceDir),
+		}
+		disk.FirmwareRevision = readSysfsFile(
+			filepath.Join(deviceDir, "firmware_rev"))
+		if disk.FirmwareRevision == "" {
+			disk.FirmwareRevision = readSysfsFile(
+				filepath.Join(deviceDir, "rev"))
+		}
+		numSectors, err := strconv.ParseUint(
+			readSysfsFile(filepath.Join(dirname, "size")), 10, 64)
+		if err == nil {
+			disk.SizeBytes = numSectors << 9
+		}
+		if smartctl != "" {
+			disk.HealthStatus, err = readSmartHealth(smartctl, name)
+			if err != nil {
+				logger.Printf("error reading SMART health for: %s: %s\n",
+					name, err)
+			}
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
+
+// readDiskSerialNumber will read the serial number from the serial attribute
+// (NVMe) or from the Unit Serial Number VPD page (SCSI/SATA).
+func readDiskSerialNumber(deviceDir string) string {
+	if serial := readSysfsFile(filepath.Join(deviceDir, "serial")); serial != "" {
+		return serial
+	}
+	data, err := os.ReadFile(filepath.Join(deviceDir, "vpd_pg80"))
+	if err != nil {
+		return ""
+	}
+	return parseVpdSerialNumber(data)
+}
+
+// parseVpdSerialNumber will extract the serial number from a Unit Serial
+// Number VPD page: a 4 byte header (with the big-endian page length in bytes
+// 2-3) followed by the serial number.
+func parseVpdSerialNumber(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	length := int(data[2])<<8 | int(data[3])
+	if length > len(data)-4 {
+		length = len(data) - 4
+	}
+	return string(bytes.TrimSpace(bytes.Trim(data[4:4+length], "\x00")))
+}
+
+// readSmartHealth will return the overall health reported by smartctl.
+func readSmartHealth(smartctl, device string) (string, error) {
+	// smartctl sets bits in the exit status for many conditions, so only fail
+	// if the health status cannot be found in the output.
+	output, err := exec.Command(smartctl, "-H",
+		filepath.Join("/dev", device)).Output()
+	if health := parseSmartHealth(output); health != "" {
+		return health, nil
+	}
+	return "", err
+}
+
+// parseSmartHealth will extract the overall health from smartctl output for
+// ATA or NVMe (SMART overall-health...) or SCSI (SMART Health Status) devices.
+func parseSmartHealth(output []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		splitLine := strings.SplitN(scanner.Text(), ":", 2)
+		if len(splitLine) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(splitLine[0]) {
+		case "SMART Health Status",
+			"SMART overall-health self-assessment test result":
+			return strings.TrimSpace(splitLine[1])
+		}
+	}
+	return ""
+}
+
+func readDirnames(dirname string) ([]string, error) {
+	if file, err := os.Open(dirname); err != nil {
+		return nil, err
+	} else {
+		defer file.Close()
+		names, err := file.Readdirnames(-1)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+}
@@ -0,0 +1,67 @@
+package hardware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSmartHealth(t *testing.T) {
+	tests := []struct {
+		output string
+		health string
+	}{
+		{"=== START OF READ SMART DATA SECTION ===\n" +
+			"SMART overall-health self-assessment test result: PASSED\n",
+			"PASSED"},
+		{"=== START OF READ SMART DATA SECTION ===\n" +
+			"SMART Health Status: OK\n", "OK"},
+		{"Smartctl open device: /dev/sda failed: No such device\n", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if health := parseSmartHealth([]byte(test.output)); health !=
+			test.health {
+			t.Errorf("%q: health: %q != %q", test.output, health,
+				test.health)
+		}
+	}
+}
+
+func TestParseVpdSerialNumber(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		serial string
+	}{
+		{append([]byte{0, 0x80, 0, 10}, "  S3Z9NB0K\x00"...), "S3Z9NB0K"},
+		{append([]byte{0, 0x80, 0, 4}, "ABCDEFGH"...), "ABCD"},
+		{append([]byte{0, 0x80, 0, 200}, "SHORT"...), "SHORT"}, // Truncated.
+		{[]byte{0, 0x80, 0}, ""},
+		{nil, ""},
+	}
+	for _, test := range tests {
+		if serial := parseVpdSerialNumber(test.data); serial != test.serial {
+			t.Errorf("%q: serial: %q != %q", test.data, serial, test.serial)
+		}
+	}
+}
+
+func TestReadDiskSerialNumber(t *testing.T) {
+	deviceDir := t.TempDir()
+	vpdPage := append([]byte{0, 0x80, 0, 8}, "WD-12345"...)
+	err := os.WriteFile(filepath.Join(deviceDir, "vpd_pg80"), vpdPage, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial := readDiskSerialNumber(deviceDir); serial != "WD-12345" {
+		t.Errorf("serial from VPD page: %q", serial)
+	}
+	err = os.WriteFile(filepath.Join(deviceDir, "serial"),
+		[]byte("NVME-6789\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial := readDiskSerialNumber(deviceDir); serial != "NVME-6789" {
+		t.Errorf("serial from serial attribute: %q", serial)
+	}
+}
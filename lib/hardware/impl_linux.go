@@ -0,0 +1,173 @@
+package hardware
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/meminfo"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	dmiDirectory  = "/sys/class/dmi/id"
+	edacDirectory = "/sys/devices/system/edac/mc"
+	procCpuinfo   = "/proc/cpuinfo"
+)
+
+type cpuKey struct {
+	core   string
+	socket string
+}
+
+func getInventory(logger log.DebugLogger) (*proto.HardwareInventory, error) {
+	inventory := &proto.HardwareInventory{DMI: readDmi()}
+	if file, err := os.Open(procCpuinfo); err != nil {
+		return nil, err
+	} else {
+		defer file.Close()
+		if inventory.CPU, err = readCpuInfo(file); err != nil {
+			return nil, err
+		}
+	}
+	if memInfo, err := meminfo.GetMemInfo(); err != nil {
+		return nil, err
+	} else {
+		inventory.MemoryInMiB = memInfo.Total >> 20
+	}
+	if dimms, err := readDimms(); err != nil {
+		logger.Printf("error reading DIMMs: %s\n", err)
+	} else {
+		inventory.DIMMs = dimms
+	}
+	if disks, err := readDisks(logger); err != nil {
+		logger.Printf("error reading disks: %s\n", err)
+	} else {
+		inventory.Disks = disks
+	}
+	if nics, err := readNics(logger); err != nil {
+		logger.Printf("error reading network interfaces: %s\n", err)
+	} else {
+		inventory.NICs = nics
+	}
+	return inventory, nil
+}
+
+// readCpuInfo will parse the contents of /proc/cpuinfo.
+func readCpuInfo(reader io.Reader) (proto.CpuInfo, error) {
+	var cpuInfo proto.CpuInfo
+	cores := make(map[cpuKey]struct{})
+	sockets := make(map[string]struct{})
+	var key cpuKey
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		splitLine := strings.SplitN(scanner.Text(), ":", 2)
+		if len(splitLine) != 2 {
+			if key.core != "" || key.socket != "" {
+				cores[key] = struct{}{}
+				sockets[key.socket] = struct{}{}
+			}
+			key = cpuKey{}
+			continue
+		}
+		value := strings.TrimSpace(splitLine[1])
+		switch strings.TrimSpace(splitLine[0]) {
+		case "core id":
+			key.core = value
+		case "Features", "flags":
+			if cpuInfo.Flags == nil {
+				cpuInfo.Flags = strings.Fields(value)
+			}
+		case "model name":
+			if cpuInfo.ModelName == "" {
+				cpuInfo.ModelName = value
+			}
+		case "physical id":
+			key.socket = value
+		case "processor":
+			cpuInfo.NumThreads++
+		case "vendor_id":
+			if cpuInfo.VendorId == "" {
+				cpuInfo.VendorId = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return proto.CpuInfo{}, err
+	}
+	if key.core != "" || key.socket != "" {
+		cores[key] = struct{}{}
+		sockets[key.socket] = struct{}{}
+	}
+	cpuInfo.NumCores = uint(len(cores))
+	cpuInfo.NumSockets = uint(len(sockets))
+	return cpuInfo, nil
+}
+
+// readDimms will read the populated DIMMs from the EDAC memory controllers.
+func readDimms() ([]proto.DimmInfo, error) {
+	dimmDirs, err := filepath.Glob(filepath.Join(edacDirectory, "mc*", "dimm*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dimmDirs)
+	var dimms []proto.DimmInfo
+	for _, dimmDir := range dimmDirs {
+		sizeInMiB, err := strconv.ParseUint(
+			readSysfsFile(filepath.Join(dimmDir, "size")), 10, 64)
+		if err != nil || sizeInMiB < 1 {
+			continue
+		}
+		dimms = append(dimms, proto.DimmInfo{
+			Label:      readSysfsFile(filepath.Join(dimmDir, "dimm_label")),
+			Location:   readSysfsFile(filepath.Join(dimmDir, "dimm_location")),
+			MemoryType: readSysfsFile(filepath.Join(dimmDir, "dimm_mem_type")),
+			SizeInMiB:  sizeInMiB,
+		})
+	}
+	return dimms, nil
+}
+
+func readDmi() proto.DmiInfo {
+	return proto.DmiInfo{
+		BiosVendor:          readDmiFile("bios_vendor"),
+		BiosVersion:         readDmiFile("bios_version"),
+		BoardName:           readDmiFile("board_name"),
+		BoardSerialNumber:   readDmiFile("board_serial"),
+		BoardVendor:         readDmiFile("board_vendor"),
+		ProductName:         readDmiFile("product_name"),
+		ProductSerialNumber: readDmiFile("product_serial"),
+		SystemVendor:        readDmiFile("sys_vendor"),
+	}
+}
+
+// readDmiFile will read a DMI attribute, ignoring common placeholder values.
+func readDmiFile(name string) string {
+	value := readSysfsFile(filepath.Join(dmiDirectory, name))
+	switch value {
+	case "0123456789":
+		return ""
+	case "Default string":
+		return ""
+	case "System Serial Number":
+		return ""
+	case "To be filled by O.E.M.":
+		return ""
+	}
+	return value
+}
+
+// readSysfsFile will return the trimmed contents of a small file, or the empty
+// string if the file could not be read.
+func readSysfsFile(filename string) string {
+	if data, err := os.ReadFile(filename); err != nil {
+		return ""
+	} else {
+		return strings.TrimSpace(string(data))
+	}
+}
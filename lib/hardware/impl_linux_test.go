@@ -0,0 +1,108 @@
+package hardware
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Two sockets, two cores per socket and two threads per core (abridged).
+const x86CpuInfo = `processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 0
+core id		: 0
+flags		: fpu vme sse sse2 vmx
+
+processor	: 1
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 0
+core id		: 1
+flags		: fpu vme sse sse2 vmx
+
+processor	: 2
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 1
+core id		: 0
+flags		: fpu vme sse sse2 vmx
+
+processor	: 3
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 1
+core id		: 1
+flags		: fpu vme sse sse2 vmx
+
+processor	: 4
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 0
+core id		: 0
+flags		: fpu vme sse sse2 vmx
+
+processor	: 5
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 0
+core id		: 1
+flags		: fpu vme sse sse2 vmx
+
+processor	: 6
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 1
+core id		: 0
+flags		: fpu vme sse sse2 vmx
+
+processor	: 7
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+physical id	: 1
+core id		: 1
+flags		: fpu vme sse sse2 vmx
+`
+
+// ARM has no model name, physical id or core id and no trailing blank line.
+const armCpuInfo = `processor	: 0
+BogoMIPS	: 50.00
+Features	: fp asimd evtstrm aes crc32
+
+processor	: 1
+BogoMIPS	: 50.00
+Features	: fp asimd evtstrm aes crc32`
+
+func TestReadCpuInfo(t *testing.T) {
+	cpuInfo, err := readCpuInfo(strings.NewReader(x86CpuInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpuInfo.ModelName != "Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz" {
+		t.Errorf("model name: %s", cpuInfo.ModelName)
+	}
+	if cpuInfo.VendorId != "GenuineIntel" {
+		t.Errorf("vendor ID: %s", cpuInfo.VendorId)
+	}
+	if cpuInfo.NumThreads != 8 || cpuInfo.NumCores != 4 ||
+		cpuInfo.NumSockets != 2 {
+		t.Errorf("threads: %d, cores: %d, sockets: %d != 8, 4, 2",
+			cpuInfo.NumThreads, cpuInfo.NumCores, cpuInfo.NumSockets)
+	}
+	expectedFlags := []string{"fpu", "vme", "sse", "sse2", "vmx"}
+	if !reflect.DeepEqual(cpuInfo.Flags, expectedFlags) {
+		t.Errorf("flags: %v != %v", cpuInfo.Flags, expectedFlags)
+	}
+	cpuInfo, err = readCpuInfo(strings.NewReader(armCpuInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpuInfo.NumThreads != 2 || cpuInfo.NumCores != 0 ||
+		cpuInfo.NumSockets != 0 {
+		t.Errorf("threads: %d, cores: %d, sockets: %d != 2, 0, 0",
+			cpuInfo.NumThreads, cpuInfo.NumCores, cpuInfo.NumSockets)
+	}
+	if len(cpuInfo.Flags) != 5 || cpuInfo.Flags[0] != "fp" {
+		t.Errorf("features: %v", cpuInfo.Flags)
+	}
+}
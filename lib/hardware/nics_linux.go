@@ -0,0 +1,58 @@
+package hardware
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+	"golang.org/x/sys/unix"
+)
+
+const sysClassNetDirectory = "/sys/class/net"
+
+func readNics(logger log.DebugLogger) ([]proto.NicInfo, error) {
+	dirnames, err := readDirnames(sysClassNetDirectory)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+	var nics []proto.NicInfo
+	for _, name := range dirnames {
+		dirname := filepath.Join(sysClassNetDirectory, name)
+		deviceDir := filepath.Join(dirname, "device")
+		if _, err := os.Stat(deviceDir); err != nil {
+			continue // Virtual interface.
+		}
+		nic := proto.NicInfo{
+			HardwareAddress: readSysfsFile(filepath.Join(dirname, "address")),
+			Name:            name,
+		}
+		if link, err := os.Readlink(filepath.Join(deviceDir, "driver")); err == nil {
+			nic.Driver = filepath.Base(link)
+		}
+		// The speed attribute is -1 or unreadable if the link is down.
+		speed, err := strconv.ParseUint(
+			readSysfsFile(filepath.Join(dirname, "speed")), 10, 32)
+		if err == nil {
+			nic.SpeedMbps = uint(speed)
+		}
+		if drvinfo, err := unix.IoctlGetEthtoolDrvinfo(fd, name); err != nil {
+			logger.Debugf(1, "error getting driver info for: %s: %s\n",
+				name, err)
+		} else {
+			nic.FirmwareVersion = unix.ByteSliceToString(
+				drvinfo.Fw_version[:])
+			if nic.Driver == "" {
+				nic.Driver = unix.ByteSliceToString(drvinfo.Driver[:])
+			}
+		}
+		nics = append(nics, nic)
+	}
+	return nics, nil
+}
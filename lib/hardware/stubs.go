@@ -0,0 +1,14 @@
+//go:build !linux
+
+package hardware
+
+import (
+	"syscall"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func getInventory(logger log.DebugLogger) (*proto.HardwareInventory, error) {
+	return nil, syscall.ENOTSUP
+}
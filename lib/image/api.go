@@ -18,7 +18,8 @@ type Annotation struct {
 }
 
 type DirectoryMetadata struct {
-	OwnerGroup string
+	OwnerGroup      string
+	RetentionPolicy RetentionPolicy `json:",omitzero"`
 }
 
 type Directory struct {
@@ -44,6 +45,16 @@ type Image struct {
 	Tags          tags.Tags
 }
 
+// RetentionPolicy specifies which images in a directory should be kept. An
+// image is kept if it is one of the KeepLatest latest images or if it is newer
+// than KeepNewerThan. Images which are referenced (by the MDB or by VMs) and
+// images with an expiration time are always kept. A zero value disables the
+// policy.
+type RetentionPolicy struct {
+	KeepLatest    uint          `json:",omitempty"`
+	KeepNewerThan time.Duration `json:",omitempty"`
+}
+
 type Package struct {
 	Name    string
 	Size    uint64 // Bytes.
@@ -104,6 +115,15 @@ func (image *Image) VerifyRequiredPaths(requiredPaths map[string]rune) error {
 	return image.verifyRequiredPaths(requiredPaths)
 }
 
+// IsEnabled returns true if the policy may cause images to be deleted.
+func (policy RetentionPolicy) IsEnabled() bool {
+	return policy.KeepLatest > 0 || policy.KeepNewerThan > 0
+}
+
+func (policy RetentionPolicy) String() string {
+	return policy.string()
+}
+
 func SortDirectories(directories []Directory) {
 	sortDirectories(directories)
 }
@@ -0,0 +1,23 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+)
+
+func (policy RetentionPolicy) string() string {
+	if !policy.IsEnabled() {
+		return ""
+	}
+	var fields []string
+	if policy.KeepLatest > 0 {
+		fields = append(fields, fmt.Sprintf("KeepLatest=%d", policy.KeepLatest))
+	}
+	if policy.KeepNewerThan > 0 {
+		fields = append(fields, "KeepNewerThan="+
+			format.Duration(policy.KeepNewerThan))
+	}
+	return strings.Join(fields, ",")
+}
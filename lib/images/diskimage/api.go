@@ -0,0 +1,52 @@
+package diskimage
+
+import (
+	"io"
+)
+
+const (
+	FormatRaw Format = iota
+	FormatQCOW2
+	FormatVHD
+	FormatVHDDynamic
+	FormatVMDK
+)
+
+// Format is the format of a disk image. It implements the flag.Value
+// interface.
+type Format uint
+
+type WriteOptions struct {
+	Compress bool // QCOW2 only. VMDK grains are always compressed.
+}
+
+// GetLength returns the number of bytes that Write would write. This requires
+// a full pass over the data.
+func GetLength(reader io.ReaderAt, size uint64, format Format,
+	options WriteOptions) (uint64, error) {
+	return getLength(reader, size, format, options)
+}
+
+// Write will write a disk image in the specified format to writer. The image
+// data are read from reader, which must provide size bytes. Blocks of zeros
+// are not stored for the sparse formats. The writer need not be seekable, so
+// images may be streamed. Some formats require the data to be read twice. The
+// number of bytes written is returned.
+func Write(writer io.Writer, reader io.ReaderAt, size uint64, format Format,
+	options WriteOptions) (uint64, error) {
+	return write(writer, reader, size, format, options)
+}
+
+// Extension returns the conventional filename extension (including the
+// leading '.') for the format.
+func (format Format) Extension() string {
+	return format.extension()
+}
+
+func (format *Format) Set(value string) error {
+	return format.set(value)
+}
+
+func (format Format) String() string {
+	return format.string()
+}
@@ -0,0 +1,52 @@
+package diskimage
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	formatToExtension = map[Format]string{
+		FormatRaw:        ".raw",
+		FormatQCOW2:      ".qcow2",
+		FormatVHD:        ".vhd",
+		FormatVHDDynamic: ".vhd",
+		FormatVMDK:       ".vmdk",
+	}
+	formatToText = map[Format]string{
+		FormatRaw:        "raw",
+		FormatQCOW2:      "qcow2",
+		FormatVHD:        "vhd",
+		FormatVHDDynamic: "vhd-dynamic",
+		FormatVMDK:       "vmdk",
+	}
+	textToFormat map[string]Format
+)
+
+func init() {
+	textToFormat = make(map[string]Format, len(formatToText))
+	for format, text := range formatToText {
+		textToFormat[text] = format
+	}
+}
+
+func (format Format) extension() string {
+	return formatToExtension[format]
+}
+
+func (format *Format) set(value string) error {
+	if val, ok := textToFormat[value]; !ok {
+		return errors.New("unknown disk image format: " + value)
+	} else {
+		*format = val
+		return nil
+	}
+}
+
+func (format Format) string() string {
+	if text, ok := formatToText[format]; ok {
+		return text
+	} else {
+		return strconv.Itoa(int(format))
+	}
+}
@@ -0,0 +1,134 @@
+package diskimage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+type countingWriter struct {
+	count  uint64
+	writer io.Writer
+}
+
+func getLength(reader io.ReaderAt, size uint64, format Format,
+	options WriteOptions) (uint64, error) {
+	return write(io.Discard, reader, size, format, options)
+}
+
+// getAllocatedBlocks returns a slice indicating which blocks contain non-zero
+// data.
+func getAllocatedBlocks(reader io.ReaderAt, size, blockSize uint64) (
+	[]bool, error) {
+	allocated := make([]bool, (size+blockSize-1)/blockSize)
+	buffer := make([]byte, blockSize)
+	for index := range allocated {
+		err := readBlock(reader, uint64(index)*blockSize, size, buffer)
+		if err != nil {
+			return nil, err
+		}
+		allocated[index] = !isZero(buffer)
+	}
+	return allocated, nil
+}
+
+func isZero(buffer []byte) bool {
+	for _, value := range buffer {
+		if value != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// padTo writes zeros to bring the number of bytes written up to a multiple of
+// alignment.
+func padTo(writer *countingWriter, alignment uint64) error {
+	if remainder := writer.count % alignment; remainder > 0 {
+		return writeZeros(writer, alignment-remainder)
+	}
+	return nil
+}
+
+// readBlock reads len(buffer) bytes at offset. Any part of the buffer beyond
+// size is zero filled.
+func readBlock(reader io.ReaderAt, offset, size uint64, buffer []byte) error {
+	length := uint64(len(buffer))
+	if offset >= size {
+		length = 0
+	} else if offset+length > size {
+		length = size - offset
+	}
+	if length > 0 {
+		nRead, err := reader.ReadAt(buffer[:length], int64(offset))
+		if err != nil && !(err == io.EOF && uint64(nRead) == length) {
+			return err
+		}
+	}
+	for index := length; index < uint64(len(buffer)); index++ {
+		buffer[index] = 0
+	}
+	return nil
+}
+
+func write(writer io.Writer, reader io.ReaderAt, size uint64, format Format,
+	options WriteOptions) (uint64, error) {
+	bufferedWriter := bufio.NewWriterSize(writer, 1<<20)
+	countingWriter := &countingWriter{writer: bufferedWriter}
+	var err error
+	switch format {
+	case FormatRaw:
+		err = writeRaw(countingWriter, reader, size)
+	case FormatQCOW2:
+		err = writeQcow2(countingWriter, reader, size, options)
+	case FormatVHD:
+		err = writeVhdFixed(countingWriter, reader, size)
+	case FormatVHDDynamic:
+		err = writeVhdDynamic(countingWriter, reader, size)
+	case FormatVMDK:
+		err = writeVmdk(countingWriter, reader, size)
+	default:
+		err = fmt.Errorf("unsupported disk image format: %s", format)
+	}
+	if err != nil {
+		return countingWriter.count, err
+	}
+	return countingWriter.count, bufferedWriter.Flush()
+}
+
+func writeRaw(writer *countingWriter, reader io.ReaderAt, size uint64) error {
+	buffer := make([]byte, 1<<20)
+	for offset := uint64(0); offset < size; offset += uint64(len(buffer)) {
+		if err := readBlock(reader, offset, size, buffer); err != nil {
+			return err
+		}
+		if offset+uint64(len(buffer)) > size {
+			buffer = buffer[:size-offset]
+		}
+		if _, err := writer.Write(buffer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZeros(writer *countingWriter, length uint64) error {
+	var zeros [4096]byte
+	for length > 0 {
+		chunk := length
+		if chunk > uint64(len(zeros)) {
+			chunk = uint64(len(zeros))
+		}
+		if _, err := writer.Write(zeros[:chunk]); err != nil {
+			return err
+		}
+		length -= chunk
+	}
+	return nil
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	nWritten, err := w.writer.Write(p)
+	w.count += uint64(nWritten)
+	return nWritten, err
+}
@@ -0,0 +1,250 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+const testSize = 70<<20 + 1000 // Spans several L2 tables/grain tables.
+
+func makeTestData() []byte {
+	data := make([]byte, testSize)
+	rand.New(rand.NewSource(1)).Read(data[:100000])
+	copy(data[40<<20:], bytes.Repeat([]byte("compressible"), 10000))
+	data[testSize-1] = 0xff
+	return data
+}
+
+func writeImage(t *testing.T, data []byte, format Format,
+	options WriteOptions) []byte {
+	buffer := &bytes.Buffer{}
+	nWritten, err := Write(buffer, bytes.NewReader(data), uint64(len(data)),
+		format, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nWritten != uint64(buffer.Len()) {
+		t.Fatalf("wrote: %d, reported: %d", buffer.Len(), nWritten)
+	}
+	length, err := GetLength(bytes.NewReader(data), uint64(len(data)), format,
+		options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length != nWritten {
+		t.Fatalf("GetLength: %d, wrote: %d", length, nWritten)
+	}
+	return buffer.Bytes()
+}
+
+func readQcow2(t *testing.T, image []byte) []byte {
+	if binary.BigEndian.Uint32(image) != qcow2Magic {
+		t.Fatal("bad QCOW2 magic")
+	}
+	if uint64(len(image))%qcow2ClusterSize != 0 {
+		t.Fatal("QCOW2 image not a whole number of clusters")
+	}
+	size := binary.BigEndian.Uint64(image[24:])
+	l1Size := binary.BigEndian.Uint32(image[36:])
+	l1Offset := binary.BigEndian.Uint64(image[40:])
+	refcountOffset := binary.BigEndian.Uint64(image[48:])
+	refcountTableSize := binary.BigEndian.Uint32(image[56:])
+	// Compute reference counts from the metadata and compare.
+	numClusters := uint64(len(image)) / qcow2ClusterSize
+	refcounts := make([]uint16, numClusters)
+	addRefs := func(offset, length uint64) {
+		for cluster := offset / qcow2ClusterSize; cluster <=
+			(offset+length-1)/qcow2ClusterSize; cluster++ {
+			refcounts[cluster]++
+		}
+	}
+	addRefs(0, qcow2ClusterSize)
+	addRefs(l1Offset, uint64(l1Size)*8)
+	addRefs(refcountOffset, uint64(refcountTableSize)*qcow2ClusterSize)
+	output := make([]byte, size)
+	const offsetMask = 1<<qcow2CompressedShift - 1
+	for l1Index := uint64(0); l1Index < uint64(l1Size); l1Index++ {
+		l1Entry := binary.BigEndian.Uint64(image[l1Offset+l1Index*8:])
+		l2Offset := l1Entry &^ qcow2FlagCopied
+		if l2Offset == 0 {
+			continue
+		}
+		addRefs(l2Offset, qcow2ClusterSize)
+		for l2Index := uint64(0); l2Index < qcow2L2Entries; l2Index++ {
+			l2Entry := binary.BigEndian.Uint64(image[l2Offset+l2Index*8:])
+			if l2Entry == 0 {
+				continue
+			}
+			guestOffset := (l1Index*qcow2L2Entries + l2Index) *
+				qcow2ClusterSize
+			var cluster []byte
+			if l2Entry&qcow2FlagCompressed != 0 {
+				offset := l2Entry & offsetMask
+				numSectors := (l2Entry>>qcow2CompressedShift)&0xff + 1
+				start := offset &^ 511
+				addRefs(start, numSectors*512)
+				reader := flate.NewReader(bytes.NewReader(
+					image[offset : start+numSectors*512]))
+				cluster = make([]byte, qcow2ClusterSize)
+				if _, err := io.ReadFull(reader, cluster); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				offset := l2Entry &^ qcow2FlagCopied
+				addRefs(offset, qcow2ClusterSize)
+				cluster = image[offset : offset+qcow2ClusterSize]
+			}
+			copy(output[guestOffset:], cluster)
+		}
+	}
+	for index := uint64(0); index < uint64(refcountTableSize)*
+		qcow2ClusterSize/8; index++ {
+		blockOffset := binary.BigEndian.Uint64(image[refcountOffset+index*8:])
+		if blockOffset == 0 {
+			continue
+		}
+		addRefs(blockOffset, qcow2ClusterSize)
+	}
+	for cluster, expected := range refcounts {
+		blockOffset := binary.BigEndian.Uint64(image[refcountOffset+
+			uint64(cluster/qcow2RefcountEntries)*8:])
+		refcount := binary.BigEndian.Uint16(image[blockOffset+
+			uint64(cluster%qcow2RefcountEntries)*2:])
+		if refcount != expected {
+			t.Fatalf("cluster: %d refcount: %d, expected: %d",
+				cluster, refcount, expected)
+		}
+	}
+	return output
+}
+
+func readVhdDynamic(t *testing.T, image []byte) []byte {
+	footer := image[len(image)-vhdSectorSize:]
+	if !bytes.Equal(footer, image[:vhdSectorSize]) {
+		t.Fatal("footer copy mismatch")
+	}
+	checkVhdChecksum(t, footer, 64)
+	header := image[vhdSectorSize : vhdSectorSize+vhdDynamicHeaderLen]
+	checkVhdChecksum(t, header, 36)
+	size := binary.BigEndian.Uint64(footer[48:])
+	batOffset := binary.BigEndian.Uint64(header[16:])
+	numEntries := binary.BigEndian.Uint32(header[28:])
+	blockSize := uint64(binary.BigEndian.Uint32(header[32:]))
+	output := make([]byte, uint64(numEntries)*blockSize)
+	for index := uint64(0); index < uint64(numEntries); index++ {
+		sector := binary.BigEndian.Uint32(image[batOffset+index*4:])
+		if sector == vhdUnusedBlock {
+			continue
+		}
+		offset := uint64(sector)*vhdSectorSize + blockSize/vhdSectorSize/8
+		copy(output[index*blockSize:], image[offset:offset+blockSize])
+	}
+	return output[:size]
+}
+
+func readVmdk(t *testing.T, image []byte) []byte {
+	if binary.LittleEndian.Uint32(image) != vmdkMagic {
+		t.Fatal("bad VMDK magic")
+	}
+	if binary.LittleEndian.Uint64(image[56:]) != vmdkGdAtEnd {
+		t.Fatal("GD not at end")
+	}
+	if len(image)%vmdkSectorSize != 0 {
+		t.Fatal("VMDK image not a whole number of sectors")
+	}
+	footer := image[len(image)-2*vmdkSectorSize : len(image)-vmdkSectorSize]
+	capacity := binary.LittleEndian.Uint64(footer[12:])
+	gdOffset := binary.LittleEndian.Uint64(footer[56:]) * vmdkSectorSize
+	numGts := ceilDiv(ceilDiv(capacity, vmdkGrainSize), vmdkGtEntries)
+	output := make([]byte, capacity*vmdkSectorSize)
+	for gtIndex := uint64(0); gtIndex < numGts; gtIndex++ {
+		gtOffset := uint64(binary.LittleEndian.Uint32(
+			image[gdOffset+gtIndex*4:])) * vmdkSectorSize
+		if gtOffset == 0 {
+			continue
+		}
+		for gtEntry := uint64(0); gtEntry < vmdkGtEntries; gtEntry++ {
+			offset := uint64(binary.LittleEndian.Uint32(
+				image[gtOffset+gtEntry*4:])) * vmdkSectorSize
+			if offset == 0 {
+				continue
+			}
+			lba := binary.LittleEndian.Uint64(image[offset:])
+			grain := gtIndex*vmdkGtEntries + gtEntry
+			if lba != grain*vmdkGrainSize {
+				t.Fatalf("grain: %d has LBA: %d", grain, lba)
+			}
+			length := uint64(binary.LittleEndian.Uint32(image[offset+8:]))
+			reader, err := zlib.NewReader(
+				bytes.NewReader(image[offset+12 : offset+12+length]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			copy(output[lba*vmdkSectorSize:], data)
+		}
+	}
+	return output
+}
+
+func checkVhdChecksum(t *testing.T, data []byte, offset int) {
+	copied := append([]byte(nil), data...)
+	checksum := binary.BigEndian.Uint32(copied[offset:])
+	binary.BigEndian.PutUint32(copied[offset:], 0)
+	if vhdChecksum(copied) != checksum {
+		t.Fatal("bad VHD checksum")
+	}
+}
+
+func checkOutput(t *testing.T, data, output []byte) {
+	if len(output) < len(data) {
+		t.Fatalf("output length: %d < %d", len(output), len(data))
+	}
+	if !bytes.Equal(data, output[:len(data)]) {
+		t.Fatal("data mismatch")
+	}
+	if !isZero(output[len(data):]) {
+		t.Fatal("non-zero padding")
+	}
+}
+
+func TestQcow2(t *testing.T) {
+	data := makeTestData()
+	image := writeImage(t, data, FormatQCOW2, WriteOptions{})
+	checkOutput(t, data, readQcow2(t, image))
+	compressedImage := writeImage(t, data, FormatQCOW2,
+		WriteOptions{Compress: true})
+	if len(compressedImage) >= len(image) {
+		t.Errorf("compressed image: %d not smaller than: %d",
+			len(compressedImage), len(image))
+	}
+	checkOutput(t, data, readQcow2(t, compressedImage))
+}
+
+func TestVhd(t *testing.T) {
+	data := makeTestData()
+	image := writeImage(t, data, FormatVHD, WriteOptions{})
+	footer := image[len(image)-vhdSectorSize:]
+	checkVhdChecksum(t, footer, 64)
+	checkOutput(t, data, image[:len(image)-vhdSectorSize])
+	image = writeImage(t, data, FormatVHDDynamic, WriteOptions{})
+	if len(image) >= len(data) {
+		t.Errorf("dynamic VHD: %d not smaller than data: %d",
+			len(image), len(data))
+	}
+	checkOutput(t, data, readVhdDynamic(t, image))
+}
+
+func TestVmdk(t *testing.T) {
+	data := makeTestData()
+	checkOutput(t, data, readVmdk(t, writeImage(t, data, FormatVMDK,
+		WriteOptions{})))
+}
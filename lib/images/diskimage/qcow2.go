@@ -0,0 +1,333 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	qcow2ClusterBits     = 16
+	qcow2ClusterSize     = 1 << qcow2ClusterBits
+	qcow2CompressedShift = 62 - (qcow2ClusterBits - 8)
+	qcow2FlagCompressed  = 1 << 62
+	qcow2FlagCopied      = 1 << 63
+	qcow2HeaderSize      = 72
+	qcow2L2Entries       = qcow2ClusterSize / 8
+	qcow2Magic           = 0x514649fb
+	qcow2RefcountEntries = qcow2ClusterSize / 2
+
+	qcow2ClusterZero         = -1
+	qcow2ClusterUncompressed = 0
+)
+
+// qcow2Layout describes where everything is placed in the image. Data clusters
+// are written in the order of the guest offsets.
+type qcow2Layout struct {
+	dataLengths       []int32 // Compressed length or qcow2Cluster* constant.
+	dataOffsets       []uint64
+	l1Offset          uint64
+	l1Size            uint64
+	l2Offsets         []uint64 // Zero: no L2 table.
+	numClusters       uint64
+	refcountBlocks    uint64
+	refcountOffset    uint64
+	refcountTableSize uint64 // Clusters.
+	refcounts         []uint16
+}
+
+type qcow2Compressor struct {
+	buffer bytes.Buffer
+	writer *flate.Writer
+}
+
+func ceilDiv(numerator, denominator uint64) uint64 {
+	return (numerator + denominator - 1) / denominator
+}
+
+// compressedSectorRange returns the first byte offset and the length which
+// QEMU will account for a compressed cluster.
+func compressedSectorRange(offset uint64, length int32) (uint64, uint64) {
+	firstSector := offset >> 9
+	lastSector := (offset + uint64(length) - 1) >> 9
+	return firstSector << 9, (lastSector - firstSector + 1) << 9
+}
+
+func newQcow2Compressor() (*qcow2Compressor, error) {
+	compressor := &qcow2Compressor{}
+	writer, err := flate.NewWriter(&compressor.buffer,
+		flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	compressor.writer = writer
+	return compressor, nil
+}
+
+// compress returns the raw deflate stream for the cluster, or nil if the data
+// do not compress.
+func (compressor *qcow2Compressor) compress(cluster []byte) ([]byte, error) {
+	compressor.buffer.Reset()
+	compressor.writer.Reset(&compressor.buffer)
+	if _, err := compressor.writer.Write(cluster); err != nil {
+		return nil, err
+	}
+	if err := compressor.writer.Close(); err != nil {
+		return nil, err
+	}
+	if compressor.buffer.Len() >= len(cluster) {
+		return nil, nil
+	}
+	return compressor.buffer.Bytes(), nil
+}
+
+// computeQcow2Layout scans the data and computes the layout of the image. If
+// compress is true, each cluster is compressed to determine its length.
+func computeQcow2Layout(reader io.ReaderAt, size uint64, compress bool) (
+	*qcow2Layout, error) {
+	numDataClusters := ceilDiv(size, qcow2ClusterSize)
+	layout := &qcow2Layout{
+		dataLengths: make([]int32, numDataClusters),
+		dataOffsets: make([]uint64, numDataClusters),
+		l1Size:      ceilDiv(numDataClusters, qcow2L2Entries),
+	}
+	layout.l2Offsets = make([]uint64, layout.l1Size)
+	var compressor *qcow2Compressor
+	if compress {
+		var err error
+		if compressor, err = newQcow2Compressor(); err != nil {
+			return nil, err
+		}
+	}
+	buffer := make([]byte, qcow2ClusterSize)
+	var numL2Tables uint64
+	for index := range layout.dataLengths {
+		err := readBlock(reader, uint64(index)*qcow2ClusterSize, size, buffer)
+		if err != nil {
+			return nil, err
+		}
+		if isZero(buffer) {
+			layout.dataLengths[index] = qcow2ClusterZero
+			continue
+		}
+		if l1Index := index / qcow2L2Entries; layout.l2Offsets[l1Index] == 0 {
+			layout.l2Offsets[l1Index] = 1 // Placeholder.
+			numL2Tables++
+		}
+		if compressor != nil {
+			compressed, err := compressor.compress(buffer)
+			if err != nil {
+				return nil, err
+			}
+			layout.dataLengths[index] = int32(len(compressed))
+		}
+	}
+	// The number of refcount blocks depends on the total number of clusters,
+	// which includes the refcount blocks, so iterate until stable.
+	l1Clusters := ceilDiv(layout.l1Size*8, qcow2ClusterSize)
+	var dataStart uint64
+	for {
+		metadataClusters := 1 + l1Clusters + layout.refcountTableSize +
+			layout.refcountBlocks + numL2Tables
+		dataStart = metadataClusters * qcow2ClusterSize
+		layout.numClusters = metadataClusters +
+			layout.placeData(dataStart)/qcow2ClusterSize
+		refcountBlocks := ceilDiv(layout.numClusters, qcow2RefcountEntries)
+		refcountTableSize := ceilDiv(refcountBlocks*8, qcow2ClusterSize)
+		if refcountBlocks == layout.refcountBlocks &&
+			refcountTableSize == layout.refcountTableSize {
+			break
+		}
+		layout.refcountBlocks = refcountBlocks
+		layout.refcountTableSize = refcountTableSize
+	}
+	layout.l1Offset = qcow2ClusterSize
+	layout.refcountOffset = layout.l1Offset + l1Clusters*qcow2ClusterSize
+	offset := layout.refcountOffset +
+		(layout.refcountTableSize+layout.refcountBlocks)*qcow2ClusterSize
+	for index, l2Offset := range layout.l2Offsets {
+		if l2Offset != 0 {
+			layout.l2Offsets[index] = offset
+			offset += qcow2ClusterSize
+		}
+	}
+	if offset != dataStart {
+		return nil, fmt.Errorf("inconsistent QCOW2 layout: %d != %d",
+			offset, dataStart)
+	}
+	layout.refcounts = make([]uint16, layout.numClusters)
+	for index := uint64(0); index < dataStart/qcow2ClusterSize; index++ {
+		layout.refcounts[index] = 1
+	}
+	for index, length := range layout.dataLengths {
+		switch length {
+		case qcow2ClusterZero:
+		case qcow2ClusterUncompressed:
+			layout.refcounts[layout.dataOffsets[index]/qcow2ClusterSize]++
+		default:
+			start, length := compressedSectorRange(layout.dataOffsets[index],
+				length)
+			first := start / qcow2ClusterSize
+			last := (start + length - 1) / qcow2ClusterSize
+			for cluster := first; cluster <= last; cluster++ {
+				layout.refcounts[cluster]++
+			}
+		}
+	}
+	return layout, nil
+}
+
+func writeQcow2(writer *countingWriter, reader io.ReaderAt, size uint64,
+	options WriteOptions) error {
+	layout, err := computeQcow2Layout(reader, size, options.Compress)
+	if err != nil {
+		return err
+	}
+	if err := layout.writeMetadata(writer, size); err != nil {
+		return err
+	}
+	var compressor *qcow2Compressor
+	if options.Compress {
+		if compressor, err = newQcow2Compressor(); err != nil {
+			return err
+		}
+	}
+	buffer := make([]byte, qcow2ClusterSize)
+	for index, length := range layout.dataLengths {
+		if length == qcow2ClusterZero {
+			continue
+		}
+		err := readBlock(reader, uint64(index)*qcow2ClusterSize, size, buffer)
+		if err != nil {
+			return err
+		}
+		data := buffer
+		if length != qcow2ClusterUncompressed {
+			if data, err = compressor.compress(buffer); err != nil {
+				return err
+			}
+			if len(data) != int(length) {
+				return fmt.Errorf("data at offset: %d changed while writing",
+					uint64(index)*qcow2ClusterSize)
+			}
+		} else if isZero(buffer) {
+			return fmt.Errorf("data at offset: %d changed while writing",
+				uint64(index)*qcow2ClusterSize)
+		}
+		if writer.count < layout.dataOffsets[index] {
+			err := writeZeros(writer,
+				layout.dataOffsets[index]-writer.count)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+	}
+	return padTo(writer, qcow2ClusterSize)
+}
+
+// placeData assigns offsets to the data clusters, starting at dataStart, and
+// returns the number of bytes used (rounded up to a whole cluster).
+// Compressed clusters are packed together, other clusters are aligned.
+func (layout *qcow2Layout) placeData(dataStart uint64) uint64 {
+	offset := dataStart
+	for index, length := range layout.dataLengths {
+		switch length {
+		case qcow2ClusterZero:
+		case qcow2ClusterUncompressed:
+			offset = ceilDiv(offset, qcow2ClusterSize) * qcow2ClusterSize
+			layout.dataOffsets[index] = offset
+			offset += qcow2ClusterSize
+		default:
+			layout.dataOffsets[index] = offset
+			offset += uint64(length)
+		}
+	}
+	return ceilDiv(offset-dataStart, qcow2ClusterSize) * qcow2ClusterSize
+}
+
+func (layout *qcow2Layout) writeMetadata(writer *countingWriter,
+	size uint64) error {
+	header := make([]byte, qcow2ClusterSize)
+	binary.BigEndian.PutUint32(header[0:], qcow2Magic)
+	binary.BigEndian.PutUint32(header[4:], 2) // Version.
+	binary.BigEndian.PutUint32(header[20:], qcow2ClusterBits)
+	binary.BigEndian.PutUint64(header[24:], size)
+	binary.BigEndian.PutUint32(header[36:], uint32(layout.l1Size))
+	binary.BigEndian.PutUint64(header[40:], layout.l1Offset)
+	binary.BigEndian.PutUint64(header[48:], layout.refcountOffset)
+	binary.BigEndian.PutUint32(header[56:], uint32(layout.refcountTableSize))
+	if _, err := writer.Write(header); err != nil {
+		return err
+	}
+	// L1 table.
+	entry := make([]byte, 8)
+	for _, l2Offset := range layout.l2Offsets {
+		if l2Offset == 0 {
+			binary.BigEndian.PutUint64(entry, 0)
+		} else {
+			binary.BigEndian.PutUint64(entry, l2Offset|qcow2FlagCopied)
+		}
+		if _, err := writer.Write(entry); err != nil {
+			return err
+		}
+	}
+	if err := padTo(writer, qcow2ClusterSize); err != nil {
+		return err
+	}
+	// Refcount table.
+	refcountBlocksOffset := layout.refcountOffset +
+		layout.refcountTableSize*qcow2ClusterSize
+	for index := uint64(0); index < layout.refcountBlocks; index++ {
+		binary.BigEndian.PutUint64(entry,
+			refcountBlocksOffset+index*qcow2ClusterSize)
+		if _, err := writer.Write(entry); err != nil {
+			return err
+		}
+	}
+	if err := padTo(writer, qcow2ClusterSize); err != nil {
+		return err
+	}
+	// Refcount blocks.
+	for _, refcount := range layout.refcounts {
+		if _, err := writer.Write(
+			[]byte{byte(refcount >> 8), byte(refcount)}); err != nil {
+			return err
+		}
+	}
+	if err := padTo(writer, qcow2ClusterSize); err != nil {
+		return err
+	}
+	// L2 tables.
+	for l1Index, l2Offset := range layout.l2Offsets {
+		if l2Offset == 0 {
+			continue
+		}
+		for l2Index := 0; l2Index < qcow2L2Entries; l2Index++ {
+			index := l1Index*qcow2L2Entries + l2Index
+			var value uint64
+			if index < len(layout.dataLengths) {
+				offset := layout.dataOffsets[index]
+				switch length := layout.dataLengths[index]; length {
+				case qcow2ClusterZero:
+				case qcow2ClusterUncompressed:
+					value = offset | qcow2FlagCopied
+				default:
+					numSectors := ((offset + uint64(length) - 1) >> 9) -
+						(offset >> 9)
+					value = qcow2FlagCompressed |
+						numSectors<<qcow2CompressedShift | offset
+				}
+			}
+			binary.BigEndian.PutUint64(entry, value)
+			if _, err := writer.Write(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
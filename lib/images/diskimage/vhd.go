@@ -0,0 +1,177 @@
+package diskimage
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	vhdBatOffset        = 3 * vhdSectorSize
+	vhdBlockSize        = 2 << 20
+	vhdDiskTypeDynamic  = 3
+	vhdDiskTypeFixed    = 2
+	vhdDynamicHeaderLen = 1024
+	vhdSectorSize       = 512
+	vhdUnusedBlock      = 0xffffffff
+)
+
+var vhdEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// getVhdGeometry returns the cylinders, heads and sectors per track, using the
+// algorithm in the VHD specification.
+func getVhdGeometry(size uint64) (uint16, uint8, uint8) {
+	totalSectors := size / vhdSectorSize
+	if totalSectors > 65535*16*255 {
+		totalSectors = 65535 * 16 * 255
+	}
+	var cylinderTimesHeads, heads, sectorsPerTrack uint64
+	if totalSectors >= 65535*16*63 {
+		sectorsPerTrack = 255
+		heads = 16
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+	} else {
+		sectorsPerTrack = 17
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+		heads = (cylinderTimesHeads + 1023) / 1024
+		if heads < 4 {
+			heads = 4
+		}
+		if cylinderTimesHeads >= heads*1024 || heads > 16 {
+			sectorsPerTrack = 31
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+		if cylinderTimesHeads >= heads*1024 {
+			sectorsPerTrack = 63
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+	}
+	return uint16(cylinderTimesHeads / heads), uint8(heads),
+		uint8(sectorsPerTrack)
+}
+
+func makeVhdFooter(size uint64, diskType uint32, dataOffset uint64) (
+	[]byte, error) {
+	footer := make([]byte, vhdSectorSize)
+	copy(footer[0:], "conectix")
+	binary.BigEndian.PutUint32(footer[8:], 2) // Features: reserved bit.
+	binary.BigEndian.PutUint32(footer[12:], 0x00010000)
+	binary.BigEndian.PutUint64(footer[16:], dataOffset)
+	binary.BigEndian.PutUint32(footer[24:],
+		uint32(time.Since(vhdEpoch)/time.Second))
+	copy(footer[28:], "dom ")
+	binary.BigEndian.PutUint32(footer[32:], 0x00010000)
+	copy(footer[36:], "Wi2k")
+	binary.BigEndian.PutUint64(footer[40:], size)
+	binary.BigEndian.PutUint64(footer[48:], size)
+	cylinders, heads, sectorsPerTrack := getVhdGeometry(size)
+	binary.BigEndian.PutUint16(footer[56:], cylinders)
+	footer[58] = heads
+	footer[59] = sectorsPerTrack
+	binary.BigEndian.PutUint32(footer[60:], diskType)
+	if _, err := rand.Read(footer[68:84]); err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(footer[64:], vhdChecksum(footer))
+	return footer, nil
+}
+
+// vhdChecksum returns the one's complement of the sum of all the bytes,
+// excluding the checksum field at offset 64 of the footer (or offset 36 of the
+// dynamic header), which must be zero.
+func vhdChecksum(data []byte) uint32 {
+	var sum uint32
+	for _, value := range data {
+		sum += uint32(value)
+	}
+	return ^sum
+}
+
+func writeVhdDynamic(writer *countingWriter, reader io.ReaderAt,
+	size uint64) error {
+	allocated, err := getAllocatedBlocks(reader, size, vhdBlockSize)
+	if err != nil {
+		return err
+	}
+	footer, err := makeVhdFooter(ceilDiv(size, vhdSectorSize)*vhdSectorSize,
+		vhdDiskTypeDynamic, vhdSectorSize)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(footer); err != nil {
+		return err
+	}
+	header := make([]byte, vhdDynamicHeaderLen)
+	copy(header[0:], "cxsparse")
+	binary.BigEndian.PutUint64(header[8:], 0xffffffffffffffff)
+	binary.BigEndian.PutUint64(header[16:], vhdBatOffset)
+	binary.BigEndian.PutUint32(header[24:], 0x00010000)
+	binary.BigEndian.PutUint32(header[28:], uint32(len(allocated)))
+	binary.BigEndian.PutUint32(header[32:], vhdBlockSize)
+	binary.BigEndian.PutUint32(header[36:], vhdChecksum(header))
+	if _, err := writer.Write(header); err != nil {
+		return err
+	}
+	// Block Allocation Table. Each block is preceded by a sector bitmap.
+	const bitmapSize = vhdBlockSize / vhdSectorSize / 8
+	batSize := ceilDiv(uint64(len(allocated))*4, vhdSectorSize) *
+		vhdSectorSize
+	nextSector := (vhdBatOffset + batSize) / vhdSectorSize
+	entry := make([]byte, 4)
+	for _, isAllocated := range allocated {
+		if isAllocated {
+			binary.BigEndian.PutUint32(entry, uint32(nextSector))
+			nextSector += (bitmapSize + vhdBlockSize) / vhdSectorSize
+		} else {
+			binary.BigEndian.PutUint32(entry, vhdUnusedBlock)
+		}
+		if _, err := writer.Write(entry); err != nil {
+			return err
+		}
+	}
+	if err := padTo(writer, vhdSectorSize); err != nil {
+		return err
+	}
+	bitmap := make([]byte, bitmapSize)
+	for index := range bitmap {
+		bitmap[index] = 0xff
+	}
+	buffer := make([]byte, vhdBlockSize)
+	for index, isAllocated := range allocated {
+		if !isAllocated {
+			continue
+		}
+		err := readBlock(reader, uint64(index)*vhdBlockSize, size, buffer)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(bitmap); err != nil {
+			return err
+		}
+		if _, err := writer.Write(buffer); err != nil {
+			return err
+		}
+	}
+	_, err = writer.Write(footer)
+	return err
+}
+
+func writeVhdFixed(writer *countingWriter, reader io.ReaderAt,
+	size uint64) error {
+	footer, err := makeVhdFooter(ceilDiv(size, vhdSectorSize)*vhdSectorSize,
+		vhdDiskTypeFixed, 0xffffffffffffffff)
+	if err != nil {
+		return err
+	}
+	if err := writeRaw(writer, reader, size); err != nil {
+		return err
+	}
+	if err := padTo(writer, vhdSectorSize); err != nil {
+		return err
+	}
+	_, err = writer.Write(footer)
+	return err
+}
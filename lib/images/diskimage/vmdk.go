@@ -0,0 +1,189 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	vmdkCompressionDeflate = 1
+	vmdkDescriptorOffset   = 1
+	vmdkDescriptorSize     = 20 // Sectors.
+	vmdkFlagCompressed     = 1 << 16
+	vmdkFlagMarkers        = 1 << 17
+	vmdkFlagNewlineTest    = 1 << 0
+	vmdkGdAtEnd            = 0xffffffffffffffff
+	vmdkGrainSize          = 128 // Sectors.
+	vmdkGrainBytes         = vmdkGrainSize * vmdkSectorSize
+	vmdkGtEntries          = 512
+	vmdkMagic              = 0x564d444b // "KDMV".
+	vmdkMarkerFooter       = 3
+	vmdkMarkerGd           = 2
+	vmdkMarkerGt           = 1
+	vmdkOverhead           = 128 // Sectors before the first grain.
+	vmdkSectorSize         = 512
+)
+
+func makeVmdkDescriptor(capacity uint64) ([]byte, error) {
+	var cid [4]byte
+	if _, err := rand.Read(cid[:]); err != nil {
+		return nil, err
+	}
+	cylinders := capacity / (255 * 63)
+	if cylinders > 65535 {
+		cylinders = 65535
+	}
+	buffer := &bytes.Buffer{}
+	fmt.Fprintln(buffer, "# Disk DescriptorFile")
+	fmt.Fprintln(buffer, "version=1")
+	fmt.Fprintf(buffer, "CID=%08x\n", binary.LittleEndian.Uint32(cid[:]))
+	fmt.Fprintln(buffer, "parentCID=ffffffff")
+	fmt.Fprintln(buffer, `createType="streamOptimized"`)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, "# Extent description")
+	fmt.Fprintf(buffer, "RW %d SPARSE \"disk.vmdk\"\n", capacity)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, "# The Disk Data Base")
+	fmt.Fprintln(buffer, "#DDB")
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, `ddb.adapterType = "lsilogic"`)
+	fmt.Fprintf(buffer, "ddb.geometry.cylinders = \"%d\"\n", cylinders)
+	fmt.Fprintln(buffer, `ddb.geometry.heads = "255"`)
+	fmt.Fprintln(buffer, `ddb.geometry.sectors = "63"`)
+	fmt.Fprintln(buffer, `ddb.virtualHWVersion = "4"`)
+	if buffer.Len() > vmdkDescriptorSize*vmdkSectorSize {
+		return nil, fmt.Errorf("VMDK descriptor too large")
+	}
+	return buffer.Bytes(), nil
+}
+
+func makeVmdkHeader(capacity, gdOffset uint64) []byte {
+	header := make([]byte, vmdkSectorSize)
+	binary.LittleEndian.PutUint32(header[0:], vmdkMagic)
+	binary.LittleEndian.PutUint32(header[4:], 3) // Version.
+	binary.LittleEndian.PutUint32(header[8:],
+		vmdkFlagNewlineTest|vmdkFlagCompressed|vmdkFlagMarkers)
+	binary.LittleEndian.PutUint64(header[12:], capacity)
+	binary.LittleEndian.PutUint64(header[20:], vmdkGrainSize)
+	binary.LittleEndian.PutUint64(header[28:], vmdkDescriptorOffset)
+	binary.LittleEndian.PutUint64(header[36:], vmdkDescriptorSize)
+	binary.LittleEndian.PutUint32(header[44:], vmdkGtEntries)
+	binary.LittleEndian.PutUint64(header[56:], gdOffset)
+	binary.LittleEndian.PutUint64(header[64:], vmdkOverhead)
+	header[73] = '\n'
+	header[74] = ' '
+	header[75] = '\r'
+	header[76] = '\n'
+	binary.LittleEndian.PutUint16(header[77:], vmdkCompressionDeflate)
+	return header
+}
+
+func writeVmdk(writer *countingWriter, reader io.ReaderAt, size uint64) error {
+	capacity := ceilDiv(size, vmdkSectorSize)
+	descriptor, err := makeVmdkDescriptor(capacity)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(makeVmdkHeader(capacity, vmdkGdAtEnd)); err != nil {
+		return err
+	}
+	if _, err := writer.Write(descriptor); err != nil {
+		return err
+	}
+	if err := writeZeros(writer,
+		vmdkOverhead*vmdkSectorSize-writer.count); err != nil {
+		return err
+	}
+	numGrains := ceilDiv(size, vmdkGrainBytes)
+	grainDirectory := make([]uint32, ceilDiv(numGrains, vmdkGtEntries))
+	grainTable := make([]byte, vmdkGtEntries*4)
+	buffer := make([]byte, vmdkGrainBytes)
+	compressed := &bytes.Buffer{}
+	compressor := zlib.NewWriter(compressed)
+	for gtIndex := range grainDirectory {
+		for index := range grainTable {
+			grainTable[index] = 0
+		}
+		var haveGrains bool
+		for gtEntry := uint64(0); gtEntry < vmdkGtEntries; gtEntry++ {
+			grain := uint64(gtIndex)*vmdkGtEntries + gtEntry
+			if grain >= numGrains {
+				break
+			}
+			err := readBlock(reader, grain*vmdkGrainBytes, size, buffer)
+			if err != nil {
+				return err
+			}
+			if isZero(buffer) {
+				continue
+			}
+			compressed.Reset()
+			compressor.Reset(compressed)
+			if _, err := compressor.Write(buffer); err != nil {
+				return err
+			}
+			if err := compressor.Close(); err != nil {
+				return err
+			}
+			binary.LittleEndian.PutUint32(grainTable[gtEntry*4:],
+				uint32(writer.count/vmdkSectorSize))
+			marker := make([]byte, 12)
+			binary.LittleEndian.PutUint64(marker[0:], grain*vmdkGrainSize)
+			binary.LittleEndian.PutUint32(marker[8:], uint32(compressed.Len()))
+			if _, err := writer.Write(marker); err != nil {
+				return err
+			}
+			if _, err := writer.Write(compressed.Bytes()); err != nil {
+				return err
+			}
+			if err := padTo(writer, vmdkSectorSize); err != nil {
+				return err
+			}
+			haveGrains = true
+		}
+		if !haveGrains {
+			continue
+		}
+		err := writeVmdkMarker(writer, uint64(len(grainTable))/vmdkSectorSize,
+			vmdkMarkerGt)
+		if err != nil {
+			return err
+		}
+		grainDirectory[gtIndex] = uint32(writer.count / vmdkSectorSize)
+		if _, err := writer.Write(grainTable); err != nil {
+			return err
+		}
+	}
+	gdSectors := ceilDiv(uint64(len(grainDirectory))*4, vmdkSectorSize)
+	if err := writeVmdkMarker(writer, gdSectors, vmdkMarkerGd); err != nil {
+		return err
+	}
+	gdOffset := writer.count / vmdkSectorSize
+	if err := binary.Write(writer, binary.LittleEndian,
+		grainDirectory); err != nil {
+		return err
+	}
+	if err := padTo(writer, vmdkSectorSize); err != nil {
+		return err
+	}
+	if err := writeVmdkMarker(writer, 1, vmdkMarkerFooter); err != nil {
+		return err
+	}
+	if _, err := writer.Write(makeVmdkHeader(capacity, gdOffset)); err != nil {
+		return err
+	}
+	return writeZeros(writer, vmdkSectorSize) // End-of-stream marker.
+}
+
+func writeVmdkMarker(writer *countingWriter, numSectors uint64,
+	markerType uint32) error {
+	marker := make([]byte, vmdkSectorSize)
+	binary.LittleEndian.PutUint64(marker[0:], numSectors)
+	binary.LittleEndian.PutUint32(marker[12:], markerType)
+	_, err := writer.Write(marker)
+	return err
+}
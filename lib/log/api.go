@@ -32,3 +32,23 @@ type FullDebugLogger interface {
 	DebugLogLevelGetter
 	DebugLogLevelSetter
 }
+
+// Standard field names for structured log records.
+const (
+	FieldComponent = "component"
+	FieldHostname  = "hostname"
+	FieldImage     = "image"
+	FieldRequestId = "requestId"
+	FieldSub       = "sub" // The sub being acted upon, not the log source.
+	FieldUsername  = "username"
+)
+
+// Fields are the structured fields (name/value pairs) attached to a log record.
+type Fields map[string]string
+
+// FieldsLogger is implemented by loggers which can record structured fields
+// with each message. Use the lib/log/fieldlogger package to attach fields.
+type FieldsLogger interface {
+	DebugWithFields(level uint8, fields Fields, msg string)
+	PrintWithFields(fields Fields, msg string)
+}
@@ -0,0 +1,96 @@
+package fieldlogger
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+type Logger struct {
+	fields       log.Fields
+	fieldsLogger log.FieldsLogger // nil: fields are written as a prefix.
+	logger       log.DebugLogger
+	prefix       string
+}
+
+// New will create a Logger which attaches fields to every message logged to
+// logger. If logger implements the log.FieldsLogger interface, the fields are
+// passed down as structured fields (merged with any fields that it attaches),
+// otherwise they are written as a "[name=value ...] " prefix to each message.
+// Fields with empty values are dropped. Since a Logger implements the
+// log.FieldsLogger interface, Loggers may be nested to add more fields.
+func New(fields log.Fields, logger log.Logger) *Logger {
+	return newLogger(fields, logger)
+}
+
+// FormatFields will return the fields as a "name=value ..." string, sorted by
+// name.
+func FormatFields(fields log.Fields) string {
+	return formatFields(fields)
+}
+
+// Merge will return a new set of fields with the contents of base and fields.
+// Values in fields take precedence. Fields with empty values are dropped.
+func Merge(base, fields log.Fields) log.Fields {
+	return merge(base, fields)
+}
+
+func (l *Logger) Debug(level uint8, v ...interface{}) {
+	l.debug(level, fmt.Sprint(v...))
+}
+
+func (l *Logger) Debugf(level uint8, format string, v ...interface{}) {
+	l.debug(level, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Debugln(level uint8, v ...interface{}) {
+	l.debug(level, fmt.Sprintln(v...))
+}
+
+// DebugWithFields will log msg at the specified debug level with the fields for
+// the Logger, merged with fields.
+func (l *Logger) DebugWithFields(level uint8, fields log.Fields, msg string) {
+	l.debugWithFields(level, fields, msg)
+}
+
+func (l *Logger) Fatal(v ...interface{}) {
+	l.logger.Fatal(l.prefix + fmt.Sprint(v...))
+}
+
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.logger.Fatal(l.prefix + fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Fatalln(v ...interface{}) {
+	l.logger.Fatal(l.prefix + fmt.Sprintln(v...))
+}
+
+func (l *Logger) Panic(v ...interface{}) {
+	l.logger.Panic(l.prefix + fmt.Sprint(v...))
+}
+
+func (l *Logger) Panicf(format string, v ...interface{}) {
+	l.logger.Panic(l.prefix + fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Panicln(v ...interface{}) {
+	l.logger.Panic(l.prefix + fmt.Sprintln(v...))
+}
+
+func (l *Logger) Print(v ...interface{}) {
+	l.print(fmt.Sprint(v...))
+}
+
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.print(fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Println(v ...interface{}) {
+	l.print(fmt.Sprintln(v...))
+}
+
+// PrintWithFields will log msg with the fields for the Logger, merged with
+// fields.
+func (l *Logger) PrintWithFields(fields log.Fields, msg string) {
+	l.printWithFields(fields, msg)
+}
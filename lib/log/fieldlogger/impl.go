@@ -0,0 +1,92 @@
+package fieldlogger
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/debuglogger"
+)
+
+func formatFields(fields log.Fields) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buffer := &strings.Builder{}
+	for _, name := range names {
+		if buffer.Len() > 0 {
+			buffer.WriteByte(' ')
+		}
+		buffer.WriteString(name)
+		buffer.WriteByte('=')
+		buffer.WriteString(fields[name])
+	}
+	return buffer.String()
+}
+func makePrefix(fields log.Fields) string {
+	if len(fields) < 1 {
+		return ""
+	}
+	return "[" + formatFields(fields) + "] "
+}
+
+func merge(base, fields log.Fields) log.Fields {
+	merged := make(log.Fields, len(base)+len(fields))
+	for name, value := range base {
+		if value != "" {
+			merged[name] = value
+		}
+	}
+	for name, value := range fields {
+		if value != "" {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+func newLogger(fields log.Fields, logger log.Logger) *Logger {
+	l := &Logger{
+		fields: merge(nil, fields),
+		logger: debuglogger.Upgrade(logger),
+	}
+	if fieldsLogger, ok := logger.(log.FieldsLogger); ok {
+		l.fieldsLogger = fieldsLogger
+	}
+	l.prefix = makePrefix(l.fields)
+	return l
+}
+
+func (l *Logger) debug(level uint8, msg string) {
+	if l.fieldsLogger == nil {
+		l.logger.Debug(level, l.prefix+msg)
+	} else {
+		l.fieldsLogger.DebugWithFields(level, l.fields, msg)
+	}
+}
+
+func (l *Logger) debugWithFields(level uint8, fields log.Fields, msg string) {
+	if l.fieldsLogger == nil {
+		l.logger.Debug(level, makePrefix(merge(l.fields, fields))+msg)
+	} else {
+		l.fieldsLogger.DebugWithFields(level, merge(l.fields, fields), msg)
+	}
+}
+
+func (l *Logger) print(msg string) {
+	if l.fieldsLogger == nil {
+		l.logger.Print(l.prefix + msg)
+	} else {
+		l.fieldsLogger.PrintWithFields(l.fields, msg)
+	}
+}
+
+func (l *Logger) printWithFields(fields log.Fields, msg string) {
+	if l.fieldsLogger == nil {
+		l.logger.Print(makePrefix(merge(l.fields, fields)) + msg)
+	} else {
+		l.fieldsLogger.PrintWithFields(merge(l.fields, fields), msg)
+	}
+}
@@ -0,0 +1,99 @@
+package fieldlogger
+
+import (
+	"bytes"
+	stdlog "log"
+	"reflect"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+type recordType struct {
+	level  int16
+	fields log.Fields
+	msg    string
+}
+
+// fieldsLoggerType records the messages logged with fields.
+type fieldsLoggerType struct {
+	*stdlog.Logger
+	records []recordType
+}
+
+func (l *fieldsLoggerType) DebugWithFields(level uint8, fields log.Fields,
+	msg string) {
+	l.records = append(l.records, recordType{int16(level), fields, msg})
+}
+
+func (l *fieldsLoggerType) PrintWithFields(fields log.Fields, msg string) {
+	l.records = append(l.records, recordType{-1, fields, msg})
+}
+
+func TestFormatFields(t *testing.T) {
+	tests := []struct {
+		fields log.Fields
+		want   string
+	}{
+		{nil, ""},
+		{log.Fields{"a": "1"}, "a=1"},
+		{log.Fields{"c": "3", "a": "1", "b": "2"}, "a=1 b=2 c=3"},
+	}
+	for _, test := range tests {
+		if got := FormatFields(test.fields); got != test.want {
+			t.Errorf("FormatFields(%v): got: %q, want: %q", test.fields, got,
+				test.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := log.Fields{"a": "1", "b": "2", "e": ""}
+	got := Merge(base, log.Fields{"b": "3", "c": "4", "d": ""})
+	want := log.Fields{"a": "1", "b": "3", "c": "4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if base["b"] != "2" {
+		t.Errorf("base modified: %v", base)
+	}
+	if got := Merge(nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("Merge(nil, nil): got: %v, want empty", got)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := New(log.Fields{"b": "2", "a": "1", "c": ""},
+		stdlog.New(buffer, "", 0))
+	logger.Print("plain")
+	logger.PrintWithFields(log.Fields{"a": "3", "d": "4"}, "merged")
+	logger.Debug(0, "dropped")
+	want := "[a=1 b=2] plain\n[a=3 b=2 d=4] merged\n"
+	if got := buffer.String(); got != want {
+		t.Errorf("got:\n%swant:\n%s", got, want)
+	}
+	buffer.Reset()
+	New(nil, stdlog.New(buffer, "", 0)).Print("no fields")
+	if got := buffer.String(); got != "no fields\n" {
+		t.Errorf("got: %q, want: \"no fields\\n\"", got)
+	}
+}
+
+func TestFieldsLogger(t *testing.T) {
+	fieldsLogger := &fieldsLoggerType{Logger: stdlog.New(&bytes.Buffer{}, "",
+		0)}
+	logger := New(log.Fields{"a": "1", "b": "2"}, fieldsLogger)
+	nested := New(log.Fields{"b": "3", "c": "4"}, logger)
+	logger.Print("outer")
+	nested.Printf("inner %d", 1)
+	nested.DebugWithFields(2, log.Fields{"d": "5"}, "debug")
+	want := []recordType{
+		{-1, log.Fields{"a": "1", "b": "2"}, "outer"},
+		{-1, log.Fields{"a": "1", "b": "3", "c": "4"}, "inner 1"},
+		{2, log.Fields{"a": "1", "b": "3", "c": "4", "d": "5"}, "debug"},
+	}
+	if !reflect.DeepEqual(fieldsLogger.records, want) {
+		t.Errorf("got: %v, want: %v", fieldsLogger.records, want)
+	}
+}
@@ -0,0 +1,155 @@
+package jsonlogger
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+var (
+	// Interface checks.
+	_ log.FieldsLogger    = (*Logger)(nil)
+	_ log.FullDebugLogger = (*Logger)(nil)
+)
+
+// Record is a structured log record. It is encoded as a single line of JSON.
+type Record struct {
+	Time       time.Time  `json:"time"`
+	DebugLevel int16      `json:"debugLevel"` // -1: not a debug message.
+	Fields     log.Fields `json:"fields,omitempty"`
+	Message    string     `json:"message"`
+}
+
+type Logger struct {
+	fields log.Fields
+	level  atomic.Int32 // Holds an int16.
+	mutex  sync.Mutex   // Protect writer.
+	writer io.Writer
+}
+
+// New will create a Logger which writes JSON encoded records to writer, one
+// per line. The specified fields are attached to every record (see
+// StandardFields). It implements the log.DebugLogger and log.FieldsLogger
+// interfaces. By default, the max debug level is -1, meaning all debug logs are
+// dropped (ignored).
+func New(writer io.Writer, fields log.Fields) *Logger {
+	return newLogger(writer, fields)
+}
+
+// Parse will parse a line containing a JSON encoded record. It returns false if
+// the line does not contain a record.
+func Parse(line []byte) (Record, bool) {
+	return parse(line)
+}
+
+// StandardFields will return the fields which identify the process: the
+// component (the program name) and the hostname.
+func StandardFields() log.Fields {
+	return standardFields()
+}
+
+// Encode will encode the record as a single line of JSON, including the
+// trailing newline.
+func (r Record) Encode() []byte {
+	return r.encode()
+}
+
+// Match returns true if the record has all the specified fields with the
+// specified values.
+func (r Record) Match(fields log.Fields) bool {
+	return r.match(fields)
+}
+
+// Debug will call the Print method if level is less than or equal to the max
+// debug level for the Logger.
+func (l *Logger) Debug(level uint8, v ...interface{}) {
+	l.log(int16(level), nil, fmt.Sprint(v...))
+}
+
+// Debugf will call the Printf method if level is less than or equal to the max
+// debug level for the Logger.
+func (l *Logger) Debugf(level uint8, format string, v ...interface{}) {
+	l.log(int16(level), nil, fmt.Sprintf(format, v...))
+}
+
+// Debugln will call the Println method if level is less than or equal to the
+// max debug level for the Logger.
+func (l *Logger) Debugln(level uint8, v ...interface{}) {
+	l.log(int16(level), nil, fmt.Sprintln(v...))
+}
+
+// DebugWithFields will log msg with the additional fields if level is less
+// than or equal to the max debug level for the Logger.
+func (l *Logger) DebugWithFields(level uint8, fields log.Fields, msg string) {
+	l.log(int16(level), fields, msg)
+}
+
+// Fatal is equivalent to Print() followed by a call to os.Exit(1).
+func (l *Logger) Fatal(v ...interface{}) {
+	l.fatals(fmt.Sprint(v...))
+}
+
+// Fatalf is equivalent to Printf() followed by a call to os.Exit(1).
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.fatals(fmt.Sprintf(format, v...))
+}
+
+// Fatalln is equivalent to Println() followed by a call to os.Exit(1).
+func (l *Logger) Fatalln(v ...interface{}) {
+	l.fatals(fmt.Sprintln(v...))
+}
+
+// GetLevel gets the current maximum debug level.
+func (l *Logger) GetLevel() int16 {
+	return int16(l.level.Load())
+}
+
+// Panic is equivalent to Print() followed by a call to panic().
+func (l *Logger) Panic(v ...interface{}) {
+	l.panics(fmt.Sprint(v...))
+}
+
+// Panicf is equivalent to Printf() followed by a call to panic().
+func (l *Logger) Panicf(format string, v ...interface{}) {
+	l.panics(fmt.Sprintf(format, v...))
+}
+
+// Panicln is equivalent to Println() followed by a call to panic().
+func (l *Logger) Panicln(v ...interface{}) {
+	l.panics(fmt.Sprintln(v...))
+}
+
+// Print prints to the logger. Arguments are handled in the manner of fmt.Print.
+func (l *Logger) Print(v ...interface{}) {
+	l.log(-1, nil, fmt.Sprint(v...))
+}
+
+// Printf prints to the logger. Arguments are handled in the manner of
+// fmt.Printf.
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.log(-1, nil, fmt.Sprintf(format, v...))
+}
+
+// Println prints to the logger. Arguments are handled in the manner of
+// fmt.Println.
+func (l *Logger) Println(v ...interface{}) {
+	l.log(-1, nil, fmt.Sprintln(v...))
+}
+
+// PrintWithFields will log msg with the additional fields.
+func (l *Logger) PrintWithFields(fields log.Fields, msg string) {
+	l.log(-1, fields, msg)
+}
+
+// SetLevel sets the maximum debug level. A negative level will cause all debug
+// messages to be dropped.
+func (l *Logger) SetLevel(maxLevel int16) {
+	if maxLevel < -1 {
+		maxLevel = -1
+	}
+	l.level.Store(int32(maxLevel))
+}
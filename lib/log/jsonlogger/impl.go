@@ -0,0 +1,95 @@
+package jsonlogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/fieldlogger"
+)
+
+func newLogger(writer io.Writer, fields log.Fields) *Logger {
+	l := &Logger{
+		fields: fieldlogger.Merge(nil, fields),
+		writer: writer,
+	}
+	l.level.Store(-1)
+	return l
+}
+
+func parse(line []byte) (Record, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) < 1 || line[0] != '{' {
+		return Record{}, false
+	}
+	var record Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return Record{}, false
+	}
+	if record.Time.IsZero() {
+		return Record{}, false
+	}
+	return record, true
+}
+
+func standardFields() log.Fields {
+	fields := log.Fields{log.FieldComponent: filepath.Base(os.Args[0])}
+	if hostname, err := os.Hostname(); err == nil {
+		fields[log.FieldHostname] = hostname
+	}
+	return fields
+}
+
+func (r Record) encode() []byte {
+	r.Message = strings.TrimSuffix(r.Message, "\n")
+	data, err := json.Marshal(r)
+	if err != nil {
+		// Should never happen: fall back to a record with just the error.
+		data, _ = json.Marshal(Record{
+			Time:       r.Time,
+			DebugLevel: r.DebugLevel,
+			Message:    err.Error(),
+		})
+	}
+	return append(data, '\n')
+}
+
+func (r Record) match(fields log.Fields) bool {
+	for name, value := range fields {
+		if r.Fields[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Logger) fatals(msg string) {
+	l.log(-1, nil, msg)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level int16, fields log.Fields, msg string) {
+	if int32(level) > l.level.Load() {
+		return
+	}
+	record := Record{
+		Time:       time.Now(),
+		DebugLevel: level,
+		Fields:     fieldlogger.Merge(l.fields, fields),
+		Message:    msg,
+	}
+	data := record.encode()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.writer.Write(data)
+}
+
+func (l *Logger) panics(msg string) {
+	l.log(-1, nil, msg)
+	panic(msg)
+}
@@ -0,0 +1,165 @@
+package jsonlogger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func readRecords(t *testing.T, buffer *bytes.Buffer) []Record {
+	var records []Record
+	for _, line := range strings.SplitAfter(buffer.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		record, ok := Parse([]byte(line))
+		if !ok {
+			t.Fatalf("unable to parse: %s", line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestEncodeParse(t *testing.T) {
+	record := Record{
+		Time:       time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		DebugLevel: 2,
+		Fields:     log.Fields{log.FieldImage: "test/image"},
+		Message:    "hello\n",
+	}
+	data := record.Encode()
+	if bytes.Count(data, []byte("\n")) != 1 || data[len(data)-1] != '\n' {
+		t.Fatalf("not a single line: %q", data)
+	}
+	parsed, ok := Parse(data)
+	if !ok {
+		t.Fatalf("unable to parse: %s", data)
+	}
+	if !parsed.Time.Equal(record.Time) {
+		t.Errorf("time: got: %s, want: %s", parsed.Time, record.Time)
+	}
+	if parsed.DebugLevel != 2 {
+		t.Errorf("debug level: got: %d, want: 2", parsed.DebugLevel)
+	}
+	if parsed.Message != "hello" {
+		t.Errorf("message: got: %q, want: \"hello\"", parsed.Message)
+	}
+	if !parsed.Match(record.Fields) {
+		t.Errorf("fields: got: %v, want: %v", parsed.Fields, record.Fields)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"2024/01/02 03:04:05 plain text",
+		"{not JSON}",
+		`{"message":"no time"}`,
+	} {
+		if _, ok := Parse([]byte(line)); ok {
+			t.Errorf("parsed invalid line: %q", line)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	record := Record{Fields: log.Fields{"a": "1", "b": "2"}}
+	tests := []struct {
+		fields log.Fields
+		want   bool
+	}{
+		{nil, true},
+		{log.Fields{"a": "1"}, true},
+		{log.Fields{"a": "1", "b": "2"}, true},
+		{log.Fields{"a": "2"}, false},
+		{log.Fields{"c": "3"}, false},
+		{log.Fields{"a": "1", "c": "3"}, false},
+	}
+	for _, test := range tests {
+		if got := record.Match(test.fields); got != test.want {
+			t.Errorf("Match(%v): got: %t, want: %t", test.fields, got,
+				test.want)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := New(buffer, nil)
+	if level := logger.GetLevel(); level != -1 {
+		t.Fatalf("default level: got: %d, want: -1", level)
+	}
+	logger.Debug(0, "dropped")
+	logger.Print("printed")
+	logger.SetLevel(1)
+	logger.Debug(1, "debug1")
+	logger.Debug(2, "dropped")
+	logger.SetLevel(-5)
+	if level := logger.GetLevel(); level != -1 {
+		t.Fatalf("clamped level: got: %d, want: -1", level)
+	}
+	logger.Debug(0, "dropped")
+	records := readRecords(t, buffer)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0].Message != "printed" || records[0].DebugLevel != -1 {
+		t.Errorf("got: %+v, want: printed at level -1", records[0])
+	}
+	if records[1].Message != "debug1" || records[1].DebugLevel != 1 {
+		t.Errorf("got: %+v, want: debug1 at level 1", records[1])
+	}
+}
+
+func TestLevelConcurrent(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := New(buffer, nil)
+	var waitGroup sync.WaitGroup
+	for index := 0; index < 4; index++ {
+		waitGroup.Add(1)
+		go func(level int16) {
+			defer waitGroup.Done()
+			for count := 0; count < 100; count++ {
+				logger.SetLevel(level)
+				logger.Debug(uint8(count%4), "message")
+			}
+		}(int16(index))
+	}
+	waitGroup.Wait()
+	readRecords(t, buffer)
+}
+
+func TestFields(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := New(buffer, log.Fields{
+		log.FieldComponent: "test",
+		log.FieldHostname:  "",
+		log.FieldSub:       "sub0",
+	})
+	logger.PrintWithFields(log.Fields{
+		log.FieldRequestId: "42",
+		log.FieldSub:       "sub1",
+	}, "with fields")
+	logger.Print("without fields")
+	records := readRecords(t, buffer)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	want := log.Fields{
+		log.FieldComponent: "test",
+		log.FieldRequestId: "42",
+		log.FieldSub:       "sub1",
+	}
+	if !records[0].Match(want) || len(records[0].Fields) != len(want) {
+		t.Errorf("got: %v, want: %v", records[0].Fields, want)
+	}
+	want = log.Fields{log.FieldComponent: "test", log.FieldSub: "sub0"}
+	if !records[1].Match(want) || len(records[1].Fields) != len(want) {
+		t.Errorf("got: %v, want: %v", records[1].Fields, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/log/debuglogger"
+	"github.com/Cloud-Foundations/Dominator/lib/log/fieldlogger"
 )
 
 type Logger struct {
@@ -28,6 +29,17 @@ func (l *Logger) Debugln(level uint8, v ...interface{}) {
 	l.logger.Debug(level, l.prefix+fmt.Sprintln(v...))
 }
 
+// DebugWithFields will pass the fields through if the underlying logger
+// supports structured fields, otherwise they are prepended to the message.
+func (l *Logger) DebugWithFields(level uint8, fields log.Fields, msg string) {
+	if fieldsLogger, ok := l.logger.(log.FieldsLogger); ok {
+		fieldsLogger.DebugWithFields(level, fields, l.prefix+msg)
+	} else {
+		l.logger.Debug(level, "["+fieldlogger.FormatFields(fields)+"] "+
+			l.prefix+msg)
+	}
+}
+
 func (l *Logger) Fatal(v ...interface{}) {
 	l.logger.Fatal(l.prefix + fmt.Sprint(v...))
 }
@@ -63,3 +75,14 @@ func (l *Logger) Printf(format string, v ...interface{}) {
 func (l *Logger) Println(v ...interface{}) {
 	l.logger.Print(l.prefix + fmt.Sprintln(v...))
 }
+
+// PrintWithFields will pass the fields through if the underlying logger
+// supports structured fields, otherwise they are prepended to the message.
+func (l *Logger) PrintWithFields(fields log.Fields, msg string) {
+	if fieldsLogger, ok := l.logger.(log.FieldsLogger); ok {
+		fieldsLogger.PrintWithFields(fields, l.prefix+msg)
+	} else {
+		l.logger.Print("[" + fieldlogger.FormatFields(fields) + "] " +
+			l.prefix + msg)
+	}
+}
@@ -18,17 +18,22 @@ var (
 		"initial debug log level")
 	logAtStartup = flag.Bool("logAtStartup", true,
 		"If true, write a log entry at startup")
+	logFormat = flag.String("logFormat", "text",
+		"Format of log records: text or json (structured)")
 	logSubseconds = flag.Bool("logSubseconds", false,
 		"if true, datestamps will have subsecond resolution")
 
-	// Interface check.
+	// Interface checks.
+	_ liblog.FieldsLogger    = (*Logger)(nil)
 	_ liblog.FullDebugLogger = (*Logger)(nil)
 )
 
 type Logger struct {
 	accessChecker  func(method string, authInfo *srpc.AuthInformation) bool
 	circularBuffer *logbuf.LogBuffer
+	fields         liblog.Fields // Standard fields for JSON records.
 	flags          int
+	jsonFormat     bool
 	mutex          sync.Mutex // Lock everything below.
 	haveStreamers  bool       // Only locked when updating.
 	level          int16      // Only locked when updating.
@@ -39,6 +44,7 @@ type Logger struct {
 type streamerType struct {
 	debugLevel   int16
 	excludeRegex *regexp.Regexp // nil: nothing excluded. Processed after incl.
+	fields       liblog.Fields  // Empty: everything included.
 	includeRegex *regexp.Regexp // nil: everything included.
 	output       chan<- []byte
 }
@@ -103,6 +109,14 @@ func (l *Logger) Debugln(level uint8, v ...interface{}) {
 	l.debugln(int16(level), v...)
 }
 
+// DebugWithFields will log msg with the structured fields if level is less
+// than or equal to the max debug level for the Logger. If the log format is
+// text, the fields are not written.
+func (l *Logger) DebugWithFields(level uint8, fields liblog.Fields,
+	msg string) {
+	l.debugWithFields(int16(level), fields, msg)
+}
+
 // GetLevel gets the current maximum debug level.
 func (l *Logger) GetLevel() int16 {
 	return l.level
@@ -162,6 +176,12 @@ func (l *Logger) Println(v ...interface{}) {
 	l.prints(fmt.Sprintln(v...))
 }
 
+// PrintWithFields will log msg with the structured fields. If the log format
+// is text, the fields are not written.
+func (l *Logger) PrintWithFields(fields liblog.Fields, msg string) {
+	l.printWithFields(fields, msg)
+}
+
 // SetAccessChecker sets the function that is called when SRPC methods are
 // called for the Logger. This allows the application to control which users or
 // groups are permitted to remotely control the Logger.
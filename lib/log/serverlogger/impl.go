@@ -13,12 +13,19 @@ import (
 	"sync"
 	"time"
 
+	liblog "github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/fieldlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/log/jsonlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/logbuf"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/serverutil"
 	proto "github.com/Cloud-Foundations/Dominator/proto/logger"
 )
 
+// logPackagesPrefix is the prefix of the functions in the logger packages,
+// which are skipped when finding the caller which logged a message.
+const logPackagesPrefix = "github.com/Cloud-Foundations/Dominator/lib/log/"
+
 type loggerMapT struct {
 	*serverutil.PerUserMethodLimiter
 	sync.Mutex
@@ -41,6 +48,23 @@ func init() {
 	srpc.RegisterName("Logger", loggerMap)
 }
 
+// getCallDepth returns the calldepth to pass to log.Logger.Output from the
+// caller of getCallDepth so that the file and line number are those of the
+// first caller outside the logger packages. This is correct when the Logger is
+// wrapped by other loggers, such as a fieldlogger.Logger.
+func getCallDepth() int {
+	pcs := make([]uintptr, 32)
+	numPcs := runtime.Callers(2, pcs) // Start at the caller of getCallDepth.
+	frames := runtime.CallersFrames(pcs[:numPcs])
+	for depth := 1; ; depth++ {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, logPackagesPrefix) ||
+			strings.HasSuffix(frame.File, "_test.go") || !more {
+			return depth
+		}
+	}
+}
+
 func getCallerName(depth int) string {
 	if pc, _, _, ok := runtime.Caller(depth); !ok {
 		return "UNKNOWN"
@@ -65,10 +89,15 @@ func newLogger(name string, options logbuf.Options, flags int) *Logger {
 	circularBuffer := logbuf.NewWithOptions(options)
 	logger := &Logger{
 		circularBuffer: circularBuffer,
+		fields:         jsonlogger.StandardFields(),
 		flags:          flags,
+		jsonFormat:     *logFormat == "json",
 		level:          int16(*initialLogDebugLevel),
 		streamers:      make(map[*streamerType]struct{}),
 	}
+	if name != "" {
+		logger.fields[liblog.FieldComponent] += "/" + name
+	}
 	if logger.level < -1 {
 		logger.level = -1
 	}
@@ -98,33 +127,60 @@ func (l *Logger) checkAuth(authInfo *srpc.AuthInformation) error {
 
 func (l *Logger) debug(level int16, v ...interface{}) {
 	if l.maxLevel >= level {
-		l.log(level, fmt.Sprint(v...), false)
+		l.log(level, nil, fmt.Sprint(v...), false)
 	}
 }
 
 func (l *Logger) debugf(level int16, format string, v ...interface{}) {
 	if l.maxLevel >= level {
-		l.log(level, fmt.Sprintf(format, v...), false)
+		l.log(level, nil, fmt.Sprintf(format, v...), false)
 	}
 }
 
 func (l *Logger) debugln(level int16, v ...interface{}) {
 	if l.maxLevel >= level {
-		l.log(level, fmt.Sprintln(v...), false)
+		l.log(level, nil, fmt.Sprintln(v...), false)
+	}
+}
+
+func (l *Logger) debugWithFields(level int16, fields liblog.Fields,
+	msg string) {
+	if l.maxLevel >= level {
+		l.log(level, fields, msg, false)
 	}
 }
 
 func (l *Logger) fatals(msg string) {
-	l.log(-1, msg, true)
+	l.log(-1, nil, msg, true)
 	os.Exit(1)
 }
 
-func (l *Logger) log(level int16, msg string, dying bool) {
+func (l *Logger) format(level int16, fields liblog.Fields,
+	msg string) []byte {
+	if l.jsonFormat {
+		record := jsonlogger.Record{
+			Time:       time.Now(),
+			DebugLevel: level,
+			Fields:     fieldlogger.Merge(l.fields, fields),
+			Message:    msg,
+		}
+		return record.Encode()
+	}
 	buffer := &bytes.Buffer{}
 	rawLogger := log.New(buffer, "", l.flags)
-	rawLogger.Output(4, msg)
+	calldepth := 1
+	if l.flags&(log.Lshortfile|log.Llongfile) != 0 {
+		calldepth = getCallDepth()
+	}
+	rawLogger.Output(calldepth, msg)
+	return buffer.Bytes()
+}
+
+func (l *Logger) log(level int16, fields liblog.Fields, msg string,
+	dying bool) {
+	data := l.format(level, fields, msg)
 	if l.level >= level {
-		l.circularBuffer.Write(buffer.Bytes())
+		l.circularBuffer.Write(data)
 	}
 	if !l.haveStreamers { // Fast return if no streamers.
 		return
@@ -134,12 +190,13 @@ func (l *Logger) log(level int16, msg string, dying bool) {
 	defer l.mutex.Unlock()
 	for streamer := range l.streamers {
 		if streamer.debugLevel >= level &&
+			streamer.matchFields(l.fields, fields) &&
 			(streamer.includeRegex == nil ||
-				streamer.includeRegex.Match(buffer.Bytes())) &&
+				streamer.includeRegex.Match(data)) &&
 			(streamer.excludeRegex == nil ||
-				!streamer.excludeRegex.Match(buffer.Bytes())) {
+				!streamer.excludeRegex.Match(data)) {
 			select {
-			case streamer.output <- buffer.Bytes():
+			case streamer.output <- data:
 			default:
 				delete(l.streamers, streamer)
 				close(streamer.output)
@@ -164,7 +221,10 @@ func (l *Logger) makeStreamer(request proto.WatchRequest) (
 	if request.DebugLevel < -1 {
 		request.DebugLevel = -1
 	}
-	streamer := &streamerType{debugLevel: request.DebugLevel}
+	streamer := &streamerType{
+		debugLevel: request.DebugLevel,
+		fields:     request.Fields,
+	}
 	if request.ExcludeRegex != "" {
 		var err error
 		streamer.excludeRegex, err = regexp.Compile(request.ExcludeRegex)
@@ -183,12 +243,16 @@ func (l *Logger) makeStreamer(request proto.WatchRequest) (
 }
 
 func (l *Logger) panics(msg string) {
-	l.log(-1, msg, true)
+	l.log(-1, nil, msg, true)
 	panic(msg)
 }
 
 func (l *Logger) prints(msg string) {
-	l.log(-1, msg, false)
+	l.log(-1, nil, msg, false)
+}
+
+func (l *Logger) printWithFields(fields liblog.Fields, msg string) {
+	l.log(-1, fields, msg, false)
 }
 
 func (l *Logger) setLevel(maxLevel int16) {
@@ -211,6 +275,22 @@ func (l *Logger) updateMaxLevel() {
 	l.maxLevel = maxLevel
 }
 
+// matchFields returns true if the streamer wants records with the standard and
+// per-record fields.
+func (streamer *streamerType) matchFields(standardFields,
+	fields liblog.Fields) bool {
+	for name, value := range streamer.fields {
+		if fieldValue, ok := fields[name]; ok {
+			if fieldValue != value {
+				return false
+			}
+		} else if standardFields[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
 func (l *Logger) watch(conn *srpc.Conn, streamer *streamerType) {
 	channel := make(chan []byte, 256)
 	streamer.output = channel
@@ -0,0 +1,42 @@
+package serverlogger
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	liblog "github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/fieldlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/logbuf"
+)
+
+func TestCallerFile(t *testing.T) {
+	logger := NewWithOptions("testCallerFile", logbuf.Options{},
+		log.Lshortfile)
+	logger.Print("direct")
+	logger.Debug(0, "dropped")
+	logger.SetLevel(0)
+	logger.Debug(0, "debug")
+	fieldLogger := fieldlogger.New(liblog.Fields{"a": "1"}, logger)
+	fieldLogger.Print("wrapped")
+	fieldlogger.New(liblog.Fields{"b": "2"}, fieldLogger).Print("nested")
+	buffer := &bytes.Buffer{}
+	if err := logger.circularBuffer.Dump(buffer, "", "", false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	messages := []string{"direct", "debug", "wrapped", "nested"}
+	if len(lines) < len(messages) {
+		t.Fatalf("got %d lines, want at least %d:\n%s",
+			len(lines), len(messages), buffer.String())
+	}
+	lines = lines[len(lines)-len(messages):]
+	for index, line := range lines {
+		if !strings.HasPrefix(line, "impl_test.go:") ||
+			!strings.HasSuffix(line, " "+messages[index]) {
+			t.Errorf("got: %s, want: impl_test.go:N: %s",
+				line, messages[index])
+		}
+	}
+}
@@ -27,10 +27,11 @@ type countingWriter struct {
 }
 
 // parseRegexp will parse "exclude=" and "include=" queries which must contain
-// regular expressions. It will return a list of exclude and include compiled
-// regular expressions or nil if there are none, and true on success. On failure
-// (such as for a parse error), it writes an error message and returns
-// nil, false.
+// regular expressions and "field=" queries which must contain name=value pairs
+// to match in structured (JSON) records. It will return a list of exclude and
+// include compiled regular expressions and fields or nil if there are none, and
+// true on success. On failure (such as for a parse error), it writes an error
+// message and returns nil, false.
 func parseRegexp(w http.ResponseWriter, req *http.Request) (
 	*regexpListType, bool) {
 	regexpList := &regexpListType{}
@@ -51,6 +52,19 @@ func parseRegexp(w http.ResponseWriter, req *http.Request) (
 		}
 		regexpList.excludeList = append(regexpList.excludeList, re)
 	}
+	for _, value := range req.URL.Query()["field"] {
+		name, fieldValue, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "bad field (expected name=value): "+value)
+			return nil, false
+		}
+		if regexpList.fields == nil {
+			regexpList.fields = make(map[string]string)
+		}
+		regexpList.fields[name] = fieldValue
+	}
 	for _, value := range req.URL.Query()["include"] {
 		expression, err := url.QueryUnescape(value)
 		if err != nil {
@@ -68,7 +82,8 @@ func parseRegexp(w http.ResponseWriter, req *http.Request) (
 		}
 		regexpList.includeList = append(regexpList.includeList, re)
 	}
-	if len(regexpList.excludeList) < 1 && len(regexpList.includeList) < 1 {
+	if len(regexpList.excludeList) < 1 && len(regexpList.includeList) < 1 &&
+		len(regexpList.fields) < 1 {
 		return nil, true
 	}
 	return regexpList, true
@@ -19,6 +19,8 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/jsonlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
 )
 
@@ -29,6 +31,7 @@ const (
 
 type regexpListType struct {
 	excludeList []*regexp.Regexp
+	fields      log.Fields // Only match structured records with these fields.
 	includeList []*regexp.Regexp
 }
 
@@ -368,7 +371,11 @@ func (lb *LogBuffer) dumpSince(writer io.Writer, name string,
 			foundReopenMessage = true
 			continue
 		}
-		if len(line) >= minLength {
+		if record, ok := jsonlogger.Parse([]byte(line)); ok {
+			if record.Time.Before(earliestTime) {
+				continue
+			}
+		} else if len(line) >= minLength {
 			timeString := line[:minLength-2]
 			timeStamp, err := time.ParseInLocation(timeFormat, timeString,
 				time.Local)
@@ -416,6 +423,9 @@ func (rl *regexpListType) include(b []byte) bool {
 	if rl == nil {
 		return true
 	}
+	if !rl.matchFields(b) {
+		return false
+	}
 	for _, re := range rl.excludeList {
 		if re.Match(b) {
 			return false
@@ -436,6 +446,9 @@ func (rl *regexpListType) includeString(str string) bool {
 	if rl == nil {
 		return true
 	}
+	if !rl.matchFields([]byte(str)) {
+		return false
+	}
 	for _, re := range rl.excludeList {
 		if re.MatchString(str) {
 			return false
@@ -452,6 +465,19 @@ func (rl *regexpListType) includeString(str string) bool {
 	return false
 }
 
+// matchFields returns true if no fields were specified or if the line is a
+// structured record which has all the fields.
+func (rl *regexpListType) matchFields(line []byte) bool {
+	if len(rl.fields) < 1 {
+		return true
+	}
+	if record, ok := jsonlogger.Parse(line); !ok {
+		return false
+	} else {
+		return record.Match(rl.fields)
+	}
+}
+
 func reverseEntries(entries [][]byte) {
 	length := len(entries)
 	for index := 0; index < length/2; index++ {
@@ -49,7 +49,15 @@ func BindAndDial(network, localAddr, remoteAddr string, timeout time.Duration) (
 // success, else an error is returned. The device will be destroyed when the
 // file is closed.
 func CreateTapDevice() (*os.File, string, error) {
-	return createTapDevice()
+	return createTapDevice("")
+}
+
+// CreateTapDeviceWithName will create a "tap" network device with the
+// specified interface name. The tap device file is returned on success, else
+// an error is returned. The device will be destroyed when the file is closed.
+func CreateTapDeviceWithName(name string) (*os.File, error) {
+	file, _, err := createTapDevice(name)
+	return file, err
 }
 
 // GetBridgeVlanId will get the VLAN Id associated with the uplink EtherNet
@@ -7,6 +7,6 @@ import (
 	"os"
 )
 
-func createTapDevice() (*os.File, string, error) {
+func createTapDevice(name string) (*os.File, string, error) {
 	return nil, "", errors.New("tap devices not implemented on this OS")
 }
@@ -1,6 +1,7 @@
 package net
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"syscall"
@@ -22,12 +23,17 @@ type ifReq struct {
 	pad   [0x28 - 0x10 - 2]byte
 }
 
-func createTapDevice() (*os.File, string, error) {
+func createTapDevice(name string) (*os.File, string, error) {
+	var req ifReq
+	if len(name) >= len(req.Name) {
+		return nil, "", errors.New("interface name too long: " + name)
+	}
+	copy(req.Name[:], name)
+	req.Flags = cIFF_TAP | cIFF_NO_PI
 	file, err := os.OpenFile("/dev/net/tun", os.O_RDWR, 0)
 	if err != nil {
 		return nil, "", err
 	}
-	req := ifReq{Flags: cIFF_TAP | cIFF_NO_PI}
 	err = wsyscall.Ioctl(int(file.Fd()), syscall.TUNSETIFF,
 		uintptr(unsafe.Pointer(&req)))
 	if err != nil {
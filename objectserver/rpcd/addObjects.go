@@ -6,9 +6,10 @@ import (
 )
 
 func (t *srpcType) AddObjects(conn *srpc.Conn) error {
-	if t.replicationMaster == "" {
+	replicationMaster := t.getReplicationMaster()
+	if replicationMaster == "" {
 		return lib.AddObjects(conn, conn, conn, t.objectServer, t.logger)
 	}
 	return lib.AddObjectsWithMaster(conn, conn, conn, t.objectServer,
-		t.replicationMaster, t.logger)
+		replicationMaster, t.logger)
 }
@@ -20,13 +20,16 @@ type Config struct {
 type Params struct {
 	Logger       log.DebugLogger
 	ObjectServer objectserver.StashingObjectServer
+	// ReplicationMasterGetter is optional. If provided, it is called to get
+	// the current replication master, overriding Config.ReplicationMaster.
+	ReplicationMasterGetter func() string
 }
 
 type srpcType struct {
-	objectServer      objectserver.StashingObjectServer
-	replicationMaster string
-	getSemaphore      chan bool
-	logger            log.DebugLogger
+	objectServer         objectserver.StashingObjectServer
+	getReplicationMaster func() string
+	getSemaphore         chan bool
+	logger               log.DebugLogger
 }
 
 type htmlWriter struct {
@@ -40,10 +43,15 @@ func (hw *htmlWriter) WriteHtml(writer io.Writer) {
 func Setup(config Config, params Params) *htmlWriter {
 	getSemaphore := make(chan bool, 100)
 	srpcObj := &srpcType{
-		objectServer:      params.ObjectServer,
-		replicationMaster: config.ReplicationMaster,
-		getSemaphore:      getSemaphore,
-		logger:            params.Logger,
+		objectServer:         params.ObjectServer,
+		getReplicationMaster: params.ReplicationMasterGetter,
+		getSemaphore:         getSemaphore,
+		logger:               params.Logger,
+	}
+	if srpcObj.getReplicationMaster == nil {
+		srpcObj.getReplicationMaster = func() string {
+			return config.ReplicationMaster
+		}
 	}
 	var publicMethods []string
 	if config.AllowPublicAddObjects {
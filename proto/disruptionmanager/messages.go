@@ -1,10 +1,31 @@
 package disruptionmanager
 
 import (
+	"time"
+
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
 	"github.com/Cloud-Foundations/Dominator/proto/sub"
 )
 
+// AddBlackout RPC request.
+type AddBlackoutRequest struct {
+	Blackout Blackout
+}
+
+// AddBlackout RPC response.
+type AddBlackoutResponse struct {
+	Error string
+}
+
+// Blackout describes a period during which no new disruptions are permitted.
+type Blackout struct {
+	Groups []string // Group identifiers. If empty, applies to all groups.
+	Name   string   // Unique name, used to remove the blackout.
+	Reason string
+	Start  time.Time
+	End    time.Time
+}
+
 // DisruptionCancel RPC request.
 type DisruptionCancelRequest struct {
 	MDB mdb.Machine
@@ -49,4 +70,23 @@ type DisruptionRequestResponse struct {
 	Response sub.DisruptionState
 }
 
+// ListBlackouts RPC request.
+type ListBlackoutsRequest struct{}
+
+// ListBlackouts RPC response.
+type ListBlackoutsResponse struct {
+	Blackouts []Blackout
+	Error     string
+}
+
+// RemoveBlackout RPC request.
+type RemoveBlackoutRequest struct {
+	Name string
+}
+
+// RemoveBlackout RPC response.
+type RemoveBlackoutResponse struct {
+	Error string
+}
+
 type RequestType uint
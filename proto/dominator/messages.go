@@ -8,6 +8,13 @@ import (
 	"github.com/Cloud-Foundations/Dominator/proto/sub"
 )
 
+const (
+	SubEventImageChange    = "ImageChange"
+	SubEventStatusChange   = "StatusChange"
+	SubEventUpdateFinished = "UpdateFinished"
+	SubEventUpdateStarted  = "UpdateStarted"
+)
+
 type ClearSafetyShutoffRequest struct {
 	Hostname string
 }
@@ -60,6 +67,7 @@ type GetSubsConfigurationResponse sub.Configuration
 
 type GetInfoForSubsRequest struct {
 	Hostnames        []string       // Empty: match all hostnames.
+	ImagesToMatch    []string       // Empty: match all images.
 	LocationsToMatch []string       // Empty: match all locations.
 	StatusesToMatch  []string       // Empty: match all statuses.
 	TagsToMatch      tags.MatchTags // Empty: match all tags.
@@ -70,8 +78,30 @@ type GetInfoForSubsResponse struct {
 	Subs  []SubInfo
 }
 
+// The HeartbeatRequest and HeartbeatResponse messages are exchanged between a
+// pair of dominators running in active/standby mode.
+type HeartbeatRequest struct {
+	Active      bool
+	ActiveSince time.Time `json:",omitempty"`
+	Hostname    string
+	StartTime   time.Time
+}
+
+type HeartbeatResponse struct {
+	Active                bool
+	ActiveSince           time.Time `json:",omitempty"`
+	DefaultImage          string    `json:",omitempty"`
+	Error                 string
+	Hostname              string
+	StartTime             time.Time
+	UpdatesDisabledBy     string    `json:",omitempty"`
+	UpdatesDisabledReason string    `json:",omitempty"`
+	UpdatesDisabledTime   time.Time `json:",omitempty"`
+}
+
 type ListSubsRequest struct {
 	Hostnames        []string            // Empty: match all hostnames.
+	ImagesToMatch    []string            // Empty: match all images.
 	LocationsToMatch []string            // Empty: match all locations.
 	StatusesToMatch  []string            // Empty: match all statuses.
 	TagsToMatch      map[string][]string // Empty: match all tags.
@@ -82,12 +112,57 @@ type ListSubsResponse struct {
 	Hostnames []string
 }
 
+type PreviewImageChangeRequest struct {
+	HostnameRegex    string         // Empty: match all hostnames.
+	Hostnames        []string       // Empty: match all hostnames.
+	ImageName        string         // Candidate RequiredImage.
+	LocationsToMatch []string       // Empty: match all locations.
+	TagsToMatch      tags.MatchTags // Empty: match all tags.
+}
+
+type PreviewImageChangeResponse struct {
+	Error string
+	Subs  []SubImageChangePreview
+}
+
 type SetDefaultImageRequest struct {
 	ImageName string
 }
 
 type SetDefaultImageResponse struct{}
 
+type SubEvent struct {
+	Duration  time.Duration `json:",omitempty"` // UpdateFinished only.
+	Error     string        `json:",omitempty"`
+	Hostname  string
+	Image     string `json:",omitempty"`
+	OldImage  string `json:",omitempty"` // ImageChange only.
+	OldStatus string `json:",omitempty"` // StatusChange only.
+	RequestId string `json:",omitempty"` // Update events only.
+	Status    string `json:",omitempty"`
+	Time      time.Time
+	Type      string
+}
+
+// SubImageChangePreview summarises the update a sub would receive if its
+// RequiredImage was changed.
+type SubImageChangePreview struct {
+	Hostname             string
+	BytesToFetch         uint64   `json:",omitempty"`
+	DirectoriesToMake    uint     `json:",omitempty"`
+	Error                string   `json:",omitempty"`
+	HardlinksToMake      uint     `json:",omitempty"`
+	HighImpactTriggers   []string `json:",omitempty"`
+	InodesToChange       uint     `json:",omitempty"`
+	InodesToMake         uint     `json:",omitempty"`
+	MissingComputedFiles bool     `json:",omitempty"` // Or unknown.
+	ObjectsToFetch       uint     `json:",omitempty"`
+	PathsToDelete        uint     `json:",omitempty"`
+	RebootTriggers       []string `json:",omitempty"`
+	Triggers             []string `json:",omitempty"` // Services to restart.
+	Unsafe               bool     `json:",omitempty"` // Too many deletions.
+}
+
 type SubInfo struct {
 	mdb.Machine
 	LastAddress         string              `json:",omitempty"`
@@ -101,3 +176,13 @@ type SubInfo struct {
 	Status              string
 	SystemUptime        *time.Duration `json:",omitempty"`
 }
+
+type WatchSubEventsRequest struct {
+	Hostnames []string // Empty: match all hostnames.
+	Types     []string // Empty: match all event types.
+}
+
+type WatchSubEventsResponse struct { // Multiple responses are sent.
+	Error  string // If non-empty, this is the final response.
+	Events []SubEvent
+}
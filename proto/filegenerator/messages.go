@@ -1,6 +1,8 @@
 package filegenerator
 
 import (
+	"time"
+
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
 )
@@ -31,6 +33,51 @@ type FileInfo struct {
 	Pathname string
 	Hash     hash.Hash
 	Length   uint64
+	Error    string // If not empty, Hash is zero and there are no data.
+}
+
+// The following RPCs may be used to inspect the data generated for machines.
+// A zero hash refers to the current version.
+
+type DiffFileVersionsRequest struct {
+	Hostname  string
+	Pathname  string
+	LeftHash  hash.Hash
+	RightHash hash.Hash
+}
+
+type DiffFileVersionsResponse struct {
+	Diff  string // Unified diff. Empty if the versions are the same.
+	Error string
+}
+
+type GetFileHistoryRequest struct {
+	Hostname string
+	Pathname string
+}
+
+type GetFileHistoryResponse struct {
+	Entries []HistoryEntry // Oldest first.
+	Error   string
+}
+
+type GetFileVersionRequest struct {
+	Hostname string
+	Pathname string
+	Hash     hash.Hash
+}
+
+type GetFileVersionResponse struct {
+	Data  []byte
+	Entry HistoryEntry
+	Error string
+}
+
+type HistoryEntry struct {
+	Hash   hash.Hash
+	Length uint64
+	Source string // Template version, commit ID, programme and so on.
+	Time   time.Time
 }
 
 type ListGeneratorsRequest struct{}
@@ -39,6 +86,23 @@ type ListGeneratorsResponse struct {
 	Pathnames []string
 }
 
+// PreviewTemplateRequest requests the data which would be generated for a
+// machine from a new template. If Machine is nil, the MDB data for Hostname
+// known to the server are used. If Pathname is not empty, the result is
+// compared with the current data for the machine.
+type PreviewTemplateRequest struct {
+	Hostname string
+	Machine  *mdb.Machine
+	Pathname string
+	Template []byte
+}
+
+type PreviewTemplateResponse struct {
+	Data  []byte
+	Diff  string // Unified diff from the current data (if Pathname given).
+	Error string
+}
+
 type YieldResponse struct {
 	Hostname string
 	Files    []FileInfo
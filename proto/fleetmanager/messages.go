@@ -52,10 +52,12 @@ type GetMachineInfoRequest struct {
 }
 
 type GetMachineInfoResponse struct {
-	Error    string          `json:",omitempty"`
-	Location string          `json:",omitempty"`
-	Machine  Machine         `json:",omitempty"`
-	Subnets  []*proto.Subnet `json:",omitempty"`
+	Error              string                   `json:",omitempty"`
+	HardwareInventory  *proto.HardwareInventory `json:",omitempty"`
+	HardwareMismatches []string                 `json:",omitempty"`
+	Location           string                   `json:",omitempty"`
+	Machine            Machine                  `json:",omitempty"`
+	Subnets            []*proto.Subnet          `json:",omitempty"`
 }
 
 // The GetUpdates() RPC is fully streamed.
@@ -155,3 +157,15 @@ type PowerOnMachineRequest struct {
 type PowerOnMachineResponse struct {
 	Error string
 }
+
+// ReportHardwareInventoryRequest is sent by a machine (such as the installer
+// running on the machine) to report its own hardware inventory.
+type ReportHardwareInventoryRequest struct {
+	HardwareInventory *proto.HardwareInventory
+	Hostname          string
+}
+
+type ReportHardwareInventoryResponse struct {
+	Error              string
+	HardwareMismatches []string `json:",omitempty"`
+}
@@ -3,7 +3,11 @@ package fleetmanager
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
+	"strings"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 func listsEqual(left, right []string) bool {
@@ -47,6 +51,53 @@ func parseMAC(text []byte) (HardwareAddr, error) {
 	return addr, nil
 }
 
+// CheckHardwareInventory will compare the hardware inventory reported by the
+// machine with the expected configuration and returns a description of each
+// mismatch. Fields which are not specified or not reported are not compared.
+func (machine *Machine) CheckHardwareInventory(
+	inventory *proto.HardwareInventory) []string {
+	var mismatches []string
+	if machine.MemoryInMiB > 0 && len(inventory.DIMMs) > 0 {
+		var memoryInMiB uint64
+		for _, dimm := range inventory.DIMMs {
+			memoryInMiB += dimm.SizeInMiB
+		}
+		if memoryInMiB != machine.MemoryInMiB {
+			mismatches = append(mismatches,
+				fmt.Sprintf("MemoryInMiB: %d, installed DIMMs: %d MiB",
+					machine.MemoryInMiB, memoryInMiB))
+		}
+	}
+	if machine.NumCPUs > 0 && inventory.CPU.NumThreads > 0 &&
+		machine.NumCPUs != inventory.CPU.NumThreads {
+		mismatches = append(mismatches,
+			fmt.Sprintf("NumCPUs: %d, found: %d",
+				machine.NumCPUs, inventory.CPU.NumThreads))
+	}
+	if len(inventory.NICs) < 1 {
+		return mismatches
+	}
+	addresses := make(map[string]struct{}, len(inventory.NICs))
+	for _, nic := range inventory.NICs {
+		addresses[strings.ToLower(nic.HardwareAddress)] = struct{}{}
+	}
+	networkEntries := make([]NetworkEntry, 0,
+		len(machine.SecondaryNetworkEntries)+1)
+	networkEntries = append(networkEntries, machine.NetworkEntry)
+	networkEntries = append(networkEntries, machine.SecondaryNetworkEntries...)
+	for _, networkEntry := range networkEntries {
+		if len(networkEntry.HostMacAddress) < 1 {
+			continue
+		}
+		if _, ok := addresses[networkEntry.HostMacAddress.String()]; !ok {
+			mismatches = append(mismatches,
+				fmt.Sprintf("HostMacAddress: %s not found",
+					networkEntry.HostMacAddress))
+		}
+	}
+	return mismatches
+}
+
 func (left *Machine) Equal(right *Machine) bool {
 	if left.GatewaySubnetId != right.GatewaySubnetId {
 		return false
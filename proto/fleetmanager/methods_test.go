@@ -0,0 +1,44 @@
+package fleetmanager
+
+import (
+	"testing"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func TestCheckHardwareInventory(t *testing.T) {
+	machine := Machine{
+		MemoryInMiB: 65536,
+		NetworkEntry: NetworkEntry{
+			HostMacAddress: HardwareAddr{0x02, 0, 0, 0, 0, 0x01},
+		},
+		NumCPUs: 32,
+		SecondaryNetworkEntries: []NetworkEntry{
+			{HostMacAddress: HardwareAddr{0x02, 0, 0, 0, 0, 0x02}},
+		},
+	}
+	inventory := proto.HardwareInventory{
+		CPU: proto.CpuInfo{NumThreads: 32},
+		DIMMs: []proto.DimmInfo{
+			{SizeInMiB: 32768},
+			{SizeInMiB: 32768},
+		},
+		NICs: []proto.NicInfo{
+			{HardwareAddress: "02:00:00:00:00:01"},
+			{HardwareAddress: "02:00:00:00:00:02"},
+		},
+	}
+	if mismatches := machine.CheckHardwareInventory(&inventory); len(mismatches) > 0 {
+		t.Errorf("unexpected mismatches: %v", mismatches)
+	}
+	inventory.CPU.NumThreads = 16
+	inventory.DIMMs = inventory.DIMMs[:1]
+	inventory.NICs = inventory.NICs[:1]
+	if mismatches := machine.CheckHardwareInventory(&inventory); len(mismatches) != 3 {
+		t.Errorf("expected 3 mismatches, got: %v", mismatches)
+	}
+	if mismatches := machine.CheckHardwareInventory(
+		&proto.HardwareInventory{}); len(mismatches) > 0 {
+		t.Errorf("unexpected mismatches for empty inventory: %v", mismatches)
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/images/diskimage"
+	"github.com/Cloud-Foundations/Dominator/lib/mbr"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
 )
 
@@ -14,9 +16,20 @@ const (
 	ConsoleDummy = 1
 	ConsoleVNC   = 2
 
+	FirewallAccept = 0
+	FirewallDrop   = 1
+
+	FirmwareBIOS           = 0
+	FirmwareUEFI           = 1
+	FirmwareUEFISecureBoot = 2
+
 	MachineTypeGenericPC = 0
 	MachineTypeQ35       = 1
 
+	NetbootMethodTFTP = 0
+	NetbootMethodHTTP = 1
+	NetbootMethodIPXE = 2
+
 	StateStarting      = 0
 	StateRunning       = 1
 	StateFailedToStart = 2
@@ -126,6 +139,15 @@ type ChangeVmDestroyProtectionResponse struct {
 	Error string
 }
 
+type ChangeVmFirewallPolicyRequest struct {
+	IpAddress      net.IP
+	FirewallPolicy *FirewallPolicy // If nil, the policy is removed.
+}
+
+type ChangeVmFirewallPolicyResponse struct {
+	Error string
+}
+
 type ChangeVmMachineTypeRequest struct {
 	MachineType MachineType
 	IpAddress   net.IP
@@ -184,6 +206,16 @@ type ChangeVmTagsResponse struct {
 	Error string
 }
 
+type ChangeVmThrottlesRequest struct {
+	IpAddress        net.IP
+	NetworkThrottles []NetworkThrottle // nil: no change.
+	VolumeThrottles  []VolumeThrottle  // nil: no change.
+}
+
+type ChangeVmThrottlesResponse struct {
+	Error string
+}
+
 type ChangeVmVolumeInterfacesRequest struct {
 	Interfaces []VolumeInterface
 	IpAddress  net.IP
@@ -262,12 +294,22 @@ type CopyVmResponse struct { // Multiple responses are sent.
 	ProgressMessage string
 }
 
+type CpuInfo struct {
+	Flags      []string `json:",omitempty"`
+	ModelName  string   `json:",omitempty"`
+	NumCores   uint     `json:",omitempty"` // Physical cores.
+	NumSockets uint     `json:",omitempty"`
+	NumThreads uint     `json:",omitempty"` // Logical CPUs.
+	VendorId   string   `json:",omitempty"`
+}
+
 type CreateVmRequest struct {
 	DhcpTimeout          time.Duration // <0: no DHCP; 0: no wait; >0 DHPC wait.
 	DoNotStart           bool
 	EnableNetboot        bool
-	IdentityCertificate  []byte // PEM encoded.
-	IdentityKey          []byte // PEM encoded.
+	ExtraFiles           map[string][]byte // Firmware state: "nvram", "tpm-state".
+	IdentityCertificate  []byte            // PEM encoded.
+	IdentityKey          []byte            // PEM encoded.
 	ImageDataSize        uint64
 	ImageTimeout         time.Duration
 	MinimumFreeBytes     uint64
@@ -334,6 +376,13 @@ type DestroyVmResponse struct {
 	Error string
 }
 
+type DimmInfo struct {
+	Label      string `json:",omitempty"`
+	Location   string `json:",omitempty"`
+	MemoryType string `json:",omitempty"`
+	SizeInMiB  uint64 `json:",omitempty"`
+}
+
 type DiscardVmAccessTokenRequest struct {
 	AccessToken []byte
 	IpAddress   net.IP
@@ -368,8 +417,30 @@ type DiscardVmSnapshotResponse struct {
 	Error string
 }
 
+type DiskInfo struct {
+	Device           string `json:",omitempty"` // Example: "sda".
+	FirmwareRevision string `json:",omitempty"`
+	HealthStatus     string `json:",omitempty"` // From SMART, if available.
+	Model            string `json:",omitempty"`
+	Rotational       bool   `json:",omitempty"`
+	SerialNumber     string `json:",omitempty"`
+	SizeBytes        uint64 `json:",omitempty"`
+}
+
+type DmiInfo struct {
+	BiosVendor          string `json:",omitempty"`
+	BiosVersion         string `json:",omitempty"`
+	BoardName           string `json:",omitempty"`
+	BoardSerialNumber   string `json:",omitempty"`
+	BoardVendor         string `json:",omitempty"`
+	ProductName         string `json:",omitempty"`
+	ProductSerialNumber string `json:",omitempty"`
+	SystemVendor        string `json:",omitempty"`
+}
+
 type ExportLocalVmInfo struct {
-	Bridges []string
+	Bridges        []string
+	ExtraFilenames map[string]string `json:",omitempty"` // Key: "nvram"...
 	LocalVmInfo
 }
 
@@ -383,6 +454,34 @@ type ExportLocalVmResponse struct {
 	VmInfo ExportLocalVmInfo
 }
 
+type FirewallAction uint
+
+// FirewallPolicy specifies the rules for filtering traffic to (ingress) and
+// from (egress) a VM. Rules are evaluated in order and the first matching rule
+// wins. If no rule matches, the default action is applied. Established and
+// related traffic is always permitted.
+type FirewallPolicy struct {
+	EgressDefault  FirewallAction `json:",omitempty"`
+	EgressRules    []FirewallRule `json:",omitempty"`
+	IngressDefault FirewallAction `json:",omitempty"`
+	IngressRules   []FirewallRule `json:",omitempty"`
+}
+
+// FirewallRule matches traffic by the remote address. Cidr and VmTags are
+// mutually exclusive; if neither is specified, all remote addresses match.
+// VmTags matches VMs on the same Hypervisor. The port range applies to the
+// destination port and requires Protocol to be "tcp" or "udp".
+type FirewallRule struct {
+	Action   FirewallAction `json:",omitempty"`
+	Cidr     string         `json:",omitempty"`
+	FromPort uint16         `json:",omitempty"`
+	Protocol string         `json:",omitempty"` // "", "icmp", "tcp", "udp".
+	ToPort   uint16         `json:",omitempty"` // 0: same as FromPort.
+	VmTags   tags.MatchTags `json:",omitempty"`
+}
+
+type FirmwareType uint
+
 type GetCapacityRequest struct{}
 
 type GetCapacityResponse struct {
@@ -421,21 +520,23 @@ type GetUpdatesRequest struct {
 }
 
 type Update struct {
-	HaveAddressPool  bool               `json:",omitempty"`
-	AddressPool      []Address          `json:",omitempty"` // Used & free.
-	HaveDisabled     bool               `json:",omitempty"`
-	Disabled         bool               `json:",omitempty"`
-	MemoryInMiB      *uint64            `json:",omitempty"`
-	NumCPUs          *uint              `json:",omitempty"`
-	NumFreeAddresses map[string]uint    `json:",omitempty"` // Key: subnet ID.
-	HealthStatus     string             `json:",omitempty"`
-	HaveSerialNumber bool               `json:",omitempty"`
-	SerialNumber     string             `json:",omitempty"`
-	HaveSubnets      bool               `json:",omitempty"`
-	Subnets          []Subnet           `json:",omitempty"`
-	TotalVolumeBytes *uint64            `json:",omitempty"`
-	HaveVMs          bool               `json:",omitempty"`
-	VMs              map[string]*VmInfo `json:",omitempty"` // Key: IP address.
+	HaveAddressPool   bool               `json:",omitempty"`
+	AddressPool       []Address          `json:",omitempty"` // Used & free.
+	HaveDisabled      bool               `json:",omitempty"`
+	Disabled          bool               `json:",omitempty"`
+	HardwareInventory *HardwareInventory `json:",omitempty"`
+	MemoryInMiB       *uint64            `json:",omitempty"`
+	NumCPUs           *uint              `json:",omitempty"`
+	NumFreeAddresses  map[string]uint    `json:",omitempty"` // Key: subnet ID.
+	HealthStatus      string             `json:",omitempty"`
+	HaveSerialNumber  bool               `json:",omitempty"`
+	SerialNumber      string             `json:",omitempty"`
+	HaveSubnets       bool               `json:",omitempty"`
+	Subnets           []Subnet           `json:",omitempty"`
+	ThrottleDefaults  *ThrottleDefaults  `json:",omitempty"` // From tags.
+	TotalVolumeBytes  *uint64            `json:",omitempty"`
+	HaveVMs           bool               `json:",omitempty"`
+	VMs               map[string]*VmInfo `json:",omitempty"` // Key: IP address.
 }
 
 type GetVmAccessTokenRequest struct {
@@ -479,6 +580,16 @@ type GetVmLastPatchLogResponse struct {
 	PatchTime time.Time
 } // Data (length=Length) are streamed afterwards.
 
+type GetVmMetricsRequest struct {
+	IpAddress      net.IP
+	MaximumSamples uint // 0: return all available samples.
+}
+
+type GetVmMetricsResponse struct {
+	Error   string
+	Samples []VmMetrics // Oldest first.
+}
+
 type GetVmUserDataRequest struct {
 	AccessToken []byte
 	IpAddress   net.IP
@@ -489,19 +600,32 @@ type GetVmUserDataResponse struct {
 	Length uint64
 } // Data (length=Length) are streamed afterwards.
 
-// The GetVmVolume() RPC is followed by the proto/rsync.GetBlocks message.
+// The GetVmVolume() RPC is followed by the proto/rsync.GetBlocks message if
+// ImageFormat is raw, else the image (length=ImageLength) is streamed.
 
 type GetVmVolumeRequest struct {
 	AccessToken      []byte
+	CompressImage    bool // QCOW2 only.
 	GetExtraFiles    bool
 	IgnoreExtraFiles bool
+	ImageFormat      diskimage.Format
 	IpAddress        net.IP
 	VolumeIndex      uint
 }
 
 type GetVmVolumeResponse struct {
-	Error      string
-	ExtraFiles map[string][]byte // May contain "kernel", "initrd" and such.
+	Error       string
+	ExtraFiles  map[string][]byte // May contain "kernel", "initrd", "nvram"...
+	ImageLength uint64
+}
+
+type HardwareInventory struct {
+	CPU         CpuInfo
+	DIMMs       []DimmInfo `json:",omitempty"`
+	DMI         DmiInfo
+	Disks       []DiskInfo `json:",omitempty"`
+	MemoryInMiB uint64     `json:",omitempty"` // Visible to the kernel.
+	NICs        []NicInfo  `json:",omitempty"`
 }
 
 type HoldLockRequest struct {
@@ -533,6 +657,7 @@ type ListSubnetsResponse struct {
 }
 
 type ImportLocalVmRequest struct {
+	ExtraFilenames     map[string]string `json:",omitempty"` // Key: "nvram"...
 	SkipMemoryCheck    bool
 	VerificationCookie []byte `json:",omitempty"`
 	VmInfo
@@ -593,11 +718,19 @@ type MigrateVmResponseResponse struct {
 	Commit bool
 }
 
+// NetbootMachineRequest configures the Hypervisor to network boot a machine.
+// If Method is NetbootMethodIPXE, the client chain-loads iPXE, which then
+// fetches a generated script which loads the kernel and initrd over HTTP. An
+// extra acknowledgement is waited for, since iPXE makes its own DHCP request.
 type NetbootMachineRequest struct {
 	Address                      Address
 	Files                        map[string][]byte
 	FilesExpiration              time.Duration
 	Hostname                     string
+	IpxeInitrd                   string `json:",omitempty"` // Default: initrd.img
+	IpxeKernel                   string `json:",omitempty"` // Default: vmlinuz
+	IpxeKernelOptions            string `json:",omitempty"`
+	Method                       NetbootMethod
 	NumAcknowledgementsToWaitFor uint
 	OfferExpiration              time.Duration
 	Subnet                       *Subnet
@@ -608,6 +741,32 @@ type NetbootMachineResponse struct {
 	Error string
 }
 
+type NetbootMethod uint
+
+// NetworkInterfaceMetrics contains the cumulative counters for a VM network
+// interface, from the perspective of the VM.
+type NetworkInterfaceMetrics struct {
+	ReceiveBytes    uint64
+	ReceivePackets  uint64
+	TransmitBytes   uint64
+	TransmitPackets uint64
+}
+
+// NetworkThrottle specifies the bandwidth limits for a VM network interface,
+// from the perspective of the VM. A zero value means no limit.
+type NetworkThrottle struct {
+	ReceiveBytesPerSecond  uint64 `json:",omitempty"`
+	TransmitBytesPerSecond uint64 `json:",omitempty"`
+}
+
+type NicInfo struct {
+	Driver          string `json:",omitempty"`
+	FirmwareVersion string `json:",omitempty"`
+	HardwareAddress string `json:",omitempty"`
+	Name            string `json:",omitempty"`
+	SpeedMbps       uint   `json:",omitempty"` // Zero if link is down.
+}
+
 type PatchVmImageRequest struct {
 	ImageName    string
 	ImageTimeout time.Duration
@@ -772,6 +931,16 @@ type SetDisabledStateResponse struct {
 	Error string
 }
 
+// SetThrottleDefaultsRequest sets the defaults which apply to all VMs on the
+// Hypervisor. These are computed by the Fleet Manager from the Hypervisor tags.
+type SetThrottleDefaultsRequest struct {
+	ThrottleDefaults ThrottleDefaults
+}
+
+type SetThrottleDefaultsResponse struct {
+	Error string
+}
+
 type SnapshotVmRequest struct {
 	IpAddress         net.IP
 	ForceIfNotStopped bool
@@ -811,13 +980,22 @@ type Subnet struct {
 	IpMask            net.IP // net.IPMask can't be JSON {en,de}coded.
 	DomainName        string `json:",omitempty"`
 	DomainNameServers []net.IP
-	DisableMetadata   bool     `json:",omitempty"`
-	Manage            bool     `json:",omitempty"`
-	VlanId            uint     `json:",omitempty"`
-	AllowedGroups     []string `json:",omitempty"`
-	AllowedUsers      []string `json:",omitempty"`
-	FirstDynamicIP    net.IP   `json:",omitempty"`
-	LastDynamicIP     net.IP   `json:",omitempty"`
+	DisableMetadata   bool             `json:",omitempty"`
+	Manage            bool             `json:",omitempty"`
+	VlanId            uint             `json:",omitempty"`
+	AllowedGroups     []string         `json:",omitempty"`
+	AllowedUsers      []string         `json:",omitempty"`
+	FirewallPolicy    *FirewallPolicy  `json:",omitempty"` // Default for VMs.
+	FirstDynamicIP    net.IP           `json:",omitempty"`
+	LastDynamicIP     net.IP           `json:",omitempty"`
+	ThrottleDefaults  ThrottleDefaults `json:",omitempty"`
+}
+
+// ThrottleDefaults specifies the default limits for VMs which do not specify
+// their own limits. Each non-zero limit in a VM takes precedence.
+type ThrottleDefaults struct {
+	Network NetworkThrottle `json:",omitempty"`
+	Volume  VolumeThrottle  `json:",omitempty"`
 }
 
 type TraceVmMetadataRequest struct {
@@ -840,26 +1018,30 @@ type UpdateSubnetsResponse struct {
 
 type VmInfo struct {
 	Address             Address
-	ChangedStateOn      time.Time   `json:",omitempty"`
-	ConsoleType         ConsoleType `json:",omitempty"`
-	CreatedOn           time.Time   `json:",omitempty"`
-	CpuPriority         int         `json:",omitempty"`
-	DestroyOnPowerdown  bool        `json:",omitempty"`
-	DestroyProtection   bool        `json:",omitempty"`
-	DisableVirtIO       bool        `json:",omitempty"`
-	ExtraKernelOptions  string      `json:",omitempty"`
-	Hostname            string      `json:",omitempty"`
-	IdentityExpires     time.Time   `json:",omitempty"`
-	IdentityName        string      `json:",omitempty"`
-	ImageName           string      `json:",omitempty"`
-	ImageURL            string      `json:",omitempty"`
-	MachineType         MachineType `json:",omitempty"`
+	ChangedStateOn      time.Time       `json:",omitempty"`
+	ConsoleType         ConsoleType     `json:",omitempty"`
+	CreatedOn           time.Time       `json:",omitempty"`
+	CpuPriority         int             `json:",omitempty"`
+	DestroyOnPowerdown  bool            `json:",omitempty"`
+	DestroyProtection   bool            `json:",omitempty"`
+	DisableVirtIO       bool            `json:",omitempty"`
+	ExtraKernelOptions  string          `json:",omitempty"`
+	FirewallPolicy      *FirewallPolicy `json:",omitempty"`
+	FirmwareType        FirmwareType    `json:",omitempty"`
+	Hostname            string          `json:",omitempty"`
+	IdentityExpires     time.Time       `json:",omitempty"`
+	IdentityName        string          `json:",omitempty"`
+	ImageName           string          `json:",omitempty"`
+	ImageURL            string          `json:",omitempty"`
+	MachineType         MachineType     `json:",omitempty"`
 	MemoryInMiB         uint64
 	MilliCPUs           uint
-	OwnerGroups         []string `json:",omitempty"`
-	OwnerUsers          []string `json:",omitempty"`
-	RootFileSystemLabel string   `json:",omitempty"`
-	SpreadVolumes       bool     `json:",omitempty"`
+	NetworkThrottles    []NetworkThrottle `json:",omitempty"` // Per interface.
+	OwnerGroups         []string          `json:",omitempty"`
+	OwnerUsers          []string          `json:",omitempty"`
+	PartitionTableType  mbr.TableType     `json:",omitempty"` // Root volume.
+	RootFileSystemLabel string            `json:",omitempty"`
+	SpreadVolumes       bool              `json:",omitempty"`
 	State               State
 	SecondaryAddresses  []Address      `json:",omitempty"`
 	SecondarySubnetIDs  []string       `json:",omitempty"`
@@ -867,16 +1049,28 @@ type VmInfo struct {
 	Tags                tags.Tags      `json:",omitempty"`
 	Uncommitted         bool           `json:",omitempty"`
 	VirtualCPUs         uint           `json:",omitempty"`
+	VirtualTPM          bool           `json:",omitempty"`
 	Volumes             []Volume       `json:",omitempty"`
 	WatchdogAction      WatchdogAction `json:",omitempty"`
 	WatchdogModel       WatchdogModel  `json:",omitempty"`
 }
 
+// VmMetrics is a sample of the resource usage for a VM.
+type VmMetrics struct {
+	BalloonBytes        uint64        `json:",omitempty"` // 0: no balloon.
+	CpuTime             time.Duration // User and system time used by VM.
+	MemoryResidentBytes uint64
+	NetworkInterfaces   []NetworkInterfaceMetrics `json:",omitempty"`
+	Timestamp           time.Time
+	Volumes             []VolumeMetrics `json:",omitempty"`
+}
+
 type Volume struct {
 	Format    VolumeFormat      `json:",omitempty"`
 	Interface VolumeInterface   `json:",omitempty"`
 	Size      uint64            `json:",omitempty"`
 	Snapshots map[string]uint64 `json:",omitempty"`
+	Throttle  VolumeThrottle    `json:",omitempty"`
 	Type      VolumeType        `json:",omitempty"`
 }
 
@@ -890,6 +1084,21 @@ type VolumeInitialisationInfo struct {
 	ReservedBlocksPercentage uint16
 }
 
+// VolumeMetrics contains the cumulative I/O counters for a VM volume.
+type VolumeMetrics struct {
+	ReadBytes       uint64
+	ReadOperations  uint64
+	WriteBytes      uint64
+	WriteOperations uint64
+}
+
+// VolumeThrottle specifies the I/O limits for a volume. A zero value means no
+// limit.
+type VolumeThrottle struct {
+	BytesPerSecond uint64 `json:",omitempty"`
+	IOPS           uint64 `json:",omitempty"`
+}
+
 type VolumeType uint
 
 // The WatchDhcp() RPC is fully streamed.
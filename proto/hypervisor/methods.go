@@ -3,13 +3,17 @@ package hypervisor
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
 	"strings"
 )
 
 const (
 	consoleTypeUnknown     = "UNKNOWN ConsoleType"
+	firewallActionUnknown  = "UNKNOWN FirewallAction"
+	firmwareTypeUnknown    = "UNKNOWN FirmwareType"
 	machineTypeUnknown     = "UNKNOWN MachineType"
+	netbootMethodUnknown   = "UNKNOWN NetbootMethod"
 	stateUnknown           = "UNKNOWN State"
 	volumeFormatUnknown    = "UNKNOWN VolumeFormat"
 	volumeInterfaceUnknown = "UNKNOWN VolumeInterface"
@@ -26,12 +30,32 @@ var (
 	}
 	textToConsoleType map[string]ConsoleType
 
+	firewallActionToText = map[FirewallAction]string{
+		FirewallAccept: "accept",
+		FirewallDrop:   "drop",
+	}
+	textToFirewallAction map[string]FirewallAction
+
+	firmwareTypeToText = map[FirmwareType]string{
+		FirmwareBIOS:           "bios",
+		FirmwareUEFI:           "uefi",
+		FirmwareUEFISecureBoot: "uefi-secure-boot",
+	}
+	textToFirmwareType map[string]FirmwareType
+
 	machineTypeToText = map[MachineType]string{
 		MachineTypeGenericPC: "pc",
 		MachineTypeQ35:       "q35",
 	}
 	textToMachineType map[string]MachineType
 
+	netbootMethodToText = map[NetbootMethod]string{
+		NetbootMethodTFTP: "tftp",
+		NetbootMethodHTTP: "http",
+		NetbootMethodIPXE: "ipxe",
+	}
+	textToNetbootMethod map[string]NetbootMethod
+
 	stateToText = map[State]string{
 		StateStarting:      "starting",
 		StateRunning:       "running",
@@ -86,10 +110,25 @@ func init() {
 	for consoleType, text := range consoleTypeToText {
 		textToConsoleType[text] = consoleType
 	}
+	textToFirewallAction = make(map[string]FirewallAction,
+		len(firewallActionToText))
+	for action, text := range firewallActionToText {
+		textToFirewallAction[text] = action
+	}
+	textToFirmwareType = make(map[string]FirmwareType,
+		len(firmwareTypeToText))
+	for firmwareType, text := range firmwareTypeToText {
+		textToFirmwareType[text] = firmwareType
+	}
 	textToMachineType = make(map[string]MachineType, len(machineTypeToText))
 	for format, text := range machineTypeToText {
 		textToMachineType[text] = format
 	}
+	textToNetbootMethod = make(map[string]NetbootMethod,
+		len(netbootMethodToText))
+	for method, text := range netbootMethodToText {
+		textToNetbootMethod[text] = method
+	}
 	textToState = make(map[string]State, len(stateToText))
 	for state, text := range stateToText {
 		textToState[text] = state
@@ -262,6 +301,214 @@ func (consoleType *ConsoleType) UnmarshalText(text []byte) error {
 	}
 }
 
+func (action *FirewallAction) CheckValid() error {
+	if _, ok := firewallActionToText[*action]; !ok {
+		return errors.New(firewallActionUnknown)
+	} else {
+		return nil
+	}
+}
+
+func (action FirewallAction) MarshalText() ([]byte, error) {
+	if text := action.String(); text == firewallActionUnknown {
+		return nil, errors.New(text)
+	} else {
+		return []byte(text), nil
+	}
+}
+
+func (action *FirewallAction) Set(value string) error {
+	if val, ok := textToFirewallAction[value]; !ok {
+		return errors.New(firewallActionUnknown)
+	} else {
+		*action = val
+		return nil
+	}
+}
+
+func (action FirewallAction) String() string {
+	if text, ok := firewallActionToText[action]; ok {
+		return text
+	} else {
+		return firewallActionUnknown
+	}
+}
+
+func (action *FirewallAction) UnmarshalText(text []byte) error {
+	txt := string(text)
+	if val, ok := textToFirewallAction[txt]; ok {
+		*action = val
+		return nil
+	} else {
+		return errors.New("unknown FirewallAction: " + txt)
+	}
+}
+
+// CheckValid checks if the policy is valid.
+func (policy *FirewallPolicy) CheckValid() error {
+	if err := policy.EgressDefault.CheckValid(); err != nil {
+		return err
+	}
+	if err := policy.IngressDefault.CheckValid(); err != nil {
+		return err
+	}
+	for index, rule := range policy.EgressRules {
+		if err := rule.CheckValid(); err != nil {
+			return fmt.Errorf("egress rule: %d: %s", index, err)
+		}
+	}
+	for index, rule := range policy.IngressRules {
+		if err := rule.CheckValid(); err != nil {
+			return fmt.Errorf("ingress rule: %d: %s", index, err)
+		}
+	}
+	return nil
+}
+
+// FirewallPoliciesEqual returns true if the two policies are equal. A nil
+// policy is not equal to an empty policy.
+func FirewallPoliciesEqual(left, right *FirewallPolicy) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+	if left.EgressDefault != right.EgressDefault {
+		return false
+	}
+	if !firewallRulesEqual(left.EgressRules, right.EgressRules) {
+		return false
+	}
+	if left.IngressDefault != right.IngressDefault {
+		return false
+	}
+	if !firewallRulesEqual(left.IngressRules, right.IngressRules) {
+		return false
+	}
+	return true
+}
+
+// CheckValid checks if the rule is valid.
+func (rule *FirewallRule) CheckValid() error {
+	if err := rule.Action.CheckValid(); err != nil {
+		return err
+	}
+	if rule.Cidr != "" {
+		if len(rule.VmTags) > 0 {
+			return errors.New("cannot specify both Cidr and VmTags")
+		}
+		if ip, _, err := net.ParseCIDR(rule.Cidr); err != nil {
+			return err
+		} else if ip.To4() == nil {
+			return errors.New("only IPv4 CIDRs are supported")
+		}
+	}
+	switch rule.Protocol {
+	case "", "icmp":
+		if rule.FromPort != 0 || rule.ToPort != 0 {
+			return errors.New("ports require tcp or udp protocol")
+		}
+	case "tcp", "udp":
+		if rule.ToPort != 0 && rule.ToPort < rule.FromPort {
+			return errors.New("ToPort less than FromPort")
+		}
+	default:
+		return errors.New("unsupported protocol: " + rule.Protocol)
+	}
+	return nil
+}
+
+func (left *FirewallRule) Equal(right *FirewallRule) bool {
+	if left.Action != right.Action {
+		return false
+	}
+	if left.Cidr != right.Cidr {
+		return false
+	}
+	if left.FromPort != right.FromPort {
+		return false
+	}
+	if left.Protocol != right.Protocol {
+		return false
+	}
+	if left.ToPort != right.ToPort {
+		return false
+	}
+	if len(left.VmTags) != len(right.VmTags) {
+		return false
+	}
+	for key, leftValues := range left.VmTags {
+		if rightValues, ok := right.VmTags[key]; !ok {
+			return false
+		} else if !stringSlicesEqual(leftValues, rightValues) {
+			return false
+		}
+	}
+	return true
+}
+
+func firewallRulesEqual(left, right []FirewallRule) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for index, leftRule := range left {
+		if !leftRule.Equal(&right[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (firmwareType *FirmwareType) CheckValid() error {
+	if _, ok := firmwareTypeToText[*firmwareType]; !ok {
+		return errors.New(firmwareTypeUnknown)
+	} else {
+		return nil
+	}
+}
+
+// IsUEFI returns true if the firmware type is one of the UEFI variants.
+func (firmwareType FirmwareType) IsUEFI() bool {
+	switch firmwareType {
+	case FirmwareUEFI, FirmwareUEFISecureBoot:
+		return true
+	}
+	return false
+}
+
+func (firmwareType FirmwareType) MarshalText() ([]byte, error) {
+	if text := firmwareType.String(); text == firmwareTypeUnknown {
+		return nil, errors.New(text)
+	} else {
+		return []byte(text), nil
+	}
+}
+
+func (firmwareType *FirmwareType) Set(value string) error {
+	if val, ok := textToFirmwareType[value]; !ok {
+		return errors.New(firmwareTypeUnknown)
+	} else {
+		*firmwareType = val
+		return nil
+	}
+}
+
+func (firmwareType FirmwareType) String() string {
+	if text, ok := firmwareTypeToText[firmwareType]; ok {
+		return text
+	} else {
+		return firmwareTypeUnknown
+	}
+}
+
+func (firmwareType *FirmwareType) UnmarshalText(text []byte) error {
+	txt := string(text)
+	if val, ok := textToFirmwareType[txt]; ok {
+		*firmwareType = val
+		return nil
+	} else {
+		return errors.New("unknown FirmwareType: " + txt)
+	}
+}
+
 func (machineType *MachineType) CheckValid() error {
 	if _, ok := machineTypeToText[*machineType]; !ok {
 		return errors.New(machineTypeUnknown)
@@ -305,6 +552,49 @@ func (machineType *MachineType) UnmarshalText(text []byte) error {
 	}
 }
 
+func (method *NetbootMethod) CheckValid() error {
+	if _, ok := netbootMethodToText[*method]; !ok {
+		return errors.New(netbootMethodUnknown)
+	} else {
+		return nil
+	}
+}
+
+func (method NetbootMethod) MarshalText() ([]byte, error) {
+	if text := method.String(); text == netbootMethodUnknown {
+		return nil, errors.New(text)
+	} else {
+		return []byte(text), nil
+	}
+}
+
+func (method *NetbootMethod) Set(value string) error {
+	if val, ok := textToNetbootMethod[value]; !ok {
+		return errors.New(netbootMethodUnknown)
+	} else {
+		*method = val
+		return nil
+	}
+}
+
+func (method NetbootMethod) String() string {
+	if text, ok := netbootMethodToText[method]; ok {
+		return text
+	} else {
+		return netbootMethodUnknown
+	}
+}
+
+func (method *NetbootMethod) UnmarshalText(text []byte) error {
+	txt := string(text)
+	if val, ok := textToNetbootMethod[txt]; ok {
+		*method = val
+		return nil
+	} else {
+		return errors.New("unknown NetbootMethod: " + txt)
+	}
+}
+
 func (state State) MarshalText() ([]byte, error) {
 	if text := state.String(); text == stateUnknown {
 		return nil, errors.New(text)
@@ -362,9 +652,15 @@ func (left *Subnet) Equal(right *Subnet) bool {
 	if !stringSlicesEqual(left.AllowedUsers, right.AllowedUsers) {
 		return false
 	}
+	if !FirewallPoliciesEqual(left.FirewallPolicy, right.FirewallPolicy) {
+		return false
+	}
 	if !CompareIPs(left.FirstDynamicIP, right.FirstDynamicIP) {
 		return false
 	}
+	if left.ThrottleDefaults != right.ThrottleDefaults {
+		return false
+	}
 	return true
 }
 
@@ -416,6 +712,12 @@ func (left *VmInfo) Equal(right *VmInfo) bool {
 	if left.ExtraKernelOptions != right.ExtraKernelOptions {
 		return false
 	}
+	if !FirewallPoliciesEqual(left.FirewallPolicy, right.FirewallPolicy) {
+		return false
+	}
+	if left.FirmwareType != right.FirmwareType {
+		return false
+	}
 	if left.Hostname != right.Hostname {
 		return false
 	}
@@ -440,12 +742,23 @@ func (left *VmInfo) Equal(right *VmInfo) bool {
 	if left.MilliCPUs != right.MilliCPUs {
 		return false
 	}
+	if len(left.NetworkThrottles) != len(right.NetworkThrottles) {
+		return false
+	}
+	for index, leftThrottle := range left.NetworkThrottles {
+		if leftThrottle != right.NetworkThrottles[index] {
+			return false
+		}
+	}
 	if !stringSlicesEqual(left.OwnerGroups, right.OwnerGroups) {
 		return false
 	}
 	if !stringSlicesEqual(left.OwnerUsers, right.OwnerUsers) {
 		return false
 	}
+	if left.PartitionTableType != right.PartitionTableType {
+		return false
+	}
 	if left.SpreadVolumes != right.SpreadVolumes {
 		return false
 	}
@@ -478,6 +791,9 @@ func (left *VmInfo) Equal(right *VmInfo) bool {
 	if left.VirtualCPUs != right.VirtualCPUs {
 		return false
 	}
+	if left.VirtualTPM != right.VirtualTPM {
+		return false
+	}
 	if len(left.Volumes) != len(right.Volumes) {
 		return false
 	}
@@ -523,6 +839,9 @@ func (left *Volume) Equal(right *Volume) bool {
 			return false
 		}
 	}
+	if left.Throttle != right.Throttle {
+		return false
+	}
 	if left.Type != right.Type {
 		return false
 	}
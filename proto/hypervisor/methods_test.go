@@ -68,6 +68,12 @@ func makeVmInfo(t *testing.T, nonZeroFill bool, invertIndex int,
 				fieldValue.Set(sliceValue)
 				sliceValue.Index(0).SetString(fieldName)
 				sliceValue.Index(1).SetString(strings.ToLower(fieldName))
+			case "NetworkThrottles":
+				throttles := []NetworkThrottle{{
+					uint64(base) + 1,
+					uint64(base) + 2,
+				}}
+				fieldValue.Set(reflect.ValueOf(throttles))
 			case "SecondaryAddresses":
 				addresses := []Address{{
 					[]byte{1, 2, 3, 4},
@@ -83,7 +89,8 @@ func makeVmInfo(t *testing.T, nonZeroFill bool, invertIndex int,
 						"":    uint64(subBase) + 4,
 						"foo": uint64(subBase) + 5,
 					},
-					VolumeType(base) + 6,
+					VolumeThrottle{uint64(base) + 6, uint64(base) + 7},
+					VolumeType(base) + 8,
 				}}
 				fieldValue.Set(reflect.ValueOf(volumes))
 			default:
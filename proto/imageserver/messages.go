@@ -61,6 +61,31 @@ type DeleteUnreferencedObjectsRequest struct {
 
 type DeleteUnreferencedObjectsResponse struct{}
 
+type DirectoryRetention struct {
+	DirectoryName    string
+	ImagesToDelete   []string
+	ImagesReferenced []string // Images kept only because they are referenced.
+	Policy           image.RetentionPolicy
+}
+
+type FindImagesWithObjectRequest struct {
+	Hash hash.Hash
+}
+
+type FindImagesWithObjectResponse struct {
+	Error  string
+	Images []ImageWithPaths
+}
+
+type FindImagesWithPathRequest struct {
+	Pathname string
+}
+
+type FindImagesWithPathResponse struct {
+	Error      string
+	ImageNames []string
+}
+
 type FindLatestImageRequest struct {
 	BuildCommitId        string // Optional.
 	DirectoryName        string
@@ -134,6 +159,9 @@ type ImageUpdate struct {
 	Name      string // "" signifies initial list is sent, changes to follow.
 	Directory *image.Directory
 	Operation uint
+	Timestamp time.Time // When the master sent the update. Used to compute lag.
+
+	ContentHash *hash.Hash `json:",omitempty"` // Of added image. Newer masters.
 }
 
 type GetReplicationMasterRequest struct{}
@@ -143,6 +171,22 @@ type GetReplicationMasterResponse struct {
 	ReplicationMaster string
 }
 
+type GetReplicationStatusRequest struct{}
+
+type GetReplicationStatusResponse struct {
+	Error string
+	ReplicationStatus
+}
+
+type GetRetentionReportRequest struct {
+	DirectoryName string // Empty: all directories.
+}
+
+type GetRetentionReportResponse struct {
+	Directories []DirectoryRetention
+	Error       string
+}
+
 type ImageArchive struct {
 	ImageName string
 	image.Image
@@ -158,6 +202,11 @@ type ImageArchive struct {
 // The server sends a stream of strings (image names) with an empty string
 // signifying the end of the list.
 
+type ImageWithPaths struct {
+	ImageName string
+	Paths     []string // Pathnames of files in the image.
+}
+
 type ListSelectedImagesRequest struct {
 	IgnoreExpiringImages bool
 	TagsToMatch          tags.MatchTags // Empty: match all tags.
@@ -183,6 +232,36 @@ type MakeDirectoryRequest struct {
 
 type MakeDirectoryResponse struct{}
 
+type PromoteToMasterRequest struct {
+	Force bool // Promote even if a replication master is still active.
+}
+
+type PromoteToMasterResponse struct {
+	Error string
+}
+
+// ReplicaStatus is the status of a replica, as seen by its master.
+type ReplicaStatus struct {
+	Address          string // Remote address of the replica connection.
+	ConnectedSince   time.Time
+	LastUpdateSentAt time.Time `json:",omitempty"`
+	NumUpdatesSent   uint64
+}
+
+// ReplicationStatus is the replication status of an imageserver. If
+// ReplicationMaster is not empty, the imageserver is a replica.
+type ReplicationStatus struct {
+	Connected          bool            // True if connected to master.
+	ConnectedSince     time.Time       `json:",omitempty"`
+	DivergentImages    []string        `json:",omitempty"` // Not on master.
+	Lag                time.Duration   // Age of oldest unapplied update.
+	LastUpdateAt       time.Time       `json:",omitempty"` // Last received.
+	PromotedAt         time.Time       `json:",omitempty"`
+	ReplicationMaster  string          `json:",omitempty"` // Current master.
+	ReplicationMasters []string        `json:",omitempty"` // In order.
+	Replicas           []ReplicaStatus `json:",omitempty"`
+}
+
 type RestoreImageFromArchiveRequest struct {
 	ExpiresAt   time.Time
 	ArchiveData []byte // GOB encoding of ImageArchive followed by HMAC.
@@ -192,3 +271,12 @@ type RestoreImageFromArchiveResponse struct {
 	Error             string
 	ReplicationMaster string // If not empty, go here instead.
 }
+
+type SetDirectoryRetentionRequest struct {
+	DirectoryName string
+	Policy        image.RetentionPolicy
+}
+
+type SetDirectoryRetentionResponse struct {
+	Error string
+}
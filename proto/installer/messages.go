@@ -3,19 +3,56 @@ package installer
 const (
 	FileSystemTypeExt4 = 0
 	FileSystemTypeVfat = 1
+	FileSystemTypeXfs  = 2
+
+	RaidLevel1  = 1
+	RaidLevel10 = 10
 )
 
 type FileSystemType uint
 
+// LogicalVolume is an LVM logical volume in a volume group.
+type LogicalVolume struct {
+	FileSystemType FileSystemType `json:",omitempty"`
+	MountPoint     string         `json:",omitempty"`
+	Name           string         `json:",omitempty"`
+	SizeBytes      uint64         `json:",omitempty"` // Zero: remaining space.
+}
+
 type Partition struct {
 	FileSystemType   FileSystemType `json:",omitempty"`
 	MountPoint       string         `json:",omitempty"`
 	MinimumFreeBytes uint64         `json:",omitempty"`
 }
 
+// RaidArray is an mdadm software RAID array built from whole extra drives.
+// If VolumeGroup is specified the array is used as an LVM physical volume,
+// else a file-system is made on the array.
+type RaidArray struct {
+	FileSystemType FileSystemType `json:",omitempty"`
+	Level          uint           // RaidLevel1 or RaidLevel10.
+	MountPoint     string         `json:",omitempty"`
+	Name           string         // Device is /dev/md/Name.
+	NumDrives      uint
+	VolumeGroup    string `json:",omitempty"`
+}
+
 type StorageLayout struct {
-	BootDriveLayout          []Partition `json:",omitempty"`
-	ExtraMountPointsBasename string      `json:",omitempty"`
-	Encrypt                  bool        `json:",omitempty"`
-	UseKexec                 bool        `json:",omitempty"`
+	BootDriveLayout          []Partition    `json:",omitempty"`
+	BootDriveRaidLevel       uint           `json:",omitempty"`
+	ExtraFileSystemType      FileSystemType `json:",omitempty"`
+	ExtraMountPointsBasename string         `json:",omitempty"`
+	Encrypt                  bool           `json:",omitempty"`
+	NumBootDrives            uint           `json:",omitempty"` // Zero: one.
+	RaidArrays               []RaidArray    `json:",omitempty"`
+	UseKexec                 bool           `json:",omitempty"`
+	VolumeGroups             []VolumeGroup  `json:",omitempty"`
+}
+
+// VolumeGroup is an LVM volume group. The physical volumes are the RAID
+// arrays which specify the group, followed by NumDrives whole extra drives.
+type VolumeGroup struct {
+	LogicalVolumes []LogicalVolume `json:",omitempty"`
+	Name           string
+	NumDrives      uint `json:",omitempty"`
 }
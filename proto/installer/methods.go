@@ -2,6 +2,8 @@ package installer
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -12,6 +14,7 @@ var (
 	fileSystemTypeToText = map[FileSystemType]string{
 		FileSystemTypeExt4: "ext4",
 		FileSystemTypeVfat: "vfat",
+		FileSystemTypeXfs:  "xfs",
 	}
 	textToFileSystemType map[string]FileSystemType
 )
@@ -52,3 +55,32 @@ func (fileSystemType FileSystemType) String() string {
 func (fileSystemType *FileSystemType) UnmarshalText(text []byte) error {
 	return fileSystemType.Set(string(text))
 }
+
+// IsDataMountPoint returns true if the mount point is for a data file-system:
+// an extra file-system (ExtraMountPointsBasename followed by a number), a RAID
+// array or an LVM logical volume.
+func (layout *StorageLayout) IsDataMountPoint(mountPoint string) bool {
+	if mountPoint == "" {
+		return false
+	}
+	if layout.ExtraMountPointsBasename != "" &&
+		strings.HasPrefix(mountPoint, layout.ExtraMountPointsBasename) {
+		suffix := mountPoint[len(layout.ExtraMountPointsBasename):]
+		if _, err := strconv.ParseUint(suffix, 10, 64); err == nil {
+			return true
+		}
+	}
+	for _, array := range layout.RaidArrays {
+		if array.VolumeGroup == "" && array.MountPoint == mountPoint {
+			return true
+		}
+	}
+	for _, volumeGroup := range layout.VolumeGroups {
+		for _, logicalVolume := range volumeGroup.LogicalVolumes {
+			if logicalVolume.MountPoint == mountPoint {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package installer
+
+import (
+	"testing"
+)
+
+func TestFileSystemTypeText(t *testing.T) {
+	var fileSystemType FileSystemType
+	if err := fileSystemType.UnmarshalText([]byte("xfs")); err != nil {
+		t.Fatal(err)
+	}
+	if fileSystemType != FileSystemTypeXfs {
+		t.Errorf("xfs decoded to: %d", fileSystemType)
+	}
+	if err := fileSystemType.UnmarshalText([]byte("btrfs")); err == nil {
+		t.Error("btrfs decoded without error")
+	}
+}
+
+func TestIsDataMountPoint(t *testing.T) {
+	layout := StorageLayout{
+		BootDriveLayout: []Partition{
+			{MountPoint: "/"},
+			{MountPoint: "/home"},
+		},
+		ExtraMountPointsBasename: "/data/",
+		RaidArrays: []RaidArray{
+			{MountPoint: "/mirror", Name: "mirror"},
+			{MountPoint: "/ignored", Name: "pv0", VolumeGroup: "vg"},
+		},
+		VolumeGroups: []VolumeGroup{
+			{
+				LogicalVolumes: []LogicalVolume{{MountPoint: "/big"}},
+				Name:           "vg",
+			},
+		},
+	}
+	tests := map[string]bool{
+		"":         false,
+		"/":        false,
+		"/big":     true,
+		"/data/":   false,
+		"/data/0":  true,
+		"/data/12": true,
+		"/data/x":  false,
+		"/home":    false,
+		"/ignored": false,
+		"/mirror":  true,
+	}
+	for mountPoint, expected := range tests {
+		if got := layout.IsDataMountPoint(mountPoint); got != expected {
+			t.Errorf("IsDataMountPoint(%s): %v, expected: %v",
+				mountPoint, got, expected)
+		}
+	}
+}
@@ -30,8 +30,9 @@ type SetDebugLevelResponse struct{}
 
 type WatchRequest struct {
 	DebugLevel   int16
-	ExcludeRegex string // Empty: nothing excluded. Processed after includes.
-	IncludeRegex string // Empty: everything included.
+	ExcludeRegex string            // Empty: nothing excluded. Processed after includes.
+	Fields       map[string]string // Empty: everything included.
+	IncludeRegex string            // Empty: everything included.
 	Name         string
 }
 
@@ -21,6 +21,9 @@ const (
 
 	ErrorDisruptionPending = "disruption pending"
 	ErrorDisruptionDenied  = "disruption denied"
+
+	ScanModeFull        = ScanMode(0) // Continuous full scans.
+	ScanModeEventDriven = ScanMode(1) // Rescan changed directories only.
 )
 
 type BoostCpuLimitRequest struct{}
@@ -94,6 +97,8 @@ type PollResponse struct {
 	ScanCount                    uint64
 	DurationOfLastScan           time.Duration
 	GenerationCount              uint64
+	ScanMode                     ScanMode
+	NumDirtyDirectories          uint // Pending rescan (event-driven mode).
 	SystemUptime                 *time.Duration
 	DisruptionState              DisruptionState
 	FileSystemFollows            bool
@@ -101,6 +106,8 @@ type PollResponse struct {
 	ObjectCache                  objectcache.ObjectCache // Streamed separately.
 } // FileSystem is encoded afterwards, followed by ObjectCache.
 
+type ScanMode uint
+
 type SetConfigurationRequest Configuration
 
 type SetConfigurationResponse struct{}
@@ -108,6 +115,7 @@ type SetConfigurationResponse struct{}
 type UpdateRequest struct {
 	ForceDisruption bool
 	ImageName       string
+	RequestId       string // Used to correlate log messages.
 	Wait            bool
 	// The ordering here reflects the ordering that the sub is expected to use.
 	FilesToCopyToCache  []FileToCopyToCache
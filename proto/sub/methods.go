@@ -8,6 +8,7 @@ import (
 const (
 	disruptionRequestUnknown = "UNKNOWN DisruptionRequest"
 	disruptionStateUnknown   = "UNKNOWN DisruptionState"
+	scanModeUnknown          = "UNKNOWN ScanMode"
 )
 
 var (
@@ -25,6 +26,12 @@ var (
 		DisruptionStateDenied:    "denied",
 	}
 	textToDisruptionState map[string]DisruptionState
+
+	scanModeToText = map[ScanMode]string{
+		ScanModeFull:        "full",
+		ScanModeEventDriven: "event-driven",
+	}
+	textToScanMode map[string]ScanMode
 )
 
 func init() {
@@ -38,6 +45,10 @@ func init() {
 	for state, text := range disruptionStateToText {
 		textToDisruptionState[text] = state
 	}
+	textToScanMode = make(map[string]ScanMode, len(scanModeToText))
+	for mode, text := range scanModeToText {
+		textToScanMode[text] = mode
+	}
 }
 
 func (disruptionRequest *DisruptionRequest) CheckValid() error {
@@ -113,3 +124,29 @@ func (state *DisruptionState) UnmarshalText(text []byte) error {
 		return fmt.Errorf("unknown DisruptionState: %s", txt)
 	}
 }
+
+func (mode ScanMode) MarshalText() ([]byte, error) {
+	if text, ok := scanModeToText[mode]; ok {
+		return []byte(text), nil
+	} else {
+		return nil, fmt.Errorf("invalid ScanMode: %d", mode)
+	}
+}
+
+func (mode ScanMode) String() string {
+	if text, ok := scanModeToText[mode]; ok {
+		return text
+	} else {
+		return scanModeUnknown
+	}
+}
+
+func (mode *ScanMode) UnmarshalText(text []byte) error {
+	txt := string(text)
+	if val, ok := textToScanMode[txt]; ok {
+		*mode = val
+		return nil
+	} else {
+		return fmt.Errorf("unknown ScanMode: %s", txt)
+	}
+}
@@ -60,6 +60,9 @@ func (t *rpcType) Poll(conn *srpc.Conn) error {
 	response.DurationOfLastScan =
 		t.params.FileSystemHistory.DurationOfLastScan()
 	response.GenerationCount = t.params.FileSystemHistory.GenerationCount()
+	response.ScanMode = t.params.ScannerConfiguration.ScanMode()
+	response.NumDirtyDirectories =
+		t.params.ScannerConfiguration.NumDirtyDirectories()
 	response.SystemUptime = t.getSystemUptime()
 	fs := t.params.FileSystemHistory.FileSystem()
 	if fs != nil &&
@@ -12,6 +12,7 @@ import (
 
 	jsonlib "github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/fieldlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/osutil"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/triggers"
@@ -38,12 +39,17 @@ func (t *rpcType) Update(conn *srpc.Conn, request sub.UpdateRequest,
 		t.params.Logger.Println(err)
 		return err
 	}
-	t.params.Logger.Printf("Update(%s)\n", conn.Username())
+	logger := fieldlogger.New(log.Fields{
+		log.FieldImage:     request.ImageName,
+		log.FieldRequestId: request.RequestId,
+		log.FieldUsername:  conn.Username(),
+	}, t.params.Logger)
+	logger.Printf("Update(%s)\n", conn.Username())
 	fs := t.params.FileSystemHistory.FileSystem()
 	if request.Wait {
-		return t.updateAndUnlock(request, fs.RootDirectoryName())
+		return t.updateAndUnlock(request, fs.RootDirectoryName(), logger)
 	}
-	go t.updateAndUnlock(request, fs.RootDirectoryName())
+	go t.updateAndUnlock(request, fs.RootDirectoryName(), logger)
 	return nil
 }
 
@@ -73,7 +79,7 @@ func (t *rpcType) getUpdateLock(conn *srpc.Conn) error {
 }
 
 func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
-	rootDirectoryName string) error {
+	rootDirectoryName string, logger log.DebugLogger) error {
 	defer t.clearUpdateInProgress()
 	defer t.params.ScannerConfiguration.BoostCpuLimit(t.params.Logger)
 	t.params.DisableScannerFunction(true)
@@ -89,7 +95,7 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 		if err == nil {
 			oldTriggers.Merge(&trig)
 		} else {
-			t.params.Logger.Printf(
+			logger.Printf(
 				"Error decoding old triggers: %s", err.Error())
 		}
 	}
@@ -102,7 +108,7 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 			writer := bufio.NewWriter(file)
 			if err := jsonlib.WriteWithIndent(writer, "    ",
 				request.Triggers.Triggers); err != nil {
-				t.params.Logger.Printf("Error marshaling triggers: %s", err)
+				logger.Printf("Error marshaling triggers: %s", err)
 			}
 			writer.Flush()
 			file.Close()
@@ -112,7 +118,7 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 	var fsChangeDuration time.Duration
 	var lastUpdateError error
 	options := lib.UpdateOptions{
-		Logger:            t.params.Logger,
+		Logger:            logger,
 		ObjectsDir:        t.config.ObjectsDirectoryName,
 		OldTriggers:       oldTriggers.ExportTriggers(),
 		RootDirectoryName: rootDirectoryName,
@@ -131,11 +137,11 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 	t.lastUpdateError = lastUpdateError
 	timeTaken := time.Since(startTime)
 	if t.lastUpdateError != nil {
-		t.params.Logger.Printf("Update(): last error: %s\n", t.lastUpdateError)
+		logger.Printf("Update(): last error: %s\n", t.lastUpdateError)
 	} else {
 		note, err := t.generateNote()
 		if err != nil {
-			t.params.Logger.Println(err)
+			logger.Println(err)
 		}
 		t.rwLock.Lock()
 		t.lastSuccessfulImageName = request.ImageName
@@ -144,7 +150,7 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 		}
 		t.rwLock.Unlock()
 	}
-	t.params.Logger.Printf("Update() completed in %s (change window: %s)\n",
+	logger.Printf("Update() completed in %s (change window: %s)\n",
 		timeTaken, fsChangeDuration)
 	return t.lastUpdateError
 }
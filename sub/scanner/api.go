@@ -14,15 +14,20 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
 	"github.com/Cloud-Foundations/Dominator/lib/rateio"
+	proto "github.com/Cloud-Foundations/Dominator/proto/sub"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
 
 type Configuration struct {
 	CpuLimiter           *cpulimiter.CpuLimiter
 	DefaultCpuPercent    uint
+	EventDrivenScanning  bool // Only rescan changed directories.
 	FsScanContext        *fsrateio.ReaderContext
+	FullScanInterval     time.Duration // Between full (verification) scans.
 	NetworkReaderContext *rateio.ReaderContext
 	ScanFilter           *filter.Filter
+	watcherMutex         sync.Mutex
+	watcher              *changeWatcher
 }
 
 func (configuration *Configuration) BoostCpuLimit(logger log.Logger) {
@@ -33,6 +38,12 @@ func (configuration *Configuration) BoostScanLimit(logger log.Logger) {
 	configuration.boostScanLimit(logger)
 }
 
+// NumDirtyDirectories returns the number of directories which are pending a
+// rescan when event-driven scanning is active.
+func (configuration *Configuration) NumDirtyDirectories() uint {
+	return configuration.numDirtyDirectories()
+}
+
 func (configuration *Configuration) RegisterMetrics(
 	dir *tricorder.DirectorySpec) error {
 	return configuration.registerMetrics(dir)
@@ -46,6 +57,11 @@ func (configuration *Configuration) RestoreScanLimit(logger log.Logger) {
 	configuration.restoreScanLimit(logger)
 }
 
+// ScanMode returns the active scanning mode.
+func (configuration *Configuration) ScanMode() proto.ScanMode {
+	return configuration.scanMode()
+}
+
 func (configuration *Configuration) WriteHtml(writer io.Writer) {
 	configuration.writeHtml(writer)
 }
@@ -115,7 +131,7 @@ type FileSystem struct {
 func ScanFileSystem(rootDirectoryName string, cacheDirectoryName string,
 	configuration *Configuration) (*FileSystem, error) {
 	return scanFileSystem(rootDirectoryName, cacheDirectoryName, configuration,
-		&FileSystem{})
+		&FileSystem{}, nil)
 }
 
 func (fs *FileSystem) ScanObjectCache() error {
@@ -46,6 +46,12 @@ func (configuration *Configuration) writeHtml(writer io.Writer) {
 			ctx.SpeedPercent(), format.FormatBytes(ctx.MaximumSpeed()))
 	}
 	fmt.Fprintf(writer, "Network Speed: %s<br>\n", speed)
+	fmt.Fprintf(writer, "Scan mode: %s", configuration.scanMode())
+	if configuration.EventDrivenScanning {
+		fmt.Fprintf(writer, " (%d dirty directories)",
+			configuration.numDirtyDirectories())
+	}
+	fmt.Fprintln(writer, "<br>")
 }
 
 func (configuration *Configuration) showScanFilterHandler(
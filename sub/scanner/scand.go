@@ -5,6 +5,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 )
@@ -42,17 +43,47 @@ func scannerDaemon(rootDirectoryName string, cacheDirectoryName string,
 	loweredPriority := false
 	var oldFS FileSystem
 	var sleepUntil time.Time
+	var watcher *changeWatcher
+	if configuration.EventDrivenScanning {
+		var err error
+		watcher, err = startChangeWatcher(rootDirectoryName, logger)
+		if err != nil {
+			logger.Printf("Falling back to full scans: %s\n", err)
+		} else {
+			configuration.setWatcher(watcher)
+		}
+	}
+	var lastFullScan time.Time
+	var lastScanFilter *filter.Filter
 	for ; ; time.Sleep(time.Until(sleepUntil)) {
 		sleepUntil = time.Now().Add(time.Second)
+		var dirtyDirectories map[string]struct{}
+		if watcher != nil && oldFS.InodeTable != nil &&
+			configuration.ScanFilter == lastScanFilter {
+			var ok bool
+			dirtyDirectories, ok = watcher.waitForChanges(
+				lastFullScan.Add(configuration.FullScanInterval))
+			if !ok {
+				logger.Println("Change watcher failed, falling back to full scans")
+				watcher = nil
+				configuration.setWatcher(nil)
+			}
+		}
+		if dirtyDirectories == nil {
+			lastFullScan = time.Now()
+			lastScanFilter = configuration.ScanFilter
+		}
 		fs, err := scanFileSystem(rootDirectoryName, cacheDirectoryName,
-			configuration, &oldFS)
+			configuration, &oldFS, dirtyDirectories)
 		if err != nil {
 			if err.Error() == "DisableScan" {
 				disableScanAcknowledge <- true
 				<-disableScanAcknowledge
+				lastScanFilter = nil // Force a full scan.
 				continue
 			}
 			logger.Printf("Error scanning: %s\n", err)
+			lastScanFilter = nil // Force a full scan.
 		} else {
 			oldFS.InodeTable = fs.InodeTable
 			oldFS.DirectoryInode = fs.DirectoryInode
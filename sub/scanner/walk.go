@@ -6,7 +6,8 @@ import (
 )
 
 func scanFileSystem(rootDirectoryName string, cacheDirectoryName string,
-	configuration *Configuration, oldFS *FileSystem) (*FileSystem, error) {
+	configuration *Configuration, oldFS *FileSystem,
+	dirtyDirectories map[string]struct{}) (*FileSystem, error) {
 	var fileSystem FileSystem
 	fileSystem.configuration = configuration
 	fileSystem.rootDirectoryName = rootDirectoryName
@@ -15,9 +16,15 @@ func scanFileSystem(rootDirectoryName string, cacheDirectoryName string,
 	if configuration.CpuLimiter != nil {
 		hasher = scanner.NewCpuLimitedHasher(configuration.CpuLimiter, hasher)
 	}
-	fs, err := scanner.ScanFileSystem(rootDirectoryName,
-		configuration.FsScanContext, configuration.ScanFilter,
-		checkScanDisableRequest, hasher, &oldFS.FileSystem)
+	fs, err := scanner.ScanFileSystemWithParams(scanner.Params{
+		CheckScanDisableRequest: checkScanDisableRequest,
+		DirtyDirectories:        dirtyDirectories,
+		FsScanContext:           configuration.FsScanContext,
+		Hasher:                  hasher,
+		OldFS:                   &oldFS.FileSystem,
+		RootDirectoryName:       rootDirectoryName,
+		ScanFilter:              configuration.ScanFilter,
+	})
 	if err != nil {
 		return nil, err
 	}
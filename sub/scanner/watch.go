@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+// If more directories than this are dirty, a full scan is cheaper.
+const maxDirtyDirectories = 65536
+
+type changeWatcher struct {
+	logger      log.Logger
+	notifier    chan struct{}
+	rootDir     string
+	mutex       sync.Mutex // Protect everything below.
+	dirty       map[string]struct{}
+	needFull    bool // Events were lost.
+	watchFailed bool
+}
+
+func newChangeWatcher(rootDir string, logger log.Logger) *changeWatcher {
+	return &changeWatcher{
+		dirty:    make(map[string]struct{}),
+		logger:   logger,
+		notifier: make(chan struct{}, 1),
+		rootDir:  rootDir,
+	}
+}
+
+// markDirty records that the specified directory (an absolute pathname) has
+// changed.
+func (w *changeWatcher) markDirty(pathname string) {
+	var relativePath string
+	if w.rootDir == "/" {
+		relativePath = path.Clean(pathname)
+	} else if pathname == w.rootDir {
+		relativePath = "/"
+	} else if strings.HasPrefix(pathname, w.rootDir+"/") {
+		relativePath = path.Clean(pathname[len(w.rootDir):])
+	} else {
+		return
+	}
+	if relativePath == "/.subd" || strings.HasPrefix(relativePath, "/.subd/") {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.dirty) >= maxDirtyDirectories {
+		w.needFull = true
+		return
+	}
+	w.dirty[relativePath] = struct{}{}
+}
+
+// markFailed records that events may have been lost, so a full scan is
+// required.
+func (w *changeWatcher) markFailed(watchFailed bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.needFull = true
+	if watchFailed {
+		w.watchFailed = true
+	}
+}
+
+func (w *changeWatcher) notify() {
+	select {
+	case w.notifier <- struct{}{}:
+	default:
+	}
+}
+
+func (w *changeWatcher) numDirty() uint {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return uint(len(w.dirty))
+}
+
+// takeDirty returns the set of dirty directories and resets it. If a full scan
+// is needed, nil is returned. The second return value is false if the watcher
+// is no longer usable.
+func (w *changeWatcher) takeDirty() (map[string]struct{}, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	dirty := w.dirty
+	w.dirty = make(map[string]struct{})
+	if w.needFull {
+		w.needFull = false
+		return nil, !w.watchFailed
+	}
+	return dirty, !w.watchFailed
+}
+
+// waitForChanges waits until there are dirty directories or until the deadline.
+// Requests to disable scanning are handled while waiting. The set of dirty
+// directories is returned, or nil if a full scan is needed.
+func (w *changeWatcher) waitForChanges(
+	deadline time.Time) (map[string]struct{}, bool) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		if !time.Now().Before(deadline) {
+			return nil, true
+		}
+		dirty, ok := w.takeDirty()
+		if dirty == nil || !ok || len(dirty) > 0 {
+			return dirty, ok
+		}
+		select {
+		case <-w.notifier:
+		case <-timer.C:
+			return nil, true
+		case <-disableScanRequest:
+			disableScanAcknowledge <- true
+			<-disableScanAcknowledge
+		}
+	}
+}
+
+func (configuration *Configuration) numDirtyDirectories() uint {
+	configuration.watcherMutex.Lock()
+	watcher := configuration.watcher
+	configuration.watcherMutex.Unlock()
+	if watcher == nil {
+		return 0
+	}
+	return watcher.numDirty()
+}
+
+func (configuration *Configuration) scanMode() proto.ScanMode {
+	configuration.watcherMutex.Lock()
+	defer configuration.watcherMutex.Unlock()
+	if configuration.watcher == nil {
+		return proto.ScanModeFull
+	}
+	return proto.ScanModeEventDriven
+}
+
+func (configuration *Configuration) setWatcher(watcher *changeWatcher) {
+	configuration.watcherMutex.Lock()
+	defer configuration.watcherMutex.Unlock()
+	configuration.watcher = watcher
+}
@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	fanotifyEventMask = unix.FAN_ATTRIB | unix.FAN_CLOSE_WRITE |
+		unix.FAN_CREATE | unix.FAN_DELETE | unix.FAN_MODIFY |
+		unix.FAN_MOVED_FROM | unix.FAN_MOVED_TO | unix.FAN_ONDIR
+	fanotifyMetadataSize = 24
+	fanotifyInfoFidSize  = 12 // Header and fsid.
+	fileHandleSize       = 8  // handle_bytes and handle_type.
+)
+
+// startChangeWatcher starts watching the file-system containing rootDir for
+// changes using fanotify (Linux 5.9 or later is required).
+func startChangeWatcher(rootDir string,
+	logger log.Logger) (*changeWatcher, error) {
+	fanotifyFd, err := unix.FanotifyInit(
+		unix.FAN_CLASS_NOTIF|unix.FAN_CLOEXEC|unix.FAN_REPORT_DFID_NAME,
+		unix.O_RDONLY|unix.O_LARGEFILE)
+	if err != nil {
+		return nil, fmt.Errorf("error initialising fanotify: %s", err)
+	}
+	err = unix.FanotifyMark(fanotifyFd,
+		unix.FAN_MARK_ADD|unix.FAN_MARK_FILESYSTEM, fanotifyEventMask,
+		unix.AT_FDCWD, rootDir)
+	if err != nil {
+		unix.Close(fanotifyFd)
+		return nil, fmt.Errorf("error adding fanotify mark: %s", err)
+	}
+	mountFd, err := unix.Open(rootDir, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		unix.Close(fanotifyFd)
+		return nil, err
+	}
+	watcher := newChangeWatcher(rootDir, logger)
+	go watcher.readEvents(fanotifyFd, mountFd)
+	return watcher, nil
+}
+
+// parseDirectoryHandle extracts the file handle of the directory from a
+// fanotify event info record.
+func parseDirectoryHandle(info []byte) ([]byte, int32, bool) {
+	if len(info) < fanotifyInfoFidSize+fileHandleSize {
+		return nil, 0, false
+	}
+	switch info[0] {
+	case unix.FAN_EVENT_INFO_TYPE_DFID_NAME, unix.FAN_EVENT_INFO_TYPE_DFID:
+	default:
+		return nil, 0, false
+	}
+	handle := info[fanotifyInfoFidSize:]
+	handleBytes := binary.NativeEndian.Uint32(handle[0:4])
+	handleType := int32(binary.NativeEndian.Uint32(handle[4:8]))
+	if uint32(len(handle)-fileHandleSize) < handleBytes {
+		return nil, 0, false
+	}
+	return handle[fileHandleSize : fileHandleSize+handleBytes], handleType, true
+}
+
+func (w *changeWatcher) readEvents(fanotifyFd, mountFd int) {
+	defer unix.Close(fanotifyFd)
+	defer unix.Close(mountFd)
+	buffer := make([]byte, 256<<10)
+	for {
+		nRead, err := unix.Read(fanotifyFd, buffer)
+		if err != nil {
+			if err == unix.EINTR || err == unix.EAGAIN {
+				continue
+			}
+			w.logger.Printf("Error reading fanotify events: %s\n", err)
+			w.markFailed(true)
+			w.notify()
+			return
+		}
+		// Resolving handles is expensive, so cache them for this batch.
+		handleCache := make(map[string]struct{})
+		for offset := 0; offset+fanotifyMetadataSize <= nRead; {
+			event := buffer[offset:nRead]
+			eventLength := int(binary.NativeEndian.Uint32(event[0:4]))
+			metadataLength := int(binary.NativeEndian.Uint16(event[6:8]))
+			mask := binary.NativeEndian.Uint64(event[8:16])
+			if eventLength < fanotifyMetadataSize ||
+				eventLength > len(event) || metadataLength > eventLength {
+				break
+			}
+			offset += eventLength
+			if mask&unix.FAN_Q_OVERFLOW != 0 {
+				w.markFailed(false)
+				continue
+			}
+			handle, handleType, ok := parseDirectoryHandle(
+				event[metadataLength:eventLength])
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("%d:%x", handleType, handle)
+			if _, ok := handleCache[key]; ok {
+				continue
+			}
+			handleCache[key] = struct{}{}
+			if pathname, err := resolveHandle(mountFd, handle,
+				handleType); err == nil {
+				w.markDirty(pathname)
+			}
+		}
+		w.notify()
+	}
+}
+
+// resolveHandle returns the pathname for a directory file handle.
+func resolveHandle(mountFd int, handle []byte, handleType int32) (
+	string, error) {
+	fd, err := unix.OpenByHandleAt(mountFd,
+		unix.NewFileHandle(handleType, handle), unix.O_PATH)
+	if err != nil {
+		return "", err // Most likely the directory was deleted.
+	}
+	defer unix.Close(fd)
+	pathname, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(pathname, " (deleted)") {
+		return "", os.ErrNotExist
+	}
+	return pathname, nil
+}
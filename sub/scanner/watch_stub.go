@@ -0,0 +1,14 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"errors"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func startChangeWatcher(rootDir string,
+	logger log.Logger) (*changeWatcher, error) {
+	return nil, errors.New("event-driven scanning not supported")
+}